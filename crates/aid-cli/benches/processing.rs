@@ -37,6 +37,7 @@ fn create_configured_processor() -> Processor {
         include_patterns: Vec::new(),
         exclude_patterns: Vec::new(),
         continue_on_error: false,
+        ..Default::default()
     };
 
     let mut processor = Processor::new(options);
@@ -2,14 +2,22 @@
 //!
 //! Extract code structure for AI consumption.
 
-use anyhow::{Context, Result};
-use clap::{Parser, ValueEnum};
+use clap::{ArgMatches, CommandFactory, FromArgMatches, Parser, ValueEnum, parser::ValueSource};
 use distiller_core::{
-    ProcessOptions,
-    ir::{File, Node},
-    processor::Processor,
+    Category, CategoryFilter, DeclFilter, DistilError, Encoding, MergeMode, Preset, ProcessOptions,
+    ProjectRoot, Result, Summary, SummaryStyle, TestMode, TokenReport, Tokenizer,
+    budget::{self, Fitted},
+    config, encoding,
+    ir::{File, Node, SourceVisibility},
+    processor::{DirectoryProcessor, Processor, SkippedPath, inputs},
+    project::{self, RootMarker},
+    summary,
 };
+use formatter_json::Metadata;
+use std::io::Read;
 use std::path::{Path, PathBuf};
+use std::process::ExitCode;
+use std::time::Instant;
 
 // Language processors
 use lang_c::CProcessor;
@@ -47,6 +55,28 @@ impl Default for Format {
     }
 }
 
+/// How errors are reported on stderr
+#[derive(Debug, Clone, Copy, Default, ValueEnum)]
+enum ErrorFormat {
+    /// Human-readable message with a source snippet
+    #[default]
+    Text,
+    /// One JSON object with a stable error code, path and span
+    Json,
+}
+
+/// How token counts are reported in the run summary
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, ValueEnum)]
+enum TokenDisplay {
+    /// Don't count tokens
+    Off,
+    /// Totals and per-language counts
+    #[default]
+    Summary,
+    /// The summary plus a line per file
+    Files,
+}
+
 #[derive(Parser, Debug)]
 #[command(
     name = "aid",
@@ -57,19 +87,42 @@ impl Default for Format {
                   preserving semantic information."
 )]
 struct Args {
-    /// Path to file or directory
+    /// Files and directories to distil into one output
     #[arg(value_name = "PATH")]
-    path: Option<PathBuf>,
+    paths: Vec<PathBuf>,
+
+    /// Also distil the files listed in FILE (one per line, or NUL-separated;
+    /// "-" reads the list from stdin)
+    #[arg(long, value_name = "FILE")]
+    files_from: Option<PathBuf>,
+
+    /// Language of source read from stdin ("-"): a name such as typescript
+    /// or an extension such as ts
+    #[arg(long, value_name = "LANG")]
+    lang: Option<String>,
+
+    /// Path shown for source read from stdin ("-"); also picks the language
+    /// when --lang is not given
+    #[arg(long, value_name = "PATH")]
+    stdin_filename: Option<PathBuf>,
 
     // Output options
     /// Output format
     #[arg(short = 'f', long, value_enum, default_value = "text")]
     format: Format,
 
-    /// Output file (auto-generated if not specified)
+    /// Output file (default: <project-root>/.aid/<name>.<options>.<ext>)
     #[arg(short, long)]
     output: Option<PathBuf>,
 
+    /// Settings profile from the project config (aid.toml or .aidrc)
+    #[arg(long, value_name = "NAME")]
+    profile: Option<String>,
+
+    /// Named option set: api, outline, full or review (flags refine it)
+    #[arg(long, value_name = "api|outline|full|review")]
+    preset: Option<Preset>,
+
     /// Print to stdout instead of file
     #[arg(long)]
     stdout: bool,
@@ -91,6 +144,11 @@ struct Args {
     #[arg(long)]
     private: bool,
 
+    /// Precise visibility levels to include, e.g. "public,crate,protected-internal"
+    /// (overrides --public/--protected/--internal/--private)
+    #[arg(long, value_name = "LEVELS", value_delimiter = ',')]
+    visibility: Vec<SourceVisibility>,
+
     // Content filtering
     /// Include regular comments
     #[arg(long)]
@@ -120,6 +178,54 @@ struct Args {
     #[arg(long, default_value = "true")]
     methods: bool,
 
+    /// Include deprecated declarations
+    #[arg(long, default_value = "true")]
+    deprecated: bool,
+
+    /// Outline only: show one signature per overload set plus a count
+    #[arg(long)]
+    collapse_overloads: bool,
+
+    // Category filtering
+    /// Include only these categories: public, protected, internal, private,
+    /// comments, docstrings, implementation, imports, annotations (comma-separated)
+    #[arg(long, value_name = "CATEGORIES", value_delimiter = ',')]
+    include_only: Vec<Category>,
+
+    /// Exclude these categories (comma-separated)
+    #[arg(long, value_name = "CATEGORIES", value_delimiter = ',')]
+    exclude_items: Vec<Category>,
+
+    // Pruning
+    /// Keep files, packages and classes left empty by filtering
+    #[arg(long)]
+    keep_empty: bool,
+
+    /// Also prune classes that were already empty in the source
+    #[arg(long)]
+    prune_source_empty: bool,
+
+    // Test code
+    /// Test code handling: 1 = include, 0 = exclude, only = tests only
+    #[arg(long, value_name = "0|1|only", default_value = "1")]
+    tests: TestMode,
+
+    // Type merging
+    /// Merge impl blocks, extensions, partial classes and out-of-line methods
+    /// into their type: 0 = off, 1 = within each file, all = across files
+    #[arg(long, value_name = "0|1|all", default_value = "0")]
+    merge_types: MergeMode,
+
+    // Modifier / metadata filters
+    /// Keep only declarations with all of these modifiers or metadata,
+    /// e.g. "suspend" or "abi=C" (comma-separated)
+    #[arg(long = "with", value_name = "FILTERS", value_delimiter = ',')]
+    with_filters: Vec<DeclFilter>,
+
+    /// Drop declarations with any of these modifiers or metadata (comma-separated)
+    #[arg(long = "without", value_name = "FILTERS", value_delimiter = ',')]
+    without_filters: Vec<DeclFilter>,
+
     // Processing options
     /// Number of worker threads (0 = auto: 80% CPU cores)
     #[arg(short = 'w', long, default_value = "0")]
@@ -137,39 +243,113 @@ struct Args {
     #[arg(long)]
     exclude: Option<String>,
 
+    /// Disregard .gitignore, .ignore and .aidignore files
+    #[arg(long)]
+    no_ignore: bool,
+
+    /// Encodings tried, in order, for files that aren't UTF-8 and have no
+    /// BOM: windows-1252, latin-1 (comma-separated; default windows-1252)
+    #[arg(long, value_name = "ENCODINGS", value_delimiter = ',')]
+    fallback_encoding: Vec<Encoding>,
+
+    /// List skipped files and the rule that skipped each (stderr)
+    #[arg(long)]
+    list_skipped: bool,
+
     // Formatter-specific options
     /// Pretty-print JSON output (JSON formatter only)
     #[arg(long)]
     pretty: bool,
 
+    /// Wrap JSON output as {"metadata": ..., "files": [...]}, recording the
+    /// version and effective options (JSON formatter only)
+    #[arg(long)]
+    json_metadata: bool,
+
     /// XML indentation spaces (XML formatter only)
     #[arg(long, default_value = "2")]
     indent: usize,
 
+    // Summary
+    /// End-of-run summary on stderr: visual-progress-bar, stock-ticker,
+    /// speedometer-dashboard, minimalist-sparkline, ci-friendly, json or off
+    #[arg(long, value_name = "TYPE", default_value = "visual-progress-bar")]
+    summary_type: SummaryStyle,
+
+    /// Plain text markers instead of emoji in the summary
+    #[arg(long)]
+    no_emoji: bool,
+
+    // Token counting
+    /// Fit the output into this many tokens, dropping implementations,
+    /// docstrings, private members, class members and then the
+    /// lowest-ranked declarations as needed
+    #[arg(long, value_name = "N")]
+    max_tokens: Option<usize>,
+
+    /// Token counts in the run summary: off, summary (per language and total) or files
+    #[arg(long, value_enum, default_value = "summary")]
+    tokens: TokenDisplay,
+
+    /// Tokenizer for counts: cl100k, o200k (bundled, offline) or estimate (bytes / 4)
+    #[arg(long, value_name = "cl100k|o200k|estimate", default_value = "cl100k")]
+    tokenizer: Tokenizer,
+
+    /// Error report format on stderr
+    #[arg(long, value_enum, default_value = "text")]
+    error_format: ErrorFormat,
+
     /// Verbosity level (-v, -vv, -vvv)
     #[arg(short, long, action = clap::ArgAction::Count)]
     verbose: u8,
 }
 
 impl Args {
-    /// Convert CLI args to `ProcessOptions`
-    fn to_process_options(&self) -> ProcessOptions {
-        let mut options = ProcessOptions {
-            include_public: self.public,
-            include_protected: self.protected,
-            include_internal: self.internal,
-            include_private: self.private,
-            include_comments: self.comments,
-            include_docstrings: self.docstrings,
-            include_implementation: self.implementation,
-            include_imports: self.imports,
-            include_annotations: self.annotations,
-            include_fields: self.fields,
-            include_methods: self.methods,
-            workers: self.workers,
-            recursive: self.recursive,
-            ..Default::default()
-        };
+    /// Write the options given on the command line into `options`
+    ///
+    /// Flags left at their defaults don't touch `options`, so values from
+    /// the config file survive unless overridden.
+    fn apply_to(&self, options: &mut ProcessOptions, matches: &ArgMatches) {
+        macro_rules! set {
+            ($($arg:ident => $option:ident),*) => {
+                $(if given(matches, stringify!($arg)) {
+                    options.$option = self.$arg.clone();
+                })*
+            };
+        }
+        set!(
+            public => include_public,
+            protected => include_protected,
+            internal => include_internal,
+            private => include_private,
+            visibility => visibility_levels,
+            comments => include_comments,
+            docstrings => include_docstrings,
+            implementation => include_implementation,
+            imports => include_imports,
+            annotations => include_annotations,
+            fields => include_fields,
+            methods => include_methods,
+            deprecated => include_deprecated,
+            collapse_overloads => collapse_overloads,
+            tests => tests,
+            merge_types => merge_types,
+            with_filters => with_filters,
+            without_filters => without_filters,
+            workers => workers,
+            recursive => recursive,
+            fallback_encoding => fallback_encodings
+        );
+
+        if given(matches, "keep_empty") {
+            options.prune_empty = !self.keep_empty;
+        }
+        if given(matches, "prune_source_empty") {
+            options.keep_empty_classes = !self.prune_source_empty;
+        }
+        if given(matches, "no_ignore") {
+            options.use_ignore_files = !self.no_ignore;
+        }
 
         // Pattern filtering
         if let Some(ref include) = self.include {
@@ -178,8 +358,21 @@ impl Args {
         if let Some(ref exclude) = self.exclude {
             options.exclude_patterns = exclude.split(',').map(|s| s.trim().to_string()).collect();
         }
+    }
 
-        options
+    /// Value of a category's boolean flag
+    fn category(&self, category: Category) -> bool {
+        match category {
+            Category::Public => self.public,
+            Category::Protected => self.protected,
+            Category::Internal => self.internal,
+            Category::Private => self.private,
+            Category::Comments => self.comments,
+            Category::Docstrings => self.docstrings,
+            Category::Implementation => self.implementation,
+            Category::Imports => self.imports,
+            Category::Annotations => self.annotations,
+        }
     }
 }
 
@@ -204,69 +397,330 @@ fn extract_files(node: &Node) -> Vec<File> {
     files
 }
 
-fn main() -> Result<()> {
-    let args = Args::parse();
+fn main() -> ExitCode {
+    let matches = Args::command().get_matches();
+    let args = Args::from_arg_matches(&matches).unwrap_or_else(|e| e.exit());
 
     // Setup logging with unified helper
     distiller_core::logging::init_logging(args.verbose);
 
     log::info!("🦀 AI Distiller v{} (Rust)", env!("CARGO_PKG_VERSION"));
 
-    // Require path argument
-    let path = args.path.as_ref().context("PATH argument is required")?;
+    match run(&args, &matches) {
+        Ok(()) => ExitCode::SUCCESS,
+        Err(err) => {
+            report_error(&err, args.error_format);
+            ExitCode::FAILURE
+        }
+    }
+}
+
+/// Whether the argument `id` was given on the command line
+fn given(matches: &ArgMatches, id: &str) -> bool {
+    matches.value_source(id) == Some(ValueSource::CommandLine)
+}
 
-    // Validate path exists
-    if !path.exists() {
-        anyhow::bail!("Path does not exist: {}", path.display());
+/// Report a failed run on stderr
+fn report_error(err: &DistilError, format: ErrorFormat) {
+    match format {
+        ErrorFormat::Text => {
+            eprintln!("❌ {err}");
+            if let Some(snippet) = err.snippet() {
+                eprintln!("{snippet}");
+            }
+        }
+        ErrorFormat::Json => eprintln!("{}", err.to_json()),
     }
+}
+
+fn run(args: &Args, matches: &ArgMatches) -> Result<()> {
+    let started = Instant::now();
+    let inputs = collect_inputs(args)?;
+
+    // Step 1: Resolve options
+    let cwd = std::env::current_dir().map_err(|e| DistilError::io(".", e))?;
+    let root = ProjectRoot::detect(&cwd);
+    let (options, format) = resolve_options(args, matches, &root)?;
 
-    log::info!("Processing: {}", path.display());
-    log::debug!("Format: {:?}", args.format);
-    log::debug!("Workers: {}", args.workers);
+    // Outputs are named after the input, or the directory holding all inputs
+    let input = match inputs.as_slice() {
+        [path] => path.clone(),
+        _ => inputs::common_ancestor(&inputs).unwrap_or_else(|| cwd.clone()),
+    };
+    log::info!("Processing: {} ({} path(s))", input.display(), inputs.len());
+    log::debug!("Format: {format:?}");
+    log::debug!("Workers: {}", options.workers);
+
+    if args.list_skipped {
+        for dir in inputs.iter().filter(|path| path.is_dir()) {
+            let skipped = DirectoryProcessor::new(options.clone()).skipped_files(dir)?;
+            report_skipped(&skipped);
+        }
+    }
 
-    // Step 1: Create processor with options
-    let options = args.to_process_options();
     let processor = Processor::new(options.clone());
 
     // Register all language processors
     let mut processor = processor;
     register_all_languages(&mut processor);
 
-    // Step 2: Process path to get IR
-    let mut node = processor
-        .process_path(path)
-        .context("Failed to process path")?;
+    // Step 2: Process inputs to get IR; several inputs become one tree
+    let (mut node, stdin) = process_inputs(args, &processor, &inputs)?;
 
     // Step 2.5: Apply stripper to filter IR based on options
     use distiller_core::ir::Visitor;
     use distiller_core::stripper::Stripper;
-    let mut stripper = Stripper::new(options);
+    let mut stripper = Stripper::new(options.clone());
     stripper.visit_node(&mut node);
+    let pruned = stripper.pruned();
+    log::debug!("Pruned containers: {pruned:?}");
 
     // Step 3: Extract files from IR node
     let files = extract_files(&node);
 
     if files.is_empty() {
-        anyhow::bail!("No files found to format");
+        return Err(DistilError::invalid_config_at(
+            &input,
+            "No files found to format",
+        ));
     }
 
     log::info!("Formatting {} file(s)...", files.len());
 
-    // Step 4: Format output based on selected format
-    let output = match args.format {
+    // Step 4: Format output based on selected format, fitting it into the
+    // token budget if one is given
+    let fitted = args
+        .max_tokens
+        .map(|max_tokens| fit_budget(args, format, &node, &options, max_tokens))
+        .transpose()?;
+    let (files, output) = if let Some(fitted) = &fitted {
+        (extract_files(&fitted.node), fitted.output.clone())
+    } else {
+        let effective = options.effective();
+        let metadata = args.json_metadata.then_some(Metadata {
+            options: Some(&effective),
+            budget: None,
+        });
+        let output = format_output(format, args, &files, metadata)?;
+        (files, output)
+    };
+    let tokens = if args.tokens == TokenDisplay::Off {
+        None
+    } else {
+        Some(TokenReport::build(
+            args.tokenizer,
+            &files,
+            &output,
+            &options.fallback_encodings,
+            // Source from stdin is the only file, with no path to read back
+            |_| stdin.as_ref().map(|source| source.text.clone()),
+            |path| processor.language_for(path),
+            |file| format_output(format, args, std::slice::from_ref(file), None),
+        )?)
+    };
+
+    // Step 5: Write output
+    let output_path = write_output(args, &root, &input, format, processor.options(), &output)?;
+
+    let summary = Summary {
+        original_bytes: stdin.map_or_else(|| summary::source_bytes(&files), |source| source.bytes),
+        distilled_bytes: output.len() as u64,
+        file_count: files.len(),
+        duration: started.elapsed(),
+        tokenizer: args.tokenizer,
+        tokens: tokens.map(|mut report| {
+            if args.tokens != TokenDisplay::Files {
+                report.files.clear();
+            }
+            report
+        }),
+        budget: fitted.map(|fitted| fitted.usage),
+        pruned,
+        output_path,
+    };
+    if let Some(rendered) = summary.render(args.summary_type, !args.no_emoji) {
+        eprintln!("{rendered}");
+    }
+
+    Ok(())
+}
+
+/// Write `output` to stdout or to the output file
+///
+/// Returns the file written, or `None` for stdout.
+fn write_output(
+    args: &Args,
+    root: &ProjectRoot,
+    input: &Path,
+    format: Format,
+    options: &ProcessOptions,
+    output: &str,
+) -> Result<Option<PathBuf>> {
+    // Source from stdin has no file to name the output after
+    if args.stdout || (args.output.is_none() && input == Path::new(inputs::STDIN)) {
+        println!("{output}");
+        log::info!("Output written to stdout");
+        return Ok(None);
+    }
+
+    let output_path = match &args.output {
+        Some(path) => path.clone(),
+        // Auto-generate output filename
+        None => generate_output_path(root, input, format, options)?,
+    };
+    std::fs::write(&output_path, output).map_err(|e| DistilError::io(&output_path, e))?;
+    log::info!("Output written to: {}", output_path.display());
+    Ok(Some(output_path))
+}
+
+/// Paths given as arguments, then the ones listed by `--files-from`
+///
+/// Every path must exist, except `-` for source from stdin, which must be
+/// the only input.
+fn collect_inputs(args: &Args) -> Result<Vec<PathBuf>> {
+    let mut inputs = args.paths.clone();
+    if let Some(list) = &args.files_from {
+        inputs.extend(inputs::read_file_list(list)?);
+    }
+    if inputs.is_empty() {
+        return Err(DistilError::invalid_config(
+            "No input: give PATH arguments or a non-empty --files-from list",
+        ));
+    }
+
+    let stdin = inputs.iter().any(|path| path == Path::new(inputs::STDIN));
+    if stdin && (inputs.len() > 1 || args.files_from.is_some()) {
+        return Err(DistilError::invalid_config(
+            "'-' reads source from stdin and can't be combined with other inputs",
+        ));
+    }
+    if stdin && args.lang.is_none() && args.stdin_filename.is_none() {
+        return Err(DistilError::invalid_config(
+            "Source from stdin needs --lang or --stdin-filename",
+        ));
+    }
+    if !stdin && (args.lang.is_some() || args.stdin_filename.is_some()) {
+        return Err(DistilError::invalid_config(
+            "--lang and --stdin-filename only apply to source from stdin ('-')",
+        ));
+    }
+
+    if let Some(missing) = inputs
+        .iter()
+        .find(|path| *path != Path::new(inputs::STDIN) && !path.exists())
+    {
+        return Err(DistilError::FileNotFound {
+            path: missing.clone(),
+        });
+    }
+    Ok(inputs)
+}
+
+/// Source read from stdin
+struct StdinSource {
+    /// Decoded text
+    text: String,
+    /// Size before decoding
+    bytes: u64,
+}
+
+/// IR for the inputs, and the source if it came from stdin
+fn process_inputs(
+    args: &Args,
+    processor: &Processor,
+    inputs: &[PathBuf],
+) -> Result<(Node, Option<StdinSource>)> {
+    match inputs {
+        [path] if path == Path::new(inputs::STDIN) => {
+            let mut bytes = Vec::new();
+            std::io::stdin()
+                .read_to_end(&mut bytes)
+                .map_err(|e| DistilError::io("<stdin>", e))?;
+            let display = args
+                .stdin_filename
+                .clone()
+                .unwrap_or_else(|| PathBuf::from("<stdin>"));
+            let fallbacks = &processor.options().fallback_encodings;
+            let source = encoding::decode_source(&bytes, &display, fallbacks);
+            let node = processor.process_source(&source, &display, args.lang.as_deref())?;
+            let stdin = StdinSource {
+                text: source,
+                bytes: bytes.len() as u64,
+            };
+            Ok((node, Some(stdin)))
+        }
+        [path] if args.files_from.is_none() => Ok((processor.process_path(path)?, None)),
+        _ => Ok((processor.process_paths(inputs)?, None)),
+    }
+}
+
+/// Resolve the process options and output format
+///
+/// Defaults, then the preset (`--preset`, or the config's), then the other
+/// keys of the project config with the selected profile, then category
+/// filters and flags given on the command line. With `-v` the effective
+/// options are shown on stderr.
+fn resolve_options(
+    args: &Args,
+    matches: &ArgMatches,
+    root: &ProjectRoot,
+) -> Result<(ProcessOptions, Format)> {
+    let mut options = ProcessOptions::default();
+    let mut format = args.format;
+    if let Some((config_path, settings)) = config::apply_config(
+        &root.path,
+        args.profile.as_deref(),
+        args.preset,
+        &mut options,
+    )? {
+        log::info!("Config: {}", config_path.display());
+        if let Some(name) = settings.format.filter(|_| !given(matches, "format")) {
+            format = Format::from_str(&name, true).map_err(|_| {
+                DistilError::invalid_config_at(&config_path, format!("Unknown format '{name}'"))
+            })?;
+        }
+    }
+
+    let categories = CategoryFilter {
+        include_only: args.include_only.clone(),
+        exclude: args.exclude_items.clone(),
+    };
+    categories
+        .check(|category| given(matches, category.name()).then(|| args.category(category)))?;
+    categories.apply(&mut options);
+    args.apply_to(&mut options, matches);
+
+    if args.verbose > 0 {
+        eprintln!("⚙️  Options: {}", options.effective());
+    }
+    Ok((options, format))
+}
+
+/// Format distilled files in the selected output format
+///
+/// JSON output is an array of files, or with `metadata` a document that
+/// records it; JSONL output records only its budget. Per-file renderings
+/// pass `None`.
+fn format_output(
+    format: Format,
+    args: &Args,
+    files: &[File],
+    metadata: Option<Metadata>,
+) -> Result<String> {
+    let output = match format {
         Format::Text => {
             use formatter_text::TextFormatter;
             let formatter = TextFormatter::new();
             formatter
-                .format_files(&files)
-                .context("Failed to format as text")?
+                .format_files(files)
+                .map_err(|e| DistilError::format_error(format!("Failed to format as text: {e}")))?
         }
         Format::Md => {
             use formatter_markdown::MarkdownFormatter;
             let formatter = MarkdownFormatter::new();
-            formatter
-                .format_files(&files)
-                .context("Failed to format as markdown")?
+            formatter.format_files(files).map_err(|e| {
+                DistilError::format_error(format!("Failed to format as markdown: {e}"))
+            })?
         }
         Format::Json => {
             use formatter_json::{JsonFormatter, JsonFormatterOptions};
@@ -274,16 +728,20 @@ fn main() -> Result<()> {
                 pretty: args.pretty,
             };
             let formatter = JsonFormatter::with_options(opts);
-            formatter
-                .format_files(&files)
-                .context("Failed to format as JSON")?
+            match metadata {
+                Some(metadata) => formatter.format_files_with_metadata(files, &metadata),
+                None => formatter.format_files(files),
+            }
+            .map_err(DistilError::from)?
         }
         Format::Jsonl => {
             use formatter_jsonl::JsonlFormatter;
             let formatter = JsonlFormatter::new();
-            formatter
-                .format_files(&files)
-                .context("Failed to format as JSONL")?
+            match metadata.and_then(|metadata| metadata.budget) {
+                Some(budget) => formatter.format_files_with_budget(files, budget),
+                None => formatter.format_files(files),
+            }
+            .map_err(DistilError::from)?
         }
         Format::Xml => {
             use formatter_xml::{XmlFormatter, XmlFormatterOptions};
@@ -293,37 +751,81 @@ fn main() -> Result<()> {
             };
             let formatter = XmlFormatter::with_options(opts);
             formatter
-                .format_files(&files)
-                .context("Failed to format as XML")?
+                .format_files(files)
+                .map_err(|e| DistilError::format_error(format!("Failed to format as XML: {e}")))?
         }
     };
+    Ok(output)
+}
 
-    // Step 5: Write output
-    if args.stdout {
-        println!("{output}");
-        log::info!("Output written to stdout");
-    } else {
-        let output_path = if let Some(ref path) = args.output {
-            path.clone()
-        } else {
-            // Auto-generate output filename
-            generate_output_path(path, args.format)?
-        };
-
-        std::fs::write(&output_path, output).context(format!(
-            "Failed to write output to {}",
-            output_path.display()
-        ))?;
-
-        println!("✨ Output written to: {}", output_path.display());
-        log::info!("Output written to: {}", output_path.display());
+/// Degrade the stripped tree until its output fits into `max_tokens`
+fn fit_budget(
+    args: &Args,
+    format: Format,
+    node: &Node,
+    options: &ProcessOptions,
+    max_tokens: usize,
+) -> Result<Fitted> {
+    let effective = options.effective();
+    budget::fit(
+        node,
+        options,
+        max_tokens,
+        args.tokenizer,
+        |node, omitted| {
+            let metadata = Metadata {
+                options: args.json_metadata.then_some(&effective),
+                budget: Some(budget::Record {
+                    max_tokens,
+                    omitted,
+                }),
+            };
+            let rendered = format_output(format, args, &extract_files(node), Some(metadata))?;
+            Ok(match budget::note(max_tokens, omitted) {
+                Some(text) => annotate(format, &rendered, &text),
+                None => rendered,
+            })
+        },
+    )
+}
+
+/// Put `note` at the top of `output` as a comment, where the format has one
+///
+/// JSON has no comment syntax; JSON and JSONL output record the omissions
+/// in their metadata instead.
+fn annotate(format: Format, output: &str, note: &str) -> String {
+    match format {
+        Format::Text => format!("<omitted>{note}</omitted>\n{output}"),
+        Format::Md => format!("> {note}\n\n{output}"),
+        Format::Xml => match output.split_once('\n') {
+            Some((declaration, rest)) => format!("{declaration}\n<!-- {note} -->\n{rest}"),
+            None => output.to_string(),
+        },
+        Format::Json | Format::Jsonl => output.to_string(),
     }
+}
 
-    Ok(())
+/// List the paths directory discovery left out, one per line
+///
+/// Written to stderr so it never mixes with `--stdout` output.
+fn report_skipped(skipped: &[SkippedPath]) {
+    eprintln!("⏭️  Skipped {} path(s):", skipped.len());
+    for entry in skipped {
+        let slash = if entry.is_dir { "/" } else { "" };
+        eprintln!("  {}{slash}: {}", entry.path.display(), entry.reason);
+    }
 }
 
-/// Generate automatic output filename based on input path and format
-fn generate_output_path(input: &Path, format: Format) -> Result<PathBuf> {
+/// Generate automatic output path based on input path, format and options
+///
+/// Outputs go to the `.aid/` directory of the project root detected from
+/// the current directory, e.g. `<root>/.aid/src.pub.impl.txt`.
+fn generate_output_path(
+    root: &ProjectRoot,
+    input: &Path,
+    format: Format,
+    options: &ProcessOptions,
+) -> Result<PathBuf> {
     let extension = match format {
         Format::Text => "txt",
         Format::Md => "md",
@@ -332,19 +834,20 @@ fn generate_output_path(input: &Path, format: Format) -> Result<PathBuf> {
         Format::Xml => "xml",
     };
 
-    let basename = if input.is_file() {
-        input
-            .file_stem()
-            .and_then(|s| s.to_str())
-            .unwrap_or("output")
-    } else {
-        input
-            .file_name()
-            .and_then(|s| s.to_str())
-            .unwrap_or("output")
-    };
+    if root.marker == RootMarker::CurrentDir {
+        log::warn!(
+            "No project root found. Using current directory. For consistent behavior, \
+             create an '.aidrc' file at your project root."
+        );
+    }
+    log::info!("Project root: {} ({})", root.path.display(), root.marker);
+
+    let output_dir = root.output_dir();
+    std::fs::create_dir_all(&output_dir).map_err(|e| DistilError::io(&output_dir, e))?;
 
-    Ok(PathBuf::from(format!(".aid.{basename}.{extension}")))
+    // `.` and `..` have no file name of their own
+    let input = input.canonicalize().unwrap_or_else(|_| input.to_path_buf());
+    Ok(output_dir.join(project::output_file_name(&input, options, extension)))
 }
 
 /// Register all supported language processors
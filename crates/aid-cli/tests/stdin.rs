@@ -0,0 +1,45 @@
+//! Distilling source piped to `aid -`
+
+use assert_cmd::Command;
+
+const SOURCE: &str = "def add(a, b):\n    \"\"\"Sum.\"\"\"\n    return a + b\n";
+
+/// Run `aid -` on `SOURCE` with `args`, returning stdout and the JSON summary
+fn distil_stdin(args: &[&str]) -> (String, serde_json::Value) {
+    let assert = Command::cargo_bin("aid")
+        .unwrap()
+        .arg("-")
+        .args(args)
+        .args(["--summary-type", "json", "--tokenizer", "estimate"])
+        .write_stdin(SOURCE)
+        .assert()
+        .success();
+    let output = assert.get_output();
+    let stderr = String::from_utf8_lossy(&output.stderr);
+    let summary = stderr.lines().last().expect("summary on stderr");
+    (
+        String::from_utf8_lossy(&output.stdout).into_owned(),
+        serde_json::from_str(summary).expect("summary is one JSON line"),
+    )
+}
+
+#[test]
+fn test_stdin_with_lang() {
+    let (stdout, summary) = distil_stdin(&["--lang", "python"]);
+    assert!(stdout.contains("def add("));
+    assert!(!stdout.contains("return a + b"));
+
+    // The original is the piped source, not a file named `<stdin>`
+    assert_eq!(summary["original_bytes"], SOURCE.len());
+    assert_eq!(summary["tokens_before"], SOURCE.len().div_ceil(4));
+    assert!(summary["tokens_after"].as_u64().unwrap() > 0);
+    assert_eq!(summary["token_languages"]["python"]["files"], 1);
+    assert!(summary["output_path"].is_null());
+}
+
+#[test]
+fn test_stdin_filename_needs_no_file() {
+    let (stdout, summary) = distil_stdin(&["--stdin-filename", "src/missing/calc.py"]);
+    assert!(stdout.contains("<file path=\"src/missing/calc.py\">"));
+    assert_eq!(summary["tokens_before"], SOURCE.len().div_ceil(4));
+}
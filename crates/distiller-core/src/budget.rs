@@ -0,0 +1,492 @@
+//! Token budgets
+//!
+//! `--max-tokens N` fits the output into N tokens. Detail is degraded one
+//! step at a time until the rendered output fits: implementations, then
+//! docstrings and comments, then private members, then class members
+//! (classes collapse to their names). If that is still too large, top-level
+//! declarations are ranked and the lowest-ranked are dropped. Every step
+//! taken is recorded so the output can say what was omitted.
+
+use crate::ProcessOptions;
+use crate::ir::{Node, Visibility, Visitor};
+use crate::stripper::Stripper;
+use crate::tokens::Tokenizer;
+use serde::{Serialize, Serializer};
+use std::collections::{HashMap, HashSet};
+use std::fmt;
+
+/// Something left out to fit the budget
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Omission {
+    /// Function and method bodies
+    Implementations,
+    /// Docstrings and comments
+    Docstrings,
+    /// Private members and declarations
+    PrivateMembers,
+    /// Members of classes, interfaces, structs and enums
+    ClassMembers,
+    /// This many lowest-ranked top-level declarations
+    Declarations(usize),
+}
+
+/// Degradation steps, in the order they are tried
+const STEPS: [Omission; 4] = [
+    Omission::Implementations,
+    Omission::Docstrings,
+    Omission::PrivateMembers,
+    Omission::ClassMembers,
+];
+
+impl fmt::Display for Omission {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Implementations => write!(f, "implementations"),
+            Self::Docstrings => write!(f, "docstrings and comments"),
+            Self::PrivateMembers => write!(f, "private members"),
+            Self::ClassMembers => write!(f, "class members"),
+            Self::Declarations(count) => write!(f, "{count} lowest-ranked declarations"),
+        }
+    }
+}
+
+impl Serialize for Omission {
+    fn serialize<S: Serializer>(&self, serializer: S) -> Result<S::Ok, S::Error> {
+        serializer.collect_str(self)
+    }
+}
+
+/// Statement of what was left out to fit `max_tokens`, if anything
+#[must_use]
+pub fn note(max_tokens: usize, omitted: &[Omission]) -> Option<String> {
+    if omitted.is_empty() {
+        return None;
+    }
+    let omitted: Vec<String> = omitted.iter().map(ToString::to_string).collect();
+    Some(format!(
+        "Omitted to fit {max_tokens} tokens: {}",
+        omitted.join(", ")
+    ))
+}
+
+/// A budget and what was left out to fit it, as recorded in JSON output
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+pub struct Record<'a> {
+    pub max_tokens: usize,
+    pub omitted: &'a [Omission],
+}
+
+/// How an output met its token budget
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct Usage {
+    /// Tokens in the output
+    pub tokens: usize,
+    pub max_tokens: usize,
+    pub omitted: Vec<Omission>,
+}
+
+impl Usage {
+    /// Whether the output is within the budget
+    ///
+    /// False only if dropping every declaration still didn't fit.
+    #[must_use]
+    pub fn fits(&self) -> bool {
+        self.tokens <= self.max_tokens
+    }
+
+    /// Statement of what was left out, if anything
+    #[must_use]
+    pub fn note(&self) -> Option<String> {
+        note(self.max_tokens, &self.omitted)
+    }
+}
+
+/// A tree fitted into a token budget, with its rendered output
+///
+/// Serializes as its [`Usage`] only, without the tree and output.
+#[derive(Debug, Clone, Serialize)]
+pub struct Fitted {
+    #[serde(skip)]
+    pub node: Node,
+    #[serde(skip)]
+    pub output: String,
+    #[serde(flatten)]
+    pub usage: Usage,
+}
+
+impl Fitted {
+    /// Whether the output is within the budget
+    #[must_use]
+    pub fn fits(&self) -> bool {
+        self.usage.fits()
+    }
+}
+
+/// Degrade `node` until its rendered output fits into `max_tokens`
+///
+/// `node` is a tree already stripped with `options`. `render` formats a
+/// tree given the omissions so far, so a note it adds is counted as well.
+///
+/// # Errors
+///
+/// Returns the first error from `render`.
+pub fn fit<E, R>(
+    node: &Node,
+    options: &ProcessOptions,
+    max_tokens: usize,
+    tokenizer: Tokenizer,
+    mut render: R,
+) -> Result<Fitted, E>
+where
+    R: FnMut(&Node, &[Omission]) -> Result<String, E>,
+{
+    let output = render(node, &[])?;
+    let mut fitted = Fitted {
+        node: node.clone(),
+        usage: Usage {
+            tokens: tokenizer.count(&output),
+            max_tokens,
+            omitted: Vec::new(),
+        },
+        output,
+    };
+
+    let mut options = options.clone();
+    for step in STEPS {
+        if fitted.fits() {
+            return Ok(fitted);
+        }
+        // Steps that change nothing aren't reported
+        if !degrade(&mut fitted.node, &mut options, step) {
+            continue;
+        }
+        fitted.usage.omitted.push(step);
+        fitted.output = render(&fitted.node, &fitted.usage.omitted)?;
+        fitted.usage.tokens = tokenizer.count(&fitted.output);
+    }
+    if fitted.fits() {
+        return Ok(fitted);
+    }
+
+    // Drop the fewest lowest-ranked declarations that make it fit, or all
+    let ranked = rank(&fitted.node, &fitted.output);
+    let (mut low, mut high) = (1, ranked.len());
+    let mut best = None;
+    while low <= high {
+        let count = low + (high - low) / 2;
+        let mut omitted = fitted.usage.omitted.clone();
+        omitted.push(Omission::Declarations(count));
+        let tree = drop_declarations(&fitted.node, &ranked[..count]);
+        let output = render(&tree, &omitted)?;
+        let tokens = tokenizer.count(&output);
+        let fits = tokens <= max_tokens;
+        if fits || count == ranked.len() {
+            best = Some(Fitted {
+                node: tree,
+                output,
+                usage: Usage {
+                    tokens,
+                    max_tokens,
+                    omitted,
+                },
+            });
+        }
+        if fits {
+            high = count - 1;
+        } else {
+            low = count + 1;
+        }
+    }
+    Ok(best.unwrap_or(fitted))
+}
+
+/// Apply one degradation step; false if it removed nothing
+fn degrade(node: &mut Node, options: &mut ProcessOptions, step: Omission) -> bool {
+    let before = weight(node);
+    match step {
+        Omission::Implementations => options.include_implementation = false,
+        Omission::Docstrings => {
+            options.include_docstrings = false;
+            options.include_comments = false;
+        }
+        Omission::PrivateMembers => {
+            options.include_private = false;
+            options
+                .visibility_levels
+                .retain(|level| level.coarse() != Visibility::Private);
+        }
+        Omission::ClassMembers => {
+            collapse_types(node);
+            return weight(node) != before;
+        }
+        Omission::Declarations(_) => return false,
+    }
+    Stripper::new(options.clone()).visit_node(node);
+    weight(node) != before
+}
+
+/// Size of a tree, to tell whether a step changed it
+fn weight(node: &Node) -> usize {
+    serde_json::to_vec(node).map_or(0, |bytes| bytes.len())
+}
+
+/// Remove the members of every type, leaving its name and header
+fn collapse_types(node: &mut Node) {
+    match node {
+        Node::Class(c) => c.children.clear(),
+        Node::Interface(i) => i.children.clear(),
+        Node::Struct(s) => s.children.clear(),
+        Node::Enum(e) => e.children.clear(),
+        _ => {
+            if let Some(children) = scope_mut(node) {
+                children.iter_mut().for_each(collapse_types);
+            }
+        }
+    }
+}
+
+/// Children of containers whose declarations count as top level
+fn scope(node: &Node) -> Option<&Vec<Node>> {
+    match node {
+        Node::Directory(d) => Some(&d.children),
+        Node::File(f) => Some(&f.children),
+        Node::Package(p) => Some(&p.children),
+        Node::Module(m) => Some(&m.children),
+        _ => None,
+    }
+}
+
+fn scope_mut(node: &mut Node) -> Option<&mut Vec<Node>> {
+    match node {
+        Node::Directory(d) => Some(&mut d.children),
+        Node::File(f) => Some(&mut f.children),
+        Node::Package(p) => Some(&mut p.children),
+        Node::Module(m) => Some(&mut m.children),
+        _ => None,
+    }
+}
+
+/// Name, visibility and kind weight of a rankable declaration
+fn declaration(node: &Node) -> Option<(&str, Visibility, u8)> {
+    match node {
+        Node::Class(c) => Some((&c.name, c.visibility, 2)),
+        Node::Interface(i) => Some((&i.name, i.visibility, 2)),
+        Node::Struct(s) => Some((&s.name, s.visibility, 2)),
+        Node::Enum(e) => Some((&e.name, e.visibility, 2)),
+        Node::TypeAlias(t) => Some((&t.name, t.visibility, 2)),
+        Node::Function(f) => Some((&f.name, f.visibility, 1)),
+        Node::OverloadSet(o) => {
+            let visibility = o
+                .signatures
+                .first()
+                .map_or(Visibility::Public, |f| f.visibility);
+            Some((&o.name, visibility, 1))
+        }
+        Node::Macro(m) => Some((&m.name, m.visibility, 1)),
+        Node::Variable(v) => Some((&v.name, v.visibility, 0)),
+        Node::Field(f) => Some((&f.name, f.visibility, 0)),
+        Node::Property(p) => Some((&p.name, p.visibility, 0)),
+        _ => None,
+    }
+}
+
+/// Visit top-level declarations in a stable order
+fn declarations<'a>(node: &'a Node, found: &mut Vec<&'a Node>) {
+    let Some(children) = scope(node) else { return };
+    for child in children {
+        if scope(child).is_some() {
+            declarations(child, found);
+        } else if declaration(child).is_some() {
+            found.push(child);
+        }
+    }
+}
+
+/// Top-level declaration indices, least important first
+///
+/// Public API outranks private code, declarations mentioned more often in
+/// `output` outrank those mentioned less, and types outrank functions,
+/// which outrank variables.
+fn rank(node: &Node, output: &str) -> Vec<usize> {
+    let mut mentions: HashMap<&str, usize> = HashMap::new();
+    for word in output.split(|c: char| !(c.is_alphanumeric() || c == '_')) {
+        if !word.is_empty() {
+            *mentions.entry(word).or_default() += 1;
+        }
+    }
+
+    let mut found = Vec::new();
+    declarations(node, &mut found);
+    let mut ranked: Vec<(usize, (u8, usize, u8))> = found
+        .iter()
+        .enumerate()
+        .filter_map(|(index, decl)| {
+            let (name, visibility, kind) = declaration(decl)?;
+            let visibility = match visibility {
+                Visibility::Public => 3,
+                Visibility::Protected => 2,
+                Visibility::Internal => 1,
+                Visibility::Private => 0,
+            };
+            let references = mentions.get(name).copied().unwrap_or(0);
+            Some((index, (visibility, references, kind)))
+        })
+        .collect();
+    // Stable, so ties drop later declarations last
+    ranked.sort_by_key(|&(_, score)| score);
+    ranked.into_iter().map(|(index, _)| index).collect()
+}
+
+/// A copy of `node` without the top-level declarations at `indices`
+fn drop_declarations(node: &Node, indices: &[usize]) -> Node {
+    fn retain(node: &mut Node, drop: &HashSet<usize>, next: &mut usize) {
+        let Some(children) = scope_mut(node) else {
+            return;
+        };
+        children.retain_mut(|child| {
+            if scope(child).is_some() {
+                retain(child, drop, next);
+                true
+            } else if declaration(child).is_some() {
+                *next += 1;
+                !drop.contains(&(*next - 1))
+            } else {
+                true
+            }
+        });
+    }
+
+    let mut node = node.clone();
+    let drop: HashSet<usize> = indices.iter().copied().collect();
+    retain(&mut node, &drop, &mut 0);
+    node
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ir::{Class, Comment, File, Function};
+    use std::collections::BTreeMap;
+
+    fn function(name: &str, visibility: Visibility, body: &str) -> Node {
+        Node::Function(Function {
+            name: name.to_string(),
+            visibility,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            parameters: vec![],
+            return_type: None,
+            implementation: Some(body.to_string()),
+            line_start: 1,
+            line_end: 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    fn sample() -> Node {
+        Node::File(File {
+            path: "lib.py".to_string(),
+            children: vec![
+                Node::Comment(Comment {
+                    text: "Module docs that take up a fair amount of room".to_string(),
+                    format: "doc".to_string(),
+                    line: 1,
+                }),
+                Node::Class(Class {
+                    name: "Api".to_string(),
+                    visibility: Visibility::Public,
+                    source_visibility: None,
+                    modifiers: vec![],
+                    decorators: vec![],
+                    type_params: vec![],
+                    extends: vec![],
+                    implements: vec![],
+                    children: vec![
+                        function("get", Visibility::Public, "return helper()"),
+                        function("_cache", Visibility::Private, "pass"),
+                    ],
+                    line_start: 1,
+                    line_end: 10,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
+                }),
+                function("helper", Visibility::Public, "return 42 * 42 * 42 * 42"),
+                function("_unused", Visibility::Private, "pass"),
+            ],
+        })
+    }
+
+    /// Render as JSON, with the note on the first line
+    fn render(node: &Node, omitted: &[Omission]) -> Result<String, serde_json::Error> {
+        let json = serde_json::to_string(node)?;
+        Ok(match note(1000, omitted) {
+            Some(text) => format!("{text}\n{json}"),
+            None => json,
+        })
+    }
+
+    fn options() -> ProcessOptions {
+        ProcessOptions {
+            include_private: true,
+            include_implementation: true,
+            include_comments: true,
+            ..Default::default()
+        }
+    }
+
+    #[test]
+    fn test_fits_without_changes() {
+        let fitted = fit(&sample(), &options(), 100_000, Tokenizer::Estimate, render).unwrap();
+        assert!(fitted.fits());
+        assert!(fitted.usage.omitted.is_empty());
+        assert_eq!(fitted.usage.note(), None);
+    }
+
+    #[test]
+    fn test_degrades_in_order() {
+        let full = Tokenizer::Estimate.count(&render(&sample(), &[]).unwrap());
+        let fitted = fit(&sample(), &options(), full - 5, Tokenizer::Estimate, render).unwrap();
+        assert!(fitted.fits());
+        assert_eq!(fitted.usage.omitted, vec![Omission::Implementations]);
+        assert!(
+            fitted
+                .output
+                .starts_with("Omitted to fit 1000 tokens: implementations")
+        );
+
+        let fitted = fit(&sample(), &options(), 1, Tokenizer::Estimate, render).unwrap();
+        assert!(!fitted.fits());
+        assert_eq!(
+            &fitted.usage.omitted[..4],
+            &[
+                Omission::Implementations,
+                Omission::Docstrings,
+                Omission::PrivateMembers,
+                Omission::ClassMembers,
+            ]
+        );
+        assert_eq!(fitted.usage.omitted[4], Omission::Declarations(2));
+
+        let json = serde_json::to_value(&fitted).unwrap();
+        assert_eq!(json["max_tokens"], 1);
+        assert_eq!(json["omitted"][4], "2 lowest-ranked declarations");
+        assert!(json.get("output").is_none());
+    }
+
+    #[test]
+    fn test_rank_prefers_public_and_referenced() {
+        let node = sample();
+        let output = "Api helper helper get";
+        // helper (public, 2 mentions) > Api (public type, 1 mention) > _unused
+        assert_eq!(rank(&node, output), vec![2, 0, 1]);
+
+        let dropped = drop_declarations(&node, &[2]);
+        let Node::File(file) = dropped else {
+            unreachable!()
+        };
+        assert_eq!(file.children.len(), 3);
+    }
+}
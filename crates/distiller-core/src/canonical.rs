@@ -0,0 +1,883 @@
+//! Cross-language type classification
+//!
+//! `TypeRef.name` keeps the type as written, so Go `int32`, Kotlin `Int`
+//! and TypeScript `number` never compare equal. `canonicalize` runs once the
+//! tree is built and fills `TypeRef.canonical` with a language-independent
+//! category. The file extension picks the primitive table, because the same
+//! spelling means different things: `long` is 64 bits in Java and C#, but
+//! platform-sized in C; `int` is 32 bits in Java and unbounded in Python.
+
+use crate::ir::{CanonicalType, Function, Node, TypeParam, TypeRef, TypeShape};
+use crate::parser::types::{closing_bracket, find_top_level, split_top_level};
+use std::path::Path;
+
+/// Language whose primitive names apply to a file
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Language {
+    Go,
+    Python,
+    Rust,
+    TypeScript,
+    Java,
+    Kotlin,
+    CSharp,
+    Ruby,
+    Php,
+    Swift,
+    C,
+    Other,
+}
+
+impl Language {
+    fn from_path(path: &Path) -> Self {
+        let Some(ext) = path.extension().and_then(|ext| ext.to_str()) else {
+            return Self::Other;
+        };
+        match ext {
+            "go" => Self::Go,
+            "py" | "pyi" => Self::Python,
+            "rs" => Self::Rust,
+            "js" | "jsx" | "mjs" | "cjs" | "ts" | "tsx" | "mts" | "cts" => Self::TypeScript,
+            "java" => Self::Java,
+            "kt" | "kts" => Self::Kotlin,
+            "cs" => Self::CSharp,
+            "rb" => Self::Ruby,
+            "php" => Self::Php,
+            "swift" => Self::Swift,
+            "c" | "h" | "cc" | "cpp" | "cxx" | "hpp" | "hh" | "hxx" => Self::C,
+            _ => Self::Other,
+        }
+    }
+}
+
+/// Fill `canonical` on every type reference in the tree
+///
+/// Covers parameter, return, field, property, variable, alias and
+/// type-parameter types, including nested type arguments. Supertypes in
+/// `extends`/`implements` are names rather than value types and are left
+/// alone, as are types that classify as unknown.
+pub fn canonicalize(node: &mut Node) {
+    canonicalize_in(node, Language::Other);
+}
+
+/// Classify a type as written in the file at `path`
+#[must_use]
+pub fn classify(name: &str, path: &Path) -> CanonicalType {
+    classify_in(name, Language::from_path(path))
+}
+
+fn canonicalize_in(node: &mut Node, language: Language) {
+    match node {
+        Node::Directory(d) => canonicalize_children(&mut d.children, language),
+        Node::File(f) => {
+            let language = Language::from_path(Path::new(&f.path));
+            canonicalize_children(&mut f.children, language);
+        }
+        Node::Package(p) => canonicalize_children(&mut p.children, language),
+        Node::Module(m) => canonicalize_children(&mut m.children, language),
+        Node::Class(c) => {
+            annotate_type_params(&mut c.type_params, language);
+            canonicalize_children(&mut c.children, language);
+        }
+        Node::Interface(i) => {
+            annotate_type_params(&mut i.type_params, language);
+            canonicalize_children(&mut i.children, language);
+        }
+        Node::Struct(s) => {
+            annotate_type_params(&mut s.type_params, language);
+            canonicalize_children(&mut s.children, language);
+        }
+        Node::Enum(e) => {
+            annotate_type_params(&mut e.type_params, language);
+            if let Some(enum_type) = &mut e.enum_type {
+                annotate(enum_type, language);
+            }
+            canonicalize_children(&mut e.children, language);
+        }
+        Node::EnumVariant(v) => {
+            for field in &mut v.fields {
+                annotate(&mut field.param_type, language);
+            }
+            canonicalize_children(&mut v.children, language);
+        }
+        Node::TypeAlias(t) => {
+            annotate_type_params(&mut t.type_params, language);
+            annotate(&mut t.alias_type, language);
+        }
+        Node::Function(f) => annotate_function(f, language),
+        Node::OverloadSet(o) => {
+            for signature in &mut o.signatures {
+                annotate_function(signature, language);
+            }
+        }
+        Node::Field(f) => {
+            if let Some(field_type) = &mut f.field_type {
+                annotate(field_type, language);
+            }
+        }
+        Node::Property(p) => {
+            if let Some(property_type) = &mut p.property_type {
+                annotate(property_type, language);
+            }
+        }
+        Node::Variable(v) => {
+            if let Some(var_type) = &mut v.var_type {
+                annotate(var_type, language);
+            }
+        }
+        Node::Import(_) | Node::Macro(_) | Node::Comment(_) | Node::RawContent(_) => {}
+    }
+}
+
+fn canonicalize_children(children: &mut [Node], language: Language) {
+    for child in children {
+        canonicalize_in(child, language);
+    }
+}
+
+fn annotate_function(function: &mut Function, language: Language) {
+    annotate_type_params(&mut function.type_params, language);
+    for param in &mut function.parameters {
+        annotate(&mut param.param_type, language);
+    }
+    if let Some(return_type) = &mut function.return_type {
+        annotate(return_type, language);
+    }
+}
+
+fn annotate_type_params(type_params: &mut [TypeParam], language: Language) {
+    for type_param in type_params {
+        for constraint in &mut type_param.constraints {
+            annotate(constraint, language);
+        }
+        if let Some(default) = &mut type_param.default {
+            annotate(default, language);
+        }
+    }
+}
+
+fn annotate(type_ref: &mut TypeRef, language: Language) {
+    for arg in &mut type_ref.type_args {
+        annotate(arg, language);
+    }
+    match &mut type_ref.shape {
+        Some(TypeShape::Union { members } | TypeShape::Intersection { members }) => {
+            for member in members {
+                annotate(member, language);
+            }
+        }
+        Some(TypeShape::Function { params, returns }) => {
+            for param in params {
+                annotate(param, language);
+            }
+            if let Some(returns) = returns {
+                annotate(returns, language);
+            }
+        }
+        Some(TypeShape::Tuple { elements }) => {
+            for element in elements {
+                annotate(element, language);
+            }
+        }
+        Some(TypeShape::Pointer { target, .. } | TypeShape::Reference { target, .. }) => {
+            annotate(target, language);
+        }
+        None => {}
+    }
+
+    let canonical = classify_in(&type_ref.name, language);
+    type_ref.canonical = (canonical != CanonicalType::Unknown).then_some(canonical);
+}
+
+fn classify_in(text: &str, language: Language) -> CanonicalType {
+    let text = text.trim().trim_matches(['"', '\'']).trim();
+    if text.is_empty() || text == "?" {
+        return CanonicalType::Unknown;
+    }
+
+    let members = split_top_level(text, "|");
+    if members.len() > 1 {
+        return union(&members, language);
+    }
+    if !find_top_level(text, "=>").is_empty()
+        || !find_top_level(text, "->").is_empty()
+        || text.contains("(*")
+        || ["fn(", "fn (", "func(", "func (", "function("]
+            .iter()
+            .any(|prefix| text.starts_with(prefix))
+    {
+        return CanonicalType::Function;
+    }
+
+    // Nullable markers: Kotlin/Swift/C#/TypeScript `T?`, PHP `?T`
+    if let Some(inner) = text.strip_suffix('?') {
+        return optional(classify_in(inner, language));
+    }
+    if let Some(inner) = text.strip_prefix('?') {
+        let inner = inner.trim_start();
+        // Java wildcards: `? extends Number`
+        for bound in ["extends ", "super "] {
+            if let Some(bounded) = inner.strip_prefix(bound) {
+                return classify_in(bounded, language);
+            }
+        }
+        return optional(classify_in(inner, language));
+    }
+
+    // References, pointers and qualifiers don't change the category
+    if let Some(rest) = text.strip_prefix('@') {
+        // Java annotations on the type use: `@NonNull String`
+        if let Some((_, inner)) = rest.split_once(char::is_whitespace) {
+            return classify_in(inner, language);
+        }
+    }
+    if let Some(inner) = text.strip_prefix('&') {
+        let inner = inner.trim_start();
+        let inner = match inner.strip_prefix('\'') {
+            Some(lifetime) => lifetime.split_once(' ').map_or("", |(_, rest)| rest),
+            None => inner,
+        };
+        return classify_in(inner.strip_prefix("mut ").unwrap_or(inner), language);
+    }
+    for prefix in [
+        "*mut ",
+        "*const ",
+        "*",
+        "const ",
+        "volatile ",
+        "dyn ",
+        "impl ",
+        "readonly ",
+        "...",
+    ] {
+        if let Some(inner) = text.strip_prefix(prefix) {
+            return classify_in(inner, language);
+        }
+    }
+    if let Some(inner) = text.strip_suffix('*') {
+        let pointee = inner
+            .trim()
+            .trim_start_matches("const ")
+            .trim_end_matches(" const");
+        if matches!(pointee.trim(), "char" | "wchar_t" | "char16_t" | "char32_t") {
+            return CanonicalType::String;
+        }
+        return classify_in(pointee, language);
+    }
+    for suffix in ["&&", "&", " const"] {
+        if let Some(inner) = text.strip_suffix(suffix) {
+            return classify_in(inner, language);
+        }
+    }
+
+    if let Some(structured) = classify_structured(text, language) {
+        return structured;
+    }
+    if text.ends_with(')') {
+        // Rust `FnOnce()`, C++ `void(int)`
+        return CanonicalType::Function;
+    }
+
+    let name = last_segment(text);
+    container(name, vec![]).unwrap_or_else(|| primitive(name, language))
+}
+
+/// Arrays, maps, tuples and generic types
+fn classify_structured(text: &str, language: Language) -> Option<CanonicalType> {
+    if let Some(element) = text.strip_suffix("[]") {
+        return Some(list(classify_in(element, language)));
+    }
+    if let Some(rest) = text.strip_prefix("map[") {
+        // Go `map[K]V`
+        if let Some(close) = closing_bracket(rest) {
+            return Some(map(
+                classify_in(&rest[..close], language),
+                classify_in(&rest[close + 1..], language),
+            ));
+        }
+    }
+    if let Some(rest) = text.strip_prefix('[')
+        && let Some(close) = closing_bracket(rest)
+    {
+        let (inside, after) = (&rest[..close], rest[close + 1..].trim());
+        // Go `[]T` and `[N]T`
+        if !after.is_empty() {
+            return Some(list(classify_in(after, language)));
+        }
+        // Swift `[K: V]`
+        if let [key, value] = split_top_level(inside, ":").as_slice() {
+            return Some(map(
+                classify_in(key, language),
+                classify_in(value, language),
+            ));
+        }
+        // Swift `[T]`, Rust `[T]` and `[T; N]`
+        let element = split_top_level(inside, ";")[0];
+        return Some(list(classify_in(element, language)));
+    }
+    if text.starts_with('(') {
+        // Tuples and the unit type
+        return Some(CanonicalType::Unknown);
+    }
+
+    // Generic types: `List<T>`, Python/Go `list[T]`
+    for (open, close) in [('<', '>'), ('[', ']')] {
+        if let (Some(start), true) = (text.find(open), text.ends_with(close)) {
+            let base = text[..start].trim();
+            let args = split_top_level(&text[start + 1..text.len() - 1], ",");
+            if base == "Union" || base.ends_with(".Union") {
+                return Some(union(&args, language));
+            }
+            if base == "Annotated" || base.ends_with(".Annotated") {
+                return Some(classify_in(args[0], language));
+            }
+            let args: Vec<CanonicalType> = args
+                .into_iter()
+                .map(|arg| classify_in(arg, language))
+                .collect();
+            return Some(container(last_segment(base), args).unwrap_or(CanonicalType::Unknown));
+        }
+    }
+    None
+}
+
+/// `T | null` and `Union[T, None]` are optional; other unions are unknown
+fn union(members: &[&str], language: Language) -> CanonicalType {
+    let present: Vec<&str> = members
+        .iter()
+        .map(|member| member.trim())
+        .filter(|member| {
+            !matches!(
+                *member,
+                "null" | "None" | "NoneType" | "undefined" | "nil" | "void"
+            )
+        })
+        .collect();
+    match present.as_slice() {
+        [inner] if present.len() < members.len() => optional(classify_in(inner, language)),
+        _ => CanonicalType::Unknown,
+    }
+}
+
+/// Collection, wrapper and callable types, by their unqualified name
+fn container(name: &str, args: Vec<CanonicalType>) -> Option<CanonicalType> {
+    let mut args = args.into_iter();
+    let mut next = || args.next().unwrap_or(CanonicalType::Unknown);
+
+    Some(match name {
+        "Option" | "Optional" | "optional" | "Nullable" => optional(next()),
+        "Vec"
+        | "VecDeque"
+        | "LinkedList"
+        | "HashSet"
+        | "BTreeSet"
+        | "IndexSet"
+        | "List"
+        | "list"
+        | "ArrayList"
+        | "MutableList"
+        | "Array"
+        | "array"
+        | "ReadonlyArray"
+        | "Set"
+        | "set"
+        | "MutableSet"
+        | "LinkedHashSet"
+        | "TreeSet"
+        | "SortedSet"
+        | "frozenset"
+        | "FrozenSet"
+        | "Sequence"
+        | "MutableSequence"
+        | "Iterable"
+        | "Collection"
+        | "MutableCollection"
+        | "Seq"
+        | "IEnumerable"
+        | "ICollection"
+        | "IList"
+        | "IReadOnlyList"
+        | "IReadOnlyCollection"
+        | "ISet"
+        | "vector"
+        | "deque"
+        | "unordered_set"
+        | "span"
+        | "ContiguousArray" => list(next()),
+        "Map"
+        | "map"
+        | "HashMap"
+        | "BTreeMap"
+        | "IndexMap"
+        | "MutableMap"
+        | "LinkedHashMap"
+        | "TreeMap"
+        | "SortedMap"
+        | "ConcurrentHashMap"
+        | "dict"
+        | "Dict"
+        | "Mapping"
+        | "MutableMapping"
+        | "OrderedDict"
+        | "defaultdict"
+        | "Record"
+        | "Dictionary"
+        | "IDictionary"
+        | "IReadOnlyDictionary"
+        | "SortedDictionary"
+        | "unordered_map" => {
+            let key = next();
+            map(key, next())
+        }
+        "Box" | "Rc" | "Arc" | "Cow" | "RefCell" | "Cell" | "Mutex" | "RwLock" | "Pin"
+        | "unique_ptr" | "shared_ptr" | "Final" | "ClassVar" | "Readonly" | "Required"
+        | "NotRequired" => next(),
+        "Fn" | "FnMut" | "FnOnce" | "Callable" | "Func" | "Action" | "Function" | "function"
+        | "Supplier" | "Consumer" | "BiConsumer" | "BiFunction" | "Predicate" | "Runnable"
+        | "Closure" | "callable" => CanonicalType::Function,
+        _ => return None,
+    })
+}
+
+/// Scalar types, by their unqualified name
+fn primitive(name: &str, language: Language) -> CanonicalType {
+    if let Some(sized) = sized_number(name) {
+        return sized;
+    }
+    match name {
+        "bool" | "boolean" | "Boolean" | "Bool" | "_Bool" => return CanonicalType::Bool,
+        "string" | "String" | "str" | "CharSequence" | "Substring" | "NSString" | "string_view"
+        | "wstring" | "AnyStr" => return CanonicalType::String,
+        _ => {}
+    }
+
+    match language {
+        Language::Go => match name {
+            "int" => integer(None, true),
+            "uint" | "uintptr" => integer(None, false),
+            "byte" => integer(Some(8), false),
+            "rune" => integer(Some(32), true),
+            _ => CanonicalType::Unknown,
+        },
+        Language::Python => match name {
+            "int" => integer(None, true),
+            "float" => float(Some(64)),
+            "Decimal" => float(None),
+            _ => CanonicalType::Unknown,
+        },
+        Language::Rust => match name {
+            "isize" => integer(None, true),
+            "usize" => integer(None, false),
+            _ => CanonicalType::Unknown,
+        },
+        Language::TypeScript => match name {
+            "number" => float(Some(64)),
+            "bigint" => integer(None, true),
+            _ => CanonicalType::Unknown,
+        },
+        Language::Java => match name {
+            "byte" | "Byte" => integer(Some(8), true),
+            "short" | "Short" => integer(Some(16), true),
+            "int" | "Integer" => integer(Some(32), true),
+            "long" | "Long" => integer(Some(64), true),
+            "char" | "Character" => integer(Some(16), false),
+            "float" | "Float" => float(Some(32)),
+            "double" | "Double" => float(Some(64)),
+            "BigInteger" => integer(None, true),
+            "BigDecimal" => float(None),
+            _ => CanonicalType::Unknown,
+        },
+        Language::Kotlin => match name {
+            "Byte" => integer(Some(8), true),
+            "Short" => integer(Some(16), true),
+            "Int" => integer(Some(32), true),
+            "Long" => integer(Some(64), true),
+            "UByte" => integer(Some(8), false),
+            "UShort" | "Char" => integer(Some(16), false),
+            "UInt" => integer(Some(32), false),
+            "ULong" => integer(Some(64), false),
+            "Float" => float(Some(32)),
+            "Double" => float(Some(64)),
+            _ => CanonicalType::Unknown,
+        },
+        Language::CSharp => match name {
+            "sbyte" | "SByte" => integer(Some(8), true),
+            "byte" | "Byte" => integer(Some(8), false),
+            "short" => integer(Some(16), true),
+            "ushort" | "char" | "Char" => integer(Some(16), false),
+            "int" => integer(Some(32), true),
+            "uint" => integer(Some(32), false),
+            "long" => integer(Some(64), true),
+            "ulong" => integer(Some(64), false),
+            "nint" | "IntPtr" => integer(None, true),
+            "nuint" | "UIntPtr" => integer(None, false),
+            "float" | "Single" => float(Some(32)),
+            "double" | "Double" => float(Some(64)),
+            "decimal" | "Decimal" => float(Some(128)),
+            _ => CanonicalType::Unknown,
+        },
+        Language::Ruby => match name {
+            "Integer" => integer(None, true),
+            "Float" => float(Some(64)),
+            "Symbol" => CanonicalType::String,
+            _ => CanonicalType::Unknown,
+        },
+        Language::Php => match name {
+            "int" | "integer" => integer(Some(64), true),
+            "float" | "double" => float(Some(64)),
+            _ => CanonicalType::Unknown,
+        },
+        Language::Swift => match name {
+            "Int" => integer(None, true),
+            "UInt" => integer(None, false),
+            "Float" => float(Some(32)),
+            "Double" => float(Some(64)),
+            "CGFloat" => float(None),
+            "Character" => CanonicalType::String,
+            _ => CanonicalType::Unknown,
+        },
+        Language::C => c_primitive(name),
+        Language::Other => CanonicalType::Unknown,
+    }
+}
+
+/// C and C++ spellings, which may be several words
+fn c_primitive(name: &str) -> CanonicalType {
+    match name {
+        "char" | "signed char" => integer(Some(8), true),
+        "unsigned char" => integer(Some(8), false),
+        "short" | "short int" | "signed short" | "signed short int" => integer(Some(16), true),
+        "unsigned short" | "unsigned short int" => integer(Some(16), false),
+        "int" | "signed" | "signed int" => integer(Some(32), true),
+        "unsigned" | "unsigned int" => integer(Some(32), false),
+        "long" | "long int" | "signed long" | "signed long int" | "ssize_t" | "ptrdiff_t"
+        | "intptr_t" => integer(None, true),
+        "unsigned long" | "unsigned long int" | "size_t" | "uintptr_t" => integer(None, false),
+        "long long" | "long long int" | "signed long long" => integer(Some(64), true),
+        "unsigned long long" | "unsigned long long int" => integer(Some(64), false),
+        "float" => float(Some(32)),
+        "double" => float(Some(64)),
+        "long double" => float(None),
+        _ => CanonicalType::Unknown,
+    }
+}
+
+/// Width-suffixed numbers: `i32`, `uint8`, `int64_t`, `Int16`, `float32`, `f64`
+fn sized_number(name: &str) -> Option<CanonicalType> {
+    let name = name.strip_suffix("_t").unwrap_or(name);
+    let bits = |prefix: &str| {
+        name.strip_prefix(prefix)
+            .and_then(|bits| bits.parse::<u16>().ok())
+            .filter(|bits| matches!(bits, 8 | 16 | 32 | 64 | 128))
+    };
+
+    for (prefix, signed) in [
+        ("uint", false),
+        ("UInt", false),
+        ("int", true),
+        ("Int", true),
+        ("u", false),
+        ("i", true),
+    ] {
+        if let Some(bits) = bits(prefix) {
+            return Some(integer(Some(bits), signed));
+        }
+    }
+    ["float", "Float", "f"]
+        .into_iter()
+        .find_map(|prefix| bits(prefix).filter(|&bits| bits >= 16))
+        .map(|bits| float(Some(bits)))
+}
+
+const fn integer(bits: Option<u16>, signed: bool) -> CanonicalType {
+    CanonicalType::Integer { bits, signed }
+}
+
+const fn float(bits: Option<u16>) -> CanonicalType {
+    CanonicalType::Float { bits }
+}
+
+fn list(element: CanonicalType) -> CanonicalType {
+    CanonicalType::List {
+        element: Box::new(element),
+    }
+}
+
+fn map(key: CanonicalType, value: CanonicalType) -> CanonicalType {
+    CanonicalType::Map {
+        key: Box::new(key),
+        value: Box::new(value),
+    }
+}
+
+fn optional(inner: CanonicalType) -> CanonicalType {
+    CanonicalType::Optional {
+        inner: Box::new(inner),
+    }
+}
+
+/// `java.util.List`, `std::vector` and `typing.Optional` by their last segment
+fn last_segment(name: &str) -> &str {
+    let name = name.rsplit("::").next().unwrap_or(name);
+    if name.contains(' ') {
+        // `unsigned long` and other multi-word C types
+        return name;
+    }
+    name.rsplit(['.', '\\']).next().unwrap_or(name)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ir::{Field, File, Parameter, Visibility};
+    use crate::parser::types::{TypeSyntax, parse_type};
+    use std::collections::BTreeMap;
+
+    fn int(bits: u16) -> CanonicalType {
+        integer(Some(bits), true)
+    }
+
+    #[test]
+    fn test_same_integer_across_languages() {
+        for (name, path) in [
+            ("int32", "a.go"),
+            ("i32", "a.rs"),
+            ("int", "A.java"),
+            ("Integer", "A.java"),
+            ("Int", "A.kt"),
+            ("int", "A.cs"),
+            ("Int32", "A.cs"),
+            ("Int32", "A.swift"),
+            ("int32_t", "a.c"),
+            ("std::int32_t", "a.cpp"),
+        ] {
+            assert_eq!(classify(name, Path::new(path)), int(32), "{name} in {path}");
+        }
+    }
+
+    #[test]
+    fn test_platform_sized_and_unbounded_integers() {
+        assert_eq!(classify("int", Path::new("a.go")), integer(None, true));
+        assert_eq!(classify("int", Path::new("a.py")), integer(None, true));
+        assert_eq!(classify("Int", Path::new("a.swift")), integer(None, true));
+        assert_eq!(classify("usize", Path::new("a.rs")), integer(None, false));
+        assert_eq!(
+            classify("unsigned long", Path::new("a.c")),
+            integer(None, false)
+        );
+        assert_eq!(classify("long", Path::new("A.java")), int(64));
+        assert_eq!(classify("byte", Path::new("a.go")), integer(Some(8), false));
+    }
+
+    #[test]
+    fn test_scalars() {
+        assert_eq!(classify("number", Path::new("a.ts")), float(Some(64)));
+        assert_eq!(classify("float32", Path::new("a.go")), float(Some(32)));
+        assert_eq!(classify("f64", Path::new("a.rs")), float(Some(64)));
+        assert_eq!(classify("&str", Path::new("a.rs")), CanonicalType::String);
+        assert_eq!(
+            classify("&'a str", Path::new("a.rs")),
+            CanonicalType::String
+        );
+        assert_eq!(
+            classify("const char*", Path::new("a.c")),
+            CanonicalType::String
+        );
+        assert_eq!(
+            classify("const std::string&", Path::new("a.cpp")),
+            CanonicalType::String
+        );
+        assert_eq!(
+            classify("boolean", Path::new("A.java")),
+            CanonicalType::Bool
+        );
+        assert_eq!(
+            classify("Widget", Path::new("a.ts")),
+            CanonicalType::Unknown
+        );
+    }
+
+    #[test]
+    fn test_optional_forms() {
+        let optional_string = optional(CanonicalType::String);
+        for (name, path) in [
+            ("String?", "A.kt"),
+            ("String?", "A.swift"),
+            ("string?", "A.cs"),
+            ("?string", "a.php"),
+            ("string | null", "a.ts"),
+            ("str | None", "a.py"),
+            ("Optional[str]", "a.py"),
+            ("typing.Optional[str]", "a.py"),
+            ("Union[str, None]", "a.py"),
+            ("Option<String>", "a.rs"),
+            ("Optional<String>", "A.java"),
+            ("*string", "a.go"),
+        ] {
+            // Go pointers are nil-able, but not optional values
+            let expected = if name == "*string" {
+                CanonicalType::String
+            } else {
+                optional_string.clone()
+            };
+            assert_eq!(
+                classify(name, Path::new(path)),
+                expected,
+                "{name} in {path}"
+            );
+        }
+        assert_eq!(
+            classify("string | number", Path::new("a.ts")),
+            CanonicalType::Unknown
+        );
+    }
+
+    #[test]
+    fn test_collections() {
+        let strings = list(CanonicalType::String);
+        for (name, path) in [
+            ("[]string", "a.go"),
+            ("string[]", "a.ts"),
+            ("Array<string>", "a.ts"),
+            ("list[str]", "a.py"),
+            ("Vec<String>", "a.rs"),
+            ("&[&str]", "a.rs"),
+            ("[String]", "a.swift"),
+            ("List<String>", "A.java"),
+            ("IEnumerable<string>", "A.cs"),
+            ("std::vector<std::string>", "a.cpp"),
+        ] {
+            assert_eq!(classify(name, Path::new(path)), strings, "{name} in {path}");
+        }
+
+        let counts = map(CanonicalType::String, int(32));
+        for (name, path) in [
+            ("map[string]int32", "a.go"),
+            ("HashMap<String, i32>", "a.rs"),
+            ("Map<String, Integer>", "A.java"),
+            ("Dictionary<string, int>", "A.cs"),
+            ("[String: Int32]", "a.swift"),
+        ] {
+            assert_eq!(classify(name, Path::new(path)), counts, "{name} in {path}");
+        }
+        assert_eq!(
+            classify("Record<string, number>", Path::new("a.ts")),
+            map(CanonicalType::String, float(Some(64)))
+        );
+    }
+
+    #[test]
+    fn test_functions() {
+        for (name, path) in [
+            ("(a: number) => void", "a.ts"),
+            ("(Int) -> Unit", "A.kt"),
+            ("suspend (Int) -> Unit", "A.kt"),
+            ("Box<dyn Fn(i32) -> i32>", "a.rs"),
+            ("impl FnOnce()", "a.rs"),
+            ("fn(u8) -> bool", "a.rs"),
+            ("func(string) error", "a.go"),
+            ("Callable[[int], str]", "a.py"),
+            ("Func<int, string>", "A.cs"),
+            ("std::function<void(int)>", "a.cpp"),
+        ] {
+            assert_eq!(
+                classify(name, Path::new(path)),
+                CanonicalType::Function,
+                "{name} in {path}"
+            );
+        }
+    }
+
+    #[test]
+    fn test_canonicalize_tree() {
+        let field = |name: &str, ty: TypeRef| {
+            Node::Field(Field {
+                name: name.to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                modifiers: vec![],
+                field_type: Some(ty),
+                default_value: None,
+                line: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })
+        };
+        let nullable = parse_type("Int?", TypeSyntax::Kotlin);
+        let ids = parse_type("List<Long>", TypeSyntax::Kotlin);
+
+        let mut node = Node::File(File {
+            path: "src/User.kt".to_string(),
+            children: vec![
+                field("age", nullable),
+                field("ids", ids),
+                field("owner", TypeRef::new("User")),
+            ],
+        });
+        canonicalize(&mut node);
+
+        let Node::File(file) = &node else {
+            unreachable!()
+        };
+        let canonical: Vec<Option<CanonicalType>> = file
+            .children
+            .iter()
+            .map(|child| match child {
+                Node::Field(f) => f.field_type.as_ref().and_then(|t| t.canonical.clone()),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(
+            canonical,
+            vec![Some(optional(int(32))), Some(list(int(64))), None]
+        );
+
+        let Node::Field(ids) = &file.children[1] else {
+            unreachable!()
+        };
+        let ids = ids.field_type.as_ref().unwrap();
+        assert_eq!(ids.type_args[0].canonical, Some(int(64)));
+    }
+
+    #[test]
+    fn test_parameter_types_canonicalized() {
+        let mut function = crate::parser::synthesized::synthesized_method(
+            "get",
+            Visibility::Public,
+            vec![Parameter {
+                name: "key".to_string(),
+                param_type: TypeRef::new("str"),
+                default_value: None,
+                is_variadic: false,
+                is_optional: false,
+                decorators: vec![],
+            }],
+            Some(TypeRef::new("dict[str, int]")),
+            1,
+            "test",
+        );
+        annotate_function(&mut function, Language::Python);
+
+        assert_eq!(
+            function.parameters[0].param_type.canonical,
+            Some(CanonicalType::String)
+        );
+        assert_eq!(
+            function.return_type.unwrap().canonical,
+            Some(map(CanonicalType::String, integer(None, true)))
+        );
+    }
+
+    #[test]
+    fn test_serialized_shape() {
+        let json = serde_json::to_value(map(CanonicalType::String, int(32))).unwrap();
+        assert_eq!(
+            json,
+            serde_json::json!({
+                "category": "map",
+                "key": {"category": "string"},
+                "value": {"category": "integer", "bits": 32, "signed": true}
+            })
+        );
+    }
+}
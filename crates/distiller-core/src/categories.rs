@@ -0,0 +1,358 @@
+//! Category-based filtering and presets
+//!
+//! `--include-only` and `--exclude-items` switch whole categories at once
+//! instead of one boolean flag each. Categories are the four visibility
+//! buckets and the content kinds `comments`, `docstrings`,
+//! `implementation`, `imports` and `annotations`; every other option is
+//! left alone. `--preset` picks a named set of options for a common job.
+//!
+//! Precedence, lowest first: defaults, config file, preset, categories,
+//! individual flags. A flag that contradicts a category filter is an error
+//! rather than a silent override.
+
+use crate::error::{DistilError, Result};
+use crate::options::ProcessOptions;
+use crate::test_filter::TestMode;
+use std::fmt;
+use std::str::FromStr;
+
+/// A group of declarations or content switched by one flag
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Category {
+    Public,
+    Protected,
+    Internal,
+    Private,
+    Comments,
+    Docstrings,
+    Implementation,
+    Imports,
+    Annotations,
+}
+
+impl Category {
+    /// Every category, in documentation order
+    pub const ALL: [Self; 9] = [
+        Self::Public,
+        Self::Protected,
+        Self::Internal,
+        Self::Private,
+        Self::Comments,
+        Self::Docstrings,
+        Self::Implementation,
+        Self::Imports,
+        Self::Annotations,
+    ];
+
+    /// Category name, which is also the name of its boolean flag
+    #[must_use]
+    pub fn name(self) -> &'static str {
+        match self {
+            Self::Public => "public",
+            Self::Protected => "protected",
+            Self::Internal => "internal",
+            Self::Private => "private",
+            Self::Comments => "comments",
+            Self::Docstrings => "docstrings",
+            Self::Implementation => "implementation",
+            Self::Imports => "imports",
+            Self::Annotations => "annotations",
+        }
+    }
+
+    /// Whether `options` include this category
+    #[must_use]
+    pub fn get(self, options: &ProcessOptions) -> bool {
+        match self {
+            Self::Public => options.include_public,
+            Self::Protected => options.include_protected,
+            Self::Internal => options.include_internal,
+            Self::Private => options.include_private,
+            Self::Comments => options.include_comments,
+            Self::Docstrings => options.include_docstrings,
+            Self::Implementation => options.include_implementation,
+            Self::Imports => options.include_imports,
+            Self::Annotations => options.include_annotations,
+        }
+    }
+
+    /// Include or drop this category
+    pub fn set(self, options: &mut ProcessOptions, value: bool) {
+        *self.field(options) = value;
+    }
+
+    fn field(self, options: &mut ProcessOptions) -> &mut bool {
+        match self {
+            Self::Public => &mut options.include_public,
+            Self::Protected => &mut options.include_protected,
+            Self::Internal => &mut options.include_internal,
+            Self::Private => &mut options.include_private,
+            Self::Comments => &mut options.include_comments,
+            Self::Docstrings => &mut options.include_docstrings,
+            Self::Implementation => &mut options.include_implementation,
+            Self::Imports => &mut options.include_imports,
+            Self::Annotations => &mut options.include_annotations,
+        }
+    }
+}
+
+impl FromStr for Category {
+    type Err = String;
+
+    fn from_str(s: &str) -> std::result::Result<Self, Self::Err> {
+        let name = s.trim().to_ascii_lowercase();
+        Self::ALL
+            .into_iter()
+            .find(|category| category.name() == name)
+            .ok_or_else(|| {
+                let names: Vec<&str> = Self::ALL.iter().map(|c| c.name()).collect();
+                format!("invalid category '{s}' (expected {})", names.join(", "))
+            })
+    }
+}
+
+impl fmt::Display for Category {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.name())
+    }
+}
+
+/// `--include-only` and `--exclude-items`
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct CategoryFilter {
+    /// Include these categories and drop every other one (empty = no change)
+    pub include_only: Vec<Category>,
+    /// Drop these categories
+    pub exclude: Vec<Category>,
+}
+
+impl CategoryFilter {
+    /// The value the filter gives `category`, or `None` if it leaves it alone
+    #[must_use]
+    pub fn value(&self, category: Category) -> Option<bool> {
+        if self.exclude.contains(&category) {
+            Some(false)
+        } else if self.include_only.is_empty() {
+            None
+        } else {
+            Some(self.include_only.contains(&category))
+        }
+    }
+
+    /// Reject contradictory combinations
+    ///
+    /// `explicit` returns the value of a category's boolean flag when that
+    /// flag was given. A flag may repeat what the filter says but not
+    /// contradict it, and no category may be both included and excluded.
+    ///
+    /// # Errors
+    ///
+    /// Returns an invalid-config error naming the first conflict.
+    pub fn check(&self, explicit: impl Fn(Category) -> Option<bool>) -> Result<()> {
+        if let Some(category) = self
+            .include_only
+            .iter()
+            .find(|category| self.exclude.contains(category))
+        {
+            return Err(DistilError::invalid_config(format!(
+                "'{category}' is in both --include-only and --exclude-items"
+            )));
+        }
+
+        for category in Category::ALL {
+            let (Some(given), Some(filtered)) = (explicit(category), self.value(category)) else {
+                continue;
+            };
+            if given != filtered {
+                let source = if self.exclude.contains(&category) {
+                    "--exclude-items"
+                } else {
+                    "--include-only"
+                };
+                let verb = if filtered { "includes" } else { "leaves out" };
+                return Err(DistilError::invalid_config(format!(
+                    "--{category}={} conflicts with {source}, which {verb} '{category}'",
+                    u8::from(given)
+                )));
+            }
+        }
+        Ok(())
+    }
+
+    /// Switch the filtered categories in `options`
+    pub fn apply(&self, options: &mut ProcessOptions) {
+        for category in Category::ALL {
+            if let Some(value) = self.value(category) {
+                category.set(options, value);
+            }
+        }
+    }
+}
+
+/// A named set of options for a common job
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Preset {
+    /// Public API with docstrings, no tests: what a library user sees
+    Api,
+    /// Signatures of every non-private declaration, nothing else
+    Outline,
+    /// Everything: all visibility, bodies, comments, tests
+    Full,
+    /// Production code in full for code review: bodies and comments, no
+    /// imports or tests
+    Review,
+}
+
+impl Preset {
+    const NAMES: [(&'static str, Self); 4] = [
+        ("api", Self::Api),
+        ("outline", Self::Outline),
+        ("full", Self::Full),
+        ("review", Self::Review),
+    ];
+
+    /// Set the categories, overload collapsing and test handling of `options`
+    ///
+    /// File selection, workers and the rest of `options` are kept.
+    pub fn apply(self, options: &mut ProcessOptions) {
+        let included: &[Category] = match self {
+            Self::Api => &[
+                Category::Public,
+                Category::Docstrings,
+                Category::Imports,
+                Category::Annotations,
+            ],
+            Self::Outline => &[Category::Public, Category::Protected, Category::Internal],
+            Self::Full => &Category::ALL,
+            Self::Review => &[
+                Category::Public,
+                Category::Protected,
+                Category::Internal,
+                Category::Private,
+                Category::Comments,
+                Category::Docstrings,
+                Category::Implementation,
+                Category::Annotations,
+            ],
+        };
+        for category in Category::ALL {
+            category.set(options, included.contains(&category));
+        }
+        options.visibility_levels.clear();
+        options.include_fields = true;
+        options.include_methods = true;
+        options.include_deprecated = true;
+        options.collapse_overloads = self == Self::Outline;
+        options.tests = match self {
+            Self::Full => TestMode::Include,
+            Self::Api | Self::Outline | Self::Review => TestMode::Exclude,
+        };
+    }
+
+    /// Default options with this preset applied
+    #[must_use]
+    pub fn options(self) -> ProcessOptions {
+        let mut options = ProcessOptions::default();
+        self.apply(&mut options);
+        options
+    }
+}
+
+impl FromStr for Preset {
+    type Err = String;
+
+    fn from_str(s: &str) -> std::result::Result<Self, Self::Err> {
+        let name = s.trim().to_ascii_lowercase();
+        Self::NAMES
+            .iter()
+            .find(|(known, _)| *known == name)
+            .map(|(_, preset)| *preset)
+            .ok_or_else(|| format!("invalid preset '{s}' (expected api, outline, full or review)"))
+    }
+}
+
+impl fmt::Display for Preset {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        let (name, _) = Self::NAMES
+            .iter()
+            .find(|(_, preset)| preset == self)
+            .expect("every preset has a name");
+        f.write_str(name)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn filter(include_only: &[Category], exclude: &[Category]) -> CategoryFilter {
+        CategoryFilter {
+            include_only: include_only.to_vec(),
+            exclude: exclude.to_vec(),
+        }
+    }
+
+    #[test]
+    fn test_category_names() {
+        for category in Category::ALL {
+            assert_eq!(category.name().parse::<Category>(), Ok(category));
+        }
+        assert_eq!(" Imports ".parse::<Category>(), Ok(Category::Imports));
+        assert!("fields".parse::<Category>().is_err());
+    }
+
+    #[test]
+    fn test_include_only_and_exclude() {
+        let mut options = ProcessOptions::default();
+        filter(&[Category::Public, Category::Imports], &[]).apply(&mut options);
+        assert!(options.include_public && options.include_imports);
+        assert!(!options.include_docstrings && !options.include_annotations);
+        assert!(options.include_methods, "non-categories are left alone");
+
+        let mut options = Preset::Full.options();
+        filter(&[], &[Category::Private, Category::Comments]).apply(&mut options);
+        assert!(!options.include_private && !options.include_comments);
+        assert!(options.include_implementation);
+    }
+
+    #[test]
+    fn test_conflicts() {
+        let only_public = filter(&[Category::Public], &[]);
+        let given = |category, value| move |c| (c == category).then_some(value);
+        assert!(only_public.check(given(Category::Public, true)).is_ok());
+        let err = only_public
+            .check(given(Category::Private, true))
+            .unwrap_err();
+        assert_eq!(
+            err.to_string(),
+            "Invalid configuration: --private=1 conflicts with --include-only, \
+             which leaves out 'private'"
+        );
+
+        let no_comments = filter(&[], &[Category::Comments]);
+        assert!(no_comments.check(given(Category::Comments, true)).is_err());
+        assert!(no_comments.check(given(Category::Private, true)).is_ok());
+
+        let both = filter(&[Category::Imports], &[Category::Imports]);
+        assert!(both.check(|_| None).is_err());
+    }
+
+    #[test]
+    fn test_presets() {
+        let api = Preset::Api.options();
+        assert!(api.include_public && !api.include_private && !api.include_implementation);
+        assert_eq!(api.tests, TestMode::Exclude);
+
+        let outline = Preset::Outline.options();
+        assert!(outline.collapse_overloads && !outline.include_docstrings);
+
+        let full = Preset::Full.options();
+        assert!(Category::ALL.iter().all(|c| c.get(&full)));
+        assert_eq!(full.tests, TestMode::Include);
+
+        let review = Preset::Review.options();
+        assert!(review.include_private && review.include_implementation && !review.include_imports);
+        assert_eq!("Review".parse::<Preset>(), Ok(Preset::Review));
+        assert_eq!(Preset::Outline.to_string(), "outline");
+    }
+}
@@ -0,0 +1,500 @@
+//! Project configuration file (`aid.toml` / `.aidrc`)
+//!
+//! A TOML file at the project root holds the settings otherwise repeated
+//! on every invocation. `[defaults]` applies to every run; each
+//! `[profiles.<name>]` table is layered on top when that profile is
+//! selected. Keys mirror the CLI flags:
+//!
+//! ```toml
+//! [defaults]
+//! format = "md"
+//! exclude = ["vendor/**"]
+//!
+//! [profiles.review]
+//! private = true
+//! implementation = true
+//!
+//! [profiles.outline]
+//! preset = "outline"
+//! docstrings = true
+//!
+//! [defaults.languages]
+//! pyi = "python"
+//! ```
+//!
+//! A `preset` is expanded first, so the other keys - from either table -
+//! refine it; `--preset` on the command line takes its place. Explicit CLI
+//! flags and MCP request options override the file.
+
+use crate::categories::Preset;
+use crate::decl_filter::DeclFilter;
+use crate::encoding::Encoding;
+use crate::error::{DistilError, Result, SourceSpan};
+use crate::ir::SourceVisibility;
+use crate::options::ProcessOptions;
+use serde::Deserialize;
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+use std::str::FromStr;
+
+/// Config file names, in lookup order
+///
+/// An empty `.aidrc` only marks the project root.
+pub const CONFIG_FILES: &[&str] = &["aid.toml", ".aidrc"];
+
+/// Settings from one table of the config file
+///
+/// Every field is optional; unset fields leave the option alone.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Deserialize)]
+#[serde(default, deny_unknown_fields, rename_all = "kebab-case")]
+pub struct Settings {
+    // Output
+    /// Output format name (`text`, `md`, `json`, `jsonl`, `xml`)
+    pub format: Option<String>,
+    /// Preset name (`api`, `outline`, `full`, `review`), applied first
+    pub preset: Option<String>,
+
+    // Visibility
+    pub public: Option<bool>,
+    pub protected: Option<bool>,
+    pub internal: Option<bool>,
+    pub private: Option<bool>,
+    /// Precise visibility levels, as for `--visibility`
+    pub visibility: Option<Vec<String>>,
+
+    // Content
+    pub comments: Option<bool>,
+    pub docstrings: Option<bool>,
+    pub implementation: Option<bool>,
+    pub imports: Option<bool>,
+    pub annotations: Option<bool>,
+    pub fields: Option<bool>,
+    pub methods: Option<bool>,
+    pub deprecated: Option<bool>,
+    pub collapse_overloads: Option<bool>,
+
+    // Processing
+    /// Test code handling (`0`, `1`, `only`)
+    pub tests: Option<String>,
+    /// Type merging (`0`, `1`, `all`)
+    pub merge_types: Option<String>,
+    pub with: Option<Vec<String>>,
+    pub without: Option<Vec<String>>,
+    pub include: Option<Vec<String>>,
+    pub exclude: Option<Vec<String>>,
+    /// Disregard `.gitignore`, `.ignore` and `.aidignore` files
+    pub no_ignore: Option<bool>,
+    pub recursive: Option<bool>,
+    pub workers: Option<usize>,
+    /// Encodings tried for files that aren't UTF-8 (`windows-1252`,
+    /// `latin-1`); empty decodes them lossily at once
+    pub fallback_encoding: Option<Vec<String>>,
+
+    /// File extension to language name, e.g. `pyi = "python"`
+    pub languages: BTreeMap<String, String>,
+}
+
+impl Settings {
+    /// Layer `other` on top of these settings
+    pub fn merge(&mut self, other: &Self) {
+        macro_rules! take {
+            ($($field:ident),*) => {
+                $(if other.$field.is_some() {
+                    self.$field.clone_from(&other.$field);
+                })*
+            };
+        }
+        take!(
+            format,
+            preset,
+            public,
+            protected,
+            internal,
+            private,
+            visibility,
+            comments,
+            docstrings,
+            implementation,
+            imports,
+            annotations,
+            fields,
+            methods,
+            deprecated,
+            collapse_overloads,
+            tests,
+            merge_types,
+            with,
+            without,
+            include,
+            exclude,
+            no_ignore,
+            recursive,
+            workers,
+            fallback_encoding
+        );
+        self.languages
+            .extend(other.languages.iter().map(|(k, v)| (k.clone(), v.clone())));
+    }
+
+    /// Write these settings into `options`
+    ///
+    /// # Errors
+    ///
+    /// Returns an error naming `path` if a value does not parse.
+    pub fn apply(&self, options: &mut ProcessOptions, path: &Path) -> Result<()> {
+        let invalid = |message: String| DistilError::invalid_config_at(path, message);
+        if let Some(preset) = &self.preset {
+            preset.parse::<Preset>().map_err(invalid)?.apply(options);
+        }
+
+        macro_rules! set {
+            ($($field:ident => $option:ident),*) => {
+                $(if let Some(value) = self.$field {
+                    options.$option = value;
+                })*
+            };
+        }
+        set!(
+            public => include_public,
+            protected => include_protected,
+            internal => include_internal,
+            private => include_private,
+            comments => include_comments,
+            docstrings => include_docstrings,
+            implementation => include_implementation,
+            imports => include_imports,
+            annotations => include_annotations,
+            fields => include_fields,
+            methods => include_methods,
+            deprecated => include_deprecated,
+            collapse_overloads => collapse_overloads,
+            recursive => recursive,
+            workers => workers
+        );
+
+        if let Some(levels) = &self.visibility {
+            options.visibility_levels = parse_all::<SourceVisibility>(levels).map_err(invalid)?;
+        }
+        if let Some(tests) = &self.tests {
+            options.tests = tests.parse().map_err(invalid)?;
+        }
+        if let Some(mode) = &self.merge_types {
+            options.merge_types = mode.parse().map_err(invalid)?;
+        }
+        if let Some(filters) = &self.with {
+            options.with_filters = parse_all::<DeclFilter>(filters).map_err(invalid)?;
+        }
+        if let Some(filters) = &self.without {
+            options.without_filters = parse_all::<DeclFilter>(filters).map_err(invalid)?;
+        }
+        if let Some(patterns) = &self.include {
+            options.include_patterns.clone_from(patterns);
+        }
+        if let Some(patterns) = &self.exclude {
+            options.exclude_patterns.clone_from(patterns);
+        }
+        if let Some(encodings) = &self.fallback_encoding {
+            options.fallback_encodings = parse_all::<Encoding>(encodings).map_err(invalid)?;
+        }
+        if let Some(no_ignore) = self.no_ignore {
+            options.use_ignore_files = !no_ignore;
+        }
+        options.language_map.extend(
+            self.languages
+                .iter()
+                .map(|(ext, lang)| (ext.trim_start_matches('.').to_string(), lang.clone())),
+        );
+        Ok(())
+    }
+}
+
+/// Parse every value, stopping at the first failure
+fn parse_all<T: FromStr<Err = String>>(values: &[String]) -> std::result::Result<Vec<T>, String> {
+    values.iter().map(|v| v.parse()).collect()
+}
+
+/// A parsed config file
+#[derive(Debug, Clone, Default, PartialEq, Eq, Deserialize)]
+#[serde(default, deny_unknown_fields)]
+pub struct Config {
+    /// Settings for every run
+    pub defaults: Settings,
+    /// Named settings layered on top of `defaults`
+    pub profiles: BTreeMap<String, Settings>,
+    /// The file the config was read from
+    #[serde(skip)]
+    pub path: PathBuf,
+}
+
+impl Config {
+    /// Load the config file in `root`, if there is one
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the file can't be read or isn't valid TOML for
+    /// this schema; parse errors point at the offending line.
+    pub fn load(root: &Path) -> Result<Option<Self>> {
+        let Some(path) = CONFIG_FILES
+            .iter()
+            .map(|name| root.join(name))
+            .find(|path| path.is_file())
+        else {
+            return Ok(None);
+        };
+
+        let text = std::fs::read_to_string(&path).map_err(|e| DistilError::io(&path, e))?;
+        Self::parse(&text, &path).map(Some)
+    }
+
+    /// Parse config text read from `path`
+    ///
+    /// # Errors
+    ///
+    /// Returns a parse error with the location of the first problem.
+    pub fn parse(text: &str, path: &Path) -> Result<Self> {
+        let mut config: Self = toml::from_str(text).map_err(|e| match e.span() {
+            Some(span) => DistilError::parse_error_at(
+                path,
+                e.message(),
+                SourceSpan::from_offsets(text, span.start, span.end),
+                text,
+            ),
+            None => DistilError::parse_error(path, e.message()),
+        })?;
+        config.path = path.to_path_buf();
+        Ok(config)
+    }
+
+    /// Settings for `profile` (or just the defaults)
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the profile isn't defined.
+    pub fn settings(&self, profile: Option<&str>) -> Result<Settings> {
+        let mut settings = self.defaults.clone();
+        if let Some(name) = profile {
+            let Some(overrides) = self.profiles.get(name) else {
+                let known = self.profiles.keys().cloned().collect::<Vec<_>>();
+                return Err(DistilError::invalid_config_at(
+                    &self.path,
+                    format!("Unknown profile '{name}' (available: {})", known.join(", ")),
+                ));
+            };
+            settings.merge(overrides);
+        }
+        Ok(settings)
+    }
+}
+
+/// Apply the config at `root` with `profile` selected to `options`
+///
+/// `preset` replaces the config's own preset, so the config's other keys
+/// refine it as they would their own; without a config file it is applied
+/// alone. Returns the config file used, if any.
+///
+/// # Errors
+///
+/// Returns an error if the file is invalid, or `profile` is given but not
+/// defined (or there is no config file at all).
+pub fn apply_config(
+    root: &Path,
+    profile: Option<&str>,
+    preset: Option<Preset>,
+    options: &mut ProcessOptions,
+) -> Result<Option<(PathBuf, Settings)>> {
+    let Some(config) = Config::load(root)? else {
+        if let Some(name) = profile {
+            return Err(DistilError::invalid_config_at(
+                root,
+                format!("Profile '{name}' requested but no aid.toml or .aidrc found"),
+            ));
+        }
+        if let Some(preset) = preset {
+            preset.apply(options);
+        }
+        return Ok(None);
+    };
+
+    let mut settings = config.settings(profile)?;
+    if let Some(preset) = preset {
+        settings.preset = Some(preset.to_string());
+    }
+    settings.apply(options, &config.path)?;
+    Ok(Some((config.path, settings)))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::test_filter::TestMode;
+    use crate::type_merge::MergeMode;
+
+    const CONFIG: &str = r#"
+[defaults]
+format = "md"
+exclude = ["vendor/**"]
+tests = "0"
+no-ignore = true
+
+[defaults.languages]
+".pyi" = "python"
+
+[profiles.review]
+private = true
+protected = true
+implementation = true
+merge-types = "all"
+
+[profiles.api]
+visibility = ["public", "protected-internal"]
+with = ["async"]
+"#;
+
+    #[test]
+    fn test_profile_layers_on_defaults() {
+        let config = Config::parse(CONFIG, Path::new("aid.toml")).unwrap();
+        let settings = config.settings(Some("review")).unwrap();
+        assert_eq!(settings.format.as_deref(), Some("md"));
+        assert_eq!(settings.private, Some(true));
+
+        let mut options = ProcessOptions::default();
+        settings.apply(&mut options, &config.path).unwrap();
+        assert!(options.include_private && options.include_implementation);
+        assert_eq!(options.tests, TestMode::Exclude);
+        assert_eq!(options.merge_types, MergeMode::All);
+        assert_eq!(options.exclude_patterns, vec!["vendor/**"]);
+        assert!(!options.use_ignore_files);
+        assert_eq!(
+            options.language_map.get("pyi").map(String::as_str),
+            Some("python")
+        );
+    }
+
+    #[test]
+    fn test_parsed_values() {
+        let config = Config::parse(CONFIG, Path::new("aid.toml")).unwrap();
+        let mut options = ProcessOptions::default();
+        config
+            .settings(Some("api"))
+            .unwrap()
+            .apply(&mut options, &config.path)
+            .unwrap();
+
+        assert_eq!(
+            options.visibility_levels,
+            vec![
+                SourceVisibility::Public,
+                SourceVisibility::ProtectedInternal
+            ]
+        );
+        assert_eq!(options.with_filters.len(), 1);
+        assert!(!options.include_private);
+    }
+
+    #[test]
+    fn test_preset_then_keys() {
+        let text = "[defaults]\ntests = \"1\"\n\n\
+                    [profiles.outline]\npreset = \"outline\"\ndocstrings = true\n";
+        let config = Config::parse(text, Path::new("aid.toml")).unwrap();
+        let mut options = ProcessOptions::default();
+        config
+            .settings(Some("outline"))
+            .unwrap()
+            .apply(&mut options, &config.path)
+            .unwrap();
+
+        assert!(options.collapse_overloads && options.include_protected);
+        assert!(options.include_docstrings);
+        // Keys from the defaults layer still override the profile's preset
+        assert_eq!(options.tests, TestMode::Include);
+    }
+
+    #[test]
+    fn test_preset_override_keeps_keys() {
+        let root = std::env::temp_dir().join(format!("aid-config-{}", std::process::id()));
+        std::fs::create_dir_all(root.join("empty")).unwrap();
+        std::fs::write(
+            root.join("aid.toml"),
+            "[defaults]\npreset = \"full\"\nprivate = false\nimports = false\n",
+        )
+        .unwrap();
+
+        // The given preset replaces the config's, and the config's keys
+        // still refine it
+        let mut options = ProcessOptions::default();
+        apply_config(&root, None, Some(Preset::Review), &mut options).unwrap();
+        assert!(options.include_implementation && options.include_comments);
+        assert_eq!(options.tests, TestMode::Exclude);
+        assert!(!options.include_private && !options.include_imports);
+
+        let mut options = ProcessOptions::default();
+        let applied = apply_config(
+            &root.join("empty"),
+            None,
+            Some(Preset::Outline),
+            &mut options,
+        );
+        assert!(applied.unwrap().is_none());
+        assert!(options.collapse_overloads && !options.include_docstrings);
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+
+    #[test]
+    fn test_fallback_encodings() {
+        let text = "[defaults]\nfallback-encoding = [\"cp1252\", \"latin-1\"]\n";
+        let config = Config::parse(text, Path::new("aid.toml")).unwrap();
+        let mut options = ProcessOptions::default();
+        config
+            .settings(None)
+            .unwrap()
+            .apply(&mut options, &config.path)
+            .unwrap();
+        assert_eq!(
+            options.fallback_encodings,
+            vec![Encoding::Windows1252, Encoding::Latin1]
+        );
+
+        let text = "[defaults]\nfallback-encoding = [\"ebcdic\"]\n";
+        let config = Config::parse(text, Path::new("aid.toml")).unwrap();
+        let err = config
+            .settings(None)
+            .unwrap()
+            .apply(&mut options, &config.path)
+            .unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("invalid fallback encoding 'ebcdic'")
+        );
+    }
+
+    #[test]
+    fn test_unknown_profile() {
+        let config = Config::parse(CONFIG, Path::new("aid.toml")).unwrap();
+        let err = config.settings(Some("full")).unwrap_err();
+        assert_eq!(
+            err.to_string(),
+            "Invalid configuration in aid.toml: Unknown profile 'full' (available: api, review)"
+        );
+    }
+
+    #[test]
+    fn test_errors_point_at_source() {
+        let err = Config::parse("[defaults]\nprivat = true\n", Path::new(".aidrc")).unwrap_err();
+        let span = err.span().unwrap();
+        assert_eq!((span.line, span.column), (2, 1));
+        assert!(err.snippet().unwrap().starts_with("2 | privat = true"));
+
+        let mut options = ProcessOptions::default();
+        let settings = Config::parse("[defaults]\ntests = \"sometimes\"\n", Path::new("aid.toml"))
+            .unwrap()
+            .defaults;
+        assert!(settings.apply(&mut options, Path::new("aid.toml")).is_err());
+    }
+
+    #[test]
+    fn test_empty_aidrc_is_valid() {
+        let config = Config::parse("", Path::new(".aidrc")).unwrap();
+        assert_eq!(config.settings(None).unwrap(), Settings::default());
+    }
+}
@@ -0,0 +1,200 @@
+//! Declaration filters on modifiers and metadata
+//!
+//! Lets callers select declarations by language facts the processors record,
+//! e.g. `--with=suspend` for Kotlin coroutines or `--without=abi=C` to hide
+//! FFI surface. A filter is either a modifier keyword, a bare metadata key
+//! (present with any value) or a `key=value` metadata pair.
+
+use crate::ir::{Modifier, Node};
+use std::collections::BTreeMap;
+use std::fmt;
+use std::str::FromStr;
+
+/// A single modifier or metadata condition on a declaration
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum DeclFilter {
+    /// Declaration carries this modifier
+    Modifier(Modifier),
+    /// Declaration has this metadata key, optionally with an exact value
+    Metadata { key: String, value: Option<String> },
+}
+
+impl DeclFilter {
+    /// Check if a node satisfies this filter
+    ///
+    /// Nodes that aren't declarations never match.
+    #[must_use]
+    pub fn matches(&self, node: &Node) -> bool {
+        match self {
+            Self::Modifier(modifier) => modifiers(node).contains(modifier),
+            Self::Metadata { key, value } => metadata(node)
+                .and_then(|metadata| metadata.get(key))
+                .is_some_and(|actual| value.as_ref().is_none_or(|expected| actual == expected)),
+        }
+    }
+
+    /// Check if a node satisfies every filter in the list
+    #[must_use]
+    pub fn all_match(filters: &[Self], node: &Node) -> bool {
+        filters.iter().all(|filter| filter.matches(node))
+    }
+}
+
+impl FromStr for DeclFilter {
+    type Err = String;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        let s = s.trim();
+        if let Some((key, value)) = s.split_once('=') {
+            let key = key.trim();
+            if key.is_empty() {
+                return Err(format!("invalid filter '{s}' (missing metadata key)"));
+            }
+            return Ok(Self::Metadata {
+                key: key.to_string(),
+                value: Some(value.trim().to_string()),
+            });
+        }
+
+        if s.is_empty() {
+            return Err("empty filter".to_string());
+        }
+        Ok(s.parse::<Modifier>().map_or_else(
+            |_| Self::Metadata {
+                key: s.to_string(),
+                value: None,
+            },
+            Self::Modifier,
+        ))
+    }
+}
+
+impl fmt::Display for DeclFilter {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Modifier(modifier) => write!(f, "{modifier}"),
+            Self::Metadata { key, value: None } => write!(f, "{key}"),
+            Self::Metadata {
+                key,
+                value: Some(value),
+            } => write!(f, "{key}={value}"),
+        }
+    }
+}
+
+/// Modifiers of a declaration (empty for kinds without modifiers)
+fn modifiers(node: &Node) -> &[Modifier] {
+    match node {
+        Node::Class(c) => &c.modifiers,
+        Node::Function(f) => &f.modifiers,
+        Node::Field(f) => &f.modifiers,
+        Node::Property(p) => &p.modifiers,
+        Node::Variable(v) => &v.modifiers,
+        _ => &[],
+    }
+}
+
+/// Metadata map of a declaration
+fn metadata(node: &Node) -> Option<&BTreeMap<String, String>> {
+    match node {
+        Node::Module(m) => Some(&m.metadata),
+        Node::Class(c) => Some(&c.metadata),
+        Node::Interface(i) => Some(&i.metadata),
+        Node::Struct(s) => Some(&s.metadata),
+        Node::Enum(e) => Some(&e.metadata),
+        Node::EnumVariant(v) => Some(&v.metadata),
+        Node::TypeAlias(t) => Some(&t.metadata),
+        Node::Function(f) => Some(&f.metadata),
+        Node::Field(f) => Some(&f.metadata),
+        Node::Property(p) => Some(&p.metadata),
+        Node::Variable(v) => Some(&v.metadata),
+        Node::Macro(m) => Some(&m.metadata),
+        _ => None,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ir::{Function, Import, Visibility};
+
+    fn function(modifiers: Vec<Modifier>, metadata: &[(&str, &str)]) -> Node {
+        Node::Function(Function {
+            name: "f".to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers,
+            decorators: vec![],
+            type_params: vec![],
+            parameters: vec![],
+            return_type: None,
+            implementation: None,
+            line_start: 1,
+            line_end: 1,
+            deprecated: None,
+            metadata: metadata
+                .iter()
+                .map(|(k, v)| ((*k).to_string(), (*v).to_string()))
+                .collect(),
+        })
+    }
+
+    #[test]
+    fn test_filter_parsing() {
+        assert_eq!(
+            "suspend".parse::<DeclFilter>().unwrap(),
+            DeclFilter::Modifier(Modifier::Suspend)
+        );
+        assert_eq!(
+            "abi=C".parse::<DeclFilter>().unwrap(),
+            DeclFilter::Metadata {
+                key: "abi".to_string(),
+                value: Some("C".to_string()),
+            }
+        );
+        assert_eq!(
+            "throws".parse::<DeclFilter>().unwrap(),
+            DeclFilter::Modifier(Modifier::Throws)
+        );
+        assert_eq!(
+            "objc".parse::<DeclFilter>().unwrap(),
+            DeclFilter::Metadata {
+                key: "objc".to_string(),
+                value: None,
+            }
+        );
+        assert!("=C".parse::<DeclFilter>().is_err());
+        assert!("".parse::<DeclFilter>().is_err());
+        assert_eq!("abi=C".parse::<DeclFilter>().unwrap().to_string(), "abi=C");
+    }
+
+    #[test]
+    fn test_filter_matching() {
+        let ffi = function(vec![Modifier::Unsafe, Modifier::Extern], &[("abi", "C")]);
+        let plain = function(vec![], &[]);
+
+        let unsafe_filter = DeclFilter::Modifier(Modifier::Unsafe);
+        assert!(unsafe_filter.matches(&ffi));
+        assert!(!unsafe_filter.matches(&plain));
+
+        let abi: DeclFilter = "abi".parse().unwrap();
+        let abi_c: DeclFilter = "abi=C".parse().unwrap();
+        let abi_rust: DeclFilter = "abi=Rust".parse().unwrap();
+        assert!(abi.matches(&ffi));
+        assert!(abi_c.matches(&ffi));
+        assert!(!abi_rust.matches(&ffi));
+        assert!(!abi.matches(&plain));
+
+        assert!(DeclFilter::all_match(&[unsafe_filter.clone(), abi_c], &ffi));
+        assert!(!DeclFilter::all_match(&[unsafe_filter, abi_rust], &ffi));
+
+        let import = Node::Import(Import {
+            import_type: "import".to_string(),
+            module: "os".to_string(),
+            symbols: vec![],
+            is_type: false,
+            line: None,
+        });
+        assert!(!abi.matches(&import));
+    }
+}
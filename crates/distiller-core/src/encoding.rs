@@ -0,0 +1,345 @@
+//! Source text decoding
+//!
+//! Source files are expected to be UTF-8, but legacy trees hold
+//! Windows-1252, Latin-1 and UTF-16 files. [`decode`] settles on an
+//! encoding in this order:
+//!
+//! 1. A byte order mark: UTF-8, UTF-16LE or UTF-16BE (the BOM is dropped)
+//! 2. Valid UTF-8
+//! 3. Each fallback encoding in turn (`ProcessOptions::fallback_encodings`)
+//! 4. UTF-8 with invalid bytes replaced by U+FFFD
+//!
+//! Only the first two are silent: [`decode_source`] logs the others, so an
+//! odd file is explained instead of failing the run. Binary files
+//! ([`is_binary`]) are skipped by directory discovery and rejected when
+//! named explicitly.
+
+use crate::error::{DistilError, Result};
+use std::fmt;
+use std::io::Read;
+use std::path::Path;
+use std::str::FromStr;
+
+/// How much of a file [`is_binary`] looks at
+pub const SNIFF_LEN: usize = 8000;
+
+/// Byte order marks and the encodings they announce
+const BOMS: [(&[u8], Encoding); 3] = [
+    (b"\xEF\xBB\xBF", Encoding::Utf8),
+    (b"\xFF\xFE", Encoding::Utf16Le),
+    (b"\xFE\xFF", Encoding::Utf16Be),
+];
+
+/// Characters for Windows-1252 bytes 0x80-0x9F; `None` is unassigned
+const WINDOWS_1252_HIGH: [Option<char>; 32] = [
+    Some('\u{20AC}'),
+    None,
+    Some('\u{201A}'),
+    Some('\u{0192}'),
+    Some('\u{201E}'),
+    Some('\u{2026}'),
+    Some('\u{2020}'),
+    Some('\u{2021}'),
+    Some('\u{02C6}'),
+    Some('\u{2030}'),
+    Some('\u{0160}'),
+    Some('\u{2039}'),
+    Some('\u{0152}'),
+    None,
+    Some('\u{017D}'),
+    None,
+    None,
+    Some('\u{2018}'),
+    Some('\u{2019}'),
+    Some('\u{201C}'),
+    Some('\u{201D}'),
+    Some('\u{2022}'),
+    Some('\u{2013}'),
+    Some('\u{2014}'),
+    Some('\u{02DC}'),
+    Some('\u{2122}'),
+    Some('\u{0161}'),
+    Some('\u{203A}'),
+    Some('\u{0153}'),
+    None,
+    Some('\u{017E}'),
+    Some('\u{0178}'),
+];
+
+/// A text encoding of source files
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Encoding {
+    Utf8,
+    Utf16Le,
+    Utf16Be,
+    /// ISO-8859-1: every byte is the code point of the same value
+    Latin1,
+    /// Latin-1 with printable characters in 0x80-0x9F, five of which are
+    /// unassigned
+    Windows1252,
+}
+
+impl Encoding {
+    /// Encoding name
+    #[must_use]
+    pub fn name(self) -> &'static str {
+        match self {
+            Self::Utf8 => "utf-8",
+            Self::Utf16Le => "utf-16le",
+            Self::Utf16Be => "utf-16be",
+            Self::Latin1 => "latin-1",
+            Self::Windows1252 => "windows-1252",
+        }
+    }
+
+    /// Decode `bytes`, or `None` if they aren't valid in this encoding
+    #[must_use]
+    pub fn decode(self, bytes: &[u8]) -> Option<String> {
+        match self {
+            Self::Utf8 => std::str::from_utf8(bytes).ok().map(str::to_owned),
+            Self::Utf16Le | Self::Utf16Be => {
+                if !bytes.len().is_multiple_of(2) {
+                    return None;
+                }
+                char::decode_utf16(self.units(bytes))
+                    .collect::<std::result::Result<_, _>>()
+                    .ok()
+            }
+            Self::Latin1 => Some(bytes.iter().copied().map(char::from).collect()),
+            Self::Windows1252 => bytes.iter().copied().map(windows_1252).collect(),
+        }
+    }
+
+    /// Decode `bytes`, replacing what isn't valid with U+FFFD
+    #[must_use]
+    pub fn decode_lossy(self, bytes: &[u8]) -> String {
+        match self {
+            Self::Utf8 => String::from_utf8_lossy(bytes).into_owned(),
+            Self::Utf16Le | Self::Utf16Be => {
+                let mut text: String = char::decode_utf16(self.units(bytes))
+                    .map(|c| c.unwrap_or(char::REPLACEMENT_CHARACTER))
+                    .collect();
+                if !bytes.len().is_multiple_of(2) {
+                    text.push(char::REPLACEMENT_CHARACTER);
+                }
+                text
+            }
+            Self::Latin1 => bytes.iter().copied().map(char::from).collect(),
+            Self::Windows1252 => bytes
+                .iter()
+                .map(|&byte| windows_1252(byte).unwrap_or(char::REPLACEMENT_CHARACTER))
+                .collect(),
+        }
+    }
+
+    /// UTF-16 code units of `bytes`, ignoring a trailing odd byte
+    fn units(self, bytes: &[u8]) -> impl Iterator<Item = u16> + '_ {
+        bytes.chunks_exact(2).map(move |pair| {
+            let pair = [pair[0], pair[1]];
+            if self == Self::Utf16Le {
+                u16::from_le_bytes(pair)
+            } else {
+                u16::from_be_bytes(pair)
+            }
+        })
+    }
+}
+
+/// Parses the encodings usable as fallbacks; UTF-8 and UTF-16 are always
+/// detected
+impl FromStr for Encoding {
+    type Err = String;
+
+    fn from_str(s: &str) -> std::result::Result<Self, Self::Err> {
+        match s.trim().to_ascii_lowercase().as_str() {
+            "windows-1252" | "cp1252" => Ok(Self::Windows1252),
+            "latin-1" | "latin1" | "iso-8859-1" => Ok(Self::Latin1),
+            _ => Err(format!(
+                "invalid fallback encoding '{s}' (expected windows-1252 or latin-1)"
+            )),
+        }
+    }
+}
+
+impl fmt::Display for Encoding {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.name())
+    }
+}
+
+fn windows_1252(byte: u8) -> Option<char> {
+    match byte {
+        0x80..=0x9F => WINDOWS_1252_HIGH[usize::from(byte - 0x80)],
+        _ => Some(char::from(byte)),
+    }
+}
+
+/// Decoded text and how it was decoded
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Decoded {
+    pub text: String,
+    pub encoding: Encoding,
+    /// Invalid bytes were replaced with U+FFFD
+    pub lossy: bool,
+}
+
+/// Decode source bytes, trying `fallbacks` in order when they aren't UTF-8
+///
+/// Never fails: bytes that no encoding accepts are decoded lossily.
+#[must_use]
+pub fn decode(bytes: &[u8], fallbacks: &[Encoding]) -> Decoded {
+    if let Some((bom, encoding)) = BOMS.iter().find(|(bom, _)| bytes.starts_with(bom)) {
+        let rest = &bytes[bom.len()..];
+        return match encoding.decode(rest) {
+            Some(text) => Decoded {
+                text,
+                encoding: *encoding,
+                lossy: false,
+            },
+            None => Decoded {
+                text: encoding.decode_lossy(rest),
+                encoding: *encoding,
+                lossy: true,
+            },
+        };
+    }
+
+    for encoding in std::iter::once(Encoding::Utf8).chain(fallbacks.iter().copied()) {
+        if let Some(text) = encoding.decode(bytes) {
+            return Decoded {
+                text,
+                encoding,
+                lossy: false,
+            };
+        }
+    }
+    Decoded {
+        text: Encoding::Utf8.decode_lossy(bytes),
+        encoding: Encoding::Utf8,
+        lossy: true,
+    }
+}
+
+/// Decode the source of `path` as by [`decode`], logging a fallback or
+/// lossy decoding
+#[must_use]
+pub fn decode_source(bytes: &[u8], path: &Path, fallbacks: &[Encoding]) -> String {
+    let decoded = decode(bytes, fallbacks);
+    if decoded.lossy {
+        log::warn!(
+            "{}: invalid {} replaced with U+FFFD",
+            path.display(),
+            decoded.encoding
+        );
+    } else if fallbacks.contains(&decoded.encoding) {
+        log::info!(
+            "{}: not UTF-8, decoded as {}",
+            path.display(),
+            decoded.encoding
+        );
+    }
+    decoded.text
+}
+
+/// Read and decode the source file at `path`
+///
+/// # Errors
+///
+/// Returns an error if the file can't be read, or
+/// [`DistilError::BinaryFile`] if it is binary.
+pub fn read_source(path: &Path, fallbacks: &[Encoding]) -> Result<String> {
+    let bytes = std::fs::read(path).map_err(|e| DistilError::io(path, e))?;
+    if is_binary(&bytes) {
+        return Err(DistilError::BinaryFile {
+            path: path.to_path_buf(),
+        });
+    }
+    Ok(decode_source(&bytes, path, fallbacks))
+}
+
+/// Whether `bytes`, the start of a file, look like binary data
+///
+/// Text has no NUL bytes in the first [`SNIFF_LEN`] bytes, except UTF-16,
+/// which is recognized by its BOM.
+#[must_use]
+pub fn is_binary(bytes: &[u8]) -> bool {
+    if BOMS[1..].iter().any(|(bom, _)| bytes.starts_with(bom)) {
+        return false;
+    }
+    bytes[..bytes.len().min(SNIFF_LEN)].contains(&0)
+}
+
+/// Whether the file at `path` looks binary; unreadable files don't, so
+/// reading them reports the error
+#[must_use]
+pub fn is_binary_file(path: &Path) -> bool {
+    let Ok(file) = std::fs::File::open(path) else {
+        return false;
+    };
+    let mut head = Vec::with_capacity(SNIFF_LEN);
+    file.take(SNIFF_LEN as u64).read_to_end(&mut head).is_ok() && is_binary(&head)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_bom_and_utf8() {
+        let decoded = decode(b"\xEF\xBB\xBFdef f(): pass", &[]);
+        assert_eq!(decoded.text, "def f(): pass");
+        assert_eq!(decoded.encoding, Encoding::Utf8);
+
+        // "ä = 1" in UTF-16 with a BOM
+        let le = decode(b"\xFF\xFE\xE4\x00 \x00=\x00 \x001\x00", &[]);
+        assert_eq!(
+            (le.text.as_str(), le.encoding),
+            ("ä = 1", Encoding::Utf16Le)
+        );
+        let be = decode(b"\xFE\xFF\x00\xE4", &[]);
+        assert_eq!((be.text.as_str(), be.encoding), ("ä", Encoding::Utf16Be));
+
+        let odd = decode(b"\xFF\xFEa\x00b", &[]);
+        assert!(odd.lossy);
+        assert_eq!(odd.text, "a\u{FFFD}");
+
+        let plain = decode("naïve".as_bytes(), &[Encoding::Latin1]);
+        assert_eq!(plain.encoding, Encoding::Utf8);
+        assert_eq!(plain.text, "naïve");
+    }
+
+    #[test]
+    fn test_fallbacks() {
+        // "// “Grüße”" in Windows-1252
+        let legacy = b"// \x93Gr\xFC\xDFe\x94";
+        let decoded = decode(legacy, &[Encoding::Windows1252]);
+        assert_eq!(decoded.encoding, Encoding::Windows1252);
+        assert_eq!(decoded.text, "// \u{201C}Grüße\u{201D}");
+
+        // 0x81 is unassigned in Windows-1252, so Latin-1 takes it
+        let unassigned = b"x\x81";
+        let fallbacks = [Encoding::Windows1252, Encoding::Latin1];
+        let decoded = decode(unassigned, &fallbacks);
+        assert_eq!(decoded.encoding, Encoding::Latin1);
+        assert_eq!(decoded.text, "x\u{81}");
+
+        let lossy = decode(unassigned, &[Encoding::Windows1252]);
+        assert!(lossy.lossy);
+        assert_eq!(lossy.text, "x\u{FFFD}");
+
+        assert_eq!("CP1252".parse(), Ok(Encoding::Windows1252));
+        assert_eq!("iso-8859-1".parse(), Ok(Encoding::Latin1));
+        assert!("utf-16le".parse::<Encoding>().is_err());
+    }
+
+    #[test]
+    fn test_binary() {
+        assert!(is_binary(b"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"));
+        assert!(!is_binary(b"fn main() {}\n"));
+        assert!(!is_binary(b"\xFF\xFEa\x00"), "UTF-16 text has NULs");
+
+        let mut late = vec![b'a'; SNIFF_LEN];
+        late.push(0);
+        assert!(!is_binary(&late), "only the start of a file is checked");
+    }
+}
@@ -1,8 +1,15 @@
 //! Error types for AI Distiller
 //!
 //! Uses `thiserror` for ergonomic error handling with proper context.
+//!
+//! Every error names the path it concerns (when there is one) and has a
+//! stable [`ErrorCode`]. [`DistilError::diagnostic`] turns an error into a
+//! serializable [`Diagnostic`] so tools can react to the code instead of
+//! parsing the message.
 
-use std::path::PathBuf;
+use serde::Serialize;
+use std::fmt::Write as _;
+use std::path::{Path, PathBuf};
 
 /// Result type alias for distiller operations
 pub type Result<T> = std::result::Result<T, DistilError>;
@@ -11,54 +18,420 @@ pub type Result<T> = std::result::Result<T, DistilError>;
 #[derive(thiserror::Error, Debug)]
 pub enum DistilError {
     /// I/O errors (file reading, writing, etc.)
-    #[error("I/O error: {0}")]
-    Io(#[from] std::io::Error),
+    #[error("I/O error in {}: {source}", path.display())]
+    Io {
+        path: PathBuf,
+        source: std::io::Error,
+    },
 
     /// Unsupported language for a given file
-    #[error("Unsupported language for {path}: {lang}")]
-    UnsupportedLanguage { path: String, lang: String },
+    #[error("Unsupported language for {}: {lang}", path.display())]
+    UnsupportedLanguage { path: PathBuf, lang: String },
 
     /// Parse error during tree-sitter processing
-    #[error("Parse error in {path}: {message}")]
-    Parse { path: String, message: String },
+    #[error("Parse error in {}{}: {message}", path.display(), position(span.as_ref()))]
+    Parse {
+        path: PathBuf,
+        message: String,
+        /// Where in the source the error was found
+        span: Option<SourceSpan>,
+        /// The source lines around `span`, rendered for display
+        snippet: Option<String>,
+    },
 
     /// Tree-sitter specific errors
-    #[error("Tree-sitter error: {0}")]
-    TreeSitter(String),
+    #[error("Tree-sitter error{}: {message}", location(path.as_deref()))]
+    TreeSitter {
+        path: Option<PathBuf>,
+        message: String,
+    },
 
     /// Invalid configuration or options
-    #[error("Invalid configuration: {0}")]
-    InvalidConfig(String),
+    #[error("Invalid configuration{}: {message}", location(path.as_deref()))]
+    InvalidConfig {
+        path: Option<PathBuf>,
+        message: String,
+    },
 
     /// File not found
-    #[error("File not found: {0}")]
-    FileNotFound(PathBuf),
+    #[error("File not found: {}", path.display())]
+    FileNotFound { path: PathBuf },
+
+    /// A file named explicitly holds binary data, not source
+    #[error("Binary file, not source: {}", path.display())]
+    BinaryFile { path: PathBuf },
 
     /// Directory traversal error
-    #[error("Directory traversal error: {0}")]
-    WalkDir(String),
+    #[error("Directory traversal error in {}: {message}", path.display())]
+    WalkDir { path: PathBuf, message: String },
 
     /// Serialization/deserialization errors
-    #[error("Serialization error: {0}")]
-    Serialization(#[from] serde_json::Error),
+    #[error("Serialization error{}: {source}", location(path.as_deref()))]
+    Serialization {
+        path: Option<PathBuf>,
+        source: serde_json::Error,
+    },
+
+    /// Formatting the output failed
+    #[error("Formatting error{}: {message}", location(path.as_deref()))]
+    Format {
+        path: Option<PathBuf>,
+        message: String,
+    },
+}
+
+/// Stable, machine-readable error codes
+///
+/// The serialized names never change between releases; match on these
+/// rather than on error messages.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ErrorCode {
+    Io,
+    UnsupportedLanguage,
+    Parse,
+    TreeSitter,
+    InvalidConfig,
+    FileNotFound,
+    BinaryFile,
+    WalkDir,
+    Serialization,
+    Format,
+}
+
+impl ErrorCode {
+    /// The serialized name of the code
+    #[must_use]
+    pub const fn as_str(self) -> &'static str {
+        match self {
+            Self::Io => "io",
+            Self::UnsupportedLanguage => "unsupported_language",
+            Self::Parse => "parse",
+            Self::TreeSitter => "tree_sitter",
+            Self::InvalidConfig => "invalid_config",
+            Self::FileNotFound => "file_not_found",
+            Self::BinaryFile => "binary_file",
+            Self::WalkDir => "walk_dir",
+            Self::Serialization => "serialization",
+            Self::Format => "format",
+        }
+    }
+}
+
+impl std::fmt::Display for ErrorCode {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.write_str(self.as_str())
+    }
+}
+
+/// A region of source text, 1-based and inclusive of both ends
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+pub struct SourceSpan {
+    pub line: usize,
+    pub column: usize,
+    pub end_line: usize,
+    pub end_column: usize,
+}
+
+impl SourceSpan {
+    /// A span covering a single position
+    #[must_use]
+    pub const fn point(line: usize, column: usize) -> Self {
+        Self {
+            line,
+            column,
+            end_line: line,
+            end_column: column,
+        }
+    }
+
+    /// The span covering bytes `start..end` of `source`
+    ///
+    /// Columns count characters, not bytes. Offsets past the end of the
+    /// source are clamped to it.
+    #[must_use]
+    pub fn from_offsets(source: &str, start: usize, end: usize) -> Self {
+        let (line, column) = line_column(source, start);
+        let (end_line, end_column) = line_column(source, end.max(start + 1) - 1);
+        Self {
+            line,
+            column,
+            end_line,
+            end_column,
+        }
+    }
+
+    /// Render the spanned lines of `source` with a gutter and carets
+    /// under the span
+    ///
+    /// ```text
+    ///   3 | def broken(:
+    ///     |            ^
+    /// ```
+    #[must_use]
+    pub fn render(&self, source: &str) -> String {
+        /// Lines shown for spans covering more of the file
+        const MAX_LINES: usize = 3;
+
+        let last = self.end_line.min(self.line + MAX_LINES - 1);
+        let width = last.to_string().len();
+        let mut out = String::new();
+
+        for (number, text) in source
+            .lines()
+            .enumerate()
+            .map(|(i, text)| (i + 1, text))
+            .skip(self.line.saturating_sub(1))
+            .take_while(|(number, _)| *number <= last)
+        {
+            writeln!(out, "{number:>width$} | {text}").unwrap();
+
+            let length = text.chars().count();
+            let start = if number == self.line { self.column } else { 1 };
+            let end = if number == self.end_line {
+                self.end_column
+            } else {
+                length
+            };
+            let carets = end.min(length).saturating_sub(start) + 1;
+            writeln!(
+                out,
+                "{:>width$} | {}{}",
+                "",
+                " ".repeat(start.saturating_sub(1)),
+                "^".repeat(carets)
+            )
+            .unwrap();
+        }
+
+        out.truncate(out.trim_end().len());
+        out
+    }
+}
+
+/// 1-based line and column of byte `offset` in `source`
+fn line_column(source: &str, offset: usize) -> (usize, usize) {
+    let mut offset = offset.min(source.len());
+    while !source.is_char_boundary(offset) {
+        offset -= 1;
+    }
+    let before = &source[..offset];
+    let line = before.matches('\n').count() + 1;
+    let line_start = before.rfind('\n').map_or(0, |i| i + 1);
+    (line, before[line_start..].chars().count() + 1)
+}
+
+/// `:line:column` for messages, or nothing without a span
+fn position(span: Option<&SourceSpan>) -> String {
+    span.map(|s| format!(":{}:{}", s.line, s.column))
+        .unwrap_or_default()
+}
+
+/// ` in <path>` for messages, or nothing without a path
+fn location(path: Option<&Path>) -> String {
+    path.map(|p| format!(" in {}", p.display()))
+        .unwrap_or_default()
+}
+
+/// Serializable description of an error
+///
+/// This is what the CLI prints with `--error-format json` and what the MCP
+/// server returns as JSON-RPC error data.
+#[derive(Debug, Clone, Serialize)]
+pub struct Diagnostic {
+    pub code: ErrorCode,
+    pub message: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub path: Option<PathBuf>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub span: Option<SourceSpan>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub snippet: Option<String>,
+}
+
+impl Diagnostic {
+    /// Render as a single-line JSON object
+    ///
+    /// # Panics
+    ///
+    /// Never in practice: every field serializes to plain JSON.
+    #[must_use]
+    pub fn to_json(&self) -> String {
+        serde_json::to_string(self).expect("diagnostics always serialize")
+    }
 }
 
 impl DistilError {
     /// Create a parse error with context
-    pub fn parse_error(path: impl Into<String>, message: impl Into<String>) -> Self {
+    pub fn parse_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
         Self::Parse {
             path: path.into(),
             message: message.into(),
+            span: None,
+            snippet: None,
+        }
+    }
+
+    /// Create a parse error pointing at `span` in `source`
+    pub fn parse_error_at(
+        path: impl Into<PathBuf>,
+        message: impl Into<String>,
+        span: SourceSpan,
+        source: &str,
+    ) -> Self {
+        Self::Parse {
+            path: path.into(),
+            message: message.into(),
+            span: Some(span),
+            snippet: Some(span.render(source)),
         }
     }
 
     /// Create an unsupported language error
-    pub fn unsupported_language(path: impl Into<String>, lang: impl Into<String>) -> Self {
+    pub fn unsupported_language(path: impl Into<PathBuf>, lang: impl Into<String>) -> Self {
         Self::UnsupportedLanguage {
             path: path.into(),
             lang: lang.into(),
         }
     }
+
+    /// Create an I/O error for `path`
+    pub fn io(path: impl Into<PathBuf>, source: std::io::Error) -> Self {
+        Self::Io {
+            path: path.into(),
+            source,
+        }
+    }
+
+    /// Create a tree-sitter error not yet tied to a file
+    pub fn tree_sitter(message: impl Into<String>) -> Self {
+        Self::TreeSitter {
+            path: None,
+            message: message.into(),
+        }
+    }
+
+    /// Create a configuration error not tied to a file
+    pub fn invalid_config(message: impl Into<String>) -> Self {
+        Self::InvalidConfig {
+            path: None,
+            message: message.into(),
+        }
+    }
+
+    /// Create a configuration error about `path`
+    pub fn invalid_config_at(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::InvalidConfig {
+            path: Some(path.into()),
+            message: message.into(),
+        }
+    }
+
+    /// Create a formatting error
+    pub fn format_error(message: impl Into<String>) -> Self {
+        Self::Format {
+            path: None,
+            message: message.into(),
+        }
+    }
+
+    /// Fill in the path on errors raised before the file was known
+    ///
+    /// Errors that already name a path keep it.
+    #[must_use]
+    pub fn with_path(mut self, file: &Path) -> Self {
+        match &mut self {
+            Self::TreeSitter { path, .. }
+            | Self::InvalidConfig { path, .. }
+            | Self::Serialization { path, .. }
+            | Self::Format { path, .. } => {
+                path.get_or_insert_with(|| file.to_path_buf());
+            }
+            Self::Io { .. }
+            | Self::UnsupportedLanguage { .. }
+            | Self::Parse { .. }
+            | Self::FileNotFound { .. }
+            | Self::BinaryFile { .. }
+            | Self::WalkDir { .. } => {}
+        }
+        self
+    }
+
+    /// Stable code identifying the kind of error
+    #[must_use]
+    pub const fn code(&self) -> ErrorCode {
+        match self {
+            Self::Io { .. } => ErrorCode::Io,
+            Self::UnsupportedLanguage { .. } => ErrorCode::UnsupportedLanguage,
+            Self::Parse { .. } => ErrorCode::Parse,
+            Self::TreeSitter { .. } => ErrorCode::TreeSitter,
+            Self::InvalidConfig { .. } => ErrorCode::InvalidConfig,
+            Self::FileNotFound { .. } => ErrorCode::FileNotFound,
+            Self::BinaryFile { .. } => ErrorCode::BinaryFile,
+            Self::WalkDir { .. } => ErrorCode::WalkDir,
+            Self::Serialization { .. } => ErrorCode::Serialization,
+            Self::Format { .. } => ErrorCode::Format,
+        }
+    }
+
+    /// The file or directory the error concerns
+    #[must_use]
+    pub fn path(&self) -> Option<&Path> {
+        match self {
+            Self::Io { path, .. }
+            | Self::UnsupportedLanguage { path, .. }
+            | Self::Parse { path, .. }
+            | Self::FileNotFound { path }
+            | Self::BinaryFile { path }
+            | Self::WalkDir { path, .. } => Some(path),
+            Self::TreeSitter { path, .. }
+            | Self::InvalidConfig { path, .. }
+            | Self::Serialization { path, .. }
+            | Self::Format { path, .. } => path.as_deref(),
+        }
+    }
+
+    /// Where in the source the error was found
+    #[must_use]
+    pub const fn span(&self) -> Option<&SourceSpan> {
+        match self {
+            Self::Parse { span, .. } => span.as_ref(),
+            _ => None,
+        }
+    }
+
+    /// The rendered source snippet around the span
+    #[must_use]
+    pub fn snippet(&self) -> Option<&str> {
+        match self {
+            Self::Parse { snippet, .. } => snippet.as_deref(),
+            _ => None,
+        }
+    }
+
+    /// Structured form of the error
+    #[must_use]
+    pub fn diagnostic(&self) -> Diagnostic {
+        Diagnostic {
+            code: self.code(),
+            message: self.to_string(),
+            path: self.path().map(Path::to_path_buf),
+            span: self.span().copied(),
+            snippet: self.snippet().map(str::to_string),
+        }
+    }
+
+    /// Render as a single-line JSON object (see [`Diagnostic`])
+    #[must_use]
+    pub fn to_json(&self) -> String {
+        self.diagnostic().to_json()
+    }
+}
+
+impl From<serde_json::Error> for DistilError {
+    fn from(source: serde_json::Error) -> Self {
+        Self::Serialization { path: None, source }
+    }
 }
 
 #[cfg(test)]
@@ -75,5 +448,73 @@ mod tests {
     fn test_unsupported_language() {
         let err = DistilError::unsupported_language("test.xyz", "xyz");
         assert_eq!(err.to_string(), "Unsupported language for test.xyz: xyz");
+        assert_eq!(err.code(), ErrorCode::UnsupportedLanguage);
+        assert_eq!(err.path(), Some(Path::new("test.xyz")));
+    }
+
+    #[test]
+    fn test_io_error_keeps_path() {
+        let err = DistilError::io(
+            "src/missing.py",
+            std::io::Error::new(std::io::ErrorKind::NotFound, "gone"),
+        );
+        assert_eq!(err.to_string(), "I/O error in src/missing.py: gone");
+        assert_eq!(err.path(), Some(Path::new("src/missing.py")));
+    }
+
+    #[test]
+    fn test_with_path_fills_missing_path_only() {
+        let err = DistilError::tree_sitter("bad language").with_path(Path::new("a.go"));
+        assert_eq!(err.to_string(), "Tree-sitter error in a.go: bad language");
+
+        let err = DistilError::parse_error("a.go", "oops").with_path(Path::new("b.go"));
+        assert_eq!(err.path(), Some(Path::new("a.go")));
+    }
+
+    #[test]
+    fn test_span_from_offsets() {
+        let source = "def ok():\n    pass\ndef broken(:\n";
+        let start = source.find("(:").unwrap() + 1;
+        let span = SourceSpan::from_offsets(source, start, start + 1);
+        assert_eq!(span, SourceSpan::point(3, 12));
+    }
+
+    #[test]
+    fn test_parse_error_snippet() {
+        let source = "def ok():\n    pass\ndef broken(:\n";
+        let err = DistilError::parse_error_at(
+            "bad.py",
+            "unexpected ':'",
+            SourceSpan::point(3, 12),
+            source,
+        );
+
+        assert_eq!(
+            err.to_string(),
+            "Parse error in bad.py:3:12: unexpected ':'"
+        );
+        assert_eq!(err.snippet(), Some("3 | def broken(:\n  |            ^"));
+    }
+
+    #[test]
+    fn test_json_rendering() {
+        let err = DistilError::parse_error_at(
+            "bad.py",
+            "unexpected ':'",
+            SourceSpan::point(1, 5),
+            "x = :",
+        );
+        let json: serde_json::Value = serde_json::from_str(&err.to_json()).unwrap();
+
+        assert_eq!(json["code"], "parse");
+        assert_eq!(json["path"], "bad.py");
+        assert_eq!(json["span"]["line"], 1);
+        assert_eq!(json["span"]["column"], 5);
+        assert_eq!(json["snippet"], "1 | x = :\n  |     ^");
+
+        let json: serde_json::Value =
+            serde_json::from_str(&DistilError::invalid_config("bad option").to_json()).unwrap();
+        assert_eq!(json["code"], "invalid_config");
+        assert!(json.get("path").is_none());
     }
 }
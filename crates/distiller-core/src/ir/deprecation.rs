@@ -0,0 +1,439 @@
+//! Deprecation markers
+//!
+//! Language processors recognize deprecation from attributes/annotations
+//! (`@Deprecated`, `#[deprecated]`, `[Obsolete]`, `@available(*, deprecated)`,
+//! Python `@deprecated`) and from doc comment tags (`@deprecated`, Go's
+//! `Deprecated:` paragraph). The parsing is shared here so every language
+//! records the same message/replacement/since shape.
+
+use serde::{Deserialize, Serialize};
+
+/// Deprecation details of a declaration
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Deprecation {
+    /// Human-readable deprecation message
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub message: Option<String>,
+    /// Suggested replacement API, if one is named
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub replacement: Option<String>,
+    /// Version the declaration was deprecated in
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub since: Option<String>,
+}
+
+impl Deprecation {
+    /// Detect deprecation from a declaration's attributes and doc comment
+    ///
+    /// Attributes win, but a bare `@Deprecated` borrows the message and
+    /// replacement from a `@deprecated` doc tag when one is present.
+    #[must_use]
+    pub fn detect(decorators: &[String], doc_comment: Option<&str>) -> Option<Self> {
+        let from_doc = doc_comment.and_then(Self::from_doc_comment);
+        match (Self::from_decorators(decorators), from_doc) {
+            (Some(attr), Some(doc)) => Some(Self {
+                message: attr.message.or(doc.message),
+                replacement: attr.replacement.or(doc.replacement),
+                since: attr.since.or(doc.since),
+            }),
+            (attr, doc) => attr.or(doc),
+        }
+    }
+
+    /// Find the first deprecation attribute among decorators
+    #[must_use]
+    pub fn from_decorators(decorators: &[String]) -> Option<Self> {
+        decorators.iter().find_map(|d| Self::from_decorator(d))
+    }
+
+    /// Parse a single attribute/annotation as written in the source
+    ///
+    /// Accepts the wrapped forms processors store (`@Deprecated(...)`,
+    /// `#[deprecated(note = "...")]`, `[Obsolete("...")]`, `[[deprecated]]`)
+    /// as well as unwrapped ones (`deprecated = "..."`).
+    #[must_use]
+    pub fn from_decorator(decorator: &str) -> Option<Self> {
+        let text = unwrap_attribute(decorator);
+        let (name, args) = match text.find(['(', '=']) {
+            Some(pos) if text[pos..].starts_with('(') => {
+                let inner = text[pos + 1..].trim_end();
+                let inner = inner.strip_suffix(')').unwrap_or(inner);
+                (text[..pos].trim(), Some(inner))
+            }
+            // Rust's `deprecated = "message"`
+            Some(pos) => (text[..pos].trim(), Some(text[pos + 1..].trim())),
+            None => (text.trim(), None),
+        };
+
+        let base = name
+            .rsplit(['.', ':', '\\'])
+            .next()
+            .unwrap_or(name)
+            .trim_end_matches("Attribute");
+        let args: Vec<&str> = args.map(split_args).unwrap_or_default();
+
+        let mut deprecation = Self::default();
+        if base.eq_ignore_ascii_case("deprecated") || base.eq_ignore_ascii_case("obsolete") {
+            for arg in &args {
+                match split_named(arg) {
+                    Some(("since", value)) => deprecation.since = Some(unquote(value)),
+                    Some(("note" | "message" | "reason", value)) => {
+                        deprecation.message = Some(unquote(value));
+                    }
+                    Some(("replaceWith" | "replacement", value)) => {
+                        deprecation.replacement = replace_with(value);
+                    }
+                    Some(_) => {}
+                    None if arg.starts_with("ReplaceWith") => {
+                        deprecation.replacement = replace_with(arg);
+                    }
+                    None if is_string_literal(arg) && deprecation.message.is_none() => {
+                        deprecation.message = Some(unquote(arg));
+                    }
+                    None => {}
+                }
+            }
+        } else if base == "available" {
+            // Swift: @available(*, deprecated, message: "...", renamed: "...")
+            // or @available(iOS, deprecated: 13.0, renamed: "...")
+            let mut deprecated = false;
+            for arg in &args {
+                match split_named(arg) {
+                    Some(("deprecated", value)) => {
+                        deprecated = true;
+                        deprecation.since = Some(unquote(value));
+                    }
+                    Some(("message", value)) => deprecation.message = Some(unquote(value)),
+                    Some(("renamed", value)) => deprecation.replacement = Some(unquote(value)),
+                    None if *arg == "deprecated" => deprecated = true,
+                    _ => {}
+                }
+            }
+            if !deprecated {
+                return None;
+            }
+        } else if text.starts_with("__attribute__") && text.contains("deprecated") {
+            // GCC/Clang: __attribute__((deprecated("message")))
+            let start = text.find("deprecated").unwrap_or_default() + "deprecated".len();
+            let rest = text[start..].trim_start();
+            if let Some(inner) = rest.strip_prefix('(') {
+                let end = inner.find(')').unwrap_or(inner.len());
+                let message = inner[..end].trim();
+                if is_string_literal(message) {
+                    deprecation.message = Some(unquote(message));
+                }
+            }
+        } else {
+            return None;
+        }
+
+        if deprecation.replacement.is_none() {
+            deprecation.replacement = deprecation.message.as_deref().and_then(replacement_hint);
+        }
+        Some(deprecation)
+    }
+
+    /// Parse a doc comment for a deprecation tag
+    ///
+    /// Recognizes `@deprecated` (JSDoc, Javadoc, PHPDoc, YARD, Doxygen), Go's
+    /// and Python's `Deprecated:` paragraph and Sphinx's `.. deprecated::
+    /// VERSION` directive. Comment markers are stripped first, so raw comment
+    /// text can be passed in.
+    #[must_use]
+    pub fn from_doc_comment(comment: &str) -> Option<Self> {
+        let lines: Vec<String> = comment.lines().map(strip_comment_marker).collect();
+
+        let (start, first, since) = lines.iter().enumerate().find_map(|(i, line)| {
+            let line = line.trim_start();
+            if let Some(version) = line.strip_prefix(".. deprecated::") {
+                let version = version.trim();
+                let since = (!version.is_empty()).then(|| version.to_string());
+                return Some((i, String::new(), since));
+            }
+            line.strip_prefix("@deprecated")
+                .or_else(|| line.strip_prefix("\\deprecated"))
+                .filter(|rest| rest.is_empty() || rest.starts_with(char::is_whitespace))
+                .or_else(|| line.strip_prefix("Deprecated:"))
+                .map(|rest| (i, rest.trim().to_string(), None))
+        })?;
+
+        // The message continues until a blank line or the next tag
+        let mut parts = vec![first];
+        for line in &lines[start + 1..] {
+            let line = line.trim();
+            if line.is_empty() || line.starts_with('@') {
+                break;
+            }
+            parts.push(line.to_string());
+        }
+        let message = parts
+            .into_iter()
+            .filter(|p| !p.is_empty())
+            .collect::<Vec<_>>()
+            .join(" ");
+
+        let message = (!message.is_empty()).then_some(message);
+        let replacement = message.as_deref().and_then(replacement_hint);
+        Some(Self {
+            message,
+            replacement,
+            since,
+        })
+    }
+}
+
+/// Strip attribute wrappers: `@`, `#[...]`, `[[...]]`, `[...]`
+fn unwrap_attribute(decorator: &str) -> &str {
+    let text = decorator.trim();
+    let text = if let Some(inner) = text.strip_prefix("[[") {
+        inner.strip_suffix("]]").unwrap_or(inner)
+    } else if let Some(inner) = text.strip_prefix("#[").or_else(|| text.strip_prefix('[')) {
+        inner.strip_suffix(']').unwrap_or(inner)
+    } else {
+        text
+    };
+    text.trim().trim_start_matches('@')
+}
+
+/// Split attribute arguments on top-level commas
+fn split_args(args: &str) -> Vec<&str> {
+    let mut parts = Vec::new();
+    let mut depth = 0i32;
+    let mut in_string = false;
+    let mut escaped = false;
+    let mut start = 0;
+
+    for (i, ch) in args.char_indices() {
+        if in_string {
+            match ch {
+                '\\' if !escaped => escaped = true,
+                '"' if !escaped => in_string = false,
+                _ => escaped = false,
+            }
+            continue;
+        }
+        match ch {
+            '"' => in_string = true,
+            '(' | '[' | '{' => depth += 1,
+            ')' | ']' | '}' => depth -= 1,
+            ',' if depth == 0 => {
+                parts.push(args[start..i].trim());
+                start = i + 1;
+            }
+            _ => {}
+        }
+    }
+    parts.push(args[start..].trim());
+    parts.retain(|p| !p.is_empty());
+    parts
+}
+
+/// Split `key = value` / `key: value` arguments
+fn split_named(arg: &str) -> Option<(&str, &str)> {
+    if is_string_literal(arg) {
+        return None;
+    }
+    let pos = arg.find(['=', ':'])?;
+    // `::` is a path separator, not a named argument
+    if arg[pos..].starts_with("::") {
+        return None;
+    }
+    let key = arg[..pos].trim();
+    key.chars()
+        .all(|c| c.is_alphanumeric() || c == '_')
+        .then(|| (key, arg[pos + 1..].trim()))
+}
+
+fn is_string_literal(text: &str) -> bool {
+    let text = text.trim_start_matches('@');
+    text.starts_with('"') || text.starts_with('\'')
+}
+
+/// Remove surrounding quotes and unescape `\"`
+fn unquote(value: &str) -> String {
+    let value = value.trim().trim_start_matches('@');
+    let inner = value
+        .strip_prefix('"')
+        .and_then(|v| v.strip_suffix('"'))
+        .or_else(|| value.strip_prefix('\'').and_then(|v| v.strip_suffix('\'')))
+        .unwrap_or(value);
+    inner.replace("\\\"", "\"")
+}
+
+/// Extract the expression from Kotlin's `ReplaceWith("expr", ...)`
+fn replace_with(value: &str) -> Option<String> {
+    let inner = value.trim().strip_prefix("ReplaceWith")?.trim();
+    let inner = inner.strip_prefix('(')?.strip_suffix(')')?;
+    let first = split_args(inner).into_iter().next()?;
+    let first = split_named(first).map_or(first, |(_, v)| v);
+    Some(unquote(first))
+}
+
+/// Strip the comment syntax from one line of a doc comment
+fn strip_comment_marker(line: &str) -> String {
+    let line = line.trim();
+    let line = line
+        .strip_prefix("/**")
+        .or_else(|| line.strip_prefix("/*!"))
+        .or_else(|| line.strip_prefix("/*"))
+        .or_else(|| line.strip_prefix("///"))
+        .or_else(|| line.strip_prefix("//!"))
+        .or_else(|| line.strip_prefix("//"))
+        .or_else(|| line.strip_prefix("##"))
+        .or_else(|| line.strip_prefix('#'))
+        .or_else(|| line.strip_prefix('*'))
+        .unwrap_or(line);
+    let line = line.strip_suffix("*/").unwrap_or(line);
+    line.trim().to_string()
+}
+
+/// Guess the replacement API from a free-form message
+///
+/// Handles "use X instead", "replaced by X", "renamed to X" and Javadoc's
+/// `{@link X}`.
+fn replacement_hint(message: &str) -> Option<String> {
+    if let Some(start) = message.find("{@link") {
+        let rest = &message[start + "{@link".len()..];
+        let end = rest.find('}')?;
+        return clean_symbol(&rest[..end]);
+    }
+
+    let lower = message.to_ascii_lowercase();
+    for marker in [
+        "use ",
+        "replaced by ",
+        "renamed to ",
+        "superseded by ",
+        "prefer ",
+    ] {
+        let found = lower
+            .match_indices(marker)
+            .find(|(pos, _)| *pos == 0 || !lower.as_bytes()[pos - 1].is_ascii_alphanumeric());
+        if let Some((pos, _)) = found {
+            let rest = &message[pos + marker.len()..];
+            let candidate = rest.split_whitespace().next()?;
+            return clean_symbol(candidate);
+        }
+    }
+    None
+}
+
+/// Trim punctuation/markup around a symbol name taken from prose
+fn clean_symbol(text: &str) -> Option<String> {
+    let symbol = text
+        .trim()
+        .trim_matches(|c: char| matches!(c, '`' | '\'' | '"' | ',' | ';' | '!' | '?'))
+        .trim_end_matches('.')
+        .trim_start_matches('#')
+        .trim_matches('`');
+    let looks_like_symbol = symbol
+        .chars()
+        .next()
+        .is_some_and(|c| c.is_alphabetic() || c == '_' || c == '$');
+    (looks_like_symbol && !matches!(symbol, "the" | "a" | "an" | "it" | "this"))
+        .then(|| symbol.to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_java_and_kotlin_annotations() {
+        let d = Deprecation::from_decorator("@Deprecated").unwrap();
+        assert_eq!(d, Deprecation::default());
+
+        let d =
+            Deprecation::from_decorator("@Deprecated(since = \"9\", forRemoval = true)").unwrap();
+        assert_eq!(d.since.as_deref(), Some("9"));
+
+        let d = Deprecation::from_decorator(
+            "@Deprecated(\"Use fetchAll\", ReplaceWith(\"fetchAll(limit)\"))",
+        )
+        .unwrap();
+        assert_eq!(d.message.as_deref(), Some("Use fetchAll"));
+        assert_eq!(d.replacement.as_deref(), Some("fetchAll(limit)"));
+
+        assert!(Deprecation::from_decorator("@Override").is_none());
+    }
+
+    #[test]
+    fn test_rust_csharp_python_swift_cpp() {
+        let d = Deprecation::from_decorator(
+            "deprecated(since = \"1.2.0\", note = \"use `parse_v2` instead\")",
+        )
+        .unwrap();
+        assert_eq!(d.since.as_deref(), Some("1.2.0"));
+        assert_eq!(d.replacement.as_deref(), Some("parse_v2"));
+
+        let d = Deprecation::from_decorator("deprecated = \"no longer needed\"").unwrap();
+        assert_eq!(d.message.as_deref(), Some("no longer needed"));
+        assert!(d.replacement.is_none());
+
+        let d = Deprecation::from_decorator("[Obsolete(\"Use SaveAsync instead\", true)]").unwrap();
+        assert_eq!(d.replacement.as_deref(), Some("SaveAsync"));
+
+        let d = Deprecation::from_decorator("@warnings.deprecated(\"Use g() instead\")").unwrap();
+        assert_eq!(d.replacement.as_deref(), Some("g()"));
+
+        let d = Deprecation::from_decorator(
+            "@available(*, deprecated, message: \"Old API\", renamed: \"load(from:)\")",
+        )
+        .unwrap();
+        assert_eq!(d.message.as_deref(), Some("Old API"));
+        assert_eq!(d.replacement.as_deref(), Some("load(from:)"));
+        assert!(Deprecation::from_decorator("@available(iOS 13, *)").is_none());
+
+        assert!(Deprecation::from_decorator("[[deprecated(\"use bar\")]]").is_some());
+        assert!(Deprecation::from_decorator("__attribute__((deprecated))").is_some());
+    }
+
+    #[test]
+    fn test_doc_comments() {
+        let d = Deprecation::from_doc_comment(
+            "/**\n * Loads a user.\n * @deprecated Use {@link #loadUser(long)} instead.\n * @param id the id\n */",
+        )
+        .unwrap();
+        assert_eq!(
+            d.message.as_deref(),
+            Some("Use {@link #loadUser(long)} instead.")
+        );
+        assert_eq!(d.replacement.as_deref(), Some("loadUser(long)"));
+
+        let d = Deprecation::from_doc_comment(
+            "// Open opens a file.\n//\n// Deprecated: Use OpenFile instead.",
+        )
+        .unwrap();
+        assert_eq!(d.replacement.as_deref(), Some("OpenFile"));
+
+        let d = Deprecation::from_doc_comment("/** @deprecated */").unwrap();
+        assert!(d.message.is_none());
+
+        assert!(Deprecation::from_doc_comment("/** @deprecatedSince is not a tag */").is_none());
+        assert!(Deprecation::from_doc_comment("// Loads a user").is_none());
+    }
+
+    #[test]
+    fn test_sphinx_directive() {
+        let d = Deprecation::from_doc_comment(
+            "Fetch one row.\n\n    .. deprecated:: 2.1\n       Use fetch_all() instead.\n\n    More text.",
+        )
+        .unwrap();
+        assert_eq!(d.since.as_deref(), Some("2.1"));
+        assert_eq!(d.message.as_deref(), Some("Use fetch_all() instead."));
+        assert_eq!(d.replacement.as_deref(), Some("fetch_all()"));
+
+        let d = Deprecation::from_doc_comment(".. deprecated::").unwrap();
+        assert!(d.since.is_none() && d.message.is_none());
+    }
+
+    #[test]
+    fn test_detect_merges_sources() {
+        let decorators = vec!["@Deprecated".to_string()];
+        let d = Deprecation::detect(&decorators, Some("/** @deprecated use save() */")).unwrap();
+        assert_eq!(d.replacement.as_deref(), Some("save()"));
+
+        assert!(Deprecation::detect(&[], Some("/** Saves */")).is_none());
+        assert!(Deprecation::detect(&[], None).is_none());
+    }
+}
@@ -3,10 +3,12 @@
 //! Language-agnostic representation of code structure.
 //! All language processors convert their ASTs to this unified IR.
 
+mod deprecation;
 mod nodes;
 mod types;
 mod visitor;
 
+pub use deprecation::*;
 pub use nodes::*;
 pub use types::*;
 pub use visitor::*;
@@ -1,7 +1,12 @@
 //! IR node types
 
-use super::types::{ImportedSymbol, Modifier, Parameter, TypeParam, TypeRef, Visibility};
+use super::deprecation::Deprecation;
+use super::types::{
+    Accessor, ImportedSymbol, MacroKind, Modifier, Parameter, SourceVisibility, TypeParam, TypeRef,
+    VariableKind, Visibility,
+};
 use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
 
 /// Root IR node - can be any type
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -10,14 +15,20 @@ pub enum Node {
     File(File),
     Directory(Directory),
     Package(Package),
+    Module(Module),
     Import(Import),
     Class(Class),
     Interface(Interface),
     Struct(Struct),
     Enum(Enum),
+    EnumVariant(EnumVariant),
     TypeAlias(TypeAlias),
     Function(Function),
+    OverloadSet(OverloadSet),
     Field(Field),
+    Property(Property),
+    Variable(Variable),
+    Macro(Macro),
     Comment(Comment),
     RawContent(RawContent),
 }
@@ -46,6 +57,29 @@ pub struct Package {
     pub children: Vec<Node>,
 }
 
+/// Lexical namespace or module block
+///
+/// C++, C# and PHP namespaces, Rust `mod`, TypeScript `namespace` /
+/// `declare module` and Ruby modules. File-level package declarations
+/// use [`Package`] instead.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Module {
+    pub name: String,
+    pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub decorators: Vec<String>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub children: Vec<Node>,
+    pub line_start: usize,
+    pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
+}
+
 /// Import statement
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Import {
@@ -64,6 +98,8 @@ pub struct Import {
 pub struct Class {
     pub name: String,
     pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub modifiers: Vec<Modifier>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
@@ -78,6 +114,10 @@ pub struct Class {
     pub children: Vec<Node>,
     pub line_start: usize,
     pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
 }
 
 /// Interface declaration
@@ -85,6 +125,8 @@ pub struct Class {
 pub struct Interface {
     pub name: String,
     pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub type_params: Vec<TypeParam>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
@@ -93,6 +135,10 @@ pub struct Interface {
     pub children: Vec<Node>,
     pub line_start: usize,
     pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
 }
 
 /// Struct declaration
@@ -100,12 +146,18 @@ pub struct Interface {
 pub struct Struct {
     pub name: String,
     pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub type_params: Vec<TypeParam>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub children: Vec<Node>,
     pub line_start: usize,
     pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
 }
 
 /// Enum declaration
@@ -114,11 +166,46 @@ pub struct Enum {
     pub name: String,
     pub visibility: Visibility,
     #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub decorators: Vec<String>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub type_params: Vec<TypeParam>,
+    #[serde(skip_serializing_if = "Option::is_none")]
     pub enum_type: Option<TypeRef>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub children: Vec<Node>,
     pub line_start: usize,
     pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
+}
+
+/// Enum variant, case or constant
+///
+/// `fields` holds the payload: Rust tuple and struct variants and Swift
+/// associated values (tuple members have an empty name). `value` previews
+/// an explicit discriminant or raw value, and `arguments` holds the
+/// constructor arguments of Java and Kotlin constants. Constant bodies
+/// (Java, Kotlin) go in `children`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct EnumVariant {
+    pub name: String,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub fields: Vec<Parameter>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub value: Option<String>, // one-line preview, see `parser::values`
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub arguments: Vec<String>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub children: Vec<Node>,
+    pub line: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
 }
 
 /// Type alias
@@ -126,10 +213,16 @@ pub struct Enum {
 pub struct TypeAlias {
     pub name: String,
     pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub type_params: Vec<TypeParam>,
     pub alias_type: TypeRef,
     pub line: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
 }
 
 /// Function/method declaration
@@ -137,6 +230,8 @@ pub struct TypeAlias {
 pub struct Function {
     pub name: String,
     pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub modifiers: Vec<Modifier>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
@@ -151,6 +246,26 @@ pub struct Function {
     pub implementation: Option<String>,
     pub line_start: usize,
     pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
+}
+
+/// Overloaded function or method: every signature declared under one name
+///
+/// Built by `parser::overloads::group_overloads` for languages with
+/// overloading. TypeScript implementation signatures and the body behind
+/// Python `@typing.overload` stubs are left out. `count` is the number of
+/// signatures in the source and survives `collapse_overloads`, which keeps
+/// only the first signature.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct OverloadSet {
+    pub name: String,
+    pub signatures: Vec<Function>,
+    pub count: usize,
+    pub line_start: usize,
+    pub line_end: usize,
 }
 
 /// Field/property declaration
@@ -158,6 +273,8 @@ pub struct Function {
 pub struct Field {
     pub name: String,
     pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub modifiers: Vec<Modifier>,
     #[serde(skip_serializing_if = "Option::is_none")]
@@ -165,6 +282,88 @@ pub struct Field {
     #[serde(skip_serializing_if = "Option::is_none")]
     pub default_value: Option<String>,
     pub line: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
+}
+
+/// Property with accessors
+///
+/// Covers C# and Kotlin properties, Swift computed properties, TS `get`/`set`
+/// pairs, Python `@property`, PHP property hooks and Ruby `attr_*`.
+/// `is_computed` marks properties without a backing field.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Property {
+    pub name: String,
+    pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub modifiers: Vec<Modifier>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub property_type: Option<TypeRef>,
+    pub accessors: Vec<Accessor>,
+    #[serde(skip_serializing_if = "std::ops::Not::not", default)]
+    pub is_computed: bool,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub default_value: Option<String>,
+    pub line_start: usize,
+    pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
+}
+
+/// Module-level variable or constant
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Variable {
+    pub name: String,
+    pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
+    pub var_kind: VariableKind,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub modifiers: Vec<Modifier>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub var_type: Option<TypeRef>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub value: Option<String>, // one-line preview, see `parser::values`
+    #[serde(skip_serializing_if = "std::ops::Not::not", default)]
+    pub is_mutable: bool,
+    pub line: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
+}
+
+/// Macro definition
+///
+/// Rust `macro_rules!` and procedural macros, C/C++ `#define`. For
+/// `macro_rules!` the parameters are the matcher of each arm, for
+/// function-like defines the argument names. Object-like defines keep a
+/// one-line preview of their replacement in `value`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Macro {
+    pub name: String,
+    pub visibility: Visibility,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub decorators: Vec<String>,
+    pub macro_kind: MacroKind,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub parameters: Vec<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub value: Option<String>,
+    pub line_start: usize,
+    pub line_end: usize,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated: Option<Deprecation>,
+    #[serde(skip_serializing_if = "BTreeMap::is_empty", default)]
+    pub metadata: BTreeMap<String, String>,
 }
 
 /// Comment
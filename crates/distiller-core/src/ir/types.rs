@@ -1,6 +1,8 @@
 //! Type system for IR nodes
 
 use serde::{Deserialize, Serialize};
+use std::fmt;
+use std::str::FromStr;
 
 /// Visibility level of a code element
 #[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
@@ -22,6 +24,262 @@ impl Default for Visibility {
     }
 }
 
+/// Source-level visibility as written in the language
+///
+/// `Visibility` is the coarse bucket used for filtering; this keeps the
+/// precise level (Swift `open`/`fileprivate`, C# `protected internal`,
+/// Rust `pub(crate)`, Java package-private, ...) so it isn't lost.
+#[derive(Debug, Clone, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum SourceVisibility {
+    /// `public`, Rust `pub`, Go exported names
+    Public,
+    /// Swift `open` - public and overridable outside the module
+    Open,
+    /// `protected`
+    Protected,
+    /// C# `protected internal` - subclasses or the same assembly
+    ProtectedInternal,
+    /// C# `private protected` - subclasses within the same assembly
+    PrivateProtected,
+    /// C#/Swift/Kotlin `internal` - the same assembly/module
+    Internal,
+    /// Java default access, Go unexported names
+    PackagePrivate,
+    /// Rust `pub(crate)`
+    Crate,
+    /// Rust `pub(super)`
+    Super,
+    /// Rust `pub(in path)`
+    Restricted(String),
+    /// Swift `fileprivate`
+    FilePrivate,
+    /// `private`
+    Private,
+}
+
+impl SourceVisibility {
+    /// Coarse visibility bucket used for filtering
+    #[must_use]
+    pub fn coarse(&self) -> Visibility {
+        match self {
+            Self::Public | Self::Open => Visibility::Public,
+            Self::Protected | Self::ProtectedInternal | Self::PrivateProtected => {
+                Visibility::Protected
+            }
+            // Rust has no protected: `pub(super)` and `pub(in path)` are
+            // module-internal like `pub(crate)`
+            Self::Internal
+            | Self::PackagePrivate
+            | Self::Crate
+            | Self::Super
+            | Self::Restricted(_) => Visibility::Internal,
+            Self::FilePrivate | Self::Private => Visibility::Private,
+        }
+    }
+
+    /// Whether this level is one of the four coarse buckets
+    ///
+    /// Filters treat these as matching the whole bucket, so `internal`
+    /// also selects `pub(crate)` and package-private declarations.
+    #[must_use]
+    pub fn is_coarse(&self) -> bool {
+        matches!(
+            self,
+            Self::Public | Self::Protected | Self::Internal | Self::Private
+        )
+    }
+
+    /// Check whether a declaration's visibility is selected by this level
+    #[must_use]
+    pub fn matches(&self, visibility: &SourceVisibility) -> bool {
+        self == visibility || (self.is_coarse() && self.coarse() == visibility.coarse())
+    }
+}
+
+impl From<Visibility> for SourceVisibility {
+    fn from(visibility: Visibility) -> Self {
+        match visibility {
+            Visibility::Public => Self::Public,
+            Visibility::Protected => Self::Protected,
+            Visibility::Internal => Self::Internal,
+            Visibility::Private => Self::Private,
+        }
+    }
+}
+
+impl fmt::Display for SourceVisibility {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Public => f.write_str("public"),
+            Self::Open => f.write_str("open"),
+            Self::Protected => f.write_str("protected"),
+            Self::ProtectedInternal => f.write_str("protected internal"),
+            Self::PrivateProtected => f.write_str("private protected"),
+            Self::Internal => f.write_str("internal"),
+            Self::PackagePrivate => f.write_str("package"),
+            Self::Crate => f.write_str("pub(crate)"),
+            Self::Super => f.write_str("pub(super)"),
+            Self::Restricted(path) => write!(f, "pub(in {path})"),
+            Self::FilePrivate => f.write_str("fileprivate"),
+            Self::Private => f.write_str("private"),
+        }
+    }
+}
+
+impl FromStr for SourceVisibility {
+    type Err = String;
+
+    /// Parse a level name (`protected-internal`, `crate`) or source keyword
+    /// (`protected internal`, `pub(crate)`)
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        if let Some(path) = s
+            .trim()
+            .strip_prefix("pub(in ")
+            .and_then(|rest| rest.strip_suffix(')'))
+        {
+            return Ok(Self::Restricted(path.trim().to_string()));
+        }
+
+        let normalized = s.trim().to_ascii_lowercase().replace(['-', '_'], " ");
+        let normalized = normalized.split_whitespace().collect::<Vec<_>>().join(" ");
+
+        match normalized.as_str() {
+            "public" | "pub" | "exported" => Ok(Self::Public),
+            "open" => Ok(Self::Open),
+            "protected" => Ok(Self::Protected),
+            "protected internal" => Ok(Self::ProtectedInternal),
+            "private protected" => Ok(Self::PrivateProtected),
+            "internal" => Ok(Self::Internal),
+            "package" | "package private" | "unexported" => Ok(Self::PackagePrivate),
+            "crate" | "pub(crate)" => Ok(Self::Crate),
+            "super" | "pub(super)" => Ok(Self::Super),
+            "fileprivate" | "file private" => Ok(Self::FilePrivate),
+            "private" => Ok(Self::Private),
+            _ => Err(format!("unknown visibility level: {s}")),
+        }
+    }
+}
+
+/// How a module-level variable is declared
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum VariableKind {
+    /// Compile-time or immutable constant (`const`, Python `UPPER_CASE`)
+    Const,
+    /// Static storage (`static`, C globals with `static`)
+    Static,
+    /// `let` / `val` bindings; mutability is tracked separately
+    Let,
+    /// `var` bindings and plain assignments
+    Var,
+}
+
+impl VariableKind {
+    /// Keyword used for this kind in output
+    #[must_use]
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::Const => "const",
+            Self::Static => "static",
+            Self::Let => "let",
+            Self::Var => "var",
+        }
+    }
+}
+
+impl fmt::Display for VariableKind {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.as_str())
+    }
+}
+
+/// How a macro is defined
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum MacroKind {
+    /// Rust `macro_rules!`
+    Rules,
+    /// Rust `#[proc_macro_derive]`
+    Derive,
+    /// Rust `#[proc_macro_attribute]`
+    Attribute,
+    /// Rust `#[proc_macro]` and C/C++ `#define NAME(args)`
+    FunctionLike,
+    /// C/C++ `#define NAME value`
+    Object,
+}
+
+impl MacroKind {
+    /// Name used for this kind in output
+    #[must_use]
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::Rules => "rules",
+            Self::Derive => "derive",
+            Self::Attribute => "attribute",
+            Self::FunctionLike => "function_like",
+            Self::Object => "object",
+        }
+    }
+}
+
+impl fmt::Display for MacroKind {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.as_str())
+    }
+}
+
+/// Kind of property accessor
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum AccessorKind {
+    Get,
+    Set,
+    /// C# `init` - settable only during object initialization
+    Init,
+}
+
+impl AccessorKind {
+    /// Keyword used for this accessor in output
+    #[must_use]
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::Get => "get",
+            Self::Set => "set",
+            Self::Init => "init",
+        }
+    }
+}
+
+impl fmt::Display for AccessorKind {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.as_str())
+    }
+}
+
+/// Property accessor; visibility is only set when it differs from the
+/// property's (`public string Name { get; private set; }`)
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Accessor {
+    pub accessor_kind: AccessorKind,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub visibility: Option<Visibility>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_visibility: Option<SourceVisibility>,
+}
+
+impl Accessor {
+    #[must_use]
+    pub fn new(accessor_kind: AccessorKind) -> Self {
+        Self {
+            accessor_kind,
+            visibility: None,
+            source_visibility: None,
+        }
+    }
+}
+
 /// Modifier for functions, classes, fields
 #[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
 #[serde(rename_all = "lowercase")]
@@ -39,9 +297,126 @@ pub enum Modifier {
     Data,
     Sealed,
     Inline,
+    /// Kotlin `suspend` functions
+    Suspend,
+    Unsafe,
+    /// `extern` declarations; the ABI, if any, is in the `abi` metadata
+    Extern,
+    Noexcept,
+    Constexpr,
+    /// C# `partial` types and methods
+    Partial,
+    Operator,
+    Infix,
+    /// Swift `mutating` methods
+    Mutating,
+    Nonisolated,
+    /// Generator functions (`function*`, Python functions that `yield`)
+    Generator,
+    /// Functions declaring thrown errors; Java's list is in the `throws` metadata
+    Throws,
+    Synchronized,
+    Volatile,
+    Transient,
+    Native,
+    Lateinit,
+    Tailrec,
+}
+
+impl Modifier {
+    /// Keyword used for this modifier in output and filters
+    #[must_use]
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            Self::Static => "static",
+            Self::Abstract => "abstract",
+            Self::Final => "final",
+            Self::Async => "async",
+            Self::Virtual => "virtual",
+            Self::Override => "override",
+            Self::Const => "const",
+            Self::Readonly => "readonly",
+            Self::Mutable => "mutable",
+            Self::Event => "event",
+            Self::Data => "data",
+            Self::Sealed => "sealed",
+            Self::Inline => "inline",
+            Self::Suspend => "suspend",
+            Self::Unsafe => "unsafe",
+            Self::Extern => "extern",
+            Self::Noexcept => "noexcept",
+            Self::Constexpr => "constexpr",
+            Self::Partial => "partial",
+            Self::Operator => "operator",
+            Self::Infix => "infix",
+            Self::Mutating => "mutating",
+            Self::Nonisolated => "nonisolated",
+            Self::Generator => "generator",
+            Self::Throws => "throws",
+            Self::Synchronized => "synchronized",
+            Self::Volatile => "volatile",
+            Self::Transient => "transient",
+            Self::Native => "native",
+            Self::Lateinit => "lateinit",
+            Self::Tailrec => "tailrec",
+        }
+    }
+}
+
+impl fmt::Display for Modifier {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.as_str())
+    }
+}
+
+impl FromStr for Modifier {
+    type Err = String;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        match s.trim().to_ascii_lowercase().as_str() {
+            "static" => Ok(Self::Static),
+            "abstract" => Ok(Self::Abstract),
+            "final" => Ok(Self::Final),
+            "async" => Ok(Self::Async),
+            "virtual" => Ok(Self::Virtual),
+            "override" => Ok(Self::Override),
+            "const" => Ok(Self::Const),
+            "readonly" => Ok(Self::Readonly),
+            "mutable" => Ok(Self::Mutable),
+            "event" => Ok(Self::Event),
+            "data" => Ok(Self::Data),
+            "sealed" => Ok(Self::Sealed),
+            "inline" => Ok(Self::Inline),
+            "suspend" => Ok(Self::Suspend),
+            "unsafe" => Ok(Self::Unsafe),
+            "extern" => Ok(Self::Extern),
+            "noexcept" => Ok(Self::Noexcept),
+            "constexpr" => Ok(Self::Constexpr),
+            "partial" => Ok(Self::Partial),
+            "operator" => Ok(Self::Operator),
+            "infix" => Ok(Self::Infix),
+            "mutating" => Ok(Self::Mutating),
+            "nonisolated" => Ok(Self::Nonisolated),
+            "generator" => Ok(Self::Generator),
+            "throws" => Ok(Self::Throws),
+            "synchronized" => Ok(Self::Synchronized),
+            "volatile" => Ok(Self::Volatile),
+            "transient" => Ok(Self::Transient),
+            "native" => Ok(Self::Native),
+            "lateinit" => Ok(Self::Lateinit),
+            "tailrec" => Ok(Self::Tailrec),
+            other => Err(format!("unknown modifier: {other}")),
+        }
+    }
 }
 
 /// Type reference
+///
+/// `name` is the type as written in the source. The remaining fields
+/// describe its structure when a processor parses it (see
+/// `parser::types`): `type_args` holds generic arguments or the element type
+/// of an array, `is_nullable` marks `T?`, `Optional[T]` and `T | null`, and
+/// `shape` covers types that are not a plain or generic name.
 #[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
 pub struct TypeRef {
     pub name: String,
@@ -55,6 +430,11 @@ pub struct TypeRef {
     pub is_array: bool,
     #[serde(skip_serializing_if = "Option::is_none")]
     pub array_dims: Option<usize>,
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub shape: Option<TypeShape>,
+    /// Language-independent classification, filled in by `canonical::canonicalize`
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub canonical: Option<CanonicalType>,
 }
 
 impl TypeRef {
@@ -66,10 +446,77 @@ impl TypeRef {
             is_nullable: false,
             is_array: false,
             array_dims: None,
+            shape: None,
+            canonical: None,
         }
     }
 }
 
+/// Structure of a type that is not a plain or generic name
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(tag = "form", rename_all = "snake_case")]
+pub enum TypeShape {
+    /// `A | B`; `T | null` also sets `is_nullable`
+    Union { members: Vec<TypeRef> },
+    /// TypeScript/Swift `A & B`, Rust `Trait + Send`
+    Intersection { members: Vec<TypeRef> },
+    /// `(x: number) => void`, `fn(i32) -> bool`, `func(string) error`,
+    /// `Callable[[int], str]`
+    Function {
+        params: Vec<TypeRef>,
+        #[serde(skip_serializing_if = "Option::is_none", default)]
+        returns: Option<Box<TypeRef>>,
+    },
+    /// `(i32, String)`, `tuple[int, str]`, TypeScript `[string, number]`
+    Tuple { elements: Vec<TypeRef> },
+    /// Go `*T`, Rust `*const T`, C `T*`
+    Pointer {
+        target: Box<TypeRef>,
+        is_mutable: bool,
+    },
+    /// Rust `&T`/`&mut T`, C++ `T&`
+    Reference {
+        target: Box<TypeRef>,
+        is_mutable: bool,
+    },
+}
+
+/// Category of a type, comparable across languages
+///
+/// Go `int32`, Java `int`, Kotlin `Int` and C# `Int32` are all a signed
+/// 32-bit integer. `bits` is `None` when the width depends on the platform
+/// (Go `int`, C `long`) or is unbounded (Python `int`).
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(tag = "category", rename_all = "snake_case")]
+pub enum CanonicalType {
+    Integer {
+        #[serde(skip_serializing_if = "Option::is_none", default)]
+        bits: Option<u16>,
+        signed: bool,
+    },
+    Float {
+        #[serde(skip_serializing_if = "Option::is_none", default)]
+        bits: Option<u16>,
+    },
+    String,
+    Bool,
+    /// Arrays, slices, lists, sets and other sequences
+    List {
+        element: Box<CanonicalType>,
+    },
+    Map {
+        key: Box<CanonicalType>,
+        value: Box<CanonicalType>,
+    },
+    /// A value that may be absent: `Option<T>`, `T?`, `T | null`
+    Optional {
+        inner: Box<CanonicalType>,
+    },
+    /// Callables of any signature
+    Function,
+    Unknown,
+}
+
 /// Type parameter (generic)
 #[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
 pub struct TypeParam {
@@ -102,3 +549,74 @@ pub struct ImportedSymbol {
     #[serde(skip_serializing_if = "Option::is_none")]
     pub alias: Option<String>,
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_modifier_names() {
+        assert_eq!(Modifier::Nonisolated.to_string(), "nonisolated");
+        assert_eq!("Constexpr".parse::<Modifier>(), Ok(Modifier::Constexpr));
+        assert_eq!(
+            serde_json::to_string(&Modifier::Suspend).unwrap(),
+            "\"suspend\""
+        );
+        assert!("friend".parse::<Modifier>().is_err());
+    }
+
+    #[test]
+    fn test_source_visibility_coarse() {
+        assert_eq!(SourceVisibility::Open.coarse(), Visibility::Public);
+        assert_eq!(
+            SourceVisibility::ProtectedInternal.coarse(),
+            Visibility::Protected
+        );
+        assert_eq!(SourceVisibility::Crate.coarse(), Visibility::Internal);
+        assert_eq!(SourceVisibility::Super.coarse(), Visibility::Internal);
+        assert_eq!(
+            SourceVisibility::Restricted("crate::net_io".to_string()).coarse(),
+            Visibility::Internal
+        );
+        assert_eq!(SourceVisibility::FilePrivate.coarse(), Visibility::Private);
+        assert_eq!(
+            SourceVisibility::from(Visibility::Internal),
+            SourceVisibility::Internal
+        );
+    }
+
+    #[test]
+    fn test_source_visibility_parse_roundtrip() {
+        for level in [
+            SourceVisibility::Public,
+            SourceVisibility::Open,
+            SourceVisibility::ProtectedInternal,
+            SourceVisibility::PrivateProtected,
+            SourceVisibility::PackagePrivate,
+            SourceVisibility::Crate,
+            SourceVisibility::Super,
+            SourceVisibility::Restricted("crate::net_io".to_string()),
+            SourceVisibility::FilePrivate,
+        ] {
+            assert_eq!(level.to_string().parse::<SourceVisibility>(), Ok(level));
+        }
+        assert_eq!(
+            "protected-internal".parse::<SourceVisibility>(),
+            Ok(SourceVisibility::ProtectedInternal)
+        );
+        assert!("friend".parse::<SourceVisibility>().is_err());
+    }
+
+    #[test]
+    fn test_source_visibility_matches() {
+        let internal = SourceVisibility::Internal;
+        assert!(internal.matches(&SourceVisibility::Crate));
+        assert!(internal.matches(&SourceVisibility::PackagePrivate));
+        assert!(!internal.matches(&SourceVisibility::Private));
+
+        let crate_only = SourceVisibility::Crate;
+        assert!(crate_only.matches(&SourceVisibility::Crate));
+        assert!(!crate_only.matches(&SourceVisibility::Internal));
+        assert!(!crate_only.matches(&SourceVisibility::Super));
+    }
+}
@@ -1,8 +1,8 @@
 //! Visitor pattern for IR traversal
 
 use super::nodes::{
-    Class, Comment, Directory, Enum, Field, File, Function, Import, Interface, Node, Package,
-    RawContent, Struct, TypeAlias,
+    Class, Comment, Directory, Enum, EnumVariant, Field, File, Function, Import, Interface, Macro,
+    Module, Node, OverloadSet, Package, Property, RawContent, Struct, TypeAlias, Variable,
 };
 
 /// Visitor trait for IR node traversal
@@ -16,14 +16,20 @@ pub trait Visitor {
             Node::File(f) => self.visit_file(f),
             Node::Directory(d) => self.visit_directory(d),
             Node::Package(p) => self.visit_package(p),
+            Node::Module(m) => self.visit_module(m),
             Node::Import(i) => self.visit_import(i),
             Node::Class(c) => self.visit_class(c),
             Node::Interface(i) => self.visit_interface(i),
             Node::Struct(s) => self.visit_struct(s),
             Node::Enum(e) => self.visit_enum(e),
+            Node::EnumVariant(v) => self.visit_enum_variant(v),
             Node::TypeAlias(t) => self.visit_type_alias(t),
             Node::Function(f) => self.visit_function(f),
+            Node::OverloadSet(o) => self.visit_overload_set(o),
             Node::Field(f) => self.visit_field(f),
+            Node::Property(p) => self.visit_property(p),
+            Node::Variable(v) => self.visit_variable(v),
+            Node::Macro(m) => self.visit_macro(m),
             Node::Comment(c) => self.visit_comment(c),
             Node::RawContent(r) => self.visit_raw_content(r),
         }
@@ -47,6 +53,12 @@ pub trait Visitor {
         }
     }
 
+    fn visit_module(&mut self, module: &mut Module) {
+        for child in &mut module.children {
+            self.visit_node(child);
+        }
+    }
+
     fn visit_import(&mut self, _import: &mut Import) {}
 
     fn visit_class(&mut self, class: &mut Class) {
@@ -73,9 +85,25 @@ pub trait Visitor {
         }
     }
 
+    fn visit_enum_variant(&mut self, variant: &mut EnumVariant) {
+        for child in &mut variant.children {
+            self.visit_node(child);
+        }
+    }
+
     fn visit_type_alias(&mut self, _alias: &mut TypeAlias) {}
     fn visit_function(&mut self, _func: &mut Function) {}
+
+    fn visit_overload_set(&mut self, overloads: &mut OverloadSet) {
+        for signature in &mut overloads.signatures {
+            self.visit_function(signature);
+        }
+    }
+
     fn visit_field(&mut self, _field: &mut Field) {}
+    fn visit_property(&mut self, _property: &mut Property) {}
+    fn visit_variable(&mut self, _variable: &mut Variable) {}
+    fn visit_macro(&mut self, _macro_def: &mut Macro) {}
     fn visit_comment(&mut self, _comment: &mut Comment) {}
     fn visit_raw_content(&mut self, _raw: &mut RawContent) {}
 }
@@ -16,16 +16,35 @@
 //! This crate uses **rayon** for CPU parallelism, NOT tokio/async.
 //! All operations are synchronous for simplicity and performance.
 
+pub mod budget;
+pub mod canonical;
+pub mod categories;
+pub mod config;
+pub mod decl_filter;
+pub mod encoding;
 pub mod error;
 pub mod ir;
 pub mod logging;
 pub mod options;
 pub mod parser;
 pub mod processor;
+pub mod project;
 pub mod stripper;
+pub mod summary;
+pub mod test_filter;
+pub mod tokens;
+pub mod type_merge;
 
 // Re-exports
+pub use categories::{Category, CategoryFilter, Preset};
+pub use decl_filter::DeclFilter;
+pub use encoding::Encoding;
 pub use error::{DistilError, Result};
-pub use options::ProcessOptions;
+pub use options::{EffectiveOptions, ProcessOptions};
 pub use parser::ParserPool;
-pub use stripper::Stripper;
+pub use project::ProjectRoot;
+pub use stripper::{PruneStats, Stripper};
+pub use summary::{Summary, SummaryStyle};
+pub use test_filter::TestMode;
+pub use tokens::{TokenReport, Tokenizer};
+pub use type_merge::MergeMode;
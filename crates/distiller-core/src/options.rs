@@ -2,6 +2,15 @@
 //!
 //! Defines how files should be processed and what content to include/exclude.
 
+use crate::categories::Category;
+use crate::decl_filter::DeclFilter;
+use crate::encoding::Encoding;
+use crate::ir::SourceVisibility;
+use crate::test_filter::TestMode;
+use crate::type_merge::MergeMode;
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::fmt;
 use std::path::PathBuf;
 
 /// Path type for output file paths
@@ -35,6 +44,9 @@ pub struct ProcessOptions {
     pub include_internal: bool,
     /// Include private members (default: false)
     pub include_private: bool,
+    /// Precise visibility levels to include; when non-empty this replaces
+    /// the four flags above (`internal` still selects the whole bucket)
+    pub visibility_levels: Vec<SourceVisibility>,
 
     // Content filtering
     /// Include regular comments (default: false)
@@ -51,6 +63,32 @@ pub struct ProcessOptions {
     pub include_fields: bool,
     /// Include methods/functions (default: true)
     pub include_methods: bool,
+    /// Include deprecated declarations (default: true)
+    pub include_deprecated: bool,
+    /// Collapse overload sets to their first signature and a count (default: false)
+    pub collapse_overloads: bool,
+
+    // Pruning
+    /// Remove files, packages and classes left empty by filtering (default: true)
+    pub prune_empty: bool,
+    /// Keep classes that were already empty in the source, e.g. marker
+    /// types and exceptions (default: true)
+    pub keep_empty_classes: bool,
+
+    // Test code
+    /// How test files and test symbols are treated (default: include)
+    pub tests: TestMode,
+
+    // Type merging
+    /// How impl blocks, extensions, partial classes and out-of-line methods
+    /// are merged into their type (default: kept where declared)
+    pub merge_types: MergeMode,
+
+    // Modifier / metadata filters
+    /// Keep only declarations matching all of these (empty = no restriction)
+    pub with_filters: Vec<DeclFilter>,
+    /// Drop declarations matching any of these
+    pub without_filters: Vec<DeclFilter>,
 
     // Processing configuration
     /// Raw mode - process all files as text (default: false)
@@ -59,6 +97,12 @@ pub struct ProcessOptions {
     pub workers: usize,
     /// Process directories recursively (default: true)
     pub recursive: bool,
+    /// Language overrides by file extension, e.g. `pyi` -> `python`
+    /// (default: none, languages come from each processor's extensions)
+    pub language_map: BTreeMap<String, String>,
+    /// Encodings tried, in order, for files that aren't UTF-8 and have no
+    /// BOM before decoding them lossily (default: windows-1252)
+    pub fallback_encodings: Vec<Encoding>,
 
     // Path configuration
     /// How to format file paths in output
@@ -73,6 +117,8 @@ pub struct ProcessOptions {
     pub include_patterns: Vec<String>,
     /// Exclude files matching these patterns
     pub exclude_patterns: Vec<String>,
+    /// Honor `.gitignore`, `.ignore` and `.aidignore` files (default: true)
+    pub use_ignore_files: bool,
 
     // Error handling
     /// Continue processing on file errors (collect partial results)
@@ -87,6 +133,7 @@ impl Default for ProcessOptions {
             include_protected: false,
             include_internal: false,
             include_private: false,
+            visibility_levels: Vec::new(),
 
             // Default: signatures with docstrings
             include_comments: false,
@@ -96,11 +143,29 @@ impl Default for ProcessOptions {
             include_annotations: true,
             include_fields: true,
             include_methods: true,
+            include_deprecated: true,
+            collapse_overloads: false,
+
+            // Default: prune containers emptied by filtering
+            prune_empty: true,
+            keep_empty_classes: true,
+
+            // Default: keep test code
+            tests: TestMode::Include,
+
+            // Default: merge type fragments declared in the same file
+            merge_types: MergeMode::Off,
+
+            // Default: no modifier / metadata filtering
+            with_filters: Vec::new(),
+            without_filters: Vec::new(),
 
             // Default: parallel processing
             raw_mode: false,
             workers: 0, // Auto-detect
             recursive: true,
+            language_map: BTreeMap::new(),
+            fallback_encodings: vec![Encoding::Windows1252],
 
             // Default: relative paths
             file_path_type: PathType::Relative,
@@ -110,6 +175,7 @@ impl Default for ProcessOptions {
             // Default: no pattern filtering
             include_patterns: Vec::new(),
             exclude_patterns: Vec::new(),
+            use_ignore_files: true,
 
             // Default: fail on first error
             continue_on_error: false,
@@ -155,6 +221,93 @@ impl ProcessOptions {
                 || !self.include_implementation
                 || self.has_visibility_filters())
     }
+
+    /// The options that decide what the output contains
+    #[must_use]
+    pub fn effective(&self) -> EffectiveOptions {
+        let mut include: Vec<&'static str> = Category::ALL
+            .into_iter()
+            .filter(|category| category.get(self))
+            .map(Category::name)
+            .collect();
+        for (name, included) in [
+            ("fields", self.include_fields),
+            ("methods", self.include_methods),
+            ("deprecated", self.include_deprecated),
+        ] {
+            if included {
+                include.push(name);
+            }
+        }
+
+        EffectiveOptions {
+            include,
+            visibility: self
+                .visibility_levels
+                .iter()
+                .map(ToString::to_string)
+                .collect(),
+            collapse_overloads: self.collapse_overloads,
+            prune_empty: self.prune_empty,
+            tests: self.tests.to_string(),
+            merge_types: self.merge_types.to_string(),
+            with: self.with_filters.iter().map(ToString::to_string).collect(),
+            without: self
+                .without_filters
+                .iter()
+                .map(ToString::to_string)
+                .collect(),
+        }
+    }
+}
+
+/// The options that decide what the output contains
+///
+/// Logged with `-v` and recorded in JSON output metadata, so an output can
+/// be traced back to the settings that produced it.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct EffectiveOptions {
+    /// Categories and content kinds included, e.g. `public`, `docstrings`
+    pub include: Vec<&'static str>,
+    /// Precise visibility levels, if given
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub visibility: Vec<String>,
+    pub collapse_overloads: bool,
+    pub prune_empty: bool,
+    pub tests: String,
+    pub merge_types: String,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub with: Vec<String>,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub without: Vec<String>,
+}
+
+impl fmt::Display for EffectiveOptions {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(
+            f,
+            "include={} tests={} merge-types={}",
+            self.include.join(","),
+            self.tests,
+            self.merge_types
+        )?;
+        for (name, values) in [
+            ("visibility", &self.visibility),
+            ("with", &self.with),
+            ("without", &self.without),
+        ] {
+            if !values.is_empty() {
+                write!(f, " {name}={}", values.join(","))?;
+            }
+        }
+        if self.collapse_overloads {
+            write!(f, " collapse-overloads")?;
+        }
+        if !self.prune_empty {
+            write!(f, " keep-empty")?;
+        }
+        Ok(())
+    }
 }
 
 /// Builder for `ProcessOptions`
@@ -183,6 +336,12 @@ impl ProcessOptionsBuilder {
         self
     }
 
+    #[must_use]
+    pub fn visibility_levels(mut self, levels: Vec<SourceVisibility>) -> Self {
+        self.options.visibility_levels = levels;
+        self
+    }
+
     #[must_use]
     pub fn include_implementation(mut self, value: bool) -> Self {
         self.options.include_implementation = value;
@@ -195,6 +354,54 @@ impl ProcessOptionsBuilder {
         self
     }
 
+    #[must_use]
+    pub fn include_deprecated(mut self, value: bool) -> Self {
+        self.options.include_deprecated = value;
+        self
+    }
+
+    #[must_use]
+    pub fn collapse_overloads(mut self, value: bool) -> Self {
+        self.options.collapse_overloads = value;
+        self
+    }
+
+    #[must_use]
+    pub fn prune_empty(mut self, value: bool) -> Self {
+        self.options.prune_empty = value;
+        self
+    }
+
+    #[must_use]
+    pub fn keep_empty_classes(mut self, value: bool) -> Self {
+        self.options.keep_empty_classes = value;
+        self
+    }
+
+    #[must_use]
+    pub fn tests(mut self, mode: TestMode) -> Self {
+        self.options.tests = mode;
+        self
+    }
+
+    #[must_use]
+    pub fn merge_types(mut self, mode: MergeMode) -> Self {
+        self.options.merge_types = mode;
+        self
+    }
+
+    #[must_use]
+    pub fn with_filters(mut self, filters: Vec<DeclFilter>) -> Self {
+        self.options.with_filters = filters;
+        self
+    }
+
+    #[must_use]
+    pub fn without_filters(mut self, filters: Vec<DeclFilter>) -> Self {
+        self.options.without_filters = filters;
+        self
+    }
+
     #[must_use]
     pub fn workers(mut self, count: usize) -> Self {
         self.options.workers = count;
@@ -207,6 +414,18 @@ impl ProcessOptionsBuilder {
         self
     }
 
+    #[must_use]
+    pub fn language_map(mut self, map: BTreeMap<String, String>) -> Self {
+        self.options.language_map = map;
+        self
+    }
+
+    #[must_use]
+    pub fn fallback_encodings(mut self, encodings: Vec<Encoding>) -> Self {
+        self.options.fallback_encodings = encodings;
+        self
+    }
+
     #[must_use]
     pub fn include_patterns(mut self, patterns: Vec<String>) -> Self {
         self.options.include_patterns = patterns;
@@ -219,6 +438,12 @@ impl ProcessOptionsBuilder {
         self
     }
 
+    #[must_use]
+    pub fn use_ignore_files(mut self, value: bool) -> Self {
+        self.options.use_ignore_files = value;
+        self
+    }
+
     #[must_use]
     pub fn build(self) -> ProcessOptions {
         self.options
@@ -236,6 +461,16 @@ mod tests {
         assert!(!opts.include_private);
         assert!(!opts.include_implementation);
         assert!(opts.include_docstrings);
+        assert!(opts.include_deprecated);
+        assert!(!opts.collapse_overloads);
+        assert!(opts.prune_empty);
+        assert!(opts.keep_empty_classes);
+        assert_eq!(opts.tests, TestMode::Include);
+        assert_eq!(opts.merge_types, MergeMode::Off);
+        assert!(opts.with_filters.is_empty());
+        assert!(opts.without_filters.is_empty());
+        assert!(opts.language_map.is_empty());
+        assert!(opts.use_ignore_files);
     }
 
     #[test]
@@ -259,6 +494,34 @@ mod tests {
         assert!(count <= num_cpus::get());
     }
 
+    #[test]
+    fn test_effective_options() {
+        let opts = ProcessOptions::builder()
+            .include_private(true)
+            .collapse_overloads(true)
+            .tests(TestMode::Exclude)
+            .build();
+        let effective = opts.effective();
+        assert_eq!(
+            effective.include,
+            [
+                "public",
+                "private",
+                "docstrings",
+                "imports",
+                "annotations",
+                "fields",
+                "methods",
+                "deprecated"
+            ]
+        );
+        assert_eq!(
+            effective.to_string(),
+            "include=public,private,docstrings,imports,annotations,fields,methods,deprecated \
+             tests=0 merge-types=0 collapse-overloads"
+        );
+    }
+
     #[test]
     fn test_worker_count_explicit() {
         let opts = ProcessOptions::builder().workers(8).build();
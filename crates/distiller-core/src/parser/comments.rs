@@ -0,0 +1,80 @@
+//! Comment lookup helpers for tree-sitter nodes
+//!
+//! Language processors don't emit comments as IR nodes by default, but some
+//! declaration metadata lives in doc comments (e.g. `@deprecated` tags).
+
+/// Wrapper nodes whose leading comment belongs to the wrapped declaration
+const WRAPPER_KINDS: &[&str] = &[
+    "export_statement",
+    "decorated_definition",
+    "template_declaration",
+    "ambient_declaration",
+    "lexical_declaration",
+    "variable_declaration",
+    "type_declaration",
+];
+
+/// Get the comment block directly preceding a declaration node
+///
+/// Collects contiguous comment siblings before `node`, skipping attributes,
+/// annotations and decorators in between (as in Rust's `/// doc` then
+/// `#[derive]`). A blank line ends the block, so a license header or an
+/// unrelated comment above the declaration isn't taken for its doc. If the
+/// node has no leading comment and is wrapped in an
+/// export/decorator node, the wrapper's leading comment is used.
+#[must_use]
+pub fn preceding_comment(node: tree_sitter::Node, source: &str) -> Option<String> {
+    let mut current = node;
+    loop {
+        if let Some(comment) = comment_before(current, source) {
+            return Some(comment);
+        }
+        match current.parent() {
+            Some(parent) if WRAPPER_KINDS.contains(&parent.kind()) => current = parent,
+            _ => return None,
+        }
+    }
+}
+
+fn comment_before(node: tree_sitter::Node, source: &str) -> Option<String> {
+    let mut comments = Vec::new();
+    let mut next_row = node.start_position().row;
+    let mut sibling = node.prev_sibling();
+
+    while let Some(prev) = sibling {
+        if last_row(prev) + 1 < next_row {
+            break;
+        }
+        next_row = prev.start_position().row;
+
+        let kind = prev.kind();
+        if kind.contains("comment") {
+            comments.push(&source[prev.start_byte()..prev.end_byte()]);
+        } else if !(kind.contains("attribute")
+            || kind.contains("annotation")
+            || kind == "decorator")
+        {
+            break;
+        }
+        sibling = prev.prev_sibling();
+    }
+
+    if comments.is_empty() {
+        return None;
+    }
+    comments.reverse();
+    Some(comments.join("\n"))
+}
+
+/// Last row holding text of `node`
+///
+/// Some grammars end line comments after their newline, at column 0 of
+/// the next row.
+fn last_row(node: tree_sitter::Node) -> usize {
+    let end = node.end_position();
+    if end.column == 0 && end.row > node.start_position().row {
+        end.row - 1
+    } else {
+        end.row
+    }
+}
@@ -3,8 +3,16 @@
 //! This module provides:
 //! - Thread-safe parser pooling
 //! - Language grammar loading
-//! - Source parsing utilities
+//! - Source parsing utilities (comments, value previews, overload grouping,
+//!   synthesized members, type expressions)
+//! - Syntax error locations
 
+pub mod comments;
+pub mod overloads;
 pub mod pool;
+pub mod syntax;
+pub mod synthesized;
+pub mod types;
+pub mod values;
 
 pub use pool::{ParserGuard, ParserPool, PoolStats};
@@ -0,0 +1,247 @@
+//! Overload grouping for languages with function overloading
+//!
+//! Processors for C#, Java, C++, Kotlin, Swift, TypeScript and Python call
+//! `group_overloads` once the file is parsed, so same-named functions in one
+//! scope come out as a single `OverloadSet` instead of unrelated functions.
+//! Out-of-line methods group by receiver as well as name, so `A::size` and
+//! `B::size` stay apart.
+
+use crate::ir::{Function, Node, OverloadSet};
+use crate::type_merge::RECEIVER;
+use std::collections::HashMap;
+
+/// Metadata key marking a declaration-only overload signature
+///
+/// Set by processors on TypeScript overload signatures and Python
+/// `@typing.overload` stubs. When a group has marked signatures, the
+/// unmarked implementation is dropped. The key is removed during grouping.
+pub const OVERLOAD_SIGNATURE: &str = "overload";
+
+/// Group same-named functions into overload sets, recursively
+///
+/// The set takes the position of the first signature. Groups left with a
+/// single signature once the implementation is dropped stay a plain
+/// function.
+pub fn group_overloads(nodes: &mut Vec<Node>) {
+    for node in nodes.iter_mut() {
+        match node {
+            Node::Package(p) => group_overloads(&mut p.children),
+            Node::Module(m) => group_overloads(&mut m.children),
+            Node::Class(c) => group_overloads(&mut c.children),
+            Node::Interface(i) => group_overloads(&mut i.children),
+            Node::Struct(s) => group_overloads(&mut s.children),
+            Node::Enum(e) => group_overloads(&mut e.children),
+            Node::EnumVariant(v) => group_overloads(&mut v.children),
+            _ => {}
+        }
+    }
+
+    let mut counts: HashMap<(&str, &str), usize> = HashMap::new();
+    for node in nodes.iter() {
+        if let Node::Function(function) = node {
+            *counts.entry(group_key(function)).or_default() += 1;
+        }
+    }
+    let overloaded: Vec<(String, String)> = counts
+        .into_iter()
+        .filter(|&(_, count)| count > 1)
+        .map(|((receiver, name), _)| (receiver.to_string(), name.to_string()))
+        .collect();
+
+    if overloaded.is_empty() {
+        for node in nodes.iter_mut() {
+            if let Node::Function(function) = node {
+                function.metadata.remove(OVERLOAD_SIGNATURE);
+            }
+        }
+        return;
+    }
+
+    // Position of each group's set in the rebuilt list
+    let mut positions: HashMap<(String, String), usize> = HashMap::new();
+    let mut grouped: Vec<Node> = Vec::with_capacity(nodes.len());
+    for node in nodes.drain(..) {
+        match node {
+            Node::Function(function)
+                if overloaded.iter().any(|(receiver, name)| {
+                    group_key(&function) == (receiver.as_str(), name.as_str())
+                }) =>
+            {
+                let (receiver, name) = group_key(&function);
+                let key = (receiver.to_string(), name.to_string());
+                if let Some(&position) = positions.get(&key) {
+                    if let Node::OverloadSet(set) = &mut grouped[position] {
+                        set.signatures.push(function);
+                    }
+                } else {
+                    positions.insert(key, grouped.len());
+                    grouped.push(Node::OverloadSet(OverloadSet {
+                        name: function.name.clone(),
+                        signatures: vec![function],
+                        count: 0,
+                        line_start: 0,
+                        line_end: 0,
+                    }));
+                }
+            }
+            Node::Function(mut function) => {
+                function.metadata.remove(OVERLOAD_SIGNATURE);
+                grouped.push(Node::Function(function));
+            }
+            other => grouped.push(other),
+        }
+    }
+
+    *nodes = grouped
+        .into_iter()
+        .filter_map(|node| match node {
+            Node::OverloadSet(set) => finish_set(set),
+            other => Some(other),
+        })
+        .collect();
+}
+
+/// Drop the implementation behind marked signatures and fill in the totals
+fn finish_set(mut set: OverloadSet) -> Option<Node> {
+    if set.signatures.iter().any(is_signature_only) {
+        set.signatures.retain(is_signature_only);
+    }
+    for signature in &mut set.signatures {
+        signature.metadata.remove(OVERLOAD_SIGNATURE);
+    }
+
+    if set.signatures.len() == 1 {
+        return set.signatures.pop().map(Node::Function);
+    }
+
+    set.count = set.signatures.len();
+    set.line_start = set.signatures.iter().map(|f| f.line_start).min()?;
+    set.line_end = set.signatures.iter().map(|f| f.line_end).max()?;
+    Some(Node::OverloadSet(set))
+}
+
+/// Receiver and name: methods of different types never overload each other
+fn group_key(function: &Function) -> (&str, &str) {
+    let receiver = function.metadata.get(RECEIVER).map_or("", String::as_str);
+    (receiver, function.name.as_str())
+}
+
+fn is_signature_only(function: &Function) -> bool {
+    function.metadata.contains_key(OVERLOAD_SIGNATURE)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ir::{Class, Parameter, TypeRef, Visibility};
+    use std::collections::BTreeMap;
+
+    fn function(name: &str, params: &[&str], line: usize, signature_only: bool) -> Node {
+        let mut metadata = BTreeMap::new();
+        if signature_only {
+            metadata.insert(OVERLOAD_SIGNATURE.to_string(), String::new());
+        }
+        Node::Function(Function {
+            name: name.to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            parameters: params
+                .iter()
+                .map(|ty| Parameter {
+                    name: "value".to_string(),
+                    param_type: TypeRef::new(*ty),
+                    default_value: None,
+                    is_variadic: false,
+                    is_optional: false,
+                    decorators: vec![],
+                })
+                .collect(),
+            return_type: None,
+            implementation: None,
+            line_start: line,
+            line_end: line,
+            deprecated: None,
+            metadata,
+        })
+    }
+
+    #[test]
+    fn test_groups_same_named_methods() {
+        let mut nodes = vec![Node::Class(Class {
+            name: "Printer".to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            extends: vec![],
+            implements: vec![],
+            children: vec![
+                function("print", &["int"], 2, false),
+                function("flush", &[], 3, false),
+                function("print", &["String"], 4, false),
+            ],
+            line_start: 1,
+            line_end: 5,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })];
+
+        group_overloads(&mut nodes);
+
+        let Node::Class(class) = &nodes[0] else {
+            panic!("Expected class");
+        };
+        assert_eq!(class.children.len(), 2);
+        let Node::OverloadSet(set) = &class.children[0] else {
+            panic!("Expected overload set first");
+        };
+        assert_eq!(set.name, "print");
+        assert_eq!(set.count, 2);
+        assert_eq!((set.line_start, set.line_end), (2, 4));
+        assert!(matches!(&class.children[1], Node::Function(f) if f.name == "flush"));
+    }
+
+    #[test]
+    fn test_implementation_signature_hidden() {
+        let mut nodes = vec![
+            function("parse", &["string"], 1, true),
+            function("parse", &["Buffer"], 2, true),
+            function("parse", &["any"], 3, false),
+        ];
+
+        group_overloads(&mut nodes);
+
+        assert_eq!(nodes.len(), 1);
+        let Node::OverloadSet(set) = &nodes[0] else {
+            panic!("Expected overload set");
+        };
+        assert_eq!(set.count, 2);
+        assert!(set.signatures.iter().all(|f| f.metadata.is_empty()));
+        assert!(
+            set.signatures
+                .iter()
+                .all(|f| f.parameters[0].param_type.name != "any")
+        );
+    }
+
+    #[test]
+    fn test_single_signature_stays_function() {
+        let mut nodes = vec![
+            function("parse", &["string"], 1, true),
+            function("parse", &["any"], 2, false),
+            function("declared", &[], 3, true),
+        ];
+
+        group_overloads(&mut nodes);
+
+        assert_eq!(nodes.len(), 2);
+        assert!(
+            matches!(&nodes[0], Node::Function(f) if f.parameters[0].param_type.name == "string" && f.metadata.is_empty())
+        );
+        assert!(matches!(&nodes[1], Node::Function(f) if f.metadata.is_empty()));
+    }
+}
@@ -131,7 +131,7 @@ impl ParserPool {
 
         parser
             .set_language(&language)
-            .map_err(|e| DistilError::TreeSitter(format!("Failed to set language: {e}")))?;
+            .map_err(|e| DistilError::tree_sitter(format!("Failed to set language: {e}")))?;
 
         Ok(ParserGuard {
             parser: Some(parser),
@@ -0,0 +1,36 @@
+//! Locating syntax errors in parse trees
+//!
+//! Tree-sitter recovers from syntax errors by wrapping what it can't parse
+//! in ERROR nodes and inserting zero-width MISSING nodes, so processors
+//! still extract the declarations around them. When nothing could be
+//! recovered, processors report the first of these nodes as a parse error
+//! with its location.
+
+use crate::error::{DistilError, SourceSpan};
+use std::path::PathBuf;
+use tree_sitter::Node;
+
+/// The first ERROR or MISSING node under `node`, in source order
+#[must_use]
+pub fn first_error(node: Node<'_>) -> Option<Node<'_>> {
+    if !node.has_error() {
+        return None;
+    }
+    if node.is_error() || node.is_missing() {
+        return Some(node);
+    }
+    let mut cursor = node.walk();
+    node.children(&mut cursor).find_map(first_error)
+}
+
+/// Parse error pointing at the first syntax error under `root`, if any
+pub fn syntax_error(path: impl Into<PathBuf>, source: &str, root: Node<'_>) -> Option<DistilError> {
+    let node = first_error(root)?;
+    let message = if node.is_missing() {
+        format!("Syntax error: missing `{}`", node.kind())
+    } else {
+        "Syntax error".to_string()
+    };
+    let span = SourceSpan::from_offsets(source, node.start_byte(), node.end_byte());
+    Some(DistilError::parse_error_at(path, message, span, source))
+}
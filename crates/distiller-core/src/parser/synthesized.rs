@@ -0,0 +1,104 @@
+//! Members created by macros and code generators
+//!
+//! Some declarations never appear in the source: Ruby `attr_accessor`
+//! expands to reader and writer methods, Python `@dataclass` writes
+//! `__init__` and the comparison methods, Kotlin `data class` adds `copy`
+//! and `componentN`. Processors add these members themselves so the output
+//! shows the API callers actually see.
+
+use crate::ir::{Function, Node, Parameter, TypeRef, Visibility};
+use std::collections::BTreeMap;
+
+/// Metadata key naming the generator of a synthesized member
+/// (`attr_accessor`, `dataclass`, `data`, ...)
+pub const SYNTHESIZED: &str = "synthesized";
+
+/// Metadata key listing derived traits on a type, comma-separated
+/// (`#[derive(Debug, Clone)]` becomes `derive=Debug, Clone`)
+pub const DERIVE: &str = "derive";
+
+/// Build a method created by `generator`
+///
+/// The method has no implementation and sits on the line of the
+/// declaration that generated it.
+#[must_use]
+pub fn synthesized_method(
+    name: &str,
+    visibility: Visibility,
+    parameters: Vec<Parameter>,
+    return_type: Option<TypeRef>,
+    line: usize,
+    generator: &str,
+) -> Function {
+    let mut metadata = BTreeMap::new();
+    metadata.insert(SYNTHESIZED.to_string(), generator.to_string());
+
+    Function {
+        name: name.to_string(),
+        visibility,
+        source_visibility: None,
+        modifiers: vec![],
+        decorators: vec![],
+        type_params: vec![],
+        parameters,
+        return_type,
+        implementation: None,
+        line_start: line,
+        line_end: line,
+        deprecated: None,
+        metadata,
+    }
+}
+
+/// Whether a type body already declares a member with this name
+///
+/// Generators skip members the source writes out by hand.
+#[must_use]
+pub fn defines_member(children: &[Node], name: &str) -> bool {
+    children.iter().any(|child| match child {
+        Node::Function(f) => f.name == name,
+        Node::OverloadSet(o) => o.name == name,
+        Node::Field(f) => f.name == name,
+        Node::Property(p) => p.name == name,
+        _ => false,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_synthesized_method_records_generator() {
+        let method = synthesized_method(
+            "__repr__",
+            Visibility::Public,
+            vec![],
+            Some(TypeRef::new("str")),
+            3,
+            "dataclass",
+        );
+
+        assert_eq!(
+            method.metadata.get(SYNTHESIZED).map(String::as_str),
+            Some("dataclass")
+        );
+        assert_eq!((method.line_start, method.line_end), (3, 3));
+        assert!(method.implementation.is_none());
+    }
+
+    #[test]
+    fn test_defines_member() {
+        let children = vec![Node::Function(synthesized_method(
+            "name",
+            Visibility::Public,
+            vec![],
+            None,
+            1,
+            "attr_reader",
+        ))];
+
+        assert!(defines_member(&children, "name"));
+        assert!(!defines_member(&children, "name="));
+    }
+}
@@ -0,0 +1,886 @@
+//! Type expression parsing
+//!
+//! Tree-sitter grammars disagree on how much structure they expose for type
+//! annotations, so processors hand over the annotation text instead and
+//! `parse_type` recovers the structure: generic arguments, array elements,
+//! nullability, and a `TypeShape` for unions, intersections, function types,
+//! tuples, pointers and references. `TypeRef.name` keeps the text as written,
+//! so output still reads like the source.
+
+use crate::ir::{TypeRef, TypeShape};
+
+/// Type syntax of a source language
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum TypeSyntax {
+    /// C and C++
+    C,
+    CSharp,
+    Go,
+    Java,
+    Kotlin,
+    Php,
+    Python,
+    Rust,
+    Swift,
+    /// TypeScript and JavaScript (`JSDoc`)
+    TypeScript,
+}
+
+impl TypeSyntax {
+    /// Bracket pair around generic arguments
+    fn generic_brackets(self) -> (char, char) {
+        match self {
+            Self::Python | Self::Go => ('[', ']'),
+            _ => ('<', '>'),
+        }
+    }
+
+    /// Arrow between parameters and result of a function type
+    fn arrow(self) -> Option<&'static str> {
+        match self {
+            Self::TypeScript => Some("=>"),
+            Self::Kotlin | Self::Swift | Self::Rust => Some("->"),
+            _ => None,
+        }
+    }
+
+    fn has_unions(self) -> bool {
+        matches!(self, Self::TypeScript | Self::Python | Self::Php | Self::Go)
+    }
+
+    fn intersection_separator(self) -> Option<&'static str> {
+        match self {
+            Self::TypeScript | Self::Php | Self::Swift | Self::Java | Self::Kotlin => Some("&"),
+            Self::Rust => Some("+"),
+            _ => None,
+        }
+    }
+
+    /// `T?` marks a nullable type
+    fn has_nullable_suffix(self) -> bool {
+        matches!(self, Self::Kotlin | Self::Swift | Self::CSharp)
+    }
+
+    /// `T[]` is an array of `T`
+    fn has_postfix_arrays(self) -> bool {
+        matches!(
+            self,
+            Self::C | Self::CSharp | Self::Java | Self::Php | Self::TypeScript
+        )
+    }
+
+    /// `(A, B)` is a tuple
+    fn has_tuples(self) -> bool {
+        matches!(self, Self::Rust | Self::Swift | Self::CSharp | Self::Go)
+    }
+
+    /// Parameters and tuple elements may carry a `label:`
+    fn has_labels(self) -> bool {
+        matches!(
+            self,
+            Self::TypeScript | Self::Swift | Self::Kotlin | Self::Rust
+        )
+    }
+
+    /// Keywords and modifiers that don't change the structure of the type
+    fn qualifiers(self) -> &'static [&'static str] {
+        match self {
+            Self::C => &[
+                "const ",
+                "volatile ",
+                "struct ",
+                "enum ",
+                "union ",
+                "typename ",
+            ],
+            Self::CSharp => &["ref ", "out ", "in ", "params ", "readonly "],
+            Self::Java => &["final ", "? extends ", "? super "],
+            Self::Kotlin => &["out ", "in ", "suspend "],
+            Self::Rust => &["dyn ", "impl ", "unsafe ", "extern \"C\" "],
+            Self::Swift => &["inout ", "some ", "any "],
+            Self::TypeScript => &["readonly ", "new ", "abstract new "],
+            Self::Go | Self::Php | Self::Python => &[],
+        }
+    }
+}
+
+/// Parse a type annotation into a structured `TypeRef`
+///
+/// Text that doesn't parse as anything more specific stays a plain name.
+#[must_use]
+pub fn parse_type(text: &str, syntax: TypeSyntax) -> TypeRef {
+    let text = text.trim();
+    let mut type_ref = TypeRef::new(text);
+    fill(&mut type_ref, text, syntax);
+    type_ref
+}
+
+/// Fill the structure of `type_ref` from `text`, leaving `name` alone
+fn fill(type_ref: &mut TypeRef, text: &str, syntax: TypeSyntax) {
+    let written = text.trim();
+    let text = strip_qualifiers(written, syntax);
+    if text.is_empty() {
+        return;
+    }
+    if syntax == TypeSyntax::Python
+        && let Some(forward) = text
+            .strip_prefix('"')
+            .and_then(|t| t.strip_suffix('"'))
+            .or_else(|| text.strip_prefix('\'').and_then(|t| t.strip_suffix('\'')))
+    {
+        return fill(type_ref, forward, syntax);
+    }
+
+    if let Some(function) = function_type(text, syntax) {
+        type_ref.shape = Some(function);
+        return;
+    }
+    if syntax.has_unions() {
+        let members = split_top_level(text, "|");
+        if members.len() > 1 {
+            type_ref.is_nullable = members.iter().any(|member| is_null(member));
+            type_ref.shape = Some(TypeShape::Union {
+                members: parse_all(&members, syntax),
+            });
+            return;
+        }
+    }
+    if let Some(separator) = syntax.intersection_separator() {
+        let members = split_top_level(text, separator);
+        if members.len() > 1 && members.iter().all(|member| !member.is_empty()) {
+            type_ref.shape = Some(TypeShape::Intersection {
+                members: parse_all(&members, syntax),
+            });
+            return;
+        }
+    }
+
+    let nullable = match syntax {
+        _ if syntax.has_nullable_suffix() => text.strip_suffix('?'),
+        TypeSyntax::Php => text.strip_prefix('?'),
+        _ => None,
+    }
+    .or_else(|| {
+        // Swift implicitly unwrapped optionals
+        (syntax == TypeSyntax::Swift)
+            .then(|| text.strip_suffix('!'))
+            .flatten()
+    });
+    if let Some(inner) = nullable {
+        type_ref.is_nullable = true;
+        return fill(type_ref, inner, syntax);
+    }
+
+    // C `const` qualifies the pointee, so it has to stay for `indirection`
+    if let Some(indirection) = indirection(written, syntax) {
+        type_ref.shape = Some(indirection);
+        return;
+    }
+    if fill_collection(type_ref, text, syntax) {
+        return;
+    }
+
+    if let Some(inner) = text.strip_prefix('(')
+        && closes_at_end(inner)
+    {
+        let inner = &inner[..inner.len() - 1];
+        let items: Vec<&str> = split_top_level(inner, ",")
+            .into_iter()
+            .filter(|item| !item.is_empty())
+            .collect();
+        // `(T,)` is a one-element tuple in Rust, `(T)` is just grouping
+        if syntax.has_tuples() && (items.len() != 1 || inner.trim_end().ends_with(',')) {
+            type_ref.shape = Some(TypeShape::Tuple {
+                elements: elements(&items, syntax),
+            });
+        } else if let [item] = items.as_slice() {
+            fill(type_ref, item, syntax);
+        }
+        return;
+    }
+
+    fill_generic(type_ref, text, syntax);
+}
+
+/// Function types written with an arrow or a `fn`/`func` keyword
+fn function_type(text: &str, syntax: TypeSyntax) -> Option<TypeShape> {
+    match syntax {
+        TypeSyntax::Go => {
+            let rest = text.strip_prefix("func")?.trim_start();
+            let inner = rest.strip_prefix('(')?;
+            let close = closing_bracket(inner)?;
+            let results = inner[close + 1..].trim();
+            return Some(TypeShape::Function {
+                params: elements(&split_top_level(&inner[..close], ","), syntax),
+                returns: (!results.is_empty()).then(|| Box::new(parse_type(results, syntax))),
+            });
+        }
+        TypeSyntax::C => {
+            // `R(A)` (as in `std::function<R(A)>`) and `R (*)(A)`
+            let inner = text.strip_suffix(')')?;
+            let open = find_top_level(inner, "(").into_iter().last()?;
+            let returns = text[..open].trim().trim_end_matches("(*)").trim_end();
+            if returns.is_empty() || closing_bracket(&inner[open + 1..]).is_some() {
+                return None;
+            }
+            return Some(TypeShape::Function {
+                params: elements(&split_top_level(&inner[open + 1..], ","), syntax),
+                returns: Some(Box::new(parse_type(returns, syntax))),
+            });
+        }
+        _ => {}
+    }
+
+    let arrow = syntax
+        .arrow()
+        .and_then(|arrow| find_top_level(text, arrow).first().copied());
+    let (mut params, returns) = match arrow {
+        Some(position) => (text[..position].trim(), Some(text[position + 2..].trim())),
+        // Rust `fn(u8)` and `FnMut(u8)` may leave out the result
+        None if syntax == TypeSyntax::Rust => (text, None),
+        None => return None,
+    };
+
+    // Swift effects: `(Int) async throws -> Int`
+    for effect in [" throws", " rethrows", " async"] {
+        params = params.strip_suffix(effect).unwrap_or(params).trim_end();
+    }
+    if syntax == TypeSyntax::Rust {
+        let after_keyword = ["fn", "FnMut", "FnOnce", "Fn"]
+            .iter()
+            .find_map(|keyword| params.strip_prefix(keyword))
+            .map(str::trim_start)
+            .filter(|rest| rest.starts_with('('));
+        match after_keyword {
+            Some(rest) => params = rest,
+            // A parenthesized type without an arrow is a tuple
+            None if returns.is_none() => return None,
+            None => {}
+        }
+    }
+    if let Some(generic) = params.strip_prefix('<') {
+        // TypeScript generic function types: `<T>(value: T) => T`
+        params = generic[closing_bracket(generic)? + 1..].trim_start();
+    }
+    if !params.starts_with('(') {
+        // Kotlin function types with a receiver: `String.(Int) -> Unit`
+        params = &params[*find_top_level(params, ".(").first()? + 1..];
+    }
+
+    let inner = params.strip_prefix('(')?;
+    if !closes_at_end(inner) {
+        return None;
+    }
+    Some(TypeShape::Function {
+        params: elements(&split_top_level(&inner[..inner.len() - 1], ","), syntax),
+        returns: returns.map(|returns| Box::new(parse_type(returns, syntax))),
+    })
+}
+
+/// Pointers and references
+fn indirection(text: &str, syntax: TypeSyntax) -> Option<TypeShape> {
+    match syntax {
+        TypeSyntax::Rust => {
+            if let Some(rest) = text.strip_prefix('&') {
+                let rest = rest.trim_start();
+                let rest = match rest.strip_prefix('\'') {
+                    Some(lifetime) => lifetime.split_once(' ').map_or("", |(_, rest)| rest),
+                    None => rest,
+                };
+                let (target, is_mutable) = match rest.strip_prefix("mut ") {
+                    Some(target) => (target, true),
+                    None => (rest, false),
+                };
+                return Some(TypeShape::Reference {
+                    target: Box::new(parse_type(target, syntax)),
+                    is_mutable,
+                });
+            }
+            [("*const ", false), ("*mut ", true)]
+                .into_iter()
+                .find_map(|(prefix, is_mutable)| {
+                    text.strip_prefix(prefix).map(|target| TypeShape::Pointer {
+                        target: Box::new(parse_type(target, syntax)),
+                        is_mutable,
+                    })
+                })
+        }
+        TypeSyntax::Go => text.strip_prefix('*').map(|target| TypeShape::Pointer {
+            target: Box::new(parse_type(target, syntax)),
+            is_mutable: true,
+        }),
+        TypeSyntax::C | TypeSyntax::CSharp => {
+            // `char *const` is a constant pointer, not a pointer to constants
+            let text = text
+                .strip_suffix("const")
+                .filter(|rest| rest.ends_with([' ', '*', '&']))
+                .map_or(text, str::trim_end);
+            ["&&", "&", "*"].into_iter().find_map(|suffix| {
+                let target = text.strip_suffix(suffix)?.trim_end();
+                let target_ref = Box::new(parse_type(target, syntax));
+                let is_mutable = !target.starts_with("const ") && !target.ends_with(" const");
+                Some(if suffix == "*" {
+                    TypeShape::Pointer {
+                        target: target_ref,
+                        is_mutable,
+                    }
+                } else {
+                    TypeShape::Reference {
+                        target: target_ref,
+                        is_mutable,
+                    }
+                })
+            })
+        }
+        _ => None,
+    }
+}
+
+/// Arrays, slices, maps and channels written with brackets or keywords
+///
+/// The element type (or key and value types) goes into `type_args`.
+fn fill_collection(type_ref: &mut TypeRef, text: &str, syntax: TypeSyntax) -> bool {
+    if syntax.has_postfix_arrays() {
+        let mut element = text;
+        let mut dims = 0;
+        if syntax == TypeSyntax::Java
+            && let Some(rest) = element.strip_suffix("...")
+        {
+            element = rest.trim_end();
+            dims += 1;
+        }
+        // `T[]`, `T[][]`, C# `T[,]`, C `T[16]`
+        while let Some(rest) = element.strip_suffix(']')
+            && let Some(open) = rest.rfind('[')
+            && rest[open + 1..]
+                .chars()
+                .all(|c| c == ',' || c.is_whitespace() || c.is_ascii_digit())
+            && open > 0
+        {
+            dims += 1 + rest[open + 1..].matches(',').count();
+            element = rest[..open].trim_end();
+        }
+        if dims > 0 {
+            set_array(type_ref, element, dims, syntax);
+            return true;
+        }
+    }
+
+    match syntax {
+        TypeSyntax::Go => fill_go_collection(type_ref, text),
+        TypeSyntax::Swift | TypeSyntax::Rust | TypeSyntax::TypeScript => {
+            let Some(inner) = text.strip_prefix('[').filter(|inner| closes_at_end(inner)) else {
+                return false;
+            };
+            let inner = &inner[..inner.len() - 1];
+            match syntax {
+                TypeSyntax::TypeScript => {
+                    type_ref.shape = Some(TypeShape::Tuple {
+                        elements: elements(&split_top_level(inner, ","), syntax),
+                    });
+                }
+                TypeSyntax::Swift => {
+                    if let [key, value] = split_top_level(inner, ":").as_slice() {
+                        type_ref.type_args = parse_all(&[*key, *value], syntax);
+                    } else {
+                        set_array(type_ref, inner, 1, syntax);
+                    }
+                }
+                _ => {
+                    // Rust `[T]` and `[T; N]`
+                    let element = split_top_level(inner, ";")[0];
+                    set_array(type_ref, element, 1, syntax);
+                }
+            }
+            true
+        }
+        _ => false,
+    }
+}
+
+/// Go `[]T`, `[N]T`, `...T`, `map[K]V` and channels
+fn fill_go_collection(type_ref: &mut TypeRef, text: &str) -> bool {
+    let syntax = TypeSyntax::Go;
+    if let Some(rest) = text.strip_prefix("map[")
+        && let Some(close) = closing_bracket(rest)
+    {
+        type_ref.type_args = parse_all(&[&rest[..close], &rest[close + 1..]], syntax);
+        return true;
+    }
+    for prefix in ["<-chan ", "chan<- ", "chan "] {
+        if let Some(element) = text.strip_prefix(prefix) {
+            type_ref.type_args = vec![parse_type(element, syntax)];
+            return true;
+        }
+    }
+
+    let mut element = text;
+    let mut dims = 0;
+    if let Some(rest) = element.strip_prefix("...") {
+        element = rest;
+        dims += 1;
+    }
+    while let Some(rest) = element.strip_prefix('[')
+        && let Some(close) = closing_bracket(rest)
+    {
+        element = rest[close + 1..].trim_start();
+        dims += 1;
+    }
+    if dims == 0 || element.is_empty() {
+        return false;
+    }
+    set_array(type_ref, element, dims, syntax);
+    true
+}
+
+fn set_array(type_ref: &mut TypeRef, element: &str, dims: usize, syntax: TypeSyntax) {
+    type_ref.is_array = true;
+    type_ref.array_dims = Some(dims);
+    type_ref.type_args = vec![parse_type(element, syntax)];
+}
+
+/// Generic types: `Map<K, V>`, Python and Go `dict[K, V]`
+fn fill_generic(type_ref: &mut TypeRef, text: &str, syntax: TypeSyntax) {
+    let (open, close) = syntax.generic_brackets();
+    let Some(start) = text.find(open).filter(|&start| start > 0) else {
+        return;
+    };
+    let inner = &text[start + open.len_utf8()..];
+    if !inner.ends_with(close) || !closes_at_end(inner) {
+        return;
+    }
+    let base = text[..start].trim();
+    let args = split_top_level(&inner[..inner.len() - close.len_utf8()], ",");
+
+    if syntax == TypeSyntax::Python {
+        match base.rsplit('.').next().unwrap_or(base) {
+            "Optional" => type_ref.is_nullable = true,
+            "Union" => {
+                type_ref.is_nullable = args.iter().any(|arg| is_null(arg));
+                type_ref.shape = Some(TypeShape::Union {
+                    members: parse_all(&args, syntax),
+                });
+                return;
+            }
+            "Callable" => {
+                let params = args
+                    .first()
+                    .and_then(|params| params.strip_prefix('['))
+                    .and_then(|params| params.strip_suffix(']'))
+                    .map(|params| parse_all(&split_top_level(params, ","), syntax))
+                    .unwrap_or_default();
+                type_ref.shape = Some(TypeShape::Function {
+                    params,
+                    returns: args
+                        .get(1)
+                        .map(|returns| Box::new(parse_type(returns, syntax))),
+                });
+                return;
+            }
+            "tuple" | "Tuple" => {
+                type_ref.shape = Some(TypeShape::Tuple {
+                    elements: parse_all(&args, syntax),
+                });
+                return;
+            }
+            _ => {}
+        }
+    }
+    type_ref.type_args = parse_all(&args, syntax);
+}
+
+/// Parameters of a function type or elements of a tuple, without labels
+fn elements(items: &[&str], syntax: TypeSyntax) -> Vec<TypeRef> {
+    let items: Vec<&str> = items
+        .iter()
+        .map(|item| item.trim())
+        .filter(|item| !item.is_empty())
+        .collect();
+
+    if syntax == TypeSyntax::Go {
+        return go_elements(&items);
+    }
+
+    items
+        .iter()
+        .map(|item| {
+            let mut item = *item;
+            if syntax.has_labels()
+                && let Some(colon) = label_colon(item)
+            {
+                item = &item[colon + 1..];
+            }
+            if syntax == TypeSyntax::CSharp
+                && let Some(&space) = find_top_level(item, " ").last()
+            {
+                // Named tuple elements: `(int Count, string Name)`
+                item = &item[..space];
+            }
+            parse_type(item, syntax)
+        })
+        .collect()
+}
+
+/// Go parameter lists: `(int, error)` or `(a, b int, err error)`
+///
+/// Once any item names its parameter, a bare item is a name sharing the
+/// type of the next item that has one.
+fn go_elements(items: &[&str]) -> Vec<TypeRef> {
+    let named = items
+        .iter()
+        .any(|item| !find_top_level(item, " ").is_empty());
+    if !named {
+        return parse_all(items, TypeSyntax::Go);
+    }
+
+    let mut types = vec![""; items.len()];
+    let mut current = "";
+    for (i, item) in items.iter().enumerate().rev() {
+        if let Some(&space) = find_top_level(item, " ").first() {
+            current = item[space + 1..].trim();
+        }
+        types[i] = current;
+    }
+    parse_all(&types, TypeSyntax::Go)
+}
+
+/// Offset of the `:` ending a `label:`, skipping Rust `::` paths
+fn label_colon(item: &str) -> Option<usize> {
+    find_top_level(item, ":")
+        .into_iter()
+        .find(|&colon| !item[..colon].ends_with(':') && !item[colon + 1..].starts_with(':'))
+}
+
+fn strip_qualifiers(mut text: &str, syntax: TypeSyntax) -> &str {
+    loop {
+        let before = text;
+        for qualifier in syntax.qualifiers() {
+            text = text.strip_prefix(qualifier).unwrap_or(text).trim_start();
+        }
+        if matches!(
+            syntax,
+            TypeSyntax::Java | TypeSyntax::Kotlin | TypeSyntax::Swift
+        ) && let Some(annotation) = text.strip_prefix('@')
+        {
+            // `@Nullable String`, `@escaping (Int) -> Void`, `@Size(max = 3) String`
+            let end = annotation
+                .find(|c: char| !(c.is_alphanumeric() || c == '_' || c == '.'))
+                .unwrap_or(annotation.len());
+            let mut rest = &annotation[end..];
+            if let Some(args) = rest.strip_prefix('(') {
+                rest = closing_bracket(args).map_or("", |close| &args[close + 1..]);
+            }
+            text = rest.trim_start();
+        }
+        if text == before {
+            return text;
+        }
+    }
+}
+
+fn parse_all(items: &[&str], syntax: TypeSyntax) -> Vec<TypeRef> {
+    items.iter().map(|item| parse_type(item, syntax)).collect()
+}
+
+fn is_null(member: &str) -> bool {
+    matches!(member.trim(), "null" | "None" | "undefined" | "NoneType")
+}
+
+/// Byte offsets of `separator` outside any brackets
+///
+/// The `>` of `->` and `=>` and the `<` of Go's `<-chan` are not brackets.
+pub(crate) fn find_top_level(text: &str, separator: &str) -> Vec<usize> {
+    let mut depth = 0usize;
+    let mut found = vec![];
+    for (i, c) in text.char_indices() {
+        if depth == 0 && text[i..].starts_with(separator) {
+            found.push(i);
+        }
+        depth = next_depth(text, i, c, depth).unwrap_or(0);
+    }
+    found
+}
+
+/// Split at `separator` outside brackets, trimming each part
+pub(crate) fn split_top_level<'a>(text: &'a str, separator: &str) -> Vec<&'a str> {
+    let mut parts = vec![];
+    let mut start = 0;
+    for position in find_top_level(text, separator) {
+        // Overlapping matches, as in `||`
+        if position < start {
+            continue;
+        }
+        parts.push(text[start..position].trim());
+        start = position + separator.len();
+    }
+    parts.push(text[start..].trim());
+    parts
+}
+
+/// Offset of the bracket closing the one opened just before `text`
+pub(crate) fn closing_bracket(text: &str) -> Option<usize> {
+    let mut depth = 0usize;
+    for (i, c) in text.char_indices() {
+        match next_depth(text, i, c, depth) {
+            Some(next) => depth = next,
+            None => return Some(i),
+        }
+    }
+    None
+}
+
+/// Whether the bracket opened just before `text` closes at its last character
+fn closes_at_end(text: &str) -> bool {
+    closing_bracket(text).is_some_and(|close| close + 1 == text.len())
+}
+
+/// Bracket depth after the character at `i`, or `None` when it closes a
+/// bracket opened before `text`
+fn next_depth(text: &str, i: usize, c: char, depth: usize) -> Option<usize> {
+    match c {
+        '<' if text[i + 1..].starts_with('-') => Some(depth),
+        '(' | '[' | '{' | '<' => Some(depth + 1),
+        '>' if i > 0 && matches!(text.as_bytes()[i - 1], b'-' | b'=') => Some(depth),
+        ')' | ']' | '}' | '>' => depth.checked_sub(1),
+        _ => Some(depth),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn names(refs: &[TypeRef]) -> Vec<&str> {
+        refs.iter().map(|r| r.name.as_str()).collect()
+    }
+
+    #[test]
+    fn test_generic_arguments() {
+        let map = parse_type("Map<String, List<User>>", TypeSyntax::Java);
+        assert_eq!(map.name, "Map<String, List<User>>");
+        assert_eq!(names(&map.type_args), ["String", "List<User>"]);
+        assert_eq!(names(&map.type_args[1].type_args), ["User"]);
+        assert!(map.shape.is_none());
+
+        let optional = parse_type("Optional[dict[str, Any]]", TypeSyntax::Python);
+        assert!(optional.is_nullable);
+        assert_eq!(names(&optional.type_args), ["dict[str, Any]"]);
+        assert_eq!(names(&optional.type_args[0].type_args), ["str", "Any"]);
+
+        let plain = parse_type("User", TypeSyntax::Go);
+        assert_eq!(plain, TypeRef::new("User"));
+    }
+
+    #[test]
+    fn test_unions_and_intersections() {
+        let union = parse_type("A | B | null", TypeSyntax::TypeScript);
+        assert!(union.is_nullable);
+        let Some(TypeShape::Union { members }) = &union.shape else {
+            panic!("Expected union, got {:?}", union.shape);
+        };
+        assert_eq!(names(members), ["A", "B", "null"]);
+
+        let python = parse_type("str | None", TypeSyntax::Python);
+        assert!(python.is_nullable);
+
+        let both = parse_type("Named & Aged", TypeSyntax::TypeScript);
+        assert!(
+            matches!(&both.shape, Some(TypeShape::Intersection { members }) if members.len() == 2)
+        );
+
+        let bounds = parse_type("dyn Error + Send + Sync", TypeSyntax::Rust);
+        assert!(
+            matches!(&bounds.shape, Some(TypeShape::Intersection { members }) if names(members) == ["Error", "Send", "Sync"])
+        );
+    }
+
+    #[test]
+    fn test_function_types() {
+        let cases = [
+            (
+                "(x: number, y?: string) => void",
+                TypeSyntax::TypeScript,
+                vec!["number", "string"],
+                Some("void"),
+            ),
+            (
+                "(Int) -> Unit",
+                TypeSyntax::Kotlin,
+                vec!["Int"],
+                Some("Unit"),
+            ),
+            (
+                "suspend String.(Int) -> Boolean",
+                TypeSyntax::Kotlin,
+                vec!["Int"],
+                Some("Boolean"),
+            ),
+            (
+                "@escaping (Int) async throws -> Int",
+                TypeSyntax::Swift,
+                vec!["Int"],
+                Some("Int"),
+            ),
+            (
+                "fn(i32, &str) -> bool",
+                TypeSyntax::Rust,
+                vec!["i32", "&str"],
+                Some("bool"),
+            ),
+            ("FnMut(u8)", TypeSyntax::Rust, vec!["u8"], None),
+            (
+                "func(a, b int, s string) (int, error)",
+                TypeSyntax::Go,
+                vec!["int", "int", "string"],
+                Some("(int, error)"),
+            ),
+            (
+                "Callable[[int, str], bool]",
+                TypeSyntax::Python,
+                vec!["int", "str"],
+                Some("bool"),
+            ),
+            (
+                "void(int, char)",
+                TypeSyntax::C,
+                vec!["int", "char"],
+                Some("void"),
+            ),
+        ];
+        for (text, syntax, params, returns) in cases {
+            let parsed = parse_type(text, syntax);
+            let Some(TypeShape::Function {
+                params: parsed_params,
+                returns: parsed_returns,
+            }) = &parsed.shape
+            else {
+                panic!("Expected function type for {text}, got {:?}", parsed.shape);
+            };
+            assert_eq!(names(parsed_params), params, "{text}");
+            assert_eq!(
+                parsed_returns.as_ref().map(|r| r.name.as_str()),
+                returns,
+                "{text}"
+            );
+        }
+
+        // `A | B` belongs to the return type
+        let parsed = parse_type("() => A | B", TypeSyntax::TypeScript);
+        let Some(TypeShape::Function {
+            returns: Some(returns),
+            ..
+        }) = &parsed.shape
+        else {
+            panic!("Expected function type");
+        };
+        assert!(matches!(returns.shape, Some(TypeShape::Union { .. })));
+    }
+
+    #[test]
+    fn test_tuples() {
+        for (text, syntax) in [
+            ("(i32, String)", TypeSyntax::Rust),
+            ("(x: Int, y: String)", TypeSyntax::Swift),
+            ("(int Count, string Name)", TypeSyntax::CSharp),
+            ("[number, string]", TypeSyntax::TypeScript),
+            ("tuple[int, str]", TypeSyntax::Python),
+        ] {
+            let parsed = parse_type(text, syntax);
+            let Some(TypeShape::Tuple { elements }) = &parsed.shape else {
+                panic!("Expected tuple for {text}, got {:?}", parsed.shape);
+            };
+            assert_eq!(elements.len(), 2, "{text}");
+        }
+
+        let grouped = parse_type("(A | B)", TypeSyntax::TypeScript);
+        assert!(matches!(grouped.shape, Some(TypeShape::Union { .. })));
+        assert!(matches!(
+            parse_type("()", TypeSyntax::Rust).shape,
+            Some(TypeShape::Tuple { elements }) if elements.is_empty()
+        ));
+    }
+
+    #[test]
+    fn test_pointers_and_references() {
+        let pointer = parse_type("*const T", TypeSyntax::Rust);
+        assert!(
+            matches!(&pointer.shape, Some(TypeShape::Pointer { target, is_mutable: false }) if target.name == "T")
+        );
+
+        let reference = parse_type("&'a mut [u8]", TypeSyntax::Rust);
+        let Some(TypeShape::Reference { target, is_mutable }) = &reference.shape else {
+            panic!("Expected reference");
+        };
+        assert!(is_mutable);
+        assert!(target.is_array);
+        assert_eq!(names(&target.type_args), ["u8"]);
+
+        let go = parse_type("*Config", TypeSyntax::Go);
+        assert!(
+            matches!(&go.shape, Some(TypeShape::Pointer { target, is_mutable: true }) if target.name == "Config")
+        );
+
+        let c = parse_type("const char *", TypeSyntax::C);
+        assert!(
+            matches!(&c.shape, Some(TypeShape::Pointer { target, is_mutable: false }) if target.name == "const char")
+        );
+
+        let cpp = parse_type("const std::vector<int>&", TypeSyntax::C);
+        let Some(TypeShape::Reference {
+            target,
+            is_mutable: false,
+        }) = &cpp.shape
+        else {
+            panic!("Expected reference, got {:?}", cpp.shape);
+        };
+        assert_eq!(names(&target.type_args), ["int"]);
+    }
+
+    #[test]
+    fn test_arrays_maps_and_nullables() {
+        let nested = parse_type("[]map[string]int", TypeSyntax::Go);
+        assert!(nested.is_array);
+        assert_eq!(nested.array_dims, Some(1));
+        assert_eq!(names(&nested.type_args), ["map[string]int"]);
+        assert_eq!(names(&nested.type_args[0].type_args), ["string", "int"]);
+
+        let matrix = parse_type("int[][]", TypeSyntax::Java);
+        assert_eq!(
+            (matrix.array_dims, names(&matrix.type_args)),
+            (Some(2), vec!["int"])
+        );
+        let grid = parse_type("double[,]", TypeSyntax::CSharp);
+        assert_eq!(grid.array_dims, Some(2));
+        let varargs = parse_type("String...", TypeSyntax::Java);
+        assert!(varargs.is_array);
+
+        let dictionary = parse_type("[String: Int]", TypeSyntax::Swift);
+        assert!(!dictionary.is_array);
+        assert_eq!(names(&dictionary.type_args), ["String", "Int"]);
+
+        let kotlin = parse_type("List<String>?", TypeSyntax::Kotlin);
+        assert!(kotlin.is_nullable);
+        assert_eq!(names(&kotlin.type_args), ["String"]);
+        let php = parse_type("?array", TypeSyntax::Php);
+        assert!(php.is_nullable);
+        let nullable_function = parse_type("((Int) -> Unit)?", TypeSyntax::Kotlin);
+        assert!(nullable_function.is_nullable);
+        assert!(matches!(
+            nullable_function.shape,
+            Some(TypeShape::Function { .. })
+        ));
+    }
+
+    #[test]
+    fn test_nested_arrows_are_not_brackets() {
+        let boxed = parse_type("Box<dyn Fn(i32) -> i32>", TypeSyntax::Rust);
+        assert_eq!(boxed.type_args.len(), 1);
+        assert!(matches!(
+            boxed.type_args[0].shape,
+            Some(TypeShape::Function { .. })
+        ));
+
+        let channel = parse_type("<-chan Event", TypeSyntax::Go);
+        assert_eq!(names(&channel.type_args), ["Event"]);
+    }
+}
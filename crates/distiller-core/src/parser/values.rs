@@ -0,0 +1,56 @@
+//! Value previews for variable and constant initializers
+//!
+//! Processors keep initializers of module-level variables as a short one-line
+//! preview: enough to show `MAX_RETRIES = 5` or `DEFAULT_URL = "https://..."`
+//! without pulling whole object literals or lookup tables into the output.
+
+/// Maximum preview length in characters, excluding the ellipsis
+pub const MAX_PREVIEW_CHARS: usize = 80;
+
+/// Collapse an initializer into a one-line preview
+///
+/// Whitespace runs (including newlines) become single spaces, and long
+/// values are cut at `MAX_PREVIEW_CHARS` with a trailing `...`.
+/// Returns `None` for empty initializers.
+#[must_use]
+pub fn value_preview(text: &str) -> Option<String> {
+    let collapsed = text.split_whitespace().collect::<Vec<_>>().join(" ");
+    if collapsed.is_empty() {
+        return None;
+    }
+
+    if collapsed.chars().count() <= MAX_PREVIEW_CHARS {
+        return Some(collapsed);
+    }
+
+    let cut: String = collapsed.chars().take(MAX_PREVIEW_CHARS).collect();
+    Some(format!("{}...", cut.trim_end()))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_short_values_unchanged() {
+        assert_eq!(value_preview("42").as_deref(), Some("42"));
+        assert_eq!(
+            value_preview("\"https://example.com\"").as_deref(),
+            Some("\"https://example.com\"")
+        );
+        assert_eq!(value_preview("  \n "), None);
+    }
+
+    #[test]
+    fn test_multiline_and_long_values() {
+        assert_eq!(
+            value_preview("{\n    retries: 3,\n    timeout: 30,\n}").as_deref(),
+            Some("{ retries: 3, timeout: 30, }")
+        );
+
+        let long = format!("[{}]", "1, ".repeat(60));
+        let preview = value_preview(&long).unwrap();
+        assert!(preview.ends_with("..."));
+        assert_eq!(preview.chars().count(), MAX_PREVIEW_CHARS + 3);
+    }
+}
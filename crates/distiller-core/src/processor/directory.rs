@@ -1,16 +1,20 @@
 //! Directory processing with rayon parallelism
 //!
 //! Processes entire directory trees in parallel while maintaining file order.
-//! Respects .gitignore patterns and provides progress tracking.
+//! Respects .gitignore and .aidignore patterns and provides progress tracking.
 
+use super::inputs::display_path;
+use super::skipped::{AIDIGNORE, IgnoreRules, SkipReason, SkippedPath};
 use crate::{
-    ProcessOptions,
+    ProcessOptions, encoding,
     error::{DistilError, Result},
     ir::{Directory, File, Node},
+    test_filter::{self, TestMode},
 };
 use glob::Pattern;
 use ignore::WalkBuilder;
 use rayon::prelude::*;
+use std::collections::{BTreeMap, HashSet};
 use std::path::{Path, PathBuf};
 use std::sync::Arc;
 
@@ -56,10 +60,10 @@ impl DirectoryProcessor {
         let path = path.as_ref();
 
         if !path.is_dir() {
-            return Err(DistilError::InvalidConfig(format!(
-                "Path is not a directory: {}",
-                path.display()
-            )));
+            return Err(DistilError::invalid_config_at(
+                path,
+                "Path is not a directory",
+            ));
         }
 
         // Collect files to process
@@ -75,51 +79,126 @@ impl DirectoryProcessor {
         })
     }
 
-    /// Check if a file path matches include/exclude patterns
-    fn should_include_file(&self, path: &Path) -> bool {
+    /// Process several files and directories as one list of files
+    ///
+    /// Directories are walked as by [`process`](Self::process); listed
+    /// files go through the same include/exclude, test and binary filters.
+    /// Files no language processor handles are skipped with a warning, as
+    /// lists like `git ls-files` output carry READMEs and assets. A file
+    /// reached twice is processed once, at its first position. Paths in the
+    /// result are relative to `base` when under it and absolute otherwise,
+    /// however each input was spelled.
+    ///
+    /// # Errors
+    /// * If a path doesn't exist or a directory isn't readable
+    /// * If any file fails to parse
+    pub fn process_paths(
+        &self,
+        paths: &[PathBuf],
+        base: &Path,
+        language_registry: &LanguageRegistry,
+    ) -> Result<Vec<File>> {
+        let base = base.canonicalize().map_err(|e| DistilError::io(base, e))?;
+
+        let mut seen = HashSet::new();
+        let mut files = Vec::new();
+        for path in paths {
+            let found = if path.is_dir() {
+                self.discover_files(path)?
+                    .into_iter()
+                    .map(|(file, _)| file)
+                    .collect()
+            } else if path.is_file() {
+                if let Some(reason) = self.filter_reason(&base, path) {
+                    log::debug!("Skipping {}: {reason}", path.display());
+                    continue;
+                }
+                vec![path.clone()]
+            } else {
+                return Err(DistilError::FileNotFound { path: path.clone() });
+            };
+
+            for file in found {
+                if language_registry
+                    .find_processor(&file, &self.options.language_map)
+                    .is_none()
+                {
+                    log::warn!("Skipping {}: unsupported language", file.display());
+                    continue;
+                }
+                let resolved = file.canonicalize().map_err(|e| DistilError::io(&file, e))?;
+                if seen.insert(resolved.clone()) {
+                    files.push((resolved, files.len()));
+                }
+            }
+        }
+
+        let mut results = self.process_files(&files, language_registry)?;
+        for file in &mut results {
+            file.path = display_path(Path::new(&file.path), &base);
+        }
+        Ok(results)
+    }
+
+    /// Why the include/exclude patterns, test filter or binary check drop
+    /// `path`
+    fn filter_reason(&self, root: &Path, path: &Path) -> Option<SkipReason> {
         let path_str = path.to_string_lossy();
+        let matches = |pattern: &String| {
+            Pattern::new(pattern)
+                .map(|p| p.matches(&path_str))
+                .unwrap_or(false)
+        };
 
         // If include patterns are specified, file must match at least one
-        if !self.options.include_patterns.is_empty() {
-            let matches_include = self.options.include_patterns.iter().any(|pattern| {
-                Pattern::new(pattern)
-                    .map(|p| p.matches(&path_str))
-                    .unwrap_or(false)
-            });
-            if !matches_include {
-                return false;
-            }
+        if !self.options.include_patterns.is_empty()
+            && !self.options.include_patterns.iter().any(matches)
+        {
+            return Some(SkipReason::NotIncluded);
         }
 
         // If exclude patterns are specified, file must not match any
-        if !self.options.exclude_patterns.is_empty() {
-            let matches_exclude = self.options.exclude_patterns.iter().any(|pattern| {
-                Pattern::new(pattern)
-                    .map(|p| p.matches(&path_str))
-                    .unwrap_or(false)
-            });
-            if matches_exclude {
-                return false;
-            }
+        if let Some(pattern) = self.options.exclude_patterns.iter().find(|p| matches(p)) {
+            return Some(SkipReason::Excluded(pattern.clone()));
+        }
+
+        // Skip test files early so they're never parsed
+        let relative = path.strip_prefix(root).unwrap_or(path);
+        if self.options.tests == TestMode::Exclude && test_filter::is_test_file(relative) {
+            return Some(SkipReason::TestFile);
+        }
+
+        // Last, as it reads the start of the file
+        if encoding::is_binary_file(path) {
+            return Some(SkipReason::Binary);
         }
 
-        true
+        None
+    }
+
+    /// Walker depth limit for the `recursive` option
+    fn max_depth(&self) -> Option<usize> {
+        if self.options.recursive {
+            None
+        } else {
+            Some(1)
+        }
     }
 
-    /// Discover files in directory respecting .gitignore
+    /// Discover files in directory respecting .gitignore and .aidignore
     fn discover_files(&self, root: &Path) -> Result<Vec<(PathBuf, usize)>> {
         let mut builder = WalkBuilder::new(root);
 
         // Configure walker
         builder
-            .standard_filters(true) // Respect .gitignore, .ignore, .git/info/exclude
+            .standard_filters(self.options.use_ignore_files) // .gitignore, .ignore, .git/info/exclude
             .hidden(false) // Include hidden files (for now)
             .follow_links(false) // Don't follow symlinks (avoid cycles)
-            .max_depth(if self.options.recursive {
-                None
-            } else {
-                Some(1)
-            });
+            .max_depth(self.max_depth());
+        if self.options.use_ignore_files {
+            // Takes precedence over .gitignore, so `!pattern` re-includes
+            builder.add_custom_ignore_filename(AIDIGNORE);
+        }
 
         // Build walker and collect files
         let walker = builder.build();
@@ -128,12 +207,20 @@ impl DirectoryProcessor {
         let mut index = 0;
 
         for entry in walker {
-            let entry = entry.map_err(|e| DistilError::Io(std::io::Error::other(e.to_string())))?;
+            let entry = entry.map_err(|e| DistilError::WalkDir {
+                path: root.to_path_buf(),
+                message: e.to_string(),
+            })?;
 
             let path = entry.path();
 
-            // Only process regular files that match include/exclude patterns
-            if path.is_file() && self.should_include_file(path) {
+            // Only process regular files that pass the pattern and test filters
+            if path.is_file() {
+                if let Some(reason) = self.filter_reason(root, path) {
+                    log::debug!("Skipping {}: {reason}", path.display());
+                    continue;
+                }
+
                 files.push((path.to_path_buf(), index));
                 index += 1;
             }
@@ -142,6 +229,79 @@ impl DirectoryProcessor {
         Ok(files)
     }
 
+    /// List the paths under `root` that discovery leaves out, and why
+    ///
+    /// A directory dropped by an ignore rule is listed once, without its
+    /// contents.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the directory can't be walked.
+    pub fn skipped_files<P: AsRef<Path>>(&self, root: P) -> Result<Vec<SkippedPath>> {
+        let root = root.as_ref();
+        let kept: HashSet<PathBuf> = self
+            .discover_files(root)?
+            .into_iter()
+            .map(|(path, _)| path)
+            .collect();
+        // Ignore files are matched against absolute paths
+        let absolute = root.canonicalize().map_err(|e| DistilError::io(root, e))?;
+
+        let mut builder = WalkBuilder::new(root);
+        builder
+            .standard_filters(false)
+            .hidden(false)
+            .follow_links(false)
+            .max_depth(self.max_depth())
+            .filter_entry(|entry| entry.file_name() != ".git");
+
+        let mut rules = IgnoreRules::default();
+        let mut skipped: Vec<SkippedPath> = Vec::new();
+        for entry in builder.build() {
+            let entry = entry.map_err(|e| DistilError::WalkDir {
+                path: root.to_path_buf(),
+                message: e.to_string(),
+            })?;
+
+            let path = entry.path();
+            let is_dir = path.is_dir();
+            if entry.depth() == 0
+                || kept.contains(path)
+                || skipped
+                    .iter()
+                    .any(|s| s.is_dir && path.starts_with(&s.path))
+            {
+                continue;
+            }
+
+            let ignored = if self.options.use_ignore_files {
+                let relative = path.strip_prefix(root).unwrap_or(path);
+                rules.explain(&absolute.join(relative), is_dir)
+            } else {
+                None
+            };
+            let reason = if is_dir {
+                // Directories are only ever dropped by ignore rules
+                let Some(reason) = ignored else { continue };
+                reason
+            } else if !path.is_file() {
+                continue;
+            } else {
+                ignored
+                    .or_else(|| self.filter_reason(root, path))
+                    .unwrap_or(SkipReason::IgnoredElsewhere)
+            };
+
+            skipped.push(SkippedPath {
+                path: path.to_path_buf(),
+                is_dir,
+                reason,
+            });
+        }
+
+        Ok(skipped)
+    }
+
     /// Process files in parallel using rayon
     fn process_files(
         &self,
@@ -193,22 +353,24 @@ impl DirectoryProcessor {
         opts: &ProcessOptions,
     ) -> Result<File> {
         // Find processor for this file
-        let processor = language_registry.find_processor(path).ok_or_else(|| {
-            DistilError::UnsupportedLanguage {
-                path: path.display().to_string(),
-                lang: path
-                    .extension()
-                    .and_then(|s| s.to_str())
-                    .unwrap_or("unknown")
-                    .to_string(),
-            }
-        })?;
+        let processor = language_registry
+            .find_processor(path, &opts.language_map)
+            .ok_or_else(|| {
+                DistilError::unsupported_language(
+                    path,
+                    path.extension()
+                        .and_then(|s| s.to_str())
+                        .unwrap_or("unknown"),
+                )
+            })?;
 
         // Read file
-        let source = std::fs::read_to_string(path).map_err(DistilError::Io)?;
+        let source = encoding::read_source(path, &opts.fallback_encodings)?;
 
         // Process with language-specific processor
-        processor.process(&source, path, opts)
+        processor
+            .process(&source, path, opts)
+            .map_err(|e| e.with_path(path))
     }
 }
 
@@ -233,11 +395,38 @@ impl LanguageRegistry {
         self.processors.push(processor);
     }
 
+    /// Language name of the processor for this file, if any
+    #[must_use]
+    pub fn language_for(
+        &self,
+        path: &Path,
+        language_map: &BTreeMap<String, String>,
+    ) -> Option<&'static str> {
+        self.find_processor(path, language_map)
+            .map(super::language::LanguageProcessor::language)
+    }
+
     /// Find a processor that can handle this file
+    ///
+    /// An entry for the file's extension in `language_map` picks the
+    /// processor by language name instead.
     pub(crate) fn find_processor(
         &self,
         path: &Path,
+        language_map: &BTreeMap<String, String>,
     ) -> Option<&dyn super::language::LanguageProcessor> {
+        if let Some(language) = path
+            .extension()
+            .and_then(|ext| ext.to_str())
+            .and_then(|ext| language_map.get(ext))
+        {
+            return self
+                .processors
+                .iter()
+                .find(|p| p.language().eq_ignore_ascii_case(language))
+                .map(AsRef::as_ref);
+        }
+
         for processor in &self.processors {
             if processor.can_process(path) {
                 return Some(processor.as_ref());
@@ -245,6 +434,22 @@ impl LanguageRegistry {
         }
         None
     }
+
+    /// Find the processor for a language name (`typescript`) or one of its
+    /// extensions (`ts`)
+    pub(crate) fn find_language(
+        &self,
+        name: &str,
+    ) -> Option<&dyn super::language::LanguageProcessor> {
+        let name = name.trim().trim_start_matches('.').to_ascii_lowercase();
+        self.processors
+            .iter()
+            .find(|p| {
+                p.language().eq_ignore_ascii_case(&name)
+                    || p.supported_extensions().contains(&name.as_str())
+            })
+            .map(AsRef::as_ref)
+    }
 }
 
 impl Default for LanguageRegistry {
@@ -268,7 +473,7 @@ mod tests {
     #[test]
     fn test_registry_creation() {
         let registry = LanguageRegistry::new();
-        let result = registry.find_processor(Path::new("test.py"));
+        let result = registry.find_processor(Path::new("test.py"), &BTreeMap::new());
 
         assert!(result.is_none()); // No processors registered yet
     }
@@ -285,5 +490,132 @@ mod tests {
         assert!(result.is_err());
     }
 
+    #[test]
+    fn test_filter_reasons() {
+        let processor = DirectoryProcessor::new(ProcessOptions {
+            exclude_patterns: vec!["*/gen/*".to_string()],
+            tests: TestMode::Exclude,
+            ..Default::default()
+        });
+        let root = Path::new("proj");
+
+        assert_eq!(
+            processor.filter_reason(root, Path::new("proj/gen/api.go")),
+            Some(SkipReason::Excluded("*/gen/*".to_string()))
+        );
+        assert_eq!(
+            processor.filter_reason(root, Path::new("proj/api_test.go")),
+            Some(SkipReason::TestFile)
+        );
+        assert_eq!(
+            processor.filter_reason(root, Path::new("proj/api.go")),
+            None
+        );
+    }
+
+    #[test]
+    fn test_skipped_files() {
+        let root = std::env::temp_dir().join(format!("aid-skip-{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        std::fs::create_dir_all(&root).unwrap();
+        std::fs::write(root.join("main.py"), "").unwrap();
+        std::fs::write(root.join("test_main.py"), "").unwrap();
+
+        let processor = DirectoryProcessor::new(ProcessOptions {
+            tests: TestMode::Exclude,
+            ..Default::default()
+        });
+        let skipped = processor.skipped_files(&root).unwrap();
+        assert_eq!(skipped.len(), 1);
+        assert_eq!(skipped[0].path, root.join("test_main.py"));
+        assert_eq!(skipped[0].reason, SkipReason::TestFile);
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+
+    /// Processor that yields an empty file for `.py` paths
+    struct EmptyFiles;
+
+    impl super::super::LanguageProcessor for EmptyFiles {
+        fn language(&self) -> &'static str {
+            "python"
+        }
+
+        fn supported_extensions(&self) -> &'static [&'static str] {
+            &["py"]
+        }
+
+        fn process(&self, _source: &str, path: &Path, _opts: &ProcessOptions) -> Result<File> {
+            Ok(File {
+                path: path.to_string_lossy().into_owned(),
+                children: Vec::new(),
+            })
+        }
+    }
+
+    #[test]
+    fn test_process_paths() {
+        let root = std::env::temp_dir().join(format!("aid-paths-{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        std::fs::create_dir_all(root.join("src/api")).unwrap();
+        std::fs::create_dir_all(root.join("lib")).unwrap();
+        std::fs::write(root.join("src/api/users.py"), "").unwrap();
+        std::fs::write(root.join("lib/util.py"), "").unwrap();
+        std::fs::write(root.join("lib/util_test.py"), "").unwrap();
+        std::fs::write(root.join("lib/gen.py"), "").unwrap();
+        std::fs::write(root.join("lib/blob.py"), b"\x00\x01\x02").unwrap();
+        std::fs::write(root.join("README.md"), "# Readme\n").unwrap();
+
+        let mut registry = LanguageRegistry::new();
+        registry.register(Box::new(EmptyFiles));
+        let processor = DirectoryProcessor::new(ProcessOptions {
+            exclude_patterns: vec!["*/gen.py".to_string()],
+            tests: TestMode::Exclude,
+            ..ProcessOptions::default()
+        });
+        let paths = [
+            root.join("src"),
+            root.join("lib/util.py"),
+            root.join("src/api/../api/users.py"),
+            // Listed files are filtered and unsupported ones skipped
+            root.join("lib/util_test.py"),
+            root.join("lib/gen.py"),
+            root.join("lib/blob.py"),
+            root.join("README.md"),
+        ];
+        let files = processor.process_paths(&paths, &root, &registry).unwrap();
+
+        let names: Vec<&str> = files.iter().map(|f| f.path.as_str()).collect();
+        assert_eq!(names, ["src/api/users.py", "lib/util.py"]);
+
+        let missing = processor.process_paths(&[root.join("nope.py")], &root, &registry);
+        assert!(matches!(missing, Err(DistilError::FileNotFound { .. })));
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+
+    #[test]
+    fn test_binary_and_legacy_files() {
+        let root = std::env::temp_dir().join(format!("aid-encoding-{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        std::fs::create_dir_all(&root).unwrap();
+        std::fs::write(root.join("blob.py"), b"\x00\x01\x02").unwrap();
+        std::fs::write(root.join("legacy.py"), b"# \x93quoted\x94\n").unwrap();
+
+        let processor = DirectoryProcessor::new(ProcessOptions::default());
+        let skipped = processor.skipped_files(&root).unwrap();
+        assert_eq!(skipped.len(), 1);
+        assert_eq!(skipped[0].path, root.join("blob.py"));
+        assert_eq!(skipped[0].reason, SkipReason::Binary);
+
+        // Windows-1252 is decoded rather than failing the run
+        let mut registry = LanguageRegistry::new();
+        registry.register(Box::new(EmptyFiles));
+        let directory = processor.process(&root, &registry).unwrap();
+        assert_eq!(directory.children.len(), 1);
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+
     // Integration tests will be added when we have actual language processors
 }
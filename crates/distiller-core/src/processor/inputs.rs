@@ -0,0 +1,112 @@
+//! Input paths for one run
+//!
+//! A run can cover several files and directories, given as arguments or
+//! read from a file list (`--files-from`). Lists hold one path per line,
+//! or NUL-separated paths (`git ls-files -z`, `find -print0`) when the
+//! list contains a NUL byte.
+
+use crate::error::{DistilError, Result};
+use std::io::Read;
+use std::path::{Path, PathBuf};
+
+/// File list name that reads from stdin
+pub const STDIN: &str = "-";
+
+/// Paths in a file list, in order, skipping blank entries
+#[must_use]
+pub fn parse_file_list(list: &[u8]) -> Vec<PathBuf> {
+    let list = String::from_utf8_lossy(list);
+    let separator = if list.contains('\0') { '\0' } else { '\n' };
+    list.split(separator)
+        .map(|entry| entry.strip_suffix('\r').unwrap_or(entry))
+        .filter(|entry| !entry.trim().is_empty())
+        .map(PathBuf::from)
+        .collect()
+}
+
+/// Read the file list at `source`, or stdin for `-`
+///
+/// # Errors
+///
+/// Returns an error if the list can't be read.
+pub fn read_file_list(source: &Path) -> Result<Vec<PathBuf>> {
+    let mut list = Vec::new();
+    if source == Path::new(STDIN) {
+        std::io::stdin()
+            .read_to_end(&mut list)
+            .map_err(|e| DistilError::io("<stdin>", e))?;
+    } else {
+        list = std::fs::read(source).map_err(|e| DistilError::io(source, e))?;
+    }
+    Ok(parse_file_list(&list))
+}
+
+/// Deepest directory (or the file itself) containing every path
+///
+/// Paths are resolved first, so `src/api` and `./src/models` meet at
+/// `src`. `None` for no paths or paths that can't be resolved.
+#[must_use]
+pub fn common_ancestor(paths: &[PathBuf]) -> Option<PathBuf> {
+    let mut resolved = paths.iter().map(|path| path.canonicalize().ok());
+    let mut ancestor = resolved.next()??;
+    for path in resolved {
+        let path = path?;
+        while !path.starts_with(&ancestor) {
+            ancestor = ancestor.parent()?.to_path_buf();
+        }
+    }
+    Some(ancestor)
+}
+
+/// `path` relative to `base` when it lies under it, unchanged otherwise
+///
+/// Both are expected to be resolved (absolute, no `..`).
+pub(crate) fn display_path(path: &Path, base: &Path) -> String {
+    path.strip_prefix(base)
+        .unwrap_or(path)
+        .to_string_lossy()
+        .into_owned()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_parse_file_list() {
+        assert_eq!(
+            parse_file_list(b"src/a.py\r\n\nlib/b.go\n"),
+            vec![PathBuf::from("src/a.py"), PathBuf::from("lib/b.go")]
+        );
+        // NUL-separated lists keep newlines and spaces inside names
+        assert_eq!(
+            parse_file_list(b"odd\nname.py\0with space.go\0"),
+            vec![
+                PathBuf::from("odd\nname.py"),
+                PathBuf::from("with space.go")
+            ]
+        );
+        assert!(parse_file_list(b"").is_empty());
+    }
+
+    #[test]
+    fn test_common_ancestor() {
+        let root = std::env::temp_dir().join(format!("aid-inputs-{}", std::process::id()));
+        std::fs::create_dir_all(root.join("src/api")).unwrap();
+        std::fs::create_dir_all(root.join("src/models")).unwrap();
+        std::fs::write(root.join("src/api/users.py"), "").unwrap();
+        let root = root.canonicalize().unwrap();
+
+        let paths = [root.join("src/api"), root.join("src/models/../models")];
+        assert_eq!(common_ancestor(&paths), Some(root.join("src")));
+        let file = [root.join("src/api/users.py")];
+        assert_eq!(common_ancestor(&file), Some(root.join("src/api/users.py")));
+        assert_eq!(common_ancestor(&[root.join("missing")]), None);
+        assert_eq!(
+            display_path(&root.join("src/api/users.py"), &root),
+            "src/api/users.py"
+        );
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+}
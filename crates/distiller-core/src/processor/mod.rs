@@ -1,19 +1,24 @@
 //! File and directory processing
 //!
 //! The processor is responsible for:
-//! - Walking directories with .gitignore support
+//! - Walking directories with .gitignore and .aidignore support
 //! - Detecting file languages
 //! - Dispatching to language processors
 //! - Parallel processing with rayon
 
 pub mod directory;
+pub mod inputs;
 pub mod language;
+pub mod skipped;
 
 pub use directory::{DirectoryProcessor, LanguageRegistry};
 pub use language::LanguageProcessor;
+pub use skipped::{SkipReason, SkippedPath};
 
-use crate::{ProcessOptions, Result, ir::Node};
-use std::path::Path;
+use crate::error::DistilError;
+use crate::ir::{Directory, Node};
+use crate::{ProcessOptions, Result, canonical, encoding, type_merge};
+use std::path::{Path, PathBuf};
 
 /// Main processor for files and directories
 pub struct Processor {
@@ -45,50 +50,127 @@ impl Processor {
     /// Process a file or directory
     ///
     /// Automatically detects whether the path is a file or directory
-    /// and dispatches to the appropriate processor.
+    /// and dispatches to the appropriate processor. Type fragments are
+    /// merged according to `merge_types` and type references are classified
+    /// (see `canonical`) before the IR is returned.
     ///
     /// # Errors
     ///
     /// Returns an error if the path does not exist, is not accessible, or processing fails.
     pub fn process_path(&self, path: &Path) -> Result<Node> {
-        if path.is_dir() {
+        let mut node = if path.is_dir() {
             // Process directory
             let dir_processor = DirectoryProcessor::new(self.options.clone());
             let directory = dir_processor.process(path, &self.language_registry)?;
-            Ok(Node::Directory(directory))
+            Node::Directory(directory)
         } else if path.is_file() {
             // Process single file
             let file = self.process_single_file(path)?;
-            Ok(Node::File(file))
+            Node::File(file)
         } else {
-            Err(crate::error::DistilError::InvalidConfig(format!(
-                "Path does not exist or is not accessible: {}",
-                path.display()
-            )))
+            return Err(crate::error::DistilError::FileNotFound {
+                path: path.to_path_buf(),
+            });
+        };
+
+        type_merge::merge_types(&mut node, self.options.merge_types);
+        canonical::canonicalize(&mut node);
+        Ok(node)
+    }
+
+    /// Process several files and directories as one tree
+    ///
+    /// Files are gathered from `paths` in order and de-duplicated (see
+    /// [`DirectoryProcessor::process_paths`]); their paths are relative to
+    /// `base_path`, or to the current directory if it isn't set. The result
+    /// is a directory node for that base, merged and classified as by
+    /// [`process_path`](Self::process_path).
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if a path does not exist, is not accessible, or processing fails.
+    pub fn process_paths(&self, paths: &[PathBuf]) -> Result<Node> {
+        let base = match &self.options.base_path {
+            Some(base) => base.clone(),
+            None => std::env::current_dir().map_err(|e| DistilError::io(".", e))?,
+        };
+        let dir_processor = DirectoryProcessor::new(self.options.clone());
+        let files = dir_processor.process_paths(paths, &base, &self.language_registry)?;
+
+        let mut node = Node::Directory(Directory {
+            path: base.to_string_lossy().into_owned(),
+            children: files.into_iter().map(Node::File).collect(),
+        });
+        type_merge::merge_types(&mut node, self.options.merge_types);
+        canonical::canonicalize(&mut node);
+        Ok(node)
+    }
+
+    /// Process source code that isn't read from a file, such as stdin
+    ///
+    /// `path` is only shown in the output and, without `language`, picks the
+    /// language processor; it need not exist. `language` is a language name
+    /// (`typescript`) or one of its extensions (`ts`). The file node is
+    /// merged and classified as by [`process_path`](Self::process_path).
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if no language processor matches or processing fails.
+    pub fn process_source(
+        &self,
+        source: &str,
+        path: &Path,
+        language: Option<&str>,
+    ) -> Result<Node> {
+        let processor = match language {
+            Some(language) => self.language_registry.find_language(language),
+            None => self
+                .language_registry
+                .find_processor(path, &self.options.language_map),
         }
+        .ok_or_else(|| {
+            let requested = language.or_else(|| path.extension().and_then(|s| s.to_str()));
+            DistilError::unsupported_language(path, requested.unwrap_or("unknown"))
+        })?;
+
+        let file = processor
+            .process(source, path, &self.options)
+            .map_err(|e| e.with_path(path))?;
+        let mut node = Node::File(file);
+        type_merge::merge_types(&mut node, self.options.merge_types);
+        canonical::canonicalize(&mut node);
+        Ok(node)
     }
 
     /// Process a single file
     fn process_single_file(&self, path: &Path) -> Result<crate::ir::File> {
-        use crate::error::DistilError;
-
         // Find processor for this file
-        let processor = self.language_registry.find_processor(path).ok_or_else(|| {
-            DistilError::UnsupportedLanguage {
-                path: path.display().to_string(),
-                lang: path
-                    .extension()
-                    .and_then(|s| s.to_str())
-                    .unwrap_or("unknown")
-                    .to_string(),
-            }
-        })?;
+        let processor = self
+            .language_registry
+            .find_processor(path, &self.options.language_map)
+            .ok_or_else(|| {
+                DistilError::unsupported_language(
+                    path,
+                    path.extension()
+                        .and_then(|s| s.to_str())
+                        .unwrap_or("unknown"),
+                )
+            })?;
 
         // Read file
-        let source = std::fs::read_to_string(path).map_err(DistilError::Io)?;
+        let source = encoding::read_source(path, &self.options.fallback_encodings)?;
 
         // Process with language-specific processor
-        processor.process(&source, path, &self.options)
+        processor
+            .process(&source, path, &self.options)
+            .map_err(|e| e.with_path(path))
+    }
+
+    /// Language name for `path`, honoring the language map
+    #[must_use]
+    pub fn language_for(&self, path: &Path) -> Option<&'static str> {
+        self.language_registry
+            .language_for(path, &self.options.language_map)
     }
 
     /// Get reference to language registry (for testing/inspection)
@@ -137,4 +219,81 @@ mod tests {
 
         assert!(result.is_err());
     }
+
+    /// Processor that keeps the source as raw content
+    struct RawSource;
+
+    impl LanguageProcessor for RawSource {
+        fn language(&self) -> &'static str {
+            "typescript"
+        }
+
+        fn supported_extensions(&self) -> &'static [&'static str] {
+            &["ts", "tsx"]
+        }
+
+        fn process(
+            &self,
+            source: &str,
+            path: &Path,
+            _opts: &ProcessOptions,
+        ) -> Result<crate::ir::File> {
+            Ok(crate::ir::File {
+                path: path.to_string_lossy().into_owned(),
+                children: vec![Node::RawContent(crate::ir::RawContent {
+                    content: source.to_string(),
+                })],
+            })
+        }
+    }
+
+    #[test]
+    fn test_process_source() {
+        let mut processor = Processor::with_defaults();
+        processor.register_language(Box::new(RawSource));
+
+        // The path needn't exist; it picks the language and names the file
+        let node = processor
+            .process_source("let x = 1;", Path::new("src/foo.ts"), None)
+            .unwrap();
+        let Node::File(file) = node else {
+            panic!("expected a file node");
+        };
+        assert_eq!(file.path, "src/foo.ts");
+        assert!(
+            matches!(&file.children[..], [Node::RawContent(raw)] if raw.content == "let x = 1;")
+        );
+
+        for language in ["typescript", "TypeScript", "tsx", ".ts"] {
+            let node = processor.process_source("", Path::new("<stdin>"), Some(language));
+            assert!(node.is_ok(), "{language}");
+        }
+
+        let err = processor
+            .process_source("", Path::new("<stdin>"), Some("cobol"))
+            .unwrap_err();
+        assert!(
+            matches!(err, DistilError::UnsupportedLanguage { ref lang, .. } if lang == "cobol")
+        );
+        let err = processor
+            .process_source("", Path::new("notes.txt"), None)
+            .unwrap_err();
+        assert!(matches!(err, DistilError::UnsupportedLanguage { ref lang, .. } if lang == "txt"));
+    }
+
+    #[test]
+    fn test_explicit_binary_file() {
+        let root = std::env::temp_dir().join(format!("aid-binary-{}", std::process::id()));
+        std::fs::create_dir_all(&root).unwrap();
+        let path = root.join("bundle.ts");
+        std::fs::write(&path, b"\x00asm\x01\x00\x00\x00").unwrap();
+
+        let mut processor = Processor::with_defaults();
+        processor.register_language(Box::new(RawSource));
+        let err = processor.process_path(&path).unwrap_err();
+        assert!(matches!(err, DistilError::BinaryFile { path: ref binary } if *binary == path));
+        assert_eq!(err.code().as_str(), "binary_file");
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
 }
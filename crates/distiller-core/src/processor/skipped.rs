@@ -0,0 +1,163 @@
+//! Why directory discovery skipped a path
+//!
+//! The walker only yields what it keeps, so explaining a skip means asking
+//! the ignore files again. Rules are checked with the walker's precedence:
+//! `.aidignore`, then `.ignore`, then `.gitignore` and `.git/info/exclude`
+//! (inside a git repository only), nearest directory first within each
+//! kind. A `!pattern` in a higher-precedence file re-includes the path, so
+//! a `.aidignore` can bring back a generated SDK that `.gitignore` hides.
+
+use ignore::Match;
+use ignore::gitignore::{Gitignore, GitignoreBuilder, Glob};
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+
+/// Tool-specific ignore file, gitignore syntax, read at every directory level
+pub const AIDIGNORE: &str = ".aidignore";
+
+/// Per-directory ignore files, highest precedence first
+const IGNORE_FILES: &[&str] = &[AIDIGNORE, ".ignore", ".gitignore"];
+
+/// Why a path was left out
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum SkipReason {
+    /// Matched `pattern` in the ignore file at `file`
+    Ignored { file: PathBuf, pattern: String },
+    /// Ignored by a rule outside the project (e.g. global git excludes)
+    IgnoredElsewhere,
+    /// Matched none of the `--include` patterns
+    NotIncluded,
+    /// Matched this `--exclude` pattern
+    Excluded(String),
+    /// A test file while tests are excluded
+    TestFile,
+    /// Binary data rather than source text
+    Binary,
+}
+
+impl std::fmt::Display for SkipReason {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Self::Ignored { file, pattern } => {
+                write!(f, "ignored by '{pattern}' in {}", file.display())
+            }
+            Self::IgnoredElsewhere => f.write_str("ignored by a global git exclude"),
+            Self::NotIncluded => f.write_str("matches no --include pattern"),
+            Self::Excluded(pattern) => write!(f, "matches --exclude '{pattern}'"),
+            Self::TestFile => f.write_str("test file (--tests=0)"),
+            Self::Binary => f.write_str("binary file"),
+        }
+    }
+}
+
+/// A skipped file, or a directory skipped with everything below it
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SkippedPath {
+    pub path: PathBuf,
+    pub is_dir: bool,
+    pub reason: SkipReason,
+}
+
+/// Ignore files loaded on demand, keyed by the file's path
+#[derive(Default)]
+pub(crate) struct IgnoreRules {
+    matchers: HashMap<PathBuf, Option<Gitignore>>,
+}
+
+impl IgnoreRules {
+    /// The ignore rule that drops the absolute `path`, if any
+    pub(crate) fn explain(&mut self, path: &Path, is_dir: bool) -> Option<SkipReason> {
+        let dirs = path.parent().map(Path::ancestors).into_iter().flatten();
+        let dirs: Vec<&Path> = dirs.collect();
+        let git_root = dirs.iter().position(|dir| dir.join(".git").exists());
+
+        for name in IGNORE_FILES {
+            // .gitignore files only count inside a repository, up to its root
+            let levels = match (*name, git_root) {
+                (".gitignore", None) => continue,
+                (".gitignore", Some(top)) => &dirs[..=top],
+                _ => &dirs[..],
+            };
+            for dir in levels {
+                match self.check(dir, &dir.join(name), path, is_dir) {
+                    Match::None => {}
+                    Match::Whitelist(_) => return None,
+                    Match::Ignore(reason) => return Some(reason),
+                }
+            }
+        }
+
+        let top = dirs[git_root?];
+        match self.check(top, &top.join(".git/info/exclude"), path, is_dir) {
+            Match::Ignore(reason) => Some(reason),
+            Match::None | Match::Whitelist(_) => None,
+        }
+    }
+
+    /// Match `path` against one ignore file rooted at `dir`
+    ///
+    /// A whitelist match carries the `!pattern` that re-included the path.
+    fn check(&mut self, dir: &Path, file: &Path, path: &Path, is_dir: bool) -> Match<SkipReason> {
+        let Some(matcher) = self
+            .matchers
+            .entry(file.to_path_buf())
+            .or_insert_with(|| load(dir, file))
+        else {
+            return Match::None;
+        };
+
+        let reason = |glob: &Glob| SkipReason::Ignored {
+            file: glob.from().unwrap_or(file).to_path_buf(),
+            pattern: glob.original().to_string(),
+        };
+        match matcher.matched(path, is_dir) {
+            Match::None => Match::None,
+            Match::Whitelist(glob) => Match::Whitelist(reason(glob)),
+            Match::Ignore(glob) => Match::Ignore(reason(glob)),
+        }
+    }
+}
+
+/// Build the matcher for `file`, or `None` if it doesn't exist or is unreadable
+fn load(dir: &Path, file: &Path) -> Option<Gitignore> {
+    if !file.is_file() {
+        return None;
+    }
+    let mut builder = GitignoreBuilder::new(dir);
+    if let Some(err) = builder.add(file) {
+        log::debug!("Ignore file {}: {err}", file.display());
+    }
+    builder.build().ok()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_reason_display() {
+        let reason = SkipReason::Ignored {
+            file: PathBuf::from("proto/.aidignore"),
+            pattern: "*.pb.go".to_string(),
+        };
+        assert_eq!(
+            reason.to_string(),
+            "ignored by '*.pb.go' in proto/.aidignore"
+        );
+        assert_eq!(
+            SkipReason::Excluded("vendor/**".to_string()).to_string(),
+            "matches --exclude 'vendor/**'"
+        );
+    }
+
+    #[test]
+    fn test_no_ignore_files() {
+        let root = std::env::temp_dir().join(format!("aid-skipped-{}", std::process::id()));
+        std::fs::create_dir_all(&root).unwrap();
+
+        let mut rules = IgnoreRules::default();
+        assert_eq!(rules.explain(&root.join("main.go"), false), None);
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+}
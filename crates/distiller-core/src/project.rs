@@ -0,0 +1,294 @@
+//! Project root detection and output file naming
+//!
+//! Outputs go to `<project-root>/.aid/` no matter which subdirectory `aid`
+//! runs from. The root is found by searching upward for marker files, in
+//! priority order: `.aidrc` or `aid.toml`, then language manifests (`go.mod`,
+//! `package.json`, `Cargo.toml`, ...), then `.git`. `AID_PROJECT_ROOT`
+//! overrides the search; without markers the current directory is used.
+
+use crate::ProcessOptions;
+use crate::config::CONFIG_FILES;
+use crate::ir::Visibility;
+use crate::test_filter::TestMode;
+use std::path::{Path, PathBuf};
+
+/// Environment variable that overrides root detection
+pub const ROOT_ENV: &str = "AID_PROJECT_ROOT";
+
+/// Directory under the project root that holds all outputs
+pub const OUTPUT_DIR: &str = ".aid";
+
+/// Language manifests marking a project root
+pub const LANGUAGE_MARKERS: &[&str] = &[
+    "go.mod",
+    "package.json",
+    "Cargo.toml",
+    "pyproject.toml",
+    "setup.py",
+    "pom.xml",
+    "build.gradle",
+];
+
+/// Parent directories searched above the start directory
+const MAX_PARENTS: usize = 12;
+
+/// Why a directory was chosen as the project root
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum RootMarker {
+    /// A config file, `.aidrc` or `aid.toml`
+    Config(&'static str),
+    /// A language manifest such as `go.mod`
+    Language(&'static str),
+    /// A `.git` directory or file
+    Git,
+    /// The `AID_PROJECT_ROOT` environment variable
+    Env,
+    /// No marker found; the start directory is used
+    CurrentDir,
+}
+
+impl std::fmt::Display for RootMarker {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Self::Config(name) | Self::Language(name) => f.write_str(name),
+            Self::Git => f.write_str(".git"),
+            Self::Env => f.write_str(ROOT_ENV),
+            Self::CurrentDir => f.write_str("current directory"),
+        }
+    }
+}
+
+/// A detected project root
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ProjectRoot {
+    pub path: PathBuf,
+    pub marker: RootMarker,
+}
+
+impl ProjectRoot {
+    /// Find the project root for `start`
+    ///
+    /// Honors `AID_PROJECT_ROOT` and never searches above the home
+    /// directory when `start` is inside it.
+    #[must_use]
+    pub fn detect(start: &Path) -> Self {
+        let env_root = std::env::var_os(ROOT_ENV)
+            .filter(|v| !v.is_empty())
+            .map(PathBuf::from);
+        let start = start.canonicalize().unwrap_or_else(|_| start.to_path_buf());
+        Self::detect_with(&start, env_root, std::env::home_dir().as_deref())
+    }
+
+    /// Root detection with the environment passed in explicitly
+    fn detect_with(start: &Path, env_root: Option<PathBuf>, home: Option<&Path>) -> Self {
+        if let Some(path) = env_root {
+            return Self {
+                path,
+                marker: RootMarker::Env,
+            };
+        }
+
+        let candidates = search_path(start, home);
+        let find_file = |names: &[&'static str]| {
+            candidates.iter().find_map(|dir| {
+                names
+                    .iter()
+                    .find(|name| dir.join(name).is_file())
+                    .map(|name| (dir.clone(), *name))
+            })
+        };
+
+        if let Some((path, name)) = find_file(CONFIG_FILES) {
+            return Self {
+                path,
+                marker: RootMarker::Config(name),
+            };
+        }
+        if let Some((path, name)) = find_file(LANGUAGE_MARKERS) {
+            return Self {
+                path,
+                marker: RootMarker::Language(name),
+            };
+        }
+        if let Some(path) = candidates
+            .iter()
+            .find(|dir| dir.join(".git").exists())
+            .cloned()
+        {
+            return Self {
+                path,
+                marker: RootMarker::Git,
+            };
+        }
+
+        Self {
+            path: start.to_path_buf(),
+            marker: RootMarker::CurrentDir,
+        }
+    }
+
+    /// The `.aid/` output directory of this root
+    #[must_use]
+    pub fn output_dir(&self) -> PathBuf {
+        self.path.join(OUTPUT_DIR)
+    }
+}
+
+/// `start` and its parents, nearest first, stopping at the home directory
+fn search_path(start: &Path, home: Option<&Path>) -> Vec<PathBuf> {
+    let mut dirs = Vec::new();
+    for dir in start.ancestors().take(MAX_PARENTS + 1) {
+        dirs.push(dir.to_path_buf());
+        if home.is_some_and(|home| dir == home) {
+            break;
+        }
+    }
+    dirs
+}
+
+/// Output file name for distilling `input` with `options`
+///
+/// The name is the input's base name followed by tags for the options
+/// that shape the output, e.g. `src.pub.prot.impl.txt`.
+#[must_use]
+pub fn output_file_name(input: &Path, options: &ProcessOptions, extension: &str) -> String {
+    let basename = input
+        .file_name()
+        .and_then(|s| s.to_str())
+        .unwrap_or("output");
+
+    let mut parts = vec![basename];
+    parts.extend(option_tags(options));
+    parts.push(extension);
+    parts.join(".")
+}
+
+/// Short tags for the visibility levels and content settings in `options`
+fn option_tags(options: &ProcessOptions) -> Vec<&'static str> {
+    let included = |bucket: Visibility, flag: bool| {
+        if options.visibility_levels.is_empty() {
+            flag
+        } else {
+            options
+                .visibility_levels
+                .iter()
+                .any(|v| v.coarse() == bucket)
+        }
+    };
+
+    let mut tags = Vec::new();
+    for (bucket, flag, tag) in [
+        (Visibility::Public, options.include_public, "pub"),
+        (Visibility::Protected, options.include_protected, "prot"),
+        (Visibility::Internal, options.include_internal, "int"),
+        (Visibility::Private, options.include_private, "priv"),
+    ] {
+        if included(bucket, flag) {
+            tags.push(tag);
+        }
+    }
+
+    // Only settings that differ from the defaults are named
+    for (differs, tag) in [
+        (options.include_implementation, "impl"),
+        (options.include_comments, "comm"),
+        (!options.include_docstrings, "nodoc"),
+        (!options.include_imports, "noimp"),
+        (!options.include_annotations, "noann"),
+        (!options.include_fields, "nofld"),
+        (!options.include_methods, "nometh"),
+        (!options.include_deprecated, "nodep"),
+        (options.tests == TestMode::Exclude, "notests"),
+        (options.tests == TestMode::Only, "tests"),
+    ] {
+        if differs {
+            tags.push(tag);
+        }
+    }
+
+    tags
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ir::SourceVisibility;
+
+    /// Fresh directory tree under the system temp directory
+    fn temp_tree(name: &str) -> PathBuf {
+        let root = std::env::temp_dir().join(format!("aid-project-{name}-{}", std::process::id()));
+        let _ = std::fs::remove_dir_all(&root);
+        std::fs::create_dir_all(root.join("a/b/c")).unwrap();
+        root
+    }
+
+    #[test]
+    fn test_marker_priority() {
+        let root = temp_tree("priority");
+        std::fs::create_dir(root.join(".git")).unwrap();
+        std::fs::write(root.join("a/package.json"), "{}").unwrap();
+        let start = root.join("a/b/c");
+
+        let found = ProjectRoot::detect_with(&start, None, Some(&root));
+        assert_eq!(found.path, root.join("a"));
+        assert_eq!(found.marker, RootMarker::Language("package.json"));
+
+        // .aidrc wins over a nearer language marker
+        std::fs::write(root.join(".aidrc"), "").unwrap();
+        let found = ProjectRoot::detect_with(&start, None, Some(&root));
+        assert_eq!(found.path, root);
+        assert_eq!(found.marker, RootMarker::Config(".aidrc"));
+        assert_eq!(found.output_dir(), root.join(".aid"));
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+
+    #[test]
+    fn test_env_override_and_fallback() {
+        let root = temp_tree("fallback");
+        let start = root.join("a/b");
+
+        let found = ProjectRoot::detect_with(&start, Some(PathBuf::from("/build")), Some(&root));
+        assert_eq!(found.path, PathBuf::from("/build"));
+        assert_eq!(found.marker, RootMarker::Env);
+
+        // The search stops at the home directory
+        std::fs::write(root.join("go.mod"), "module x").unwrap();
+        let found = ProjectRoot::detect_with(&start, None, Some(&root.join("a")));
+        assert_eq!(found.path, start);
+        assert_eq!(found.marker, RootMarker::CurrentDir);
+
+        std::fs::remove_dir_all(&root).unwrap();
+    }
+
+    #[test]
+    fn test_output_file_name() {
+        let options = ProcessOptions::default();
+        assert_eq!(
+            output_file_name(Path::new("/work/src"), &options, "txt"),
+            "src.pub.txt"
+        );
+
+        let options = ProcessOptions {
+            include_protected: true,
+            include_private: true,
+            include_implementation: true,
+            tests: TestMode::Exclude,
+            ..Default::default()
+        };
+        assert_eq!(
+            output_file_name(Path::new("lib/main.go"), &options, "md"),
+            "main.go.pub.prot.priv.impl.notests.md"
+        );
+
+        let options = ProcessOptions {
+            visibility_levels: vec![SourceVisibility::Crate],
+            include_docstrings: false,
+            ..Default::default()
+        };
+        assert_eq!(
+            output_file_name(Path::new("core"), &options, "json"),
+            "core.int.nodoc.json"
+        );
+    }
+}
@@ -1,24 +1,85 @@
 //! Stripper visitor for filtering IR nodes
 //!
 //! Applies ProcessOptions to filter IR based on visibility and content preferences.
+//! After filtering, containers that were left empty (files, packages, classes)
+//! are pruned so the output doesn't carry empty shells.
 
 use crate::{
-    ProcessOptions,
+    DeclFilter, ProcessOptions,
     ir::{
-        Class, Enum, Field, File, Function, Interface, Node, Package, Struct, TypeAlias,
-        Visibility, Visitor,
+        Class, Enum, EnumVariant, Field, File, Function, Interface, Macro, Module, Node,
+        OverloadSet, Package, Property, SourceVisibility, Struct, TypeAlias, Visibility, Visitor,
     },
+    test_filter::{self, TestMode},
 };
+use serde::Serialize;
+use std::fmt;
+use std::path::{Path, PathBuf};
+
+/// Counts of containers removed because filtering left them empty
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize)]
+pub struct PruneStats {
+    /// Files pruned from a directory
+    pub files: usize,
+    /// Packages and modules pruned
+    pub packages: usize,
+    /// Classes, interfaces, structs and enums pruned
+    pub classes: usize,
+}
+
+impl PruneStats {
+    /// Total number of pruned containers
+    #[must_use]
+    pub fn total(&self) -> usize {
+        self.files + self.packages + self.classes
+    }
+}
+
+/// E.g. `3 (1 file(s), 0 package(s), 2 class(es))`
+impl fmt::Display for PruneStats {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(
+            f,
+            "{} ({} file(s), {} package(s), {} class(es))",
+            self.total(),
+            self.files,
+            self.packages,
+            self.classes
+        )
+    }
+}
 
 /// Stripper visitor - filters IR nodes based on ProcessOptions
 pub struct Stripper {
     options: ProcessOptions,
+    pruned: PruneStats,
+    /// Root of the directory being stripped, for root-relative test detection
+    root: Option<PathBuf>,
+    /// Path of the file currently being stripped
+    current_path: PathBuf,
+    /// Whether we're inside a test file or a test symbol
+    in_test_code: bool,
+    /// Whether we're inside a declaration matching the `with` filters
+    in_decl_match: bool,
 }
 
 impl Stripper {
     #[must_use]
     pub fn new(options: ProcessOptions) -> Self {
-        Self { options }
+        Self {
+            options,
+            pruned: PruneStats::default(),
+            root: None,
+            current_path: PathBuf::new(),
+            in_test_code: false,
+            in_decl_match: false,
+        }
+    }
+
+    /// Containers pruned so far by this stripper
+    #[must_use]
+    pub fn pruned(&self) -> PruneStats {
+        self.pruned
     }
 
     /// Check if a visibility level should be included
@@ -31,8 +92,37 @@ impl Stripper {
         }
     }
 
+    /// Check a declaration's visibility, honoring precise `visibility_levels`
+    fn should_include_access(
+        &self,
+        visibility: Visibility,
+        source_visibility: Option<&SourceVisibility>,
+    ) -> bool {
+        if self.options.visibility_levels.is_empty() {
+            return self.should_include_visibility(visibility);
+        }
+
+        let precise = source_visibility
+            .cloned()
+            .unwrap_or_else(|| SourceVisibility::from(visibility));
+        self.options
+            .visibility_levels
+            .iter()
+            .any(|level| level.matches(&precise))
+    }
+
     /// Check if a node should be included based on type and options
     fn should_include_node(&self, node: &Node) -> bool {
+        if !self.should_include_test_code(node) {
+            return false;
+        }
+        if !self.options.include_deprecated && Self::is_deprecated(node) {
+            return false;
+        }
+        if !self.should_include_decl(node) {
+            return false;
+        }
+
         match node {
             Node::Import(_) => self.options.include_imports,
             Node::Comment(c) => {
@@ -43,26 +133,258 @@ impl Stripper {
                 }
             }
             Node::Function(f) => {
-                self.options.include_methods && self.should_include_visibility(f.visibility)
+                self.options.include_methods
+                    && self.should_include_access(f.visibility, f.source_visibility.as_ref())
             }
+            // Signatures were already filtered one by one
+            Node::OverloadSet(o) => !o.signatures.is_empty(),
             Node::Field(f) => {
-                self.options.include_fields && self.should_include_visibility(f.visibility)
+                self.options.include_fields
+                    && self.should_include_access(f.visibility, f.source_visibility.as_ref())
+            }
+            Node::Property(p) => {
+                self.options.include_fields
+                    && self.should_include_access(p.visibility, p.source_visibility.as_ref())
+            }
+            Node::Module(m) => {
+                self.should_include_access(m.visibility, m.source_visibility.as_ref())
+            }
+            Node::Class(c) => {
+                self.should_include_access(c.visibility, c.source_visibility.as_ref())
+            }
+            Node::Interface(i) => {
+                self.should_include_access(i.visibility, i.source_visibility.as_ref())
+            }
+            Node::Struct(s) => {
+                self.should_include_access(s.visibility, s.source_visibility.as_ref())
+            }
+            Node::Enum(e) => self.should_include_access(e.visibility, e.source_visibility.as_ref()),
+            Node::TypeAlias(t) => {
+                self.should_include_access(t.visibility, t.source_visibility.as_ref())
+            }
+            Node::Variable(v) => {
+                self.should_include_access(v.visibility, v.source_visibility.as_ref())
+            }
+            Node::Macro(m) => {
+                self.should_include_access(m.visibility, m.source_visibility.as_ref())
             }
-            Node::Class(c) => self.should_include_visibility(c.visibility),
-            Node::Interface(i) => self.should_include_visibility(i.visibility),
-            Node::Struct(s) => self.should_include_visibility(s.visibility),
-            Node::Enum(e) => self.should_include_visibility(e.visibility),
-            Node::TypeAlias(t) => self.should_include_visibility(t.visibility),
             _ => true, // Include other node types by default
         }
     }
 
+    /// Check a node against the test mode
+    ///
+    /// With `--tests=only`, containers are kept here and dropped after
+    /// recursion if no test code was found inside them.
+    fn should_include_test_code(&self, node: &Node) -> bool {
+        match self.options.tests {
+            TestMode::Include => true,
+            TestMode::Exclude => match node {
+                Node::File(f) => !test_filter::is_test_file(&self.relative_path(&f.path)),
+                _ => !self.is_test_node(node),
+            },
+            TestMode::Only => {
+                self.in_test_code
+                    || self.is_test_node(node)
+                    || !matches!(
+                        node,
+                        Node::Function(_)
+                            | Node::Field(_)
+                            | Node::Property(_)
+                            | Node::TypeAlias(_)
+                            | Node::Variable(_)
+                            | Node::Macro(_)
+                    )
+            }
+        }
+    }
+
+    /// Check a node against the `with` / `without` modifier and metadata filters
+    ///
+    /// Like `--tests=only`, `with` filters only drop leaves here; containers
+    /// are dropped after recursion if nothing inside them matched.
+    fn should_include_decl(&self, node: &Node) -> bool {
+        if self
+            .options
+            .without_filters
+            .iter()
+            .any(|filter| filter.matches(node))
+        {
+            return false;
+        }
+
+        self.in_decl_match
+            || !matches!(
+                node,
+                Node::Function(_)
+                    | Node::Field(_)
+                    | Node::Property(_)
+                    | Node::TypeAlias(_)
+                    | Node::Variable(_)
+                    | Node::Macro(_)
+            )
+            || self.is_decl_match(node)
+    }
+
+    /// Check if a node satisfies all `with` filters
+    fn is_decl_match(&self, node: &Node) -> bool {
+        DeclFilter::all_match(&self.options.with_filters, node)
+    }
+
+    /// Whether `with` filters require dropping containers without matches
+    fn only_decl_matches_here(&self) -> bool {
+        !self.options.with_filters.is_empty() && !self.in_decl_match
+    }
+
+    /// Check if a declaration carries a deprecation marker
+    fn is_deprecated(node: &Node) -> bool {
+        match node {
+            Node::Module(m) => m.deprecated.is_some(),
+            Node::Class(c) => c.deprecated.is_some(),
+            Node::Interface(i) => i.deprecated.is_some(),
+            Node::Struct(s) => s.deprecated.is_some(),
+            Node::Enum(e) => e.deprecated.is_some(),
+            Node::EnumVariant(v) => v.deprecated.is_some(),
+            Node::TypeAlias(t) => t.deprecated.is_some(),
+            Node::Function(f) => f.deprecated.is_some(),
+            Node::Field(f) => f.deprecated.is_some(),
+            Node::Property(p) => p.deprecated.is_some(),
+            Node::Variable(v) => v.deprecated.is_some(),
+            Node::Macro(m) => m.deprecated.is_some(),
+            _ => false,
+        }
+    }
+
+    /// Check if a node is test code according to the current file's language
+    fn is_test_node(&self, node: &Node) -> bool {
+        test_filter::is_test_symbol(node, &self.current_path)
+    }
+
+    /// Whether `--tests=only` requires dropping containers without test code
+    fn only_tests_here(&self) -> bool {
+        self.options.tests == TestMode::Only && !self.in_test_code
+    }
+
+    /// Path of a file relative to the directory being stripped
+    fn relative_path(&self, path: &str) -> PathBuf {
+        let path = Path::new(path);
+        self.root
+            .as_deref()
+            .and_then(|root| path.strip_prefix(root).ok())
+            .unwrap_or(path)
+            .to_path_buf()
+    }
+
     /// Filter decorators if annotations are disabled
     fn filter_decorators(&self, decorators: &mut Vec<String>) {
         if !self.options.include_annotations {
             decorators.clear();
         }
     }
+
+    /// Keep the signatures of an overload set that pass on their own
+    fn filter_signatures(&self, overloads: &mut OverloadSet) {
+        let signatures = std::mem::take(&mut overloads.signatures);
+        overloads.signatures = signatures
+            .into_iter()
+            .map(Node::Function)
+            .filter(|node| self.should_include_node(node))
+            .filter_map(|node| match node {
+                Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+    }
+
+    /// Filter a child list, recurse into the survivors and prune the
+    /// containers that filtering left empty
+    fn filter_children(&mut self, children: &mut Vec<Node>) {
+        for child in children.iter_mut() {
+            if let Node::OverloadSet(overloads) = child {
+                self.filter_signatures(overloads);
+            }
+        }
+        children.retain(|child| self.should_include_node(child));
+
+        // Remember which containers were already empty before recursing,
+        // so we only prune the ones emptied by filtering
+        let was_empty: Vec<bool> = children.iter().map(is_empty_container).collect();
+
+        for child in children.iter_mut() {
+            // Everything below a test symbol is test code
+            let entering_test = !self.in_test_code && self.is_test_node(child);
+            if entering_test {
+                self.in_test_code = true;
+            }
+            // Everything below a matching declaration matches too
+            let entering_match = self.only_decl_matches_here() && self.is_decl_match(child);
+            if entering_match {
+                self.in_decl_match = true;
+            }
+            self.visit_node(child);
+            if entering_match {
+                self.in_decl_match = false;
+            }
+            if entering_test {
+                self.in_test_code = false;
+            }
+        }
+
+        if self.options.prune_empty || self.only_tests_here() || self.only_decl_matches_here() {
+            let mut index = 0;
+            children.retain(|child| {
+                let keep = !self.should_prune(child, was_empty[index]);
+                index += 1;
+                keep
+            });
+        }
+    }
+
+    /// Decide whether a filtered child should be pruned, recording it in the stats
+    fn should_prune(&mut self, node: &Node, was_empty: bool) -> bool {
+        if !is_empty_container(node) {
+            return false;
+        }
+
+        // Outside test code, --tests=only drops every container without tests,
+        // and `with` filters every container without matches
+        let force = (self.only_tests_here() && !self.is_test_node(node))
+            || (self.only_decl_matches_here() && !self.is_decl_match(node));
+
+        match node {
+            Node::File(_) if force || !was_empty => self.pruned.files += 1,
+            Node::Package(_) | Node::Module(_) if force || !was_empty => {
+                self.pruned.packages += 1;
+            }
+            Node::Class(_) | Node::Interface(_) | Node::Struct(_) | Node::Enum(_)
+                if force || !was_empty || !self.options.keep_empty_classes =>
+            {
+                self.pruned.classes += 1;
+            }
+            _ => return false,
+        }
+        true
+    }
+}
+
+/// Check if a node is a container without any declarations left
+///
+/// Imports and comments alone don't keep a container alive.
+fn is_empty_container(node: &Node) -> bool {
+    let children = match node {
+        Node::File(f) => &f.children,
+        Node::Package(p) => &p.children,
+        Node::Module(m) => &m.children,
+        Node::Class(c) => &c.children,
+        Node::Interface(i) => &i.children,
+        Node::Struct(s) => &s.children,
+        Node::Enum(e) => &e.children,
+        _ => return false,
+    };
+
+    children
+        .iter()
+        .all(|child| matches!(child, Node::Import(_) | Node::Comment(_)))
 }
 
 impl Visitor for Stripper {
@@ -74,94 +396,87 @@ impl Visitor for Stripper {
             Node::Interface(i) => self.visit_interface(i),
             Node::Struct(s) => self.visit_struct(s),
             Node::Enum(e) => self.visit_enum(e),
+            Node::EnumVariant(v) => self.visit_enum_variant(v),
             Node::Package(p) => self.visit_package(p),
+            Node::Module(m) => self.visit_module(m),
             Node::Function(f) => self.visit_function(f),
+            Node::OverloadSet(o) => self.visit_overload_set(o),
             Node::Field(f) => self.visit_field(f),
+            Node::Property(p) => self.visit_property(p),
             Node::TypeAlias(t) => self.visit_type_alias(t),
+            Node::Macro(m) => self.visit_macro(m),
             _ => {}
         }
     }
 
     fn visit_file(&mut self, file: &mut File) {
-        // Filter children based on options
-        file.children
-            .retain(|child| self.should_include_node(child));
+        self.current_path = self.relative_path(&file.path);
 
-        // Recurse into remaining children
-        for child in &mut file.children {
-            self.visit_node(child);
+        let outer_test_code = self.in_test_code;
+        self.in_test_code = outer_test_code || test_filter::is_test_file(&self.current_path);
+
+        if self.in_test_code && self.options.tests == TestMode::Exclude {
+            // Whole test file excluded; the parent prunes the empty file
+            file.children.clear();
+        } else {
+            // Filter children based on options, then recurse and prune
+            self.filter_children(&mut file.children);
         }
+
+        self.in_test_code = outer_test_code;
     }
 
     fn visit_directory(&mut self, dir: &mut crate::ir::Directory) {
-        // Filter children based on options
-        dir.children.retain(|child| self.should_include_node(child));
-
-        // Recurse into remaining children
-        for child in &mut dir.children {
-            self.visit_node(child);
+        if self.root.is_none() {
+            self.root = Some(PathBuf::from(&dir.path));
         }
+
+        // Filter children based on options, then recurse and prune
+        self.filter_children(&mut dir.children);
     }
 
     fn visit_package(&mut self, package: &mut Package) {
-        // Filter children
-        package
-            .children
-            .retain(|child| self.should_include_node(child));
+        // Filter children, then recurse and prune
+        self.filter_children(&mut package.children);
+    }
 
-        // Recurse into remaining children
-        for child in &mut package.children {
-            self.visit_node(child);
-        }
+    fn visit_module(&mut self, module: &mut Module) {
+        // Filter attributes
+        self.filter_decorators(&mut module.decorators);
+
+        // Filter children, then recurse and prune
+        self.filter_children(&mut module.children);
     }
 
     fn visit_class(&mut self, class: &mut Class) {
         // Filter decorators
         self.filter_decorators(&mut class.decorators);
 
-        // Filter children
-        class
-            .children
-            .retain(|child| self.should_include_node(child));
-
-        // Recurse into remaining children
-        for child in &mut class.children {
-            self.visit_node(child);
-        }
+        // Filter children, then recurse and prune
+        self.filter_children(&mut class.children);
     }
 
     fn visit_interface(&mut self, interface: &mut Interface) {
-        // Filter children
-        interface
-            .children
-            .retain(|child| self.should_include_node(child));
-
-        // Recurse into remaining children
-        for child in &mut interface.children {
-            self.visit_node(child);
-        }
+        // Filter children, then recurse and prune
+        self.filter_children(&mut interface.children);
     }
 
     fn visit_struct(&mut self, strukt: &mut Struct) {
-        // Filter children
-        strukt
-            .children
-            .retain(|child| self.should_include_node(child));
-
-        // Recurse into remaining children
-        for child in &mut strukt.children {
-            self.visit_node(child);
-        }
+        // Filter children, then recurse and prune
+        self.filter_children(&mut strukt.children);
     }
 
     fn visit_enum(&mut self, enm: &mut Enum) {
-        // Filter children (enum variants)
-        enm.children.retain(|child| self.should_include_node(child));
+        // Filter attributes
+        self.filter_decorators(&mut enm.decorators);
 
-        // Recurse into remaining children
-        for child in &mut enm.children {
-            self.visit_node(child);
-        }
+        // Filter children (enum variants), then recurse and prune
+        self.filter_children(&mut enm.children);
+    }
+
+    fn visit_enum_variant(&mut self, variant: &mut EnumVariant) {
+        // Filter constant bodies
+        self.filter_children(&mut variant.children);
     }
 
     fn visit_function(&mut self, function: &mut Function) {
@@ -174,19 +489,96 @@ impl Visitor for Stripper {
         }
     }
 
+    fn visit_overload_set(&mut self, overloads: &mut OverloadSet) {
+        // Outline only: the first signature stands in for the set, `count`
+        // keeps the total
+        if self.options.collapse_overloads {
+            overloads.signatures.truncate(1);
+        }
+
+        for signature in &mut overloads.signatures {
+            self.visit_function(signature);
+        }
+    }
+
     fn visit_field(&mut self, _field: &mut Field) {
         // Fields don't have children, nothing to do
     }
 
+    fn visit_property(&mut self, property: &mut Property) {
+        // Drop accessors whose own visibility is filtered out (`private set`)
+        let accessors = std::mem::take(&mut property.accessors);
+        property.accessors = accessors
+            .into_iter()
+            .filter(|accessor| {
+                accessor.visibility.is_none_or(|visibility| {
+                    self.should_include_access(visibility, accessor.source_visibility.as_ref())
+                })
+            })
+            .collect();
+    }
+
     fn visit_type_alias(&mut self, _type_alias: &mut TypeAlias) {
         // Type aliases don't have children, nothing to do
     }
+
+    fn visit_macro(&mut self, macro_def: &mut Macro) {
+        // Filter attributes
+        self.filter_decorators(&mut macro_def.decorators);
+    }
 }
 
 #[cfg(test)]
 mod tests {
     use super::*;
-    use crate::ir::{Function, Visibility};
+    use crate::ir::{
+        Accessor, AccessorKind, Deprecation, Directory, Function, Modifier, Module, TypeRef,
+        Variable, VariableKind, Visibility,
+    };
+    use std::collections::BTreeMap;
+
+    fn method(name: &str, visibility: Visibility) -> Node {
+        Node::Function(Function {
+            name: name.to_string(),
+            visibility,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            parameters: vec![],
+            return_type: None,
+            implementation: None,
+            line_start: 1,
+            line_end: 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    fn class(name: &str, children: Vec<Node>) -> Node {
+        Node::Class(Class {
+            name: name.to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            extends: vec![],
+            implements: vec![],
+            children,
+            line_start: 1,
+            line_end: 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    fn file(path: &str, children: Vec<Node>) -> Node {
+        Node::File(File {
+            path: path.to_string(),
+            children,
+        })
+    }
 
     #[test]
     fn test_stripper_creation() {
@@ -218,6 +610,7 @@ mod tests {
         let mut func = Function {
             name: "test".to_string(),
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers: vec![],
             decorators: vec![],
             type_params: vec![],
@@ -226,9 +619,519 @@ mod tests {
             implementation: Some("return 42;".to_string()),
             line_start: 1,
             line_end: 3,
+            deprecated: None,
+            metadata: BTreeMap::new(),
         };
 
         stripper.visit_function(&mut func);
         assert!(func.implementation.is_none());
     }
+
+    #[test]
+    fn test_prune_class_emptied_by_filtering() {
+        let mut stripper = Stripper::new(ProcessOptions::default());
+        let mut node = file(
+            "a.py",
+            vec![
+                class("Hidden", vec![method("_secret", Visibility::Private)]),
+                class("Visible", vec![method("run", Visibility::Public)]),
+            ],
+        );
+
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        assert_eq!(f.children.len(), 1);
+        assert!(matches!(&f.children[0], Node::Class(c) if c.name == "Visible"));
+        assert_eq!(stripper.pruned().classes, 1);
+    }
+
+    #[test]
+    fn test_keep_class_empty_in_source() {
+        let mut node = file("errors.py", vec![class("NotFound", vec![])]);
+
+        let mut stripper = Stripper::new(ProcessOptions::default());
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = &node else {
+            panic!("expected file")
+        };
+        assert_eq!(f.children.len(), 1);
+        assert_eq!(stripper.pruned().total(), 0);
+
+        // Opting out of keep_empty_classes prunes marker classes too
+        let opts = ProcessOptions::builder().keep_empty_classes(false).build();
+        let mut stripper = Stripper::new(opts);
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = &node else {
+            panic!("expected file")
+        };
+        assert!(f.children.is_empty());
+        assert_eq!(stripper.pruned().classes, 1);
+    }
+
+    #[test]
+    fn test_prune_emptied_files_from_directory() {
+        let mut node = Node::Directory(Directory {
+            path: "src".to_string(),
+            children: vec![
+                file("private.py", vec![method("_helper", Visibility::Private)]),
+                file("public.py", vec![method("run", Visibility::Public)]),
+            ],
+        });
+
+        let mut stripper = Stripper::new(ProcessOptions::default());
+        stripper.visit_node(&mut node);
+
+        let Node::Directory(d) = node else {
+            panic!("expected directory")
+        };
+        assert_eq!(d.children.len(), 1);
+        assert!(matches!(&d.children[0], Node::File(f) if f.path == "public.py"));
+        assert_eq!(
+            stripper.pruned(),
+            PruneStats {
+                files: 1,
+                packages: 0,
+                classes: 0,
+            }
+        );
+    }
+
+    #[test]
+    fn test_prune_disabled() {
+        let opts = ProcessOptions::builder().prune_empty(false).build();
+        let mut stripper = Stripper::new(opts);
+        let mut node = file(
+            "a.py",
+            vec![class(
+                "Hidden",
+                vec![method("_secret", Visibility::Private)],
+            )],
+        );
+
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        assert_eq!(f.children.len(), 1);
+        assert_eq!(stripper.pruned().total(), 0);
+    }
+
+    #[test]
+    fn test_precise_visibility_levels() {
+        let opts = ProcessOptions::builder()
+            .visibility_levels(vec![SourceVisibility::Public, SourceVisibility::Crate])
+            .build();
+        let with_level = |name: &str, level: SourceVisibility| {
+            let mut node = method(name, level.coarse());
+            if let Node::Function(f) = &mut node {
+                f.source_visibility = Some(level);
+            }
+            node
+        };
+        let mut node = file(
+            "lib.rs",
+            vec![
+                method("plain", Visibility::Public),
+                with_level("in_crate", SourceVisibility::Crate),
+                with_level("in_parent", SourceVisibility::Super),
+                with_level("hidden", SourceVisibility::Private),
+            ],
+        );
+
+        let mut stripper = Stripper::new(opts);
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        let names: Vec<_> = f
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(func) => Some(func.name.as_str()),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(names, ["plain", "in_crate"]);
+    }
+
+    #[test]
+    fn test_hide_deprecated() {
+        let opts = ProcessOptions::builder().include_deprecated(false).build();
+        let mut old = method("load", Visibility::Public);
+        if let Node::Function(f) = &mut old {
+            f.deprecated = Some(Deprecation::default());
+        }
+        let mut node = file(
+            "api.ts",
+            vec![class("Api", vec![old, method("fetch", Visibility::Public)])],
+        );
+
+        let mut stripper = Stripper::new(opts);
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        let Node::Class(c) = &f.children[0] else {
+            panic!("expected class")
+        };
+        assert_eq!(c.children.len(), 1);
+        assert!(matches!(&c.children[0], Node::Function(func) if func.name == "fetch"));
+    }
+
+    #[test]
+    fn test_exclude_tests() {
+        let opts = ProcessOptions::builder().tests(TestMode::Exclude).build();
+        let mut node = Node::Directory(Directory {
+            path: "pkg".to_string(),
+            children: vec![
+                file(
+                    "pkg/server.go",
+                    vec![
+                        method("Serve", Visibility::Public),
+                        // Go test names only mark tests in `_test.go` files
+                        method("TestConnection", Visibility::Public),
+                    ],
+                ),
+                file(
+                    "pkg/server_test.go",
+                    vec![method("Helper", Visibility::Public)],
+                ),
+            ],
+        });
+
+        let mut stripper = Stripper::new(opts);
+        stripper.visit_node(&mut node);
+
+        let Node::Directory(d) = node else {
+            panic!("expected directory")
+        };
+        assert_eq!(d.children.len(), 1);
+        let Node::File(f) = &d.children[0] else {
+            panic!("expected file")
+        };
+        assert_eq!(f.path, "pkg/server.go");
+        assert_eq!(f.children.len(), 2);
+    }
+
+    #[test]
+    fn test_only_tests() {
+        let opts = ProcessOptions::builder().tests(TestMode::Only).build();
+        // Outside test files only markers count, not `test_` names
+        let mut test_class = class(
+            "UserTests",
+            vec![
+                method("setUp", Visibility::Public),
+                method("test_save", Visibility::Public),
+            ],
+        );
+        if let Node::Class(c) = &mut test_class {
+            c.extends = vec![TypeRef::new("unittest.TestCase")];
+        }
+        let mut fixture = method("db", Visibility::Public);
+        if let Node::Function(f) = &mut fixture {
+            f.decorators = vec!["@pytest.fixture".to_string()];
+        }
+        let mut node = file(
+            "app/models.py",
+            vec![
+                class("User", vec![method("save", Visibility::Public)]),
+                test_class,
+                fixture,
+                method("test_connection", Visibility::Public),
+            ],
+        );
+
+        let mut stripper = Stripper::new(opts);
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        let names: Vec<_> = f
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Class(c) => Some(c.name.as_str()),
+                Node::Function(func) => Some(func.name.as_str()),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(names, ["UserTests", "db"]);
+
+        // Non-test members of a test class are kept
+        let Node::Class(test_class) = &f.children[0] else {
+            panic!("expected class")
+        };
+        assert_eq!(test_class.children.len(), 2);
+    }
+
+    #[test]
+    fn test_with_and_without_filters() {
+        let mut suspended = method("load", Visibility::Public);
+        let mut ffi = method("raw_load", Visibility::Public);
+        if let Node::Function(f) = &mut suspended {
+            f.modifiers.push(Modifier::Suspend);
+        }
+        if let Node::Function(f) = &mut ffi {
+            f.modifiers.push(Modifier::Extern);
+            f.metadata.insert("abi".to_string(), "C".to_string());
+        }
+        let mut partial = class("Generated", vec![method("init", Visibility::Public)]);
+        if let Node::Class(c) = &mut partial {
+            c.modifiers.push(Modifier::Partial);
+        }
+        let tree = file(
+            "Repo.kt",
+            vec![
+                class(
+                    "Repo",
+                    vec![suspended, ffi, method("close", Visibility::Public)],
+                ),
+                class("Empty", vec![method("noop", Visibility::Public)]),
+                partial,
+            ],
+        );
+
+        let names = |node: &Node| -> Vec<String> {
+            let Node::File(f) = node else {
+                panic!("expected file")
+            };
+            f.children
+                .iter()
+                .filter_map(|n| match n {
+                    Node::Class(c) => Some(format!(
+                        "{}({})",
+                        c.name,
+                        c.children
+                            .iter()
+                            .filter_map(|m| match m {
+                                Node::Function(func) => Some(func.name.as_str()),
+                                _ => None,
+                            })
+                            .collect::<Vec<_>>()
+                            .join(",")
+                    )),
+                    _ => None,
+                })
+                .collect()
+        };
+
+        // `with`: only matching members, and matching containers whole
+        let opts = ProcessOptions::builder()
+            .with_filters(vec!["suspend".parse().unwrap()])
+            .build();
+        let mut node = tree.clone();
+        Stripper::new(opts).visit_node(&mut node);
+        assert_eq!(names(&node), ["Repo(load)"]);
+
+        let opts = ProcessOptions::builder()
+            .with_filters(vec!["partial".parse().unwrap()])
+            .build();
+        let mut node = tree.clone();
+        Stripper::new(opts).visit_node(&mut node);
+        assert_eq!(names(&node), ["Generated(init)"]);
+
+        // `without`: drop anything matching
+        let opts = ProcessOptions::builder()
+            .without_filters(vec!["abi=C".parse().unwrap(), "partial".parse().unwrap()])
+            .build();
+        let mut node = tree;
+        Stripper::new(opts).visit_node(&mut node);
+        assert_eq!(names(&node), ["Repo(load,close)", "Empty(noop)"]);
+    }
+
+    #[test]
+    fn test_variables_follow_visibility() {
+        let variable = |name: &str, visibility: Visibility| {
+            Node::Variable(Variable {
+                name: name.to_string(),
+                visibility,
+                source_visibility: None,
+                var_kind: VariableKind::Const,
+                modifiers: vec![],
+                var_type: None,
+                value: Some("1".to_string()),
+                is_mutable: false,
+                line: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })
+        };
+        let mut node = file(
+            "config.go",
+            vec![
+                variable("MaxRetries", Visibility::Public),
+                variable("defaultTimeout", Visibility::Private),
+            ],
+        );
+
+        let mut stripper = Stripper::new(ProcessOptions::default());
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        assert_eq!(f.children.len(), 1);
+        assert!(matches!(&f.children[0], Node::Variable(v) if v.name == "MaxRetries"));
+    }
+
+    #[test]
+    fn test_overload_sets_filter_and_collapse() {
+        let signature = |visibility: Visibility, line: usize| {
+            let Node::Function(mut f) = method("write", visibility) else {
+                unreachable!()
+            };
+            f.line_start = line;
+            f.line_end = line;
+            f
+        };
+        let overloads = || {
+            class(
+                "Writer",
+                vec![Node::OverloadSet(OverloadSet {
+                    name: "write".to_string(),
+                    signatures: vec![
+                        signature(Visibility::Public, 2),
+                        signature(Visibility::Private, 3),
+                        signature(Visibility::Public, 4),
+                    ],
+                    count: 3,
+                    line_start: 2,
+                    line_end: 4,
+                })],
+            )
+        };
+        let signatures = |node: &Node| {
+            let Node::Class(c) = node else {
+                panic!("expected class")
+            };
+            match &c.children[..] {
+                [Node::OverloadSet(o)] => (o.signatures.len(), o.count),
+                other => panic!("expected one overload set, got {other:?}"),
+            }
+        };
+
+        let mut node = overloads();
+        Stripper::new(ProcessOptions::default()).visit_node(&mut node);
+        assert_eq!(signatures(&node), (2, 3));
+
+        let mut node = overloads();
+        let opts = ProcessOptions::builder().collapse_overloads(true).build();
+        Stripper::new(opts).visit_node(&mut node);
+        assert_eq!(signatures(&node), (1, 3));
+
+        // A set whose signatures are all filtered out disappears
+        let mut node = overloads();
+        let opts = ProcessOptions {
+            include_methods: false,
+            prune_empty: false,
+            ..Default::default()
+        };
+        Stripper::new(opts).visit_node(&mut node);
+        let Node::Class(c) = node else {
+            panic!("expected class")
+        };
+        assert!(c.children.is_empty());
+    }
+
+    #[test]
+    fn test_property_accessors_follow_visibility() {
+        let mut setter = Accessor::new(AccessorKind::Set);
+        setter.visibility = Some(Visibility::Private);
+        setter.source_visibility = Some(SourceVisibility::Private);
+        let mut node = class(
+            "Order",
+            vec![Node::Property(Property {
+                name: "Total".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                modifiers: vec![],
+                property_type: None,
+                accessors: vec![Accessor::new(AccessorKind::Get), setter],
+                is_computed: false,
+                default_value: None,
+                line_start: 1,
+                line_end: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        );
+
+        let mut stripper = Stripper::new(ProcessOptions::default());
+        stripper.visit_node(&mut node);
+
+        let Node::Class(c) = node else {
+            panic!("expected class")
+        };
+        let Node::Property(p) = &c.children[0] else {
+            panic!("expected property")
+        };
+        assert_eq!(p.accessors, [Accessor::new(AccessorKind::Get)]);
+    }
+
+    #[test]
+    fn test_rust_test_module_excluded() {
+        let module = |name: &str, decorators: &[&str], children: Vec<Node>| {
+            Node::Module(Module {
+                name: name.to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                decorators: decorators.iter().map(ToString::to_string).collect(),
+                children,
+                line_start: 1,
+                line_end: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })
+        };
+        let mut node = file(
+            "src/lib.rs",
+            vec![
+                method("parse", Visibility::Public),
+                module(
+                    "tests",
+                    &["cfg(test)"],
+                    vec![method("helper", Visibility::Private)],
+                ),
+                module("internal", &[], vec![method("scan", Visibility::Private)]),
+            ],
+        );
+
+        let opts = ProcessOptions::builder()
+            .tests(TestMode::Exclude)
+            .include_private(true)
+            .build();
+        let mut stripper = Stripper::new(opts);
+        stripper.visit_node(&mut node);
+
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        assert_eq!(f.children.len(), 2);
+        assert!(matches!(&f.children[1], Node::Module(m) if m.name == "internal"));
+
+        // Modules emptied by filtering are pruned
+        let mut node = file(
+            "src/lib.rs",
+            vec![module(
+                "util",
+                &[],
+                vec![method("scan", Visibility::Private)],
+            )],
+        );
+        let mut stripper = Stripper::new(ProcessOptions::default());
+        stripper.visit_node(&mut node);
+        let Node::File(f) = node else {
+            panic!("expected file")
+        };
+        assert!(f.children.is_empty());
+    }
 }
@@ -0,0 +1,604 @@
+//! End-of-run summary
+//!
+//! One set of numbers - bytes, files, time, tokens, the token budget,
+//! pruned containers and where the output went - rendered in several
+//! styles: progress bar, ticker, dashboard and sparkline for terminals,
+//! `ci-friendly` for build logs, and `json`, one line with a stable schema,
+//! for dashboards. Callers print it to stderr so `--stdout` output stays
+//! clean.
+
+use crate::budget::Usage;
+use crate::ir::File;
+use crate::stripper::PruneStats;
+use crate::tokens::{FileTokens, LanguageTokens, TokenReport, Tokenizer};
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::fmt;
+use std::fmt::Write as _;
+use std::path::{Path, PathBuf};
+use std::str::FromStr;
+use std::time::Duration;
+
+/// Cells in the progress bar
+const BAR_WIDTH: usize = 15;
+
+/// How the summary is rendered
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum SummaryStyle {
+    /// Progress bar of the share saved, with sizes, time and tokens
+    #[default]
+    VisualProgressBar,
+    /// One compact stock-ticker line
+    StockTicker,
+    /// Multi-line box with every metric
+    SpeedometerDashboard,
+    /// One short line led by a sparkline
+    MinimalistSparkline,
+    /// Plain, grep-friendly line for build logs
+    CiFriendly,
+    /// One JSON object with a stable schema
+    Json,
+    /// No summary
+    Off,
+}
+
+impl SummaryStyle {
+    const NAMES: [(&'static str, Self); 7] = [
+        ("visual-progress-bar", Self::VisualProgressBar),
+        ("stock-ticker", Self::StockTicker),
+        ("speedometer-dashboard", Self::SpeedometerDashboard),
+        ("minimalist-sparkline", Self::MinimalistSparkline),
+        ("ci-friendly", Self::CiFriendly),
+        ("json", Self::Json),
+        ("off", Self::Off),
+    ];
+}
+
+impl FromStr for SummaryStyle {
+    type Err = String;
+
+    fn from_str(s: &str) -> std::result::Result<Self, Self::Err> {
+        let name = s.trim().to_ascii_lowercase();
+        Self::NAMES
+            .iter()
+            .find(|(known, _)| *known == name)
+            .map(|(_, style)| *style)
+            .ok_or_else(|| {
+                let names: Vec<&str> = Self::NAMES.iter().map(|(known, _)| *known).collect();
+                format!("invalid summary type '{s}' (expected {})", names.join(", "))
+            })
+    }
+}
+
+impl fmt::Display for SummaryStyle {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        let (name, _) = Self::NAMES
+            .iter()
+            .find(|(_, style)| style == self)
+            .expect("every style has a name");
+        f.write_str(name)
+    }
+}
+
+/// Numbers for one run
+#[derive(Debug, Clone, PartialEq)]
+pub struct Summary {
+    /// Size of the source files distilled
+    pub original_bytes: u64,
+    /// Size of the complete output
+    pub distilled_bytes: u64,
+    pub file_count: usize,
+    pub duration: Duration,
+    pub tokenizer: Tokenizer,
+    /// Token counts, if tokens were counted; files are listed only if
+    /// per-file counts were asked for
+    pub tokens: Option<TokenReport>,
+    /// The token budget and what was left out to meet it, if one was given
+    pub budget: Option<Usage>,
+    /// Containers left empty by filtering and pruned
+    pub pruned: PruneStats,
+    /// Output file, or `None` for stdout
+    pub output_path: Option<PathBuf>,
+}
+
+/// The `json` rendering; field names and order are a stable interface
+#[derive(Serialize)]
+struct JsonSummary<'a> {
+    original_bytes: u64,
+    distilled_bytes: u64,
+    savings_pct: f64,
+    duration_ms: u128,
+    tokens_before: Option<usize>,
+    tokens_after: Option<usize>,
+    tokens_saved: Option<usize>,
+    token_savings_pct: Option<f64>,
+    file_count: usize,
+    output_path: Option<&'a Path>,
+    tokenizer: Tokenizer,
+    pruned: PruneStats,
+    token_languages: Option<&'a BTreeMap<String, LanguageTokens>>,
+    token_files: Option<&'a [FileTokens]>,
+    budget: Option<&'a Usage>,
+}
+
+impl Summary {
+    /// Share of the original bytes removed, in percent
+    #[must_use]
+    #[allow(clippy::cast_precision_loss)]
+    pub fn savings_percent(&self) -> f64 {
+        if self.original_bytes == 0 {
+            return 0.0;
+        }
+        100.0 * (1.0 - self.distilled_bytes as f64 / self.original_bytes as f64)
+    }
+
+    /// Render in `style`, or `None` for [`SummaryStyle::Off`]
+    ///
+    /// Without `emoji` the human styles use plain text markers instead.
+    #[must_use]
+    pub fn render(&self, style: SummaryStyle, emoji: bool) -> Option<String> {
+        let rendered = self.headline(style, emoji)?;
+        let details = match style {
+            SummaryStyle::Json | SummaryStyle::SpeedometerDashboard | SummaryStyle::Off => {
+                Vec::new()
+            }
+            SummaryStyle::CiFriendly => self
+                .details(emoji)
+                .iter()
+                .map(|line| format!("[aid] {}", line.trim_start()))
+                .collect(),
+            _ => self.details(emoji),
+        };
+        Some(
+            details
+                .iter()
+                .fold(rendered, |text, line| text + "\n" + line),
+        )
+    }
+
+    /// The rendering in `style` without the lines of [`Self::details`],
+    /// which the dashboard has as rows
+    fn headline(&self, style: SummaryStyle, emoji: bool) -> Option<String> {
+        let icon = |fancy: &'static str, plain: &'static str| if emoji { fancy } else { plain };
+        let percent = self.savings_percent();
+        let sizes = format!(
+            "{} → {}",
+            format_bytes(self.original_bytes),
+            format_bytes(self.distilled_bytes)
+        );
+        let time = format_duration(self.duration);
+        let total = self.tokens.as_ref().map(|report| report.total);
+        let output = self
+            .output_path
+            .as_ref()
+            .map_or_else(|| "stdout".to_string(), |path| path.display().to_string());
+
+        let rendered = match style {
+            SummaryStyle::Off => return None,
+            SummaryStyle::Json => self.to_json(),
+            SummaryStyle::VisualProgressBar => {
+                let mut line = format!(
+                    "{} Distilled {} files [{}] {percent:.0}% ({sizes}) in {time}",
+                    icon("✨", "*"),
+                    self.file_count,
+                    progress_bar(percent)
+                );
+                if let Some(tokens) = total {
+                    write!(
+                        line,
+                        " {} ~{} tokens saved (~{} remaining)",
+                        icon("💰", "|"),
+                        format_count(tokens.original.saturating_sub(tokens.distilled)),
+                        format_count(tokens.distilled)
+                    )
+                    .unwrap();
+                }
+                if self.pruned.total() > 0 {
+                    write!(line, " {} Pruned {}", icon("🧹", "|"), self.pruned).unwrap();
+                }
+                format!("{line}\n{} Output: {output}", icon("📄", " "))
+            }
+            SummaryStyle::StockTicker => {
+                let mut line = format!(
+                    "{} AID {percent:.1}% {} │ SIZE: {sizes} │ TIME: {time}",
+                    icon("📊", "AID:"),
+                    if percent >= 0.0 { "▲" } else { "▼" },
+                );
+                if let Some(tokens) = total {
+                    write!(
+                        line,
+                        " │ TOKENS: ~{} saved",
+                        format_count(tokens.original.saturating_sub(tokens.distilled))
+                    )
+                    .unwrap();
+                }
+                if self.pruned.total() > 0 {
+                    write!(line, " │ PRUNED: {}", self.pruned.total()).unwrap();
+                }
+                format!("{line} │ OUT: {output}")
+            }
+            SummaryStyle::SpeedometerDashboard => {
+                let mut rows = vec![
+                    ("Files", self.file_count.to_string()),
+                    ("Size", sizes),
+                    ("Saved", format!("{percent:.1}%")),
+                ];
+                if let Some(tokens) = total {
+                    rows.push(("Tokens", format!("{tokens} [{}]", self.tokenizer)));
+                }
+                if self.pruned.total() > 0 {
+                    rows.push(("Pruned", self.pruned.to_string()));
+                }
+                rows.push(("Time", time));
+                rows.push(("Output", output));
+                let details = self.details(false);
+                rows.extend(details.iter().map(|line| ("", line.clone())));
+                dashboard("AI Distiller", &rows)
+            }
+            SummaryStyle::MinimalistSparkline => format!(
+                "{} {} files → {percent:.1}% reduction ({}) in {time} → {output} {}",
+                sparkline(percent),
+                self.file_count,
+                format_bytes(self.distilled_bytes),
+                icon("✓", "ok")
+            ),
+            SummaryStyle::CiFriendly => {
+                let mut line = format!(
+                    "[aid] {} {percent:.1}% saved | {sizes} | {time} | {} files",
+                    icon("✓", "OK"),
+                    self.file_count
+                );
+                if let Some(tokens) = total {
+                    write!(line, " | tokens {tokens}").unwrap();
+                }
+                if self.pruned.total() > 0 {
+                    write!(line, " | pruned {}", self.pruned).unwrap();
+                }
+                format!("{line} | {output}")
+            }
+        };
+        Some(rendered)
+    }
+
+    /// Lines for the token breakdown and the budget, below the summary
+    fn details(&self, emoji: bool) -> Vec<String> {
+        let icon = |fancy: &'static str, plain: &'static str| if emoji { fancy } else { plain };
+        let mut lines = Vec::new();
+        if let Some(report) = &self.tokens {
+            for (language, counts) in &report.languages {
+                lines.push(format!(
+                    "  {language} ({} files): {}",
+                    counts.files, counts.tokens
+                ));
+            }
+            for file in &report.files {
+                lines.push(format!("  {}: {}", file.path, file.tokens));
+            }
+        }
+        if let Some(budget) = &self.budget {
+            if let Some(note) = budget.note() {
+                lines.push(format!(
+                    "{} {note} ({} tokens)",
+                    icon("✂️ ", "-"),
+                    budget.tokens
+                ));
+            }
+            if !budget.fits() {
+                lines.push(format!(
+                    "{} Output is {} tokens, over the {}-token budget even with every \
+                     declaration dropped",
+                    icon("⚠️ ", "!"),
+                    budget.tokens,
+                    budget.max_tokens
+                ));
+            }
+        }
+        lines
+    }
+
+    /// The `json` rendering
+    fn to_json(&self) -> String {
+        let tokens = self.tokens.as_ref().map(|report| report.total);
+        let json = JsonSummary {
+            original_bytes: self.original_bytes,
+            distilled_bytes: self.distilled_bytes,
+            savings_pct: self.savings_percent(),
+            duration_ms: self.duration.as_millis(),
+            tokens_before: tokens.map(|t| t.original),
+            tokens_after: tokens.map(|t| t.distilled),
+            tokens_saved: tokens.map(|t| t.original.saturating_sub(t.distilled)),
+            token_savings_pct: tokens.map(|t| t.saved_percent()),
+            file_count: self.file_count,
+            output_path: self.output_path.as_deref(),
+            tokenizer: self.tokenizer,
+            pruned: self.pruned,
+            token_languages: self.tokens.as_ref().map(|report| &report.languages),
+            token_files: self.tokens.as_ref().map(|report| report.files.as_slice()),
+            budget: self.budget.as_ref(),
+        };
+        serde_json::to_string(&json).expect("summary serializes")
+    }
+}
+
+/// Total size on disk of the source files behind `files`
+///
+/// Files that can't be read any more count as empty.
+#[must_use]
+pub fn source_bytes(files: &[File]) -> u64 {
+    files
+        .iter()
+        .filter_map(|file| std::fs::metadata(&file.path).ok())
+        .map(|metadata| metadata.len())
+        .sum()
+}
+
+/// Bar with one filled cell per `1 / BAR_WIDTH` of `percent` saved
+#[allow(
+    clippy::cast_possible_truncation,
+    clippy::cast_sign_loss,
+    clippy::cast_precision_loss
+)]
+fn progress_bar(percent: f64) -> String {
+    let filled = ((percent.clamp(0.0, 100.0) / 100.0) * BAR_WIDTH as f64).round() as usize;
+    format!("{}{}", "█".repeat(filled), "░".repeat(BAR_WIDTH - filled))
+}
+
+/// Rising sparkline that ends higher the more was saved
+#[allow(clippy::cast_possible_truncation, clippy::cast_sign_loss)]
+fn sparkline(percent: f64) -> String {
+    const LEVELS: [char; 8] = ['▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'];
+    let top = ((percent.clamp(0.0, 100.0) / 100.0) * 7.0).round() as usize;
+    (0..5).map(|step| LEVELS[top * step / 4]).collect()
+}
+
+/// Box of label/value rows under `title`
+///
+/// Only single-width characters keep the right edge aligned, so the box
+/// has no emoji in either mode.
+fn dashboard(title: &str, rows: &[(&str, String)]) -> String {
+    let label_width = rows.iter().map(|(label, _)| label.len()).max().unwrap_or(0);
+    let lines: Vec<String> = rows
+        .iter()
+        .map(|(label, value)| format!("{label:<label_width$}  {value}"))
+        .collect();
+    let width = lines
+        .iter()
+        .map(|line| line.chars().count())
+        .chain([title.chars().count()])
+        .max()
+        .unwrap_or(0);
+
+    let mut out = format!("╭─{}─╮\n", "─".repeat(width));
+    writeln!(out, "│ {title}{} │", pad(title, width)).unwrap();
+    writeln!(out, "├─{}─┤", "─".repeat(width)).unwrap();
+    for line in &lines {
+        writeln!(out, "│ {line}{} │", pad(line, width)).unwrap();
+    }
+    write!(out, "╰─{}─╯", "─".repeat(width)).unwrap();
+    out
+}
+
+/// Spaces that pad `text` to `width` characters
+fn pad(text: &str, width: usize) -> String {
+    " ".repeat(width.saturating_sub(text.chars().count()))
+}
+
+/// Size in decimal units, e.g. `980 B`, `21.4 kB`, `3.1 MB`
+#[allow(clippy::cast_precision_loss)]
+fn format_bytes(bytes: u64) -> String {
+    const UNITS: [&str; 3] = ["kB", "MB", "GB"];
+    if bytes < 1000 {
+        return format!("{bytes} B");
+    }
+    let mut value = bytes as f64 / 1000.0;
+    let mut unit = UNITS[0];
+    for next in &UNITS[1..] {
+        if value < 1000.0 {
+            break;
+        }
+        value /= 1000.0;
+        unit = next;
+    }
+    format!("{value:.1} {unit}")
+}
+
+/// Count with a `k`/`M` suffix, e.g. `64.0k`, `2.4M`
+#[allow(clippy::cast_precision_loss)]
+fn format_count(count: usize) -> String {
+    match count {
+        0..1_000 => count.to_string(),
+        1_000..1_000_000 => format!("{:.1}k", count as f64 / 1e3),
+        _ => format!("{:.1}M", count as f64 / 1e6),
+    }
+}
+
+/// `231ms` below a second, `4.2s` above
+fn format_duration(duration: Duration) -> String {
+    if duration < Duration::from_secs(1) {
+        format!("{}ms", duration.as_millis())
+    } else {
+        format!("{:.1}s", duration.as_secs_f64())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::budget::Omission;
+    use crate::tokens::TokenCount;
+
+    fn summary() -> Summary {
+        let mut tokens = TokenReport::new(Tokenizer::Cl100k);
+        tokens.languages.insert(
+            "python".to_string(),
+            LanguageTokens {
+                files: 9,
+                tokens: TokenCount {
+                    original: 17_505,
+                    distilled: 1_900,
+                },
+            },
+        );
+        tokens.total = TokenCount {
+            original: 17_505,
+            distilled: 2_061,
+        };
+        Summary {
+            original_bytes: 70_020,
+            distilled_bytes: 8_244,
+            file_count: 9,
+            duration: Duration::from_millis(6),
+            tokenizer: Tokenizer::Cl100k,
+            tokens: Some(tokens),
+            budget: None,
+            pruned: PruneStats {
+                files: 1,
+                packages: 0,
+                classes: 2,
+            },
+            output_path: Some(PathBuf::from(".aid/src.pub.txt")),
+        }
+    }
+
+    #[test]
+    fn test_style_names() {
+        for (name, style) in SummaryStyle::NAMES {
+            assert_eq!(name.parse::<SummaryStyle>(), Ok(style));
+            assert_eq!(style.to_string(), name);
+        }
+        assert!("fancy".parse::<SummaryStyle>().is_err());
+    }
+
+    #[test]
+    fn test_json_schema() {
+        let rendered = summary().render(SummaryStyle::Json, true).unwrap();
+        assert!(!rendered.contains('\n'), "one line per run");
+        let json: serde_json::Value = serde_json::from_str(&rendered).unwrap();
+        let keys = [
+            "original_bytes",
+            "distilled_bytes",
+            "savings_pct",
+            "duration_ms",
+            "tokens_before",
+            "tokens_after",
+            "tokens_saved",
+            "token_savings_pct",
+            "file_count",
+            "output_path",
+            "tokenizer",
+            "pruned",
+            "token_languages",
+            "token_files",
+            "budget",
+        ];
+        let positions: Vec<usize> = keys
+            .iter()
+            .map(|key| rendered.find(&format!("\"{key}\":")).unwrap())
+            .collect();
+        assert!(positions.is_sorted(), "keys in schema order");
+        assert_eq!(json.as_object().unwrap().len(), keys.len());
+        assert_eq!(json["tokens_saved"], 15_444);
+        assert_eq!(json["pruned"]["classes"], 2);
+        assert_eq!(json["token_languages"]["python"]["distilled"], 1_900);
+        assert_eq!(json["token_files"], serde_json::json!([]));
+        assert_eq!(json["tokenizer"], "cl100k");
+
+        let stdout = Summary {
+            tokens: None,
+            output_path: None,
+            ..summary()
+        };
+        let json: serde_json::Value = serde_json::from_str(&stdout.to_json()).unwrap();
+        assert!(json["tokens_before"].is_null());
+        assert!(json["token_languages"].is_null());
+        assert!(json["budget"].is_null());
+        assert!(json["output_path"].is_null());
+    }
+
+    #[test]
+    fn test_human_styles() {
+        let summary = summary();
+        assert_eq!(
+            summary.render(SummaryStyle::CiFriendly, false).unwrap(),
+            "[aid] OK 88.2% saved | 70.0 kB → 8.2 kB | 6ms | 9 files \
+             | tokens 17505 → 2061 (88.2% saved) | pruned 3 (1 file(s), 0 package(s), 2 class(es)) \
+             | .aid/src.pub.txt\n\
+             [aid] python (9 files): 17505 → 1900 (89.1% saved)"
+        );
+        let unpruned = Summary {
+            pruned: PruneStats::default(),
+            ..summary.clone()
+        };
+        assert!(
+            !unpruned
+                .render(SummaryStyle::CiFriendly, false)
+                .unwrap()
+                .contains("pruned")
+        );
+        assert!(
+            summary
+                .render(SummaryStyle::VisualProgressBar, true)
+                .unwrap()
+                .starts_with(
+                    "✨ Distilled 9 files [█████████████░░] 88% (70.0 kB → 8.2 kB) in 6ms"
+                )
+        );
+        assert!(
+            !summary
+                .render(SummaryStyle::StockTicker, false)
+                .unwrap()
+                .contains('📊')
+        );
+        assert_eq!(summary.render(SummaryStyle::Off, true), None);
+
+        let dashboard = summary
+            .render(SummaryStyle::SpeedometerDashboard, true)
+            .unwrap();
+        let widths: Vec<usize> = dashboard.lines().map(|l| l.chars().count()).collect();
+        assert!(widths.iter().all(|w| *w == widths[0]));
+    }
+
+    #[test]
+    fn test_budget() {
+        let summary = Summary {
+            budget: Some(Usage {
+                tokens: 4_200,
+                max_tokens: 4_000,
+                omitted: vec![Omission::Implementations, Omission::Declarations(3)],
+            }),
+            ..summary()
+        };
+        let rendered = summary.render(SummaryStyle::StockTicker, false).unwrap();
+        let lines: Vec<&str> = rendered.lines().collect();
+        assert_eq!(
+            &lines[2..],
+            [
+                "- Omitted to fit 4000 tokens: implementations, 3 lowest-ranked declarations \
+                 (4200 tokens)",
+                "! Output is 4200 tokens, over the 4000-token budget even with every \
+                 declaration dropped",
+            ]
+        );
+
+        let json: serde_json::Value =
+            serde_json::from_str(&summary.render(SummaryStyle::Json, true).unwrap()).unwrap();
+        assert_eq!(json["budget"]["max_tokens"], 4_000);
+        assert_eq!(json["budget"]["omitted"][0], "implementations");
+
+        let dashboard = summary
+            .render(SummaryStyle::SpeedometerDashboard, true)
+            .unwrap();
+        assert!(dashboard.contains("Omitted to fit 4000 tokens"));
+        let widths: Vec<usize> = dashboard.lines().map(|l| l.chars().count()).collect();
+        assert!(widths.iter().all(|w| *w == widths[0]));
+    }
+
+    #[test]
+    fn test_units() {
+        assert_eq!(format_bytes(999), "999 B");
+        assert_eq!(format_bytes(10_485_760), "10.5 MB");
+        assert_eq!(format_count(2_400_000), "2.4M");
+        assert_eq!(format_duration(Duration::from_millis(4_200)), "4.2s");
+        assert_eq!(sparkline(100.0), "▁▂▄▆█");
+    }
+}
@@ -0,0 +1,457 @@
+//! Test code detection
+//!
+//! Classifies whole files and individual IR symbols as test code using
+//! per-language conventions (file naming, test attributes, test base classes).
+//! The Stripper and directory walker use this to honor `--tests=0|1|only`.
+
+use crate::ir::{Node, TypeRef};
+use std::fmt;
+use std::path::{Component, Path};
+use std::str::FromStr;
+
+/// How test code should be treated
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum TestMode {
+    /// Keep test code alongside everything else (`--tests=1`)
+    #[default]
+    Include,
+    /// Drop test files and test symbols (`--tests=0`)
+    Exclude,
+    /// Keep only test files and test symbols (`--tests=only`)
+    Only,
+}
+
+impl FromStr for TestMode {
+    type Err = String;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        match s.trim().to_ascii_lowercase().as_str() {
+            "1" | "true" | "include" => Ok(Self::Include),
+            "0" | "false" | "exclude" => Ok(Self::Exclude),
+            "only" => Ok(Self::Only),
+            other => Err(format!(
+                "invalid test mode '{other}' (expected 0, 1 or only)"
+            )),
+        }
+    }
+}
+
+impl fmt::Display for TestMode {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Include => write!(f, "1"),
+            Self::Exclude => write!(f, "0"),
+            Self::Only => write!(f, "only"),
+        }
+    }
+}
+
+/// Language families that share test conventions
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Family {
+    Go,
+    Python,
+    Rust,
+    JavaScript,
+    Jvm,
+    CSharp,
+    Ruby,
+    Php,
+    Swift,
+    C,
+}
+
+impl Family {
+    fn from_path(path: &Path) -> Option<Self> {
+        let ext = path.extension()?.to_str()?;
+        Some(match ext {
+            "go" => Self::Go,
+            "py" | "pyi" => Self::Python,
+            "rs" => Self::Rust,
+            "js" | "jsx" | "mjs" | "cjs" | "ts" | "tsx" | "mts" | "cts" => Self::JavaScript,
+            "java" | "kt" | "kts" => Self::Jvm,
+            "cs" => Self::CSharp,
+            "rb" => Self::Ruby,
+            "php" => Self::Php,
+            "swift" => Self::Swift,
+            "c" | "h" | "cc" | "cpp" | "cxx" | "hpp" | "hh" | "hxx" => Self::C,
+            _ => return None,
+        })
+    }
+
+    /// Attributes/annotations that mark a function as test code
+    fn test_function_attributes(self) -> &'static [&'static str] {
+        match self {
+            Self::Rust => &["test", "bench", "rstest", "cfg(test)"],
+            Self::Python => &["pytest.fixture", "pytest.mark"],
+            Self::Jvm => &[
+                "Test",
+                "ParameterizedTest",
+                "RepeatedTest",
+                "TestFactory",
+                "TestTemplate",
+                "BeforeEach",
+                "AfterEach",
+                "BeforeAll",
+                "AfterAll",
+                "Before",
+                "After",
+                "BeforeClass",
+                "AfterClass",
+            ],
+            Self::CSharp => &[
+                "Test",
+                "TestCase",
+                "TestCaseSource",
+                "Fact",
+                "Theory",
+                "TestMethod",
+                "DataTestMethod",
+                "SetUp",
+                "TearDown",
+                "OneTimeSetUp",
+                "OneTimeTearDown",
+                "TestInitialize",
+                "TestCleanup",
+            ],
+            Self::Php => &["Test", "DataProvider"],
+            Self::Swift => &["Test"],
+            Self::Go | Self::JavaScript | Self::Ruby | Self::C => &[],
+        }
+    }
+
+    /// Attributes/annotations that mark a type as test code
+    fn test_class_attributes(self) -> &'static [&'static str] {
+        match self {
+            Self::Rust => &["cfg(test)"],
+            Self::CSharp => &["TestClass", "TestFixture"],
+            Self::Jvm => &["Nested"],
+            Self::Swift => &["Suite"],
+            _ => &[],
+        }
+    }
+
+    /// Base classes whose subclasses are test cases
+    fn test_base_classes(self) -> &'static [&'static str] {
+        match self {
+            Self::Python => &["TestCase", "IsolatedAsyncioTestCase"],
+            Self::Jvm | Self::Php => &["TestCase"],
+            Self::Swift => &["XCTestCase"],
+            Self::Ruby => &[
+                "Minitest::Test",
+                "Test::Unit::TestCase",
+                "ActiveSupport::TestCase",
+                "ActionDispatch::IntegrationTest",
+            ],
+            _ => &[],
+        }
+    }
+}
+
+/// Check if a file is test code based on its path
+///
+/// Pass paths relative to the project root where possible, so directories
+/// named `test`/`tests` above the project don't turn everything into tests.
+#[must_use]
+pub fn is_test_file(path: &Path) -> bool {
+    let Some(family) = Family::from_path(path) else {
+        return false;
+    };
+    let file_name = path
+        .file_name()
+        .and_then(|n| n.to_str())
+        .unwrap_or_default();
+    let stem = path
+        .file_stem()
+        .and_then(|n| n.to_str())
+        .unwrap_or_default();
+
+    let name_matches = match family {
+        Family::Go => file_name.ends_with("_test.go"),
+        Family::Python => {
+            stem.starts_with("test_") || stem.ends_with("_test") || stem == "conftest"
+        }
+        Family::JavaScript => [".test.", ".spec.", "-test.", "_test."]
+            .iter()
+            .any(|marker| file_name.contains(marker)),
+        Family::Jvm | Family::CSharp | Family::Php | Family::Swift => ["Test", "Tests"]
+            .iter()
+            .any(|suffix| stem.ends_with(suffix) && stem.len() > suffix.len()),
+        Family::Ruby => stem.ends_with("_spec") || stem.ends_with("_test"),
+        Family::C => {
+            stem.starts_with("test_") || stem.ends_with("_test") || stem.ends_with("_unittest")
+        }
+        Family::Rust => false,
+    };
+
+    name_matches || in_test_directory(path, family)
+}
+
+/// Check if any directory component of the path is a conventional test directory
+fn in_test_directory(path: &Path, family: Family) -> bool {
+    let Some(parent) = path.parent() else {
+        return false;
+    };
+
+    parent.components().any(|component| {
+        let Component::Normal(name) = component else {
+            return false;
+        };
+        let name = name.to_str().unwrap_or_default();
+        match name {
+            "test" | "tests" | "__tests__" => true,
+            "spec" => matches!(family, Family::Ruby | Family::JavaScript),
+            _ => {
+                // .NET test projects (`Foo.Tests`) and Swift test targets (`FooTests`)
+                (family == Family::CSharp && (name.ends_with(".Tests") || name.ends_with(".Test")))
+                    || (family == Family::Swift && name.ends_with("Tests"))
+            }
+        }
+    })
+}
+
+/// Check if an IR symbol is test code
+///
+/// `path` is the path of the file containing the symbol and selects the
+/// language rules to apply.
+#[must_use]
+pub fn is_test_symbol(node: &Node, path: &Path) -> bool {
+    let Some(family) = Family::from_path(path) else {
+        return false;
+    };
+
+    match node {
+        Node::Function(f) => {
+            has_attribute(&f.decorators, family.test_function_attributes())
+                || is_test_function_name(&f.name, family, path)
+        }
+        Node::Module(m) => has_attribute(&m.decorators, family.test_class_attributes()),
+        Node::Class(c) => {
+            has_attribute(&c.decorators, family.test_class_attributes())
+                || extends_test_base(&c.extends, family)
+                || (family == Family::Python && is_test_file(path) && is_test_class_name(&c.name))
+        }
+        _ => false,
+    }
+}
+
+/// Check naming conventions for test functions
+fn is_test_function_name(name: &str, family: Family, path: &Path) -> bool {
+    match family {
+        // TestXxx, BenchmarkXxx, ExampleXxx and FuzzXxx (the suffix must not
+        // start lowercase), which `go test` only runs from `_test.go` files
+        Family::Go if !path.to_string_lossy().ends_with("_test.go") => false,
+        Family::Go => ["Test", "Benchmark", "Example", "Fuzz"]
+            .iter()
+            .any(|prefix| {
+                name.strip_prefix(prefix)
+                    .is_some_and(|rest| !rest.starts_with(|c: char| c.is_ascii_lowercase()))
+            }),
+        // pytest only collects these from test files; elsewhere they are
+        // ordinary names (`def test_connection()` in `db.py`)
+        Family::Python if !is_test_file(path) => false,
+        Family::Python => name == "test" || name.starts_with("test_"),
+        _ => false,
+    }
+}
+
+/// Check the `TestXxx` naming convention for Python test classes
+///
+/// `Test` must end the name or be followed by an uppercase letter or `_`,
+/// so `Testimonial` isn't a test.
+fn is_test_class_name(name: &str) -> bool {
+    name.strip_prefix("Test").is_some_and(|rest| {
+        rest.chars()
+            .next()
+            .is_none_or(|c| c.is_ascii_uppercase() || c == '_')
+    })
+}
+
+/// Check if any base type is a known test base class
+fn extends_test_base(extends: &[TypeRef], family: Family) -> bool {
+    let bases = family.test_base_classes();
+    extends.iter().any(|base| {
+        let name = base.name.trim();
+        bases.contains(&name) || bases.contains(&last_segment(name))
+    })
+}
+
+/// Check if any decorator matches one of the given attribute names
+///
+/// Decorators are stored as written (`@Test`, `[Fact]`, `#[tokio::test]`),
+/// so wrappers, arguments and path prefixes are stripped before comparing.
+/// `cfg(test)` is compared with its argument since the bare `cfg` isn't a marker.
+fn has_attribute(decorators: &[String], names: &[&str]) -> bool {
+    decorators.iter().any(|decorator| {
+        let full = normalize_attribute(decorator);
+        let base = full.split('(').next().unwrap_or_default().trim();
+        names.iter().any(|name| {
+            *name == full
+                || *name == base
+                || *name == last_segment(base)
+                || base.starts_with(&format!("{name}."))
+        })
+    })
+}
+
+/// Strip attribute syntax (`@`, `#[...]`, `[...]`) and a trailing `Attribute` suffix
+fn normalize_attribute(decorator: &str) -> String {
+    let text = decorator.trim();
+    let text = text
+        .strip_prefix("#[")
+        .or_else(|| text.strip_prefix('['))
+        .map_or(text, |inner| inner.strip_suffix(']').unwrap_or(inner));
+    let text = text.trim_start_matches('@').trim();
+
+    let compact: String = text.chars().filter(|c| !c.is_whitespace()).collect();
+    match compact.split_once('(') {
+        Some((name, args)) => {
+            let name = name.strip_suffix("Attribute").unwrap_or(name);
+            format!("{name}({args}")
+        }
+        None => compact
+            .strip_suffix("Attribute")
+            .map_or_else(|| compact.clone(), str::to_string),
+    }
+}
+
+/// Last segment of a qualified name (`unittest.TestCase` -> `TestCase`)
+fn last_segment(name: &str) -> &str {
+    name.rsplit(['.', ':', '\\']).next().unwrap_or(name)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ir::{Class, Function, Visibility};
+    use std::collections::BTreeMap;
+
+    fn function(name: &str, decorators: &[&str]) -> Node {
+        Node::Function(Function {
+            name: name.to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: decorators.iter().map(ToString::to_string).collect(),
+            type_params: vec![],
+            parameters: vec![],
+            return_type: None,
+            implementation: None,
+            line_start: 1,
+            line_end: 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    fn class(name: &str, extends: &[&str]) -> Node {
+        Node::Class(Class {
+            name: name.to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            extends: extends.iter().map(|e| TypeRef::new(*e)).collect(),
+            implements: vec![],
+            children: vec![],
+            line_start: 1,
+            line_end: 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    #[test]
+    fn test_mode_parsing() {
+        assert_eq!("0".parse::<TestMode>().unwrap(), TestMode::Exclude);
+        assert_eq!("1".parse::<TestMode>().unwrap(), TestMode::Include);
+        assert_eq!("only".parse::<TestMode>().unwrap(), TestMode::Only);
+        assert!("2".parse::<TestMode>().is_err());
+        assert_eq!(TestMode::Only.to_string(), "only");
+    }
+
+    #[test]
+    fn test_file_classification() {
+        assert!(is_test_file(Path::new("pkg/server_test.go")));
+        assert!(!is_test_file(Path::new("pkg/server.go")));
+        assert!(is_test_file(Path::new("app/test_models.py")));
+        assert!(is_test_file(Path::new("app/conftest.py")));
+        assert!(is_test_file(Path::new("src/button.test.tsx")));
+        assert!(is_test_file(Path::new("src/__tests__/button.ts")));
+        assert!(is_test_file(Path::new(
+            "src/test/java/UserServiceTest.java"
+        )));
+        assert!(is_test_file(Path::new("Api.Tests/UserService.cs")));
+        assert!(is_test_file(Path::new("spec/models/user_spec.rb")));
+        assert!(is_test_file(Path::new("crate/tests/integration.rs")));
+        assert!(!is_test_file(Path::new("src/lib.rs")));
+        assert!(!is_test_file(Path::new("src/Test.java")));
+        assert!(is_test_file(Path::new("src/test/java/UserIT.java")));
+        assert!(is_test_file(Path::new("src/test/kotlin/UserSpec.kt")));
+        assert!(!is_test_file(Path::new("src/main/java/AuditIT.java")));
+        assert!(!is_test_file(Path::new("src/main/kotlin/ApiSpec.kt")));
+        assert!(!is_test_file(Path::new("README.md")));
+    }
+
+    #[test]
+    fn test_symbol_attributes() {
+        let py = Path::new("conftest_helpers.py");
+        assert!(is_test_symbol(&function("db", &["@pytest.fixture"]), py));
+        assert!(is_test_symbol(
+            &function("check", &["@pytest.mark.parametrize(\"x\", [1, 2])"]),
+            py
+        ));
+
+        let rs = Path::new("src/lib.rs");
+        assert!(is_test_symbol(&function("works", &["test"]), rs));
+        assert!(is_test_symbol(&function("works", &["#[tokio::test]"]), rs));
+        assert!(is_test_symbol(&function("helper", &["cfg(test)"]), rs));
+        assert!(!is_test_symbol(
+            &function("helper", &["cfg(feature = \"x\")"]),
+            rs
+        ));
+
+        let java = Path::new("src/main/java/Foo.java");
+        assert!(is_test_symbol(&function("works", &["@Test"]), java));
+        assert!(!is_test_symbol(&function("works", &["@Override"]), java));
+
+        let cs = Path::new("Foo.cs");
+        assert!(is_test_symbol(&function("Works", &["[Fact]"]), cs));
+        assert!(is_test_symbol(
+            &function("Works", &["[TestMethodAttribute]"]),
+            cs
+        ));
+    }
+
+    #[test]
+    fn test_symbol_names_and_bases() {
+        let go = Path::new("server_test.go");
+        assert!(is_test_symbol(&function("TestServe", &[]), go));
+        assert!(is_test_symbol(&function("BenchmarkServe", &[]), go));
+        assert!(!is_test_symbol(&function("Testify", &[]), go));
+        // Production code may use the same names outside `_test.go` files
+        let go = Path::new("server.go");
+        assert!(!is_test_symbol(&function("TestConnection", &[]), go));
+        assert!(!is_test_symbol(&function("ExampleHandler", &[]), go));
+
+        let py = Path::new("tests/test_models.py");
+        assert!(is_test_symbol(&function("test_save", &[]), py));
+        assert!(!is_test_symbol(&function("testimony", &[]), py));
+        assert!(is_test_symbol(&class("TestUser", &[]), py));
+        assert!(is_test_symbol(&class("Test_Legacy", &[]), py));
+        assert!(!is_test_symbol(&class("Testimonial", &[]), py));
+        // Test names in production modules are ordinary symbols
+        let py = Path::new("app/db.py");
+        assert!(!is_test_symbol(&function("test_connection", &[]), py));
+        assert!(!is_test_symbol(&class("TestUser", &[]), py));
+        assert!(is_test_symbol(
+            &class("UserCase", &["unittest.TestCase"]),
+            py
+        ));
+
+        let rb = Path::new("user.rb");
+        assert!(is_test_symbol(&class("UserTest", &["Minitest::Test"]), rb));
+        assert!(!is_test_symbol(&class("User", &["ApplicationRecord"]), rb));
+    }
+}
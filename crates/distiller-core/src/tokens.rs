@@ -0,0 +1,343 @@
+//! Token counting
+//!
+//! Sizes are reported in LLM tokens. `cl100k` and `o200k` run the real BPE
+//! tokenizers with their vocabularies compiled into the binary, so counting
+//! works offline; `estimate` is a byte-based approximation (about four
+//! bytes per token) for when speed matters more than precision.
+
+use crate::encoding::{self, Encoding};
+use crate::error::{DistilError, Result};
+use crate::ir::File;
+use rayon::prelude::*;
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::fmt;
+use std::path::Path;
+use std::str::FromStr;
+use std::sync::OnceLock;
+use tiktoken_rs::CoreBPE;
+
+/// Bytes per token assumed by the estimate
+const BYTES_PER_TOKEN: usize = 4;
+
+/// Which tokenizer to count with
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Tokenizer {
+    /// `cl100k_base` (GPT-4, GPT-3.5)
+    #[default]
+    Cl100k,
+    /// `o200k_base` (GPT-4o and later)
+    O200k,
+    /// Byte-based estimate, no tokenization
+    Estimate,
+}
+
+impl FromStr for Tokenizer {
+    type Err = String;
+
+    fn from_str(s: &str) -> std::result::Result<Self, Self::Err> {
+        match s.trim().to_ascii_lowercase().as_str() {
+            "cl100k" | "cl100k_base" => Ok(Self::Cl100k),
+            "o200k" | "o200k_base" => Ok(Self::O200k),
+            "estimate" | "bytes" => Ok(Self::Estimate),
+            other => Err(format!(
+                "invalid tokenizer '{other}' (expected cl100k, o200k or estimate)"
+            )),
+        }
+    }
+}
+
+impl fmt::Display for Tokenizer {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Cl100k => write!(f, "cl100k"),
+            Self::O200k => write!(f, "o200k"),
+            Self::Estimate => write!(f, "estimate"),
+        }
+    }
+}
+
+impl Tokenizer {
+    /// Number of tokens in `text`
+    #[must_use]
+    pub fn count(self, text: &str) -> usize {
+        let bpe = match self {
+            Self::Cl100k => {
+                static CL100K: OnceLock<Option<CoreBPE>> = OnceLock::new();
+                CL100K.get_or_init(|| load(tiktoken_rs::cl100k_base()))
+            }
+            Self::O200k => {
+                static O200K: OnceLock<Option<CoreBPE>> = OnceLock::new();
+                O200K.get_or_init(|| load(tiktoken_rs::o200k_base()))
+            }
+            Self::Estimate => return estimate(text),
+        };
+
+        match bpe {
+            Some(bpe) => bpe.encode_ordinary(text).len(),
+            None => estimate(text),
+        }
+    }
+}
+
+/// Keep a loaded vocabulary, falling back to the estimate if it failed
+fn load(bpe: anyhow::Result<CoreBPE>) -> Option<CoreBPE> {
+    bpe.inspect_err(|e| log::warn!("Tokenizer unavailable, estimating: {e}"))
+        .ok()
+}
+
+/// Byte-based token estimate
+#[must_use]
+pub fn estimate(text: &str) -> usize {
+    text.len().div_ceil(BYTES_PER_TOKEN)
+}
+
+/// Tokens before and after distillation
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize)]
+pub struct TokenCount {
+    pub original: usize,
+    pub distilled: usize,
+}
+
+impl TokenCount {
+    /// Share of the original tokens removed, in percent
+    #[must_use]
+    #[allow(clippy::cast_precision_loss)]
+    pub fn saved_percent(&self) -> f64 {
+        if self.original == 0 {
+            return 0.0;
+        }
+        100.0 * (1.0 - self.distilled as f64 / self.original as f64)
+    }
+}
+
+impl std::ops::AddAssign for TokenCount {
+    fn add_assign(&mut self, other: Self) {
+        self.original += other.original;
+        self.distilled += other.distilled;
+    }
+}
+
+impl fmt::Display for TokenCount {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(
+            f,
+            "{} → {} ({:.1}% saved)",
+            self.original,
+            self.distilled,
+            self.saved_percent()
+        )
+    }
+}
+
+/// Token counts for one file
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct FileTokens {
+    pub path: String,
+    pub language: String,
+    #[serde(flatten)]
+    pub tokens: TokenCount,
+}
+
+/// Token counts for one language
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize)]
+pub struct LanguageTokens {
+    pub files: usize,
+    #[serde(flatten)]
+    pub tokens: TokenCount,
+}
+
+/// Token counts per file, per language and in total
+#[derive(Debug, Clone, Default, PartialEq, Serialize)]
+pub struct TokenReport {
+    pub tokenizer: Tokenizer,
+    pub files: Vec<FileTokens>,
+    pub languages: BTreeMap<String, LanguageTokens>,
+    /// Sum of the originals, and the complete output with its framing
+    pub total: TokenCount,
+}
+
+impl TokenReport {
+    /// Empty report counting with `tokenizer`
+    #[must_use]
+    pub fn new(tokenizer: Tokenizer) -> Self {
+        Self {
+            tokenizer,
+            ..Self::default()
+        }
+    }
+
+    /// Add one file's counts
+    pub fn add_file(&mut self, file: FileTokens) {
+        let language = self.languages.entry(file.language.clone()).or_default();
+        language.files += 1;
+        language.tokens += file.tokens;
+        self.total.original += file.tokens.original;
+        self.files.push(file);
+    }
+
+    /// Count distilled `files` and the complete `output` they formatted to
+    ///
+    /// `original_of` gives the source of a file that isn't on disk, such
+    /// as source from stdin; other originals are read back from each file's
+    /// path and decoded like the source was, trying `fallbacks` when they
+    /// aren't UTF-8. `format` renders a single file the way it appears in the
+    /// output, and `language_of` names the language of a path.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if an original can't be read or `format` fails.
+    pub fn build<E, F, L, O>(
+        tokenizer: Tokenizer,
+        files: &[File],
+        output: &str,
+        fallbacks: &[Encoding],
+        original_of: O,
+        language_of: L,
+        format: F,
+    ) -> std::result::Result<Self, E>
+    where
+        E: From<DistilError> + Send,
+        F: Fn(&File) -> std::result::Result<String, E> + Sync,
+        L: Fn(&Path) -> Option<&'static str> + Sync,
+        O: Fn(&File) -> Option<String> + Sync,
+    {
+        let counted = files
+            .par_iter()
+            .map(|file| {
+                let path = Path::new(&file.path);
+                let original = match original_of(file) {
+                    Some(source) => source,
+                    None => read_original(path, fallbacks)?,
+                };
+                let distilled = format(file)?;
+                Ok(FileTokens {
+                    path: file.path.clone(),
+                    language: language_of(path).unwrap_or("unknown").to_string(),
+                    tokens: TokenCount {
+                        original: tokenizer.count(&original),
+                        distilled: tokenizer.count(&distilled),
+                    },
+                })
+            })
+            .collect::<std::result::Result<Vec<_>, E>>()?;
+
+        let mut report = Self::new(tokenizer);
+        for file in counted {
+            report.add_file(file);
+        }
+        report.total.distilled = tokenizer.count(output);
+        Ok(report)
+    }
+}
+
+/// Source text of an original file
+///
+/// Decoded as by [`encoding::decode`], without logging again what reading
+/// the source already logged.
+fn read_original(path: &Path, fallbacks: &[Encoding]) -> Result<String> {
+    let bytes = std::fs::read(path).map_err(|e| DistilError::io(path, e))?;
+    Ok(encoding::decode(&bytes, fallbacks).text)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_tokenizer_names() {
+        assert_eq!("o200k_base".parse::<Tokenizer>(), Ok(Tokenizer::O200k));
+        assert_eq!("Estimate".parse::<Tokenizer>(), Ok(Tokenizer::Estimate));
+        assert!("gpt2".parse::<Tokenizer>().is_err());
+        assert_eq!(Tokenizer::default().to_string(), "cl100k");
+    }
+
+    #[test]
+    fn test_estimate() {
+        assert_eq!(estimate(""), 0);
+        assert_eq!(estimate("abcd"), 1);
+        assert_eq!(Tokenizer::Estimate.count("def main():"), 3);
+        assert!(Tokenizer::Cl100k.count("def main(): pass") > 0);
+    }
+
+    #[test]
+    fn test_report_totals() {
+        let mut report = TokenReport::new(Tokenizer::Estimate);
+        for (path, language, original, distilled) in [
+            ("a.py", "python", 100, 10),
+            ("b.py", "python", 50, 20),
+            ("main.go", "go", 40, 10),
+        ] {
+            report.add_file(FileTokens {
+                path: path.to_string(),
+                language: language.to_string(),
+                tokens: TokenCount {
+                    original,
+                    distilled,
+                },
+            });
+        }
+
+        let python = report.languages["python"];
+        assert_eq!(python.files, 2);
+        assert_eq!(python.tokens.distilled, 30);
+        assert_eq!(report.total.original, 190);
+        assert!((python.tokens.saved_percent() - 80.0).abs() < 1e-9);
+
+        let json = serde_json::to_value(&report).unwrap();
+        assert_eq!(json["tokenizer"], "estimate");
+        assert_eq!(json["files"][2]["original"], 40);
+        assert_eq!(json["languages"]["go"]["files"], 1);
+    }
+
+    #[test]
+    fn test_build_with_given_original() {
+        let files = [File {
+            path: "<stdin>".to_string(),
+            children: Vec::new(),
+        }];
+        let report = TokenReport::build(
+            Tokenizer::Estimate,
+            &files,
+            "def f(): ...",
+            &[],
+            |_| Some("def f():\n    return 1\n".to_string()),
+            |_| Some("python"),
+            |_| Ok::<_, DistilError>("def f(): ...".to_string()),
+        )
+        .unwrap();
+        assert_eq!(report.total.original, 6);
+        assert_eq!(report.total.distilled, 3);
+        assert_eq!(report.files[0].language, "python");
+
+        let missing = TokenReport::build(
+            Tokenizer::Estimate,
+            &files,
+            "",
+            &[],
+            |_| None,
+            |_| None,
+            |_| Ok::<_, DistilError>(String::new()),
+        );
+        assert!(matches!(missing, Err(DistilError::Io { .. })));
+    }
+
+    #[test]
+    fn test_original_fallback_encoding() {
+        let path = std::env::temp_dir().join(format!("aid-tokens-{}.py", std::process::id()));
+        // "# “Grüße”" in Windows-1252
+        std::fs::write(&path, b"# \x93Gr\xFC\xDFe\x94").unwrap();
+
+        assert_eq!(
+            read_original(&path, &[Encoding::Windows1252]).unwrap(),
+            "# \u{201C}Grüße\u{201D}"
+        );
+        assert_eq!(
+            read_original(&path, &[]).unwrap(),
+            "# \u{FFFD}Gr\u{FFFD}\u{FFFD}e\u{FFFD}"
+        );
+
+        std::fs::remove_file(&path).unwrap();
+    }
+}
@@ -0,0 +1,941 @@
+//! Merged type view
+//!
+//! One type's API is often spread over several declarations: Rust `impl`
+//! blocks, Swift extensions, Kotlin extension functions, C# `partial`
+//! classes, Go methods, C++ out-of-line `Type::method` definitions and Ruby
+//! reopened classes. Processors emit those pieces as they appear in the
+//! source and tag them with `FRAGMENT` or `RECEIVER` metadata; this pass folds
+//! them into the primary declaration so each type reads as one unit.
+//! `--merge-types=0|1|all` picks between separate pieces, merging within each
+//! file and merging across the whole processed tree.
+
+use crate::ir::{
+    Class, Deprecation, Directory, File, Function, Modifier, Node, OverloadSet, TypeParam, TypeRef,
+};
+use std::collections::HashMap;
+use std::fmt;
+use std::path::Path;
+use std::str::FromStr;
+
+/// Metadata key on a class that adds members to a type declared elsewhere
+///
+/// The value says what kind of piece it is: `impl` for Rust `impl` blocks and
+/// `extension` for Swift extensions. Implemented traits and protocols go in
+/// `implements`.
+pub const FRAGMENT: &str = "fragment";
+
+/// Metadata key on a function declared outside its type, naming the type
+///
+/// Set on Go methods, Kotlin extension functions and C++ `Type::method`
+/// definitions. The key is removed once the function is merged.
+pub const RECEIVER: &str = "receiver";
+
+/// Metadata key recording where a merged member was declared, as `path:line`
+pub const DECLARED_IN: &str = "declared_in";
+
+/// Metadata key listing traits implemented by merged types that have no
+/// `implements` list of their own (enums and structs)
+pub const IMPLEMENTS: &str = "implements";
+
+/// How type fragments should be merged
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum MergeMode {
+    /// Keep every piece where it was declared (`--merge-types=0`)
+    #[default]
+    Off,
+    /// Merge pieces declared in the same file (`--merge-types=1`)
+    File,
+    /// Merge pieces across all processed files (`--merge-types=all`)
+    All,
+}
+
+impl FromStr for MergeMode {
+    type Err = String;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        match s.trim().to_ascii_lowercase().as_str() {
+            "0" | "false" | "off" => Ok(Self::Off),
+            "1" | "true" | "file" => Ok(Self::File),
+            "all" => Ok(Self::All),
+            other => Err(format!(
+                "invalid merge mode '{other}' (expected 0, 1 or all)"
+            )),
+        }
+    }
+}
+
+impl fmt::Display for MergeMode {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Off => write!(f, "0"),
+            Self::File => write!(f, "1"),
+            Self::All => write!(f, "all"),
+        }
+    }
+}
+
+/// Merge type fragments in a processed file or directory
+pub fn merge_types(node: &mut Node, mode: MergeMode) {
+    match (node, mode) {
+        (_, MergeMode::Off) => {}
+        (Node::File(file), _) => merge_file(file),
+        (Node::Directory(dir), MergeMode::File) => {
+            for file in files_mut(dir) {
+                merge_file(file);
+            }
+        }
+        (Node::Directory(dir), MergeMode::All) => {
+            for file in files_mut(dir) {
+                merge_file(file);
+            }
+            merge_across_files(dir);
+        }
+        _ => {}
+    }
+}
+
+/// Merge the fragments of types declared in the same file
+pub fn merge_file(file: &mut File) {
+    let reopens = reopens_classes(&file.path);
+    merge_scope(&mut file.children, &file.path, reopens);
+}
+
+/// Scope-qualified identity of a type across files
+#[derive(Debug, Clone, PartialEq, Eq, Hash)]
+struct TypeKey {
+    /// Language family, plus the directory for Go (methods stay in their package)
+    unit: String,
+    /// Enclosing package and module names
+    scope: Vec<String>,
+    name: String,
+}
+
+/// A declaration taken out of one file to be merged into another
+struct Piece {
+    key: TypeKey,
+    node: Node,
+    path: String,
+}
+
+fn merge_scope(nodes: &mut Vec<Node>, path: &str, reopens: bool) {
+    for node in nodes.iter_mut() {
+        match node {
+            Node::Package(p) => merge_scope(&mut p.children, path, reopens),
+            Node::Module(m) => merge_scope(&mut m.children, path, reopens),
+            _ => {}
+        }
+    }
+
+    // Primary declaration per type name: the first non-fragment, else the
+    // first fragment
+    let mut primaries: HashMap<String, (usize, bool)> = HashMap::new();
+    for (index, node) in nodes.iter().enumerate() {
+        if let Some(name) = type_name(node) {
+            let fragment = is_fragment(node);
+            match primaries.get(name) {
+                Some(&(_, false)) => {}
+                Some(&(_, true)) if fragment => {}
+                _ => {
+                    primaries.insert(name.to_string(), (index, fragment));
+                }
+            }
+        }
+    }
+
+    let mut positions: HashMap<String, usize> = HashMap::new();
+    let mut kept: Vec<Node> = Vec::with_capacity(nodes.len());
+    let mut pieces: Vec<(String, Node)> = Vec::new();
+    for (index, node) in nodes.drain(..).enumerate() {
+        let target = target_name(&node).and_then(|name| {
+            let &(primary, _) = primaries.get(name)?;
+            (primary != index && can_absorb(&node, reopens)).then(|| name.to_string())
+        });
+        match target {
+            Some(name) => pieces.push((name, node)),
+            None => {
+                if let Some(name) = type_name(&node)
+                    && primaries
+                        .get(name)
+                        .is_some_and(|&(primary, _)| primary == index)
+                {
+                    positions.insert(name.to_string(), kept.len());
+                }
+                kept.push(node);
+            }
+        }
+    }
+
+    for (name, piece) in pieces {
+        if let Some(&position) = positions.get(&name) {
+            absorb(&mut kept[position], piece, path);
+        }
+    }
+
+    *nodes = kept;
+}
+
+/// Fold fragments, partial classes and receiver functions left in one file
+/// into the primary declaration from another file
+fn merge_across_files(dir: &mut Directory) {
+    // Owning file per type, preferring real declarations over fragments
+    let mut owners: HashMap<TypeKey, (usize, bool)> = HashMap::new();
+    for (index, file) in files_mut(dir).into_iter().enumerate() {
+        let unit = merge_unit(&file.path);
+        index_types(
+            &file.children,
+            &unit,
+            &mut Vec::new(),
+            &mut |key, fragment| match owners.get(&key) {
+                Some(&(_, false)) => {}
+                Some(&(_, true)) if fragment => {}
+                _ => {
+                    owners.insert(key, (index, fragment));
+                }
+            },
+        );
+    }
+
+    let mut pieces: Vec<(usize, Piece)> = Vec::new();
+    for (index, file) in files_mut(dir).into_iter().enumerate() {
+        let unit = merge_unit(&file.path);
+        let reopens = reopens_classes(&file.path);
+        let path = file.path.clone();
+        extract_pieces(
+            &mut file.children,
+            &unit,
+            &mut Vec::new(),
+            &mut |key, node| {
+                let owner = owners.get(key).map(|&(owner, _)| owner);
+                owner.is_some_and(|owner| owner != index) && can_absorb(node, reopens)
+            },
+            &mut |piece| {
+                if let Some(&(owner, _)) = owners.get(&piece.key) {
+                    pieces.push((
+                        owner,
+                        Piece {
+                            path: path.clone(),
+                            ..piece
+                        },
+                    ));
+                }
+            },
+        );
+    }
+
+    let mut files = files_mut(dir);
+    for (owner, piece) in pieces {
+        let Some(file) = files.get_mut(owner) else {
+            continue;
+        };
+        if let Some(target) = find_type(&mut file.children, &piece.key.scope, &piece.key.name) {
+            absorb(target, piece.node, &piece.path);
+        }
+    }
+}
+
+fn index_types(
+    nodes: &[Node],
+    unit: &str,
+    scope: &mut Vec<String>,
+    record: &mut impl FnMut(TypeKey, bool),
+) {
+    for node in nodes {
+        match node {
+            Node::Package(p) => {
+                scope.push(p.name.clone());
+                index_types(&p.children, unit, scope, record);
+                scope.pop();
+            }
+            Node::Module(m) => {
+                scope.push(m.name.clone());
+                index_types(&m.children, unit, scope, record);
+                scope.pop();
+            }
+            _ => {
+                if let Some(name) = type_name(node) {
+                    let key = TypeKey {
+                        unit: unit.to_string(),
+                        scope: scope.clone(),
+                        name: name.to_string(),
+                    };
+                    record(key, is_fragment(node));
+                }
+            }
+        }
+    }
+}
+
+fn extract_pieces(
+    nodes: &mut Vec<Node>,
+    unit: &str,
+    scope: &mut Vec<String>,
+    movable: &mut impl FnMut(&TypeKey, &Node) -> bool,
+    take: &mut impl FnMut(Piece),
+) {
+    let mut kept = Vec::with_capacity(nodes.len());
+    for mut node in nodes.drain(..) {
+        match &mut node {
+            Node::Package(p) => {
+                scope.push(p.name.clone());
+                extract_pieces(&mut p.children, unit, scope, movable, take);
+                scope.pop();
+            }
+            Node::Module(m) => {
+                scope.push(m.name.clone());
+                extract_pieces(&mut m.children, unit, scope, movable, take);
+                scope.pop();
+            }
+            _ => {}
+        }
+
+        let key = target_name(&node).map(|name| TypeKey {
+            unit: unit.to_string(),
+            scope: scope.clone(),
+            name: name.to_string(),
+        });
+        match key {
+            Some(key) if movable(&key, &node) => take(Piece {
+                key,
+                node,
+                path: String::new(),
+            }),
+            _ => kept.push(node),
+        }
+    }
+    *nodes = kept;
+}
+
+/// Find the primary declaration of a type, preferring non-fragments
+fn find_type<'a>(nodes: &'a mut [Node], scope: &[String], name: &str) -> Option<&'a mut Node> {
+    if let Some((first, rest)) = scope.split_first() {
+        return nodes.iter_mut().find_map(|node| match node {
+            Node::Package(p) if &p.name == first => find_type(&mut p.children, rest, name),
+            Node::Module(m) if &m.name == first => find_type(&mut m.children, rest, name),
+            _ => None,
+        });
+    }
+
+    let position = nodes
+        .iter()
+        .position(|node| type_name(node) == Some(name) && !is_fragment(node))
+        .or_else(|| nodes.iter().position(|node| type_name(node) == Some(name)))?;
+    nodes.get_mut(position)
+}
+
+/// Move a fragment's members, traits and attributes into the primary
+/// declaration
+fn absorb(target: &mut Node, piece: Node, path: &str) {
+    let (members, traits) = match piece {
+        Node::Class(class) => {
+            let Class {
+                modifiers,
+                decorators,
+                type_params,
+                extends,
+                implements,
+                children,
+                deprecated,
+                ..
+            } = class;
+            Attributes {
+                modifiers,
+                decorators,
+                type_params,
+                deprecated,
+            }
+            .apply(target);
+            (children, extends.into_iter().chain(implements).collect())
+        }
+        Node::Function(mut function) => {
+            function.metadata.remove(RECEIVER);
+            (vec![Node::Function(function)], Vec::new())
+        }
+        Node::OverloadSet(mut set) => {
+            for signature in &mut set.signatures {
+                signature.metadata.remove(RECEIVER);
+            }
+            (vec![Node::OverloadSet(set)], Vec::new())
+        }
+        _ => return,
+    };
+
+    let Some((children, implements)) = members_mut(target) else {
+        return;
+    };
+    for mut member in members {
+        stamp_declared_in(&mut member, path);
+        add_member(children, member);
+    }
+    match implements {
+        Implements::List(list) => {
+            for ty in traits {
+                if !list.iter().any(|existing| existing.name == ty.name) {
+                    list.push(ty);
+                }
+            }
+        }
+        Implements::Metadata(metadata) => {
+            let mut names: Vec<String> = metadata
+                .get(IMPLEMENTS)
+                .map(|existing| existing.split(", ").map(str::to_string).collect())
+                .unwrap_or_default();
+            for ty in traits {
+                if !names.contains(&ty.name) {
+                    names.push(ty.name);
+                }
+            }
+            if !names.is_empty() {
+                metadata.insert(IMPLEMENTS.to_string(), names.join(", "));
+            }
+        }
+    }
+}
+
+/// Declaration attributes a fragment carries over to its type
+///
+/// Interfaces and structs have no modifiers of their own to take them, and
+/// only classes and enums have decorators.
+struct Attributes {
+    modifiers: Vec<Modifier>,
+    decorators: Vec<String>,
+    type_params: Vec<TypeParam>,
+    deprecated: Option<Deprecation>,
+}
+
+impl Attributes {
+    fn apply(self, target: &mut Node) {
+        let (modifiers, decorators, type_params, deprecated) = match target {
+            Node::Class(c) => (
+                Some(&mut c.modifiers),
+                Some(&mut c.decorators),
+                &mut c.type_params,
+                &mut c.deprecated,
+            ),
+            Node::Interface(i) => (None, None, &mut i.type_params, &mut i.deprecated),
+            Node::Struct(s) => (None, None, &mut s.type_params, &mut s.deprecated),
+            Node::Enum(e) => (
+                None,
+                Some(&mut e.decorators),
+                &mut e.type_params,
+                &mut e.deprecated,
+            ),
+            _ => return,
+        };
+        if let Some(modifiers) = modifiers {
+            union(modifiers, self.modifiers);
+        }
+        if let Some(decorators) = decorators {
+            union(decorators, self.decorators);
+        }
+        merge_type_params(type_params, self.type_params);
+        if deprecated.is_none() {
+            *deprecated = self.deprecated;
+        }
+    }
+}
+
+/// Append the items of `more` not already in `list`
+fn union<T: PartialEq>(list: &mut Vec<T>, more: Vec<T>) {
+    for item in more {
+        if !list.contains(&item) {
+            list.push(item);
+        }
+    }
+}
+
+/// Add the constraints a fragment puts on the type's parameters
+/// (`impl<T: Display>`, `where` clauses)
+///
+/// Parameters pair up by name. Ones the type doesn't declare belong to the
+/// fragment alone (`impl<T> From<T> for Id`) and aren't added.
+fn merge_type_params(params: &mut [TypeParam], more: Vec<TypeParam>) {
+    for param in more {
+        let Some(existing) = params.iter_mut().find(|p| p.name == param.name) else {
+            continue;
+        };
+        for constraint in param.constraints {
+            if !existing
+                .constraints
+                .iter()
+                .any(|c| c.name == constraint.name)
+            {
+                existing.constraints.push(constraint);
+            }
+        }
+        if existing.default.is_none() {
+            existing.default = param.default;
+        }
+    }
+}
+
+/// Where a merged type keeps its implemented traits
+enum Implements<'a> {
+    List(&'a mut Vec<TypeRef>),
+    Metadata(&'a mut std::collections::BTreeMap<String, String>),
+}
+
+fn members_mut(node: &mut Node) -> Option<(&mut Vec<Node>, Implements<'_>)> {
+    match node {
+        Node::Class(c) => Some((&mut c.children, Implements::List(&mut c.implements))),
+        Node::Interface(i) => Some((&mut i.children, Implements::List(&mut i.extends))),
+        Node::Struct(s) => Some((&mut s.children, Implements::Metadata(&mut s.metadata))),
+        Node::Enum(e) => Some((&mut e.children, Implements::Metadata(&mut e.metadata))),
+        _ => None,
+    }
+}
+
+/// Add a member, folding a definition into a matching declaration
+///
+/// C++ prototypes and their out-of-line definitions describe the same
+/// method; the definition only contributes its body.
+fn add_member(members: &mut Vec<Node>, member: Node) {
+    let Node::Function(function) = member else {
+        members.push(member);
+        return;
+    };
+
+    let existing = members.iter_mut().find_map(|node| match node {
+        Node::Function(f) if same_signature(f, &function) => Some(f),
+        Node::OverloadSet(set) => set
+            .signatures
+            .iter_mut()
+            .find(|f| same_signature(f, &function)),
+        _ => None,
+    });
+    match existing {
+        Some(existing) => {
+            if existing.implementation.is_none() {
+                existing.implementation = function.implementation;
+            }
+        }
+        None => members.push(Node::Function(function)),
+    }
+}
+
+fn same_signature(a: &Function, b: &Function) -> bool {
+    a.name == b.name
+        && a.parameters.len() == b.parameters.len()
+        && a.parameters
+            .iter()
+            .zip(&b.parameters)
+            .all(|(x, y)| x.param_type.name == y.param_type.name)
+}
+
+fn stamp_declared_in(node: &mut Node, path: &str) {
+    if let Node::OverloadSet(OverloadSet { signatures, .. }) = node {
+        for signature in signatures {
+            let location = format!("{path}:{}", signature.line_start);
+            signature
+                .metadata
+                .entry(DECLARED_IN.to_string())
+                .or_insert(location);
+        }
+        return;
+    }
+
+    let (metadata, line) = match node {
+        Node::Function(f) => (&mut f.metadata, f.line_start),
+        Node::Field(f) => (&mut f.metadata, f.line),
+        Node::Property(p) => (&mut p.metadata, p.line_start),
+        Node::Variable(v) => (&mut v.metadata, v.line),
+        Node::Macro(m) => (&mut m.metadata, m.line_start),
+        Node::TypeAlias(t) => (&mut t.metadata, t.line),
+        Node::Class(c) => (&mut c.metadata, c.line_start),
+        Node::Interface(i) => (&mut i.metadata, i.line_start),
+        Node::Struct(s) => (&mut s.metadata, s.line_start),
+        Node::Enum(e) => (&mut e.metadata, e.line_start),
+        Node::EnumVariant(v) => (&mut v.metadata, v.line),
+        Node::Module(m) => (&mut m.metadata, m.line_start),
+        _ => return,
+    };
+    metadata
+        .entry(DECLARED_IN.to_string())
+        .or_insert_with(|| format!("{path}:{line}"));
+}
+
+/// Name of a type declaration that fragments can merge into
+fn type_name(node: &Node) -> Option<&str> {
+    match node {
+        Node::Class(c) => Some(&c.name),
+        Node::Interface(i) => Some(&i.name),
+        Node::Struct(s) => Some(&s.name),
+        Node::Enum(e) => Some(&e.name),
+        _ => None,
+    }
+}
+
+/// Name of the type a declaration would merge into
+fn target_name(node: &Node) -> Option<&str> {
+    match node {
+        Node::Class(c) => Some(&c.name),
+        Node::Function(f) => f.metadata.get(RECEIVER).map(String::as_str),
+        Node::OverloadSet(set) => set
+            .signatures
+            .first()?
+            .metadata
+            .get(RECEIVER)
+            .map(String::as_str),
+        _ => None,
+    }
+}
+
+fn is_fragment(node: &Node) -> bool {
+    matches!(node, Node::Class(c) if c.metadata.contains_key(FRAGMENT))
+}
+
+/// Whether a declaration is a piece of a type rather than a type of its own
+fn can_absorb(node: &Node, reopens: bool) -> bool {
+    match node {
+        Node::Class(c) => {
+            c.metadata.contains_key(FRAGMENT) || c.modifiers.contains(&Modifier::Partial) || reopens
+        }
+        Node::Function(_) | Node::OverloadSet(_) => target_name(node).is_some(),
+        _ => false,
+    }
+}
+
+/// Ruby classes can be reopened anywhere; a second `class Foo` adds to the first
+fn reopens_classes(path: &str) -> bool {
+    Path::new(path).extension().and_then(|ext| ext.to_str()) == Some("rb")
+}
+
+/// Files whose types can merge: same language family, and for Go the same
+/// package directory
+fn merge_unit(path: &str) -> String {
+    let path = Path::new(path);
+    let ext = path.extension().and_then(|ext| ext.to_str()).unwrap_or("");
+    match ext {
+        "go" => {
+            let dir = path
+                .parent()
+                .map(|dir| dir.to_string_lossy())
+                .unwrap_or_default();
+            format!("go:{dir}")
+        }
+        "c" | "h" | "cc" | "cpp" | "cxx" | "hpp" | "hh" | "hxx" => "cpp".to_string(),
+        "kt" | "kts" => "kt".to_string(),
+        other => other.to_string(),
+    }
+}
+
+fn files_mut(dir: &mut Directory) -> Vec<&mut File> {
+    let mut files = Vec::new();
+    for child in &mut dir.children {
+        match child {
+            Node::File(file) => files.push(file),
+            Node::Directory(sub) => files.extend(files_mut(sub)),
+            _ => {}
+        }
+    }
+    files
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ir::{Enum, Visibility};
+    use std::collections::BTreeMap;
+
+    fn class(name: &str, line: usize, children: Vec<Node>) -> Class {
+        Class {
+            name: name.to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            extends: vec![],
+            implements: vec![],
+            children,
+            line_start: line,
+            line_end: line,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        }
+    }
+
+    fn fragment(name: &str, line: usize, traits: &[&str], children: Vec<Node>) -> Node {
+        let mut class = class(name, line, children);
+        class.implements = traits.iter().map(|t| TypeRef::new(*t)).collect();
+        class
+            .metadata
+            .insert(FRAGMENT.to_string(), "impl".to_string());
+        Node::Class(class)
+    }
+
+    fn method(name: &str, line: usize, receiver: Option<&str>) -> Node {
+        let mut metadata = BTreeMap::new();
+        if let Some(receiver) = receiver {
+            metadata.insert(RECEIVER.to_string(), receiver.to_string());
+        }
+        Node::Function(Function {
+            name: name.to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            parameters: vec![],
+            return_type: None,
+            implementation: None,
+            line_start: line,
+            line_end: line,
+            deprecated: None,
+            metadata,
+        })
+    }
+
+    fn file(path: &str, children: Vec<Node>) -> Node {
+        Node::File(File {
+            path: path.to_string(),
+            children,
+        })
+    }
+
+    fn members(node: &Node) -> Vec<(&str, Option<&str>)> {
+        let Node::Class(class) = node else {
+            panic!("Expected class, got {node:?}");
+        };
+        class
+            .children
+            .iter()
+            .filter_map(|child| match child {
+                Node::Function(f) => Some((
+                    f.name.as_str(),
+                    f.metadata.get(DECLARED_IN).map(String::as_str),
+                )),
+                _ => None,
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_merge_mode_parse() {
+        assert_eq!("1".parse::<MergeMode>(), Ok(MergeMode::File));
+        assert_eq!("off".parse::<MergeMode>(), Ok(MergeMode::Off));
+        assert_eq!("ALL".parse::<MergeMode>(), Ok(MergeMode::All));
+        assert!("both".parse::<MergeMode>().is_err());
+        assert_eq!(MergeMode::All.to_string(), "all");
+    }
+
+    #[test]
+    fn test_impl_blocks_merge_into_struct() {
+        let mut node = file(
+            "src/user.rs",
+            vec![
+                fragment("User", 1, &[], vec![method("new", 2, None)]),
+                Node::Class(class("User", 5, vec![])),
+                fragment("User", 8, &["Display"], vec![method("fmt", 9, None)]),
+            ],
+        );
+
+        merge_types(&mut node, MergeMode::File);
+
+        let Node::File(file) = &node else {
+            unreachable!()
+        };
+        assert_eq!(file.children.len(), 1);
+        let Node::Class(user) = &file.children[0] else {
+            panic!("Expected class");
+        };
+        assert!(!user.metadata.contains_key(FRAGMENT));
+        assert_eq!(user.implements, vec![TypeRef::new("Display")]);
+        assert_eq!(
+            members(&file.children[0]),
+            vec![
+                ("new", Some("src/user.rs:2")),
+                ("fmt", Some("src/user.rs:9"))
+            ]
+        );
+    }
+
+    #[test]
+    fn test_receivers_and_enum_traits() {
+        let mut node = file(
+            "shapes.go",
+            vec![
+                Node::Enum(Enum {
+                    name: "Shape".to_string(),
+                    visibility: Visibility::Public,
+                    source_visibility: None,
+                    decorators: vec![],
+                    type_params: vec![],
+                    enum_type: None,
+                    children: vec![],
+                    line_start: 1,
+                    line_end: 3,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
+                }),
+                method("Area", 5, Some("Shape")),
+                method("Orphan", 7, Some("Missing")),
+                fragment("Shape", 9, &["Debug"], vec![]),
+            ],
+        );
+
+        merge_types(&mut node, MergeMode::File);
+
+        let Node::File(file) = &node else {
+            unreachable!()
+        };
+        assert_eq!(file.children.len(), 2);
+        let Node::Enum(shape) = &file.children[0] else {
+            panic!("Expected enum");
+        };
+        assert_eq!(
+            shape.metadata.get(IMPLEMENTS).map(String::as_str),
+            Some("Debug")
+        );
+        assert!(
+            matches!(&shape.children[0], Node::Function(f) if f.name == "Area" && !f.metadata.contains_key(RECEIVER))
+        );
+        // Receivers without a known type stay where they are
+        assert!(
+            matches!(&file.children[1], Node::Function(f) if f.metadata.get(RECEIVER).map(String::as_str) == Some("Missing"))
+        );
+    }
+
+    #[test]
+    fn test_merge_off_and_across_files() {
+        let tree = || {
+            Node::Directory(Directory {
+                path: "src".to_string(),
+                children: vec![
+                    file("src/user.rs", vec![Node::Class(class("User", 1, vec![]))]),
+                    file(
+                        "src/display.rs",
+                        vec![fragment(
+                            "User",
+                            3,
+                            &["Display"],
+                            vec![method("fmt", 4, None)],
+                        )],
+                    ),
+                    file(
+                        "src/other.swift",
+                        vec![fragment("User", 1, &[], vec![method("greet", 2, None)])],
+                    ),
+                ],
+            })
+        };
+
+        let mut off = tree();
+        merge_types(&mut off, MergeMode::Off);
+        let mut per_file = tree();
+        merge_types(&mut per_file, MergeMode::File);
+        for node in [&off, &per_file] {
+            let Node::Directory(dir) = node else {
+                unreachable!()
+            };
+            assert!(matches!(&dir.children[1], Node::File(f) if f.children.len() == 1));
+        }
+
+        let mut all = tree();
+        merge_types(&mut all, MergeMode::All);
+        let Node::Directory(dir) = &all else {
+            unreachable!()
+        };
+        let Node::File(user) = &dir.children[0] else {
+            unreachable!()
+        };
+        assert_eq!(
+            members(&user.children[0]),
+            vec![("fmt", Some("src/display.rs:4"))]
+        );
+        assert!(matches!(&dir.children[1], Node::File(f) if f.children.is_empty()));
+        // Different language, different type
+        assert!(matches!(&dir.children[2], Node::File(f) if f.children.len() == 1));
+    }
+
+    #[test]
+    fn test_partial_and_reopened_classes() {
+        let partial = |line, name| {
+            let mut part = class("Context", line, vec![method(name, line + 1, None)]);
+            part.modifiers.push(Modifier::Partial);
+            Node::Class(part)
+        };
+        let mut csharp = file(
+            "Context.cs",
+            vec![
+                partial(1, "Save"),
+                partial(10, "Load"),
+                Node::Class(class("Plain", 20, vec![])),
+                Node::Class(class("Plain", 30, vec![])),
+            ],
+        );
+        merge_types(&mut csharp, MergeMode::File);
+        let Node::File(file_node) = &csharp else {
+            unreachable!()
+        };
+        // Only partial classes merge outside Ruby
+        assert_eq!(file_node.children.len(), 3);
+        assert_eq!(
+            members(&file_node.children[0]),
+            vec![("Save", None), ("Load", Some("Context.cs:11"))]
+        );
+
+        let mut ruby = file(
+            "user.rb",
+            vec![
+                Node::Class(class("User", 1, vec![method("name", 2, None)])),
+                Node::Class(class("User", 5, vec![method("email", 6, None)])),
+            ],
+        );
+        merge_types(&mut ruby, MergeMode::File);
+        let Node::File(file_node) = &ruby else {
+            unreachable!()
+        };
+        assert_eq!(file_node.children.len(), 1);
+        assert_eq!(
+            members(&file_node.children[0]),
+            vec![("name", None), ("email", Some("user.rb:6"))]
+        );
+    }
+
+    #[test]
+    fn test_fragment_attributes_merge() {
+        let param = |name: &str, constraints: &[&str]| TypeParam {
+            name: name.to_string(),
+            constraints: constraints.iter().map(|c| TypeRef::new(*c)).collect(),
+            default: None,
+        };
+
+        let mut primary = class("Cache", 1, vec![]);
+        primary.modifiers = vec![Modifier::Partial];
+        primary.decorators = vec!["[Serializable]".to_string()];
+        primary.type_params = vec![param("T", &["class"])];
+
+        let mut part = class("Cache", 10, vec![method("Evict", 11, None)]);
+        part.modifiers = vec![Modifier::Partial, Modifier::Sealed];
+        part.decorators = vec![
+            "[Serializable]".to_string(),
+            "[Obsolete(\"Use Store\")]".to_string(),
+        ];
+        part.type_params = vec![param("T", &["class", "IDisposable"]), param("U", &[])];
+        part.deprecated = Some(Deprecation::default());
+
+        let mut node = file("Cache.cs", vec![Node::Class(primary), Node::Class(part)]);
+        merge_types(&mut node, MergeMode::File);
+
+        let Node::File(file_node) = &node else {
+            unreachable!()
+        };
+        let Node::Class(cache) = &file_node.children[0] else {
+            panic!("Expected class");
+        };
+        assert_eq!(cache.modifiers, [Modifier::Partial, Modifier::Sealed]);
+        assert_eq!(
+            cache.decorators,
+            ["[Serializable]", "[Obsolete(\"Use Store\")]"]
+        );
+        assert!(cache.deprecated.is_some());
+        // Constraints join the type's own parameter; `U` isn't the type's
+        assert_eq!(cache.type_params.len(), 1);
+        let constraints: Vec<&str> = cache.type_params[0]
+            .constraints
+            .iter()
+            .map(|c| c.name.as_str())
+            .collect();
+        assert_eq!(constraints, ["class", "IDisposable"]);
+    }
+}
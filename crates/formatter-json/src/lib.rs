@@ -3,8 +3,11 @@
 //! Structured JSON format for tools and programmatic processing.
 //! Provides both pretty-printed and compact JSON output.
 
+use distiller_core::EffectiveOptions;
+use distiller_core::budget;
 #[allow(clippy::wildcard_imports)]
 use distiller_core::ir::*;
+use serde::Serialize;
 
 /// JSON formatter options
 #[derive(Debug, Clone)]
@@ -64,6 +67,58 @@ impl JsonFormatter {
             serde_json::to_string(files)
         }
     }
+
+    /// Format multiple files as `{"metadata": ..., "files": [...]}`
+    ///
+    /// The metadata records the tool version next to `metadata`, so the
+    /// output can be traced back to the run that produced it.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if formatting or serialization fails
+    pub fn format_files_with_metadata(
+        &self,
+        files: &[File],
+        metadata: &Metadata,
+    ) -> Result<String, serde_json::Error> {
+        let document = Document {
+            metadata: Versioned {
+                version: env!("CARGO_PKG_VERSION"),
+                metadata,
+            },
+            files,
+        };
+        if self.options.pretty {
+            serde_json::to_string_pretty(&document)
+        } else {
+            serde_json::to_string(&document)
+        }
+    }
+}
+
+/// Run metadata recorded next to the files
+#[derive(Debug, Clone, Copy, Default, Serialize)]
+pub struct Metadata<'a> {
+    /// Effective options of the run
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub options: Option<&'a EffectiveOptions>,
+    /// Token budget and what was left out to fit it
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub budget: Option<budget::Record<'a>>,
+}
+
+#[derive(Serialize)]
+struct Versioned<'a> {
+    version: &'static str,
+    #[serde(flatten)]
+    metadata: &'a Metadata<'a>,
+}
+
+/// Files with the metadata of the run that produced them
+#[derive(Serialize)]
+struct Document<'a> {
+    metadata: Versioned<'a>,
+    files: &'a [File],
 }
 
 impl Default for JsonFormatter {
@@ -75,6 +130,7 @@ impl Default for JsonFormatter {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use std::collections::BTreeMap;
 
     #[test]
     fn test_json_format_simple() {
@@ -83,6 +139,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Example".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -91,6 +148,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "__init__".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -106,9 +164,13 @@ mod tests {
                     implementation: None,
                     line_start: 2,
                     line_end: 3,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -132,6 +194,7 @@ mod tests {
             children: vec![Node::Function(Function {
                 name: "hello".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -140,6 +203,8 @@ mod tests {
                 implementation: None,
                 line_start: 1,
                 line_end: 2,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -163,6 +228,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "func1".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -171,6 +237,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
             File {
@@ -178,6 +246,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "func2".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -186,6 +255,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
         ];
@@ -204,6 +275,47 @@ mod tests {
         assert!(result.contains("\"name\": \"func2\""));
     }
 
+    #[test]
+    fn test_json_metadata() {
+        let files = vec![File {
+            path: "empty.py".to_string(),
+            children: Vec::new(),
+        }];
+        let options = distiller_core::ProcessOptions::default().effective();
+
+        let formatter = JsonFormatter::with_options(JsonFormatterOptions { pretty: false });
+        let metadata = Metadata {
+            options: Some(&options),
+            budget: None,
+        };
+        let result = formatter
+            .format_files_with_metadata(&files, &metadata)
+            .unwrap();
+        let json: serde_json::Value = serde_json::from_str(&result).unwrap();
+
+        assert_eq!(json["metadata"]["options"]["include"][0], "public");
+        assert_eq!(json["metadata"]["options"]["tests"], "1");
+        assert!(json["metadata"].get("budget").is_none());
+        assert_eq!(json["files"][0]["path"], "empty.py");
+
+        let omitted = [budget::Omission::Implementations];
+        let metadata = Metadata {
+            options: None,
+            budget: Some(budget::Record {
+                max_tokens: 500,
+                omitted: &omitted,
+            }),
+        };
+        let result = formatter
+            .format_files_with_metadata(&files, &metadata)
+            .unwrap();
+        let json: serde_json::Value = serde_json::from_str(&result).unwrap();
+
+        assert_eq!(json["metadata"]["budget"]["max_tokens"], 500);
+        assert_eq!(json["metadata"]["budget"]["omitted"][0], "implementations");
+        assert!(json["metadata"].get("options").is_none());
+    }
+
     #[test]
     fn test_json_visibility() {
         let file = File {
@@ -212,18 +324,24 @@ mod tests {
                 Node::Field(Field {
                     name: "_private".to_string(),
                     visibility: Visibility::Private,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("str")),
                     default_value: None,
                     line: 1,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 }),
                 Node::Field(Field {
                     name: "public".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("int")),
                     default_value: None,
                     line: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 }),
             ],
         };
@@ -245,6 +363,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Container".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: vec![TypeParam {
@@ -257,6 +376,8 @@ mod tests {
                 children: Vec::new(),
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -267,4 +388,31 @@ mod tests {
         assert!(result.contains("\"type_params\""));
         assert!(result.contains("\"name\": \"T\""));
     }
+
+    #[test]
+    fn test_json_canonical_types() {
+        let mut node = Node::File(File {
+            path: "user.go".to_string(),
+            children: vec![Node::Field(Field {
+                name: "Age".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                modifiers: Vec::new(),
+                field_type: Some(TypeRef::new("int32")),
+                default_value: None,
+                line: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        });
+        distiller_core::canonical::canonicalize(&mut node);
+        let Node::File(file) = node else {
+            unreachable!()
+        };
+
+        let formatter = JsonFormatter::with_options(JsonFormatterOptions { pretty: false });
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains(r#""canonical":{"category":"integer","bits":32,"signed":true}"#));
+    }
 }
@@ -4,8 +4,10 @@
 //! Optimized for streaming processing and log aggregation.
 //! Always uses compact format (no pretty-printing).
 
+use distiller_core::budget;
 #[allow(clippy::wildcard_imports)]
 use distiller_core::ir::*;
+use serde::Serialize;
 
 /// JSONL formatter (always compact, one JSON per line)
 pub struct JsonlFormatter;
@@ -51,6 +53,41 @@ impl JsonlFormatter {
 
         Ok(output)
     }
+
+    /// Format multiple files as JSONL after a `{"metadata": ...}` line
+    /// recording the token budget and what was left out to fit it
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if formatting or serialization fails
+    pub fn format_files_with_budget(
+        &self,
+        files: &[File],
+        budget: budget::Record,
+    ) -> Result<String, serde_json::Error> {
+        let header = Header {
+            metadata: Metadata {
+                version: env!("CARGO_PKG_VERSION"),
+                budget,
+            },
+        };
+        let mut output = serde_json::to_string(&header)?;
+        output.push('\n');
+        output.push_str(&self.format_files(files)?);
+        Ok(output)
+    }
+}
+
+/// Run metadata, the first line of budgeted output
+#[derive(Serialize)]
+struct Header<'a> {
+    metadata: Metadata<'a>,
+}
+
+#[derive(Serialize)]
+struct Metadata<'a> {
+    version: &'static str,
+    budget: budget::Record<'a>,
 }
 
 impl Default for JsonlFormatter {
@@ -62,6 +99,7 @@ impl Default for JsonlFormatter {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use std::collections::BTreeMap;
 
     #[test]
     fn test_jsonl_format_simple() {
@@ -70,6 +108,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Example".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -78,6 +117,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "__init__".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -93,9 +133,13 @@ mod tests {
                     implementation: None,
                     line_start: 2,
                     line_end: 3,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -121,6 +165,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "func1".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -129,6 +174,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
             File {
@@ -136,6 +183,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "func2".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -144,6 +192,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
         ];
@@ -202,6 +252,36 @@ mod tests {
         assert!(!result.ends_with("\n\n"));
     }
 
+    #[test]
+    fn test_jsonl_budget() {
+        let files = vec![File {
+            path: "a.py".to_string(),
+            children: vec![],
+        }];
+        let omitted = [
+            budget::Omission::Implementations,
+            budget::Omission::Docstrings,
+        ];
+        let budget = budget::Record {
+            max_tokens: 100,
+            omitted: &omitted,
+        };
+
+        let result = JsonlFormatter::new()
+            .format_files_with_budget(&files, budget)
+            .unwrap();
+        let lines: Vec<&str> = result.lines().collect();
+        assert_eq!(lines.len(), 2);
+
+        let header: serde_json::Value = serde_json::from_str(lines[0]).unwrap();
+        assert_eq!(header["metadata"]["budget"]["max_tokens"], 100);
+        assert_eq!(
+            header["metadata"]["budget"]["omitted"][1],
+            "docstrings and comments"
+        );
+        assert!(lines[1].contains("\"path\":\"a.py\""));
+    }
+
     #[test]
     fn test_jsonl_visibility() {
         let file = File {
@@ -210,18 +290,24 @@ mod tests {
                 Node::Field(Field {
                     name: "_private".to_string(),
                     visibility: Visibility::Private,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("str")),
                     default_value: None,
                     line: 1,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 }),
                 Node::Field(Field {
                     name: "public".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("int")),
                     default_value: None,
                     line: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 }),
             ],
         };
@@ -243,6 +329,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Container".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: vec![TypeParam {
@@ -255,6 +342,8 @@ mod tests {
                 children: Vec::new(),
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -275,6 +364,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "hello".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -283,6 +373,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
             File {
@@ -290,6 +382,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "world".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -298,6 +391,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
         ];
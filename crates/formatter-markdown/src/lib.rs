@@ -136,6 +136,7 @@ fn get_language_from_path(path: &str) -> &str {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use std::collections::BTreeMap;
 
     #[test]
     fn test_markdown_format_simple() {
@@ -144,6 +145,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Example".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -152,6 +154,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "__init__".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -167,9 +170,13 @@ mod tests {
                     implementation: None,
                     line_start: 2,
                     line_end: 3,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -205,6 +212,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "hello".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -213,6 +221,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
             File {
@@ -220,6 +230,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "world".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -228,6 +239,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
         ];
@@ -248,6 +261,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Example".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -256,13 +270,18 @@ mod tests {
                 children: vec![Node::Field(Field {
                     name: "_private".to_string(),
                     visibility: Visibility::Private,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("str")),
                     default_value: None,
                     line: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -5,13 +5,15 @@
 //! optimal AI consumption.
 
 use distiller_core::ir::{
-    Class, Comment, Enum, Field, File, Function, Import, Interface, Node, Package, Parameter,
-    RawContent, Struct, TypeAlias, TypeParam, TypeRef, Visibility,
+    Class, Comment, Deprecation, Enum, EnumVariant, Field, File, Function, Import, Interface,
+    Macro, MacroKind, Module, Node, OverloadSet, Package, Parameter, Property, RawContent,
+    SourceVisibility, Struct, TypeAlias, TypeParam, TypeRef, Variable, VariableKind, Visibility,
 };
+use std::collections::BTreeMap;
 use std::fmt::Write as FmtWrite;
 
 #[cfg(test)]
-use distiller_core::ir::ImportedSymbol;
+use distiller_core::ir::{Accessor, AccessorKind, ImportedSymbol, Modifier};
 
 /// Text formatter options
 #[derive(Debug, Clone, Default)]
@@ -86,11 +88,17 @@ impl TextFormatter {
             Node::Interface(interface) => self.format_interface(output, interface, indent)?,
             Node::Struct(struct_node) => self.format_struct(output, struct_node, indent)?,
             Node::Enum(enum_node) => self.format_enum(output, enum_node, indent)?,
+            Node::EnumVariant(variant) => self.format_enum_variant(output, variant, indent)?,
             Node::TypeAlias(alias) => self.format_type_alias(output, alias, indent)?,
             Node::Function(func) => self.format_function(output, func, indent)?,
+            Node::OverloadSet(overloads) => self.format_overload_set(output, overloads, indent)?,
             Node::Field(field) => self.format_field(output, field, indent)?,
+            Node::Property(property) => self.format_property(output, property, indent)?,
+            Node::Variable(variable) => self.format_variable(output, variable, indent)?,
+            Node::Macro(macro_def) => Self::format_macro(output, macro_def, indent)?,
             Node::Comment(comment) => self.format_comment(output, comment, indent)?,
             Node::Package(package) => self.format_package(output, package, indent)?,
+            Node::Module(module) => self.format_module(output, module, indent)?,
             Node::RawContent(raw) => Self::format_raw(output, raw, indent)?,
             Node::File(_) | Node::Directory(_) => {
                 // Files and directories are handled separately
@@ -156,7 +164,7 @@ impl TextFormatter {
         indent: usize,
     ) -> Result<(), std::fmt::Error> {
         let ind = Self::indent(indent);
-        let vis_symbol = Self::visibility_symbol(class.visibility);
+        let vis_symbol = Self::access_prefix(class.visibility, class.source_visibility.as_ref());
 
         // Write decorators
         for decorator in &class.decorators {
@@ -183,7 +191,9 @@ impl TextFormatter {
             write!(output, "({})", inheritance.join(", "))?;
         }
 
-        writeln!(output, ":")?;
+        let marker = Self::metadata_suffix(&class.metadata)
+            + &Self::deprecation_marker(class.deprecated.as_ref());
+        writeln!(output, ":{marker}")?;
 
         // Children
         for child in &class.children {
@@ -201,7 +211,8 @@ impl TextFormatter {
         indent: usize,
     ) -> Result<(), std::fmt::Error> {
         let ind = Self::indent(indent);
-        let vis_symbol = Self::visibility_symbol(interface.visibility);
+        let vis_symbol =
+            Self::access_prefix(interface.visibility, interface.source_visibility.as_ref());
 
         write!(output, "{}{}interface {}", ind, vis_symbol, interface.name)?;
 
@@ -223,7 +234,9 @@ impl TextFormatter {
             write!(output, "({extends})")?;
         }
 
-        writeln!(output, ":")?;
+        let marker = Self::metadata_suffix(&interface.metadata)
+            + &Self::deprecation_marker(interface.deprecated.as_ref());
+        writeln!(output, ":{marker}")?;
 
         for child in &interface.children {
             self.format_node(output, child, indent + 1)?;
@@ -240,7 +253,10 @@ impl TextFormatter {
         indent: usize,
     ) -> Result<(), std::fmt::Error> {
         let ind = Self::indent(indent);
-        let vis_symbol = Self::visibility_symbol(struct_node.visibility);
+        let vis_symbol = Self::access_prefix(
+            struct_node.visibility,
+            struct_node.source_visibility.as_ref(),
+        );
 
         write!(output, "{}{}struct {}", ind, vis_symbol, struct_node.name)?;
 
@@ -252,7 +268,9 @@ impl TextFormatter {
             )?;
         }
 
-        writeln!(output, ":")?;
+        let marker = Self::metadata_suffix(&struct_node.metadata)
+            + &Self::deprecation_marker(struct_node.deprecated.as_ref());
+        writeln!(output, ":{marker}")?;
 
         for child in &struct_node.children {
             self.format_node(output, child, indent + 1)?;
@@ -269,15 +287,30 @@ impl TextFormatter {
         indent: usize,
     ) -> Result<(), std::fmt::Error> {
         let ind = Self::indent(indent);
-        let vis_symbol = Self::visibility_symbol(enum_node.visibility);
+        let vis_symbol =
+            Self::access_prefix(enum_node.visibility, enum_node.source_visibility.as_ref());
+
+        for decorator in &enum_node.decorators {
+            writeln!(output, "{ind}@{decorator}")?;
+        }
 
         write!(output, "{}{}enum {}", ind, vis_symbol, enum_node.name)?;
 
+        if !enum_node.type_params.is_empty() {
+            write!(
+                output,
+                "<{}>",
+                self.format_type_params(&enum_node.type_params)
+            )?;
+        }
+
         if let Some(ref enum_type) = enum_node.enum_type {
             write!(output, ": {}", self.format_type_ref(enum_type))?;
         }
 
-        writeln!(output, ":")?;
+        let marker = Self::metadata_suffix(&enum_node.metadata)
+            + &Self::deprecation_marker(enum_node.deprecated.as_ref());
+        writeln!(output, ":{marker}")?;
 
         for child in &enum_node.children {
             self.format_node(output, child, indent + 1)?;
@@ -286,6 +319,53 @@ impl TextFormatter {
         Ok(())
     }
 
+    /// Format an enum variant on one line, e.g. `Move(x: i32, y: i32)`,
+    /// `Some(T)`, `MERCURY(3.303e+23, 2.4397e6)` or `Active = "ACTIVE"`
+    fn format_enum_variant(
+        &self,
+        output: &mut String,
+        variant: &EnumVariant,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = Self::indent(indent);
+        write!(output, "{}{}", ind, variant.name)?;
+
+        if !variant.fields.is_empty() {
+            let fields = variant
+                .fields
+                .iter()
+                .map(|field| {
+                    if field.name.is_empty() {
+                        self.format_type_ref(&field.param_type)
+                    } else {
+                        self.format_parameter(field)
+                    }
+                })
+                .collect::<Vec<_>>()
+                .join(", ");
+            write!(output, "({fields})")?;
+        } else if !variant.arguments.is_empty() {
+            write!(output, "({})", variant.arguments.join(", "))?;
+        }
+
+        if let Some(ref value) = variant.value {
+            write!(output, " = {value}")?;
+        }
+
+        let marker = Self::metadata_suffix(&variant.metadata)
+            + &Self::deprecation_marker(variant.deprecated.as_ref());
+        if variant.children.is_empty() {
+            writeln!(output, "{marker}")?;
+        } else {
+            writeln!(output, ":{marker}")?;
+            for child in &variant.children {
+                self.format_node(output, child, indent + 1)?;
+            }
+        }
+
+        Ok(())
+    }
+
     /// Format a type alias
     fn format_type_alias(
         &self,
@@ -294,7 +374,7 @@ impl TextFormatter {
         indent: usize,
     ) -> Result<(), std::fmt::Error> {
         let ind = Self::indent(indent);
-        let vis_symbol = Self::visibility_symbol(alias.visibility);
+        let vis_symbol = Self::access_prefix(alias.visibility, alias.source_visibility.as_ref());
 
         write!(output, "{}{}type {}", ind, vis_symbol, alias.name)?;
 
@@ -302,7 +382,13 @@ impl TextFormatter {
             write!(output, "<{}>", self.format_type_params(&alias.type_params))?;
         }
 
-        writeln!(output, " = {}", self.format_type_ref(&alias.alias_type))?;
+        let marker = Self::metadata_suffix(&alias.metadata)
+            + &Self::deprecation_marker(alias.deprecated.as_ref());
+        writeln!(
+            output,
+            " = {}{marker}",
+            self.format_type_ref(&alias.alias_type)
+        )?;
 
         Ok(())
     }
@@ -315,68 +401,112 @@ impl TextFormatter {
         indent: usize,
     ) -> Result<(), std::fmt::Error> {
         let ind = Self::indent(indent);
-        let vis_symbol = Self::visibility_symbol(func.visibility);
+        let vis_symbol = Self::access_prefix(func.visibility, func.source_visibility.as_ref());
 
         // Write decorators
         for decorator in &func.decorators {
             writeln!(output, "{ind}@{decorator}")?;
         }
 
-        // Modifiers
-        let mut modifiers = func
-            .modifiers
-            .iter()
-            .map(|m| format!("{m:?}").to_lowercase())
-            .collect::<Vec<_>>();
-        let modifiers_str = if modifiers.is_empty() {
-            String::new()
-        } else {
-            modifiers.push(String::new());
-            modifiers.join(" ")
-        };
-
         write!(
             output,
-            "{}{}{}def {}",
-            ind, vis_symbol, modifiers_str, func.name
+            "{}{}{}def {}{}",
+            ind,
+            vis_symbol,
+            Self::function_modifiers(func),
+            func.name,
+            self.format_signature(func)
         )?;
 
-        // Type parameters
+        let marker = Self::metadata_suffix(&func.metadata)
+            + &Self::deprecation_marker(func.deprecated.as_ref());
+        if self.options.include_implementation {
+            if let Some(ref implementation) = func.implementation {
+                writeln!(output, ":{marker}")?;
+                // Indent implementation
+                for line in implementation.lines() {
+                    writeln!(output, "{ind}    {line}")?;
+                }
+            } else {
+                writeln!(output, "{marker}")?;
+            }
+        } else {
+            writeln!(output, "{marker}")?;
+        }
+
+        Ok(())
+    }
+
+    /// Format an overload set compactly: the first signature as a regular
+    /// function, the others as `| (params) -> ret` continuation lines
+    ///
+    /// With implementations included every signature is written in full.
+    /// Sets collapsed for outline-only output end with `| +N more overloads`.
+    fn format_overload_set(
+        &self,
+        output: &mut String,
+        overloads: &OverloadSet,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = Self::indent(indent);
+        let Some((first, rest)) = overloads.signatures.split_first() else {
+            return Ok(());
+        };
+
+        self.format_function(output, first, indent)?;
+        for func in rest {
+            if self.options.include_implementation {
+                self.format_function(output, func, indent)?;
+                continue;
+            }
+            writeln!(
+                output,
+                "{ind}    | {}{}{}{}{}",
+                Self::access_prefix(func.visibility, func.source_visibility.as_ref()),
+                Self::function_modifiers(func),
+                self.format_signature(func),
+                Self::metadata_suffix(&func.metadata),
+                Self::deprecation_marker(func.deprecated.as_ref())
+            )?;
+        }
+
+        let hidden = overloads.count.saturating_sub(overloads.signatures.len());
+        if hidden > 0 {
+            writeln!(output, "{ind}    | +{hidden} more overloads")?;
+        }
+
+        Ok(())
+    }
+
+    /// Function modifiers with a trailing space, e.g. `static async `
+    fn function_modifiers(func: &Function) -> String {
+        func.modifiers
+            .iter()
+            .map(|m| format!("{} ", m.as_str()))
+            .collect()
+    }
+
+    /// Type parameters, parameters and return type, e.g. `<T>(x: T) -> T`
+    fn format_signature(&self, func: &Function) -> String {
+        let mut result = String::new();
+
         if !func.type_params.is_empty() {
-            write!(output, "<{}>", self.format_type_params(&func.type_params))?;
+            write!(result, "<{}>", self.format_type_params(&func.type_params)).unwrap();
         }
 
-        // Parameters
-        write!(output, "(")?;
         let params = func
             .parameters
             .iter()
             .map(|p| self.format_parameter(p))
             .collect::<Vec<_>>()
             .join(", ");
-        write!(output, "{params}")?;
-        write!(output, ")")?;
+        write!(result, "({params})").unwrap();
 
-        // Return type
         if let Some(ref ret_type) = func.return_type {
-            write!(output, " -> {}", self.format_type_ref(ret_type))?;
+            write!(result, " -> {}", self.format_type_ref(ret_type)).unwrap();
         }
 
-        if self.options.include_implementation {
-            if let Some(ref implementation) = func.implementation {
-                writeln!(output, ":")?;
-                // Indent implementation
-                for line in implementation.lines() {
-                    writeln!(output, "{ind}    {line}")?;
-                }
-            } else {
-                writeln!(output)?;
-            }
-        } else {
-            writeln!(output)?;
-        }
-
-        Ok(())
+        result
     }
 
     /// Format a field
@@ -387,13 +517,13 @@ impl TextFormatter {
         indent: usize,
     ) -> Result<(), std::fmt::Error> {
         let ind = Self::indent(indent);
-        let vis_symbol = Self::visibility_symbol(field.visibility);
+        let vis_symbol = Self::access_prefix(field.visibility, field.source_visibility.as_ref());
 
         // Modifiers
         let mut modifiers = field
             .modifiers
             .iter()
-            .map(|m| format!("{m:?}").to_lowercase())
+            .map(|m| m.as_str().to_string())
             .collect::<Vec<_>>();
         let modifiers_str = if modifiers.is_empty() {
             String::new()
@@ -416,7 +546,161 @@ impl TextFormatter {
             write!(output, " = {default_value}")?;
         }
 
-        writeln!(output)?;
+        let marker = Self::metadata_suffix(&field.metadata)
+            + &Self::deprecation_marker(field.deprecated.as_ref());
+        writeln!(output, "{marker}")?;
+
+        Ok(())
+    }
+
+    /// Format a property with its accessors, e.g. `Total: decimal { get; -set }`
+    fn format_property(
+        &self,
+        output: &mut String,
+        property: &Property,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = Self::indent(indent);
+        let vis_symbol =
+            Self::access_prefix(property.visibility, property.source_visibility.as_ref());
+
+        let mut modifiers = property
+            .modifiers
+            .iter()
+            .map(|m| m.as_str().to_string())
+            .collect::<Vec<_>>();
+        let modifiers_str = if modifiers.is_empty() {
+            String::new()
+        } else {
+            modifiers.push(String::new());
+            modifiers.join(" ")
+        };
+
+        write!(
+            output,
+            "{}{}{}{}",
+            ind, vis_symbol, modifiers_str, property.name
+        )?;
+
+        if let Some(ref property_type) = property.property_type {
+            write!(output, ": {}", self.format_type_ref(property_type))?;
+        }
+
+        if !property.accessors.is_empty() {
+            let accessors = property
+                .accessors
+                .iter()
+                .map(|accessor| match accessor.visibility {
+                    Some(visibility) => format!(
+                        "{}{}",
+                        Self::access_prefix(visibility, accessor.source_visibility.as_ref()),
+                        accessor.accessor_kind
+                    ),
+                    None => accessor.accessor_kind.to_string(),
+                })
+                .collect::<Vec<_>>()
+                .join("; ");
+            write!(output, " {{ {accessors} }}")?;
+        }
+
+        if let Some(ref default_value) = property.default_value {
+            write!(output, " = {default_value}")?;
+        }
+
+        let marker = Self::metadata_suffix(&property.metadata)
+            + &Self::deprecation_marker(property.deprecated.as_ref());
+        writeln!(output, "{marker}")?;
+
+        Ok(())
+    }
+
+    /// Format a module-level variable or constant
+    fn format_variable(
+        &self,
+        output: &mut String,
+        variable: &Variable,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = Self::indent(indent);
+        let vis_symbol =
+            Self::access_prefix(variable.visibility, variable.source_visibility.as_ref());
+
+        // Modifiers, then the declaration keyword (`static mut` for mutable statics)
+        let mut keywords = variable
+            .modifiers
+            .iter()
+            .map(|m| m.as_str().to_string())
+            .collect::<Vec<_>>();
+        keywords.push(variable.var_kind.as_str().to_string());
+        if variable.is_mutable && variable.var_kind == VariableKind::Static {
+            keywords.push("mut".to_string());
+        }
+
+        write!(
+            output,
+            "{}{}{} {}",
+            ind,
+            vis_symbol,
+            keywords.join(" "),
+            variable.name
+        )?;
+
+        if let Some(ref var_type) = variable.var_type {
+            write!(output, ": {}", self.format_type_ref(var_type))?;
+        }
+
+        if let Some(ref value) = variable.value {
+            write!(output, " = {value}")?;
+        }
+
+        let marker = Self::metadata_suffix(&variable.metadata)
+            + &Self::deprecation_marker(variable.deprecated.as_ref());
+        writeln!(output, "{marker}")?;
+
+        Ok(())
+    }
+
+    /// Format a macro definition
+    ///
+    /// `macro_rules!` lists its arms in source syntax; other kinds read
+    /// `macro derive(Name)`, `macro #[name]`, `macro name(args)` and
+    /// `macro NAME = value`.
+    fn format_macro(
+        output: &mut String,
+        macro_def: &Macro,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = Self::indent(indent);
+        let vis_symbol =
+            Self::access_prefix(macro_def.visibility, macro_def.source_visibility.as_ref());
+
+        for decorator in &macro_def.decorators {
+            writeln!(output, "{ind}@{decorator}")?;
+        }
+
+        let name = &macro_def.name;
+        let signature = match macro_def.macro_kind {
+            MacroKind::Rules if macro_def.parameters.is_empty() => format!("macro_rules! {name}"),
+            MacroKind::Rules => format!(
+                "macro_rules! {name} {{ {} }}",
+                macro_def.parameters.join("; ")
+            ),
+            MacroKind::Derive => format!("macro derive({name})"),
+            MacroKind::Attribute => format!("macro #[{name}]"),
+            MacroKind::FunctionLike => {
+                format!("macro {name}({})", macro_def.parameters.join(", "))
+            }
+            MacroKind::Object => format!("macro {name}"),
+        };
+        write!(output, "{ind}{vis_symbol}{signature}")?;
+
+        if let Some(ref value) = macro_def.value {
+            write!(output, " = {value}")?;
+        }
+
+        let marker = Self::metadata_suffix(&macro_def.metadata)
+            + &Self::deprecation_marker(macro_def.deprecated.as_ref());
+        writeln!(output, "{marker}")?;
 
         Ok(())
     }
@@ -455,6 +739,35 @@ impl TextFormatter {
         Ok(())
     }
 
+    /// Format a namespace or module block
+    fn format_module(
+        &self,
+        output: &mut String,
+        module: &Module,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = Self::indent(indent);
+        let vis_symbol = Self::access_prefix(module.visibility, module.source_visibility.as_ref());
+
+        for decorator in &module.decorators {
+            writeln!(output, "{ind}@{decorator}")?;
+        }
+
+        let marker = Self::metadata_suffix(&module.metadata)
+            + &Self::deprecation_marker(module.deprecated.as_ref());
+        writeln!(
+            output,
+            "{}{}module {}:{}",
+            ind, vis_symbol, module.name, marker
+        )?;
+
+        for child in &module.children {
+            self.format_node(output, child, indent + 1)?;
+        }
+
+        Ok(())
+    }
+
     /// Format raw content
     fn format_raw(
         output: &mut String,
@@ -465,10 +778,51 @@ impl TextFormatter {
         Ok(())
     }
 
+    /// Language-specific metadata, e.g. ` [abi=C, throws=IOException]`
+    fn metadata_suffix(metadata: &BTreeMap<String, String>) -> String {
+        if metadata.is_empty() {
+            return String::new();
+        }
+
+        let entries = metadata
+            .iter()
+            .map(|(key, value)| {
+                if value.is_empty() {
+                    key.clone()
+                } else {
+                    format!("{key}={value}")
+                }
+            })
+            .collect::<Vec<_>>()
+            .join(", ");
+        format!(" [{entries}]")
+    }
+
+    /// Trailing marker for deprecated declarations, e.g. `  # deprecated: use fetch`
+    fn deprecation_marker(deprecated: Option<&Deprecation>) -> String {
+        let Some(deprecated) = deprecated else {
+            return String::new();
+        };
+
+        if let Some(ref replacement) = deprecated.replacement {
+            format!("  # deprecated: use {replacement}")
+        } else if let Some(message) = deprecated.message.as_deref().and_then(|m| m.lines().next()) {
+            format!("  # deprecated: {message}")
+        } else {
+            "  # deprecated".to_string()
+        }
+    }
+
     /// Format a type reference
-    #[allow(clippy::only_used_in_recursion)]
+    ///
+    /// Parsed types keep the text as written in the name, generic arguments
+    /// and all, so only a bare name (`List`, `std::vec::Vec`) gets its
+    /// structured parts appended.
     fn format_type_ref(&self, type_ref: &TypeRef) -> String {
         let mut result = type_ref.name.clone();
+        if !is_bare_name(&type_ref.name) {
+            return result;
+        }
 
         if !type_ref.type_args.is_empty() {
             let args = type_ref
@@ -532,6 +886,19 @@ impl TextFormatter {
         result
     }
 
+    /// Visibility symbol plus the source keyword when it refines the bucket,
+    /// e.g. `~pub(crate) ` or `open `
+    fn access_prefix(
+        visibility: Visibility,
+        source_visibility: Option<&SourceVisibility>,
+    ) -> String {
+        let symbol = Self::visibility_symbol(visibility);
+        match source_visibility {
+            Some(level) if !level.is_coarse() => format!("{symbol}{level} "),
+            _ => symbol.to_string(),
+        }
+    }
+
     /// Get visibility symbol
     fn visibility_symbol(visibility: Visibility) -> &'static str {
         match visibility {
@@ -554,9 +921,18 @@ impl Default for TextFormatter {
     }
 }
 
+/// Whether a type name is a plain, possibly qualified identifier
+fn is_bare_name(name: &str) -> bool {
+    !name.is_empty()
+        && name
+            .chars()
+            .all(|c| c.is_alphanumeric() || matches!(c, '_' | '$' | '.' | ':' | '\\'))
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
+    use distiller_core::parser::types::{TypeSyntax, parse_type};
 
     #[test]
     fn test_simple_class() {
@@ -565,6 +941,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Example".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -573,6 +950,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "__init__".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -588,9 +966,13 @@ mod tests {
                     implementation: None,
                     line_start: 2,
                     line_end: 3,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -612,6 +994,52 @@ mod tests {
         assert_eq!(TextFormatter::visibility_symbol(Visibility::Internal), "~");
     }
 
+    #[test]
+    fn test_access_prefix() {
+        assert_eq!(
+            TextFormatter::access_prefix(Visibility::Internal, Some(&SourceVisibility::Crate)),
+            "~pub(crate) "
+        );
+        assert_eq!(
+            TextFormatter::access_prefix(Visibility::Public, Some(&SourceVisibility::Open)),
+            "open "
+        );
+        assert_eq!(
+            TextFormatter::access_prefix(Visibility::Private, Some(&SourceVisibility::Private)),
+            "-"
+        );
+        assert_eq!(
+            TextFormatter::access_prefix(Visibility::Protected, None),
+            "*"
+        );
+    }
+
+    #[test]
+    fn test_type_refs() {
+        let formatter = TextFormatter::new();
+
+        // Parsed types are shown as written, without repeating their parts
+        for (text, syntax) in [
+            ("Map<String, List<User>>", TypeSyntax::Java),
+            ("Optional[dict[str, Any]]", TypeSyntax::Python),
+            ("int | None", TypeSyntax::Python),
+            ("[]map[string]int", TypeSyntax::Go),
+            ("String?", TypeSyntax::Kotlin),
+            ("&mut [u8]", TypeSyntax::Rust),
+        ] {
+            assert_eq!(formatter.format_type_ref(&parse_type(text, syntax)), text);
+        }
+
+        // Types built from parts render them
+        let mut list = TypeRef::new("List");
+        list.type_args = vec![TypeRef::new("User")];
+        list.is_nullable = true;
+        assert_eq!(formatter.format_type_ref(&list), "List<User>?");
+        let mut ids = TypeRef::new("int");
+        ids.is_array = true;
+        assert_eq!(formatter.format_type_ref(&ids), "int[]");
+    }
+
     #[test]
     fn test_import() {
         let file = File {
@@ -647,6 +1075,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Example".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -655,13 +1084,18 @@ mod tests {
                 children: vec![Node::Field(Field {
                     name: "_private_field".to_string(),
                     visibility: Visibility::Private,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("str")),
                     default_value: None,
                     line: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -670,4 +1104,339 @@ mod tests {
 
         assert!(result.contains("-_private_field: str"));
     }
+
+    #[test]
+    fn test_deprecated_marker() {
+        let deprecated_method = |name: &str, deprecated: Deprecation| {
+            Node::Function(Function {
+                name: name.to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                modifiers: Vec::new(),
+                decorators: Vec::new(),
+                type_params: Vec::new(),
+                parameters: Vec::new(),
+                return_type: None,
+                implementation: None,
+                line_start: 1,
+                line_end: 1,
+                deprecated: Some(deprecated),
+                metadata: BTreeMap::new(),
+            })
+        };
+        let file = File {
+            path: "api.py".to_string(),
+            children: vec![
+                deprecated_method(
+                    "load",
+                    Deprecation {
+                        message: Some("Use fetch instead".to_string()),
+                        replacement: Some("fetch".to_string()),
+                        since: None,
+                    },
+                ),
+                deprecated_method(
+                    "save",
+                    Deprecation {
+                        message: Some("Writes are going away\nsee #42".to_string()),
+                        ..Deprecation::default()
+                    },
+                ),
+                deprecated_method("close", Deprecation::default()),
+            ],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("def load()  # deprecated: use fetch\n"));
+        assert!(result.contains("def save()  # deprecated: Writes are going away\n"));
+        assert!(result.contains("def close()  # deprecated\n"));
+    }
+
+    #[test]
+    fn test_modifiers_and_metadata() {
+        let file = File {
+            path: "ffi.rs".to_string(),
+            children: vec![Node::Function(Function {
+                name: "write".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                modifiers: vec![Modifier::Unsafe, Modifier::Extern],
+                decorators: Vec::new(),
+                type_params: Vec::new(),
+                parameters: Vec::new(),
+                return_type: None,
+                implementation: None,
+                line_start: 1,
+                line_end: 1,
+                deprecated: Some(Deprecation::default()),
+                metadata: BTreeMap::from([
+                    ("abi".to_string(), "C".to_string()),
+                    ("no_mangle".to_string(), String::new()),
+                ]),
+            })],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("unsafe extern def write() [abi=C, no_mangle]  # deprecated\n"));
+    }
+
+    #[test]
+    fn test_variables() {
+        let variable = |name: &str, var_kind: VariableKind, is_mutable: bool, value: &str| {
+            Node::Variable(Variable {
+                name: name.to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                var_kind,
+                modifiers: Vec::new(),
+                var_type: Some(TypeRef::new("u32")),
+                value: Some(value.to_string()),
+                is_mutable,
+                line: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })
+        };
+        let file = File {
+            path: "limits.rs".to_string(),
+            children: vec![
+                variable("MAX_CONNECTIONS", VariableKind::Const, false, "128"),
+                variable("COUNTER", VariableKind::Static, true, "0"),
+            ],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("const MAX_CONNECTIONS: u32 = 128\n"));
+        assert!(result.contains("static mut COUNTER: u32 = 0\n"));
+    }
+
+    #[test]
+    fn test_macros() {
+        let macro_def = |name: &str, macro_kind: MacroKind, parameters: &[&str], value: &str| {
+            Node::Macro(Macro {
+                name: name.to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                decorators: Vec::new(),
+                macro_kind,
+                parameters: parameters.iter().map(ToString::to_string).collect(),
+                value: (!value.is_empty()).then(|| value.to_string()),
+                line_start: 1,
+                line_end: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })
+        };
+        let file = File {
+            path: "macros.h".to_string(),
+            children: vec![
+                macro_def(
+                    "hashmap",
+                    MacroKind::Rules,
+                    &["()", "($($k:expr => $v:expr),+)"],
+                    "",
+                ),
+                macro_def("Builder", MacroKind::Derive, &[], ""),
+                macro_def(
+                    "MIN",
+                    MacroKind::FunctionLike,
+                    &["a", "b"],
+                    "((a) < (b) ? (a) : (b))",
+                ),
+                macro_def("MAX_USERS", MacroKind::Object, &[], "128"),
+            ],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("macro_rules! hashmap { (); ($($k:expr => $v:expr),+) }\n"));
+        assert!(result.contains("macro derive(Builder)\n"));
+        assert!(result.contains("macro MIN(a, b) = ((a) < (b) ? (a) : (b))\n"));
+        assert!(result.contains("macro MAX_USERS = 128\n"));
+    }
+
+    #[test]
+    fn test_nested_modules() {
+        let module = |name: &str, children: Vec<Node>| {
+            Node::Module(Module {
+                name: name.to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                decorators: Vec::new(),
+                children,
+                line_start: 1,
+                line_end: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })
+        };
+        let file = File {
+            path: "net.cpp".to_string(),
+            children: vec![module(
+                "net",
+                vec![module(
+                    "http",
+                    vec![Node::Function(Function {
+                        name: "get".to_string(),
+                        visibility: Visibility::Public,
+                        source_visibility: None,
+                        modifiers: Vec::new(),
+                        decorators: Vec::new(),
+                        type_params: Vec::new(),
+                        parameters: Vec::new(),
+                        return_type: None,
+                        implementation: None,
+                        line_start: 1,
+                        line_end: 1,
+                        deprecated: None,
+                        metadata: BTreeMap::new(),
+                    })],
+                )],
+            )],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("module net:\n    module http:\n        def get()\n"));
+    }
+
+    #[test]
+    fn test_enum_variants() {
+        let variant = |name: &str, fields: Vec<Parameter>, value: Option<&str>| {
+            Node::EnumVariant(EnumVariant {
+                name: name.to_string(),
+                fields,
+                value: value.map(str::to_string),
+                arguments: Vec::new(),
+                children: Vec::new(),
+                line: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })
+        };
+        let field = |name: &str, ty: &str| Parameter {
+            name: name.to_string(),
+            param_type: TypeRef::new(ty),
+            default_value: None,
+            is_variadic: false,
+            is_optional: false,
+            decorators: Vec::new(),
+        };
+        let file = File {
+            path: "shape.rs".to_string(),
+            children: vec![Node::Enum(Enum {
+                name: "Message".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                decorators: vec!["derive(Debug)".to_string()],
+                type_params: Vec::new(),
+                enum_type: None,
+                children: vec![
+                    variant("Quit", Vec::new(), None),
+                    variant("Move", vec![field("x", "i32"), field("y", "i32")], None),
+                    variant("Write", vec![field("", "String")], None),
+                    variant("Code", Vec::new(), Some("7")),
+                ],
+                line_start: 1,
+                line_end: 6,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("@derive(Debug)\nenum Message:\n"));
+        assert!(result.contains("    Quit\n"));
+        assert!(result.contains("    Move(x: i32, y: i32)\n"));
+        assert!(result.contains("    Write(String)\n"));
+        assert!(result.contains("    Code = 7\n"));
+    }
+
+    #[test]
+    fn test_property_accessors() {
+        let mut setter = Accessor::new(AccessorKind::Set);
+        setter.visibility = Some(Visibility::Private);
+        let file = File {
+            path: "Order.cs".to_string(),
+            children: vec![Node::Property(Property {
+                name: "Total".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                modifiers: Vec::new(),
+                property_type: Some(TypeRef::new("decimal")),
+                accessors: vec![Accessor::new(AccessorKind::Get), setter],
+                is_computed: false,
+                default_value: None,
+                line_start: 1,
+                line_end: 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("Total: decimal { get; -set }\n"));
+    }
+
+    #[test]
+    fn test_overload_set() {
+        let signature = |param_type: &str, line: usize| Function {
+            name: "write".to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            parameters: vec![Parameter {
+                name: "value".to_string(),
+                param_type: TypeRef::new(param_type),
+                default_value: None,
+                is_variadic: false,
+                is_optional: false,
+                decorators: vec![],
+            }],
+            return_type: Some(TypeRef::new("void")),
+            implementation: None,
+            line_start: line,
+            line_end: line,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        };
+        let overloads = |signatures: Vec<Function>| File {
+            path: "Writer.java".to_string(),
+            children: vec![Node::OverloadSet(OverloadSet {
+                name: "write".to_string(),
+                signatures,
+                count: 2,
+                line_start: 1,
+                line_end: 2,
+            })],
+        };
+
+        let formatter = TextFormatter::new();
+        let result = formatter
+            .format_file(&overloads(vec![
+                signature("int", 1),
+                signature("String", 2),
+            ]))
+            .unwrap();
+        assert!(result.contains("def write(value: int) -> void\n    | (value: String) -> void\n"));
+
+        let collapsed = formatter
+            .format_file(&overloads(vec![signature("int", 1)]))
+            .unwrap();
+        assert!(collapsed.contains("def write(value: int) -> void\n    | +1 more overloads\n"));
+    }
 }
@@ -4,9 +4,11 @@
 //! Provides proper XML escaping and semantic structure.
 
 use distiller_core::ir::{
-    Class, Comment, Directory, Enum, Field, File, Function, Import, Interface, Modifier, Node,
-    Package, Parameter, RawContent, Struct, TypeAlias, TypeParam, TypeRef, Visibility,
+    Class, Comment, Directory, Enum, EnumVariant, Field, File, Function, Import, Interface, Macro,
+    Modifier, Module, Node, OverloadSet, Package, Parameter, Property, RawContent,
+    SourceVisibility, Struct, TypeAlias, TypeParam, TypeRef, TypeShape, Variable, Visibility,
 };
+use std::collections::BTreeMap;
 use std::fmt::Write;
 
 /// XML formatter options
@@ -106,14 +108,20 @@ impl XmlFormatter {
             Node::File(file) => self.format_file_element(output, file, indent),
             Node::Directory(dir) => self.format_directory(output, dir, indent),
             Node::Package(pkg) => self.format_package(output, pkg, indent),
+            Node::Module(module) => self.format_module(output, module, indent),
             Node::Import(import) => self.format_import(output, import, indent),
             Node::Class(class) => self.format_class(output, class, indent),
             Node::Interface(interface) => self.format_interface(output, interface, indent),
             Node::Struct(struct_node) => self.format_struct(output, struct_node, indent),
             Node::Enum(enum_node) => self.format_enum(output, enum_node, indent),
+            Node::EnumVariant(variant) => self.format_enum_variant(output, variant, indent),
             Node::TypeAlias(type_alias) => self.format_type_alias(output, type_alias, indent),
             Node::Function(function) => self.format_function(output, function, indent),
+            Node::OverloadSet(overloads) => self.format_overload_set(output, overloads, indent),
             Node::Field(field) => self.format_field(output, field, indent),
+            Node::Property(property) => self.format_property(output, property, indent),
+            Node::Variable(variable) => self.format_variable(output, variable, indent),
+            Node::Macro(macro_def) => self.format_macro(output, macro_def, indent),
             Node::Comment(comment) => self.format_comment(output, comment, indent),
             Node::RawContent(raw) => self.format_raw_content(output, raw, indent),
         }
@@ -161,6 +169,33 @@ impl XmlFormatter {
         Ok(())
     }
 
+    /// Format a namespace or module block
+    fn format_module(
+        &self,
+        output: &mut String,
+        module: &Module,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        self.format_container(
+            output,
+            "module",
+            &module.name,
+            module.visibility,
+            module.source_visibility.as_ref(),
+            module.line_start,
+            module.line_end,
+            &[],
+            &module.metadata,
+            &module.decorators,
+            &[],
+            &[],
+            &[],
+            &module.children,
+            None,
+            indent,
+        )
+    }
+
     /// Format an import
     fn format_import(
         &self,
@@ -206,9 +241,11 @@ impl XmlFormatter {
         tag: &str,
         name: &str,
         visibility: Visibility,
+        source_visibility: Option<&SourceVisibility>,
         line_start: usize,
         line_end: usize,
         modifiers: &[Modifier],
+        metadata: &BTreeMap<String, String>,
         decorators: &[String],
         type_params: &[TypeParam],
         extends: &[TypeRef],
@@ -233,6 +270,7 @@ impl XmlFormatter {
         write!(output, "{ind}<{tag}")?;
         write!(output, " name=\"{}\"", escape_xml(name))?;
         write!(output, " visibility=\"{}\"", visibility_str(visibility))?;
+        write!(output, "{}", access_attr(source_visibility))?;
         write!(output, " line-start=\"{line_start}\"")?;
         write!(output, " line-end=\"{line_end}\"")?;
         if !modifiers.is_empty() {
@@ -242,6 +280,7 @@ impl XmlFormatter {
                 escape_xml(&modifiers_to_string(modifiers))
             )?;
         }
+        write!(output, "{}", metadata_attr(metadata))?;
 
         // Check if self-closing
         if type_params.is_empty()
@@ -315,9 +354,11 @@ impl XmlFormatter {
             "class",
             &class.name,
             class.visibility,
+            class.source_visibility.as_ref(),
             class.line_start,
             class.line_end,
             &class.modifiers,
+            &class.metadata,
             &class.decorators,
             &class.type_params,
             &class.extends,
@@ -340,9 +381,11 @@ impl XmlFormatter {
             "interface",
             &interface.name,
             interface.visibility,
+            interface.source_visibility.as_ref(),
             interface.line_start,
             interface.line_end,
             &[], // interfaces don't have modifiers
+            &interface.metadata,
             &[], // interfaces don't have decorators
             &interface.type_params,
             &interface.extends,
@@ -365,9 +408,11 @@ impl XmlFormatter {
             "struct",
             &struct_node.name,
             struct_node.visibility,
+            struct_node.source_visibility.as_ref(),
             struct_node.line_start,
             struct_node.line_end,
             &[], // structs don't have modifiers in IR
+            &struct_node.metadata,
             &[], // structs don't have decorators
             &struct_node.type_params,
             &[], // structs don't extend
@@ -390,11 +435,13 @@ impl XmlFormatter {
             "enum",
             &enum_node.name,
             enum_node.visibility,
+            enum_node.source_visibility.as_ref(),
             enum_node.line_start,
             enum_node.line_end,
             &[], // enums don't have modifiers
-            &[], // enums don't have decorators
-            &[], // enums don't have type params
+            &enum_node.metadata,
+            &enum_node.decorators,
+            &enum_node.type_params,
             &[], // enums don't extend
             &[], // enums don't implement
             &enum_node.children,
@@ -403,6 +450,48 @@ impl XmlFormatter {
         )
     }
 
+    /// Format an enum variant with its payload, value and constant body
+    fn format_enum_variant(
+        &self,
+        output: &mut String,
+        variant: &EnumVariant,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = self.indent(indent);
+        write!(output, "{ind}<variant")?;
+        write!(output, " name=\"{}\"", escape_xml(&variant.name))?;
+        write!(output, " line=\"{}\"", variant.line)?;
+        if let Some(ref value) = variant.value {
+            write!(output, " value=\"{}\"", escape_xml(value))?;
+        }
+        write!(output, "{}", metadata_attr(&variant.metadata))?;
+
+        if variant.fields.is_empty() && variant.arguments.is_empty() && variant.children.is_empty()
+        {
+            writeln!(output, " />")?;
+            return Ok(());
+        }
+
+        writeln!(output, ">")?;
+        for field in &variant.fields {
+            self.format_parameter(output, field, indent + 1)?;
+        }
+        let arg_ind = self.indent(indent + 1);
+        for argument in &variant.arguments {
+            writeln!(
+                output,
+                "{}<argument>{}</argument>",
+                arg_ind,
+                escape_xml(argument)
+            )?;
+        }
+        for child in &variant.children {
+            self.format_node(output, child, indent + 1)?;
+        }
+        writeln!(output, "{ind}</variant>")?;
+        Ok(())
+    }
+
     /// Format a type alias
     fn format_type_alias(
         &self,
@@ -418,7 +507,13 @@ impl XmlFormatter {
             " visibility=\"{}\"",
             visibility_str(type_alias.visibility)
         )?;
+        write!(
+            output,
+            "{}",
+            access_attr(type_alias.source_visibility.as_ref())
+        )?;
         write!(output, " line=\"{}\"", type_alias.line)?;
+        write!(output, "{}", metadata_attr(&type_alias.metadata))?;
         writeln!(output, ">")?;
 
         if !type_alias.type_params.is_empty() {
@@ -440,6 +535,30 @@ impl XmlFormatter {
     }
 
     /// Format a function
+    /// Format an overload set; `count` exceeds the listed signatures when
+    /// the set was collapsed
+    fn format_overload_set(
+        &self,
+        output: &mut String,
+        overloads: &OverloadSet,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = self.indent(indent);
+        write!(output, "{ind}<overload-set")?;
+        write!(output, " name=\"{}\"", escape_xml(&overloads.name))?;
+        write!(output, " count=\"{}\"", overloads.count)?;
+        write!(output, " line-start=\"{}\"", overloads.line_start)?;
+        write!(output, " line-end=\"{}\"", overloads.line_end)?;
+        writeln!(output, ">")?;
+
+        for signature in &overloads.signatures {
+            self.format_function(output, signature, indent + 1)?;
+        }
+
+        writeln!(output, "{ind}</overload-set>")?;
+        Ok(())
+    }
+
     fn format_function(
         &self,
         output: &mut String,
@@ -463,6 +582,11 @@ impl XmlFormatter {
             " visibility=\"{}\"",
             visibility_str(function.visibility)
         )?;
+        write!(
+            output,
+            "{}",
+            access_attr(function.source_visibility.as_ref())
+        )?;
         write!(output, " line-start=\"{}\"", function.line_start)?;
         write!(output, " line-end=\"{}\"", function.line_end)?;
         if !function.modifiers.is_empty() {
@@ -472,6 +596,7 @@ impl XmlFormatter {
                 escape_xml(&modifiers_to_string(&function.modifiers))
             )?;
         }
+        write!(output, "{}", metadata_attr(&function.metadata))?;
         writeln!(output, ">")?;
 
         if !function.type_params.is_empty() {
@@ -525,6 +650,7 @@ impl XmlFormatter {
             " visibility=\"{}\"",
             visibility_str(field.visibility)
         )?;
+        write!(output, "{}", access_attr(field.source_visibility.as_ref()))?;
         write!(output, " line=\"{}\"", field.line)?;
         if !field.modifiers.is_empty() {
             write!(
@@ -533,6 +659,7 @@ impl XmlFormatter {
                 escape_xml(&modifiers_to_string(&field.modifiers))
             )?;
         }
+        write!(output, "{}", metadata_attr(&field.metadata))?;
 
         if let Some(ref field_type) = field.field_type {
             writeln!(output, ">")?;
@@ -558,6 +685,183 @@ impl XmlFormatter {
         Ok(())
     }
 
+    /// Format a property with its accessors
+    fn format_property(
+        &self,
+        output: &mut String,
+        property: &Property,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = self.indent(indent);
+        write!(output, "{ind}<property")?;
+        write!(output, " name=\"{}\"", escape_xml(&property.name))?;
+        write!(
+            output,
+            " visibility=\"{}\"",
+            visibility_str(property.visibility)
+        )?;
+        write!(
+            output,
+            "{}",
+            access_attr(property.source_visibility.as_ref())
+        )?;
+        write!(output, " line-start=\"{}\"", property.line_start)?;
+        write!(output, " line-end=\"{}\"", property.line_end)?;
+        if !property.modifiers.is_empty() {
+            write!(
+                output,
+                " modifiers=\"{}\"",
+                escape_xml(&modifiers_to_string(&property.modifiers))
+            )?;
+        }
+        if property.is_computed {
+            write!(output, " computed=\"true\"")?;
+        }
+        write!(output, "{}", metadata_attr(&property.metadata))?;
+        writeln!(output, ">")?;
+
+        let inner_ind = self.indent(indent + 1);
+        if let Some(ref property_type) = property.property_type {
+            writeln!(output, "{inner_ind}<type>")?;
+            self.format_type_ref(output, property_type, indent + 2)?;
+            writeln!(output, "{inner_ind}</type>")?;
+        }
+        for accessor in &property.accessors {
+            write!(
+                output,
+                "{inner_ind}<accessor kind=\"{}\"",
+                accessor.accessor_kind
+            )?;
+            if let Some(visibility) = accessor.visibility {
+                write!(output, " visibility=\"{}\"", visibility_str(visibility))?;
+                write!(
+                    output,
+                    "{}",
+                    access_attr(accessor.source_visibility.as_ref())
+                )?;
+            }
+            writeln!(output, " />")?;
+        }
+        if let Some(ref default_value) = property.default_value {
+            writeln!(
+                output,
+                "{}<default-value>{}</default-value>",
+                inner_ind,
+                escape_xml(default_value)
+            )?;
+        }
+        writeln!(output, "{ind}</property>")?;
+        Ok(())
+    }
+
+    /// Format a module-level variable or constant
+    fn format_variable(
+        &self,
+        output: &mut String,
+        variable: &Variable,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = self.indent(indent);
+        write!(output, "{ind}<variable")?;
+        write!(output, " name=\"{}\"", escape_xml(&variable.name))?;
+        write!(
+            output,
+            " visibility=\"{}\"",
+            visibility_str(variable.visibility)
+        )?;
+        write!(
+            output,
+            "{}",
+            access_attr(variable.source_visibility.as_ref())
+        )?;
+        write!(output, " kind=\"{}\"", variable.var_kind)?;
+        if variable.is_mutable {
+            write!(output, " mutable=\"true\"")?;
+        }
+        write!(output, " line=\"{}\"", variable.line)?;
+        if !variable.modifiers.is_empty() {
+            write!(
+                output,
+                " modifiers=\"{}\"",
+                escape_xml(&modifiers_to_string(&variable.modifiers))
+            )?;
+        }
+        write!(output, "{}", metadata_attr(&variable.metadata))?;
+
+        if let Some(ref var_type) = variable.var_type {
+            writeln!(output, ">")?;
+            let type_ind = self.indent(indent + 1);
+            writeln!(output, "{type_ind}<type>")?;
+            self.format_type_ref(output, var_type, indent + 2)?;
+            writeln!(output, "{type_ind}</type>")?;
+            if let Some(ref value) = variable.value {
+                writeln!(output, "{}<value>{}</value>", type_ind, escape_xml(value))?;
+            }
+            writeln!(output, "{ind}</variable>")?;
+        } else {
+            if let Some(ref value) = variable.value {
+                write!(output, " value=\"{}\"", escape_xml(value))?;
+            }
+            writeln!(output, " />")?;
+        }
+        Ok(())
+    }
+
+    fn format_macro(
+        &self,
+        output: &mut String,
+        macro_def: &Macro,
+        indent: usize,
+    ) -> Result<(), std::fmt::Error> {
+        let ind = self.indent(indent);
+        for decorator in &macro_def.decorators {
+            writeln!(
+                output,
+                "{}<decorator value=\"{}\" />",
+                ind,
+                escape_xml(decorator)
+            )?;
+        }
+
+        write!(output, "{ind}<macro")?;
+        write!(output, " name=\"{}\"", escape_xml(&macro_def.name))?;
+        write!(
+            output,
+            " visibility=\"{}\"",
+            visibility_str(macro_def.visibility)
+        )?;
+        write!(
+            output,
+            "{}",
+            access_attr(macro_def.source_visibility.as_ref())
+        )?;
+        write!(output, " kind=\"{}\"", macro_def.macro_kind)?;
+        write!(output, " line-start=\"{}\"", macro_def.line_start)?;
+        write!(output, " line-end=\"{}\"", macro_def.line_end)?;
+        if let Some(ref value) = macro_def.value {
+            write!(output, " value=\"{}\"", escape_xml(value))?;
+        }
+        write!(output, "{}", metadata_attr(&macro_def.metadata))?;
+
+        if macro_def.parameters.is_empty() {
+            writeln!(output, " />")?;
+            return Ok(());
+        }
+
+        writeln!(output, ">")?;
+        let param_ind = self.indent(indent + 1);
+        for param in &macro_def.parameters {
+            writeln!(
+                output,
+                "{}<parameter value=\"{}\" />",
+                param_ind,
+                escape_xml(param)
+            )?;
+        }
+        writeln!(output, "{ind}</macro>")?;
+        Ok(())
+    }
+
     /// Format a comment
     fn format_comment(
         &self,
@@ -641,19 +945,70 @@ impl XmlFormatter {
             ind,
             escape_xml(&type_ref.name)
         )?;
+        if type_ref.is_nullable {
+            write!(output, " nullable=\"true\"")?;
+        }
+        if let Some(dims) = type_ref.array_dims {
+            write!(output, " array-dims=\"{dims}\"")?;
+        }
 
-        if type_ref.type_args.is_empty() {
+        // Nested type lists: type arguments first, then the shape's parts
+        let mut groups: Vec<(&str, Vec<&TypeRef>)> = Vec::new();
+        if !type_ref.type_args.is_empty() {
+            groups.push(("type-args", type_ref.type_args.iter().collect()));
+        }
+        match &type_ref.shape {
+            Some(TypeShape::Union { members }) => {
+                write!(output, " form=\"union\"")?;
+                groups.push(("members", members.iter().collect()));
+            }
+            Some(TypeShape::Intersection { members }) => {
+                write!(output, " form=\"intersection\"")?;
+                groups.push(("members", members.iter().collect()));
+            }
+            Some(TypeShape::Function { params, returns }) => {
+                write!(output, " form=\"function\"")?;
+                groups.push(("params", params.iter().collect()));
+                if let Some(returns) = returns {
+                    groups.push(("returns", vec![returns.as_ref()]));
+                }
+            }
+            Some(TypeShape::Tuple { elements }) => {
+                write!(output, " form=\"tuple\"")?;
+                groups.push(("elements", elements.iter().collect()));
+            }
+            Some(TypeShape::Pointer { target, is_mutable }) => {
+                write!(output, " form=\"pointer\"")?;
+                if *is_mutable {
+                    write!(output, " mutable=\"true\"")?;
+                }
+                groups.push(("target", vec![target.as_ref()]));
+            }
+            Some(TypeShape::Reference { target, is_mutable }) => {
+                write!(output, " form=\"reference\"")?;
+                if *is_mutable {
+                    write!(output, " mutable=\"true\"")?;
+                }
+                groups.push(("target", vec![target.as_ref()]));
+            }
+            None => {}
+        }
+        groups.retain(|(_, types)| !types.is_empty());
+
+        if groups.is_empty() {
             writeln!(output, " />")?;
-        } else {
-            writeln!(output, ">")?;
-            let args_ind = self.indent(indent + 1);
-            writeln!(output, "{args_ind}<type-args>")?;
-            for arg in &type_ref.type_args {
-                self.format_type_ref(output, arg, indent + 2)?;
+            return Ok(());
+        }
+        writeln!(output, ">")?;
+        let group_ind = self.indent(indent + 1);
+        for (tag, types) in groups {
+            writeln!(output, "{group_ind}<{tag}>")?;
+            for nested in types {
+                self.format_type_ref(output, nested, indent + 2)?;
             }
-            writeln!(output, "{args_ind}</type-args>")?;
-            writeln!(output, "{ind}</type>")?;
+            writeln!(output, "{group_ind}</{tag}>")?;
         }
+        writeln!(output, "{ind}</type>")?;
         Ok(())
     }
 
@@ -732,25 +1087,41 @@ fn visibility_str(vis: Visibility) -> &'static str {
     }
 }
 
+/// `access` attribute for a source visibility that refines the coarse bucket
+fn access_attr(source_visibility: Option<&SourceVisibility>) -> String {
+    match source_visibility {
+        Some(level) if !level.is_coarse() => {
+            format!(" access=\"{}\"", escape_xml(&level.to_string()))
+        }
+        _ => String::new(),
+    }
+}
+
+/// `metadata` attribute with comma-separated `key=value` pairs
+fn metadata_attr(metadata: &BTreeMap<String, String>) -> String {
+    if metadata.is_empty() {
+        return String::new();
+    }
+
+    let pairs = metadata
+        .iter()
+        .map(|(key, value)| {
+            if value.is_empty() {
+                key.clone()
+            } else {
+                format!("{key}={value}")
+            }
+        })
+        .collect::<Vec<_>>()
+        .join(",");
+    format!(" metadata=\"{}\"", escape_xml(&pairs))
+}
+
 /// Convert modifiers to comma-separated string
 fn modifiers_to_string(modifiers: &[Modifier]) -> String {
     modifiers
         .iter()
-        .map(|m| match m {
-            Modifier::Static => "static",
-            Modifier::Abstract => "abstract",
-            Modifier::Final => "final",
-            Modifier::Async => "async",
-            Modifier::Virtual => "virtual",
-            Modifier::Override => "override",
-            Modifier::Const => "const",
-            Modifier::Readonly => "readonly",
-            Modifier::Mutable => "mutable",
-            Modifier::Event => "event",
-            Modifier::Data => "data",
-            Modifier::Sealed => "sealed",
-            Modifier::Inline => "inline",
-        })
+        .map(Modifier::as_str)
         .collect::<Vec<_>>()
         .join(",")
 }
@@ -766,6 +1137,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Example".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -774,6 +1146,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "__init__".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -789,9 +1162,13 @@ mod tests {
                     implementation: None,
                     line_start: 2,
                     line_end: 3,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -814,6 +1191,7 @@ mod tests {
             children: vec![Node::Function(Function {
                 name: "func<>&\"".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -822,6 +1200,8 @@ mod tests {
                 implementation: None,
                 line_start: 1,
                 line_end: 2,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -844,18 +1224,46 @@ mod tests {
                 Node::Field(Field {
                     name: "_private".to_string(),
                     visibility: Visibility::Private,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("str")),
                     default_value: None,
                     line: 1,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 }),
                 Node::Field(Field {
                     name: "public".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     field_type: Some(TypeRef::new("int")),
                     default_value: None,
                     line: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
+                }),
+                Node::Field(Field {
+                    name: "crate_only".to_string(),
+                    visibility: Visibility::Internal,
+                    source_visibility: Some(SourceVisibility::Crate),
+                    modifiers: Vec::new(),
+                    field_type: Some(TypeRef::new("int")),
+                    default_value: None,
+                    line: 3,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
+                }),
+                Node::Field(Field {
+                    name: "errno".to_string(),
+                    visibility: Visibility::Private,
+                    source_visibility: None,
+                    modifiers: vec![Modifier::Extern],
+                    field_type: None,
+                    default_value: None,
+                    line: 4,
+                    deprecated: None,
+                    metadata: BTreeMap::from([("abi".to_string(), "C".to_string())]),
                 }),
             ],
         };
@@ -864,6 +1272,8 @@ mod tests {
         let result = formatter.format_file(&file).unwrap();
 
         assert!(result.contains("visibility=\"private\""));
+        assert!(result.contains("visibility=\"internal\" access=\"pub(crate)\""));
+        assert!(result.contains("modifiers=\"extern\" metadata=\"abi=C\" />"));
         assert!(result.contains("visibility=\"public\""));
     }
 
@@ -875,6 +1285,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "func1".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -883,6 +1294,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
             File {
@@ -890,6 +1303,7 @@ mod tests {
                 children: vec![Node::Function(Function {
                     name: "func2".to_string(),
                     visibility: Visibility::Public,
+                    source_visibility: None,
                     modifiers: Vec::new(),
                     decorators: Vec::new(),
                     type_params: Vec::new(),
@@ -898,6 +1312,8 @@ mod tests {
                     implementation: None,
                     line_start: 1,
                     line_end: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
                 })],
             },
         ];
@@ -920,6 +1336,7 @@ mod tests {
             children: vec![Node::Function(Function {
                 name: "hello".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: Vec::new(),
@@ -928,6 +1345,8 @@ mod tests {
                 implementation: None,
                 line_start: 1,
                 line_end: 2,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -948,6 +1367,7 @@ mod tests {
             children: vec![Node::Class(Class {
                 name: "Container".to_string(),
                 visibility: Visibility::Public,
+                source_visibility: None,
                 modifiers: Vec::new(),
                 decorators: Vec::new(),
                 type_params: vec![TypeParam {
@@ -960,6 +1380,8 @@ mod tests {
                 children: Vec::new(),
                 line_start: 1,
                 line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             })],
         };
 
@@ -969,4 +1391,231 @@ mod tests {
         assert!(result.contains("<type-params>"));
         assert!(result.contains("<type-param name=\"T\""));
     }
+
+    #[test]
+    fn test_xml_type_shapes() {
+        let mut callback = TypeRef::new("(value: string) => void");
+        callback.shape = Some(TypeShape::Function {
+            params: vec![TypeRef::new("string")],
+            returns: Some(Box::new(TypeRef::new("void"))),
+        });
+        let mut maybe = TypeRef::new("string | null");
+        maybe.is_nullable = true;
+        maybe.shape = Some(TypeShape::Union {
+            members: vec![TypeRef::new("string"), TypeRef::new("null")],
+        });
+
+        let formatter = XmlFormatter::new();
+        let mut output = String::new();
+        formatter
+            .format_type_ref(&mut output, &callback, 0)
+            .unwrap();
+        formatter.format_type_ref(&mut output, &maybe, 0).unwrap();
+
+        assert!(output.contains("<type name=\"(value: string) =&gt; void\" form=\"function\">"));
+        assert!(output.contains("<params>"));
+        assert!(output.contains("<returns>"));
+        assert!(output.contains("<type name=\"string | null\" nullable=\"true\" form=\"union\">"));
+        assert!(output.contains("<members>"));
+    }
+
+    #[test]
+    fn test_xml_variable() {
+        let file = File {
+            path: "config.ts".to_string(),
+            children: vec![Node::Variable(Variable {
+                name: "DEFAULT_HEADERS".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                var_kind: distiller_core::ir::VariableKind::Const,
+                modifiers: Vec::new(),
+                var_type: None,
+                value: Some("{ \"Accept\": \"application/json\" }".to_string()),
+                is_mutable: false,
+                line: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        };
+
+        let formatter = XmlFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains(
+            "<variable name=\"DEFAULT_HEADERS\" visibility=\"public\" kind=\"const\" line=\"3\" value=\"{ &quot;Accept&quot;: &quot;application/json&quot; }\" />"
+        ));
+    }
+
+    #[test]
+    fn test_xml_macro() {
+        let file = File {
+            path: "macros.rs".to_string(),
+            children: vec![Node::Macro(Macro {
+                name: "hashmap".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                decorators: vec!["macro_export".to_string()],
+                macro_kind: distiller_core::ir::MacroKind::Rules,
+                parameters: vec!["()".to_string(), "($($k:expr => $v:expr),+)".to_string()],
+                value: None,
+                line_start: 2,
+                line_end: 5,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        };
+
+        let formatter = XmlFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("<decorator value=\"macro_export\" />"));
+        assert!(result.contains(
+            "<macro name=\"hashmap\" visibility=\"public\" kind=\"rules\" line-start=\"2\" line-end=\"5\">"
+        ));
+        assert!(result.contains("<parameter value=\"($($k:expr =&gt; $v:expr),+)\" />"));
+    }
+
+    #[test]
+    fn test_xml_module() {
+        let file = File {
+            path: "lib.rs".to_string(),
+            children: vec![Node::Module(Module {
+                name: "parser".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                decorators: vec![],
+                children: vec![Node::Module(Module {
+                    name: "lexer".to_string(),
+                    visibility: Visibility::Internal,
+                    source_visibility: Some(SourceVisibility::Crate),
+                    decorators: vec![],
+                    children: vec![],
+                    line_start: 2,
+                    line_end: 4,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
+                })],
+                line_start: 1,
+                line_end: 5,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        };
+
+        let formatter = XmlFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains(
+            "<module name=\"parser\" visibility=\"public\" line-start=\"1\" line-end=\"5\">"
+        ));
+        assert!(result.contains(
+            "<module name=\"lexer\" visibility=\"internal\" access=\"pub(crate)\" line-start=\"2\" line-end=\"4\" />"
+        ));
+        assert!(result.contains("</module>"));
+    }
+
+    #[test]
+    fn test_xml_enum_variants() {
+        let file = File {
+            path: "Status.cs".to_string(),
+            children: vec![Node::Enum(Enum {
+                name: "Status".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                decorators: vec![],
+                type_params: vec![],
+                enum_type: Some(TypeRef::new("byte")),
+                children: vec![Node::EnumVariant(EnumVariant {
+                    name: "Active".to_string(),
+                    fields: vec![],
+                    value: Some("1".to_string()),
+                    arguments: vec![],
+                    children: vec![],
+                    line: 2,
+                    deprecated: None,
+                    metadata: BTreeMap::new(),
+                })],
+                line_start: 1,
+                line_end: 3,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        };
+
+        let formatter = XmlFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains("<variant name=\"Active\" line=\"2\" value=\"1\" />"));
+    }
+
+    #[test]
+    fn test_xml_property() {
+        let file = File {
+            path: "Shape.swift".to_string(),
+            children: vec![Node::Property(Property {
+                name: "area".to_string(),
+                visibility: Visibility::Public,
+                source_visibility: None,
+                modifiers: vec![],
+                property_type: Some(TypeRef::new("Double")),
+                accessors: vec![distiller_core::ir::Accessor::new(
+                    distiller_core::ir::AccessorKind::Get,
+                )],
+                is_computed: true,
+                default_value: None,
+                line_start: 3,
+                line_end: 5,
+                deprecated: None,
+                metadata: BTreeMap::new(),
+            })],
+        };
+
+        let formatter = XmlFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(result.contains(
+            "<property name=\"area\" visibility=\"public\" line-start=\"3\" line-end=\"5\" computed=\"true\">"
+        ));
+        assert!(result.contains("<accessor kind=\"get\" />"));
+    }
+
+    #[test]
+    fn test_xml_overload_set() {
+        let signature = |line: usize| Function {
+            name: "write".to_string(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            parameters: vec![],
+            return_type: None,
+            implementation: None,
+            line_start: line,
+            line_end: line,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        };
+        let file = File {
+            path: "Writer.java".to_string(),
+            children: vec![Node::OverloadSet(OverloadSet {
+                name: "write".to_string(),
+                signatures: vec![signature(2)],
+                count: 3,
+                line_start: 2,
+                line_end: 4,
+            })],
+        };
+
+        let formatter = XmlFormatter::new();
+        let result = formatter.format_file(&file).unwrap();
+
+        assert!(
+            result.contains(
+                "<overload-set name=\"write\" count=\"3\" line-start=\"2\" line-end=\"4\">"
+            )
+        );
+        assert_eq!(result.matches("<function name=\"write\"").count(), 1);
+        assert!(result.contains("</overload-set>"));
+    }
 }
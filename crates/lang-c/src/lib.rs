@@ -1,10 +1,20 @@
 use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
-    ir::{Class, Field, File, Function, Import, Modifier, Node, Parameter, TypeRef, Visibility},
-    parser::ParserPool,
+    ir::{
+        Class, Deprecation, EnumVariant, Field, File, Function, Import, Macro, MacroKind, Modifier,
+        Node, Parameter, TypeRef, Variable, VariableKind, Visibility,
+    },
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        syntax::syntax_error,
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -35,6 +45,29 @@ impl CProcessor {
         source[start..end].to_string()
     }
 
+    /// Parse the type written at `node`
+    fn type_ref(node: TSNode, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::C)
+    }
+
+    /// Detect `[[deprecated]]` / `__attribute__((deprecated))` and `@deprecated` doc tags
+    fn parse_deprecation(node: TSNode, source: &str) -> Option<Deprecation> {
+        let mut attributes = Vec::new();
+        let mut cursor = node.walk();
+
+        for child in node.children(&mut cursor) {
+            if matches!(
+                child.kind(),
+                "attribute_declaration" | "attribute_specifier"
+            ) {
+                attributes.push(Self::node_text(child, source));
+            }
+        }
+
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(&attributes, doc.as_deref())
+    }
+
     fn parse_struct(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
         let mut children = Vec::new();
@@ -65,6 +98,7 @@ impl CProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             extends: Vec::new(),
             implements: Vec::new(),
@@ -73,6 +107,8 @@ impl CProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -105,14 +141,18 @@ impl CProcessor {
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
 
-        // Check for static keyword (makes function internal/private)
+        // Check for storage classes (static makes function internal/private)
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
             if child.kind() == "storage_class_specifier" {
-                let text = Self::node_text(child, source);
-                if text == "static" {
-                    modifiers.push(Modifier::Static);
-                    visibility = Visibility::Internal;
+                match Self::node_text(child, source).as_str() {
+                    "static" => {
+                        modifiers.push(Modifier::Static);
+                        visibility = Visibility::Internal;
+                    }
+                    "inline" => modifiers.push(Modifier::Inline),
+                    "extern" => modifiers.push(Modifier::Extern),
+                    _ => {}
                 }
             }
         }
@@ -136,14 +176,26 @@ impl CProcessor {
                 "function_declarator" => {
                     found_declarator = true;
                     if !return_type_parts.is_empty() {
-                        return_type = Some(TypeRef::new(return_type_parts.join(" ")));
+                        return_type = Some(parse_type(&return_type_parts.join(" "), TypeSyntax::C));
                     }
                     name = self.parse_function_declarator(child, source, &mut parameters);
                 }
                 "pointer_declarator" => {
                     found_declarator = true;
                     if !return_type_parts.is_empty() {
-                        return_type = Some(TypeRef::new(return_type_parts.join(" ")));
+                        // One `*` per nested pointer declarator: `char **names(void)`
+                        let mut pointers = "*".to_string();
+                        let mut inner = child.child_by_field_name("declarator");
+                        while let Some(pointer) =
+                            inner.filter(|inner| inner.kind() == "pointer_declarator")
+                        {
+                            pointers.push('*');
+                            inner = pointer.child_by_field_name("declarator");
+                        }
+                        return_type = Some(parse_type(
+                            &format!("{}{pointers}", return_type_parts.join(" ")),
+                            TypeSyntax::C,
+                        ));
                     }
                     // Handle pointer return types
                     name = self.parse_pointer_declarator(child, source, &mut parameters);
@@ -159,6 +211,7 @@ impl CProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             parameters,
             return_type,
@@ -167,6 +220,8 @@ impl CProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -227,36 +282,166 @@ impl CProcessor {
         name
     }
 
+    /// Check if a declarator (possibly behind pointers) declares a function
+    fn declares_function(node: TSNode) -> bool {
+        match node.kind() {
+            "function_declarator" => true,
+            "pointer_declarator" => node
+                .child_by_field_name("declarator")
+                .is_some_and(Self::declares_function),
+            _ => false,
+        }
+    }
+
+    /// Parse a file-scope variable declaration, one variable per declarator
+    ///
+    /// `const` makes a constant, `static` a file-local static; `extern`
+    /// declarations keep the `Extern` modifier.
+    fn parse_global_variables(node: TSNode, source: &str) -> Vec<Variable> {
+        let mut type_parts = Vec::new();
+        let mut is_const = false;
+        let mut is_static = false;
+        let mut modifiers = Vec::new();
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "storage_class_specifier" => match Self::node_text(child, source).as_str() {
+                    "static" => is_static = true,
+                    "extern" => modifiers.push(Modifier::Extern),
+                    _ => {}
+                },
+                "type_qualifier" => {
+                    let qualifier = Self::node_text(child, source);
+                    match qualifier.as_str() {
+                        "const" => is_const = true,
+                        "volatile" => modifiers.push(Modifier::Volatile),
+                        _ => {}
+                    }
+                    type_parts.push(qualifier);
+                }
+                "primitive_type" | "type_identifier" | "sized_type_specifier" => {
+                    type_parts.push(Self::node_text(child, source));
+                }
+                "struct_specifier" | "union_specifier" | "enum_specifier"
+                    if child.child_by_field_name("body").is_none() =>
+                {
+                    type_parts.push(Self::node_text(child, source));
+                }
+                _ => {}
+            }
+        }
+
+        let var_kind = if is_const {
+            VariableKind::Const
+        } else if is_static {
+            VariableKind::Static
+        } else {
+            VariableKind::Var
+        };
+        let deprecated = Self::parse_deprecation(node, source);
+
+        let mut variables = Vec::new();
+        let mut cursor = node.walk();
+        for declarator in node.named_children(&mut cursor).filter(|child| {
+            matches!(
+                child.kind(),
+                "identifier" | "init_declarator" | "pointer_declarator" | "array_declarator"
+            )
+        }) {
+            let (target, value) = if declarator.kind() == "init_declarator" {
+                (
+                    declarator.child_by_field_name("declarator"),
+                    declarator.child_by_field_name("value"),
+                )
+            } else {
+                (Some(declarator), None)
+            };
+            let Some((name, suffix)) = target.and_then(|t| Self::declarator_name(t, source)) else {
+                continue;
+            };
+
+            let mut type_name = type_parts.join(" ");
+            type_name.push_str(&suffix);
+            variables.push(Variable {
+                name,
+                visibility: if is_static {
+                    Visibility::Internal
+                } else {
+                    Visibility::Public
+                },
+                source_visibility: None,
+                var_kind,
+                modifiers: modifiers.clone(),
+                var_type: (!type_name.is_empty()).then(|| parse_type(&type_name, TypeSyntax::C)),
+                value: value.and_then(|v| value_preview(&Self::node_text(v, source))),
+                is_mutable: !is_const,
+                line: declarator.start_position().row + 1,
+                deprecated: deprecated.clone(),
+                metadata: BTreeMap::new(),
+            });
+        }
+
+        variables
+    }
+
+    /// Resolve a variable declarator to its name and pointer/array type suffix
+    fn declarator_name(node: TSNode, source: &str) -> Option<(String, String)> {
+        match node.kind() {
+            "identifier" => Some((Self::node_text(node, source), String::new())),
+            "pointer_declarator" => {
+                let (name, suffix) =
+                    Self::declarator_name(node.child_by_field_name("declarator")?, source)?;
+                Some((name, format!("*{suffix}")))
+            }
+            "array_declarator" => {
+                let (name, suffix) =
+                    Self::declarator_name(node.child_by_field_name("declarator")?, source)?;
+                Some((name, format!("{suffix}[]")))
+            }
+            _ => None,
+        }
+    }
+
     fn parse_parameters(node: TSNode, source: &str) -> Vec<Parameter> {
         let mut parameters = Vec::new();
         let mut cursor = node.walk();
 
         for child in node.children(&mut cursor) {
             if child.kind() == "parameter_declaration" {
-                let mut param_type = TypeRef::new("unknown".to_string());
+                let mut type_parts = Vec::new();
+                let mut suffix = String::new();
                 let mut name = String::new();
 
                 let mut param_cursor = child.walk();
                 for param_child in child.children(&mut param_cursor) {
                     match param_child.kind() {
-                        "primitive_type" | "type_identifier" => {
-                            param_type = TypeRef::new(Self::node_text(param_child, source));
+                        "primitive_type"
+                        | "type_identifier"
+                        | "sized_type_specifier"
+                        | "type_qualifier" => {
+                            type_parts.push(Self::node_text(param_child, source));
                         }
                         "identifier" => {
                             name = Self::node_text(param_child, source);
                         }
-                        "pointer_declarator" => {
-                            // Handle pointer parameters
-                            let mut ptr_cursor = param_child.walk();
-                            for ptr_child in param_child.children(&mut ptr_cursor) {
-                                if ptr_child.kind() == "identifier" {
-                                    name = Self::node_text(ptr_child, source);
-                                }
+                        "pointer_declarator" | "array_declarator" => {
+                            if let Some((declared, declared_suffix)) =
+                                Self::declarator_name(param_child, source)
+                            {
+                                name = declared;
+                                suffix = declared_suffix;
                             }
                         }
+                        "abstract_pointer_declarator" => suffix.push('*'),
                         _ => {}
                     }
                 }
+                let param_type = if type_parts.is_empty() {
+                    TypeRef::new("unknown".to_string())
+                } else {
+                    parse_type(&format!("{}{suffix}", type_parts.join(" ")), TypeSyntax::C)
+                };
 
                 if name.is_empty() {
                     // C allows unnamed parameters in function declarations
@@ -299,7 +484,7 @@ impl CProcessor {
             match child.kind() {
                 "primitive_type" | "type_identifier" => {
                     if field_type.is_none() {
-                        field_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        field_type = Some(Self::type_ref(child, source));
                     }
                 }
                 "field_identifier" | "identifier" => {
@@ -325,10 +510,13 @@ impl CProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: None,
             field_type,
             default_value: None,
             modifiers,
             line,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -347,18 +535,27 @@ impl CProcessor {
             return None;
         }
 
+        // `typedef enum { ... } Name;` keeps its enumerators
+        let children = match node.child_by_field_name("type") {
+            Some(ty) if ty.kind() == "enum_specifier" => Self::parse_enumerators(ty, source),
+            _ => Vec::new(),
+        };
+
         // Represent typedef as a class with special decorator
         Some(Node::Class(Class {
             name,
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers: Vec::new(),
             extends: Vec::new(),
             implements: Vec::new(),
             type_params: Vec::new(),
             decorators: vec!["typedef".to_string()],
-            children: Vec::new(),
+            children,
             line_start: node.start_position().row + 1,
             line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -379,14 +576,48 @@ impl CProcessor {
         Some(Class {
             name,
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers: Vec::new(),
             extends: Vec::new(),
             implements: Vec::new(),
             type_params: Vec::new(),
             decorators: vec!["enum".to_string()],
-            children: Vec::new(),
+            children: Self::parse_enumerators(node, source),
             line_start: node.start_position().row + 1,
             line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Parse the enumerators of an `enum_specifier`'s body
+    fn parse_enumerators(node: TSNode, source: &str) -> Vec<Node> {
+        let Some(body) = node.child_by_field_name("body") else {
+            return Vec::new();
+        };
+
+        let mut cursor = body.walk();
+        body.children(&mut cursor)
+            .filter(|child| child.kind() == "enumerator")
+            .filter_map(|enumerator| Self::parse_enumerator(enumerator, source))
+            .map(Node::EnumVariant)
+            .collect()
+    }
+
+    /// Parse an enumerator with its explicit value, e.g. `RED = 1`
+    fn parse_enumerator(node: TSNode, source: &str) -> Option<EnumVariant> {
+        let name = node.child_by_field_name("name")?;
+        Some(EnumVariant {
+            name: Self::node_text(name, source),
+            fields: Vec::new(),
+            value: node
+                .child_by_field_name("value")
+                .and_then(|value| value_preview(&Self::node_text(value, source))),
+            arguments: Vec::new(),
+            children: Vec::new(),
+            line: node.start_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         })
     }
 
@@ -407,6 +638,7 @@ impl CProcessor {
         Some(Class {
             name,
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers: Vec::new(),
             extends: Vec::new(),
             implements: Vec::new(),
@@ -415,6 +647,55 @@ impl CProcessor {
             children: Vec::new(),
             line_start: node.start_position().row + 1,
             line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Parse a `#define` into an object-like or function-like macro
+    ///
+    /// Include guards (a bare define of the name the enclosing `#ifndef`
+    /// tests) are skipped.
+    fn parse_define(node: TSNode, source: &str) -> Option<Macro> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+        let value = node
+            .child_by_field_name("value")
+            .and_then(|value| value_preview(&Self::node_text(value, source).replace("\\\n", " ")));
+
+        let is_guard = value.is_none()
+            && node
+                .parent()
+                .and_then(|parent| parent.child_by_field_name("name"))
+                .is_some_and(|guard| Self::node_text(guard, source) == name);
+        if is_guard {
+            return None;
+        }
+
+        let (macro_kind, parameters) = match node.child_by_field_name("parameters") {
+            Some(params) => {
+                let mut cursor = params.walk();
+                let names = params
+                    .children(&mut cursor)
+                    .filter(|param| matches!(param.kind(), "identifier" | "..."))
+                    .map(|param| Self::node_text(param, source))
+                    .collect();
+                (MacroKind::FunctionLike, names)
+            }
+            None => (MacroKind::Object, Vec::new()),
+        };
+
+        Some(Macro {
+            name,
+            visibility: Visibility::Public,
+            source_visibility: None,
+            decorators: Vec::new(),
+            macro_kind,
+            parameters,
+            value,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
         })
     }
 
@@ -431,16 +712,21 @@ impl CProcessor {
                 }
             }
             "declaration" => {
-                // Function declarations (prototypes)
+                // Function declarations (prototypes) or global variables
                 let mut cursor = node.walk();
-                for child in node.children(&mut cursor) {
-                    if child.kind() == "function_declarator" || child.kind() == "pointer_declarator"
-                    {
-                        if let Some(func) = self.parse_function(node, source)? {
-                            file.children.push(Node::Function(func));
-                        }
-                        break;
+                let is_prototype = node
+                    .children(&mut cursor)
+                    .any(|child| Self::declares_function(child));
+                if is_prototype {
+                    if let Some(func) = self.parse_function(node, source)? {
+                        file.children.push(Node::Function(func));
                     }
+                } else {
+                    file.children.extend(
+                        Self::parse_global_variables(node, source)
+                            .into_iter()
+                            .map(Node::Variable),
+                    );
                 }
             }
             "type_definition" => {
@@ -463,6 +749,11 @@ impl CProcessor {
                     file.children.push(Node::Import(import));
                 }
             }
+            "preproc_def" | "preproc_function_def" => {
+                if let Some(macro_def) = Self::parse_define(node, source) {
+                    file.children.push(Node::Macro(macro_def));
+                }
+            }
             _ => {
                 let mut cursor = node.walk();
                 for child in node.children(&mut cursor) {
@@ -536,7 +827,7 @@ impl LanguageProcessor for CProcessor {
 
         let tree = parser
             .parse(source, None)
-            .ok_or_else(|| DistilError::parse_error("c", "Failed to parse source"))?;
+            .ok_or_else(|| DistilError::parse_error(path, "Failed to parse source"))?;
 
         let mut file = File {
             path: path.to_string_lossy().to_string(),
@@ -545,6 +836,13 @@ impl LanguageProcessor for CProcessor {
 
         self.process_node(tree.root_node(), source, &mut file)?;
 
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
+
         Ok(file)
     }
 }
@@ -552,6 +850,7 @@ impl LanguageProcessor for CProcessor {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use distiller_core::ir::TypeShape;
     use std::path::PathBuf;
 
     #[test]
@@ -595,6 +894,41 @@ int add(int a, int b) {
         }
     }
 
+    #[test]
+    fn test_pointer_types() {
+        let source = r#"
+char *copy(const char *text, size_t len) {
+    return 0;
+}
+"#;
+        let processor = CProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("test.c"), &opts)
+            .unwrap();
+
+        let Node::Function(func) = &file.children[0] else {
+            panic!("Expected function node");
+        };
+        let return_type = func.return_type.as_ref().unwrap();
+        assert_eq!(return_type.name, "char*");
+        assert!(matches!(
+            &return_type.shape,
+            Some(TypeShape::Pointer { target, is_mutable: true }) if target.name == "char"
+        ));
+
+        assert_eq!(func.parameters[0].name, "text");
+        assert_eq!(func.parameters[0].param_type.name, "const char*");
+        assert!(matches!(
+            func.parameters[0].param_type.shape,
+            Some(TypeShape::Pointer {
+                is_mutable: false,
+                ..
+            })
+        ));
+        assert_eq!(func.parameters[1].param_type.name, "size_t");
+    }
+
     #[test]
     fn test_static_function() {
         let source = r#"
@@ -807,6 +1141,46 @@ int calculate(int x, int y);
         }
     }
 
+    #[test]
+    fn test_global_variables() {
+        let source = r#"
+static int counter = 0;
+const char *greeting = "hello";
+extern int errno_value;
+int buffer[64], *cursor;
+"#;
+        let processor = CProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("globals.c"), &opts)
+            .unwrap();
+
+        let vars: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        let names: Vec<_> = vars.iter().map(|v| v.name.as_str()).collect();
+        assert_eq!(
+            names,
+            ["counter", "greeting", "errno_value", "buffer", "cursor"]
+        );
+
+        assert_eq!(vars[0].var_kind, VariableKind::Static);
+        assert_eq!(vars[0].visibility, Visibility::Internal);
+        assert_eq!(vars[0].value.as_deref(), Some("0"));
+
+        assert_eq!(vars[1].var_kind, VariableKind::Const);
+        assert_eq!(vars[1].var_type.as_ref().unwrap().name, "const char*");
+
+        assert!(vars[2].modifiers.contains(&Modifier::Extern));
+        assert_eq!(vars[3].var_type.as_ref().unwrap().name, "int[]");
+        assert_eq!(vars[4].var_type.as_ref().unwrap().name, "int*");
+    }
+
     #[test]
     fn test_typedef() {
         let source = r#"
@@ -830,7 +1204,7 @@ typedef struct Point Point;
         let source = r#"
 enum Color {
     RED,
-    GREEN,
+    GREEN = 4,
     BLUE
 };
 "#;
@@ -844,11 +1218,47 @@ enum Color {
         if let Node::Class(enum_node) = &file.children[0] {
             assert_eq!(enum_node.name, "Color");
             assert!(enum_node.decorators.contains(&"enum".to_string()));
+
+            let enumerators: Vec<_> = enum_node
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    Node::EnumVariant(v) => Some((v.name.as_str(), v.value.as_deref())),
+                    _ => None,
+                })
+                .collect();
+            assert_eq!(
+                enumerators,
+                [("RED", None), ("GREEN", Some("4")), ("BLUE", None)]
+            );
         } else {
             panic!("Expected enum node");
         }
     }
 
+    #[test]
+    fn test_typedef_enum() {
+        let source = r#"
+typedef enum {
+    LOG_ERROR = 1 << 0,
+    LOG_WARN = 1 << 1
+} LogLevel;
+"#;
+        let processor = CProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("log.h"), &opts)
+            .unwrap();
+
+        let Node::Class(typedef) = &file.children[0] else {
+            panic!("Expected typedef");
+        };
+        assert_eq!(typedef.name, "LogLevel");
+        assert!(
+            matches!(&typedef.children[1], Node::EnumVariant(v) if v.name == "LOG_WARN" && v.value.as_deref() == Some("1 << 1"))
+        );
+    }
+
     #[test]
     fn test_struct_with_pointers() {
         let source = r#"
@@ -1080,6 +1490,49 @@ int get_status(void);
         assert_eq!(funcs[1].name, "get_status");
     }
 
+    #[test]
+    fn test_preprocessor_macros() {
+        let source = r#"
+#ifndef CONFIG_H
+#define CONFIG_H
+
+#define MAX_USERS 128
+#define MIN(a, b) \
+    ((a) < (b) ? (a) : (b))
+#define LOG(fmt, ...) printf(fmt, __VA_ARGS__)
+
+#endif
+"#;
+        let processor = CProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("config.h"), &opts)
+            .unwrap();
+
+        let macros: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| {
+                if let Node::Macro(m) = n {
+                    Some(m)
+                } else {
+                    None
+                }
+            })
+            .collect();
+
+        // The include guard is not a macro anyone calls
+        assert_eq!(macros.len(), 3);
+        assert_eq!(macros[0].name, "MAX_USERS");
+        assert_eq!(macros[0].macro_kind, MacroKind::Object);
+        assert_eq!(macros[0].value.as_deref(), Some("128"));
+        assert_eq!(macros[1].name, "MIN");
+        assert_eq!(macros[1].macro_kind, MacroKind::FunctionLike);
+        assert_eq!(macros[1].parameters, ["a", "b"]);
+        assert_eq!(macros[1].value.as_deref(), Some("((a) < (b) ? (a) : (b))"));
+        assert_eq!(macros[2].parameters, ["fmt", "..."]);
+    }
+
     #[test]
     fn test_mixed_includes() {
         let source = r#"
@@ -1166,4 +1619,55 @@ unsigned long get_timestamp(void);
         assert_eq!(funcs[0].name, "hash_string");
         assert_eq!(funcs[1].name, "get_timestamp");
     }
+
+    #[test]
+    fn test_deprecated_attribute() {
+        let source = r#"
+/** @deprecated Use open_v2() instead. */
+int open_v1(const char *path);
+
+__attribute__((deprecated("use close_v2"))) int close_v1(int fd);
+"#;
+        let processor = CProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("io.h"), &opts)
+            .unwrap();
+
+        let functions: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+
+        assert_eq!(functions.len(), 2);
+        let first = functions[0]
+            .deprecated
+            .as_ref()
+            .expect("open_v1 is deprecated");
+        assert_eq!(first.replacement.as_deref(), Some("open_v2()"));
+        let second = functions[1]
+            .deprecated
+            .as_ref()
+            .expect("close_v1 is deprecated");
+        assert_eq!(second.message.as_deref(), Some("use close_v2"));
+    }
+
+    #[test]
+    fn test_unrecoverable_source_reports_location() {
+        let processor = CProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let err = processor
+            .process("}}}\n", &PathBuf::from("broken.c"), &opts)
+            .unwrap_err();
+
+        let DistilError::Parse { path, span, .. } = err else {
+            panic!("expected a parse error, got {err:?}");
+        };
+        assert_eq!(path, PathBuf::from("broken.c"));
+        assert_eq!(span.expect("span of the ERROR node").line, 1);
+    }
 }
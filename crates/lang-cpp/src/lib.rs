@@ -2,11 +2,20 @@ use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
     ir::{
-        Class, Field, File, Function, Import, Modifier, Node, Parameter, TypeParam, TypeRef,
-        Visibility,
+        Class, Deprecation, Enum, EnumVariant, Field, File, Function, Import, Macro, MacroKind,
+        Modifier, Module, Node, Parameter, TypeParam, TypeRef, Variable, VariableKind, Visibility,
+    },
+    parser::{
+        comments::preceding_comment,
+        overloads::group_overloads,
+        syntax::syntax_error,
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
     },
     processor::LanguageProcessor,
+    type_merge::RECEIVER,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -37,6 +46,29 @@ impl CppProcessor {
         source[start..end].to_string()
     }
 
+    /// Parse the type written at `node`
+    fn type_ref(node: TSNode, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::C)
+    }
+
+    /// Detect `[[deprecated]]` / `__attribute__((deprecated))` and `@deprecated` doc tags
+    fn parse_deprecation(node: TSNode, source: &str) -> Option<Deprecation> {
+        let mut attributes = Vec::new();
+        let mut cursor = node.walk();
+
+        for child in node.children(&mut cursor) {
+            if matches!(
+                child.kind(),
+                "attribute_declaration" | "attribute_specifier"
+            ) {
+                attributes.push(Self::node_text(child, source));
+            }
+        }
+
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(&attributes, doc.as_deref())
+    }
+
     fn parse_class(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
         let mut extends = Vec::new();
@@ -82,6 +114,7 @@ impl CppProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             extends,
             implements,
@@ -90,9 +123,66 @@ impl CppProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
+    /// Parse an enum definition; scoped enums (`enum class`) are marked in
+    /// the metadata and the underlying type goes in `enum_type`
+    fn parse_enum(node: TSNode, source: &str) -> Option<Enum> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+        let body = node.child_by_field_name("body")?;
+
+        let mut variants = Vec::new();
+        let mut cursor = body.walk();
+        for enumerator in body.children(&mut cursor) {
+            if enumerator.kind() != "enumerator" {
+                continue;
+            }
+            let Some(variant_name) = enumerator.child_by_field_name("name") else {
+                continue;
+            };
+            variants.push(Node::EnumVariant(EnumVariant {
+                name: Self::node_text(variant_name, source),
+                fields: Vec::new(),
+                value: enumerator
+                    .child_by_field_name("value")
+                    .and_then(|value| value_preview(&Self::node_text(value, source))),
+                arguments: Vec::new(),
+                children: Vec::new(),
+                line: enumerator.start_position().row + 1,
+                deprecated: Self::parse_deprecation(enumerator, source),
+                metadata: BTreeMap::new(),
+            }));
+        }
+
+        let mut metadata = BTreeMap::new();
+        let mut cursor = node.walk();
+        if node
+            .children(&mut cursor)
+            .any(|child| matches!(child.kind(), "class" | "struct"))
+        {
+            metadata.insert("scoped".to_string(), String::new());
+        }
+
+        Some(Enum {
+            name,
+            visibility: Visibility::Public,
+            source_visibility: None,
+            decorators: Vec::new(),
+            type_params: Vec::new(),
+            enum_type: node
+                .child_by_field_name("base")
+                .map(|base| Self::type_ref(base, source)),
+            children: variants,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata,
+        })
+    }
+
     fn parse_template_parameters(node: TSNode, source: &str) -> Vec<TypeParam> {
         let mut params = Vec::new();
         let mut cursor = node.walk();
@@ -130,7 +220,7 @@ impl CppProcessor {
 
         for child in node.children(&mut cursor) {
             if child.kind() == "type_identifier" {
-                bases.push(TypeRef::new(Self::node_text(child, source)));
+                bases.push(Self::type_ref(child, source));
             }
         }
 
@@ -153,7 +243,15 @@ impl CppProcessor {
                     }
                 }
                 "field_declaration" => {
-                    if let Some(mut field) = Self::parse_field(child, source)? {
+                    // Nested `enum E { ... };` is a field declaration without a declarator
+                    if let Some(mut enum_decl) = child
+                        .child_by_field_name("type")
+                        .filter(|ty| ty.kind() == "enum_specifier")
+                        .and_then(|ty| Self::parse_enum(ty, source))
+                    {
+                        enum_decl.visibility = current_visibility;
+                        children.push(Node::Enum(enum_decl));
+                    } else if let Some(mut field) = Self::parse_field(child, source)? {
                         field.visibility = current_visibility;
                         children.push(Node::Field(field));
                     }
@@ -192,6 +290,7 @@ impl CppProcessor {
         let decorators = Vec::new();
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
+        let mut receiver = None;
 
         // Parse return type - collect ALL type nodes before declarator for multi-word types
         let mut return_type_parts = Vec::new();
@@ -200,6 +299,20 @@ impl CppProcessor {
 
         for child in node.children(&mut cursor) {
             match child.kind() {
+                "type_qualifier"
+                    if matches!(
+                        Self::node_text(child, source).as_str(),
+                        "constexpr" | "consteval"
+                    ) =>
+                {
+                    modifiers.push(Modifier::Constexpr);
+                }
+                "storage_class_specifier" => match Self::node_text(child, source).as_str() {
+                    "static" => modifiers.push(Modifier::Static),
+                    "inline" => modifiers.push(Modifier::Inline),
+                    "extern" => modifiers.push(Modifier::Extern),
+                    _ => {}
+                },
                 "primitive_type"
                 | "type_identifier"
                 | "qualified_identifier"
@@ -215,13 +328,14 @@ impl CppProcessor {
                 "function_declarator" => {
                     found_declarator = true;
                     if !return_type_parts.is_empty() {
-                        return_type = Some(TypeRef::new(return_type_parts.join(" ")));
+                        return_type = Some(parse_type(&return_type_parts.join(" "), TypeSyntax::C));
                     }
                     name = self.parse_function_declarator(
                         child,
                         source,
                         &mut parameters,
                         &mut modifiers,
+                        &mut receiver,
                     );
                 }
                 "type_qualifier" if Self::node_text(child, source) == "virtual" => {
@@ -238,6 +352,7 @@ impl CppProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             parameters,
             return_type,
@@ -246,6 +361,10 @@ impl CppProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: receiver
+                .map(|receiver| BTreeMap::from([(RECEIVER.to_string(), receiver)]))
+                .unwrap_or_default(),
         }))
     }
 
@@ -256,6 +375,7 @@ impl CppProcessor {
         source: &str,
         parameters: &mut Vec<Parameter>,
         modifiers: &mut Vec<Modifier>,
+        receiver: &mut Option<String>,
     ) -> String {
         let mut name = String::new();
         let mut cursor = node.walk();
@@ -270,6 +390,13 @@ impl CppProcessor {
                 "destructor_name" => {
                     name = Self::node_text(child, source);
                 }
+                // Out-of-line member definition: `void Widget::draw()`
+                "qualified_identifier" => {
+                    if let Some((scope, member)) = Self::split_qualified(child, source) {
+                        name = member;
+                        *receiver = Some(scope);
+                    }
+                }
                 "parameter_list" => {
                     *parameters = Self::parse_parameters(child, source);
                 }
@@ -279,6 +406,7 @@ impl CppProcessor {
                         modifiers.push(Modifier::Const);
                     }
                 }
+                "noexcept" => modifiers.push(Modifier::Noexcept),
                 "virtual_specifier" => {
                     let text = Self::node_text(child, source);
                     if text == "override" {
@@ -294,6 +422,20 @@ impl CppProcessor {
         name
     }
 
+    /// Split `ns::Stack<T>::push` into the innermost scope (`Stack`) and member (`push`)
+    fn split_qualified(node: TSNode, source: &str) -> Option<(String, String)> {
+        let mut scope = None;
+        let mut current = node;
+        while current.kind() == "qualified_identifier" {
+            scope = current.child_by_field_name("scope");
+            current = current.child_by_field_name("name")?;
+        }
+
+        let scope = Self::node_text(scope?, source);
+        let scope = scope.split('<').next().unwrap_or(&scope).trim().to_string();
+        Some((scope, Self::node_text(current, source)))
+    }
+
     fn parse_parameters(node: TSNode, source: &str) -> Vec<Parameter> {
         let mut parameters = Vec::new();
         let mut cursor = node.walk();
@@ -302,21 +444,42 @@ impl CppProcessor {
             if child.kind() == "parameter_declaration"
                 || child.kind() == "optional_parameter_declaration"
             {
-                let mut param_type = TypeRef::new("unknown".to_string());
+                let mut type_parts = Vec::new();
+                let mut suffix = String::new();
                 let mut name = String::new();
 
                 let mut param_cursor = child.walk();
                 for param_child in child.children(&mut param_cursor) {
                     match param_child.kind() {
-                        "primitive_type" | "type_identifier" => {
-                            param_type = TypeRef::new(Self::node_text(param_child, source));
+                        "primitive_type"
+                        | "type_identifier"
+                        | "qualified_identifier"
+                        | "template_type"
+                        | "sized_type_specifier"
+                        | "type_qualifier" => {
+                            type_parts.push(Self::node_text(param_child, source));
                         }
                         "identifier" => {
                             name = Self::node_text(param_child, source);
                         }
+                        "pointer_declarator" | "reference_declarator" | "array_declarator" => {
+                            if let Some((declared, declared_suffix)) =
+                                Self::declarator_name(param_child, source)
+                            {
+                                name = declared;
+                                suffix = declared_suffix;
+                            }
+                        }
+                        "abstract_pointer_declarator" => suffix.push('*'),
+                        "abstract_reference_declarator" => suffix.push('&'),
                         _ => {}
                     }
                 }
+                let param_type = if type_parts.is_empty() {
+                    TypeRef::new("unknown".to_string())
+                } else {
+                    parse_type(&format!("{}{suffix}", type_parts.join(" ")), TypeSyntax::C)
+                };
 
                 if name.is_empty() {
                     // C++ allows unnamed parameters
@@ -349,7 +512,7 @@ impl CppProcessor {
             match child.kind() {
                 "primitive_type" | "type_identifier" => {
                     if field_type.is_none() {
-                        field_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        field_type = Some(Self::type_ref(child, source));
                     }
                 }
                 "field_identifier" => {
@@ -366,28 +529,224 @@ impl CppProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: None,
             field_type,
             default_value: None,
             modifiers,
             line,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
-    fn parse_namespace(&self, node: TSNode, source: &str, file: &mut File) -> Result<()> {
+    /// Parse a namespace-scope variable declaration, one variable per declarator
+    ///
+    /// `constexpr` and `const` make constants, `static` a file-local static.
+    /// Function prototypes yield nothing.
+    fn parse_global_variables(node: TSNode, source: &str) -> Vec<Variable> {
+        let mut type_parts = Vec::new();
+        let mut is_const = false;
+        let mut is_static = false;
+        let mut modifiers = Vec::new();
+
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
-            if child.kind() == "declaration_list" {
-                self.process_node(child, source, file)?;
+            match child.kind() {
+                "storage_class_specifier" => match Self::node_text(child, source).as_str() {
+                    "static" => is_static = true,
+                    "extern" => modifiers.push(Modifier::Extern),
+                    "inline" => modifiers.push(Modifier::Inline),
+                    _ => {}
+                },
+                "type_qualifier" => {
+                    let qualifier = Self::node_text(child, source);
+                    match qualifier.as_str() {
+                        "constexpr" | "constinit" => {
+                            is_const = true;
+                            modifiers.push(Modifier::Constexpr);
+                            continue;
+                        }
+                        "const" => is_const = true,
+                        "volatile" => modifiers.push(Modifier::Volatile),
+                        _ => {}
+                    }
+                    type_parts.push(qualifier);
+                }
+                "primitive_type"
+                | "type_identifier"
+                | "qualified_identifier"
+                | "sized_type_specifier"
+                | "template_type"
+                | "placeholder_type_specifier" => {
+                    type_parts.push(Self::node_text(child, source));
+                }
+                "function_declarator" => return Vec::new(),
+                _ => {}
             }
         }
-        Ok(())
+
+        let var_kind = if is_const {
+            VariableKind::Const
+        } else if is_static {
+            VariableKind::Static
+        } else {
+            VariableKind::Var
+        };
+        let deprecated = Self::parse_deprecation(node, source);
+
+        let mut variables = Vec::new();
+        let mut cursor = node.walk();
+        for declarator in node.named_children(&mut cursor).filter(|child| {
+            matches!(
+                child.kind(),
+                "identifier"
+                    | "init_declarator"
+                    | "pointer_declarator"
+                    | "reference_declarator"
+                    | "array_declarator"
+            )
+        }) {
+            let (target, value) = if declarator.kind() == "init_declarator" {
+                (
+                    declarator.child_by_field_name("declarator"),
+                    declarator.child_by_field_name("value"),
+                )
+            } else {
+                (Some(declarator), None)
+            };
+            let Some((name, suffix)) = target.and_then(|t| Self::declarator_name(t, source)) else {
+                continue;
+            };
+
+            let mut type_name = type_parts.join(" ");
+            type_name.push_str(&suffix);
+            variables.push(Variable {
+                name,
+                visibility: if is_static {
+                    Visibility::Internal
+                } else {
+                    Visibility::Public
+                },
+                source_visibility: None,
+                var_kind,
+                modifiers: modifiers.clone(),
+                var_type: (!type_name.is_empty()).then(|| parse_type(&type_name, TypeSyntax::C)),
+                value: value.and_then(|v| value_preview(&Self::node_text(v, source))),
+                is_mutable: !is_const,
+                line: declarator.start_position().row + 1,
+                deprecated: deprecated.clone(),
+                metadata: BTreeMap::new(),
+            });
+        }
+
+        variables
+    }
+
+    /// Resolve a variable declarator to its name and pointer/reference/array suffix
+    ///
+    /// Returns `None` for function declarators.
+    fn declarator_name(node: TSNode, source: &str) -> Option<(String, String)> {
+        let suffix = match node.kind() {
+            "identifier" => return Some((Self::node_text(node, source), String::new())),
+            "pointer_declarator" => "*",
+            "reference_declarator" => "&",
+            "array_declarator" => "[]",
+            _ => return None,
+        };
+        // reference_declarator has no `declarator` field, its target is the last named child
+        let inner = node
+            .child_by_field_name("declarator")
+            .or_else(|| node.named_child(node.named_child_count().checked_sub(1)?))?;
+        let (name, inner_suffix) = Self::declarator_name(inner, source)?;
+        let suffix = if node.kind() == "array_declarator" {
+            format!("{inner_suffix}{suffix}")
+        } else {
+            format!("{suffix}{inner_suffix}")
+        };
+        Some((name, suffix))
+    }
+
+    /// Parse a `namespace` block into a module holding its declarations
+    ///
+    /// Anonymous namespaces give their members internal linkage.
+    fn parse_namespace(&self, node: TSNode, source: &str) -> Result<Module> {
+        let name = node
+            .child_by_field_name("name")
+            .map(|name| Self::node_text(name, source));
+        let mut children = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            self.process_node(body, source, &mut children)?;
+        }
+
+        Ok(Module {
+            visibility: if name.is_some() {
+                Visibility::Public
+            } else {
+                Visibility::Internal
+            },
+            name: name.unwrap_or_else(|| "(anonymous)".to_string()),
+            source_visibility: None,
+            decorators: Vec::new(),
+            children,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
     }
 
-    fn process_node(&self, node: TSNode, source: &str, file: &mut File) -> Result<()> {
+    /// Parse a `#define` into an object-like or function-like macro
+    ///
+    /// Include guards (a bare define of the name the enclosing `#ifndef`
+    /// tests) are skipped.
+    fn parse_define(node: TSNode, source: &str) -> Option<Macro> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+        let value = node
+            .child_by_field_name("value")
+            .and_then(|value| value_preview(&Self::node_text(value, source).replace("\\\n", " ")));
+
+        let is_guard = value.is_none()
+            && node
+                .parent()
+                .and_then(|parent| parent.child_by_field_name("name"))
+                .is_some_and(|guard| Self::node_text(guard, source) == name);
+        if is_guard {
+            return None;
+        }
+
+        let (macro_kind, parameters) = match node.child_by_field_name("parameters") {
+            Some(params) => {
+                let mut cursor = params.walk();
+                let names = params
+                    .children(&mut cursor)
+                    .filter(|param| matches!(param.kind(), "identifier" | "..."))
+                    .map(|param| Self::node_text(param, source))
+                    .collect();
+                (MacroKind::FunctionLike, names)
+            }
+            None => (MacroKind::Object, Vec::new()),
+        };
+
+        Some(Macro {
+            name,
+            visibility: Visibility::Public,
+            source_visibility: None,
+            decorators: Vec::new(),
+            macro_kind,
+            parameters,
+            value,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    fn process_node(&self, node: TSNode, source: &str, children: &mut Vec<Node>) -> Result<()> {
         match node.kind() {
             "class_specifier" | "struct_specifier" => {
                 if let Some(class) = self.parse_class(node, source)? {
-                    file.children.push(Node::Class(class));
+                    children.push(Node::Class(class));
                 }
             }
             "template_declaration" => {
@@ -396,33 +755,56 @@ impl CppProcessor {
                 for child in node.children(&mut cursor) {
                     if child.kind() == "class_specifier" || child.kind() == "struct_specifier" {
                         if let Some(class) = self.parse_class(child, source)? {
-                            file.children.push(Node::Class(class));
+                            children.push(Node::Class(class));
                         }
                     } else if child.kind() == "function_definition"
                         && let Some(func) = self.parse_function(child, source)?
                     {
-                        file.children.push(Node::Function(func));
+                        children.push(Node::Function(func));
                     }
                 }
             }
             "function_definition" => {
                 if let Some(func) = self.parse_function(node, source)? {
-                    file.children.push(Node::Function(func));
+                    children.push(Node::Function(func));
                 }
             }
             "namespace_definition" => {
-                self.parse_namespace(node, source, file)?;
+                children.push(Node::Module(self.parse_namespace(node, source)?));
+            }
+            "enum_specifier" => {
+                // Only definitions; `enum Color c;` just names the type
+                if let Some(enum_decl) = Self::parse_enum(node, source) {
+                    children.push(Node::Enum(enum_decl));
+                }
+            }
+            "declaration" => {
+                children.extend(
+                    Self::parse_global_variables(node, source)
+                        .into_iter()
+                        .map(Node::Variable),
+                );
+                // `struct S { ... } s;` still declares the struct
+                let mut cursor = node.walk();
+                for child in node.children(&mut cursor) {
+                    self.process_node(child, source, children)?;
+                }
             }
             "preproc_include" => {
                 // Parse includes as imports using AST traversal
                 if let Some(import) = self.parse_include_node(node, source) {
-                    file.children.push(Node::Import(import));
+                    children.push(Node::Import(import));
+                }
+            }
+            "preproc_def" | "preproc_function_def" => {
+                if let Some(macro_def) = Self::parse_define(node, source) {
+                    children.push(Node::Macro(macro_def));
                 }
             }
             _ => {
                 let mut cursor = node.walk();
                 for child in node.children(&mut cursor) {
-                    self.process_node(child, source, file)?;
+                    self.process_node(child, source, children)?;
                 }
             }
         }
@@ -492,14 +874,24 @@ impl LanguageProcessor for CppProcessor {
 
         let tree = parser
             .parse(source, None)
-            .ok_or_else(|| DistilError::parse_error("cpp", "Failed to parse source"))?;
+            .ok_or_else(|| DistilError::parse_error(path, "Failed to parse source"))?;
 
         let mut file = File {
             path: path.to_string_lossy().to_string(),
             children: Vec::new(),
         };
 
-        self.process_node(tree.root_node(), source, &mut file)?;
+        self.process_node(tree.root_node(), source, &mut file.children)?;
+
+        // Same-named functions in one scope are overloads
+        group_overloads(&mut file.children);
+
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
 
         Ok(file)
     }
@@ -508,6 +900,7 @@ impl LanguageProcessor for CppProcessor {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use distiller_core::type_merge::{DECLARED_IN, merge_file};
     use std::path::PathBuf;
 
     #[test]
@@ -627,8 +1020,12 @@ namespace MathUtils {
             .process(source, &PathBuf::from("MathUtils.cpp"), &opts)
             .unwrap();
 
-        assert!(!file.children.is_empty());
-        let has_function = file.children.iter().any(|child| {
+        assert_eq!(file.children.len(), 1);
+        let Node::Module(module) = &file.children[0] else {
+            panic!("Expected namespace module");
+        };
+        assert_eq!(module.name, "MathUtils");
+        let has_function = module.children.iter().any(|child| {
             if let Node::Function(func) = child {
                 func.name == "max"
             } else {
@@ -935,12 +1332,10 @@ private:
             let constructors: Vec<_> = class
                 .children
                 .iter()
-                .filter_map(|n| {
-                    if let Node::Function(f) = n {
-                        Some(f)
-                    } else {
-                        None
-                    }
+                .flat_map(|n| match n {
+                    Node::Function(f) => vec![f],
+                    Node::OverloadSet(o) => o.signatures.iter().collect(),
+                    _ => vec![],
                 })
                 .filter(|f| f.name == "Resource" || f.name.starts_with('~'))
                 .collect();
@@ -954,6 +1349,94 @@ private:
         }
     }
 
+    #[test]
+    fn test_preprocessor_macros() {
+        let source = r#"
+#pragma once
+#define VERSION "2.1"
+#define SQUARE(x) ((x) * (x))
+
+namespace util {
+#define UTIL_ASSERT(cond) assert(cond)
+}
+"#;
+        let processor = CppProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("util.hpp"), &opts)
+            .unwrap();
+
+        assert!(matches!(
+            &file.children[0],
+            Node::Macro(m) if m.name == "VERSION"
+                && m.macro_kind == MacroKind::Object
+                && m.value.as_deref() == Some("\"2.1\"")
+        ));
+        assert!(matches!(
+            &file.children[1],
+            Node::Macro(m) if m.name == "SQUARE" && m.parameters == ["x"]
+        ));
+        let Node::Module(util) = &file.children[2] else {
+            panic!("Expected util namespace");
+        };
+        assert!(matches!(
+            &util.children[0],
+            Node::Macro(m) if m.name == "UTIL_ASSERT" && m.macro_kind == MacroKind::FunctionLike
+        ));
+    }
+
+    #[test]
+    fn test_out_of_line_definitions() {
+        let source = r#"
+class Widget {
+public:
+    int id() const { return 1; }
+};
+
+void Widget::draw(int x) {}
+void Widget::draw(double x) {}
+Widget::~Widget() {}
+size_t Gadget::size() const { return 0; }
+"#;
+        let processor = CppProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let mut file = processor
+            .process(source, Path::new("widget.cpp"), &opts)
+            .unwrap();
+
+        // `draw` overloads group per receiver; `~Widget` and `size` stay apart
+        assert_eq!(file.children.len(), 4);
+        let Node::OverloadSet(draw) = &file.children[1] else {
+            panic!("Expected draw overloads");
+        };
+        assert_eq!(draw.count, 2);
+        assert_eq!(
+            draw.signatures[0]
+                .metadata
+                .get(RECEIVER)
+                .map(String::as_str),
+            Some("Widget")
+        );
+
+        merge_file(&mut file);
+        assert_eq!(file.children.len(), 2);
+        let Node::Class(widget) = &file.children[0] else {
+            panic!("Expected Widget class");
+        };
+        assert_eq!(widget.children.len(), 3);
+        assert!(matches!(
+            &widget.children[2],
+            Node::Function(f) if f.name == "~Widget"
+                && f.metadata.get(DECLARED_IN).map(String::as_str) == Some("widget.cpp:9")
+        ));
+        // No Gadget in this file: the definition keeps its receiver
+        assert!(matches!(
+            &file.children[1],
+            Node::Function(f) if f.name == "size"
+                && f.metadata.get(RECEIVER).map(String::as_str) == Some("Gadget")
+        ));
+    }
+
     #[test]
     fn test_multiple_inheritance() {
         let source = r#"
@@ -1152,27 +1635,207 @@ private:
         if let Node::Class(class) = &file.children[0] {
             assert_eq!(class.name, "String");
 
-            // Count constructors
+            // Constructors are grouped into one overload set
             let constructors: Vec<_> = class
                 .children
                 .iter()
-                .filter_map(|n| {
-                    if let Node::Function(f) = n {
-                        Some(f)
-                    } else {
-                        None
-                    }
+                .filter_map(|n| match n {
+                    Node::OverloadSet(o) if o.name == "String" => Some(o),
+                    _ => None,
                 })
-                .filter(|f| f.name == "String")
                 .collect();
 
+            assert_eq!(constructors.len(), 1, "Expected one overload set");
             assert!(
-                !constructors.is_empty(),
+                constructors[0].count > 1,
                 "Expected multiple constructors, found {}",
-                constructors.len()
+                constructors[0].count
             );
         } else {
             panic!("Expected String class");
         }
     }
+
+    #[test]
+    fn test_deprecated_attribute() {
+        let source = r#"
+class [[deprecated("use Widget2")]] Widget {
+public:
+    void draw();
+};
+"#;
+        let processor = CppProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("widget.hpp"), &opts)
+            .unwrap();
+
+        let Node::Class(class) = &file.children[0] else {
+            panic!("Expected class node");
+        };
+        let deprecated = class.deprecated.as_ref().expect("Widget is deprecated");
+        assert_eq!(deprecated.message.as_deref(), Some("use Widget2"));
+        assert_eq!(deprecated.replacement.as_deref(), Some("Widget2"));
+    }
+
+    #[test]
+    fn test_global_variables() {
+        let source = r#"
+constexpr int kMaxUsers = 100;
+static std::string name = "server";
+namespace config {
+    double ratio = 0.5;
+}
+void shutdown();
+"#;
+        let processor = CppProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("globals.cpp"), &opts)
+            .unwrap();
+
+        let vars: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(vars.len(), 2);
+
+        assert_eq!(vars[0].name, "kMaxUsers");
+        assert_eq!(vars[0].var_kind, VariableKind::Const);
+        assert!(vars[0].modifiers.contains(&Modifier::Constexpr));
+        assert_eq!(vars[0].value.as_deref(), Some("100"));
+
+        assert_eq!(vars[1].name, "name");
+        assert_eq!(vars[1].var_kind, VariableKind::Static);
+        assert_eq!(vars[1].var_type.as_ref().unwrap().name, "std::string");
+
+        let Some(Node::Module(config)) =
+            file.children.iter().find(|n| matches!(n, Node::Module(_)))
+        else {
+            panic!("Expected config namespace");
+        };
+        assert!(matches!(
+            &config.children[..],
+            [Node::Variable(v)] if v.name == "ratio" && v.var_kind == VariableKind::Var
+        ));
+    }
+
+    #[test]
+    fn test_scoped_enum() {
+        let source = r#"
+enum class Color : uint8_t {
+    Red = 1,
+    Green,
+};
+
+class Widget {
+public:
+    enum State { Idle, Busy };
+};
+"#;
+        let processor = CppProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("color.hpp"), &opts)
+            .unwrap();
+
+        let Node::Enum(color) = &file.children[0] else {
+            panic!("Expected enum, got {:?}", file.children[0]);
+        };
+        assert_eq!(color.name, "Color");
+        assert_eq!(color.enum_type.as_ref().unwrap().name, "uint8_t");
+        assert!(color.metadata.contains_key("scoped"));
+        assert!(
+            matches!(&color.children[0], Node::EnumVariant(v) if v.name == "Red" && v.value.as_deref() == Some("1"))
+        );
+        assert!(
+            matches!(&color.children[1], Node::EnumVariant(v) if v.name == "Green" && v.value.is_none())
+        );
+
+        let Node::Class(widget) = &file.children[1] else {
+            panic!("Expected class, got {:?}", file.children[1]);
+        };
+        let Node::Enum(state) = &widget.children[0] else {
+            panic!("Expected nested enum");
+        };
+        assert_eq!(state.name, "State");
+        assert!(!state.metadata.contains_key("scoped"));
+        assert_eq!(state.children.len(), 2);
+    }
+
+    #[test]
+    fn test_nested_namespaces() {
+        let source = r#"
+namespace net {
+    namespace http {
+        class Client {};
+    }
+}
+namespace {
+    int helper() { return 0; }
+}
+"#;
+        let processor = CppProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("net.cpp"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 2);
+        let Node::Module(net) = &file.children[0] else {
+            panic!("Expected net namespace");
+        };
+        assert_eq!(net.name, "net");
+        let Node::Module(http) = &net.children[0] else {
+            panic!("Expected nested http namespace");
+        };
+        assert_eq!(http.name, "http");
+        assert!(matches!(&http.children[0], Node::Class(c) if c.name == "Client"));
+
+        let Node::Module(anonymous) = &file.children[1] else {
+            panic!("Expected anonymous namespace");
+        };
+        assert_eq!(anonymous.visibility, Visibility::Internal);
+    }
+
+    #[test]
+    fn test_constexpr_and_noexcept() {
+        let source = r#"
+class Buffer {
+public:
+    constexpr int capacity() const { return 64; }
+
+    void swap(Buffer& other) noexcept {}
+
+    static Buffer empty() { return Buffer(); }
+};
+"#;
+        let processor = CppProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("buffer.hpp"), &opts)
+            .unwrap();
+
+        let Node::Class(class) = &file.children[0] else {
+            panic!("Expected class node");
+        };
+        let modifiers_of = |name: &str| {
+            class
+                .children
+                .iter()
+                .find_map(|n| match n {
+                    Node::Function(f) if f.name == name => Some(f.modifiers.clone()),
+                    _ => None,
+                })
+                .unwrap_or_default()
+        };
+        assert!(modifiers_of("capacity").contains(&Modifier::Constexpr));
+        assert!(modifiers_of("capacity").contains(&Modifier::Const));
+        assert_eq!(modifiers_of("swap"), [Modifier::Noexcept]);
+        assert_eq!(modifiers_of("empty"), [Modifier::Static]);
+    }
 }
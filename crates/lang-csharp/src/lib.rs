@@ -1,10 +1,22 @@
 use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
-    ir::{Class, Field, File, Function, Modifier, Node, Parameter, TypeParam, TypeRef, Visibility},
-    parser::ParserPool,
+    ir::{
+        Accessor, AccessorKind, Class, Deprecation, Enum, EnumVariant, Field, File, Function,
+        Modifier, Module, Node, Parameter, Property, SourceVisibility, TypeParam, TypeRef,
+        Visibility,
+    },
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        overloads::group_overloads,
+        syntax::syntax_error,
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -44,16 +56,17 @@ impl CSharpProcessor {
         source[start..end].to_string()
     }
 
+    /// Parse the type written at `node`
+    fn type_ref(node: TSNode, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::CSharp)
+    }
+
     fn parse_modifiers(
         node: TSNode,
         source: &str,
         context: VisibilityContext,
-    ) -> (Visibility, Vec<Modifier>) {
-        let mut visibility = match context {
-            VisibilityContext::TopLevel => Visibility::Internal,
-            VisibilityContext::InterfaceMember => Visibility::Public,
-            VisibilityContext::ClassMember => Visibility::Private,
-        };
+    ) -> (SourceVisibility, Vec<Modifier>) {
+        let mut access = Vec::new();
         let mut modifiers = Vec::new();
         let mut cursor = node.walk();
 
@@ -62,10 +75,9 @@ impl CSharpProcessor {
                 "modifier" | "modifiers" => {
                     let text = Self::node_text(child, source);
                     match text.as_str() {
-                        "public" => visibility = Visibility::Public,
-                        "protected" => visibility = Visibility::Protected,
-                        "private" => visibility = Visibility::Private,
-                        "internal" => visibility = Visibility::Internal,
+                        "public" | "protected" | "private" | "internal" => {
+                            access.push(text);
+                        }
                         "static" => modifiers.push(Modifier::Static),
                         "abstract" => modifiers.push(Modifier::Abstract),
                         "sealed" => modifiers.push(Modifier::Final),
@@ -74,6 +86,10 @@ impl CSharpProcessor {
                         "async" => modifiers.push(Modifier::Async),
                         "readonly" => modifiers.push(Modifier::Readonly),
                         "const" => modifiers.push(Modifier::Const),
+                        "partial" => modifiers.push(Modifier::Partial),
+                        "unsafe" => modifiers.push(Modifier::Unsafe),
+                        "extern" => modifiers.push(Modifier::Extern),
+                        "volatile" => modifiers.push(Modifier::Volatile),
                         _ => {}
                     }
                 }
@@ -81,6 +97,29 @@ impl CSharpProcessor {
             }
         }
 
+        let visibility = match access
+            .iter()
+            .map(String::as_str)
+            .collect::<Vec<_>>()
+            .as_slice()
+        {
+            ["protected", "internal"] | ["internal", "protected"] => {
+                SourceVisibility::ProtectedInternal
+            }
+            ["private", "protected"] | ["protected", "private"] => {
+                SourceVisibility::PrivateProtected
+            }
+            ["public", ..] => SourceVisibility::Public,
+            ["protected", ..] => SourceVisibility::Protected,
+            ["internal", ..] => SourceVisibility::Internal,
+            ["private", ..] => SourceVisibility::Private,
+            _ => match context {
+                VisibilityContext::TopLevel => SourceVisibility::Internal,
+                VisibilityContext::InterfaceMember => SourceVisibility::Public,
+                VisibilityContext::ClassMember => SourceVisibility::Private,
+            },
+        };
+
         (visibility, modifiers)
     }
 
@@ -88,7 +127,7 @@ impl CSharpProcessor {
         match node.kind() {
             "identifier" | "type_identifier" | "generic_name" | "predefined_type"
             | "qualified_name" => {
-                results.push(TypeRef::new(Self::node_text(node, source)));
+                results.push(Self::type_ref(node, source));
             }
             _ => {
                 let mut cursor = node.walk();
@@ -146,13 +185,11 @@ impl CSharpProcessor {
                             if param_name.is_empty() {
                                 param_name = Self::node_text(constraint_child, source);
                             } else {
-                                constraints
-                                    .push(TypeRef::new(Self::node_text(constraint_child, source)));
+                                constraints.push(Self::type_ref(constraint_child, source));
                             }
                         }
                         "generic_name" | "predefined_type" => {
-                            constraints
-                                .push(TypeRef::new(Self::node_text(constraint_child, source)));
+                            constraints.push(Self::type_ref(constraint_child, source));
                         }
                         _ => {}
                     }
@@ -174,12 +211,104 @@ impl CSharpProcessor {
         (extends, implements)
     }
 
+    /// Detect `[Obsolete]` attributes and `@deprecated` doc tags
+    fn parse_deprecation(node: TSNode, source: &str) -> Option<Deprecation> {
+        let mut attributes = Vec::new();
+        let mut cursor = node.walk();
+
+        for child in node.children(&mut cursor) {
+            if child.kind() == "attribute_list" {
+                let mut attr_cursor = child.walk();
+                for attr in child.children(&mut attr_cursor) {
+                    if attr.kind() == "attribute" {
+                        attributes.push(Self::node_text(attr, source));
+                    }
+                }
+            }
+        }
+
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(&attributes, doc.as_deref())
+    }
+
+    /// Parse a type or namespace declaration into `children`
+    fn parse_declaration(
+        &self,
+        node: TSNode,
+        source: &str,
+        children: &mut Vec<Node>,
+    ) -> Result<()> {
+        match node.kind() {
+            "class_declaration" => {
+                if let Some(class) = self.parse_class(node, source)? {
+                    children.push(Node::Class(class));
+                }
+            }
+            "struct_declaration" => {
+                if let Some(struct_node) = self.parse_struct(node, source)? {
+                    children.push(Node::Class(struct_node));
+                }
+            }
+            "record_declaration" => {
+                if let Some(record) = self.parse_record(node, source)? {
+                    children.push(Node::Class(record));
+                }
+            }
+            "interface_declaration" => {
+                if let Some(interface) = self.parse_interface(node, source)? {
+                    children.push(Node::Class(interface));
+                }
+            }
+            "enum_declaration" => {
+                if let Some(enum_decl) = Self::parse_enum(node, source, VisibilityContext::TopLevel)
+                {
+                    children.push(Node::Enum(enum_decl));
+                }
+            }
+            "namespace_declaration" => {
+                children.push(Node::Module(self.parse_namespace(node, source)?));
+            }
+            _ => {}
+        }
+        Ok(())
+    }
+
+    /// Parse a block or file-scoped namespace into a module
+    ///
+    /// Dotted names (`namespace Acme.Billing`) are kept as one module.
+    fn parse_namespace(&self, node: TSNode, source: &str) -> Result<Module> {
+        let name = node
+            .child_by_field_name("name")
+            .map(|name| Self::node_text(name, source))
+            .unwrap_or_default();
+
+        let mut children = Vec::new();
+        let body = node.child_by_field_name("body").unwrap_or(node);
+        let mut cursor = body.walk();
+        for child in body.children(&mut cursor) {
+            self.parse_declaration(child, source, &mut children)?;
+        }
+
+        Ok(Module {
+            name,
+            visibility: Visibility::Public,
+            source_visibility: None,
+            decorators: Vec::new(),
+            children,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
     fn parse_class(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
         let mut extends = Vec::new();
         let mut implements = Vec::new();
-        let (visibility, modifiers) =
+        let (source_visibility, modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::TopLevel);
+        let visibility = source_visibility.coarse();
         let mut type_params = Vec::new();
         let mut children = Vec::new();
         let line_start = node.start_position().row + 1;
@@ -218,6 +347,7 @@ impl CSharpProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             extends,
             implements,
@@ -226,9 +356,69 @@ impl CSharpProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
+    /// Parse an enum with its underlying type (`enum Status : byte`) and
+    /// explicit member values
+    fn parse_enum(node: TSNode, source: &str, context: VisibilityContext) -> Option<Enum> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+        let (source_visibility, _) = Self::parse_modifiers(node, source, context);
+
+        let mut enum_type = None;
+        let mut variants = Vec::new();
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "base_list" => {
+                    let mut types = Vec::new();
+                    Self::collect_type_refs(child, source, &mut types);
+                    enum_type = types.into_iter().next();
+                }
+                "enum_member_declaration_list" => {
+                    let mut member_cursor = child.walk();
+                    for member in child.children(&mut member_cursor) {
+                        if member.kind() != "enum_member_declaration" {
+                            continue;
+                        }
+                        let Some(member_name) = member.child_by_field_name("name") else {
+                            continue;
+                        };
+                        variants.push(Node::EnumVariant(EnumVariant {
+                            name: Self::node_text(member_name, source),
+                            fields: vec![],
+                            value: member
+                                .child_by_field_name("value")
+                                .and_then(|value| value_preview(&Self::node_text(value, source))),
+                            arguments: vec![],
+                            children: vec![],
+                            line: member.start_position().row + 1,
+                            deprecated: Self::parse_deprecation(member, source),
+                            metadata: BTreeMap::new(),
+                        }));
+                    }
+                }
+                _ => {}
+            }
+        }
+
+        Some(Enum {
+            name,
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
+            decorators: vec![],
+            type_params: vec![],
+            enum_type,
+            children: variants,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
     fn parse_struct(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut class = self.parse_class(node, source)?;
         if let Some(ref mut c) = class {
@@ -249,8 +439,9 @@ impl CSharpProcessor {
         let mut name = String::new();
         let extends = Vec::new();
         let mut implements = Vec::new();
-        let (visibility, modifiers) =
+        let (source_visibility, modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::TopLevel);
+        let visibility = source_visibility.coarse();
         let mut type_params = Vec::new();
         let mut children = Vec::new();
         let line_start = node.start_position().row + 1;
@@ -288,6 +479,7 @@ impl CSharpProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             extends,
             implements,
@@ -296,6 +488,8 @@ impl CSharpProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -319,8 +513,8 @@ impl CSharpProcessor {
                     }
                 }
                 "property_declaration" => {
-                    if let Some(prop) = Self::parse_property(child, source)? {
-                        children.push(Node::Field(prop));
+                    if let Some(prop) = Self::parse_property(child, source) {
+                        children.push(Node::Property(prop));
                     }
                 }
                 "event_declaration" | "event_field_declaration" => {
@@ -333,6 +527,13 @@ impl CSharpProcessor {
                         children.push(Node::Function(op));
                     }
                 }
+                "enum_declaration" => {
+                    if let Some(enum_decl) =
+                        Self::parse_enum(child, source, VisibilityContext::ClassMember)
+                    {
+                        children.push(Node::Enum(enum_decl));
+                    }
+                }
                 _ => {}
             }
         }
@@ -340,8 +541,9 @@ impl CSharpProcessor {
     }
 
     fn parse_field(node: TSNode, source: &str) -> Result<Option<Field>> {
-        let (visibility, modifiers) =
+        let (source_visibility, modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::ClassMember);
+        let visibility = source_visibility.coarse();
         let mut field_type = None;
         let mut name = String::new();
         let line = node.start_position().row + 1;
@@ -354,7 +556,7 @@ impl CSharpProcessor {
                     match var_child.kind() {
                         "type_identifier" | "predefined_type" | "generic_name" | "array_type"
                         | "nullable_type" => {
-                            field_type = Some(TypeRef::new(Self::node_text(var_child, source)));
+                            field_type = Some(Self::type_ref(var_child, source));
                         }
                         "variable_declarator" => {
                             if let Some(name_node) = var_child.child_by_field_name("name") {
@@ -374,53 +576,108 @@ impl CSharpProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             field_type,
             default_value: None,
             modifiers,
             line,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
-    fn parse_property(node: TSNode, source: &str) -> Result<Option<Field>> {
-        let (visibility, modifiers) =
+    /// Parse a property with its accessor list or expression body
+    ///
+    /// Auto-properties (`{ get; set; }`) have a backing field; properties
+    /// whose accessors all have bodies, or `=> expr`, are computed.
+    fn parse_property(node: TSNode, source: &str) -> Option<Property> {
+        let (source_visibility, modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::ClassMember);
-        let mut field_type = None;
-        let mut name = String::new();
-        let line = node.start_position().row + 1;
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
 
+        let mut accessors = Vec::new();
+        let mut is_computed = false;
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
             match child.kind() {
-                "identifier" => {
-                    if name.is_empty() {
-                        name = Self::node_text(child, source);
+                "accessor_list" => {
+                    let mut has_auto_accessor = false;
+                    let mut accessor_cursor = child.walk();
+                    for declaration in child.children(&mut accessor_cursor) {
+                        if declaration.kind() != "accessor_declaration" {
+                            continue;
+                        }
+                        if let Some(accessor) = Self::parse_accessor(declaration, source) {
+                            has_auto_accessor |= declaration.child_by_field_name("body").is_none();
+                            accessors.push(accessor);
+                        }
                     }
+                    is_computed = !accessors.is_empty() && !has_auto_accessor;
                 }
-                "type_identifier" | "predefined_type" | "generic_name" | "array_type"
-                | "nullable_type" => {
-                    field_type = Some(TypeRef::new(Self::node_text(child, source)));
+                "arrow_expression_clause" => {
+                    accessors.push(Accessor::new(AccessorKind::Get));
+                    is_computed = true;
                 }
                 _ => {}
             }
         }
 
-        if name.is_empty() {
-            return Ok(None);
-        }
-
-        Ok(Some(Field {
+        Some(Property {
             name,
-            visibility,
-            field_type,
-            default_value: None,
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
             modifiers,
-            line,
-        }))
+            property_type: node
+                .child_by_field_name("type")
+                .map(|ty| Self::type_ref(ty, source)),
+            accessors,
+            is_computed,
+            default_value: node
+                .child_by_field_name("value")
+                .filter(|value| value.kind() != "arrow_expression_clause")
+                .and_then(|value| value_preview(&Self::node_text(value, source))),
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Parse a `get`, `set` or `init` accessor; its visibility is only kept
+    /// when written (`private set`)
+    fn parse_accessor(node: TSNode, source: &str) -> Option<Accessor> {
+        let mut accessor_kind = None;
+        let mut has_access_modifier = false;
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "get" => accessor_kind = Some(AccessorKind::Get),
+                "set" => accessor_kind = Some(AccessorKind::Set),
+                "init" => accessor_kind = Some(AccessorKind::Init),
+                "modifier" => {
+                    has_access_modifier |= matches!(
+                        Self::node_text(child, source).as_str(),
+                        "public" | "protected" | "private" | "internal"
+                    );
+                }
+                _ => {}
+            }
+        }
+
+        let mut accessor = Accessor::new(accessor_kind?);
+        if has_access_modifier {
+            let (source_visibility, _) =
+                Self::parse_modifiers(node, source, VisibilityContext::ClassMember);
+            accessor.visibility = Some(source_visibility.coarse());
+            accessor.source_visibility = Some(source_visibility);
+        }
+        Some(accessor)
     }
 
     fn parse_event(node: TSNode, source: &str) -> Result<Option<Field>> {
-        let (visibility, mut modifiers) =
+        let (source_visibility, mut modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::ClassMember);
+        let visibility = source_visibility.coarse();
         modifiers.push(Modifier::Event);
         let mut field_type = None;
         let mut name = String::new();
@@ -435,14 +692,14 @@ impl CSharpProcessor {
                     }
                 }
                 "type_identifier" | "generic_name" | "predefined_type" => {
-                    field_type = Some(TypeRef::new(Self::node_text(child, source)));
+                    field_type = Some(Self::type_ref(child, source));
                 }
                 "variable_declaration" => {
                     let mut var_cursor = child.walk();
                     for var_child in child.children(&mut var_cursor) {
                         match var_child.kind() {
                             "type_identifier" | "generic_name" => {
-                                field_type = Some(TypeRef::new(Self::node_text(var_child, source)));
+                                field_type = Some(Self::type_ref(var_child, source));
                             }
                             "variable_declarator" => {
                                 if let Some(name_node) = var_child.child_by_field_name("name") {
@@ -464,16 +721,20 @@ impl CSharpProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             field_type,
             default_value: None,
             modifiers,
             line,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
     fn parse_method(&self, node: TSNode, source: &str) -> Result<Option<Function>> {
-        let (visibility, modifiers) =
+        let (source_visibility, modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::ClassMember);
+        let visibility = source_visibility.coarse();
         let mut name = String::new();
         let mut return_type = None;
         let mut parameters = Vec::new();
@@ -492,7 +753,7 @@ impl CSharpProcessor {
                 "type_identifier" | "predefined_type" | "generic_name" | "array_type"
                 | "nullable_type" => {
                     if return_type.is_none() {
-                        return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        return_type = Some(Self::type_ref(child, source));
                     }
                 }
                 "void_keyword" => {
@@ -515,6 +776,7 @@ impl CSharpProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             parameters,
             return_type,
@@ -523,13 +785,16 @@ impl CSharpProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
     #[allow(clippy::unused_self)]
     fn parse_constructor(&self, node: TSNode, source: &str) -> Result<Option<Function>> {
-        let (visibility, modifiers) =
+        let (source_visibility, modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::ClassMember);
+        let visibility = source_visibility.coarse();
         let mut name = String::new();
         let mut parameters = Vec::new();
         let line_start = node.start_position().row + 1;
@@ -557,6 +822,7 @@ impl CSharpProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             parameters,
             return_type: None,
@@ -565,14 +831,18 @@ impl CSharpProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
     #[allow(clippy::unused_self)]
     fn parse_operator(&self, node: TSNode, source: &str) -> Result<Option<Function>> {
-        let (visibility, mut modifiers) =
+        let (source_visibility, mut modifiers) =
             Self::parse_modifiers(node, source, VisibilityContext::ClassMember);
+        let visibility = source_visibility.coarse();
         modifiers.push(Modifier::Static); // Operators are always static
+        modifiers.push(Modifier::Operator);
         let mut name = String::new();
         let mut return_type = None;
         let mut parameters = Vec::new();
@@ -593,7 +863,7 @@ impl CSharpProcessor {
                 }
                 "type_identifier" | "predefined_type" | "generic_name" => {
                     if return_type.is_none() {
-                        return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        return_type = Some(Self::type_ref(child, source));
                     }
                 }
                 "parameter_list" => {
@@ -604,12 +874,17 @@ impl CSharpProcessor {
         }
 
         if name.is_empty() {
-            name = "operator".to_string();
+            // The overloaded token is an anonymous node in the `operator` field
+            name = match node.child_by_field_name("operator") {
+                Some(token) => format!("operator{}", Self::node_text(token, source)),
+                None => "operator".to_string(),
+            };
         }
 
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             parameters,
             return_type,
@@ -618,6 +893,8 @@ impl CSharpProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -640,7 +917,7 @@ impl CSharpProcessor {
                         }
                         "type_identifier" | "predefined_type" | "generic_name" | "array_type"
                         | "nullable_type" => {
-                            param_type = TypeRef::new(Self::node_text(param_child, source));
+                            param_type = Self::type_ref(param_child, source);
                         }
                         "this_expression" | "ref_keyword" | "out_keyword" | "in_keyword"
                         | "params_keyword" => {
@@ -694,76 +971,42 @@ impl LanguageProcessor for CSharpProcessor {
 
         let tree = parser
             .parse(source, None)
-            .ok_or_else(|| DistilError::parse_error("csharp", "Failed to parse source"))?;
+            .ok_or_else(|| DistilError::parse_error(path, "Failed to parse source"))?;
 
         let root = tree.root_node();
         let mut children = Vec::new();
+        // Declarations following a file-scoped `namespace X;` belong to it
+        let mut file_scoped: Option<Module> = None;
 
         let mut cursor = root.walk();
         for child in root.children(&mut cursor) {
-            match child.kind() {
-                "class_declaration" => {
-                    if let Some(class) = self.parse_class(child, source)? {
-                        children.push(Node::Class(class));
-                    }
+            if child.kind() == "file_scoped_namespace_declaration" {
+                let mut module = self.parse_namespace(child, source)?;
+                module.line_end = root.end_position().row + 1;
+                if let Some(previous) = file_scoped.replace(module) {
+                    children.push(Node::Module(previous));
                 }
-                "struct_declaration" => {
-                    if let Some(struct_node) = self.parse_struct(child, source)? {
-                        children.push(Node::Class(struct_node));
-                    }
-                }
-                "record_declaration" => {
-                    if let Some(record) = self.parse_record(child, source)? {
-                        children.push(Node::Class(record));
-                    }
-                }
-                "interface_declaration" => {
-                    if let Some(interface) = self.parse_interface(child, source)? {
-                        children.push(Node::Class(interface));
-                    }
-                }
-                "namespace_declaration" | "file_scoped_namespace_declaration" => {
-                    // Recursively process namespace contents
-                    let mut ns_cursor = child.walk();
-                    for ns_child in child.children(&mut ns_cursor) {
-                        if ns_child.kind() == "declaration_list" {
-                            let mut decl_cursor = ns_child.walk();
-                            for decl_child in ns_child.children(&mut decl_cursor) {
-                                match decl_child.kind() {
-                                    "class_declaration" => {
-                                        if let Some(class) = self.parse_class(decl_child, source)? {
-                                            children.push(Node::Class(class));
-                                        }
-                                    }
-                                    "struct_declaration" => {
-                                        if let Some(struct_node) =
-                                            self.parse_struct(decl_child, source)?
-                                        {
-                                            children.push(Node::Class(struct_node));
-                                        }
-                                    }
-                                    "record_declaration" => {
-                                        if let Some(record) =
-                                            self.parse_record(decl_child, source)?
-                                        {
-                                            children.push(Node::Class(record));
-                                        }
-                                    }
-                                    "interface_declaration" => {
-                                        if let Some(interface) =
-                                            self.parse_interface(decl_child, source)?
-                                        {
-                                            children.push(Node::Class(interface));
-                                        }
-                                    }
-                                    _ => {}
-                                }
-                            }
-                        }
-                    }
-                }
-                _ => {}
+                continue;
             }
+
+            let target = match &mut file_scoped {
+                Some(module) => &mut module.children,
+                None => &mut children,
+            };
+            self.parse_declaration(child, source, target)?;
+        }
+        if let Some(module) = file_scoped {
+            children.push(Node::Module(module));
+        }
+
+        // Same-named methods in one scope are overloads
+        group_overloads(&mut children);
+
+        // Nothing recovered: report where parsing went wrong
+        if children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
         }
 
         Ok(File {
@@ -809,7 +1052,12 @@ public static class MathHelpers
             .unwrap();
 
         assert_eq!(file.children.len(), 1);
-        if let Node::Class(class) = &file.children[0] {
+        let Node::Module(namespace) = &file.children[0] else {
+            panic!("Expected file-scoped namespace");
+        };
+        assert_eq!(namespace.name, "Test");
+        assert_eq!(namespace.children.len(), 1);
+        if let Node::Class(class) = &namespace.children[0] {
             assert_eq!(class.name, "MathHelpers");
             assert_eq!(class.visibility, Visibility::Public);
             assert!(class.modifiers.contains(&Modifier::Static));
@@ -834,6 +1082,91 @@ public static class MathHelpers
         }
     }
 
+    #[test]
+    fn test_block_namespaces() {
+        let source = r#"
+namespace Acme.Billing
+{
+    namespace Internal
+    {
+        internal class Ledger { }
+    }
+
+    public class Invoice { }
+}
+"#;
+        let processor = CSharpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Billing.cs"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 1);
+        let Node::Module(billing) = &file.children[0] else {
+            panic!("Expected namespace");
+        };
+        assert_eq!(billing.name, "Acme.Billing");
+        assert_eq!(billing.children.len(), 2);
+
+        let Node::Module(internal) = &billing.children[0] else {
+            panic!("Expected nested namespace");
+        };
+        assert_eq!(internal.name, "Internal");
+        assert!(matches!(&internal.children[0], Node::Class(c) if c.name == "Ledger"));
+        assert!(matches!(&billing.children[1], Node::Class(c) if c.name == "Invoice"));
+    }
+
+    #[test]
+    fn test_enum_with_values() {
+        let source = r#"
+public enum Status : byte
+{
+    Active = 1,
+    [Obsolete]
+    Inactive = 2,
+    Pending
+}
+
+public class Order
+{
+    private enum Stage { Draft, Sent }
+}
+"#;
+        let processor = CSharpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Status.cs"), &opts)
+            .unwrap();
+
+        let Node::Enum(status) = &file.children[0] else {
+            panic!("Expected enum");
+        };
+        assert_eq!(status.name, "Status");
+        assert_eq!(status.visibility, Visibility::Public);
+        assert_eq!(status.enum_type.as_ref().unwrap().name, "byte");
+
+        let members: Vec<_> = status
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::EnumVariant(member) => Some(member),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(members.len(), 3);
+        assert_eq!(members[0].value.as_deref(), Some("1"));
+        assert!(members[1].deprecated.is_some());
+        assert_eq!(members[2].name, "Pending");
+        assert!(members[2].value.is_none());
+
+        let Node::Class(order) = &file.children[1] else {
+            panic!("Expected class");
+        };
+        assert!(
+            matches!(&order.children[0], Node::Enum(e) if e.name == "Stage" && e.visibility == Visibility::Private)
+        );
+    }
+
     #[test]
     fn test_interface_with_generics() {
         let source = r#"
@@ -1005,6 +1338,50 @@ public class Test
         }
     }
 
+    #[test]
+    fn test_compound_visibility() {
+        let source = r#"
+public class Widget
+{
+    protected internal void Render() { }
+    private protected void Layout() { }
+    void Measure() { }
+}
+"#;
+        let processor = CSharpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Widget.cs"), &opts)
+            .unwrap();
+
+        let Node::Class(class) = &file.children[0] else {
+            panic!("Expected a class");
+        };
+        let levels: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(f) => Some((f.visibility, f.source_visibility.clone())),
+                _ => None,
+            })
+            .collect();
+
+        assert_eq!(
+            levels,
+            [
+                (
+                    Visibility::Protected,
+                    Some(SourceVisibility::ProtectedInternal)
+                ),
+                (
+                    Visibility::Protected,
+                    Some(SourceVisibility::PrivateProtected)
+                ),
+                (Visibility::Private, Some(SourceVisibility::Private)),
+            ]
+        );
+    }
+
     #[test]
     fn test_properties_and_events() {
         let source = r#"
@@ -1031,7 +1408,7 @@ public class Account
                     _ => None,
                 })
                 .collect();
-            assert_eq!(fields.len(), 3);
+            assert_eq!(fields.len(), 1);
 
             // Check that event has Event modifier
             let event = fields.iter().find(|f| f.name == "BalanceChanged");
@@ -1039,6 +1416,25 @@ public class Account
             if let Some(e) = event {
                 assert!(e.modifiers.contains(&Modifier::Event));
             }
+
+            let properties: Vec<_> = class
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    Node::Property(p) => Some(p),
+                    _ => None,
+                })
+                .collect();
+            assert_eq!(properties.len(), 2);
+            assert_eq!(properties[0].name, "AccountNumber");
+            assert_eq!(properties[0].accessors, [Accessor::new(AccessorKind::Get)]);
+            assert!(!properties[0].is_computed);
+
+            let balance = properties[1];
+            assert_eq!(balance.property_type.as_ref().unwrap().name, "decimal");
+            assert_eq!(balance.accessors.len(), 2);
+            assert_eq!(balance.accessors[1].accessor_kind, AccessorKind::Set);
+            assert_eq!(balance.accessors[1].visibility, Some(Visibility::Protected));
         } else {
             panic!("Expected a class");
         }
@@ -1090,15 +1486,12 @@ public interface ILogger
             assert_eq!(methods.len(), 1);
             assert_eq!(methods[0].name, "Log");
 
-            let properties: Vec<_> = interface
+            let members: Vec<_> = interface
                 .children
                 .iter()
-                .filter_map(|n| match n {
-                    Node::Field(f) => Some(f),
-                    _ => None,
-                })
+                .filter(|n| matches!(n, Node::Field(_) | Node::Property(_)))
                 .collect();
-            assert_eq!(properties.len(), 2);
+            assert_eq!(members.len(), 2);
         } else {
             panic!("Expected an interface");
         }
@@ -1368,6 +1761,7 @@ public class Vector
                 })
                 .collect();
             assert_eq!(operators.len(), 3);
+            assert_eq!(operators[0].name, "operator+");
 
             // All operators should be static
             for op in &operators {
@@ -1446,7 +1840,7 @@ public class Person
                 .children
                 .iter()
                 .filter_map(|n| match n {
-                    Node::Field(f) => Some(f),
+                    Node::Property(p) => Some(p),
                     _ => None,
                 })
                 .collect();
@@ -1457,9 +1851,89 @@ public class Person
             assert!(first_name.is_some());
             if let Some(prop) = first_name {
                 assert_eq!(prop.visibility, Visibility::Public);
+                assert_eq!(
+                    prop.accessors,
+                    [
+                        Accessor::new(AccessorKind::Get),
+                        Accessor::new(AccessorKind::Init)
+                    ]
+                );
             }
+
+            let full_name = properties.iter().find(|p| p.name == "FullName").unwrap();
+            assert!(full_name.is_computed);
+            assert_eq!(full_name.accessors, [Accessor::new(AccessorKind::Get)]);
         } else {
             panic!("Expected a class with init-only properties");
         }
     }
+
+    #[test]
+    fn test_obsolete_attribute() {
+        let source = r#"
+public class Store
+{
+    [Obsolete("Use SaveAsync instead")]
+    public void Save() {}
+
+    public Task SaveAsync() => Task.CompletedTask;
+}
+"#;
+        let processor = CSharpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("Store.cs"), &opts)
+            .unwrap();
+
+        if let Node::Class(class) = &file.children[0] {
+            let methods: Vec<_> = class
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    Node::Function(f) => Some(f),
+                    _ => None,
+                })
+                .collect();
+            let deprecated = methods[0].deprecated.as_ref().expect("Save is obsolete");
+            assert_eq!(deprecated.message.as_deref(), Some("Use SaveAsync instead"));
+            assert_eq!(deprecated.replacement.as_deref(), Some("SaveAsync"));
+            assert!(methods[1].deprecated.is_none());
+        } else {
+            panic!("Expected a class");
+        }
+    }
+
+    #[test]
+    fn test_partial_and_unsafe_modifiers() {
+        let source = r#"
+public partial class Native
+{
+    public unsafe void Copy(byte* dst, byte* src) {}
+
+    public static extern int GetTickCount();
+}
+"#;
+        let processor = CSharpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("Native.cs"), &opts)
+            .unwrap();
+
+        if let Node::Class(class) = &file.children[0] {
+            assert!(class.modifiers.contains(&Modifier::Partial));
+            let methods: Vec<_> = class
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    Node::Function(f) => Some(f),
+                    _ => None,
+                })
+                .collect();
+            assert!(methods[0].modifiers.contains(&Modifier::Unsafe));
+            assert!(methods[1].modifiers.contains(&Modifier::Extern));
+            assert!(methods[1].modifiers.contains(&Modifier::Static));
+        } else {
+            panic!("Expected a class");
+        }
+    }
 }
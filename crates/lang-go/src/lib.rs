@@ -2,13 +2,21 @@ use distiller_core::error::Result;
 use distiller_core::{
     error::DistilError,
     ir::{
-        Class, Field, File, Function, Import, Interface, Modifier, Node, Parameter, TypeParam,
-        TypeRef, Visibility,
+        Class, Deprecation, Field, File, Function, Import, Interface, Modifier, Node, Parameter,
+        SourceVisibility, TypeParam, TypeRef, Variable, VariableKind,
     },
     options::ProcessOptions,
-    parser::ParserPool,
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        syntax::syntax_error,
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::language::LanguageProcessor,
+    type_merge::RECEIVER,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 
@@ -38,6 +46,27 @@ impl GoProcessor {
         source[start..end].to_string()
     }
 
+    /// Parse the type written at `node`
+    fn type_ref(node: tree_sitter::Node, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::Go)
+    }
+
+    /// Exported (capitalized) names are public, the rest are package-private
+    fn name_visibility(name: &str) -> SourceVisibility {
+        if name.chars().next().is_some_and(char::is_uppercase) {
+            SourceVisibility::Public
+        } else {
+            SourceVisibility::PackagePrivate
+        }
+    }
+
+    /// Detect the `Deprecated:` paragraph of a doc comment
+    fn parse_deprecation(node: tree_sitter::Node, source: &str) -> Option<Deprecation> {
+        preceding_comment(node, source)
+            .as_deref()
+            .and_then(Deprecation::from_doc_comment)
+    }
+
     fn parse_imports(&self, node: tree_sitter::Node, source: &str) -> Result<Vec<Import>> {
         let mut imports = Vec::new();
 
@@ -151,15 +180,13 @@ impl GoProcessor {
             return Ok(None);
         }
 
-        let visibility = if name.chars().next().unwrap_or('a').is_uppercase() {
-            Visibility::Public
-        } else {
-            Visibility::Internal
-        };
+        let source_visibility = Self::name_visibility(&name);
+        let visibility = source_visibility.coarse();
 
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             decorators: vec![],
             type_params,
             extends: vec![],
@@ -168,6 +195,8 @@ impl GoProcessor {
             modifiers: vec![],
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -208,9 +237,9 @@ impl GoProcessor {
                     if name.is_empty() {
                         // Embedded field: type name IS the field name
                         name = Self::node_text(child, source);
-                        field_type = Some(TypeRef::new(name.clone()));
+                        field_type = Some(Self::type_ref(child, source));
                     } else {
-                        field_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        field_type = Some(Self::type_ref(child, source));
                     }
                 }
                 _ => {}
@@ -221,19 +250,19 @@ impl GoProcessor {
             return Ok(None);
         }
 
-        let visibility = if name.chars().next().unwrap_or('a').is_uppercase() {
-            Visibility::Public
-        } else {
-            Visibility::Internal
-        };
+        let source_visibility = Self::name_visibility(&name);
+        let visibility = source_visibility.coarse();
 
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             field_type,
             modifiers: vec![],
             default_value: None,
             line,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -272,20 +301,20 @@ impl GoProcessor {
             return Ok(None);
         }
 
-        let visibility = if name.chars().next().unwrap_or('a').is_uppercase() {
-            Visibility::Public
-        } else {
-            Visibility::Internal
-        };
+        let source_visibility = Self::name_visibility(&name);
+        let visibility = source_visibility.coarse();
 
         Ok(Some(Interface {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             type_params,
             extends: vec![],
             children: methods.into_iter().map(Node::Function).collect(),
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -319,15 +348,13 @@ impl GoProcessor {
             return Ok(None);
         }
 
-        let visibility = if name.chars().next().unwrap_or('a').is_uppercase() {
-            Visibility::Public
-        } else {
-            Visibility::Internal
-        };
+        let source_visibility = Self::name_visibility(&name);
+        let visibility = source_visibility.coarse();
 
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             parameters,
             return_type,
             decorators: vec![],
@@ -336,6 +363,8 @@ impl GoProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -391,11 +420,8 @@ impl GoProcessor {
             return Ok(None);
         }
 
-        let visibility = if name.chars().next().unwrap_or('a').is_uppercase() {
-            Visibility::Public
-        } else {
-            Visibility::Internal
-        };
+        let source_visibility = Self::name_visibility(&name);
+        let visibility = source_visibility.coarse();
 
         let modifiers = if receiver_type.is_some() {
             vec![Modifier::Static]
@@ -403,9 +429,20 @@ impl GoProcessor {
             vec![]
         };
 
+        // Methods merge into their struct by receiver type: `*List[T]` -> `List`
+        let mut metadata = BTreeMap::new();
+        if let Some(receiver) = &receiver_type {
+            let base = receiver.name.trim_start_matches('*');
+            let base = base.split('[').next().unwrap_or(base);
+            if !base.is_empty() {
+                metadata.insert(RECEIVER.to_string(), base.to_string());
+            }
+        }
+
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             parameters,
             return_type,
             decorators: vec![],
@@ -414,6 +451,8 @@ impl GoProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata,
         }))
     }
 
@@ -451,10 +490,10 @@ impl GoProcessor {
                 "identifier" | "field_identifier" => {
                     names.push(Self::node_text(child, source));
                 }
-                "type_identifier" | "qualified_type" | "pointer_type" | "array_type"
-                | "slice_type" | "map_type" | "channel_type" | "function_type"
+                "type_identifier" | "qualified_type" | "pointer_type" | "generic_type"
+                | "array_type" | "slice_type" | "map_type" | "channel_type" | "function_type"
                 | "interface_type" | "struct_type" => {
-                    param_type = Some(TypeRef::new(Self::node_text(child, source)));
+                    param_type = Some(Self::type_ref(child, source));
                 }
                 _ => {}
             }
@@ -507,7 +546,7 @@ impl GoProcessor {
                 "type_identifier" | "qualified_type" | "pointer_type" | "array_type"
                 | "slice_type" | "map_type" | "channel_type" | "function_type"
                 | "interface_type" | "struct_type" => {
-                    param_type = Some(TypeRef::new(Self::node_text(child, source)));
+                    param_type = Some(Self::type_ref(child, source));
                 }
                 "..." => {
                     // Variadic marker, already captured by node kind
@@ -557,7 +596,7 @@ impl GoProcessor {
         match node.kind() {
             "type_identifier" | "qualified_type" | "pointer_type" | "array_type" | "slice_type"
             | "map_type" | "channel_type" | "function_type" | "interface_type" | "struct_type" => {
-                Some(TypeRef::new(Self::node_text(node, source)))
+                Some(Self::type_ref(node, source))
             }
             _ => None,
         }
@@ -598,11 +637,11 @@ impl GoProcessor {
                     if name.is_empty() {
                         name = Self::node_text(child, source);
                     } else {
-                        constraint = Some(TypeRef::new(Self::node_text(child, source)));
+                        constraint = Some(Self::type_ref(child, source));
                     }
                 }
                 "qualified_type" | "interface_type" => {
-                    constraint = Some(TypeRef::new(Self::node_text(child, source)));
+                    constraint = Some(Self::type_ref(child, source));
                 }
                 _ => {}
             }
@@ -634,6 +673,11 @@ impl GoProcessor {
                     file.children.push(Node::Function(func));
                 }
             }
+            "const_declaration" | "var_declaration" => {
+                for variable in Self::parse_var_declaration(node, source) {
+                    file.children.push(Node::Variable(variable));
+                }
+            }
             _ => {
                 let mut cursor = node.walk();
                 for child in node.children(&mut cursor) {
@@ -645,6 +689,99 @@ impl GoProcessor {
         Ok(())
     }
 
+    /// Parse a package-level `const` or `var` declaration, one variable per name
+    ///
+    /// Constants without an expression repeat the previous spec's type and
+    /// expression, as in `iota` enumerations; those record their `iota` index.
+    fn parse_var_declaration(node: tree_sitter::Node, source: &str) -> Vec<Variable> {
+        let is_const = node.kind() == "const_declaration";
+        let mut specs = Vec::new();
+        let mut cursor = node.walk();
+        for child in node.named_children(&mut cursor) {
+            match child.kind() {
+                "const_spec" | "var_spec" => specs.push(child),
+                "var_spec_list" => {
+                    let mut list_cursor = child.walk();
+                    specs.extend(
+                        child
+                            .named_children(&mut list_cursor)
+                            .filter(|spec| spec.kind() == "var_spec"),
+                    );
+                }
+                _ => {}
+            }
+        }
+
+        // Doc comments on a single-spec declaration sit before the keyword
+        let declaration_deprecated = Self::parse_deprecation(node, source);
+
+        let mut variables = Vec::new();
+        let mut previous: (Option<TypeRef>, Vec<String>) = (None, Vec::new());
+        for (iota, spec) in specs.into_iter().enumerate() {
+            let mut spec_cursor = spec.walk();
+            let names: Vec<String> = spec
+                .named_children(&mut spec_cursor)
+                .filter(|child| child.kind() == "identifier")
+                .map(|child| Self::node_text(child, source))
+                .collect();
+            let mut var_type = spec
+                .child_by_field_name("type")
+                .map(|t| Self::type_ref(t, source));
+            let mut values: Vec<String> = spec
+                .child_by_field_name("value")
+                .map(|list| {
+                    let mut list_cursor = list.walk();
+                    list.named_children(&mut list_cursor)
+                        .map(|value| Self::node_text(value, source))
+                        .collect()
+                })
+                .unwrap_or_default();
+
+            if is_const {
+                if values.is_empty() {
+                    var_type.clone_from(&previous.0);
+                    values.clone_from(&previous.1);
+                } else {
+                    previous = (var_type.clone(), values.clone());
+                }
+            }
+
+            let deprecated =
+                Self::parse_deprecation(spec, source).or_else(|| declaration_deprecated.clone());
+            for (index, name) in names.into_iter().enumerate() {
+                if name == "_" {
+                    continue;
+                }
+
+                let mut metadata = BTreeMap::new();
+                if is_const && values.get(index).is_some_and(|v| v.contains("iota")) {
+                    metadata.insert("iota".to_string(), iota.to_string());
+                }
+
+                let source_visibility = Self::name_visibility(&name);
+                variables.push(Variable {
+                    name,
+                    visibility: source_visibility.coarse(),
+                    source_visibility: Some(source_visibility),
+                    var_kind: if is_const {
+                        VariableKind::Const
+                    } else {
+                        VariableKind::Var
+                    },
+                    modifiers: vec![],
+                    var_type: var_type.clone(),
+                    value: values.get(index).and_then(|v| value_preview(v)),
+                    is_mutable: !is_const,
+                    line: spec.start_position().row + 1,
+                    deprecated: deprecated.clone(),
+                    metadata,
+                });
+            }
+        }
+
+        variables
+    }
+
     fn process_type_spec(
         &self,
         node: tree_sitter::Node,
@@ -712,6 +849,13 @@ impl LanguageProcessor for GoProcessor {
 
         self.process_node(root_node, source, &mut file)?;
 
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
+
         Ok(file)
     }
 }
@@ -725,6 +869,8 @@ impl Default for GoProcessor {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use distiller_core::ir::Visibility;
+    use distiller_core::type_merge::{DECLARED_IN, merge_file};
 
     #[test]
     fn test_processor_creation() {
@@ -1010,6 +1156,28 @@ func (c *Counter) Increment() {
         // Both methods should have Static modifier (receiver methods)
         assert!(methods[0].modifiers.contains(&Modifier::Static));
         assert!(methods[1].modifiers.contains(&Modifier::Static));
+
+        // The type merge pass moves them into the struct
+        let mut file = file;
+        merge_file(&mut file);
+        assert_eq!(file.children.len(), 1);
+        let Node::Class(counter) = &file.children[0] else {
+            panic!("Expected Counter struct");
+        };
+        let merged: Vec<_> = counter
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(merged.len(), 2);
+        assert!(merged.iter().all(|f| !f.metadata.contains_key(RECEIVER)));
+        assert_eq!(
+            merged[1].metadata.get(DECLARED_IN).map(String::as_str),
+            Some("test.go:12")
+        );
     }
 
     #[test]
@@ -1194,14 +1362,72 @@ var (
 "#;
         let opts = ProcessOptions::default();
 
-        // Note: Current implementation may not parse package-level vars as separate nodes
         let file = processor
             .process(source, Path::new("test.go"), &opts)
             .unwrap();
-        assert!(file.path.contains("test.go"));
 
-        // This test validates the processor doesn't crash on package-level variables
-        // Full var parsing may be added in future enhancements
+        let variables: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(variables.len(), 3);
+        assert_eq!(variables[0].name, "GlobalCounter");
+        assert_eq!(variables[0].var_kind, VariableKind::Var);
+        assert!(variables[0].is_mutable);
+        assert_eq!(variables[0].var_type.as_ref().unwrap().name, "int");
+        assert_eq!(variables[1].name, "AppName");
+        assert_eq!(variables[1].value.as_deref(), Some("\"MyApp\""));
+        assert_eq!(variables[2].name, "Version");
+    }
+
+    #[test]
+    fn test_iota_constants() {
+        let processor = GoProcessor::new().unwrap();
+        let source = r#"
+package main
+
+const MaxRetries = 3
+
+type Weekday int
+
+const (
+    Sunday Weekday = iota
+    Monday
+    tuesday
+)
+"#;
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("days.go"), &opts)
+            .unwrap();
+
+        let constants: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(constants.len(), 4);
+        assert_eq!(constants[0].name, "MaxRetries");
+        assert_eq!(constants[0].var_kind, VariableKind::Const);
+        assert!(!constants[0].is_mutable);
+        assert_eq!(constants[0].value.as_deref(), Some("3"));
+
+        // Implicit repetition carries the type and `iota` expression
+        assert_eq!(constants[2].name, "Monday");
+        assert_eq!(constants[2].var_type.as_ref().unwrap().name, "Weekday");
+        assert_eq!(constants[2].value.as_deref(), Some("iota"));
+        assert_eq!(
+            constants[2].metadata.get("iota").map(String::as_str),
+            Some("1")
+        );
+        assert_eq!(constants[3].visibility, Visibility::Internal);
     }
 
     #[test]
@@ -1272,6 +1498,10 @@ type internalInterface interface {
             Visibility::Internal,
             "Lowercase field should be internal"
         );
+        assert_eq!(
+            fields[1].source_visibility,
+            Some(SourceVisibility::PackagePrivate)
+        );
 
         // Validate functions
         let functions: Vec<_> = file
@@ -1396,6 +1626,10 @@ func (b *Buffer) Reset() {
             methods[1].modifiers.contains(&Modifier::Static),
             "Pointer receiver method should have Static modifier"
         );
+        assert_eq!(
+            methods[1].metadata.get(RECEIVER).map(String::as_str),
+            Some("Buffer")
+        );
     }
 
     #[test]
@@ -1632,4 +1866,72 @@ func Sum(numbers ...int) int {
             duration
         );
     }
+
+    #[test]
+    fn test_deprecated_comment() {
+        let processor = GoProcessor::new().unwrap();
+        let source = r#"package store
+
+// Get returns the value for key.
+//
+// Deprecated: Use Lookup instead.
+func Get(key string) string {
+	return ""
+}
+
+// Lookup returns the value for key.
+func Lookup(key string) (string, bool) {
+	return "", false
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("store.go"), &opts)
+            .unwrap();
+
+        let functions: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+
+        assert_eq!(functions.len(), 2);
+        let deprecated = functions[0].deprecated.as_ref().expect("Get is deprecated");
+        assert_eq!(deprecated.message.as_deref(), Some("Use Lookup instead."));
+        assert_eq!(deprecated.replacement.as_deref(), Some("Lookup"));
+        assert!(functions[1].deprecated.is_none());
+    }
+
+    #[test]
+    fn test_comment_after_blank_line_is_not_doc() {
+        let processor = GoProcessor::new().unwrap();
+        let source = r#"// Copyright 2020 Example Corp.
+// Deprecated: this header predates the package.
+
+package store
+
+// Deprecated: the old store API was removed.
+
+func Get(key string) string {
+	return ""
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("store.go"), &opts)
+            .unwrap();
+        let Some(Node::Function(get)) = file
+            .children
+            .iter()
+            .find(|n| matches!(n, Node::Function(_)))
+        else {
+            panic!("expected Get");
+        };
+        assert!(get.deprecated.is_none());
+    }
 }
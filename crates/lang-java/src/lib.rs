@@ -2,12 +2,19 @@ use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
     ir::{
-        self, Class, Field, File, Function, Import, Modifier, Parameter, TypeParam, TypeRef,
-        Visibility,
+        self, Class, Deprecation, EnumVariant, Field, File, Function, Import, Modifier, Package,
+        Parameter, SourceVisibility, TypeParam, TypeRef, Visibility,
+    },
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        overloads::group_overloads,
+        syntax::syntax_error,
+        types::{TypeSyntax, parse_type},
     },
-    parser::ParserPool,
     processor::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -35,11 +42,18 @@ impl JavaProcessor {
         source[node.start_byte()..node.end_byte()].to_string()
     }
 
-    fn parse_modifiers(node: TSNode, source: &str) -> (Visibility, Vec<Modifier>, Vec<String>) {
-        let mut visibility = Visibility::Internal; // Java default is package-private
+    /// Parse the type written at `node`
+    fn type_ref(node: TSNode, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::Java)
+    }
+
+    fn parse_modifiers(
+        node: TSNode,
+        source: &str,
+    ) -> (SourceVisibility, Vec<Modifier>, Vec<String>) {
+        let mut visibility = SourceVisibility::PackagePrivate; // Java default
         let mut modifiers = Vec::new();
         let mut decorators = Vec::new();
-        let mut has_visibility_keyword = false;
         let mut cursor = node.walk();
 
         // Find the modifiers child node
@@ -49,21 +63,16 @@ impl JavaProcessor {
                 let mut mod_cursor = child.walk();
                 for mod_child in child.children(&mut mod_cursor) {
                     match mod_child.kind() {
-                        "public" => {
-                            visibility = Visibility::Public;
-                            has_visibility_keyword = true;
-                        }
-                        "protected" => {
-                            visibility = Visibility::Protected;
-                            has_visibility_keyword = true;
-                        }
-                        "private" => {
-                            visibility = Visibility::Private;
-                            has_visibility_keyword = true;
-                        }
+                        "public" => visibility = SourceVisibility::Public,
+                        "protected" => visibility = SourceVisibility::Protected,
+                        "private" => visibility = SourceVisibility::Private,
                         "static" => modifiers.push(Modifier::Static),
                         "final" => modifiers.push(Modifier::Final),
                         "abstract" => modifiers.push(Modifier::Abstract),
+                        "synchronized" => modifiers.push(Modifier::Synchronized),
+                        "native" => modifiers.push(Modifier::Native),
+                        "transient" => modifiers.push(Modifier::Transient),
+                        "volatile" => modifiers.push(Modifier::Volatile),
                         "marker_annotation" | "annotation" => {
                             decorators.push(Self::node_text(mod_child, source));
                         }
@@ -74,14 +83,19 @@ impl JavaProcessor {
             }
         }
 
-        // If no visibility keyword, use Internal (package-private)
-        if !has_visibility_keyword {
-            visibility = Visibility::Internal;
-        }
-
         (visibility, modifiers, decorators)
     }
 
+    /// Detect `@Deprecated` annotations and Javadoc `@deprecated` tags
+    fn parse_deprecation(
+        node: TSNode,
+        source: &str,
+        annotations: &[String],
+    ) -> Option<Deprecation> {
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(annotations, doc.as_deref())
+    }
+
     fn parse_type_parameters(node: TSNode, source: &str) -> Vec<TypeParam> {
         let mut params = Vec::new();
         let mut cursor = node.walk();
@@ -101,7 +115,7 @@ impl JavaProcessor {
                     let mut constraints = Vec::new();
 
                     if let Some(bound_node) = child.child_by_field_name("bound") {
-                        constraints.push(TypeRef::new(Self::node_text(bound_node, source)));
+                        constraints.push(Self::type_ref(bound_node, source));
                     }
 
                     params.push(TypeParam {
@@ -127,12 +141,12 @@ impl JavaProcessor {
                 for type_child in child.children(&mut type_cursor) {
                     if type_child.kind() == "type_identifier" || type_child.kind() == "generic_type"
                     {
-                        interfaces.push(TypeRef::new(Self::node_text(type_child, source)));
+                        interfaces.push(Self::type_ref(type_child, source));
                     }
                 }
             } else if child.kind() == "type_identifier" || child.kind() == "generic_type" {
                 // Direct type nodes (for extends_interfaces)
-                interfaces.push(TypeRef::new(Self::node_text(child, source)));
+                interfaces.push(Self::type_ref(child, source));
             }
         }
 
@@ -144,7 +158,8 @@ impl JavaProcessor {
         let mut name = String::new();
         let mut extends = Vec::new();
         let mut implements = Vec::new();
-        let (visibility, modifiers, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers, annotations) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let mut type_params = Vec::new();
         let mut children = Vec::new();
         let line_start = node.start_position().row + 1;
@@ -164,7 +179,7 @@ impl JavaProcessor {
                 }
                 "superclass" => {
                     if let Some(type_node) = child.child_by_field_name("type") {
-                        extends.push(TypeRef::new(Self::node_text(type_node, source)));
+                        extends.push(Self::type_ref(type_node, source));
                     }
                 }
                 "super_interfaces" => {
@@ -180,6 +195,7 @@ impl JavaProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends,
             implements,
             type_params,
@@ -188,6 +204,8 @@ impl JavaProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source, &annotations),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -195,7 +213,8 @@ impl JavaProcessor {
     fn parse_interface(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
         let mut extends = Vec::new();
-        let (visibility, modifiers, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers, annotations) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let mut type_params = Vec::new();
         let mut children = Vec::new();
         let line_start = node.start_position().row + 1;
@@ -226,6 +245,7 @@ impl JavaProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends,
             implements: vec![],
             type_params,
@@ -234,13 +254,28 @@ impl JavaProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source, &annotations),
+            metadata: BTreeMap::new(),
         }))
     }
 
+    /// Parse `package x.y;` into an empty package the file's declarations go into
+    fn parse_package(node: TSNode, source: &str) -> Option<Package> {
+        let mut cursor = node.walk();
+        let name = node
+            .named_children(&mut cursor)
+            .find(|child| matches!(child.kind(), "scoped_identifier" | "identifier"))?;
+        Some(Package {
+            name: Self::node_text(name, source),
+            children: vec![],
+        })
+    }
+
     #[allow(clippy::match_same_arms)]
     fn parse_annotation(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
-        let (visibility, modifiers, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers, annotations) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let mut children = Vec::new();
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
@@ -264,6 +299,7 @@ impl JavaProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends: vec![],
             implements: vec![],
             type_params: vec![],
@@ -272,15 +308,17 @@ impl JavaProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source, &annotations),
+            metadata: BTreeMap::new(),
         }))
     }
 
     #[allow(clippy::match_same_arms)]
     fn parse_enum(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
-        let (visibility, modifiers, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers, annotations) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let mut children = Vec::new();
-        let mut enum_constants = Vec::new();
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
 
@@ -296,13 +334,10 @@ impl JavaProcessor {
                     for body_child in child.children(&mut body_cursor) {
                         match body_child.kind() {
                             "enum_constant" => {
-                                let mut const_cursor = body_child.walk();
-                                for const_child in body_child.children(&mut const_cursor) {
-                                    if const_child.kind() == "identifier" {
-                                        let const_name = Self::node_text(const_child, source);
-                                        enum_constants.push(const_name);
-                                        break;
-                                    }
+                                if let Some(constant) =
+                                    self.parse_enum_constant(body_child, source)?
+                                {
+                                    children.push(ir::Node::EnumVariant(constant));
                                 }
                             }
                             "enum_body_declarations" => {
@@ -341,23 +376,10 @@ impl JavaProcessor {
             }
         }
 
-        for const_name in enum_constants {
-            children.insert(
-                0,
-                ir::Node::Field(Field {
-                    name: const_name,
-                    visibility: Visibility::Public,
-                    field_type: Some(TypeRef::new(name.clone())),
-                    default_value: None,
-                    modifiers: vec![Modifier::Static, Modifier::Final],
-                    line: line_start,
-                }),
-            );
-        }
-
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends: vec![],
             implements: vec![],
             type_params: vec![],
@@ -366,8 +388,46 @@ impl JavaProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source, &annotations),
+            metadata: BTreeMap::new(),
+        }))
+    }
+
+    /// Parse an enum constant with its constructor arguments and body,
+    /// e.g. `MERCURY(3.303e+23, 2.4397e6) { ... }`
+    fn parse_enum_constant(&self, node: TSNode, source: &str) -> Result<Option<EnumVariant>> {
+        let Some(name) = node.child_by_field_name("name") else {
+            return Ok(None);
+        };
+        let (_, _, annotations) = Self::parse_modifiers(node, source);
+
+        let mut arguments = Vec::new();
+        if let Some(argument_list) = node.child_by_field_name("arguments") {
+            let mut cursor = argument_list.walk();
+            for argument in argument_list.named_children(&mut cursor) {
+                if !argument.kind().contains("comment") {
+                    arguments.push(Self::node_text(argument, source));
+                }
+            }
+        }
+
+        let mut children = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            self.parse_class_body(body, source, &mut children)?;
+        }
+
+        Ok(Some(EnumVariant {
+            name: Self::node_text(name, source),
+            fields: vec![],
+            value: None,
+            arguments,
+            children,
+            line: node.start_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source, &annotations),
+            metadata: BTreeMap::new(),
         }))
     }
+
     fn parse_class_body(
         &self,
         node: TSNode,
@@ -451,7 +511,7 @@ impl JavaProcessor {
         for child in node.children(&mut cursor) {
             match child.kind() {
                 "type" | "integral_type" | "floating_point_type" | "boolean_type" => {
-                    return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                    return_type = Some(Self::type_ref(child, source));
                 }
                 "identifier" => {
                     name = Self::node_text(child, source);
@@ -466,6 +526,7 @@ impl JavaProcessor {
             Ok(Some(Function {
                 name,
                 visibility: Visibility::Public,
+                source_visibility: None,
                 parameters: vec![],
                 return_type,
                 decorators: vec![],
@@ -474,6 +535,8 @@ impl JavaProcessor {
                 implementation: None,
                 line_start,
                 line_end,
+                deprecated: Self::parse_deprecation(node, source, &[]),
+                metadata: BTreeMap::new(),
             }))
         }
     }
@@ -481,7 +544,9 @@ impl JavaProcessor {
     #[allow(clippy::match_same_arms)]
     fn parse_field(node: TSNode, source: &str) -> Result<Vec<Field>> {
         let mut fields = Vec::new();
-        let (visibility, modifiers, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers, annotations) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
+        let deprecated = Self::parse_deprecation(node, source, &annotations);
         let mut field_type = None;
         let line = node.start_position().row + 1;
 
@@ -499,7 +564,7 @@ impl JavaProcessor {
                 | "generic_type"
                 | "type_identifier"
                 | "array_type" => {
-                    field_type = Some(TypeRef::new(Self::node_text(child, source)));
+                    field_type = Some(Self::type_ref(child, source));
                 }
                 "variable_declarator" => {
                     if let Some(name_node) = child.child_by_field_name("name") {
@@ -507,10 +572,13 @@ impl JavaProcessor {
                         fields.push(Field {
                             name,
                             visibility,
+                            source_visibility: Some(source_visibility.clone()),
                             field_type: field_type.clone(),
                             default_value: None,
                             modifiers: modifiers.clone(),
                             line,
+                            deprecated: deprecated.clone(),
+                            metadata: BTreeMap::new(),
                         });
                     }
                 }
@@ -525,7 +593,10 @@ impl JavaProcessor {
     #[allow(clippy::match_same_arms)]
     fn parse_method(&self, node: TSNode, source: &str) -> Result<Option<Function>> {
         let mut name = String::new();
-        let (visibility, modifiers, method_decorators) = Self::parse_modifiers(node, source);
+        let (source_visibility, mut modifiers, method_decorators) =
+            Self::parse_modifiers(node, source);
+        let mut metadata = BTreeMap::new();
+        let visibility = source_visibility.coarse();
         let mut type_params = Vec::new();
         let mut return_type = None;
         let mut parameters = Vec::new();
@@ -550,7 +621,7 @@ impl JavaProcessor {
                 | "generic_type"
                 | "type_identifier"
                 | "array_type" => {
-                    return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                    return_type = Some(Self::type_ref(child, source));
                 }
                 "identifier" => {
                     name = Self::node_text(child, source);
@@ -558,6 +629,10 @@ impl JavaProcessor {
                 "formal_parameters" => {
                     parameters = Self::parse_parameters(child, source)?;
                 }
+                "throws" => {
+                    modifiers.push(Modifier::Throws);
+                    metadata.insert("throws".to_string(), Self::parse_throws(child, source));
+                }
                 "marker_annotation" | "annotation" => {
                     // This case probably never executes since annotations are in modifiers
                     decorators.push(Self::node_text(child, source));
@@ -569,9 +644,11 @@ impl JavaProcessor {
         if name.is_empty() {
             Ok(None)
         } else {
+            let deprecated = Self::parse_deprecation(node, source, &decorators);
             Ok(Some(Function {
                 name,
                 visibility,
+                source_visibility: Some(source_visibility),
                 parameters,
                 return_type,
                 decorators,
@@ -580,16 +657,29 @@ impl JavaProcessor {
                 implementation: None,
                 line_start,
                 line_end,
+                deprecated,
+                metadata,
             }))
         }
     }
 
+    /// Exception types of a `throws` clause, comma-separated
+    fn parse_throws(node: TSNode, source: &str) -> String {
+        let mut cursor = node.walk();
+        node.named_children(&mut cursor)
+            .map(|child| Self::node_text(child, source))
+            .collect::<Vec<_>>()
+            .join(",")
+    }
+
     #[allow(clippy::unused_self)]
     #[allow(clippy::match_same_arms)]
     fn parse_constructor(&self, node: TSNode, source: &str) -> Result<Option<Function>> {
         let mut name = String::new();
-        let (visibility, modifiers, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, mut modifiers, annotations) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let mut parameters = Vec::new();
+        let mut metadata = BTreeMap::new();
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
 
@@ -605,6 +695,10 @@ impl JavaProcessor {
                 "formal_parameters" => {
                     parameters = Self::parse_parameters(child, source)?;
                 }
+                "throws" => {
+                    modifiers.push(Modifier::Throws);
+                    metadata.insert("throws".to_string(), Self::parse_throws(child, source));
+                }
                 _ => {}
             }
         }
@@ -615,6 +709,7 @@ impl JavaProcessor {
             Ok(Some(Function {
                 name,
                 visibility,
+                source_visibility: Some(source_visibility),
                 parameters,
                 return_type: None,
                 decorators: vec!["constructor".to_string()],
@@ -623,6 +718,8 @@ impl JavaProcessor {
                 implementation: None,
                 line_start,
                 line_end,
+                deprecated: Self::parse_deprecation(node, source, &annotations),
+                metadata,
             }))
         }
     }
@@ -647,7 +744,7 @@ impl JavaProcessor {
                         | "generic_type"
                         | "type_identifier"
                         | "array_type" => {
-                            param_type = TypeRef::new(Self::node_text(param_child, source));
+                            param_type = Self::type_ref(param_child, source);
                         }
                         "identifier" => {
                             name = Self::node_text(param_child, source);
@@ -718,16 +815,24 @@ impl LanguageProcessor for JavaProcessor {
 
         let root = tree.root_node();
         let mut cursor = root.walk();
+        // Declarations following `package x.y;` are nested in the package
+        let mut package: Option<Package> = None;
 
         for child in root.children(&mut cursor) {
+            if child.kind() == "package_declaration" {
+                package = Self::parse_package(child, source);
+                continue;
+            }
+
+            let children = match &mut package {
+                Some(package) => &mut package.children,
+                None => &mut file.children,
+            };
             match child.kind() {
-                "package_declaration" => {
-                    // Skip package for now
-                }
                 "import_declaration" => {
                     if let Some(import_node) = child.child_by_field_name("name") {
                         let module = Self::node_text(import_node, source);
-                        file.children.push(ir::Node::Import(Import {
+                        children.push(ir::Node::Import(Import {
                             import_type: "import".to_string(),
                             module,
                             symbols: vec![],
@@ -738,27 +843,40 @@ impl LanguageProcessor for JavaProcessor {
                 }
                 "class_declaration" => {
                     if let Some(class) = self.parse_class(child, source)? {
-                        file.children.push(ir::Node::Class(class));
+                        children.push(ir::Node::Class(class));
                     }
                 }
                 "interface_declaration" => {
                     if let Some(interface) = self.parse_interface(child, source)? {
-                        file.children.push(ir::Node::Class(interface));
+                        children.push(ir::Node::Class(interface));
                     }
                 }
                 "annotation_type_declaration" => {
                     if let Some(annotation) = self.parse_annotation(child, source)? {
-                        file.children.push(ir::Node::Class(annotation));
+                        children.push(ir::Node::Class(annotation));
                     }
                 }
                 "enum_declaration" => {
                     if let Some(enum_decl) = self.parse_enum(child, source)? {
-                        file.children.push(ir::Node::Class(enum_decl));
+                        children.push(ir::Node::Class(enum_decl));
                     }
                 }
                 _ => {}
             }
         }
+        if let Some(package) = package {
+            file.children.push(ir::Node::Package(package));
+        }
+
+        // Same-named methods in one scope are overloads
+        group_overloads(&mut file.children);
+
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
 
         Ok(file)
     }
@@ -960,27 +1078,37 @@ public class Visibility {
             assert_eq!(fields[1].visibility, Visibility::Protected);
             assert_eq!(fields[2].visibility, Visibility::Private);
             assert_eq!(fields[3].visibility, Visibility::Internal);
+            assert_eq!(
+                fields[3].source_visibility,
+                Some(SourceVisibility::PackagePrivate)
+            );
         } else {
             panic!("Expected a class");
         }
     }
 
     #[test]
-    fn test_constructor_parsing() {
+    fn test_deprecated_members() {
         let source = r#"
-public class SimpleOOP {
-    public SimpleOOP(String id, String name) {}
-    public SimpleOOP(String id) {}
+public class Client {
+    /**
+     * Fetches a page.
+     * @deprecated Use {@link #fetchAll(int)} instead.
+     */
+    @Deprecated(since = "2.0")
+    public void fetch(int page) {}
+
+    public void fetchAll(int limit) {}
 }
 "#;
         let processor = JavaProcessor::new().unwrap();
         let opts = ProcessOptions::default();
         let file = processor
-            .process(source, &PathBuf::from("SimpleOOP.java"), &opts)
+            .process(source, &PathBuf::from("Client.java"), &opts)
             .unwrap();
 
         if let ir::Node::Class(class) = &file.children[0] {
-            let constructors: Vec<_> = class
+            let methods: Vec<_> = class
                 .children
                 .iter()
                 .filter_map(|n| {
@@ -990,9 +1118,43 @@ public class SimpleOOP {
                         None
                     }
                 })
+                .collect();
+
+            let deprecated = methods[0].deprecated.as_ref().expect("fetch is deprecated");
+            assert_eq!(deprecated.since.as_deref(), Some("2.0"));
+            assert_eq!(deprecated.replacement.as_deref(), Some("fetchAll(int)"));
+            assert!(methods[1].deprecated.is_none());
+        } else {
+            panic!("Expected a class");
+        }
+    }
+
+    #[test]
+    fn test_constructor_parsing() {
+        let source = r#"
+public class SimpleOOP {
+    public SimpleOOP(String id, String name) {}
+    public SimpleOOP(String id) {}
+}
+"#;
+        let processor = JavaProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("SimpleOOP.java"), &opts)
+            .unwrap();
+
+        if let ir::Node::Class(class) = &file.children[0] {
+            // Both constructors are overloads of one name
+            let Some(ir::Node::OverloadSet(overloads)) = class.children.first() else {
+                panic!("Expected an overload set");
+            };
+            let constructors: Vec<_> = overloads
+                .signatures
+                .iter()
                 .filter(|f| f.decorators.contains(&"constructor".to_string()))
                 .collect();
 
+            assert_eq!(overloads.count, 2);
             assert_eq!(constructors.len(), 2);
             assert_eq!(constructors[0].parameters.len(), 2);
             assert_eq!(constructors[1].parameters.len(), 1);
@@ -1329,8 +1491,70 @@ public enum Status {
             .unwrap();
 
         // Note: tree-sitter-java parses enums as enum_declaration, not class_declaration
-        // This test validates that we don't crash on enum parsing
-        assert!(!file.children.is_empty(), "Expected enum to be parsed");
+        assert_eq!(file.children.len(), 1);
+        let ir::Node::Class(status) = &file.children[0] else {
+            panic!("Expected enum to be parsed as a class");
+        };
+        assert!(status.decorators.contains(&"enum".to_string()));
+
+        let constants: Vec<_> = status
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                ir::Node::EnumVariant(constant) => Some(constant.name.as_str()),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(constants, ["ACTIVE", "INACTIVE", "PENDING"]);
+        assert!(
+            status
+                .children
+                .iter()
+                .any(|n| matches!(n, ir::Node::Function(f) if f.name == "isActive"))
+        );
+    }
+
+    #[test]
+    fn test_enum_constant_arguments_and_body() {
+        let source = r#"
+public enum Planet {
+    MERCURY(3.303e+23, 2.4397e6),
+    @Deprecated
+    PLUTO(1.309e+22, 1.188e6) {
+        @Override
+        public String label() { return "dwarf"; }
+    };
+
+    Planet(double mass, double radius) {}
+
+    public String label() { return name(); }
+}
+"#;
+        let processor = JavaProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("Planet.java"), &opts)
+            .unwrap();
+
+        let ir::Node::Class(planet) = &file.children[0] else {
+            panic!("Expected enum to be parsed as a class");
+        };
+        let ir::Node::EnumVariant(mercury) = &planet.children[0] else {
+            panic!("Expected enum constant first");
+        };
+        assert_eq!(mercury.name, "MERCURY");
+        assert_eq!(mercury.arguments, ["3.303e+23", "2.4397e6"]);
+        assert!(mercury.children.is_empty());
+
+        let ir::Node::EnumVariant(pluto) = &planet.children[1] else {
+            panic!("Expected enum constant second");
+        };
+        assert_eq!(pluto.name, "PLUTO");
+        assert!(pluto.deprecated.is_some());
+        assert!(
+            matches!(&pluto.children[0], ir::Node::Function(f) if f.name == "label"),
+            "Expected the constant body to be parsed"
+        );
     }
 
     #[test]
@@ -1420,7 +1644,57 @@ public class Counter {
             assert_eq!(methods.len(), 2);
             assert_eq!(methods[0].name, "increment");
             assert_eq!(methods[1].name, "getCount");
-            // Note: synchronized is not currently tracked in modifiers but parsed correctly
+            assert!(
+                methods
+                    .iter()
+                    .all(|m| m.modifiers.contains(&Modifier::Synchronized))
+            );
+        } else {
+            panic!("Expected class node");
+        }
+    }
+
+    #[test]
+    fn test_throws_clause() {
+        let source = r#"
+public class Loader {
+    public Loader(Path path) throws IOException {}
+
+    public byte[] read(int n) throws IOException, InterruptedException {
+        return null;
+    }
+
+    public void close() {}
+}
+"#;
+        let processor = JavaProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("Loader.java"), &opts)
+            .unwrap();
+
+        if let ir::Node::Class(class) = &file.children[0] {
+            let methods: Vec<_> = class
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    ir::Node::Function(f) => Some(f),
+                    _ => None,
+                })
+                .collect();
+
+            assert_eq!(methods.len(), 3);
+            assert!(methods[0].modifiers.contains(&Modifier::Throws));
+            assert_eq!(
+                methods[0].metadata.get("throws").map(String::as_str),
+                Some("IOException")
+            );
+            assert_eq!(
+                methods[1].metadata.get("throws").map(String::as_str),
+                Some("IOException,InterruptedException")
+            );
+            assert!(!methods[2].modifiers.contains(&Modifier::Throws));
+            assert!(methods[2].metadata.is_empty());
         } else {
             panic!("Expected class node");
         }
@@ -1491,6 +1765,35 @@ public final class Constants {
             panic!("Expected class node");
         }
     }
+
+    #[test]
+    fn test_package_declaration() {
+        let source = r#"
+package com.example.billing;
+
+import java.util.List;
+
+public class Invoice {
+    public List<String> lines() { return null; }
+}
+"#;
+        let processor = JavaProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Invoice.java"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 1);
+        let ir::Node::Package(package) = &file.children[0] else {
+            panic!("Expected package");
+        };
+        assert_eq!(package.name, "com.example.billing");
+        assert_eq!(package.children.len(), 2);
+        assert!(
+            matches!(&package.children[0], ir::Node::Import(i) if i.module == "java.util.List")
+        );
+        assert!(matches!(&package.children[1], ir::Node::Class(c) if c.name == "Invoice"));
+    }
 }
 #[cfg(test)]
 mod debug_tests {
@@ -1,13 +1,16 @@
 use distiller_core::{
     error::{DistilError, Result},
     ir::{
-        Class, File, Function, Import, ImportedSymbol, Modifier, Node, Parameter, TypeRef,
-        Visibility,
+        Class, Deprecation, File, Function, Import, ImportedSymbol, Modifier, Node, Parameter,
+        TypeRef, Variable, VariableKind, Visibility,
     },
     options::ProcessOptions,
-    parser::ParserPool,
+    parser::{
+        ParserPool, comments::preceding_comment, syntax::syntax_error, values::value_preview,
+    },
     processor::language::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 
@@ -34,6 +37,85 @@ impl JavaScriptProcessor {
         source[node.start_byte()..node.end_byte()].to_string()
     }
 
+    /// Detect `@deprecated` JSDoc tags
+    fn parse_deprecation(node: tree_sitter::Node, source: &str) -> Option<Deprecation> {
+        preceding_comment(node, source)
+            .as_deref()
+            .and_then(Deprecation::from_doc_comment)
+    }
+
+    /// Check if a declaration sits at the top level of the module
+    fn is_module_level(node: tree_sitter::Node) -> bool {
+        match node.parent() {
+            Some(parent) if parent.kind() == "export_statement" => parent
+                .parent()
+                .is_some_and(|grandparent| grandparent.kind() == "program"),
+            Some(parent) => parent.kind() == "program",
+            None => false,
+        }
+    }
+
+    /// Parse a `const`/`let`/`var` declaration into one variable per declarator
+    ///
+    /// Declarators holding functions or classes and destructuring patterns
+    /// are skipped.
+    fn parse_variables(node: tree_sitter::Node, source: &str) -> Vec<Variable> {
+        let keyword = node
+            .child(0)
+            .map(|k| Self::node_text(k, source))
+            .unwrap_or_default();
+        let var_kind = match keyword.as_str() {
+            "const" => VariableKind::Const,
+            "let" => VariableKind::Let,
+            _ => VariableKind::Var,
+        };
+        let deprecated = Self::parse_deprecation(node, source);
+
+        let mut variables = Vec::new();
+        let mut cursor = node.walk();
+        for declarator in node.named_children(&mut cursor) {
+            if declarator.kind() != "variable_declarator" {
+                continue;
+            }
+            let Some(name_node) = declarator
+                .child_by_field_name("name")
+                .filter(|n| n.kind() == "identifier")
+            else {
+                continue;
+            };
+            let value = declarator.child_by_field_name("value");
+            if value.is_some_and(|v| {
+                matches!(
+                    v.kind(),
+                    "arrow_function" | "function_expression" | "function" | "class"
+                )
+            }) {
+                continue;
+            }
+
+            let name = Self::node_text(name_node, source);
+            variables.push(Variable {
+                visibility: if name.starts_with('_') {
+                    Visibility::Private
+                } else {
+                    Visibility::Public
+                },
+                name,
+                source_visibility: None,
+                var_kind,
+                modifiers: vec![],
+                var_type: None,
+                value: value.and_then(|v| value_preview(&Self::node_text(v, source))),
+                is_mutable: var_kind != VariableKind::Const,
+                line: declarator.start_position().row + 1,
+                deprecated: deprecated.clone(),
+                metadata: BTreeMap::new(),
+            });
+        }
+
+        variables
+    }
+
     #[allow(clippy::unused_self)]
     fn parse_import(&self, node: tree_sitter::Node, source: &str) -> Result<Option<Import>> {
         let mut module = String::new();
@@ -145,6 +227,7 @@ impl JavaScriptProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: None,
             extends,
             implements: vec![],
             type_params: vec![],
@@ -153,6 +236,8 @@ impl JavaScriptProcessor {
             children: methods.into_iter().map(Node::Function).collect(),
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -161,6 +246,7 @@ impl JavaScriptProcessor {
         let mut parameters = Vec::new();
         let mut is_static = false;
         let mut is_async = false;
+        let mut is_generator = false;
         let mut is_private = false;
 
         let line_start = node.start_position().row + 1;
@@ -190,6 +276,9 @@ impl JavaScriptProcessor {
                 "async" => {
                     is_async = true;
                 }
+                "*" => {
+                    is_generator = true;
+                }
                 _ => {}
             }
         }
@@ -211,10 +300,14 @@ impl JavaScriptProcessor {
         if is_async {
             modifiers.push(Modifier::Async);
         }
+        if is_generator {
+            modifiers.push(Modifier::Generator);
+        }
 
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             parameters,
             return_type: None,
             decorators: vec![],
@@ -223,6 +316,8 @@ impl JavaScriptProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -230,6 +325,7 @@ impl JavaScriptProcessor {
         let mut name = String::new();
         let mut parameters = Vec::new();
         let mut is_async = false;
+        let mut is_generator = false;
 
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
@@ -248,6 +344,9 @@ impl JavaScriptProcessor {
                 "async" => {
                     is_async = true;
                 }
+                "*" => {
+                    is_generator = true;
+                }
                 _ => {}
             }
         }
@@ -266,10 +365,14 @@ impl JavaScriptProcessor {
         if is_async {
             modifiers.push(Modifier::Async);
         }
+        if is_generator {
+            modifiers.push(Modifier::Generator);
+        }
 
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             parameters,
             return_type: None,
             decorators: vec![],
@@ -278,6 +381,8 @@ impl JavaScriptProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -339,6 +444,17 @@ impl JavaScriptProcessor {
                     file.children.push(Node::Function(func));
                 }
             }
+            "lexical_declaration" | "variable_declaration" if Self::is_module_level(node) => {
+                for variable in Self::parse_variables(node, source) {
+                    file.children.push(Node::Variable(variable));
+                }
+
+                // Function and class values are still picked up below
+                let mut cursor = node.walk();
+                for child in node.children(&mut cursor) {
+                    self.process_node(child, source, file)?;
+                }
+            }
             _ => {
                 // Recurse into children
                 let mut cursor = node.walk();
@@ -390,6 +506,13 @@ impl LanguageProcessor for JavaScriptProcessor {
 
         self.process_node(tree.root_node(), source, &mut file)?;
 
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
+
         Ok(file)
     }
 }
@@ -1082,6 +1205,52 @@ class Range {
 
         assert!(functions.iter().any(|f| f.name == "numberGenerator"));
         assert!(functions.iter().any(|f| f.name == "fibonacciGenerator"));
+        assert!(
+            functions
+                .iter()
+                .all(|f| f.modifiers.contains(&Modifier::Generator))
+        );
+    }
+
+    #[test]
+    fn test_module_variables() {
+        let processor = JavaScriptProcessor::new().unwrap();
+        let source = r#"
+export const API_VERSION = "v2";
+let retries = 3, _attempts = 0;
+var legacy;
+const handler = () => {};
+const { a, b } = config;
+
+function setup() {
+    const local = 1;
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("config.js"), &opts)
+            .unwrap();
+
+        let variables: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+
+        let names: Vec<_> = variables.iter().map(|v| v.name.as_str()).collect();
+        assert_eq!(names, ["API_VERSION", "retries", "_attempts", "legacy"]);
+        assert_eq!(variables[0].var_kind, VariableKind::Const);
+        assert!(!variables[0].is_mutable);
+        assert_eq!(variables[0].value.as_deref(), Some("\"v2\""));
+        assert_eq!(variables[1].var_kind, VariableKind::Let);
+        assert!(variables[1].is_mutable);
+        assert_eq!(variables[2].visibility, Visibility::Private);
+        assert_eq!(variables[3].var_kind, VariableKind::Var);
+        assert!(variables[3].value.is_none());
     }
 
     #[test]
@@ -1153,4 +1322,40 @@ export { namedFunction as renamedFunction };
             "Should have exported function"
         );
     }
+
+    #[test]
+    fn test_jsdoc_deprecated() {
+        let processor = JavaScriptProcessor::new().unwrap();
+        let source = r#"
+class Api {
+    /**
+     * @deprecated since 2.0, use fetchAll instead
+     */
+    fetch() {}
+
+    fetchAll() {}
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("test.js"), &opts)
+            .unwrap();
+
+        let Node::Class(class) = &file.children[0] else {
+            panic!("Expected class node");
+        };
+        let methods: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+
+        let deprecated = methods[0].deprecated.as_ref().expect("fetch is deprecated");
+        assert_eq!(deprecated.replacement.as_deref(), Some("fetchAll"));
+        assert!(methods[1].deprecated.is_none());
+    }
 }
@@ -1,10 +1,24 @@
 use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
-    ir::{Class, Field, File, Function, Import, Modifier, Node, Parameter, TypeRef, Visibility},
-    parser::ParserPool,
+    ir::{
+        Accessor, AccessorKind, Class, Deprecation, EnumVariant, Field, File, Function, Import,
+        Modifier, Node, Package, Parameter, Property, SourceVisibility, TypeRef, Variable,
+        VariableKind, Visibility,
+    },
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        overloads::group_overloads,
+        syntax::syntax_error,
+        synthesized::{defines_member, synthesized_method},
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::LanguageProcessor,
+    type_merge::RECEIVER,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -35,8 +49,13 @@ impl KotlinProcessor {
         source[start..end].to_string()
     }
 
-    fn parse_modifiers(node: TSNode, source: &str) -> (Visibility, Vec<Modifier>) {
-        let mut visibility = Visibility::Public; // Kotlin default
+    /// Parse the type written at `node`
+    fn type_ref(node: TSNode, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::Kotlin)
+    }
+
+    fn parse_modifiers(node: TSNode, source: &str) -> (SourceVisibility, Vec<Modifier>) {
+        let mut visibility = SourceVisibility::Public; // Kotlin default
         let mut modifiers = Vec::new();
         let mut cursor = node.walk();
 
@@ -46,19 +65,24 @@ impl KotlinProcessor {
                 for mod_child in child.children(&mut mod_cursor) {
                     let text = Self::node_text(mod_child, source);
                     match text.as_str() {
-                        "public" => visibility = Visibility::Public,
-                        "private" => visibility = Visibility::Private,
-                        "protected" => visibility = Visibility::Protected,
-                        "internal" => visibility = Visibility::Internal,
+                        "public" => visibility = SourceVisibility::Public,
+                        "private" => visibility = SourceVisibility::Private,
+                        "protected" => visibility = SourceVisibility::Protected,
+                        "internal" => visibility = SourceVisibility::Internal,
                         "abstract" => modifiers.push(Modifier::Abstract),
                         "open" => modifiers.push(Modifier::Virtual),
                         "final" => modifiers.push(Modifier::Final),
                         "override" => modifiers.push(Modifier::Override),
-                        "suspend" => modifiers.push(Modifier::Async),
+                        "suspend" => modifiers.push(Modifier::Suspend),
                         "inline" => modifiers.push(Modifier::Inline),
                         "data" => modifiers.push(Modifier::Data),
                         "sealed" => modifiers.push(Modifier::Sealed),
                         "const" => modifiers.push(Modifier::Const),
+                        "operator" => modifiers.push(Modifier::Operator),
+                        "infix" => modifiers.push(Modifier::Infix),
+                        "tailrec" => modifiers.push(Modifier::Tailrec),
+                        "lateinit" => modifiers.push(Modifier::Lateinit),
+                        "external" => modifiers.push(Modifier::Native),
                         _ => {}
                     }
                 }
@@ -68,14 +92,36 @@ impl KotlinProcessor {
         (visibility, modifiers)
     }
 
+    /// Detect `@Deprecated` annotations and KDoc `@deprecated` tags
+    fn parse_deprecation(node: TSNode, source: &str) -> Option<Deprecation> {
+        let mut annotations = Vec::new();
+        let mut cursor = node.walk();
+
+        for child in node.children(&mut cursor) {
+            if child.kind() == "modifiers" {
+                let mut mod_cursor = child.walk();
+                for mod_child in child.children(&mut mod_cursor) {
+                    if mod_child.kind() == "annotation" {
+                        annotations.push(Self::node_text(mod_child, source));
+                    }
+                }
+            }
+        }
+
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(&annotations, doc.as_deref())
+    }
+
     fn parse_class(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
         let extends = Vec::new();
         let implements = Vec::new();
-        let (visibility, modifiers) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let type_params = Vec::new();
-        let decorators = Vec::new();
+        let mut decorators = Vec::new();
         let mut children = Vec::new();
+        let mut constructor_properties = Vec::new();
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
 
@@ -87,9 +133,24 @@ impl KotlinProcessor {
                         name = Self::node_text(child, source);
                     }
                 }
+                "primary_constructor" => {
+                    constructor_properties = Self::parse_constructor_properties(child, source);
+                }
                 "class_body" => {
                     self.parse_class_body(child, source, &mut children)?;
                 }
+                "enum_class_body" => {
+                    decorators.push("enum".to_string());
+                    let mut body_cursor = child.walk();
+                    for entry in child.children(&mut body_cursor) {
+                        if entry.kind() == "enum_entry"
+                            && let Some(variant) = self.parse_enum_entry(entry, source)?
+                        {
+                            children.push(Node::EnumVariant(variant));
+                        }
+                    }
+                    self.parse_class_body(child, source, &mut children)?;
+                }
                 _ => {}
             }
         }
@@ -98,9 +159,28 @@ impl KotlinProcessor {
             return Ok(None);
         }
 
+        if modifiers.contains(&Modifier::Data) {
+            Self::synthesize_data_members(
+                &name,
+                &constructor_properties,
+                &mut children,
+                line_start,
+            );
+        }
+        // Constructor properties go after the entries of an enum class
+        let position = children
+            .iter()
+            .take_while(|child| matches!(child, Node::EnumVariant(_)))
+            .count();
+        children.splice(
+            position..position,
+            constructor_properties.into_iter().map(Node::Field),
+        );
+
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             extends,
             implements,
@@ -109,6 +189,188 @@ impl KotlinProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        }))
+    }
+
+    /// Parse the `val` / `var` parameters of a primary constructor as fields
+    fn parse_constructor_properties(node: TSNode, source: &str) -> Vec<Field> {
+        let mut properties = Vec::new();
+        let mut cursor = node.walk();
+        for parameters in node.children(&mut cursor) {
+            if parameters.kind() != "class_parameters" {
+                continue;
+            }
+            let mut param_cursor = parameters.walk();
+            for param in parameters.children(&mut param_cursor) {
+                if param.kind() != "class_parameter" {
+                    continue;
+                }
+
+                let mut name = String::new();
+                let mut field_type = None;
+                let mut default_value = None;
+                let mut is_property = false;
+                let mut after_assign = false;
+                let mut child_cursor = param.walk();
+                for child in param.children(&mut child_cursor) {
+                    match child.kind() {
+                        "val" | "var" | "binding_pattern_kind" => is_property = true,
+                        "identifier" | "simple_identifier" if name.is_empty() => {
+                            name = Self::node_text(child, source);
+                        }
+                        "user_type" | "nullable_type" | "function_type" => {
+                            field_type = Some(Self::type_ref(child, source));
+                        }
+                        "=" => after_assign = true,
+                        _ if after_assign && child.is_named() && default_value.is_none() => {
+                            default_value = value_preview(&Self::node_text(child, source));
+                        }
+                        _ => {}
+                    }
+                }
+
+                if !is_property || name.is_empty() {
+                    continue;
+                }
+                let (source_visibility, modifiers) = Self::parse_modifiers(param, source);
+                properties.push(Field {
+                    name,
+                    visibility: source_visibility.coarse(),
+                    source_visibility: Some(source_visibility),
+                    field_type,
+                    default_value,
+                    modifiers,
+                    line: param.start_position().row + 1,
+                    deprecated: Self::parse_deprecation(param, source),
+                    metadata: BTreeMap::new(),
+                });
+            }
+        }
+        properties
+    }
+
+    /// Add the members a `data class` generates from its constructor
+    /// properties: `componentN`, `copy`, `equals`, `hashCode` and `toString`
+    ///
+    /// Members the class overrides itself are left alone.
+    fn synthesize_data_members(
+        class_name: &str,
+        properties: &[Field],
+        children: &mut Vec<Node>,
+        line: usize,
+    ) {
+        let property_type = |field: &Field| {
+            field
+                .field_type
+                .clone()
+                .unwrap_or_else(|| TypeRef::new("unknown"))
+        };
+
+        let mut members = Vec::new();
+        for (index, field) in properties.iter().enumerate() {
+            let mut component = synthesized_method(
+                &format!("component{}", index + 1),
+                Visibility::Public,
+                Vec::new(),
+                Some(property_type(field)),
+                line,
+                "data",
+            );
+            component.modifiers.push(Modifier::Operator);
+            members.push(component);
+        }
+
+        let copy_parameters = properties
+            .iter()
+            .map(|field| Parameter {
+                name: field.name.clone(),
+                param_type: property_type(field),
+                default_value: None,
+                is_variadic: false,
+                is_optional: true,
+                decorators: Vec::new(),
+            })
+            .collect();
+        members.push(synthesized_method(
+            "copy",
+            Visibility::Public,
+            copy_parameters,
+            Some(TypeRef::new(class_name)),
+            line,
+            "data",
+        ));
+
+        let other = Parameter {
+            name: "other".to_string(),
+            param_type: TypeRef::new("Any?"),
+            default_value: None,
+            is_variadic: false,
+            is_optional: false,
+            decorators: Vec::new(),
+        };
+        for (name, parameters, return_type) in [
+            ("equals", vec![other], "Boolean"),
+            ("hashCode", Vec::new(), "Int"),
+            ("toString", Vec::new(), "String"),
+        ] {
+            let mut method = synthesized_method(
+                name,
+                Visibility::Public,
+                parameters,
+                Some(TypeRef::new(return_type)),
+                line,
+                "data",
+            );
+            method.modifiers.push(Modifier::Override);
+            members.push(method);
+        }
+
+        for member in members {
+            if !defines_member(children, &member.name) {
+                children.push(Node::Function(member));
+            }
+        }
+    }
+
+    /// Parse an enum entry with its constructor arguments and body,
+    /// e.g. `RED(0xFF0000) { ... }`
+    fn parse_enum_entry(&self, node: TSNode, source: &str) -> Result<Option<EnumVariant>> {
+        let mut name = String::new();
+        let mut arguments = Vec::new();
+        let mut children = Vec::new();
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "identifier" if name.is_empty() => name = Self::node_text(child, source),
+                "value_arguments" => {
+                    let mut arg_cursor = child.walk();
+                    for argument in child.children(&mut arg_cursor) {
+                        if argument.kind() == "value_argument" {
+                            arguments.push(Self::node_text(argument, source));
+                        }
+                    }
+                }
+                "class_body" => self.parse_class_body(child, source, &mut children)?,
+                _ => {}
+            }
+        }
+
+        if name.is_empty() {
+            return Ok(None);
+        }
+
+        Ok(Some(EnumVariant {
+            name,
+            fields: Vec::new(),
+            value: None,
+            arguments,
+            children,
+            line: node.start_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -116,7 +378,8 @@ impl KotlinProcessor {
         let mut name = String::new();
         let extends = Vec::new();
         let implements = Vec::new();
-        let (visibility, modifiers) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let type_params = Vec::new();
         let decorators = vec!["object".to_string()];
         let mut children = Vec::new();
@@ -145,6 +408,7 @@ impl KotlinProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             extends,
             implements,
@@ -153,6 +417,8 @@ impl KotlinProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -166,7 +432,9 @@ impl KotlinProcessor {
                     }
                 }
                 "property_declaration" => {
-                    if let Some(field) = self.parse_property(child, source)? {
+                    if let Some(property) = Self::parse_accessor_property(child, source) {
+                        children.push(Node::Property(property));
+                    } else if let Some(field) = self.parse_property(child, source)? {
                         children.push(Node::Field(field));
                     }
                 }
@@ -190,11 +458,13 @@ impl KotlinProcessor {
         let mut name = String::new();
         let return_type = None;
         let mut parameters = Vec::new();
-        let (visibility, modifiers) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let type_params = Vec::new();
         let decorators = Vec::new();
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
+        let mut metadata = BTreeMap::new();
 
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
@@ -204,6 +474,14 @@ impl KotlinProcessor {
                         name = Self::node_text(child, source);
                     }
                 }
+                // A type before the name is an extension receiver: `fun User.greet()`
+                "user_type" | "nullable_type" if name.is_empty() => {
+                    let receiver = Self::node_text(child, source);
+                    let base = receiver.trim_end_matches('?');
+                    let base = base.split('<').next().unwrap_or(base);
+                    let base = base.rsplit('.').next().unwrap_or(base);
+                    metadata.insert(RECEIVER.to_string(), base.to_string());
+                }
                 "function_value_parameters" => {
                     parameters = self.parse_parameters(child, source);
                 }
@@ -218,6 +496,7 @@ impl KotlinProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             modifiers,
             parameters,
             return_type,
@@ -226,6 +505,8 @@ impl KotlinProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata,
         }))
     }
 
@@ -233,7 +514,8 @@ impl KotlinProcessor {
     fn parse_property(&self, node: TSNode, source: &str) -> Result<Option<Field>> {
         let mut name = String::new();
         let field_type = None;
-        let (visibility, modifiers) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let line = node.start_position().row + 1;
 
         let mut cursor = node.walk();
@@ -255,13 +537,183 @@ impl KotlinProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             field_type,
             default_value: None,
             modifiers,
             line,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
+    /// Parse a property with a custom getter or setter; plain `val`/`var`
+    /// properties stay fields
+    ///
+    /// A property is computed when its getter has a body and there is no
+    /// initializer to back it.
+    fn parse_accessor_property(node: TSNode, source: &str) -> Option<Property> {
+        let mut name = String::new();
+        let mut property_type = None;
+        let mut default_value = None;
+        let mut is_var = false;
+        let mut getter = None;
+        let mut setter = None;
+        let mut after_assign = false;
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "binding_pattern_kind" | "var" | "val" => {
+                    is_var = Self::node_text(child, source) == "var";
+                }
+                "variable_declaration" => {
+                    let mut var_cursor = child.walk();
+                    for var_child in child.named_children(&mut var_cursor) {
+                        match var_child.kind() {
+                            "simple_identifier" | "identifier" if name.is_empty() => {
+                                name = Self::node_text(var_child, source);
+                            }
+                            "user_type" | "nullable_type" | "function_type" => {
+                                property_type = Some(Self::type_ref(var_child, source));
+                            }
+                            _ => {}
+                        }
+                    }
+                }
+                "getter" => getter = Some(child),
+                "setter" => setter = Some(child),
+                "=" => after_assign = true,
+                _ if after_assign && child.is_named() && default_value.is_none() => {
+                    default_value = value_preview(&Self::node_text(child, source));
+                }
+                _ => {}
+            }
+        }
+
+        if name.is_empty() || (getter.is_none() && setter.is_none()) {
+            return None;
+        }
+
+        let mut accessors = vec![Self::parse_accessor(getter, AccessorKind::Get, source)];
+        if is_var || setter.is_some() {
+            accessors.push(Self::parse_accessor(setter, AccessorKind::Set, source));
+        }
+
+        let has_getter_body = getter.is_some_and(|getter| {
+            let mut getter_cursor = getter.walk();
+            getter
+                .children(&mut getter_cursor)
+                .any(|child| child.kind() == "function_body")
+        });
+
+        let (source_visibility, modifiers) = Self::parse_modifiers(node, source);
+        Some(Property {
+            name,
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
+            modifiers,
+            property_type,
+            accessors,
+            is_computed: has_getter_body && default_value.is_none(),
+            default_value,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Accessor of a property; visibility is only kept when the custom
+    /// accessor declares one (`private set`)
+    fn parse_accessor(node: Option<TSNode>, accessor_kind: AccessorKind, source: &str) -> Accessor {
+        let mut accessor = Accessor::new(accessor_kind);
+        let Some(node) = node else {
+            return accessor;
+        };
+
+        let mut cursor = node.walk();
+        let declares_visibility = node.children(&mut cursor).any(|child| {
+            child.kind() == "modifiers"
+                && ["public", "private", "protected", "internal"]
+                    .iter()
+                    .any(|keyword| Self::node_text(child, source).contains(keyword))
+        });
+        if declares_visibility {
+            let (source_visibility, _) = Self::parse_modifiers(node, source);
+            accessor.visibility = Some(source_visibility.coarse());
+            accessor.source_visibility = Some(source_visibility);
+        }
+        accessor
+    }
+
+    /// Parse a top-level `val`/`var`/`const val` property
+    fn parse_top_level_property(node: TSNode, source: &str) -> Option<Variable> {
+        let (source_visibility, modifiers) = Self::parse_modifiers(node, source);
+        let mut name = String::new();
+        let mut var_type = None;
+        let mut value = None;
+        let mut is_var = false;
+        let mut after_assign = false;
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "binding_pattern_kind" | "var" | "val" => {
+                    is_var = Self::node_text(child, source) == "var";
+                }
+                "variable_declaration" => {
+                    let mut var_cursor = child.walk();
+                    for var_child in child.named_children(&mut var_cursor) {
+                        match var_child.kind() {
+                            "simple_identifier" | "identifier" if name.is_empty() => {
+                                name = Self::node_text(var_child, source);
+                            }
+                            "user_type" | "nullable_type" | "function_type" => {
+                                var_type = Some(Self::type_ref(var_child, source));
+                            }
+                            _ => {}
+                        }
+                    }
+                }
+                "=" => after_assign = true,
+                _ if after_assign && child.is_named() && value.is_none() => {
+                    value = value_preview(&Self::node_text(child, source));
+                }
+                _ => {}
+            }
+        }
+
+        if name.is_empty() {
+            return None;
+        }
+
+        let var_kind = if modifiers.contains(&Modifier::Const) {
+            VariableKind::Const
+        } else if is_var {
+            VariableKind::Var
+        } else {
+            VariableKind::Let
+        };
+
+        Some(Variable {
+            name,
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
+            var_kind,
+            modifiers: modifiers
+                .into_iter()
+                .filter(|m| *m != Modifier::Const)
+                .collect(),
+            var_type,
+            value,
+            is_mutable: is_var,
+            line: node.start_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
     #[allow(clippy::unused_self)]
     fn parse_parameters(&self, node: TSNode, source: &str) -> Vec<Parameter> {
         let mut parameters = Vec::new();
@@ -279,7 +731,7 @@ impl KotlinProcessor {
                             name = Self::node_text(param_child, source);
                         }
                         "user_type" => {
-                            param_type = TypeRef::new(Self::node_text(param_child, source));
+                            param_type = Self::type_ref(param_child, source);
                         }
                         _ => {}
                     }
@@ -315,37 +767,49 @@ impl KotlinProcessor {
         })
     }
 
-    fn process_node(&self, node: TSNode, source: &str, file: &mut File) -> Result<()> {
+    /// Parse `package a.b` into an empty package the file's declarations go into
+    fn parse_package(node: TSNode, source: &str) -> Option<Package> {
+        let mut cursor = node.walk();
+        let name = node
+            .named_children(&mut cursor)
+            .find(|child| matches!(child.kind(), "qualified_identifier" | "identifier"))?;
+        Some(Package {
+            name: Self::node_text(name, source),
+            children: Vec::new(),
+        })
+    }
+
+    fn process_node(&self, node: TSNode, source: &str, children: &mut Vec<Node>) -> Result<()> {
         match node.kind() {
             "import_header" => {
                 if let Some(import) = self.parse_import(node, source) {
-                    file.children.push(Node::Import(import));
+                    children.push(Node::Import(import));
                 }
             }
             "class_declaration" => {
                 if let Some(class) = self.parse_class(node, source)? {
-                    file.children.push(Node::Class(class));
+                    children.push(Node::Class(class));
                 }
             }
             "object_declaration" => {
                 if let Some(obj) = self.parse_object(node, source)? {
-                    file.children.push(Node::Class(obj));
+                    children.push(Node::Class(obj));
                 }
             }
             "function_declaration" => {
                 if let Some(func) = self.parse_function(node, source)? {
-                    file.children.push(Node::Function(func));
+                    children.push(Node::Function(func));
                 }
             }
             "property_declaration" => {
-                if let Some(field) = self.parse_property(node, source)? {
-                    file.children.push(Node::Field(field));
+                if let Some(variable) = Self::parse_top_level_property(node, source) {
+                    children.push(Node::Variable(variable));
                 }
             }
             _ => {
                 let mut cursor = node.walk();
                 for child in node.children(&mut cursor) {
-                    self.process_node(child, source, file)?;
+                    self.process_node(child, source, children)?;
                 }
             }
         }
@@ -377,14 +841,42 @@ impl LanguageProcessor for KotlinProcessor {
 
         let tree = parser
             .parse(source, None)
-            .ok_or_else(|| DistilError::parse_error("kotlin", "Failed to parse source"))?;
+            .ok_or_else(|| DistilError::parse_error(path, "Failed to parse source"))?;
 
         let mut file = File {
             path: path.to_string_lossy().to_string(),
             children: Vec::new(),
         };
 
-        self.process_node(tree.root_node(), source, &mut file)?;
+        // Declarations following `package a.b` are nested in the package
+        let root = tree.root_node();
+        let mut package: Option<Package> = None;
+        let mut cursor = root.walk();
+        for child in root.children(&mut cursor) {
+            if child.kind() == "package_header" {
+                package = Self::parse_package(child, source);
+                continue;
+            }
+
+            let children = match &mut package {
+                Some(package) => &mut package.children,
+                None => &mut file.children,
+            };
+            self.process_node(child, source, children)?;
+        }
+        if let Some(package) = package {
+            file.children.push(Node::Package(package));
+        }
+
+        // Same-named functions in one scope are overloads
+        group_overloads(&mut file.children);
+
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
 
         Ok(file)
     }
@@ -393,6 +885,8 @@ impl LanguageProcessor for KotlinProcessor {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use distiller_core::parser::synthesized::SYNTHESIZED;
+    use distiller_core::type_merge::{DECLARED_IN, merge_file};
     use std::path::PathBuf;
 
     #[test]
@@ -435,6 +929,70 @@ data class User(
         assert!(has_user_class, "Expected a User data class");
     }
 
+    #[test]
+    fn test_data_class_members() {
+        let source = r#"
+data class Point(val x: Int, var y: Int = 0, scale: Int = 1) {
+    override fun toString() = "($x, $y)"
+}
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Point.kt"), &opts)
+            .unwrap();
+
+        let Node::Class(point) = &file.children[0] else {
+            panic!("Expected Point class");
+        };
+
+        // Only `val` / `var` parameters are properties
+        let fields: Vec<_> = point
+            .children
+            .iter()
+            .filter_map(|child| match child {
+                Node::Field(field) => Some(field),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(fields.len(), 2);
+        assert_eq!(fields[1].name, "y");
+        assert_eq!(fields[1].default_value.as_deref(), Some("0"));
+
+        let methods: Vec<_> = point
+            .children
+            .iter()
+            .filter_map(|child| match child {
+                Node::Function(func) => Some(func),
+                _ => None,
+            })
+            .collect();
+        let names: Vec<_> = methods.iter().map(|func| func.name.as_str()).collect();
+        // The hand-written toString is kept instead of a generated one
+        assert_eq!(
+            names,
+            [
+                "toString",
+                "component1",
+                "component2",
+                "copy",
+                "equals",
+                "hashCode"
+            ]
+        );
+        let copy = methods[3];
+        assert_eq!(copy.parameters.len(), 2);
+        assert!(copy.parameters.iter().all(|param| param.is_optional));
+        assert_eq!(
+            copy.return_type.as_ref().map(|t| t.name.as_str()),
+            Some("Point")
+        );
+        assert_eq!(
+            copy.metadata.get(SYNTHESIZED).map(String::as_str),
+            Some("data")
+        );
+    }
+
     #[test]
     fn test_sealed_class_parsing() {
         let source = r#"
@@ -484,6 +1042,48 @@ fun String.isValidEmail(): Boolean {
         assert!(has_func, "Expected isValidEmail function");
     }
 
+    #[test]
+    fn test_extension_function_merges_into_class() {
+        let source = r#"
+class User(val email: String)
+
+fun User.isActive(): Boolean = true
+
+fun List<User>?.emails(): List<String> = emptyList()
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let mut file = processor
+            .process(source, &PathBuf::from("User.kt"), &opts)
+            .unwrap();
+
+        let receivers: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|child| match child {
+                Node::Function(func) => func.metadata.get(RECEIVER).map(String::as_str),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(receivers, ["User", "List"]);
+
+        merge_file(&mut file);
+        let Some(Node::Class(user)) = file.children.first() else {
+            panic!("Expected User class");
+        };
+        assert!(user.children.iter().any(|child| matches!(
+            child,
+            Node::Function(func) if func.name == "isActive"
+                && func.metadata.get(DECLARED_IN).map(String::as_str) == Some("User.kt:4")
+        )));
+        // Extensions of types outside the file stay top-level
+        assert!(
+            file.children
+                .iter()
+                .any(|child| matches!(child, Node::Function(func) if func.name == "emails"))
+        );
+    }
+
     #[test]
     fn test_companion_object() {
         let source = r#"
@@ -581,7 +1181,7 @@ suspend fun fetchUser(id: Long): User? {
         assert!(!file.children.is_empty());
         let has_suspend = file.children.iter().any(|child| {
             if let Node::Function(func) = child {
-                func.name == "fetchUser" && func.modifiers.contains(&Modifier::Async)
+                func.name == "fetchUser" && func.modifiers.contains(&Modifier::Suspend)
             } else {
                 false
             }
@@ -799,6 +1399,46 @@ object Singleton {
         }
     }
 
+    #[test]
+    fn test_enum_class_entries() {
+        let source = r#"
+enum class Color(val rgb: Int) {
+    RED(0xFF0000),
+    GREEN(0x00FF00) {
+        override fun label() = "go"
+    };
+
+    open fun label() = name
+}
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Color.kt"), &opts)
+            .unwrap();
+
+        let Node::Class(color) = &file.children[0] else {
+            panic!("Expected class node for enum class");
+        };
+        assert!(color.decorators.contains(&"enum".to_string()));
+
+        let Node::EnumVariant(red) = &color.children[0] else {
+            panic!("Expected enum entry first");
+        };
+        assert_eq!(red.name, "RED");
+        assert_eq!(red.arguments, ["0xFF0000"]);
+
+        let Node::EnumVariant(green) = &color.children[1] else {
+            panic!("Expected enum entry second");
+        };
+        assert!(matches!(&green.children[0], Node::Function(f) if f.name == "label"));
+        assert!(
+            color.children[2..]
+                .iter()
+                .any(|n| matches!(n, Node::Function(f) if f.name == "label"))
+        );
+    }
+
     #[test]
     fn test_properties_with_types() {
         let source = r#"
@@ -840,6 +1480,59 @@ class User {
         }
     }
 
+    #[test]
+    fn test_custom_accessors() {
+        let source = r#"
+class Counter {
+    var count: Int = 0
+        private set
+    val isEmpty: Boolean
+        get() = count == 0
+    val plain: Int = 1
+}
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let file = processor
+            .process(
+                source,
+                &PathBuf::from("Counter.kt"),
+                &ProcessOptions::default(),
+            )
+            .unwrap();
+
+        let Node::Class(class) = &file.children[0] else {
+            panic!("Expected class node");
+        };
+        let properties: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Property(p) => Some(p),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(properties.len(), 2);
+
+        assert_eq!(properties[0].name, "count");
+        assert!(!properties[0].is_computed);
+        assert_eq!(properties[0].accessors.len(), 2);
+        assert_eq!(
+            properties[0].accessors[1].visibility,
+            Some(Visibility::Private)
+        );
+
+        assert_eq!(properties[1].name, "isEmpty");
+        assert!(properties[1].is_computed);
+        assert_eq!(properties[1].accessors.len(), 1);
+
+        assert!(
+            class
+                .children
+                .iter()
+                .any(|n| matches!(n, Node::Field(f) if f.name == "plain"))
+        );
+    }
+
     #[test]
     fn test_inline_function() {
         let source = r#"
@@ -901,6 +1594,43 @@ object MySingleton {}
         );
     }
 
+    #[test]
+    fn test_top_level_properties() {
+        let source = r#"
+const val MAX_RETRIES: Int = 3
+val greeting = "hello"
+internal var counter: Int = 0
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Config.kt"), &opts)
+            .unwrap();
+
+        let vars: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(vars.len(), 3);
+
+        assert_eq!(vars[0].name, "MAX_RETRIES");
+        assert_eq!(vars[0].var_kind, VariableKind::Const);
+        assert_eq!(vars[0].value.as_deref(), Some("3"));
+
+        assert_eq!(vars[1].name, "greeting");
+        assert_eq!(vars[1].var_kind, VariableKind::Let);
+        assert!(!vars[1].is_mutable);
+
+        assert_eq!(vars[2].name, "counter");
+        assert_eq!(vars[2].var_kind, VariableKind::Var);
+        assert_eq!(vars[2].source_visibility, Some(SourceVisibility::Internal));
+        assert!(vars[2].is_mutable);
+    }
+
     #[test]
     fn test_final_override_modifiers() {
         let source = r#"
@@ -976,8 +1706,110 @@ internal class InternalClass {
                 Visibility::Internal,
                 "Expected internal visibility"
             );
+            assert_eq!(class.source_visibility, Some(SourceVisibility::Internal));
+        } else {
+            panic!("Expected class node");
+        }
+    }
+
+    #[test]
+    fn test_deprecated_annotation() {
+        let source = r#"
+class Repo {
+    @Deprecated("Use findAll", ReplaceWith("findAll()"))
+    fun list() {}
+
+    fun findAll() {}
+}
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Repo.kt"), &opts)
+            .unwrap();
+
+        if let Node::Class(class) = &file.children[0] {
+            let functions: Vec<_> = class
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    Node::Function(f) => Some(f),
+                    _ => None,
+                })
+                .collect();
+            let deprecated = functions[0]
+                .deprecated
+                .as_ref()
+                .expect("list is deprecated");
+            assert_eq!(deprecated.message.as_deref(), Some("Use findAll"));
+            assert_eq!(deprecated.replacement.as_deref(), Some("findAll()"));
+            assert!(functions[1].deprecated.is_none());
         } else {
             panic!("Expected class node");
         }
     }
+
+    #[test]
+    fn test_operator_and_infix_modifiers() {
+        let source = r#"
+data class Vec2(val x: Int, val y: Int) {
+    operator fun plus(other: Vec2): Vec2 = Vec2(x + other.x, y + other.y)
+
+    infix fun dot(other: Vec2): Int = x * other.x + y * other.y
+
+    tailrec fun gcd(a: Int, b: Int): Int = if (b == 0) a else gcd(b, a % b)
+}
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Vec2.kt"), &opts)
+            .unwrap();
+
+        if let Node::Class(class) = &file.children[0] {
+            let modifiers_of = |name: &str| {
+                class
+                    .children
+                    .iter()
+                    .find_map(|n| match n {
+                        Node::Function(f) if f.name == name => Some(f.modifiers.clone()),
+                        _ => None,
+                    })
+                    .unwrap_or_default()
+            };
+            assert!(modifiers_of("plus").contains(&Modifier::Operator));
+            assert!(modifiers_of("dot").contains(&Modifier::Infix));
+            assert!(modifiers_of("gcd").contains(&Modifier::Tailrec));
+        } else {
+            panic!("Expected class node");
+        }
+    }
+
+    #[test]
+    fn test_package_header() {
+        let source = r#"
+package com.example.app
+
+import kotlinx.coroutines.flow.Flow
+
+class Repository
+"#;
+        let processor = KotlinProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Repository.kt"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 1);
+        let Node::Package(package) = &file.children[0] else {
+            panic!("Expected package");
+        };
+        assert_eq!(package.name, "com.example.app");
+        assert!(
+            package
+                .children
+                .iter()
+                .any(|n| matches!(n, Node::Class(c) if c.name == "Repository"))
+        );
+    }
 }
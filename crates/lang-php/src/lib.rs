@@ -1,10 +1,20 @@
 use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
-    ir::{Class, Field, File, Function, Import, Node, Parameter, TypeRef, Visibility},
-    parser::ParserPool,
+    ir::{
+        Accessor, AccessorKind, Class, Deprecation, EnumVariant, Field, File, Function, Import,
+        Module, Node, Parameter, Property, TypeRef, Variable, VariableKind, Visibility,
+    },
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        syntax::syntax_error,
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -35,6 +45,34 @@ impl PhpProcessor {
         source[start..end].to_string()
     }
 
+    /// Parse the type written at `node`
+    fn type_ref(node: TSNode, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::Php)
+    }
+
+    /// Detect `#[\Deprecated]` attributes and PHPDoc `@deprecated` tags
+    fn parse_deprecation(node: TSNode, source: &str) -> Option<Deprecation> {
+        let mut attributes = Vec::new();
+        let mut cursor = node.walk();
+
+        for child in node.children(&mut cursor) {
+            if child.kind() == "attribute_list" {
+                let mut group_cursor = child.walk();
+                for group in child.children(&mut group_cursor) {
+                    let mut attr_cursor = group.walk();
+                    for attr in group.children(&mut attr_cursor) {
+                        if attr.kind() == "attribute" {
+                            attributes.push(Self::node_text(attr, source));
+                        }
+                    }
+                }
+            }
+        }
+
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(&attributes, doc.as_deref())
+    }
+
     fn parse_class(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
         let mut extends = Vec::new();
@@ -72,6 +110,7 @@ impl PhpProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             extends,
             implements,
@@ -80,6 +119,8 @@ impl PhpProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -117,6 +158,7 @@ impl PhpProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             extends,
             implements,
@@ -125,6 +167,66 @@ impl PhpProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        }))
+    }
+
+    /// Parse an enum as a class with the `enum` decorator; the backing type
+    /// of a backed enum (`enum Suit: string`) is kept in the metadata
+    fn parse_enum(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
+        let Some(name) = node.child_by_field_name("name") else {
+            return Ok(None);
+        };
+        let mut children = Vec::new();
+        let mut metadata = BTreeMap::new();
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "primitive_type" => {
+                    metadata.insert("backed".to_string(), Self::node_text(child, source));
+                }
+                "enum_declaration_list" => {
+                    let mut body_cursor = child.walk();
+                    for member in child.children(&mut body_cursor) {
+                        if member.kind() == "enum_case"
+                            && let Some(case_name) = member.child_by_field_name("name")
+                        {
+                            children.push(Node::EnumVariant(EnumVariant {
+                                name: Self::node_text(case_name, source),
+                                fields: Vec::new(),
+                                value: member.child_by_field_name("value").and_then(|value| {
+                                    value_preview(&Self::node_text(value, source))
+                                }),
+                                arguments: Vec::new(),
+                                children: Vec::new(),
+                                line: member.start_position().row + 1,
+                                deprecated: Self::parse_deprecation(member, source),
+                                metadata: BTreeMap::new(),
+                            }));
+                        }
+                    }
+                    self.parse_class_body(child, source, &mut children)?;
+                }
+                _ => {}
+            }
+        }
+
+        Ok(Some(Class {
+            name: Self::node_text(name, source),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: Vec::new(),
+            extends: Vec::new(),
+            implements: Vec::new(),
+            type_params: Vec::new(),
+            decorators: vec!["enum".to_string()],
+            children,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata,
         }))
     }
 
@@ -134,7 +236,7 @@ impl PhpProcessor {
 
         for child in node.children(&mut cursor) {
             if child.kind() == "name" {
-                bases.push(TypeRef::new(Self::node_text(child, source)));
+                bases.push(Self::type_ref(child, source));
             }
         }
 
@@ -152,7 +254,9 @@ impl PhpProcessor {
                     }
                 }
                 "property_declaration" => {
-                    if let Some(property) = Self::parse_property(child, source)? {
+                    if let Some(property) = Self::parse_hooked_property(child, source) {
+                        children.push(Node::Property(property));
+                    } else if let Some(property) = Self::parse_property(child, source)? {
                         children.push(Node::Field(property));
                     }
                 }
@@ -191,7 +295,7 @@ impl PhpProcessor {
                 }
                 "primitive_type" | "named_type" | "optional_type" => {
                     if return_type.is_none() {
-                        return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        return_type = Some(Self::type_ref(child, source));
                     }
                 }
                 _ => {}
@@ -205,6 +309,7 @@ impl PhpProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             parameters,
             return_type,
@@ -213,6 +318,8 @@ impl PhpProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -240,7 +347,7 @@ impl PhpProcessor {
                 for param_child in child.children(&mut param_cursor) {
                     match param_child.kind() {
                         "primitive_type" | "named_type" | "optional_type" => {
-                            param_type = TypeRef::new(Self::node_text(param_child, source));
+                            param_type = Self::type_ref(param_child, source);
                         }
                         "variable_name" => {
                             name = Self::node_text(param_child, source);
@@ -280,7 +387,7 @@ impl PhpProcessor {
                 }
                 "primitive_type" | "named_type" | "optional_type" => {
                     if field_type.is_none() {
-                        field_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        field_type = Some(Self::type_ref(child, source));
                     }
                 }
                 "property_element" => {
@@ -302,13 +409,139 @@ impl PhpProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: None,
             field_type,
             default_value: None,
             modifiers,
             line,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
+    /// Parse a PHP 8.4 property with hooks (`{ get => ...; set { ... } }`)
+    /// or asymmetric visibility (`public private(set) string $name`)
+    ///
+    /// The property is computed (virtual) when no hook touches its backing
+    /// value. Plain properties yield `None`.
+    fn parse_hooked_property(node: TSNode, source: &str) -> Option<Property> {
+        let mut name = String::new();
+        let mut property_type = None;
+        let mut default_value = None;
+        let mut visibility = Visibility::Public;
+        let mut set_visibility = None;
+        let mut hooks = None;
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "visibility_modifier" => {
+                    let text = Self::node_text(child, source);
+                    match text.strip_suffix("(set)") {
+                        Some(keyword) => {
+                            set_visibility = Some(match keyword {
+                                "protected" => Visibility::Protected,
+                                "private" => Visibility::Private,
+                                _ => Visibility::Public,
+                            });
+                        }
+                        None => visibility = Self::parse_visibility(child, source),
+                    }
+                }
+                "primitive_type" | "named_type" | "optional_type" => {
+                    if property_type.is_none() {
+                        property_type = Some(Self::type_ref(child, source));
+                    }
+                }
+                "property_element" => {
+                    let mut elem_cursor = child.walk();
+                    for elem_child in child.named_children(&mut elem_cursor) {
+                        match elem_child.kind() {
+                            "variable_name" => name = Self::node_text(elem_child, source),
+                            "property_initializer" => {
+                                default_value = elem_child.named_child(0).and_then(|value| {
+                                    value_preview(&Self::node_text(value, source))
+                                });
+                            }
+                            _ if !name.is_empty() => {
+                                default_value = value_preview(&Self::node_text(elem_child, source));
+                            }
+                            _ => {}
+                        }
+                    }
+                }
+                "property_hook_list" => hooks = Some(child),
+                _ => {}
+            }
+        }
+
+        if name.is_empty() || (hooks.is_none() && set_visibility.is_none()) {
+            return None;
+        }
+
+        let mut accessors = Vec::new();
+        let mut is_computed = false;
+        if let Some(hooks) = hooks {
+            let mut hook_cursor = hooks.walk();
+            for hook in hooks.named_children(&mut hook_cursor) {
+                if hook.kind() != "property_hook" {
+                    continue;
+                }
+                let hook_name = hook
+                    .child_by_field_name("name")
+                    .map(|hook_name| Self::node_text(hook_name, source));
+                match hook_name.as_deref() {
+                    Some("get") => accessors.push(Accessor::new(AccessorKind::Get)),
+                    Some("set") => accessors.push(Accessor::new(AccessorKind::Set)),
+                    _ => {}
+                }
+            }
+
+            let backing = format!("$this->{}", name.trim_start_matches('$'));
+            let hooks_text = Self::node_text(hooks, source);
+            let uses_backing = hooks_text.match_indices(&backing).any(|(at, _)| {
+                !hooks_text[at + backing.len()..]
+                    .starts_with(|c: char| c.is_alphanumeric() || c == '_')
+            });
+            is_computed = !uses_backing && default_value.is_none();
+        }
+
+        if !accessors
+            .iter()
+            .any(|a| a.accessor_kind == AccessorKind::Get)
+        {
+            accessors.insert(0, Accessor::new(AccessorKind::Get));
+        }
+        if let Some(set_visibility) = set_visibility {
+            let position = accessors
+                .iter()
+                .position(|a| a.accessor_kind == AccessorKind::Set);
+            let setter = match position {
+                Some(index) => &mut accessors[index],
+                None => {
+                    accessors.push(Accessor::new(AccessorKind::Set));
+                    accessors.last_mut().expect("setter was just pushed")
+                }
+            };
+            setter.visibility = Some(set_visibility);
+        }
+
+        Some(Property {
+            name,
+            visibility,
+            source_visibility: None,
+            modifiers: Vec::new(),
+            property_type,
+            accessors,
+            is_computed,
+            default_value,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
     fn parse_use(node: TSNode, source: &str) -> Option<Import> {
         let mut module = String::new();
         let mut cursor = node.walk();
@@ -332,33 +565,75 @@ impl PhpProcessor {
         })
     }
 
-    fn process_node(&self, node: TSNode, source: &str, file: &mut File) -> Result<()> {
+    /// Parse a `namespace` into a module
+    ///
+    /// Only the braced form has a body here; statements following
+    /// `namespace X;` are attached by the caller.
+    fn parse_namespace(&self, node: TSNode, source: &str) -> Result<Module> {
+        let mut children = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            self.process_node(body, source, &mut children)?;
+        }
+
+        Ok(Module {
+            name: node
+                .child_by_field_name("name")
+                .map(|name| Self::node_text(name, source))
+                .unwrap_or_default(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            decorators: Vec::new(),
+            children,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    fn process_node(&self, node: TSNode, source: &str, children: &mut Vec<Node>) -> Result<()> {
         match node.kind() {
             "class_declaration" => {
                 if let Some(class) = self.parse_class(node, source)? {
-                    file.children.push(Node::Class(class));
+                    children.push(Node::Class(class));
                 }
             }
             "trait_declaration" => {
                 if let Some(trait_class) = self.parse_trait(node, source)? {
-                    file.children.push(Node::Class(trait_class));
+                    children.push(Node::Class(trait_class));
+                }
+            }
+            "enum_declaration" => {
+                if let Some(enum_class) = self.parse_enum(node, source)? {
+                    children.push(Node::Class(enum_class));
                 }
             }
             "namespace_use_declaration" => {
                 if let Some(import) = Self::parse_use(node, source) {
-                    file.children.push(Node::Import(import));
+                    children.push(Node::Import(import));
                 }
             }
+            "namespace_definition" => {
+                children.push(Node::Module(self.parse_namespace(node, source)?));
+            }
+            "const_declaration" => {
+                // Namespace-level `const X = ...;`
+                children.extend(
+                    Self::parse_constants(node, source)
+                        .into_iter()
+                        .map(Node::Variable),
+                );
+            }
             "function_definition" => {
                 // Top-level functions
                 if let Some(func) = self.parse_top_level_function(node, source)? {
-                    file.children.push(Node::Function(func));
+                    children.push(Node::Function(func));
                 }
             }
             _ => {
                 let mut cursor = node.walk();
                 for child in node.children(&mut cursor) {
-                    self.process_node(child, source, file)?;
+                    self.process_node(child, source, children)?;
                 }
             }
         }
@@ -366,6 +641,48 @@ impl PhpProcessor {
         Ok(())
     }
 
+    /// Parse a namespace-level `const` declaration, one constant per element
+    fn parse_constants(node: TSNode, source: &str) -> Vec<Variable> {
+        let deprecated = Self::parse_deprecation(node, source);
+        let mut constants = Vec::new();
+
+        let mut cursor = node.walk();
+        for element in node.named_children(&mut cursor) {
+            if element.kind() != "const_element" {
+                continue;
+            }
+            let mut name = String::new();
+            let mut value = None;
+            let mut el_cursor = element.walk();
+            for child in element.named_children(&mut el_cursor) {
+                if name.is_empty() && matches!(child.kind(), "name" | "reserved_identifier") {
+                    name = Self::node_text(child, source);
+                } else if !name.is_empty() && value.is_none() {
+                    value = value_preview(&Self::node_text(child, source));
+                }
+            }
+            if name.is_empty() {
+                continue;
+            }
+
+            constants.push(Variable {
+                name,
+                visibility: Visibility::Public,
+                source_visibility: None,
+                var_kind: VariableKind::Const,
+                modifiers: vec![],
+                var_type: None,
+                value,
+                is_mutable: false,
+                line: element.start_position().row + 1,
+                deprecated: deprecated.clone(),
+                metadata: BTreeMap::new(),
+            });
+        }
+
+        constants
+    }
+
     #[allow(clippy::unused_self)]
     fn parse_top_level_function(&self, node: TSNode, source: &str) -> Result<Option<Function>> {
         let mut name = String::new();
@@ -391,7 +708,7 @@ impl PhpProcessor {
                 }
                 "primitive_type" | "named_type" | "optional_type" => {
                     if return_type.is_none() {
-                        return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        return_type = Some(Self::type_ref(child, source));
                     }
                 }
                 _ => {}
@@ -405,6 +722,7 @@ impl PhpProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             parameters,
             return_type,
@@ -413,6 +731,8 @@ impl PhpProcessor {
             line_start,
             line_end,
             implementation: None,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 }
@@ -440,14 +760,45 @@ impl LanguageProcessor for PhpProcessor {
 
         let tree = parser
             .parse(source, None)
-            .ok_or_else(|| DistilError::parse_error("php", "Failed to parse source"))?;
+            .ok_or_else(|| DistilError::parse_error(path, "Failed to parse source"))?;
 
         let mut file = File {
             path: path.to_string_lossy().to_string(),
             children: Vec::new(),
         };
 
-        self.process_node(tree.root_node(), source, &mut file)?;
+        // Statements following `namespace X;` belong to it, up to the next namespace
+        let root = tree.root_node();
+        let mut unbraced: Option<Module> = None;
+        let mut cursor = root.walk();
+        for child in root.children(&mut cursor) {
+            if child.kind() == "namespace_definition" && child.child_by_field_name("body").is_none()
+            {
+                let mut module = self.parse_namespace(child, source)?;
+                module.line_end = root.end_position().row + 1;
+                if let Some(mut previous) = unbraced.replace(module) {
+                    previous.line_end = child.start_position().row;
+                    file.children.push(Node::Module(previous));
+                }
+                continue;
+            }
+
+            let target = match &mut unbraced {
+                Some(module) => &mut module.children,
+                None => &mut file.children,
+            };
+            self.process_node(child, source, target)?;
+        }
+        if let Some(module) = unbraced {
+            file.children.push(Node::Module(module));
+        }
+
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
 
         Ok(file)
     }
@@ -532,6 +883,52 @@ trait Timestampable {
         assert!(has_trait, "Expected Timestampable trait");
     }
 
+    #[test]
+    fn test_backed_enum() {
+        let source = r#"<?php
+enum Suit: string
+{
+    case Hearts = 'H';
+    case Spades = 'S';
+
+    public function color(): string
+    {
+        return 'Red';
+    }
+}
+"#;
+        let processor = PhpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Suit.php"), &opts)
+            .unwrap();
+
+        let Node::Class(suit) = &file.children[0] else {
+            panic!("Expected enum class");
+        };
+        assert_eq!(suit.name, "Suit");
+        assert_eq!(suit.decorators, ["enum"]);
+        assert_eq!(
+            suit.metadata.get("backed").map(String::as_str),
+            Some("string")
+        );
+
+        let cases: Vec<_> = suit
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::EnumVariant(case) => Some((case.name.as_str(), case.value.as_deref())),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(cases, [("Hearts", Some("'H'")), ("Spades", Some("'S'"))]);
+        assert!(
+            suit.children
+                .iter()
+                .any(|n| matches!(n, Node::Function(f) if f.name == "color"))
+        );
+    }
+
     #[test]
     fn test_namespace_and_use() {
         let source = r#"<?php
@@ -550,7 +947,13 @@ class User {
             .process(source, &PathBuf::from("User.php"), &opts)
             .unwrap();
 
-        let import_count = file
+        assert_eq!(file.children.len(), 1);
+        let Node::Module(namespace) = &file.children[0] else {
+            panic!("Expected namespace module");
+        };
+        assert_eq!(namespace.name, "App\\Basic");
+
+        let import_count = namespace
             .children
             .iter()
             .filter(|child| matches!(child, Node::Import(_)))
@@ -593,6 +996,55 @@ class User {
         assert!(has_typed_props, "Expected typed properties");
     }
 
+    #[test]
+    fn test_property_hooks() {
+        let source = r#"<?php
+class User {
+    public string $fullName {
+        get => $this->first . ' ' . $this->last;
+    }
+    public private(set) string $email;
+    public int $id;
+}
+"#;
+        let processor = PhpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("User.php"), &opts)
+            .unwrap();
+
+        let Some(Node::Class(class)) = file.children.first() else {
+            panic!("Expected class node");
+        };
+        let properties: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Property(p) => Some(p),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(properties.len(), 2);
+
+        assert_eq!(properties[0].name, "$fullName");
+        assert!(properties[0].is_computed);
+        assert_eq!(properties[0].accessors.len(), 1);
+
+        assert_eq!(properties[1].name, "$email");
+        assert!(!properties[1].is_computed);
+        assert_eq!(
+            properties[1].accessors[1].visibility,
+            Some(Visibility::Private)
+        );
+
+        assert!(
+            class
+                .children
+                .iter()
+                .any(|n| matches!(n, Node::Field(f) if f.name == "$id"))
+        );
+    }
+
     #[test]
     fn test_visibility_modifiers() {
         let source = r#"<?php
@@ -913,8 +1365,12 @@ class Settings {
             .process(source, Path::new("test.php"), &opts)
             .unwrap();
 
+        let Some(Node::Module(namespace)) = file.children.first() else {
+            panic!("Expected namespace module");
+        };
+
         // Check for imports
-        let imports: Vec<_> = file
+        let imports: Vec<_> = namespace
             .children
             .iter()
             .filter_map(|n| {
@@ -929,7 +1385,7 @@ class Settings {
         assert!(imports.len() >= 2, "Expected at least 2 imports");
 
         // Check for classes
-        let classes: Vec<_> = file
+        let classes: Vec<_> = namespace
             .children
             .iter()
             .filter_map(|n| {
@@ -944,6 +1400,37 @@ class Settings {
         assert!(classes.len() >= 2, "Expected at least 2 classes");
     }
 
+    #[test]
+    fn test_braced_namespaces() {
+        let source = r#"<?php
+namespace App\Http {
+    class Request {}
+}
+
+namespace {
+    function helper() {}
+}
+"#;
+        let processor = PhpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("app.php"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 2);
+        let Node::Module(http) = &file.children[0] else {
+            panic!("Expected App\\Http namespace");
+        };
+        assert_eq!(http.name, "App\\Http");
+        assert!(matches!(&http.children[..], [Node::Class(c)] if c.name == "Request"));
+
+        let Node::Module(global) = &file.children[1] else {
+            panic!("Expected global namespace");
+        };
+        assert!(global.name.is_empty());
+        assert!(matches!(&global.children[..], [Node::Function(f)] if f.name == "helper"));
+    }
+
     #[test]
     fn test_constructor_property_promotion() {
         let source = r#"<?php
@@ -1213,4 +1700,78 @@ class MathHelper {
             panic!("Expected class node");
         }
     }
+
+    #[test]
+    fn test_namespace_constants() {
+        let source = r#"<?php
+namespace App;
+
+const VERSION = "2.1.0", DEBUG = false;
+
+class Config {
+    const DEFAULT_TTL = 60;
+}
+"#;
+        let processor = PhpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("config.php"), &opts)
+            .unwrap();
+
+        let Some(Node::Module(namespace)) = file.children.first() else {
+            panic!("Expected namespace module");
+        };
+        let constants: Vec<_> = namespace
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(constants.len(), 2);
+        assert_eq!(constants[0].name, "VERSION");
+        assert_eq!(constants[0].var_kind, VariableKind::Const);
+        assert_eq!(constants[0].value.as_deref(), Some("\"2.1.0\""));
+        assert_eq!(constants[1].name, "DEBUG");
+        assert_eq!(constants[1].value.as_deref(), Some("false"));
+    }
+
+    #[test]
+    fn test_deprecated_docblock() {
+        let source = r#"<?php
+class Mailer {
+    /**
+     * @deprecated 3.0 Use send() instead.
+     */
+    public function deliver(string $to): void {}
+
+    public function send(string $to): void {}
+}
+"#;
+        let processor = PhpProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("test.php"), &opts)
+            .unwrap();
+
+        let Node::Class(class) = &file.children[0] else {
+            panic!("Expected class node");
+        };
+        let methods: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+
+        let deprecated = methods[0]
+            .deprecated
+            .as_ref()
+            .expect("deliver is deprecated");
+        assert_eq!(deprecated.replacement.as_deref(), Some("send()"));
+        assert!(methods[1].deprecated.is_none());
+    }
 }
@@ -2,21 +2,34 @@
 //!
 //! Parses Python source code into IR nodes, handling:
 //! - Classes and methods
-//! - Functions and decorators
+//! - `@property` getters and their `@x.setter` counterparts
+//! - Enum members of `Enum` subclasses
+//! - Functions and decorators, with `@typing.overload` stubs grouped
 //! - Import statements
-//! - Field assignments
+//! - Field assignments and annotated class attributes
+//! - Members generated by `@dataclass`
+//! - Module-level variables and constants
 //! - Visibility detection (_private, __dunder__)
 
 use distiller_core::{
     error::{DistilError, Result},
     ir::{
-        Class, Field, File, Function, Import, ImportedSymbol, Modifier, Node, Parameter, TypeRef,
+        Accessor, AccessorKind, Class, Deprecation, EnumVariant, Field, File, Function, Import,
+        ImportedSymbol, Modifier, Node, Parameter, Property, TypeRef, Variable, VariableKind,
         Visibility,
     },
     options::ProcessOptions,
-    parser::ParserPool,
+    parser::{
+        ParserPool,
+        overloads::{OVERLOAD_SIGNATURE, group_overloads},
+        syntax::syntax_error,
+        synthesized::{defines_member, synthesized_method},
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::language::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 
@@ -58,6 +71,16 @@ impl PythonProcessor {
         // Process all top-level nodes
         self.process_node(root, source, &mut file)?;
 
+        // `@typing.overload` stubs hide the implementation that follows them
+        group_overloads(&mut file.children);
+
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(filename, source, tree.root_node())
+        {
+            return Err(err);
+        }
+
         Ok(file)
     }
 
@@ -92,6 +115,11 @@ impl PythonProcessor {
                     file.children.push(decorated_node);
                 }
             }
+            "expression_statement" if node.parent().is_some_and(|p| p.kind() == "module") => {
+                if let Some(variable) = self.parse_module_variable(node, source) {
+                    file.children.push(Node::Variable(variable));
+                }
+            }
             _ => {
                 // Recurse into other nodes
                 let mut cursor = node.walk();
@@ -175,6 +203,7 @@ impl PythonProcessor {
         let mut class = Class {
             name: String::new(),
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers: Vec::new(),
             decorators: Vec::new(),
             type_params: Vec::new(),
@@ -183,6 +212,8 @@ impl PythonProcessor {
             children: Vec::new(),
             line_start: node.start_position().row + 1,
             line_end: node.end_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
         };
 
         let mut cursor = node.walk();
@@ -208,6 +239,7 @@ impl PythonProcessor {
         if class.name.is_empty() {
             return Ok(None);
         }
+        class.deprecated = Deprecation::detect(&[], Self::docstring(node, source).as_deref());
 
         Ok(Some(class))
     }
@@ -223,9 +255,24 @@ impl PythonProcessor {
     ) -> Result<()> {
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
-            if child.kind() == "identifier" || child.kind() == "attribute" {
-                let base_name = Self::node_text(child, source);
-                class.extends.push(TypeRef::new(base_name));
+            match child.kind() {
+                "identifier" | "attribute" => {
+                    let base_name = Self::node_text(child, source);
+                    class.extends.push(TypeRef::new(base_name));
+                }
+                // `metaclass=ABCMeta`
+                "keyword_argument" => {
+                    if let (Some(name), Some(value)) = (
+                        child.child_by_field_name("name"),
+                        child.child_by_field_name("value"),
+                    ) && Self::node_text(name, source) == "metaclass"
+                    {
+                        class
+                            .metadata
+                            .insert("metaclass".to_string(), Self::node_text(value, source));
+                    }
+                }
+                _ => {}
             }
         }
         Ok(())
@@ -238,6 +285,7 @@ impl PythonProcessor {
         source: &str,
         class: &mut Class,
     ) -> Result<()> {
+        let is_enum = Self::is_enum_class(class);
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
             match child.kind() {
@@ -250,13 +298,26 @@ impl PythonProcessor {
                 }
                 "decorated_definition" => {
                     // Handle @decorator syntax on methods
-                    if let Some(decorated_node) = self.parse_decorated(child, source)? {
-                        class.children.push(decorated_node);
+                    match self.parse_decorated(child, source)? {
+                        Some(Node::Function(function)) => {
+                            Self::add_method(&mut class.children, function);
+                        }
+                        Some(decorated_node) => class.children.push(decorated_node),
+                        None => {}
+                    }
+                }
+                "expression_statement" if is_enum => {
+                    // Enum members (RED = 1)
+                    if let Some(member) = Self::parse_enum_member(child, source) {
+                        class.children.push(Node::EnumVariant(member));
                     }
                 }
                 "expression_statement" => {
-                    // Parse field assignments (self.field = value)
-                    if let Some(field) = self.parse_field_assignment(child, source)? {
+                    // Annotated class attributes (x: int = 0), then field
+                    // assignments (self.field = value)
+                    if let Some(field) = self.parse_class_attribute(child, source) {
+                        class.children.push(Node::Field(field));
+                    } else if let Some(field) = self.parse_field_assignment(child, source)? {
                         class.children.push(Node::Field(field));
                     }
                 }
@@ -266,6 +327,99 @@ impl PythonProcessor {
         Ok(())
     }
 
+    /// Add a decorated method to a class, folding `@property`, `@x.setter`
+    /// and `@x.deleter` methods into a single property
+    fn add_method(children: &mut Vec<Node>, function: Function) {
+        let is_getter = function.decorators.iter().any(|decorator| {
+            matches!(
+                decorator.as_str(),
+                "@property" | "@cached_property" | "@functools.cached_property"
+            )
+        });
+        if is_getter {
+            let mut metadata = BTreeMap::new();
+            if function
+                .decorators
+                .iter()
+                .any(|decorator| decorator.ends_with("cached_property"))
+            {
+                metadata.insert("cached".to_string(), String::new());
+            }
+            children.push(Node::Property(Property {
+                name: function.name,
+                visibility: function.visibility,
+                source_visibility: None,
+                modifiers: Vec::new(),
+                property_type: function.return_type,
+                accessors: vec![Accessor::new(AccessorKind::Get)],
+                is_computed: true,
+                default_value: None,
+                line_start: function.line_start,
+                line_end: function.line_end,
+                deprecated: function.deprecated,
+                metadata,
+            }));
+            return;
+        }
+
+        let setter = format!("@{}.setter", function.name);
+        let deleter = format!("@{}.deleter", function.name);
+        let property = children.iter_mut().find_map(|node| match node {
+            Node::Property(property) if property.name == function.name => Some(property),
+            _ => None,
+        });
+        if let Some(property) = property {
+            if function.decorators.contains(&setter) {
+                property.accessors.push(Accessor::new(AccessorKind::Set));
+                property.line_end = property.line_end.max(function.line_end);
+                return;
+            }
+            if function.decorators.contains(&deleter) {
+                property.line_end = property.line_end.max(function.line_end);
+                return;
+            }
+        }
+        children.push(Node::Function(function));
+    }
+
+    /// Check if a class derives from one of the `enum` module's base classes
+    fn is_enum_class(class: &Class) -> bool {
+        class.extends.iter().any(|base| {
+            let name = base.name.rsplit('.').next().unwrap_or(&base.name);
+            matches!(
+                name,
+                "Enum" | "IntEnum" | "StrEnum" | "Flag" | "IntFlag" | "ReprEnum"
+            )
+        })
+    }
+
+    /// Parse an enum member assignment, e.g. `RED = 1` or `GREEN = auto()`
+    ///
+    /// Private names and dunders (`_ignore_`, `__slots__`) are not members.
+    fn parse_enum_member(node: tree_sitter::Node, source: &str) -> Option<EnumVariant> {
+        let assignment = node.named_child(0).filter(|n| n.kind() == "assignment")?;
+        let left = assignment
+            .child_by_field_name("left")
+            .filter(|n| n.kind() == "identifier")?;
+        let name = Self::node_text(left, source);
+        if name.starts_with('_') {
+            return None;
+        }
+
+        Some(EnumVariant {
+            name,
+            fields: Vec::new(),
+            value: assignment
+                .child_by_field_name("right")
+                .and_then(|value| value_preview(&Self::node_text(value, source))),
+            arguments: Vec::new(),
+            children: Vec::new(),
+            line: node.start_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
     /// Parse a function/method definition
     fn parse_function(
         &self,
@@ -276,6 +430,7 @@ impl PythonProcessor {
         let mut function = Function {
             name: String::new(),
             visibility,
+            source_visibility: None,
             modifiers: Vec::new(),
             decorators: Vec::new(),
             type_params: Vec::new(),
@@ -284,6 +439,8 @@ impl PythonProcessor {
             implementation: None,
             line_start: node.start_position().row + 1,
             line_end: node.end_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
         };
 
         // Check for async modifier
@@ -308,12 +465,14 @@ impl PythonProcessor {
                 }
                 "type" => {
                     // Return type annotation
-                    let return_type = Self::node_text(child, source);
-                    function.return_type = Some(TypeRef::new(return_type));
+                    function.return_type = Some(Self::type_ref(child, source));
                 }
                 "block" => {
                     // Function body
                     function.implementation = Some(Self::node_text(child, source));
+                    if Self::contains_yield(child) {
+                        function.modifiers.push(Modifier::Generator);
+                    }
                 }
                 _ => {}
             }
@@ -322,10 +481,37 @@ impl PythonProcessor {
         if function.name.is_empty() {
             return Ok(None);
         }
+        function.deprecated = Deprecation::detect(&[], Self::docstring(node, source).as_deref());
 
         Ok(Some(function))
     }
 
+    /// Docstring of a class or function definition, without its quotes
+    fn docstring(definition: tree_sitter::Node, source: &str) -> Option<String> {
+        let first = definition.child_by_field_name("body")?.named_child(0)?;
+        if first.kind() != "expression_statement" {
+            return None;
+        }
+        let string = first.named_child(0).filter(|n| n.kind() == "string")?;
+        let text = Self::node_text(string, source);
+        let text = text.trim_start_matches(|c: char| "rRuUbB".contains(c));
+        let quote = ["\"\"\"", "'''", "\"", "'"]
+            .into_iter()
+            .find(|quote| text.starts_with(quote))?;
+        let inner = &text[quote.len()..];
+        Some(inner.strip_suffix(quote).unwrap_or(inner).to_string())
+    }
+
+    /// Check if a function body yields, ignoring nested functions and classes
+    fn contains_yield(node: tree_sitter::Node) -> bool {
+        let mut cursor = node.walk();
+        node.children(&mut cursor).any(|child| match child.kind() {
+            "yield" => true,
+            "function_definition" | "lambda" | "class_definition" => false,
+            _ => Self::contains_yield(child),
+        })
+    }
+
     /// Parse function parameters
     fn parse_parameters(
         &self,
@@ -379,8 +565,7 @@ impl PythonProcessor {
                             param.name = Self::node_text(child, source);
                         }
                         "type" => {
-                            let type_name = Self::node_text(child, source);
-                            param.param_type = TypeRef::new(type_name);
+                            param.param_type = Self::type_ref(child, source);
                         }
                         _ => {
                             // Could be default value
@@ -428,13 +613,27 @@ impl PythonProcessor {
             match def_node.kind() {
                 "class_definition" => {
                     if let Some(mut class) = self.parse_class(def_node, source)? {
+                        let docstring = Self::docstring(def_node, source);
+                        class.deprecated = Deprecation::detect(&decorators, docstring.as_deref());
                         class.decorators = decorators;
+                        Self::synthesize_dataclass(&mut class);
                         return Ok(Some(Node::Class(class)));
                     }
                 }
                 "function_definition" => {
                     let visibility = self.detect_visibility_from_node(def_node, source);
                     if let Some(mut function) = self.parse_function(def_node, source, visibility)? {
+                        let docstring = Self::docstring(def_node, source);
+                        function.deprecated =
+                            Deprecation::detect(&decorators, docstring.as_deref());
+                        if decorators
+                            .iter()
+                            .any(|d| d == "@overload" || d == "@typing.overload")
+                        {
+                            function
+                                .metadata
+                                .insert(OVERLOAD_SIGNATURE.to_string(), String::new());
+                        }
                         function.decorators = decorators;
                         return Ok(Some(Node::Function(function)));
                     }
@@ -446,6 +645,206 @@ impl PythonProcessor {
         Ok(None)
     }
 
+    /// Parse a module-level assignment (`MAX_RETRIES = 3`, `timeout: float = 2.5`)
+    ///
+    /// `UPPER_CASE` names and `Final` annotations are constants; tuple
+    /// unpacking and attribute assignments are skipped.
+    fn parse_module_variable(&self, node: tree_sitter::Node, source: &str) -> Option<Variable> {
+        let assignment = node.named_child(0).filter(|n| n.kind() == "assignment")?;
+        let target = assignment
+            .child_by_field_name("left")
+            .filter(|n| n.kind() == "identifier")?;
+        let name = Self::node_text(target, source);
+        let var_type = assignment
+            .child_by_field_name("type")
+            .map(|t| Self::type_ref(t, source));
+
+        let is_final = var_type
+            .as_ref()
+            .is_some_and(|t| t.name == "Final" || t.name.starts_with("Final["));
+        let is_constant = is_final
+            || (name.chars().any(char::is_alphabetic)
+                && name
+                    .chars()
+                    .all(|c| c.is_ascii_uppercase() || c.is_ascii_digit() || c == '_'));
+
+        Some(Variable {
+            visibility: self.detect_visibility(&name),
+            name,
+            source_visibility: None,
+            var_kind: if is_constant {
+                VariableKind::Const
+            } else {
+                VariableKind::Var
+            },
+            modifiers: Vec::new(),
+            var_type,
+            value: assignment
+                .child_by_field_name("right")
+                .and_then(|v| value_preview(&Self::node_text(v, source))),
+            is_mutable: !is_constant,
+            line: node.start_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Parse an annotated class attribute (`x: int`, `tags: list[str] = []`)
+    ///
+    /// `ClassVar` annotations mark class-level attributes as static.
+    fn parse_class_attribute(&self, node: tree_sitter::Node, source: &str) -> Option<Field> {
+        let assignment = node.named_child(0).filter(|n| n.kind() == "assignment")?;
+        let target = assignment
+            .child_by_field_name("left")
+            .filter(|n| n.kind() == "identifier")?;
+        let field_type = Self::type_ref(assignment.child_by_field_name("type")?, source);
+        let name = Self::node_text(target, source);
+
+        let is_class_var = field_type.name == "ClassVar"
+            || field_type.name.starts_with("ClassVar[")
+            || field_type.name.starts_with("typing.ClassVar");
+
+        Some(Field {
+            visibility: self.detect_visibility(&name),
+            name,
+            source_visibility: None,
+            modifiers: if is_class_var {
+                vec![Modifier::Static]
+            } else {
+                Vec::new()
+            },
+            field_type: Some(field_type),
+            default_value: assignment
+                .child_by_field_name("right")
+                .and_then(|v| value_preview(&Self::node_text(v, source))),
+            line: node.start_position().row + 1,
+            deprecated: None,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Add the methods `@dataclass` generates to a class
+    ///
+    /// Honors the `init`, `repr`, `eq`, `order`, `frozen` and `unsafe_hash`
+    /// flags. Fields become `__init__` parameters unless they are `ClassVar`
+    /// or declared with `field(init=False)`; methods the class writes out
+    /// itself are left alone.
+    fn synthesize_dataclass(class: &mut Class) {
+        let Some(decorator) = class.decorators.iter().find(|decorator| {
+            let name = decorator.split('(').next().unwrap_or_default();
+            matches!(name, "@dataclass" | "@dataclasses.dataclass")
+        }) else {
+            return;
+        };
+
+        let flags = decorator
+            .split_once('(')
+            .map(|(_, args)| keyword_arguments(args.trim_end_matches(')')))
+            .unwrap_or_default();
+        let flag = |name: &str, default: bool| {
+            flags
+                .get(name)
+                .map_or(default, |value| value.as_str() == "True")
+        };
+        let eq = flag("eq", true);
+
+        let line = class.line_start;
+        let bool_type = || Some(TypeRef::new("bool"));
+        let other = || {
+            vec![Parameter {
+                name: "other".to_string(),
+                param_type: TypeRef::new("object"),
+                default_value: None,
+                is_variadic: false,
+                is_optional: false,
+                decorators: Vec::new(),
+            }]
+        };
+
+        let mut methods = Vec::new();
+        if flag("init", true) {
+            methods.push((
+                "__init__",
+                Self::dataclass_init_parameters(class),
+                Some(TypeRef::new("None")),
+            ));
+        }
+        if flag("repr", true) {
+            methods.push(("__repr__", Vec::new(), Some(TypeRef::new("str"))));
+        }
+        if eq {
+            methods.push(("__eq__", other(), bool_type()));
+        }
+        if flag("order", false) {
+            for name in ["__lt__", "__le__", "__gt__", "__ge__"] {
+                methods.push((name, other(), bool_type()));
+            }
+        }
+        if flag("unsafe_hash", false) || (eq && flag("frozen", false)) {
+            methods.push(("__hash__", Vec::new(), Some(TypeRef::new("int"))));
+        }
+
+        for (name, parameters, return_type) in methods {
+            if !defines_member(&class.children, name) {
+                class.children.push(Node::Function(synthesized_method(
+                    name,
+                    Visibility::Public,
+                    parameters,
+                    return_type,
+                    line,
+                    "dataclass",
+                )));
+            }
+        }
+    }
+
+    /// `__init__` parameters of a dataclass, one per instance field
+    fn dataclass_init_parameters(class: &Class) -> Vec<Parameter> {
+        class
+            .children
+            .iter()
+            .filter_map(|child| match child {
+                Node::Field(field)
+                    if field.field_type.is_some()
+                        && !field.modifiers.contains(&Modifier::Static) =>
+                {
+                    Some(field)
+                }
+                _ => None,
+            })
+            .filter_map(|field| {
+                // `field(default=..., default_factory=..., init=False)`
+                let options = field.default_value.as_deref().and_then(|value| {
+                    let args = value
+                        .strip_prefix("dataclasses.field(")
+                        .or_else(|| value.strip_prefix("field("))?
+                        .strip_suffix(')')?;
+                    Some(keyword_arguments(args))
+                });
+                let default_value = match &options {
+                    Some(options) if options.get("init").is_some_and(|init| init == "False") => {
+                        return None;
+                    }
+                    Some(options) => options.get("default").cloned().or_else(|| {
+                        options
+                            .get("default_factory")
+                            .map(|factory| format!("{factory}()"))
+                    }),
+                    None => field.default_value.clone(),
+                };
+
+                Some(Parameter {
+                    name: field.name.clone(),
+                    param_type: field.field_type.clone()?,
+                    is_optional: default_value.is_some(),
+                    default_value,
+                    is_variadic: false,
+                    decorators: Vec::new(),
+                })
+            })
+            .collect()
+    }
+
     /// Parse field assignment (self.field = value)
     fn parse_field_assignment(
         &self,
@@ -466,10 +865,13 @@ impl PythonProcessor {
             Ok(Some(Field {
                 name: field_name.clone(),
                 visibility: self.detect_visibility(&field_name),
+                source_visibility: None,
                 modifiers: Vec::new(),
                 field_type: None,
                 default_value: None,
                 line: node.start_position().row + 1,
+                deprecated: None,
+                metadata: BTreeMap::new(),
             }))
         } else {
             Ok(None)
@@ -514,6 +916,43 @@ impl PythonProcessor {
         }
         source[start..end].to_string()
     }
+
+    /// Parse the type written at `node`
+    fn type_ref(node: tree_sitter::Node, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::Python)
+    }
+}
+
+/// Split `name=value` call arguments, ignoring positional ones
+///
+/// Commas inside brackets and strings don't split (`default=(1, 2)`).
+fn keyword_arguments(args: &str) -> BTreeMap<String, String> {
+    let mut parts = Vec::new();
+    let mut depth = 0usize;
+    let mut quote = None;
+    let mut start = 0;
+    for (i, c) in args.char_indices() {
+        match (quote, c) {
+            (Some(q), c) if c == q => quote = None,
+            (None, '\'' | '"') => quote = Some(c),
+            (None, '(' | '[' | '{') => depth += 1,
+            (None, ')' | ']' | '}') => depth = depth.saturating_sub(1),
+            (None, ',') if depth == 0 => {
+                parts.push(&args[start..i]);
+                start = i + 1;
+            }
+            _ => {}
+        }
+    }
+    parts.push(&args[start..]);
+
+    parts
+        .into_iter()
+        .filter_map(|part| {
+            let (name, value) = part.split_once('=')?;
+            Some((name.trim().to_string(), value.trim().to_string()))
+        })
+        .collect()
 }
 
 impl Default for PythonProcessor {
@@ -705,6 +1144,67 @@ fn test_async_function() {
     }
 }
 
+#[test]
+fn test_module_variables() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = "MAX_RETRIES = 3\ntimeout: float = 2.5\n_cache = {}\nAPI_URL: Final = \"https://example.com\"\na, b = 1, 2\n\ndef f():\n    LOCAL = 1\n";
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("settings.py"), &opts)
+        .unwrap();
+
+    let variables: Vec<_> = file
+        .children
+        .iter()
+        .filter_map(|n| match n {
+            Node::Variable(v) => Some(v),
+            _ => None,
+        })
+        .collect();
+    assert_eq!(
+        variables.len(),
+        4,
+        "Expected 4 module variables, got {variables:?}"
+    );
+
+    assert_eq!(variables[0].name, "MAX_RETRIES");
+    assert_eq!(variables[0].var_kind, VariableKind::Const);
+    assert_eq!(variables[0].value.as_deref(), Some("3"));
+
+    assert_eq!(variables[1].name, "timeout");
+    assert_eq!(variables[1].var_kind, VariableKind::Var);
+    assert!(variables[1].is_mutable);
+    assert_eq!(variables[1].var_type.as_ref().unwrap().name, "float");
+
+    assert_eq!(variables[2].visibility, Visibility::Protected);
+    assert_eq!(variables[3].var_kind, VariableKind::Const);
+}
+
+#[test]
+fn test_generator_function() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = "def chunks(items):\n    for item in items:\n        yield item\n\ndef outer():\n    def inner():\n        yield 1\n    return inner\n";
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("test.py"), &opts)
+        .unwrap();
+
+    let functions: Vec<_> = file
+        .children
+        .iter()
+        .filter_map(|n| match n {
+            Node::Function(f) => Some(f),
+            _ => None,
+        })
+        .collect();
+    assert_eq!(functions.len(), 2);
+    assert!(functions[0].modifiers.contains(&Modifier::Generator));
+    // A nested generator doesn't make the outer function one
+    assert!(!functions[1].modifiers.contains(&Modifier::Generator));
+}
+
 #[test]
 fn test_decorated_function() {
     let processor = PythonProcessor::new().unwrap();
@@ -745,6 +1245,133 @@ fn test_class_with_inheritance() {
     }
 }
 
+#[test]
+fn test_enum_members() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = "class Color(enum.Enum):\n    _ignore_ = ['tmp']\n    RED = 1\n    GREEN = auto()\n\n    def describe(self):\n        return self.name\n";
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("test.py"), &opts)
+        .unwrap();
+
+    let Node::Class(class) = &file.children[0] else {
+        panic!("Expected class node");
+    };
+    let members: Vec<_> = class
+        .children
+        .iter()
+        .filter_map(|n| match n {
+            Node::EnumVariant(v) => Some((v.name.as_str(), v.value.as_deref())),
+            _ => None,
+        })
+        .collect();
+    assert_eq!(members, [("RED", Some("1")), ("GREEN", Some("auto()"))]);
+    assert!(
+        class
+            .children
+            .iter()
+            .any(|n| matches!(n, Node::Function(f) if f.name == "describe"))
+    );
+}
+
+#[test]
+fn test_property_accessors() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = r#"class Account:
+    @property
+    def balance(self) -> Decimal:
+        return self._balance
+
+    @balance.setter
+    def balance(self, value: Decimal) -> None:
+        self._balance = value
+
+    @property
+    def owner(self) -> str:
+        return self._owner
+
+    @staticmethod
+    def helper() -> str:
+        return "help"
+"#;
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("test.py"), &opts)
+        .unwrap();
+
+    let Node::Class(class) = &file.children[0] else {
+        panic!("Expected class node");
+    };
+    let properties: Vec<_> = class
+        .children
+        .iter()
+        .filter_map(|n| match n {
+            Node::Property(p) => Some(p),
+            _ => None,
+        })
+        .collect();
+    assert_eq!(properties.len(), 2);
+
+    assert_eq!(properties[0].name, "balance");
+    assert!(properties[0].is_computed);
+    assert_eq!(
+        properties[0]
+            .property_type
+            .as_ref()
+            .map(|t| t.name.as_str()),
+        Some("Decimal")
+    );
+    assert_eq!(properties[0].accessors.len(), 2);
+    assert_eq!(properties[1].name, "owner");
+    assert_eq!(properties[1].accessors.len(), 1);
+
+    let functions: Vec<_> = class
+        .children
+        .iter()
+        .filter(|n| matches!(n, Node::Function(_)))
+        .collect();
+    assert_eq!(functions.len(), 1);
+}
+
+#[test]
+fn test_typing_overload_stubs() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = r#"from typing import overload
+
+@overload
+def load(path: str) -> bytes: ...
+@overload
+def load(path: str, encoding: str) -> str: ...
+def load(path, encoding=None):
+    return read(path, encoding)
+"#;
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("test.py"), &opts)
+        .unwrap();
+
+    let sets: Vec<_> = file
+        .children
+        .iter()
+        .filter_map(|n| match n {
+            Node::OverloadSet(set) => Some(set),
+            _ => None,
+        })
+        .collect();
+    assert_eq!(sets.len(), 1);
+    assert_eq!(sets[0].name, "load");
+    assert_eq!(sets[0].count, 2);
+    assert!(
+        !file
+            .children
+            .iter()
+            .any(|n| matches!(n, Node::Function(f) if f.name == "load"))
+    );
+}
+
 #[test]
 fn test_class_with_multiple_inheritance() {
     let processor = PythonProcessor::new().unwrap();
@@ -854,6 +1481,101 @@ fn test_decorated_class() {
     }
 }
 
+#[test]
+fn test_dataclass_members() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = r#"
+@dataclass(order=True, frozen=True)
+class Version:
+    major: int
+    minor: int = 0
+    tags: list[str] = field(default_factory=list)
+    cache: dict = field(init=False, repr=False)
+    registry: ClassVar[dict] = {}
+
+    def __repr__(self) -> str:
+        return f"{self.major}.{self.minor}"
+"#;
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("version.py"), &opts)
+        .unwrap();
+    let Node::Class(class) = &file.children[0] else {
+        panic!("Expected class node");
+    };
+
+    let fields: Vec<_> = class
+        .children
+        .iter()
+        .filter_map(|n| match n {
+            Node::Field(f) => Some(f),
+            _ => None,
+        })
+        .collect();
+    assert_eq!(fields.len(), 5);
+    assert_eq!(fields[1].default_value.as_deref(), Some("0"));
+    assert_eq!(fields[4].modifiers, [Modifier::Static]);
+
+    let methods: Vec<_> = class
+        .children
+        .iter()
+        .filter_map(|n| match n {
+            Node::Function(f) => Some(f),
+            _ => None,
+        })
+        .collect();
+    let names: Vec<_> = methods.iter().map(|f| f.name.as_str()).collect();
+    // The hand-written __repr__ wins; frozen + eq adds __hash__
+    assert_eq!(
+        names,
+        [
+            "__repr__", "__init__", "__eq__", "__lt__", "__le__", "__gt__", "__ge__", "__hash__"
+        ]
+    );
+    assert!(methods[0].metadata.is_empty());
+
+    let init = methods[1];
+    assert_eq!(
+        init.metadata
+            .get(distiller_core::parser::synthesized::SYNTHESIZED)
+            .map(String::as_str),
+        Some("dataclass")
+    );
+    let params: Vec<_> = init
+        .parameters
+        .iter()
+        .map(|p| (p.name.as_str(), p.default_value.as_deref()))
+        .collect();
+    assert_eq!(
+        params,
+        [
+            ("major", None),
+            ("minor", Some("0")),
+            ("tags", Some("list()"))
+        ]
+    );
+}
+
+#[test]
+fn test_metaclass_keyword() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = "class Plugin(Base, metaclass=PluginMeta):\n    pass";
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("test.py"), &opts)
+        .unwrap();
+    let Node::Class(class) = &file.children[0] else {
+        panic!("Expected class node");
+    };
+    assert_eq!(class.extends.len(), 1);
+    assert_eq!(
+        class.metadata.get("metaclass").map(String::as_str),
+        Some("PluginMeta")
+    );
+}
+
 #[test]
 fn test_function_with_typed_parameters() {
     let processor = PythonProcessor::new().unwrap();
@@ -890,6 +1612,69 @@ fn test_function_with_typed_parameters() {
     }
 }
 
+#[test]
+fn test_deprecated_decorator() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = "@deprecated(\"Use fetch_all() instead\")\ndef fetch():\n    pass";
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("test.py"), &opts)
+        .unwrap();
+
+    if let Node::Function(func) = &file.children[0] {
+        let deprecated = func.deprecated.as_ref().expect("deprecated");
+        assert_eq!(
+            deprecated.message.as_deref(),
+            Some("Use fetch_all() instead")
+        );
+        assert_eq!(deprecated.replacement.as_deref(), Some("fetch_all()"));
+    } else {
+        panic!("Expected function node");
+    }
+}
+
+#[test]
+fn test_deprecated_docstring() {
+    let processor = PythonProcessor::new().unwrap();
+    let source = r#"def fetch():
+    """Fetch one row.
+
+    .. deprecated:: 2.1
+       Use fetch_all() instead.
+    """
+
+def load():
+    """Deprecated: use read() instead."""
+
+class Store:
+    '''Row storage.'''
+"#;
+    let opts = ProcessOptions::default();
+
+    let file = processor
+        .process(source, Path::new("store.py"), &opts)
+        .unwrap();
+
+    let Node::Function(fetch) = &file.children[0] else {
+        panic!("Expected function node");
+    };
+    let deprecated = fetch.deprecated.as_ref().expect("fetch is deprecated");
+    assert_eq!(deprecated.since.as_deref(), Some("2.1"));
+    assert_eq!(deprecated.replacement.as_deref(), Some("fetch_all()"));
+
+    let Node::Function(load) = &file.children[1] else {
+        panic!("Expected function node");
+    };
+    let deprecated = load.deprecated.as_ref().expect("load is deprecated");
+    assert_eq!(deprecated.replacement.as_deref(), Some("read()"));
+
+    let Node::Class(store) = &file.children[2] else {
+        panic!("Expected class node");
+    };
+    assert!(store.deprecated.is_none());
+}
+
 #[test]
 fn test_multiple_decorators() {
     let processor = PythonProcessor::new().unwrap();
@@ -1184,6 +1969,29 @@ fn test_malformed_python() {
     }
 }
 
+#[test]
+fn test_unrecoverable_python_reports_location() {
+    let processor = PythonProcessor::new().unwrap();
+    let opts = ProcessOptions::default();
+
+    let err = processor
+        .process("# broken\n)))\n", Path::new("broken.py"), &opts)
+        .unwrap_err();
+
+    let DistilError::Parse {
+        path,
+        span,
+        snippet,
+        ..
+    } = err
+    else {
+        panic!("expected a parse error, got {err:?}");
+    };
+    assert_eq!(path, Path::new("broken.py"));
+    assert_eq!(span.expect("span of the ERROR node").line, 2);
+    assert!(snippet.expect("rendered snippet").contains(")))"));
+}
+
 #[test]
 fn test_unicode_python() {
     let manifest_dir = env!("CARGO_MANIFEST_DIR");
@@ -1,10 +1,20 @@
 use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
-    ir::{self, Class, File, Function, Parameter, TypeRef, Visibility},
-    parser::ParserPool,
+    ir::{
+        self, Accessor, AccessorKind, Class, Deprecation, File, Function, Module, Parameter,
+        Property, TypeRef, Variable, VariableKind, Visibility,
+    },
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        syntax::syntax_error,
+        synthesized::{SYNTHESIZED, synthesized_method},
+        values::value_preview,
+    },
     processor::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -32,6 +42,13 @@ impl RubyProcessor {
         source[node.start_byte()..node.end_byte()].to_string()
     }
 
+    /// Detect YARD `@deprecated` tags
+    fn parse_deprecation(node: TSNode, source: &str) -> Option<Deprecation> {
+        preceding_comment(node, source)
+            .as_deref()
+            .and_then(Deprecation::from_doc_comment)
+    }
+
     fn parse_visibility(node: TSNode, source: &str) -> Visibility {
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
@@ -96,6 +113,7 @@ impl RubyProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: None,
             extends,
             implements: vec![],
             type_params: vec![],
@@ -104,45 +122,37 @@ impl RubyProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
-    fn parse_module(&self, node: TSNode, source: &str) -> Result<Option<Class>> {
-        // Ruby modules are similar to classes
-        let mut name = String::new();
+    /// Parse a `module` into a module node holding its body
+    ///
+    /// Compact names (`module Billing::Tax`) are kept as written.
+    fn parse_module(&self, node: TSNode, source: &str) -> Result<Module> {
         let mut children = Vec::new();
-        let visibility = Self::parse_visibility(node, source);
-
-        let line_start = node.start_position().row + 1;
-        let line_end = node.end_position().row + 1;
-
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
-            match child.kind() {
-                "constant" => {
-                    if name.is_empty() {
-                        name = Self::node_text(child, source);
-                    }
-                }
-                "body_statement" => {
-                    self.parse_body(child, source, &mut children)?;
-                }
-                _ => {}
+            if child.kind() == "body_statement" {
+                self.parse_body(child, source, &mut children)?;
             }
         }
 
-        Ok(Some(Class {
-            name,
-            visibility,
-            extends: vec![],
-            implements: vec![],
-            type_params: vec![],
-            decorators: vec!["module".to_string()],
-            modifiers: vec![],
+        Ok(Module {
+            name: node
+                .child_by_field_name("name")
+                .map(|name| Self::node_text(name, source))
+                .unwrap_or_default(),
+            visibility: Self::parse_visibility(node, source),
+            source_visibility: None,
+            decorators: vec![],
             children,
-            line_start,
-            line_end,
-        }))
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
     }
 
     #[allow(clippy::unused_self)]
@@ -175,6 +185,7 @@ impl RubyProcessor {
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             parameters,
             return_type: None,
             decorators: vec![],
@@ -183,6 +194,8 @@ impl RubyProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -230,6 +243,180 @@ impl RubyProcessor {
         Ok(())
     }
 
+    /// Parse a top-level `CONSTANT = ...` or `$global = ...` assignment
+    fn parse_assignment(node: TSNode, source: &str) -> Option<Variable> {
+        let left = node.child_by_field_name("left")?;
+        let var_kind = match left.kind() {
+            "constant" => VariableKind::Const,
+            "global_variable" => VariableKind::Var,
+            _ => return None,
+        };
+
+        Some(Variable {
+            name: Self::node_text(left, source),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            var_kind,
+            modifiers: vec![],
+            var_type: None,
+            value: node
+                .child_by_field_name("right")
+                .and_then(|right| value_preview(&Self::node_text(right, source))),
+            is_mutable: var_kind == VariableKind::Var,
+            line: node.start_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Name held by a symbol, string or hash key (`:name`, `"name"`, `to:`)
+    fn symbol_name(node: TSNode, source: &str) -> String {
+        Self::node_text(node, source)
+            .trim_start_matches(':')
+            .trim_end_matches(':')
+            .trim_matches(['"', '\''])
+            .to_string()
+    }
+
+    /// Members generated by a class-body macro call
+    ///
+    /// `attr_reader`, `attr_writer` and `attr_accessor` become properties;
+    /// `define_method(:name)` and Rails' `delegate :a, to: :b` become
+    /// methods. Other calls generate nothing.
+    fn parse_generator_call(node: TSNode, source: &str) -> Result<Vec<ir::Node>> {
+        if node.child_by_field_name("receiver").is_some() {
+            return Ok(Vec::new());
+        }
+        let Some(method) = node.child_by_field_name("method") else {
+            return Ok(Vec::new());
+        };
+        let generator = Self::node_text(method, source);
+        let line = node.start_position().row + 1;
+
+        let mut names = Vec::new();
+        let mut options = BTreeMap::new();
+        if let Some(arguments) = node.child_by_field_name("arguments") {
+            let mut cursor = arguments.walk();
+            for argument in arguments.named_children(&mut cursor) {
+                match argument.kind() {
+                    "simple_symbol" | "string" => names.push(Self::symbol_name(argument, source)),
+                    "pair" => {
+                        if let (Some(key), Some(value)) = (
+                            argument.child_by_field_name("key"),
+                            argument.child_by_field_name("value"),
+                        ) {
+                            options.insert(
+                                Self::symbol_name(key, source),
+                                Self::symbol_name(value, source),
+                            );
+                        }
+                    }
+                    _ => {}
+                }
+            }
+        }
+
+        let members = match generator.as_str() {
+            "attr_reader" | "attr_writer" | "attr_accessor" => {
+                Self::attribute_properties(&generator, names, line)
+            }
+            "define_method" => {
+                let Some(name) = names.first() else {
+                    return Ok(Vec::new());
+                };
+                let mut parameters = Vec::new();
+                if let Some(block) = node.child_by_field_name("block") {
+                    let mut cursor = block.walk();
+                    for child in block.children(&mut cursor) {
+                        if child.kind() == "block_parameters" {
+                            // Block parameters use the same node kinds as method parameters
+                            Self::parse_parameters(child, source, &mut parameters)?;
+                        }
+                    }
+                }
+                vec![ir::Node::Function(synthesized_method(
+                    name,
+                    Visibility::Public,
+                    parameters,
+                    None,
+                    line,
+                    &generator,
+                ))]
+            }
+            "delegate" => Self::delegated_methods(names, &options, line),
+            _ => Vec::new(),
+        };
+        Ok(members)
+    }
+
+    /// Properties created by `attr_reader`, `attr_writer` or `attr_accessor`
+    fn attribute_properties(generator: &str, names: Vec<String>, line: usize) -> Vec<ir::Node> {
+        let accessors = match generator {
+            "attr_reader" => vec![Accessor::new(AccessorKind::Get)],
+            "attr_writer" => vec![Accessor::new(AccessorKind::Set)],
+            _ => vec![
+                Accessor::new(AccessorKind::Get),
+                Accessor::new(AccessorKind::Set),
+            ],
+        };
+
+        names
+            .into_iter()
+            .map(|name| {
+                ir::Node::Property(Property {
+                    name,
+                    visibility: Visibility::Public,
+                    source_visibility: None,
+                    modifiers: vec![],
+                    property_type: None,
+                    accessors: accessors.clone(),
+                    is_computed: false,
+                    default_value: None,
+                    line_start: line,
+                    line_end: line,
+                    deprecated: None,
+                    metadata: BTreeMap::from([(SYNTHESIZED.to_string(), generator.to_string())]),
+                })
+            })
+            .collect()
+    }
+
+    /// Methods forwarded by `delegate :a, :b, to: :target`
+    ///
+    /// `prefix: true` names them after the target, `prefix: :x` after x.
+    fn delegated_methods(
+        names: Vec<String>,
+        options: &BTreeMap<String, String>,
+        line: usize,
+    ) -> Vec<ir::Node> {
+        let Some(target) = options.get("to") else {
+            return Vec::new();
+        };
+        let prefix = match options.get("prefix").map(String::as_str) {
+            Some("true") => format!("{target}_"),
+            Some("false") | None => String::new(),
+            Some(prefix) => format!("{prefix}_"),
+        };
+
+        names
+            .into_iter()
+            .map(|name| {
+                let mut method = synthesized_method(
+                    &format!("{prefix}{name}"),
+                    Visibility::Public,
+                    vec![],
+                    None,
+                    line,
+                    "delegate",
+                );
+                method
+                    .metadata
+                    .insert("delegate_to".to_string(), target.clone());
+                ir::Node::Function(method)
+            })
+            .collect()
+    }
+
     fn parse_body(&self, node: TSNode, source: &str, children: &mut Vec<ir::Node>) -> Result<()> {
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
@@ -245,10 +432,9 @@ impl RubyProcessor {
                     }
                 }
                 "module" => {
-                    if let Some(module) = self.parse_module(child, source)? {
-                        children.push(ir::Node::Class(module));
-                    }
+                    children.push(ir::Node::Module(self.parse_module(child, source)?));
                 }
+                "call" => children.extend(Self::parse_generator_call(child, source)?),
                 _ => {}
             }
         }
@@ -304,19 +490,30 @@ impl LanguageProcessor for RubyProcessor {
                     }
                 }
                 "module" => {
-                    if let Some(module) = self.parse_module(child, source)? {
-                        file.children.push(ir::Node::Class(module));
-                    }
+                    file.children
+                        .push(ir::Node::Module(self.parse_module(child, source)?));
                 }
                 "method" | "singleton_method" => {
                     if let Some(method) = self.parse_method(child, source)? {
                         file.children.push(ir::Node::Function(method));
                     }
                 }
+                "assignment" => {
+                    if let Some(variable) = Self::parse_assignment(child, source) {
+                        file.children.push(ir::Node::Variable(variable));
+                    }
+                }
                 _ => {}
             }
         }
 
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
+
         Ok(file)
     }
 }
@@ -403,9 +600,8 @@ end
         let file = result.unwrap();
         assert_eq!(file.children.len(), 1);
 
-        if let ir::Node::Class(module) = &file.children[0] {
+        if let ir::Node::Module(module) = &file.children[0] {
             assert_eq!(module.name, "Utilities");
-            assert!(module.decorators.contains(&"module".to_string()));
             assert_eq!(module.children.len(), 2);
         } else {
             panic!("Expected a module");
@@ -549,13 +745,91 @@ end
 
         if let ir::Node::Class(class) = &file.children[0] {
             assert_eq!(class.name, "Person");
-            // At minimum, should have initialize method
-            assert!(!class.children.is_empty());
+            assert_eq!(class.children.len(), 5);
+
+            let properties: Vec<_> = class
+                .children
+                .iter()
+                .filter_map(|n| {
+                    if let ir::Node::Property(p) = n {
+                        Some(p)
+                    } else {
+                        None
+                    }
+                })
+                .collect();
+
+            let names: Vec<_> = properties.iter().map(|p| p.name.as_str()).collect();
+            assert_eq!(names, ["name", "age", "id", "email"]);
+            assert_eq!(properties[0].accessors.len(), 2);
+            assert_eq!(properties[2].accessors, [Accessor::new(AccessorKind::Get)]);
+            assert_eq!(properties[3].accessors, [Accessor::new(AccessorKind::Set)]);
+            assert_eq!(
+                properties[0].metadata.get(SYNTHESIZED).map(String::as_str),
+                Some("attr_accessor")
+            );
+            assert!(matches!(&class.children[4], ir::Node::Function(f) if f.name == "initialize"));
         } else {
             panic!("Expected a class");
         }
     }
 
+    #[test]
+    fn test_define_method_and_delegate() {
+        let source = r##"
+class Order
+  delegate :name, :email, to: :customer
+  delegate :total, to: :invoice, prefix: true
+
+  %w[draft paid].each do |state|
+    define_method(:"#{state}?") { status == state }
+  end
+
+  define_method(:ship) do |carrier, tracking|
+    update(carrier: carrier, tracking: tracking)
+  end
+end
+"##;
+        let processor = RubyProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("order.rb"), &opts)
+            .unwrap();
+
+        let ir::Node::Class(class) = &file.children[0] else {
+            panic!("Expected a class");
+        };
+        let methods: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| {
+                if let ir::Node::Function(f) = n {
+                    Some(f)
+                } else {
+                    None
+                }
+            })
+            .collect();
+
+        // Interpolated names inside a loop can't be known statically
+        let names: Vec<_> = methods.iter().map(|f| f.name.as_str()).collect();
+        assert_eq!(names, ["name", "email", "invoice_total", "ship"]);
+        assert_eq!(
+            methods[0].metadata.get("delegate_to").map(String::as_str),
+            Some("customer")
+        );
+        assert_eq!(
+            methods[3].metadata.get(SYNTHESIZED).map(String::as_str),
+            Some("define_method")
+        );
+        let params: Vec<_> = methods[3]
+            .parameters
+            .iter()
+            .map(|p| p.name.as_str())
+            .collect();
+        assert_eq!(params, ["carrier", "tracking"]);
+    }
+
     #[test]
     fn test_block_syntax() {
         let source = r#"
@@ -646,9 +920,8 @@ end
         assert_eq!(file.children.len(), 2);
 
         // Check module
-        if let ir::Node::Class(module) = &file.children[0] {
+        if let ir::Node::Module(module) = &file.children[0] {
             assert_eq!(module.name, "Loggable");
-            assert!(module.decorators.contains(&"module".to_string()));
         } else {
             panic!("Expected a module");
         }
@@ -661,6 +934,35 @@ end
         }
     }
 
+    #[test]
+    fn test_nested_modules() {
+        let source = r#"
+module Billing
+  module Tax::Rules
+    class Rate
+      def amount; end
+    end
+  end
+end
+"#;
+        let processor = RubyProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("billing.rb"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 1);
+        let ir::Node::Module(billing) = &file.children[0] else {
+            panic!("Expected Billing module");
+        };
+        assert_eq!(billing.name, "Billing");
+        let ir::Node::Module(rules) = &billing.children[0] else {
+            panic!("Expected nested module");
+        };
+        assert_eq!(rules.name, "Tax::Rules");
+        assert!(matches!(&rules.children[0], ir::Node::Class(c) if c.name == "Rate"));
+    }
+
     #[test]
     fn test_visibility_keywords() {
         let source = r#"
@@ -855,12 +1157,8 @@ end
             .children
             .iter()
             .filter_map(|n| {
-                if let ir::Node::Class(c) = n {
-                    if c.decorators.contains(&"module".to_string()) {
-                        Some(c)
-                    } else {
-                        None
-                    }
+                if let ir::Node::Module(m) = n {
+                    Some(m)
                 } else {
                     None
                 }
@@ -938,4 +1236,73 @@ end
             panic!("Expected a class");
         }
     }
+
+    #[test]
+    fn test_top_level_constants() {
+        let source = r#"
+MAX_RETRIES = 3
+$verbose = false
+counter = 0
+"#;
+        let processor = RubyProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("config.rb"), &opts)
+            .unwrap();
+
+        let vars: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                ir::Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(vars.len(), 2);
+
+        assert_eq!(vars[0].name, "MAX_RETRIES");
+        assert_eq!(vars[0].var_kind, VariableKind::Const);
+        assert_eq!(vars[0].value.as_deref(), Some("3"));
+        assert!(!vars[0].is_mutable);
+
+        assert_eq!(vars[1].name, "$verbose");
+        assert_eq!(vars[1].var_kind, VariableKind::Var);
+        assert!(vars[1].is_mutable);
+    }
+
+    #[test]
+    fn test_yard_deprecated() {
+        let source = r#"
+class Cache
+  # Reads a value.
+  # @deprecated Use {#fetch} instead.
+  def read(key)
+  end
+
+  def fetch(key)
+  end
+end
+"#;
+        let processor = RubyProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("cache.rb"), &opts)
+            .unwrap();
+
+        let ir::Node::Class(class) = &file.children[0] else {
+            panic!("Expected a class");
+        };
+        let methods: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                ir::Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+
+        let deprecated = methods[0].deprecated.as_ref().expect("read is deprecated");
+        assert_eq!(deprecated.message.as_deref(), Some("Use {#fetch} instead."));
+        assert!(methods[1].deprecated.is_none());
+    }
 }
@@ -1,13 +1,22 @@
 use distiller_core::{
     error::{DistilError, Result},
     ir::{
-        Class, Field, File, Function, Import, Interface, Modifier, Node, Parameter, TypeRef,
-        Visibility,
+        Class, Deprecation, Enum, EnumVariant, Field, File, Function, Import, Interface, Macro,
+        MacroKind, Modifier, Module, Node, Parameter, SourceVisibility, TypeRef, Variable,
+        VariableKind, Visibility,
     },
     options::ProcessOptions,
-    parser::ParserPool,
+    parser::{
+        ParserPool,
+        syntax::syntax_error,
+        synthesized::DERIVE,
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::language::LanguageProcessor,
+    type_merge::FRAGMENT,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 
@@ -34,29 +43,102 @@ impl RustProcessor {
         source[node.start_byte()..node.end_byte()].to_string()
     }
 
-    fn parse_visibility(node: tree_sitter::Node, source: &str) -> Visibility {
+    /// Parse the type written at `node`
+    fn type_ref(node: tree_sitter::Node, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::Rust)
+    }
+
+    /// Parse the visibility modifier; `SourceVisibility::coarse` gives the
+    /// filtering bucket (`pub(crate)`, `pub(super)` and `pub(in path)` are
+    /// internal)
+    fn parse_visibility(node: tree_sitter::Node, source: &str) -> SourceVisibility {
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
             if child.kind() == "visibility_modifier" {
-                let text = Self::node_text(child, source);
+                let text: String = Self::node_text(child, source)
+                    .split_whitespace()
+                    .collect::<Vec<_>>()
+                    .join(" ");
                 return match text.as_str() {
-                    "pub" => Visibility::Public,
-                    text if text.contains("pub(crate)") => Visibility::Internal,
-                    text if text.contains("pub(super)") || text.contains("pub(in ") => {
-                        Visibility::Protected
-                    }
-                    _ => Visibility::Public,
+                    "pub" => SourceVisibility::Public,
+                    "pub(crate)" => SourceVisibility::Crate,
+                    "pub(super)" => SourceVisibility::Super,
+                    "pub(self)" => SourceVisibility::Private,
+                    text => text
+                        .strip_prefix("pub(in ")
+                        .and_then(|rest| rest.strip_suffix(')'))
+                        .map_or(SourceVisibility::Public, |path| {
+                            SourceVisibility::Restricted(path.trim().to_string())
+                        }),
                 };
             }
         }
-        Visibility::Private // Default in Rust
+        SourceVisibility::Private // Default in Rust
+    }
+
+    /// Collect the outer attributes (`#[...]`) attached to an item
+    ///
+    /// Attributes are preceding siblings of the item; doc comments between
+    /// them are skipped. The `#[` `]` wrapper is stripped (`derive(Debug)`).
+    fn parse_attributes(node: tree_sitter::Node, source: &str) -> Vec<String> {
+        let mut attributes = Vec::new();
+        let mut sibling = node.prev_sibling();
+
+        while let Some(prev) = sibling {
+            match prev.kind() {
+                "attribute_item" => {
+                    let text = Self::node_text(prev, source);
+                    let inner = text
+                        .trim()
+                        .trim_start_matches("#[")
+                        .trim_end_matches(']')
+                        .trim();
+                    attributes.push(inner.to_string());
+                }
+                "line_comment" | "block_comment" => {}
+                _ => break,
+            }
+            sibling = prev.prev_sibling();
+        }
+
+        attributes.reverse();
+        attributes
+    }
+
+    /// Move `derive(...)` attributes into the `derive` metadata list
+    ///
+    /// Several derive attributes on one type are joined in source order.
+    fn take_derives(decorators: &mut Vec<String>) -> BTreeMap<String, String> {
+        let mut derives = Vec::new();
+        decorators.retain(|decorator| {
+            let Some(list) = decorator
+                .strip_prefix("derive(")
+                .and_then(|rest| rest.strip_suffix(')'))
+            else {
+                return true;
+            };
+            derives.extend(
+                list.split(',')
+                    .map(str::trim)
+                    .filter(|derive| !derive.is_empty())
+                    .map(String::from),
+            );
+            false
+        });
+
+        let mut metadata = BTreeMap::new();
+        if !derives.is_empty() {
+            metadata.insert(DERIVE.to_string(), derives.join(", "));
+        }
+        metadata
     }
 
     #[allow(clippy::unused_self)]
     fn parse_field(&self, node: tree_sitter::Node, source: &str) -> Result<Option<Field>> {
         let mut name = String::new();
         let mut field_type = TypeRef::new(String::new());
-        let visibility = Self::parse_visibility(node, source);
+        let source_visibility = Self::parse_visibility(node, source);
+        let visibility = source_visibility.coarse();
         let line = node.start_position().row + 1;
 
         let mut cursor = node.walk();
@@ -66,7 +148,7 @@ impl RustProcessor {
                     name = Self::node_text(child, source);
                 }
                 "type_identifier" | "primitive_type" | "generic_type" => {
-                    field_type = TypeRef::new(Self::node_text(child, source));
+                    field_type = Self::type_ref(child, source);
                 }
                 _ => {}
             }
@@ -79,10 +161,13 @@ impl RustProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             field_type: Some(field_type),
             modifiers: vec![],
             default_value: None,
             line,
+            deprecated: Deprecation::from_decorators(&Self::parse_attributes(node, source)),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -118,7 +203,7 @@ impl RustProcessor {
                     name = "self".to_string();
                 }
                 "type_identifier" | "primitive_type" | "reference_type" | "generic_type" => {
-                    param_type = TypeRef::new(Self::node_text(child, source));
+                    param_type = Self::type_ref(child, source);
                 }
                 _ => {}
             }
@@ -138,37 +223,65 @@ impl RustProcessor {
         }))
     }
 
-    fn parse_impl_block(
-        &self,
-        node: tree_sitter::Node,
-        source: &str,
-    ) -> Result<(String, Vec<Function>)> {
-        let mut type_name = String::new();
-        let mut methods = Vec::new();
+    /// Parse an `impl` block as a fragment of its type
+    ///
+    /// `impl Trait for Type` records the trait in `implements`. The type
+    /// merge pass folds the fragment into the struct, enum or trait.
+    fn parse_impl_block(&self, node: tree_sitter::Node, source: &str) -> Result<Option<Class>> {
+        let Some(name) = node
+            .child_by_field_name("type")
+            .and_then(|ty| Self::impl_type_name(ty, source))
+        else {
+            return Ok(None);
+        };
 
-        let mut cursor = node.walk();
-        for child in node.children(&mut cursor) {
-            match child.kind() {
-                "type_identifier" => {
-                    if type_name.is_empty() {
-                        type_name = Self::node_text(child, source);
-                    }
-                }
-                "declaration_list" => {
-                    let mut decl_cursor = child.walk();
-                    for decl_child in child.children(&mut decl_cursor) {
-                        if decl_child.kind() == "function_item"
-                            && let Some(method) = self.parse_function(decl_child, source)?
-                        {
-                            methods.push(method);
-                        }
-                    }
+        let implements = node
+            .child_by_field_name("trait")
+            .map(|tr| vec![Self::type_ref(tr, source)])
+            .unwrap_or_default();
+
+        let mut methods = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            let mut cursor = body.walk();
+            for child in body.children(&mut cursor) {
+                if child.kind() == "function_item"
+                    && let Some(method) = self.parse_function(child, source)?
+                {
+                    methods.push(Node::Function(method));
                 }
-                _ => {}
             }
         }
 
-        Ok((type_name, methods))
+        let mut metadata = BTreeMap::new();
+        metadata.insert(FRAGMENT.to_string(), "impl".to_string());
+
+        Ok(Some(Class {
+            name,
+            visibility: Visibility::Public,
+            source_visibility: None,
+            modifiers: vec![],
+            decorators: vec![],
+            type_params: vec![],
+            extends: vec![],
+            implements,
+            children: methods,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: None,
+            metadata,
+        }))
+    }
+
+    /// Base name of an impl's self type: `Wrapper` for `Wrapper<T>` or `crate::Wrapper`
+    fn impl_type_name(node: tree_sitter::Node, source: &str) -> Option<String> {
+        match node.kind() {
+            "type_identifier" | "primitive_type" => Some(Self::node_text(node, source)),
+            "generic_type" => Self::impl_type_name(node.child_by_field_name("type")?, source),
+            "scoped_type_identifier" => {
+                Self::impl_type_name(node.child_by_field_name("name")?, source)
+            }
+            _ => None,
+        }
     }
 
     #[allow(clippy::unused_self)]
@@ -198,7 +311,8 @@ impl RustProcessor {
     fn parse_struct(&self, node: tree_sitter::Node, source: &str) -> Result<Option<Class>> {
         let mut name = String::new();
         let mut fields = Vec::new();
-        let visibility = Self::parse_visibility(node, source);
+        let source_visibility = Self::parse_visibility(node, source);
+        let visibility = source_visibility.coarse();
 
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
@@ -229,24 +343,116 @@ impl RustProcessor {
             return Ok(None);
         }
 
+        let mut decorators = Self::parse_attributes(node, source);
+        let deprecated = Deprecation::from_decorators(&decorators);
+        let metadata = Self::take_derives(&mut decorators);
+
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends: vec![],
             implements: vec![],
             type_params: vec![],
-            decorators: vec![],
+            decorators,
             modifiers: vec![],
             children: fields.into_iter().map(Node::Field).collect(),
             line_start,
             line_end,
+            deprecated,
+            metadata,
         }))
     }
 
     #[allow(clippy::unused_self)]
+    fn parse_enum(node: tree_sitter::Node, source: &str) -> Option<Enum> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+        let source_visibility = Self::parse_visibility(node, source);
+
+        let mut variants = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            let mut cursor = body.walk();
+            for child in body.named_children(&mut cursor) {
+                if child.kind() == "enum_variant"
+                    && let Some(variant) = Self::parse_enum_variant(child, source)
+                {
+                    variants.push(Node::EnumVariant(variant));
+                }
+            }
+        }
+
+        let mut decorators = Self::parse_attributes(node, source);
+        let deprecated = Deprecation::from_decorators(&decorators);
+        let metadata = Self::take_derives(&mut decorators);
+        Some(Enum {
+            name,
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
+            deprecated,
+            decorators,
+            type_params: vec![],
+            enum_type: None,
+            children: variants,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            metadata,
+        })
+    }
+
+    /// Parse a unit, tuple (`Write(String)`) or struct (`Move { x: i32 }`)
+    /// variant, with its explicit discriminant if any
+    fn parse_enum_variant(node: tree_sitter::Node, source: &str) -> Option<EnumVariant> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+
+        let mut fields = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            let mut cursor = body.walk();
+            for child in body.named_children(&mut cursor) {
+                let (field_name, field_type) = match child.kind() {
+                    "field_declaration" => (
+                        child
+                            .child_by_field_name("name")
+                            .map(|n| Self::node_text(n, source))
+                            .unwrap_or_default(),
+                        child.child_by_field_name("type"),
+                    ),
+                    "visibility_modifier" | "attribute_item" | "line_comment" | "block_comment" => {
+                        continue;
+                    }
+                    _ => (String::new(), Some(child)),
+                };
+                fields.push(Parameter {
+                    name: field_name,
+                    param_type: field_type.map_or_else(
+                        || TypeRef::new(String::new()),
+                        |t| Self::type_ref(t, source),
+                    ),
+                    default_value: None,
+                    is_variadic: false,
+                    is_optional: false,
+                    decorators: vec![],
+                });
+            }
+        }
+
+        Some(EnumVariant {
+            name,
+            fields,
+            value: node
+                .child_by_field_name("value")
+                .and_then(|value| value_preview(&Self::node_text(value, source))),
+            arguments: vec![],
+            children: vec![],
+            line: node.start_position().row + 1,
+            deprecated: Deprecation::from_decorators(&Self::parse_attributes(node, source)),
+            metadata: BTreeMap::new(),
+        })
+    }
+
     fn parse_trait(&self, node: tree_sitter::Node, source: &str) -> Result<Option<Interface>> {
         let mut name = String::new();
-        let visibility = Self::parse_visibility(node, source);
+        let source_visibility = Self::parse_visibility(node, source);
+        let visibility = source_visibility.coarse();
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
         let mut children = Vec::new();
@@ -279,11 +485,14 @@ impl RustProcessor {
         Ok(Some(Interface {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends: vec![],
             type_params: vec![],
             children,
             line_start,
             line_end,
+            deprecated: Deprecation::from_decorators(&Self::parse_attributes(node, source)),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -291,8 +500,10 @@ impl RustProcessor {
         let mut name = String::new();
         let mut parameters = Vec::new();
         let mut return_type = None;
-        let mut is_async = false;
-        let visibility = Self::parse_visibility(node, source);
+        let mut modifiers = vec![];
+        let mut metadata = BTreeMap::new();
+        let source_visibility = Self::parse_visibility(node, source);
+        let visibility = source_visibility.coarse();
 
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
@@ -310,13 +521,26 @@ impl RustProcessor {
                 }
                 "type_identifier" | "primitive_type" | "generic_type" => {
                     // This might be return type
-                    return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                    return_type = Some(Self::type_ref(child, source));
                 }
                 "function_modifiers" => {
-                    // Check for async in function_modifiers
-                    let text = Self::node_text(child, source);
-                    if text.contains("async") {
-                        is_async = true;
+                    let mut mod_cursor = child.walk();
+                    for modifier in child.children(&mut mod_cursor) {
+                        match modifier.kind() {
+                            "async" => modifiers.push(Modifier::Async),
+                            "const" => modifiers.push(Modifier::Const),
+                            "unsafe" => modifiers.push(Modifier::Unsafe),
+                            "extern_modifier" => {
+                                modifiers.push(Modifier::Extern);
+                                // A bare `extern fn` uses the C ABI
+                                let text = Self::node_text(modifier, source);
+                                let abi =
+                                    text.trim_start_matches("extern").trim().trim_matches('"');
+                                let abi = if abi.is_empty() { "C" } else { abi };
+                                metadata.insert("abi".to_string(), abi.to_string());
+                            }
+                            _ => {}
+                        }
                     }
                 }
                 _ => {}
@@ -327,90 +551,246 @@ impl RustProcessor {
             return Ok(None);
         }
 
-        let mut modifiers = vec![];
-        if is_async {
-            modifiers.push(Modifier::Async);
-        }
+        let decorators = Self::parse_attributes(node, source);
+        let deprecated = Deprecation::from_decorators(&decorators);
 
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             parameters,
             return_type,
-            decorators: vec![],
+            decorators,
             type_params: vec![],
             modifiers,
             implementation: None,
             line_start,
             line_end,
+            deprecated,
+            metadata,
         }))
     }
 
-    fn process_node(&self, node: tree_sitter::Node, source: &str, file: &mut File) -> Result<()> {
+    /// Turn a `#[proc_macro]`, `#[proc_macro_attribute]` or
+    /// `#[proc_macro_derive]` function into the macro it defines
+    ///
+    /// Derive macros take the derive name; the function name and helper
+    /// attributes go to metadata. Other functions are returned unchanged.
+    fn into_proc_macro(mut function: Function) -> Node {
+        let Some(position) = function.decorators.iter().position(|decorator| {
+            let path = decorator.split('(').next().unwrap_or_default().trim();
+            matches!(
+                path,
+                "proc_macro" | "proc_macro_attribute" | "proc_macro_derive"
+            )
+        }) else {
+            return Node::Function(function);
+        };
+
+        let attribute = function.decorators.remove(position);
+        let (path, args) = attribute
+            .split_once('(')
+            .map_or((attribute.as_str(), ""), |(path, args)| {
+                (path.trim(), args.trim().trim_end_matches(')'))
+            });
+
+        let mut metadata = function.metadata;
+        let (macro_kind, name) = match path {
+            "proc_macro_derive" => {
+                let (derive, helpers) = args.split_once(',').unwrap_or((args, ""));
+                if let Some(helpers) = helpers
+                    .trim()
+                    .strip_prefix("attributes(")
+                    .and_then(|rest| rest.strip_suffix(')'))
+                {
+                    metadata.insert("attributes".to_string(), helpers.trim().to_string());
+                }
+                metadata.insert("function".to_string(), function.name.clone());
+                (MacroKind::Derive, derive.trim().to_string())
+            }
+            "proc_macro_attribute" => (MacroKind::Attribute, function.name),
+            _ => (MacroKind::FunctionLike, function.name),
+        };
+
+        Node::Macro(Macro {
+            name,
+            visibility: function.visibility,
+            source_visibility: function.source_visibility,
+            decorators: function.decorators,
+            macro_kind,
+            parameters: vec![],
+            value: None,
+            line_start: function.line_start,
+            line_end: function.line_end,
+            deprecated: function.deprecated,
+            metadata,
+        })
+    }
+
+    /// Parse a `macro_rules!` definition; the matchers of its arms become
+    /// the parameters
+    ///
+    /// Without `#[macro_export]` the macro is only usable inside the crate.
+    fn parse_macro_rules(node: tree_sitter::Node, source: &str) -> Option<Macro> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+        let decorators = Self::parse_attributes(node, source);
+        let source_visibility = if decorators
+            .iter()
+            .any(|decorator| decorator.starts_with("macro_export"))
+        {
+            SourceVisibility::Public
+        } else {
+            SourceVisibility::Crate
+        };
+
+        let mut cursor = node.walk();
+        let parameters = node
+            .named_children(&mut cursor)
+            .filter(|child| child.kind() == "macro_rule")
+            .filter_map(|rule| rule.child_by_field_name("left"))
+            .map(|matcher| {
+                Self::node_text(matcher, source)
+                    .split_whitespace()
+                    .collect::<Vec<_>>()
+                    .join(" ")
+            })
+            .collect();
+
+        Some(Macro {
+            name,
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
+            deprecated: Deprecation::from_decorators(&decorators),
+            decorators,
+            macro_kind: MacroKind::Rules,
+            parameters,
+            value: None,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Parse a `const` or `static` item
+    fn parse_variable(node: tree_sitter::Node, source: &str) -> Option<Variable> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+        let source_visibility = Self::parse_visibility(node, source);
+        let is_static = node.kind() == "static_item";
+
+        let mut cursor = node.walk();
+        let is_mutable = node
+            .children(&mut cursor)
+            .any(|child| child.kind() == "mutable_specifier");
+
+        Some(Variable {
+            name,
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
+            var_kind: if is_static {
+                VariableKind::Static
+            } else {
+                VariableKind::Const
+            },
+            modifiers: vec![],
+            var_type: node
+                .child_by_field_name("type")
+                .map(|t| Self::type_ref(t, source)),
+            value: node
+                .child_by_field_name("value")
+                .and_then(|v| value_preview(&Self::node_text(v, source))),
+            is_mutable,
+            line: node.start_position().row + 1,
+            deprecated: Deprecation::from_decorators(&Self::parse_attributes(node, source)),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Parse a `mod` item into a module holding its items
+    ///
+    /// Out-of-line `mod name;` declarations become empty modules.
+    fn parse_module(&self, node: tree_sitter::Node, source: &str) -> Result<Module> {
+        let source_visibility = Self::parse_visibility(node, source);
+        let decorators = Self::parse_attributes(node, source);
+        let mut children = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            self.process_node(body, source, &mut children)?;
+        }
+
+        Ok(Module {
+            name: node
+                .child_by_field_name("name")
+                .map(|name| Self::node_text(name, source))
+                .unwrap_or_default(),
+            visibility: source_visibility.coarse(),
+            source_visibility: Some(source_visibility),
+            deprecated: Deprecation::from_decorators(&decorators),
+            decorators,
+            children,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    fn process_node(
+        &self,
+        node: tree_sitter::Node,
+        source: &str,
+        children: &mut Vec<Node>,
+    ) -> Result<()> {
         match node.kind() {
             "use_declaration" => {
                 if let Some(import) = self.parse_use(node, source)? {
-                    file.children.push(Node::Import(import));
+                    children.push(Node::Import(import));
                 }
             }
             "struct_item" => {
                 if let Some(struct_def) = self.parse_struct(node, source)? {
-                    file.children.push(Node::Class(struct_def));
+                    children.push(Node::Class(struct_def));
+                }
+            }
+            "enum_item" => {
+                if let Some(enum_def) = Self::parse_enum(node, source) {
+                    children.push(Node::Enum(enum_def));
                 }
             }
             "trait_item" => {
                 if let Some(trait_def) = self.parse_trait(node, source)? {
-                    file.children.push(Node::Interface(trait_def));
+                    children.push(Node::Interface(trait_def));
                 }
             }
             "function_item" => {
                 if let Some(func) = self.parse_function(node, source)? {
-                    file.children.push(Node::Function(func));
+                    children.push(Self::into_proc_macro(func));
+                }
+            }
+            "macro_definition" => {
+                if let Some(macro_def) = Self::parse_macro_rules(node, source) {
+                    children.push(Node::Macro(macro_def));
+                }
+            }
+            "const_item" | "static_item" => {
+                if let Some(variable) = Self::parse_variable(node, source) {
+                    children.push(Node::Variable(variable));
                 }
             }
             "impl_item" => {
-                // Handle impl blocks in second pass
+                if let Some(fragment) = self.parse_impl_block(node, source)? {
+                    children.push(Node::Class(fragment));
+                }
+            }
+            "mod_item" => {
+                children.push(Node::Module(self.parse_module(node, source)?));
             }
             _ => {
                 let mut cursor = node.walk();
                 for child in node.children(&mut cursor) {
-                    self.process_node(child, source, file)?;
+                    self.process_node(child, source, children)?;
                 }
             }
         }
         Ok(())
     }
-
-    fn associate_impl_blocks(
-        &self,
-        node: tree_sitter::Node,
-        source: &str,
-        file: &mut File,
-    ) -> Result<()> {
-        if node.kind() == "impl_item" {
-            let (type_name, methods) = self.parse_impl_block(node, source)?;
-            if !type_name.is_empty() {
-                // Find the struct and add methods
-                for child in &mut file.children {
-                    if let Node::Class(class) = child
-                        && class.name == type_name
-                    {
-                        // Add methods to the struct
-                        for method in methods {
-                            class.children.push(Node::Function(method));
-                        }
-                        break;
-                    }
-                }
-            }
-        } else {
-            let mut cursor = node.walk();
-            for child in node.children(&mut cursor) {
-                self.associate_impl_blocks(child, source, file)?;
-            }
-        }
-        Ok(())
-    }
 }
 
 impl LanguageProcessor for RustProcessor {
@@ -449,11 +829,14 @@ impl LanguageProcessor for RustProcessor {
             children: vec![],
         };
 
-        // First pass: collect structs, traits, functions, imports
-        self.process_node(tree.root_node(), source, &mut file)?;
+        self.process_node(tree.root_node(), source, &mut file.children)?;
 
-        // Second pass: associate impl blocks with structs
-        self.associate_impl_blocks(tree.root_node(), source, &mut file)?;
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
 
         Ok(file)
     }
@@ -468,6 +851,7 @@ impl Default for RustProcessor {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use distiller_core::type_merge::{DECLARED_IN, merge_file};
 
     #[test]
     fn test_processor_creation() {
@@ -543,9 +927,10 @@ impl User {
 "#;
 
         let opts = ProcessOptions::default();
-        let file = processor
+        let mut file = processor
             .process(source, Path::new("test.rs"), &opts)
             .unwrap();
+        merge_file(&mut file);
 
         let classes: Vec<_> = file
             .children
@@ -684,6 +1069,91 @@ pub async fn fetch_data(url: &str) -> Result<String, Error> {
         assert!(functions[3].modifiers.contains(&Modifier::Async));
     }
 
+    #[test]
+    fn test_const_and_static_items() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+pub const MAX_FRAME: usize = 16 * 1024;
+
+static mut COUNTER: u64 = 0;
+
+#[deprecated(note = "use MAX_FRAME")]
+pub(crate) static LIMIT: &str = "16k";
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("limits.rs"), &opts)
+            .unwrap();
+
+        let variables: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+
+        assert_eq!(variables.len(), 3);
+        assert_eq!(variables[0].name, "MAX_FRAME");
+        assert_eq!(variables[0].var_kind, VariableKind::Const);
+        assert_eq!(variables[0].visibility, Visibility::Public);
+        assert_eq!(variables[0].var_type.as_ref().unwrap().name, "usize");
+        assert_eq!(variables[0].value.as_deref(), Some("16 * 1024"));
+
+        assert_eq!(variables[1].var_kind, VariableKind::Static);
+        assert!(variables[1].is_mutable);
+        assert_eq!(variables[1].visibility, Visibility::Private);
+
+        assert_eq!(
+            variables[2].source_visibility,
+            Some(SourceVisibility::Crate)
+        );
+        assert!(variables[2].deprecated.is_some());
+    }
+
+    #[test]
+    fn test_function_modifiers_and_abi() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+pub const fn max_len() -> usize {
+    64
+}
+
+pub unsafe extern "system" fn callback(data: *mut u8) {}
+
+pub extern fn legacy() {}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("ffi.rs"), &opts)
+            .unwrap();
+
+        let functions: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(func) => Some(func),
+                _ => None,
+            })
+            .collect();
+
+        assert_eq!(functions.len(), 3);
+        assert_eq!(functions[0].modifiers, [Modifier::Const]);
+        assert!(functions[0].metadata.is_empty());
+        assert_eq!(functions[1].modifiers, [Modifier::Unsafe, Modifier::Extern]);
+        assert_eq!(
+            functions[1].metadata.get("abi").map(String::as_str),
+            Some("system")
+        );
+        assert_eq!(
+            functions[2].metadata.get("abi").map(String::as_str),
+            Some("C")
+        );
+    }
+
     // ===== Enhanced Test Coverage =====
 
     #[test]
@@ -751,9 +1221,10 @@ impl Drawable for Circle {
 "#;
 
         let opts = ProcessOptions::default();
-        let file = processor
+        let mut file = processor
             .process(source, Path::new("test.rs"), &opts)
             .unwrap();
+        merge_file(&mut file);
 
         // Validate trait
         let traits: Vec<_> = file
@@ -784,6 +1255,17 @@ impl Drawable for Circle {
             .collect();
         assert_eq!(classes.len(), 1);
         assert_eq!(classes[0].name, "Circle");
+        assert_eq!(classes[0].implements[0].name, "Drawable");
+
+        // The trait impl's method joins the struct and records its origin
+        let Some(Node::Function(draw)) = classes[0].children.last() else {
+            panic!("Expected draw method on Circle");
+        };
+        assert_eq!(draw.name, "draw");
+        assert_eq!(
+            draw.metadata.get(DECLARED_IN).map(String::as_str),
+            Some("test.rs:11")
+        );
     }
 
     #[test]
@@ -806,9 +1288,10 @@ impl<T> Container<T> {
 "#;
 
         let opts = ProcessOptions::default();
-        let file = processor
+        let mut file = processor
             .process(source, Path::new("test.rs"), &opts)
             .unwrap();
+        merge_file(&mut file);
 
         let classes: Vec<_> = file
             .children
@@ -826,8 +1309,6 @@ impl<T> Container<T> {
         assert_eq!(classes[0].name, "Container");
         assert_eq!(classes[0].visibility, Visibility::Public);
 
-        // Generic impl blocks may not be associated yet - just verify struct parses correctly
-        // If methods are associated, verify they're correct
         let methods: Vec<_> = classes[0]
             .children
             .iter()
@@ -840,13 +1321,52 @@ impl<T> Container<T> {
             })
             .collect();
 
-        // Methods may or may not be associated depending on parser support for generic impl blocks
-        if !methods.is_empty() {
-            assert_eq!(methods[0].name, "new");
-            if methods.len() > 1 {
-                assert_eq!(methods[1].name, "get");
-            }
-        }
+        assert_eq!(methods.len(), 2);
+        assert_eq!(methods[0].name, "new");
+        assert_eq!(methods[1].name, "get");
+    }
+
+    #[test]
+    fn test_impl_blocks_are_fragments() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+impl<T: Clone> fmt::Display for Stack<T> {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        Ok(())
+    }
+}
+
+impl std::ops::Deref for crate::store::Store {
+    fn deref(&self) -> &Self::Target {
+        &self.inner
+    }
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("test.rs"), &opts)
+            .unwrap();
+
+        // Without the type in this file the impl blocks stay separate
+        let fragments: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Class(cls) => Some(cls),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(fragments.len(), 2);
+        assert_eq!(fragments[0].name, "Stack");
+        assert_eq!(fragments[0].implements[0].name, "fmt::Display");
+        assert_eq!(
+            fragments[0].metadata.get(FRAGMENT).map(String::as_str),
+            Some("impl")
+        );
+        assert_eq!(fragments[0].children.len(), 1);
+        assert_eq!(fragments[1].name, "Store");
+        assert_eq!(fragments[1].implements[0].name, "std::ops::Deref");
     }
 
     #[test]
@@ -869,9 +1389,10 @@ impl<'a> Wrapper<'a> {
 "#;
 
         let opts = ProcessOptions::default();
-        let file = processor
+        let mut file = processor
             .process(source, Path::new("test.rs"), &opts)
             .unwrap();
+        merge_file(&mut file);
 
         // Validate function with lifetime
         let functions: Vec<_> = file
@@ -982,7 +1503,14 @@ pub fn use_macro() {
             .process(source, Path::new("test.rs"), &opts)
             .unwrap();
 
-        // Macros may not be parsed as functions, but we should at least parse the function
+        let Node::Macro(macro_def) = &file.children[0] else {
+            panic!("Expected macro first, got {:?}", file.children[0]);
+        };
+        assert_eq!(macro_def.name, "vec_of_strings");
+        assert_eq!(macro_def.macro_kind, MacroKind::Rules);
+        assert_eq!(macro_def.parameters, ["($($x:expr),*)"]);
+        assert_eq!(macro_def.source_visibility, Some(SourceVisibility::Crate));
+
         let functions: Vec<_> = file
             .children
             .iter()
@@ -1027,9 +1555,10 @@ impl InternalService {
 "#;
 
         let opts = ProcessOptions::default();
-        let file = processor
+        let mut file = processor
             .process(source, Path::new("test.rs"), &opts)
             .unwrap();
+        merge_file(&mut file);
 
         let classes: Vec<_> = file
             .children
@@ -1070,7 +1599,9 @@ impl InternalService {
         assert_eq!(methods[1].name, "process");
         assert_eq!(methods[1].visibility, Visibility::Internal);
         assert_eq!(methods[2].name, "super_method");
-        assert_eq!(methods[2].visibility, Visibility::Protected);
+        assert_eq!(methods[2].visibility, Visibility::Internal);
+        assert_eq!(methods[0].source_visibility, Some(SourceVisibility::Crate));
+        assert_eq!(methods[2].source_visibility, Some(SourceVisibility::Super));
     }
 
     #[test]
@@ -1173,6 +1704,269 @@ pub struct User {
         assert_eq!(point_fields.len(), 2);
         assert_eq!(point_fields[0].name, "x");
         assert_eq!(point_fields[1].name, "y");
+
+        // Derives become a metadata list, other attributes stay decorators
+        assert!(classes[0].decorators.is_empty());
+        assert_eq!(
+            classes[0].metadata.get(DERIVE).map(String::as_str),
+            Some("Debug, Clone, PartialEq")
+        );
+        assert_eq!(classes[1].decorators, ["serde(rename_all = \"camelCase\")"]);
+        assert_eq!(
+            classes[1].metadata.get(DERIVE).map(String::as_str),
+            Some("Debug, Serialize, Deserialize")
+        );
+    }
+
+    #[test]
+    fn test_exported_macros_and_proc_macros() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+#[macro_export]
+macro_rules! hashmap {
+    () => { HashMap::new() };
+    ($($k:expr => $v:expr),+ $(,)?) => {{ let mut m = HashMap::new(); $(m.insert($k, $v);)+ m }};
+}
+
+#[proc_macro_derive(Builder, attributes(builder))]
+pub fn derive_builder(input: TokenStream) -> TokenStream {
+    input
+}
+
+#[proc_macro_attribute]
+pub fn route(attr: TokenStream, item: TokenStream) -> TokenStream {
+    item
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("lib.rs"), &opts)
+            .unwrap();
+
+        let macros: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Macro(m) => Some(m),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(macros.len(), 3);
+
+        assert_eq!(macros[0].name, "hashmap");
+        assert_eq!(macros[0].visibility, Visibility::Public);
+        assert_eq!(macros[0].parameters.len(), 2);
+        assert_eq!(macros[0].parameters[0], "()");
+
+        assert_eq!(macros[1].name, "Builder");
+        assert_eq!(macros[1].macro_kind, MacroKind::Derive);
+        assert_eq!(
+            macros[1].metadata.get("function").map(String::as_str),
+            Some("derive_builder")
+        );
+        assert_eq!(
+            macros[1].metadata.get("attributes").map(String::as_str),
+            Some("builder")
+        );
+        assert!(macros[1].decorators.is_empty());
+
+        assert_eq!(macros[2].name, "route");
+        assert_eq!(macros[2].macro_kind, MacroKind::Attribute);
+    }
+
+    #[test]
+    fn test_enum_variants() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+#[derive(Debug)]
+pub enum Message {
+    Quit,
+    Move { x: i32, y: i32 },
+    Write(String),
+    #[deprecated]
+    Code = 7,
+}
+
+impl Message {
+    pub fn call(&self) {}
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let mut file = processor
+            .process(source, Path::new("test.rs"), &opts)
+            .unwrap();
+        merge_file(&mut file);
+
+        let Node::Enum(message) = &file.children[0] else {
+            panic!("Expected enum, got {:?}", file.children[0]);
+        };
+        assert_eq!(message.name, "Message");
+        assert_eq!(message.visibility, Visibility::Public);
+        assert_eq!(message.decorators, ["derive(Debug)"]);
+
+        let variants: Vec<_> = message
+            .children
+            .iter()
+            .filter_map(|n| {
+                if let Node::EnumVariant(variant) = n {
+                    Some(variant)
+                } else {
+                    None
+                }
+            })
+            .collect();
+        assert_eq!(variants.len(), 4);
+        assert!(variants[0].fields.is_empty());
+        assert_eq!(variants[1].fields.len(), 2);
+        assert_eq!(variants[1].fields[0].name, "x");
+        assert_eq!(variants[1].fields[0].param_type.name, "i32");
+        assert_eq!(variants[2].fields[0].name, "");
+        assert_eq!(variants[2].fields[0].param_type.name, "String");
+        assert_eq!(variants[3].value.as_deref(), Some("7"));
+        assert!(variants[3].deprecated.is_some());
+
+        // Methods from the impl block are attached to the enum
+        assert!(
+            message
+                .children
+                .iter()
+                .any(|n| matches!(n, Node::Function(f) if f.name == "call"))
+        );
+    }
+
+    #[test]
+    fn test_cfg_test_module() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+pub fn add(a: i32, b: i32) -> i32 {
+    a + b
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn adds() {
+        assert_eq!(add(1, 2), 3);
+    }
+
+    fn helper() {}
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("lib.rs"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 2);
+        assert!(matches!(&file.children[0], Node::Function(f) if f.name == "add"));
+
+        let Node::Module(module) = &file.children[1] else {
+            panic!("Expected tests module");
+        };
+        assert_eq!(module.name, "tests");
+        assert_eq!(module.decorators, ["cfg(test)"]);
+
+        let functions: Vec<_> = module
+            .children
+            .iter()
+            .filter_map(|n| {
+                if let Node::Function(func) = n {
+                    Some(func)
+                } else {
+                    None
+                }
+            })
+            .collect();
+
+        assert_eq!(functions.len(), 2);
+        assert_eq!(functions[0].name, "adds");
+        assert_eq!(functions[0].decorators, ["test"]);
+        assert_eq!(functions[1].name, "helper");
+        assert!(functions[1].decorators.is_empty());
+    }
+
+    #[test]
+    fn test_nested_modules() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+pub mod net {
+    pub(crate) mod http {
+        pub struct Client;
+
+        impl Client {
+            pub fn get(&self) {}
+        }
+    }
+}
+
+mod config;
+"#;
+
+        let opts = ProcessOptions::default();
+        let mut file = processor
+            .process(source, Path::new("lib.rs"), &opts)
+            .unwrap();
+        merge_file(&mut file);
+
+        assert_eq!(file.children.len(), 2);
+        let Node::Module(net) = &file.children[0] else {
+            panic!("Expected net module");
+        };
+        assert_eq!(net.name, "net");
+        assert_eq!(net.source_visibility, Some(SourceVisibility::Public));
+
+        let Node::Module(http) = &net.children[0] else {
+            panic!("Expected http module");
+        };
+        assert_eq!(http.source_visibility, Some(SourceVisibility::Crate));
+        let Node::Class(client) = &http.children[0] else {
+            panic!("Expected Client struct");
+        };
+        assert!(matches!(&client.children[..], [Node::Function(f)] if f.name == "get"));
+
+        let Node::Module(config) = &file.children[1] else {
+            panic!("Expected out-of-line module");
+        };
+        assert_eq!(config.name, "config");
+        assert!(config.children.is_empty());
+    }
+
+    #[test]
+    fn test_deprecated_attribute() {
+        let processor = RustProcessor::new().unwrap();
+        let source = r#"
+#[deprecated(since = "0.3.0", note = "use `parse_v2` instead")]
+pub fn parse(input: &str) -> Ast {
+    parse_v2(input)
+}
+
+#[deprecated]
+pub struct OldConfig {
+    pub name: String,
+}
+"#;
+
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, Path::new("lib.rs"), &opts)
+            .unwrap();
+
+        let Node::Function(func) = &file.children[0] else {
+            panic!("Expected function node");
+        };
+        let deprecated = func.deprecated.as_ref().expect("parse is deprecated");
+        assert_eq!(deprecated.since.as_deref(), Some("0.3.0"));
+        assert_eq!(deprecated.replacement.as_deref(), Some("parse_v2"));
+
+        let Node::Class(class) = &file.children[1] else {
+            panic!("Expected class node");
+        };
+        assert_eq!(class.deprecated, Some(Deprecation::default()));
     }
 
     #[test]
@@ -1201,9 +1995,10 @@ impl Calculator {
 "#;
 
         let opts = ProcessOptions::default();
-        let file = processor
+        let mut file = processor
             .process(source, Path::new("test.rs"), &opts)
             .unwrap();
+        merge_file(&mut file);
 
         let classes: Vec<_> = file
             .children
@@ -1,10 +1,23 @@
 use distiller_core::{
     ProcessOptions,
     error::{DistilError, Result},
-    ir::{self, Class, Field, File, Function, Modifier, Parameter, TypeParam, TypeRef, Visibility},
-    parser::ParserPool,
+    ir::{
+        self, Accessor, AccessorKind, Class, Deprecation, EnumVariant, Field, File, Function,
+        Modifier, Parameter, Property, SourceVisibility, TypeParam, TypeRef, Variable,
+        VariableKind,
+    },
+    parser::{
+        ParserPool,
+        comments::preceding_comment,
+        overloads::group_overloads,
+        syntax::syntax_error,
+        types::{TypeSyntax, parse_type},
+        values::value_preview,
+    },
     processor::LanguageProcessor,
+    type_merge::FRAGMENT,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::Node as TSNode;
@@ -32,31 +45,67 @@ impl SwiftProcessor {
         source[node.start_byte()..node.end_byte()].to_string()
     }
 
-    fn parse_modifiers(node: TSNode, source: &str) -> (Visibility, Vec<String>) {
-        let mut visibility = Visibility::Internal; // Swift default
+    /// Parse the type written at `node`
+    fn type_ref(node: TSNode, source: &str) -> TypeRef {
+        parse_type(&Self::node_text(node, source), TypeSyntax::Swift)
+    }
+
+    fn parse_modifiers(node: TSNode, source: &str) -> (SourceVisibility, Vec<Modifier>) {
+        let mut visibility = SourceVisibility::Internal; // Swift default
         let mut modifiers = Vec::new();
 
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
             if child.kind() == "modifiers" {
                 let text = Self::node_text(child, source);
-                if text.contains("private") || text.contains("fileprivate") {
-                    visibility = Visibility::Private;
-                } else if text.contains("public") || text.contains("open") {
-                    visibility = Visibility::Public;
-                } else if text.contains("internal") {
-                    visibility = Visibility::Internal;
+                // `private(set)` and friends only restrict the setter
+                let level = text.split_whitespace().find_map(|word| match word {
+                    "open" => Some(SourceVisibility::Open),
+                    "public" => Some(SourceVisibility::Public),
+                    "internal" => Some(SourceVisibility::Internal),
+                    "fileprivate" => Some(SourceVisibility::FilePrivate),
+                    "private" => Some(SourceVisibility::Private),
+                    _ => None,
+                });
+                if let Some(level) = level {
+                    visibility = level;
                 }
 
-                if text.contains("open") {
-                    modifiers.push("open".to_string());
-                }
+                modifiers.extend(text.split_whitespace().filter_map(|word| match word {
+                    "open" => Some(Modifier::Virtual),
+                    "final" => Some(Modifier::Final),
+                    "override" => Some(Modifier::Override),
+                    "static" => Some(Modifier::Static),
+                    "mutating" => Some(Modifier::Mutating),
+                    "nonisolated" => Some(Modifier::Nonisolated),
+                    _ => None,
+                }));
             }
         }
 
         (visibility, modifiers)
     }
 
+    /// Detect `@available(*, deprecated)` attributes and `@deprecated` doc tags
+    fn parse_deprecation(node: TSNode, source: &str) -> Option<Deprecation> {
+        let mut attributes = Vec::new();
+        let mut cursor = node.walk();
+
+        for child in node.children(&mut cursor) {
+            if child.kind() == "modifiers" {
+                let mut mod_cursor = child.walk();
+                for mod_child in child.children(&mut mod_cursor) {
+                    if mod_child.kind() == "attribute" {
+                        attributes.push(Self::node_text(mod_child, source));
+                    }
+                }
+            }
+        }
+
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(&attributes, doc.as_deref())
+    }
+
     fn parse_type_parameters(node: TSNode, source: &str) -> Vec<TypeParam> {
         let mut type_params = Vec::new();
         let mut cursor = node.walk();
@@ -111,6 +160,7 @@ impl SwiftProcessor {
                 "struct" => return Some("struct".to_string()),
                 "class" => return Some("class".to_string()),
                 "protocol" => return Some("protocol".to_string()),
+                "extension" => return Some("extension".to_string()),
                 _ => {}
             }
         }
@@ -123,7 +173,8 @@ impl SwiftProcessor {
         let mut name = String::new();
         let mut extends = Vec::new();
         let mut children = Vec::new();
-        let (visibility, extra_modifiers) = Self::parse_modifiers(node, source);
+        let (source_visibility, extra_modifiers) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let type_params = Self::parse_type_parameters(node, source);
 
         let line_start = node.start_position().row + 1;
@@ -137,6 +188,11 @@ impl SwiftProcessor {
                         name = Self::node_text(child, source);
                     }
                 }
+                // `extension Array<Int>` names its type with a user_type
+                "user_type" if name.is_empty() => {
+                    let extended = Self::node_text(child, source);
+                    name = extended.split('<').next().unwrap_or(&extended).to_string();
+                }
                 "type_inheritance_clause" | "inheritance_specifier" => {
                     Self::parse_type_inheritance(child, source, &mut extends)?;
                 }
@@ -167,17 +223,26 @@ impl SwiftProcessor {
             _ => (vec![], extends),
         };
 
+        // Extensions add to a type declared elsewhere; the merge pass folds them in
+        let mut metadata = BTreeMap::new();
+        if class_type.as_deref() == Some("extension") {
+            metadata.insert(FRAGMENT.to_string(), "extension".to_string());
+        }
+
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends: extends_final,
             implements: implements_final,
             type_params,
             decorators,
-            modifiers: extra_modifiers.iter().map(|_| Modifier::Final).collect(),
+            modifiers: extra_modifiers,
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata,
         }))
     }
 
@@ -185,7 +250,8 @@ impl SwiftProcessor {
         let mut name = String::new();
         let mut extends = Vec::new();
         let mut children = Vec::new();
-        let (visibility, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, _) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
 
         let line_start = node.start_position().row + 1;
         let line_end = node.end_position().row + 1;
@@ -211,6 +277,7 @@ impl SwiftProcessor {
         Ok(Some(Class {
             name,
             visibility,
+            source_visibility: Some(source_visibility),
             extends,
             implements: vec![],
             type_params: vec![],
@@ -219,6 +286,8 @@ impl SwiftProcessor {
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -244,7 +313,8 @@ impl SwiftProcessor {
         let mut name = String::new();
         let mut parameters = Vec::new();
         let mut return_type = None;
-        let (visibility, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, mut modifiers) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let type_params = Self::parse_type_parameters(node, source);
 
         let line_start = node.start_position().row + 1;
@@ -267,6 +337,9 @@ impl SwiftProcessor {
                 "function_value_parameters" | "parameter_clause" => {
                     self.parse_parameters(child, source, &mut parameters)?;
                 }
+                // Effects come between the parameters and the return type
+                "async" => modifiers.push(Modifier::Async),
+                "throws" | "rethrows" => modifiers.push(Modifier::Throws),
                 // Track arrow operator for return type
                 "->" => {
                     saw_arrow = true;
@@ -275,7 +348,7 @@ impl SwiftProcessor {
                 "user_type" | "optional_type" | "type_identifier" => {
                     if saw_arrow && return_type.is_none() {
                         // Extract full type text including optional marker
-                        return_type = Some(TypeRef::new(Self::node_text(child, source)));
+                        return_type = Some(Self::type_ref(child, source));
                         saw_arrow = false; // Reset flag after capturing
                     }
                 }
@@ -287,7 +360,7 @@ impl SwiftProcessor {
                             if ft_child.kind() == "type_identifier"
                                 || ft_child.kind() == "user_type"
                             {
-                                return_type = Some(TypeRef::new(Self::node_text(ft_child, source)));
+                                return_type = Some(Self::type_ref(ft_child, source));
                             }
                         }
                     }
@@ -302,14 +375,17 @@ impl SwiftProcessor {
             Ok(Some(Function {
                 name,
                 visibility,
+                source_visibility: Some(source_visibility),
                 parameters,
                 return_type,
                 decorators: vec![],
-                modifiers: vec![],
+                modifiers,
                 type_params,
                 implementation: None,
                 line_start,
                 line_end,
+                deprecated: Self::parse_deprecation(node, source),
+                metadata: BTreeMap::new(),
             }))
         }
     }
@@ -349,7 +425,7 @@ impl SwiftProcessor {
                 // Direct type handling (tree-sitter-swift puts types as direct children)
                 "user_type" | "optional_type" => {
                     if param_type.name.is_empty() {
-                        param_type = TypeRef::new(Self::node_text(child, source));
+                        param_type = Self::type_ref(child, source);
                     }
                 }
                 // Legacy type_annotation wrapper handling (for compatibility)
@@ -360,7 +436,7 @@ impl SwiftProcessor {
                             || ta_child.kind() == "user_type"
                             || ta_child.kind() == "optional_type"
                         {
-                            param_type = TypeRef::new(Self::node_text(ta_child, source));
+                            param_type = Self::type_ref(ta_child, source);
                         }
                     }
                 }
@@ -388,7 +464,8 @@ impl SwiftProcessor {
     fn parse_property(node: TSNode, source: &str) -> Result<Option<Field>> {
         let mut name = String::new();
         let mut field_type = None;
-        let (visibility, _) = Self::parse_modifiers(node, source);
+        let (source_visibility, modifiers) = Self::parse_modifiers(node, source);
+        let visibility = source_visibility.coarse();
         let line = node.start_position().row + 1;
 
         let mut cursor = node.walk();
@@ -403,8 +480,7 @@ impl SwiftProcessor {
                             let mut ta_cursor = pattern_child.walk();
                             for ta_child in pattern_child.children(&mut ta_cursor) {
                                 if ta_child.kind() == "type_identifier" {
-                                    field_type =
-                                        Some(TypeRef::new(Self::node_text(ta_child, source)));
+                                    field_type = Some(Self::type_ref(ta_child, source));
                                 }
                             }
                         }
@@ -420,14 +496,117 @@ impl SwiftProcessor {
             Ok(Some(Field {
                 name,
                 visibility,
+                source_visibility: Some(source_visibility),
                 field_type,
                 default_value: None,
-                modifiers: vec![],
+                modifiers,
                 line,
+                deprecated: Self::parse_deprecation(node, source),
+                metadata: BTreeMap::new(),
             }))
         }
     }
 
+    /// Parse a computed property (`var area: Int { ... }`) or a protocol
+    /// property requirement (`var name: String { get set }`)
+    ///
+    /// Stored properties have no accessor block and yield `None`.
+    fn parse_accessor_property(node: TSNode, source: &str) -> Result<Option<Property>> {
+        let mut cursor = node.walk();
+        let Some(block) = node.children(&mut cursor).find(|child| {
+            matches!(
+                child.kind(),
+                "computed_property" | "protocol_property_requirements"
+            )
+        }) else {
+            return Ok(None);
+        };
+        let Some(field) = Self::parse_property(node, source)? else {
+            return Ok(None);
+        };
+
+        let is_computed = block.kind() == "computed_property";
+        let mut accessors = Vec::new();
+        let mut block_cursor = block.walk();
+        for child in block.named_children(&mut block_cursor) {
+            match child.kind() {
+                "computed_getter" | "getter_specifier" => {
+                    accessors.push(Accessor::new(AccessorKind::Get));
+                }
+                "computed_setter" | "setter_specifier" => {
+                    accessors.push(Accessor::new(AccessorKind::Set));
+                }
+                _ => {}
+            }
+        }
+        // Shorthand `var area: Int { width * height }` is an implicit getter
+        if accessors.is_empty() {
+            accessors.push(Accessor::new(AccessorKind::Get));
+        }
+
+        let property_type = field.field_type.or_else(|| {
+            let mut type_cursor = node.walk();
+            node.children(&mut type_cursor)
+                .find(|child| child.kind() == "type_annotation")
+                .and_then(|annotation| annotation.child_by_field_name("type"))
+                .map(|ty| Self::type_ref(ty, source))
+        });
+
+        Ok(Some(Property {
+            name: field.name,
+            visibility: field.visibility,
+            source_visibility: field.source_visibility,
+            modifiers: field.modifiers,
+            property_type,
+            accessors,
+            is_computed,
+            default_value: None,
+            line_start: field.line,
+            line_end: node.end_position().row + 1,
+            deprecated: field.deprecated,
+            metadata: field.metadata,
+        }))
+    }
+
+    /// Parse a global `let`/`var` declaration
+    ///
+    /// Reuses the property parser for name, type and modifiers.
+    fn parse_global_variable(node: TSNode, source: &str) -> Result<Option<Variable>> {
+        let Some(field) = Self::parse_property(node, source)? else {
+            return Ok(None);
+        };
+
+        let mut is_var = false;
+        let mut value = None;
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            if child.kind() == "value_binding_pattern" {
+                is_var = Self::node_text(child, source).starts_with("var");
+            }
+        }
+        if let Some(value_node) = node.child_by_field_name("value") {
+            value = value_preview(&Self::node_text(value_node, source));
+        }
+
+        Ok(Some(Variable {
+            name: field.name,
+            visibility: field.visibility,
+            source_visibility: field.source_visibility,
+            var_kind: if is_var {
+                VariableKind::Var
+            } else {
+                VariableKind::Let
+            },
+            modifiers: field.modifiers,
+            var_type: field.field_type,
+            value,
+            is_mutable: is_var,
+            line: field.line,
+            deprecated: field.deprecated,
+            metadata: field.metadata,
+        }))
+    }
+
     fn parse_body(&self, node: TSNode, source: &str, children: &mut Vec<ir::Node>) -> Result<()> {
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
@@ -438,7 +617,9 @@ impl SwiftProcessor {
                     }
                 }
                 "property_declaration" | "protocol_property_declaration" => {
-                    if let Some(field) = Self::parse_property(child, source)? {
+                    if let Some(property) = Self::parse_accessor_property(child, source)? {
+                        children.push(ir::Node::Property(property));
+                    } else if let Some(field) = Self::parse_property(child, source)? {
                         children.push(ir::Node::Field(field));
                     }
                 }
@@ -447,11 +628,96 @@ impl SwiftProcessor {
                         children.push(ir::Node::Class(class));
                     }
                 }
+                "enum_entry" => {
+                    children.extend(
+                        Self::parse_enum_entry(child, source)
+                            .into_iter()
+                            .map(ir::Node::EnumVariant),
+                    );
+                }
                 _ => {}
             }
         }
         Ok(())
     }
+
+    /// Parse an enum `case`, which may declare several comma-separated cases,
+    /// each with associated values or a raw value
+    fn parse_enum_entry(node: TSNode, source: &str) -> Vec<EnumVariant> {
+        let deprecated = Self::parse_deprecation(node, source);
+        let line = node.start_position().row + 1;
+        let mut variants: Vec<EnumVariant> = Vec::new();
+        let mut after_equals = false;
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "=" => after_equals = true,
+                "simple_identifier" if !after_equals => variants.push(EnumVariant {
+                    name: Self::node_text(child, source),
+                    fields: Vec::new(),
+                    value: None,
+                    arguments: Vec::new(),
+                    children: Vec::new(),
+                    line,
+                    deprecated: deprecated.clone(),
+                    metadata: BTreeMap::new(),
+                }),
+                "enum_type_parameters" => {
+                    if let Some(variant) = variants.last_mut() {
+                        variant.fields = Self::parse_associated_values(child, source);
+                    }
+                }
+                "," => after_equals = false,
+                kind if after_equals && child.is_named() && kind != "comment" => {
+                    if let Some(variant) = variants.last_mut() {
+                        variant.value = value_preview(&Self::node_text(child, source));
+                    }
+                    after_equals = false;
+                }
+                _ => {}
+            }
+        }
+
+        variants
+    }
+
+    /// Parse associated values, e.g. `(code: Int, String)`; unlabeled values
+    /// get an empty name
+    fn parse_associated_values(node: TSNode, source: &str) -> Vec<Parameter> {
+        let mut fields: Vec<Parameter> = Vec::new();
+        let mut label = String::new();
+        let mut after_equals = false;
+
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            match child.kind() {
+                "(" | ")" | ":" | "wildcard_pattern" | "comment" => {}
+                "," => after_equals = false,
+                "=" => after_equals = true,
+                _ if after_equals => {
+                    if let Some(field) = fields.last_mut() {
+                        field.default_value = Some(Self::node_text(child, source));
+                    }
+                }
+                "simple_identifier"
+                    if child.next_sibling().is_some_and(|next| next.kind() == ":") =>
+                {
+                    label = Self::node_text(child, source);
+                }
+                _ => fields.push(Parameter {
+                    name: std::mem::take(&mut label),
+                    param_type: Self::type_ref(child, source),
+                    default_value: None,
+                    is_variadic: false,
+                    is_optional: false,
+                    decorators: Vec::new(),
+                }),
+            }
+        }
+
+        fields
+    }
 }
 
 impl LanguageProcessor for SwiftProcessor {
@@ -503,10 +769,25 @@ impl LanguageProcessor for SwiftProcessor {
                         file.children.push(ir::Node::Function(func));
                     }
                 }
+                "property_declaration" => {
+                    if let Some(variable) = Self::parse_global_variable(child, source)? {
+                        file.children.push(ir::Node::Variable(variable));
+                    }
+                }
                 _ => {}
             }
         }
 
+        // Same-named functions in one scope are overloads
+        group_overloads(&mut file.children);
+
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(path, source, tree.root_node())
+        {
+            return Err(err);
+        }
+
         Ok(file)
     }
 }
@@ -520,6 +801,8 @@ impl Default for SwiftProcessor {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use distiller_core::ir::Visibility;
+    use distiller_core::type_merge::{DECLARED_IN, merge_file};
     use std::path::PathBuf;
 
     #[test]
@@ -557,11 +840,62 @@ enum TemperatureScale: String {
             assert!(enum_decl.decorators.contains(&"enum".to_string()));
             assert_eq!(enum_decl.implements.len(), 1);
             assert_eq!(enum_decl.implements[0].name, "String");
+
+            let ir::Node::EnumVariant(celsius) = &enum_decl.children[0] else {
+                panic!("Expected an enum case");
+            };
+            assert_eq!(celsius.name, "celsius");
+            assert_eq!(celsius.value.as_deref(), Some("\"°C\""));
         } else {
             panic!("Expected an enum");
         }
     }
 
+    #[test]
+    fn test_extension_merges_into_type() {
+        let source = r#"
+struct Point {
+    var x: Int
+}
+
+extension Point: CustomStringConvertible {
+    var description: String { "(\(x))" }
+    func moved(by dx: Int) -> Point { Point(x: x + dx) }
+}
+
+extension Array<Int> {
+    func total() -> Int { 0 }
+}
+"#;
+        let processor = SwiftProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let mut file = processor
+            .process(source, &PathBuf::from("Point.swift"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 3);
+        let ir::Node::Class(array) = &file.children[2] else {
+            panic!("Expected the Array extension");
+        };
+        assert_eq!(array.name, "Array");
+        assert_eq!(
+            array.metadata.get(FRAGMENT).map(String::as_str),
+            Some("extension")
+        );
+
+        merge_file(&mut file);
+        assert_eq!(file.children.len(), 2);
+        let ir::Node::Class(point) = &file.children[0] else {
+            panic!("Expected Point");
+        };
+        assert_eq!(point.implements[0].name, "CustomStringConvertible");
+        assert!(point.children.iter().any(|child| matches!(
+            child,
+            ir::Node::Function(func) if func.name == "moved"
+                && func.metadata.get(DECLARED_IN).map(String::as_str) == Some("Point.swift:8")
+        )));
+    }
+
     #[test]
     fn test_struct_with_protocol() {
         let source = r#"
@@ -820,11 +1154,60 @@ enum Result<T, E> {
             assert_eq!(enum_decl.type_params.len(), 2, "Expected 2 type parameters");
             assert_eq!(enum_decl.type_params[0].name, "T");
             assert_eq!(enum_decl.type_params[1].name, "E");
+
+            let cases: Vec<_> = enum_decl
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    ir::Node::EnumVariant(case) => Some(case),
+                    _ => None,
+                })
+                .collect();
+            assert_eq!(cases.len(), 3);
+            assert_eq!(cases[0].name, "success");
+            assert_eq!(cases[0].fields.len(), 1);
+            assert_eq!(cases[0].fields[0].name, "");
+            assert_eq!(cases[0].fields[0].param_type.name, "T");
+            assert!(cases[2].fields.is_empty());
         } else {
             panic!("Expected an enum");
         }
     }
 
+    #[test]
+    fn test_enum_cases_on_one_line() {
+        let source = r#"
+enum Barcode {
+    case upc(Int, Int, Int, Int), qrCode(payload: String)
+    case none
+}
+"#;
+        let processor = SwiftProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Test.swift"), &opts)
+            .unwrap();
+
+        let ir::Node::Class(barcode) = &file.children[0] else {
+            panic!("Expected an enum");
+        };
+        let cases: Vec<_> = barcode
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                ir::Node::EnumVariant(case) => Some(case),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(cases.len(), 3);
+        assert_eq!(cases[0].name, "upc");
+        assert_eq!(cases[0].fields.len(), 4);
+        assert_eq!(cases[1].name, "qrCode");
+        assert_eq!(cases[1].fields[0].name, "payload");
+        assert_eq!(cases[1].fields[0].param_type.name, "String");
+        assert_eq!(cases[2].name, "none");
+    }
+
     #[test]
     fn test_optional_types() {
         let source = r#"
@@ -982,9 +1365,24 @@ struct Rectangle {
                 })
                 .collect();
 
-            assert!(fields.len() >= 2, "Expected at least 2 properties");
+            assert_eq!(fields.len(), 2);
             assert_eq!(fields[0].name, "width");
             assert_eq!(fields[1].name, "height");
+
+            let properties: Vec<_> = struct_decl
+                .children
+                .iter()
+                .filter_map(|n| match n {
+                    ir::Node::Property(p) => Some(p),
+                    _ => None,
+                })
+                .collect();
+            assert_eq!(properties.len(), 2);
+            assert_eq!(properties[0].name, "area");
+            assert!(properties[0].is_computed);
+            assert_eq!(properties[0].accessors.len(), 1);
+            assert_eq!(properties[1].name, "perimeter");
+            assert_eq!(properties[1].accessors[0].accessor_kind, AccessorKind::Get);
         } else {
             panic!("Expected a struct");
         }
@@ -1032,6 +1430,39 @@ class DataManager: Codable, Equatable, Hashable {
         }
     }
 
+    #[test]
+    fn test_available_deprecated() {
+        let source = r#"
+struct Client {
+    @available(*, deprecated, renamed: "send(_:)")
+    func post(_ body: String) {}
+
+    func send(_ body: String) {}
+}
+"#;
+        let processor = SwiftProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Client.swift"), &opts)
+            .unwrap();
+
+        let ir::Node::Class(class) = &file.children[0] else {
+            panic!("Expected a class");
+        };
+        let methods: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                ir::Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+
+        let deprecated = methods[0].deprecated.as_ref().expect("post is deprecated");
+        assert_eq!(deprecated.replacement.as_deref(), Some("send(_:)"));
+        assert!(methods[1].deprecated.is_none());
+    }
+
     #[test]
     fn test_private_visibility() {
         let source = r#"
@@ -1082,11 +1513,88 @@ class Service {
             let private_method = funcs.iter().find(|f| f.name == "privateMethod");
             assert!(private_method.is_some());
             assert_eq!(private_method.unwrap().visibility, Visibility::Private);
+
+            let fileprivate_method = funcs
+                .iter()
+                .find(|f| f.name == "fileprivateMethod")
+                .unwrap();
+            assert_eq!(fileprivate_method.visibility, Visibility::Private);
+            assert_eq!(
+                fileprivate_method.source_visibility,
+                Some(SourceVisibility::FilePrivate)
+            );
         } else {
             panic!("Expected a class");
         }
     }
 
+    #[test]
+    fn test_function_modifiers() {
+        let source = r#"
+struct Counter {
+    mutating func increment() {}
+
+    func load() async throws -> Data {}
+
+    nonisolated func describe() -> String {}
+}
+"#;
+        let processor = SwiftProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Counter.swift"), &opts)
+            .unwrap();
+
+        if let ir::Node::Class(class) = &file.children[0] {
+            let modifiers_of = |name: &str| {
+                class
+                    .children
+                    .iter()
+                    .find_map(|n| match n {
+                        ir::Node::Function(f) if f.name == name => Some(f.modifiers.clone()),
+                        _ => None,
+                    })
+                    .unwrap_or_default()
+            };
+            assert_eq!(modifiers_of("increment"), [Modifier::Mutating]);
+            assert_eq!(modifiers_of("load"), [Modifier::Async, Modifier::Throws]);
+            assert_eq!(modifiers_of("describe"), [Modifier::Nonisolated]);
+        } else {
+            panic!("Expected a struct");
+        }
+    }
+
+    #[test]
+    fn test_global_variables() {
+        let source = r#"
+let maxRetries: Int = 3
+var currentUser: String = "guest"
+"#;
+        let processor = SwiftProcessor::new().unwrap();
+        let opts = ProcessOptions::default();
+        let file = processor
+            .process(source, &PathBuf::from("Globals.swift"), &opts)
+            .unwrap();
+
+        let vars: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                ir::Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(vars.len(), 2);
+
+        assert_eq!(vars[0].name, "maxRetries");
+        assert_eq!(vars[0].var_kind, VariableKind::Let);
+        assert!(!vars[0].is_mutable);
+
+        assert_eq!(vars[1].name, "currentUser");
+        assert_eq!(vars[1].var_kind, VariableKind::Var);
+        assert!(vars[1].is_mutable);
+    }
+
     #[test]
     fn test_init_method() {
         let source = r#"
@@ -8,16 +8,25 @@
 //! - Generics and decorators
 
 use distiller_core::error::Result;
-use distiller_core::parser::ParserPool;
+use distiller_core::parser::{
+    ParserPool,
+    comments::preceding_comment,
+    overloads::{OVERLOAD_SIGNATURE, group_overloads},
+    syntax::syntax_error,
+    types::{TypeSyntax, parse_type},
+    values::value_preview,
+};
 use distiller_core::{
     error::DistilError,
     ir::{
-        Class, Field, File, Function, Import, ImportedSymbol, Interface, Modifier, Node, Parameter,
-        TypeParam, TypeRef, Visibility,
+        Accessor, AccessorKind, Class, Deprecation, Enum, EnumVariant, Field, File, Function,
+        Import, ImportedSymbol, Interface, Modifier, Module, Node, Parameter, Property, TypeParam,
+        TypeRef, Variable, VariableKind, Visibility,
     },
     options::ProcessOptions,
     processor::language::LanguageProcessor,
 };
+use std::collections::BTreeMap;
 use std::path::Path;
 use std::sync::Arc;
 use tree_sitter::TreeCursor;
@@ -43,9 +52,9 @@ impl TypeScriptProcessor {
             Ok(tree_sitter_typescript::LANGUAGE_TYPESCRIPT.into())
         })?;
         let parser = parser_guard.get_mut();
-        let tree = parser
-            .parse(source, None)
-            .ok_or_else(|| DistilError::TreeSitter("Failed to parse TypeScript".to_string()))?;
+        let tree = parser.parse(source, None).ok_or_else(|| {
+            DistilError::parse_error(filename, "Failed to parse TypeScript source")
+        })?;
 
         let root_node = tree.root_node();
         let mut file = File {
@@ -54,7 +63,17 @@ impl TypeScriptProcessor {
         };
 
         let mut cursor = root_node.walk();
-        self.process_node(root_node, &mut file, source, &mut cursor)?;
+        self.process_node(root_node, &mut file.children, source, &mut cursor)?;
+
+        // Overload signatures hide the implementation signature that follows them
+        group_overloads(&mut file.children);
+
+        // Nothing recovered: report where parsing went wrong
+        if file.children.is_empty()
+            && let Some(err) = syntax_error(filename, source, tree.root_node())
+        {
+            return Err(err);
+        }
 
         Ok(file)
     }
@@ -62,46 +81,69 @@ impl TypeScriptProcessor {
     fn process_node(
         &self,
         node: tree_sitter::Node,
-        file: &mut File,
+        children: &mut Vec<Node>,
         source: &str,
         _cursor: &mut TreeCursor,
     ) -> Result<()> {
         match node.kind() {
             "import_statement" => {
                 if let Some(import) = self.parse_import(node, source)? {
-                    file.children.push(Node::Import(import));
+                    children.push(Node::Import(import));
                 }
             }
             "export_statement" => {
                 // Handle export { ... } and export * from '...'
                 let mut child_cursor = node.walk();
                 for child in node.children(&mut child_cursor) {
-                    self.process_node(child, file, source, _cursor)?;
+                    self.process_node(child, children, source, _cursor)?;
                 }
             }
             "class_declaration" => {
                 if let Some(class) = self.parse_class(node, source)? {
-                    file.children.push(Node::Class(class));
+                    children.push(Node::Class(class));
                 }
             }
             "interface_declaration" => {
                 if let Some(interface) = self.parse_interface(node, source)? {
-                    file.children.push(Node::Interface(interface));
+                    children.push(Node::Interface(interface));
                 }
             }
-            "function_declaration" => {
+            "function_declaration" | "generator_function_declaration" => {
                 if let Some(function) = self.parse_function(node, source)? {
-                    file.children.push(Node::Function(function));
+                    children.push(Node::Function(function));
+                }
+            }
+            "function_signature" => {
+                // Overload signature (or an ambient declaration)
+                if let Some(mut function) = self.parse_function(node, source)? {
+                    function
+                        .metadata
+                        .insert(OVERLOAD_SIGNATURE.to_string(), String::new());
+                    children.push(Node::Function(function));
                 }
             }
+            "enum_declaration" => {
+                if let Some(enum_decl) = Self::parse_enum(node, source) {
+                    children.push(Node::Enum(enum_decl));
+                }
+            }
+            "internal_module" | "module" => {
+                children.push(Node::Module(self.parse_module(node, source, _cursor)?));
+            }
             "lexical_declaration" | "variable_declaration" => {
                 // Handle const/let/var declarations that might be functions
+                let module_level = Self::is_module_level(node);
                 let mut child_cursor = node.walk();
                 for child in node.children(&mut child_cursor) {
-                    if child.kind() == "variable_declarator"
-                        && let Some(func) = self.parse_variable_function(child, source)?
+                    if child.kind() != "variable_declarator" {
+                        continue;
+                    }
+                    if let Some(func) = self.parse_variable_function(child, source)? {
+                        children.push(Node::Function(func));
+                    } else if module_level
+                        && let Some(variable) = self.parse_variable(node, child, source)?
                     {
-                        file.children.push(Node::Function(func));
+                        children.push(Node::Variable(variable));
                     }
                 }
             }
@@ -109,7 +151,7 @@ impl TypeScriptProcessor {
                 // Recursively process children
                 let mut child_cursor = node.walk();
                 for child in node.children(&mut child_cursor) {
-                    self.process_node(child, file, source, _cursor)?;
+                    self.process_node(child, children, source, _cursor)?;
                 }
             }
         }
@@ -251,8 +293,19 @@ impl TypeScriptProcessor {
                     for body_child in child.children(&mut body_cursor) {
                         match body_child.kind() {
                             "method_definition" | "method_signature" => {
-                                if let Some(method) = self.parse_method(body_child, source)? {
-                                    children.push(Node::Function(method));
+                                let Some(mut method) = self.parse_method(body_child, source)?
+                                else {
+                                    continue;
+                                };
+                                // A bodiless method in a class is an overload signature
+                                if body_child.kind() == "method_signature" {
+                                    method
+                                        .metadata
+                                        .insert(OVERLOAD_SIGNATURE.to_string(), String::new());
+                                }
+                                match Self::accessor_kind(body_child) {
+                                    Some(kind) => Self::add_accessor(&mut children, method, kind),
+                                    None => children.push(Node::Function(method)),
                                 }
                             }
                             "field_definition" | "public_field_definition" => {
@@ -275,9 +328,11 @@ impl TypeScriptProcessor {
             return Ok(None);
         }
 
+        let deprecated = Self::parse_deprecation(node, source, &decorators);
         Ok(Some(Class {
             name,
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers,
             decorators,
             type_params,
@@ -286,6 +341,8 @@ impl TypeScriptProcessor {
             children,
             line_start,
             line_end,
+            deprecated,
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -346,14 +403,86 @@ impl TypeScriptProcessor {
         Ok(Some(Interface {
             name,
             visibility: Visibility::Public,
+            source_visibility: None,
             type_params,
             extends,
             children,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source, &[]),
+            metadata: BTreeMap::new(),
         }))
     }
 
+    /// Parse an enum with its members and initializers; `const enum` is
+    /// marked in the metadata
+    fn parse_enum(node: tree_sitter::Node, source: &str) -> Option<Enum> {
+        let name = Self::node_text(node.child_by_field_name("name")?, source);
+
+        let mut variants = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            let mut cursor = body.walk();
+            for member in body.named_children(&mut cursor) {
+                let (name_node, value) = match member.kind() {
+                    "enum_assignment" => (
+                        member.child_by_field_name("name"),
+                        member
+                            .child_by_field_name("value")
+                            .and_then(|value| value_preview(&Self::node_text(value, source))),
+                    ),
+                    "comment" => continue,
+                    _ => (Some(member), None),
+                };
+                let Some(name_node) = name_node else {
+                    continue;
+                };
+                variants.push(Node::EnumVariant(EnumVariant {
+                    name: Self::node_text(name_node, source),
+                    fields: Vec::new(),
+                    value,
+                    arguments: Vec::new(),
+                    children: Vec::new(),
+                    line: member.start_position().row + 1,
+                    deprecated: Self::parse_deprecation(member, source, &[]),
+                    metadata: BTreeMap::new(),
+                }));
+            }
+        }
+
+        let mut metadata = BTreeMap::new();
+        let mut cursor = node.walk();
+        if node
+            .children(&mut cursor)
+            .any(|child| child.kind() == "const")
+        {
+            metadata.insert("const".to_string(), String::new());
+        }
+
+        Some(Enum {
+            name,
+            visibility: Visibility::Public,
+            source_visibility: None,
+            decorators: Vec::new(),
+            type_params: Vec::new(),
+            enum_type: None,
+            children: variants,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source, &[]),
+            metadata,
+        })
+    }
+
+    /// Detect `@deprecated` JSDoc tags
+    fn parse_deprecation(
+        node: tree_sitter::Node,
+        source: &str,
+        decorators: &[String],
+    ) -> Option<Deprecation> {
+        let doc = preceding_comment(node, source);
+        Deprecation::detect(decorators, doc.as_deref())
+    }
+
     fn parse_extends_clause(node: tree_sitter::Node, source: &str) -> Result<Vec<TypeRef>> {
         let mut extends = Vec::new();
         let mut cursor = node.walk();
@@ -432,7 +561,7 @@ impl TypeScriptProcessor {
                     parameters = self.parse_parameters(child, source)?;
                 }
                 "type_annotation" => {
-                    return_type = self.parse_type_annotation(child, source)?;
+                    return_type = Self::parse_type_annotation(child, source);
                 }
                 "type_parameters" => {
                     type_params = Self::parse_type_parameters(child, source)?;
@@ -446,6 +575,9 @@ impl TypeScriptProcessor {
                 "async" => {
                     modifiers.push(Modifier::Async);
                 }
+                "*" => {
+                    modifiers.push(Modifier::Generator);
+                }
                 "decorator" => {
                     decorators.push(Self::node_text(child, source));
                 }
@@ -457,9 +589,11 @@ impl TypeScriptProcessor {
             return Ok(None);
         }
 
+        let deprecated = Self::parse_deprecation(node, source, &decorators);
         Ok(Some(Function {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             decorators,
             type_params,
@@ -468,6 +602,8 @@ impl TypeScriptProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated,
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -493,7 +629,7 @@ impl TypeScriptProcessor {
                     parameters = self.parse_parameters(child, source)?;
                 }
                 "type_annotation" => {
-                    return_type = self.parse_type_annotation(child, source)?;
+                    return_type = Self::parse_type_annotation(child, source);
                 }
                 "type_parameters" => {
                     type_params = Self::parse_type_parameters(child, source)?;
@@ -501,6 +637,9 @@ impl TypeScriptProcessor {
                 "async" => {
                     modifiers.push(Modifier::Async);
                 }
+                "*" => {
+                    modifiers.push(Modifier::Generator);
+                }
                 _ => {}
             }
         }
@@ -512,6 +651,7 @@ impl TypeScriptProcessor {
         Ok(Some(Function {
             name,
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers,
             decorators: Vec::new(),
             type_params,
@@ -520,6 +660,101 @@ impl TypeScriptProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated: Self::parse_deprecation(node, source, &[]),
+            metadata: BTreeMap::new(),
+        }))
+    }
+
+    /// Parse a `namespace` / `module` / `declare module` block into a module
+    ///
+    /// Ambient module names keep their quotes (`"express"`).
+    fn parse_module(
+        &self,
+        node: tree_sitter::Node,
+        source: &str,
+        cursor: &mut TreeCursor,
+    ) -> Result<Module> {
+        let mut children = Vec::new();
+        if let Some(body) = node.child_by_field_name("body") {
+            self.process_node(body, &mut children, source, cursor)?;
+        }
+
+        Ok(Module {
+            name: node
+                .child_by_field_name("name")
+                .map(|name| Self::node_text(name, source))
+                .unwrap_or_default(),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            decorators: Vec::new(),
+            children,
+            line_start: node.start_position().row + 1,
+            line_end: node.end_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source, &[]),
+            metadata: BTreeMap::new(),
+        })
+    }
+
+    /// Check if a declaration sits at the top level of the file or a namespace
+    fn is_module_level(node: tree_sitter::Node) -> bool {
+        let scope = match node.parent() {
+            Some(parent) if parent.kind() == "export_statement" => parent.parent(),
+            parent => parent,
+        };
+        scope.is_some_and(|scope| match scope.kind() {
+            "program" => true,
+            "statement_block" => scope
+                .parent()
+                .is_some_and(|owner| matches!(owner.kind(), "internal_module" | "module")),
+            _ => false,
+        })
+    }
+
+    /// Parse a non-function declarator of a `const`/`let`/`var` declaration
+    ///
+    /// Destructuring patterns and class expressions are skipped.
+    fn parse_variable(
+        &self,
+        declaration: tree_sitter::Node,
+        declarator: tree_sitter::Node,
+        source: &str,
+    ) -> Result<Option<Variable>> {
+        let Some(name_node) = declarator
+            .child_by_field_name("name")
+            .filter(|n| n.kind() == "identifier")
+        else {
+            return Ok(None);
+        };
+        let value = declarator.child_by_field_name("value");
+        if value.is_some_and(|v| v.kind() == "class") {
+            return Ok(None);
+        }
+
+        let var_kind = match declaration
+            .child(0)
+            .map(|k| Self::node_text(k, source))
+            .as_deref()
+        {
+            Some("const") => VariableKind::Const,
+            Some("let") => VariableKind::Let,
+            _ => VariableKind::Var,
+        };
+        let var_type = declarator
+            .child_by_field_name("type")
+            .and_then(|annotation| Self::parse_type_annotation(annotation, source));
+
+        Ok(Some(Variable {
+            name: Self::node_text(name_node, source),
+            visibility: Visibility::Public,
+            source_visibility: None,
+            var_kind,
+            modifiers: vec![],
+            var_type,
+            value: value.and_then(|v| value_preview(&Self::node_text(v, source))),
+            is_mutable: var_kind != VariableKind::Const,
+            line: declarator.start_position().row + 1,
+            deprecated: Self::parse_deprecation(declaration, source, &[]),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -539,6 +774,7 @@ impl TypeScriptProcessor {
                 "arrow_function" | "function" | "function_expression" => {
                     if let Some(mut func) = self.parse_arrow_function(child, source)? {
                         func.name = name;
+                        func.deprecated = Self::parse_deprecation(node, source, &[]);
                         return Ok(Some(func));
                     }
                 }
@@ -569,7 +805,7 @@ impl TypeScriptProcessor {
                     parameters = self.parse_parameters(child, source)?;
                 }
                 "type_annotation" => {
-                    return_type = self.parse_type_annotation(child, source)?;
+                    return_type = Self::parse_type_annotation(child, source);
                 }
                 "type_parameters" => {
                     type_params = Self::parse_type_parameters(child, source)?;
@@ -577,6 +813,9 @@ impl TypeScriptProcessor {
                 "async" => {
                     modifiers.push(Modifier::Async);
                 }
+                "*" => {
+                    modifiers.push(Modifier::Generator);
+                }
                 _ => {}
             }
         }
@@ -584,6 +823,7 @@ impl TypeScriptProcessor {
         Ok(Some(Function {
             name: String::new(), // Will be filled by caller
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers,
             decorators: Vec::new(),
             type_params,
@@ -592,6 +832,8 @@ impl TypeScriptProcessor {
             implementation: None,
             line_start,
             line_end,
+            deprecated: None,
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -618,7 +860,7 @@ impl TypeScriptProcessor {
                     name = Self::node_text(child, source);
                 }
                 "type_annotation" => {
-                    field_type = self.parse_type_annotation(child, source)?;
+                    field_type = Self::parse_type_annotation(child, source);
                 }
                 "accessibility_modifier" => {
                     visibility = Self::parse_visibility(child, source);
@@ -645,10 +887,13 @@ impl TypeScriptProcessor {
         Ok(Some(Field {
             name,
             visibility,
+            source_visibility: None,
             modifiers,
             field_type,
             default_value: None,
             line: node.start_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source, &[]),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -667,7 +912,7 @@ impl TypeScriptProcessor {
                     name = Self::node_text(child, source);
                 }
                 "type_annotation" => {
-                    field_type = self.parse_type_annotation(child, source)?;
+                    field_type = Self::parse_type_annotation(child, source);
                 }
                 _ => {}
             }
@@ -680,10 +925,13 @@ impl TypeScriptProcessor {
         Ok(Some(Field {
             name,
             visibility: Visibility::Public,
+            source_visibility: None,
             modifiers: Vec::new(),
             field_type,
             default_value: None,
             line: node.start_position().row + 1,
+            deprecated: Self::parse_deprecation(node, source, &[]),
+            metadata: BTreeMap::new(),
         }))
     }
 
@@ -713,7 +961,7 @@ impl TypeScriptProcessor {
                             name = Self::node_text(child, source);
                         }
                         "type_annotation" => {
-                            if let Some(t) = self.parse_type_annotation(child, source)? {
+                            if let Some(t) = Self::parse_type_annotation(child, source) {
                                 param_type = t;
                             }
                         }
@@ -741,67 +989,90 @@ impl TypeScriptProcessor {
         }
     }
 
+    /// Type of a `type_annotation` node (`: T`)
+    fn parse_type_annotation(node: tree_sitter::Node, source: &str) -> Option<TypeRef> {
+        let mut cursor = node.walk();
+        let type_node = node.named_children(&mut cursor).next()?;
+        Some(parse_type(
+            &Self::node_text(type_node, source),
+            TypeSyntax::TypeScript,
+        ))
+    }
+
     #[allow(clippy::match_same_arms)]
-    fn parse_type_annotation(
-        &self,
-        node: tree_sitter::Node,
-        source: &str,
-    ) -> Result<Option<TypeRef>> {
+    /// `get`/`set` keyword of an accessor method, if any
+    fn accessor_kind(node: tree_sitter::Node) -> Option<AccessorKind> {
         let mut cursor = node.walk();
-        for child in node.children(&mut cursor) {
-            match child.kind() {
-                "type_identifier" | "predefined_type" => {
-                    return Ok(Some(TypeRef::new(Self::node_text(child, source))));
-                }
-                "generic_type" => {
-                    return self.parse_generic_type(child, source);
-                }
-                "union_type" | "intersection_type" => {
-                    return Ok(Some(TypeRef::new(Self::node_text(child, source))));
-                }
-                _ => {}
-            }
-        }
-        Ok(None)
+        node.children(&mut cursor)
+            .find_map(|child| match child.kind() {
+                "get" => Some(AccessorKind::Get),
+                "set" => Some(AccessorKind::Set),
+                _ => None,
+            })
     }
 
-    fn parse_generic_type(&self, node: tree_sitter::Node, source: &str) -> Result<Option<TypeRef>> {
-        let mut name = String::new();
-        let mut type_args = Vec::new();
+    /// Fold a `get x()`/`set x()` method into the class property of the
+    /// same name, creating the property on first sight
+    ///
+    /// The type comes from the getter's return type or the setter's
+    /// parameter; an accessor only carries its own visibility when it
+    /// differs from the property's.
+    fn add_accessor(children: &mut Vec<Node>, method: Function, kind: AccessorKind) {
+        let value_type = match kind {
+            AccessorKind::Get => method.return_type,
+            _ => method
+                .parameters
+                .into_iter()
+                .next()
+                .map(|param| param.param_type),
+        };
 
-        let mut cursor = node.walk();
-        for child in node.children(&mut cursor) {
-            match child.kind() {
-                "type_identifier" => {
-                    name = Self::node_text(child, source);
-                }
-                "type_arguments" => {
-                    let mut args_cursor = child.walk();
-                    for arg_child in child.children(&mut args_cursor) {
-                        if let Some(t) = self.parse_type_annotation(arg_child, source)? {
-                            type_args.push(t);
-                        }
-                    }
-                }
-                _ => {}
+        let existing = children.iter_mut().find_map(|node| match node {
+            Node::Property(property) if property.name == method.name => Some(property),
+            _ => None,
+        });
+        let property = match existing {
+            Some(property) => property,
+            None => {
+                children.push(Node::Property(Property {
+                    name: method.name,
+                    visibility: method.visibility,
+                    source_visibility: None,
+                    modifiers: method.modifiers,
+                    property_type: None,
+                    accessors: Vec::new(),
+                    is_computed: true,
+                    default_value: None,
+                    line_start: method.line_start,
+                    line_end: method.line_end,
+                    deprecated: method.deprecated.clone(),
+                    metadata: BTreeMap::new(),
+                }));
+                let Some(Node::Property(property)) = children.last_mut() else {
+                    unreachable!("property was just pushed");
+                };
+                property
             }
-        }
+        };
 
-        if name.is_empty() {
-            return Ok(None);
+        let mut accessor = Accessor::new(kind);
+        if method.visibility != property.visibility {
+            accessor.visibility = Some(method.visibility);
+        }
+        property.accessors.push(accessor);
+        property
+            .accessors
+            .sort_by_key(|accessor| accessor.accessor_kind);
+        if property.property_type.is_none() {
+            property.property_type = value_type;
+        }
+        property.line_start = property.line_start.min(method.line_start);
+        property.line_end = property.line_end.max(method.line_end);
+        if property.deprecated.is_none() {
+            property.deprecated = method.deprecated;
         }
-
-        Ok(Some(TypeRef {
-            name,
-            package: None,
-            type_args,
-            is_nullable: false,
-            is_array: false,
-            array_dims: None,
-        }))
     }
 
-    #[allow(clippy::match_same_arms)]
     fn parse_visibility(node: tree_sitter::Node, source: &str) -> Visibility {
         match Self::node_text(node, source).as_str() {
             "private" => Visibility::Private,
@@ -1125,13 +1396,165 @@ enum Status {
 "#;
         let opts = ProcessOptions::default();
 
-        let _file = processor
+        let file = processor
             .process(source, &PathBuf::from("test.ts"), &opts)
             .unwrap();
 
-        // Enums might not be fully captured in current implementation
-        // This test verifies no errors occur during parsing
-        // Parsing should not error (no assertion needed for non-negative length)
+        let Node::Enum(status) = &file.children[0] else {
+            panic!("Expected enum, got {:?}", file.children[0]);
+        };
+        assert_eq!(status.name, "Status");
+
+        let members: Vec<_> = status
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::EnumVariant(member) => Some((member.name.as_str(), member.value.as_deref())),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(
+            members,
+            [
+                ("Active", Some("\"ACTIVE\"")),
+                ("Inactive", Some("\"INACTIVE\"")),
+                ("Pending", Some("1")),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_const_enum() {
+        let processor = TypeScriptProcessor::new().unwrap();
+        let source = r#"
+export const enum Direction {
+    Up,
+    Down,
+}
+"#;
+        let opts = ProcessOptions::default();
+
+        let file = processor
+            .process(source, &PathBuf::from("test.ts"), &opts)
+            .unwrap();
+
+        let Node::Enum(direction) = &file.children[0] else {
+            panic!("Expected enum, got {:?}", file.children[0]);
+        };
+        assert!(direction.metadata.contains_key("const"));
+        assert_eq!(direction.children.len(), 2);
+        assert!(
+            matches!(&direction.children[0], Node::EnumVariant(v) if v.name == "Up" && v.value.is_none())
+        );
+    }
+
+    #[test]
+    fn test_accessor_properties() {
+        let source = r#"
+class Temperature {
+    private celsius = 0;
+
+    get fahrenheit(): number {
+        return this.celsius * 1.8 + 32;
+    }
+
+    set fahrenheit(value: number) {
+        this.celsius = (value - 32) / 1.8;
+    }
+
+    get kelvin(): number {
+        return this.celsius + 273.15;
+    }
+}
+"#;
+        let processor = TypeScriptProcessor::new().unwrap();
+        let file = processor
+            .process(
+                source,
+                &PathBuf::from("temperature.ts"),
+                &ProcessOptions::default(),
+            )
+            .unwrap();
+
+        let Node::Class(class) = &file.children[0] else {
+            panic!("Expected class node");
+        };
+        let properties: Vec<_> = class
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Property(p) => Some(p),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(properties.len(), 2);
+        assert!(
+            !class
+                .children
+                .iter()
+                .any(|n| matches!(n, Node::Function(_)))
+        );
+
+        assert_eq!(properties[0].name, "fahrenheit");
+        assert!(properties[0].is_computed);
+        assert_eq!(
+            properties[0]
+                .property_type
+                .as_ref()
+                .map(|t| t.name.as_str()),
+            Some("number")
+        );
+        let kinds: Vec<_> = properties[0]
+            .accessors
+            .iter()
+            .map(|a| a.accessor_kind)
+            .collect();
+        assert_eq!(kinds, vec![AccessorKind::Get, AccessorKind::Set]);
+
+        assert_eq!(properties[1].name, "kelvin");
+        assert_eq!(properties[1].accessors.len(), 1);
+    }
+
+    #[test]
+    fn test_overload_signatures() {
+        let source = r#"
+export function parse(input: string): Ast;
+export function parse(input: Buffer, encoding: string): Ast;
+export function parse(input: any, encoding?: string): Ast {
+    return build(input, encoding);
+}
+
+class Logger {
+    log(message: string): void;
+    log(level: number, message: string): void;
+    log(a: any, b?: any): void {}
+}
+"#;
+        let processor = TypeScriptProcessor::new().unwrap();
+        let file = processor
+            .process(
+                source,
+                &PathBuf::from("parse.ts"),
+                &ProcessOptions::default(),
+            )
+            .unwrap();
+
+        let Node::OverloadSet(parse) = &file.children[0] else {
+            panic!("Expected overload set, got {:?}", file.children[0]);
+        };
+        assert_eq!(parse.name, "parse");
+        assert_eq!(parse.count, 2);
+        assert!(parse.signatures.iter().all(|f| f.metadata.is_empty()));
+        assert_eq!(parse.signatures[1].parameters.len(), 2);
+
+        let Node::Class(logger) = &file.children[1] else {
+            panic!("Expected class");
+        };
+        assert_eq!(logger.children.len(), 1);
+        let Node::OverloadSet(log) = &logger.children[0] else {
+            panic!("Expected overload set");
+        };
+        assert_eq!(log.count, 2);
     }
 
     #[test]
@@ -1245,6 +1668,45 @@ const multiply = (x: number, y: number) => x * y;
         assert_eq!(funcs[1].name, "multiply");
     }
 
+    #[test]
+    fn test_module_variables() {
+        let processor = TypeScriptProcessor::new().unwrap();
+        let source = r#"
+export const API_URL: string = "https://example.com";
+let retries = 3;
+const handler = () => retries;
+
+function setup() {
+    const local = 1;
+}
+"#;
+        let opts = ProcessOptions::default();
+
+        let file = processor
+            .process(source, &PathBuf::from("test.ts"), &opts)
+            .unwrap();
+
+        let vars: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Variable(v) => Some(v),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(vars.len(), 2);
+
+        assert_eq!(vars[0].name, "API_URL");
+        assert_eq!(vars[0].var_kind, VariableKind::Const);
+        assert_eq!(vars[0].var_type.as_ref().unwrap().name, "string");
+        assert_eq!(vars[0].value.as_deref(), Some("\"https://example.com\""));
+        assert!(!vars[0].is_mutable);
+
+        assert_eq!(vars[1].name, "retries");
+        assert_eq!(vars[1].var_kind, VariableKind::Let);
+        assert!(vars[1].is_mutable);
+    }
+
     #[test]
     fn test_class_with_decorators() {
         let processor = TypeScriptProcessor::new().unwrap();
@@ -1277,6 +1739,46 @@ class UserComponent {
         }
     }
 
+    #[test]
+    fn test_jsdoc_deprecated() {
+        let processor = TypeScriptProcessor::new().unwrap();
+        let source = r#"
+/**
+ * Loads a user.
+ * @deprecated Use {@link fetchUser} instead.
+ */
+export function loadUser(id: string): User {
+    return fetchUser(id);
+}
+
+export function fetchUser(id: string): User {
+    return db.get(id);
+}
+"#;
+        let opts = ProcessOptions::default();
+
+        let file = processor
+            .process(source, &PathBuf::from("test.ts"), &opts)
+            .unwrap();
+
+        let funcs: Vec<_> = file
+            .children
+            .iter()
+            .filter_map(|n| match n {
+                Node::Function(f) => Some(f),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(funcs.len(), 2);
+
+        let deprecated = funcs[0]
+            .deprecated
+            .as_ref()
+            .expect("loadUser is deprecated");
+        assert_eq!(deprecated.replacement.as_deref(), Some("fetchUser"));
+        assert!(funcs[1].deprecated.is_none());
+    }
+
     #[test]
     fn test_namespace() {
         let processor = TypeScriptProcessor::new().unwrap();
@@ -1293,13 +1795,46 @@ namespace Utils {
 "#;
         let opts = ProcessOptions::default();
 
-        let _file = processor
+        let file = processor
             .process(source, &PathBuf::from("test.ts"), &opts)
             .unwrap();
 
-        // Namespaces might be processed as nested structures
-        // This test verifies parsing doesn't error
-        // Parsing should not error (no assertion needed for non-negative length)
+        assert_eq!(file.children.len(), 1);
+        let Node::Module(module) = &file.children[0] else {
+            panic!("Expected namespace module");
+        };
+        assert_eq!(module.name, "Utils");
+        assert!(matches!(&module.children[0], Node::Function(f) if f.name == "format"));
+        assert!(matches!(&module.children[1], Node::Class(c) if c.name == "Helper"));
+    }
+
+    #[test]
+    fn test_ambient_module() {
+        let processor = TypeScriptProcessor::new().unwrap();
+        let source = r#"
+declare module "express" {
+    namespace Express.Http {
+        const VERSION: string;
+    }
+    export function json(): void;
+}
+"#;
+        let opts = ProcessOptions::default();
+
+        let file = processor
+            .process(source, &PathBuf::from("express.d.ts"), &opts)
+            .unwrap();
+
+        assert_eq!(file.children.len(), 1);
+        let Node::Module(ambient) = &file.children[0] else {
+            panic!("Expected ambient module");
+        };
+        assert_eq!(ambient.name, "\"express\"");
+        let Node::Module(nested) = &ambient.children[0] else {
+            panic!("Expected nested namespace");
+        };
+        assert_eq!(nested.name, "Express.Http");
+        assert!(matches!(&nested.children[..], [Node::Variable(v)] if v.name == "VERSION"));
     }
 
     #[test]
@@ -1,19 +1,24 @@
 //! Simplified MCP Server for AI Distiller
 //!
-//! Provides 4 core operations via JSON-RPC:
+//! Provides 5 core operations via JSON-RPC:
 //! 1. `distil_directory` - Process entire directory
 //! 2. `distil_file` - Process single file
-//! 3. `list_dir` - List directory contents with metadata
-//! 4. `get_capa` - Get server capabilities
+//! 3. `distil_source` - Process inline source, such as an unsaved buffer
+//! 4. `list_dir` - List directory contents with metadata
+//! 5. `get_capa` - Get server capabilities
 
 use anyhow::{Context, Result};
 use distiller_core::{
-    ProcessOptions,
-    ir::{File, Node},
+    DistilError, ProcessOptions, ProjectRoot, TokenReport, Tokenizer,
+    budget::{self, Fitted},
+    config,
+    error::ErrorCode,
+    ir::{File, Node, Visitor},
     processor::Processor,
+    stripper::Stripper,
 };
 use serde::{Deserialize, Serialize};
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 use tokio::io::{AsyncBufReadExt, AsyncReadExt, AsyncWriteExt, BufReader};
 
 // Language processors
@@ -32,7 +37,7 @@ use lang_swift::SwiftProcessor;
 use lang_typescript::TypeScriptProcessor;
 
 // Formatters
-use formatter_json::JsonFormatter;
+use formatter_json::{JsonFormatter, Metadata};
 use formatter_jsonl::JsonlFormatter;
 use formatter_markdown::MarkdownFormatter;
 use formatter_text::TextFormatter;
@@ -59,6 +64,28 @@ struct JsonRpcResponse {
     error: Option<JsonRpcError>,
 }
 
+impl JsonRpcResponse {
+    /// Response to `id` carrying `result`
+    fn success<T: Serialize>(id: serde_json::Value, result: &T) -> Self {
+        let (result, error) = match serde_json::to_value(result) {
+            Ok(value) => (Some(value), None),
+            Err(e) => (
+                None,
+                Some(JsonRpcError::processing_failed(
+                    format!("Failed to serialize result: {e}"),
+                    None,
+                )),
+            ),
+        };
+        Self {
+            jsonrpc: "2.0".to_string(),
+            id,
+            result,
+            error,
+        }
+    }
+}
+
 /// JSON-RPC error codes
 const ERROR_METHOD_NOT_FOUND: i32 = -32601;
 const ERROR_FILE_NOT_FOUND: i32 = -32011;
@@ -82,14 +109,6 @@ impl JsonRpcError {
         }
     }
 
-    fn file_not_found(path: String) -> Self {
-        Self {
-            code: ERROR_FILE_NOT_FOUND,
-            message: "File or directory not found".to_string(),
-            data: Some(serde_json::json!({ "path": path })),
-        }
-    }
-
     fn processing_failed(message: String, path: Option<String>) -> Self {
         let mut data = serde_json::Map::new();
         if let Some(p) = path {
@@ -105,6 +124,32 @@ impl JsonRpcError {
             },
         }
     }
+
+    /// Error response for a failed operation on `path`
+    ///
+    /// Distiller errors carry their diagnostic (stable `code`, `path`,
+    /// `span`, `snippet`) as `data`; anything else falls back to
+    /// `processing_failed`.
+    fn from_error(err: &anyhow::Error, path: &Path) -> Self {
+        let Some(distil) = err.downcast_ref::<DistilError>() else {
+            return Self::processing_failed(err.to_string(), Some(path.display().to_string()));
+        };
+
+        let code = if distil.code() == ErrorCode::FileNotFound {
+            ERROR_FILE_NOT_FOUND
+        } else {
+            ERROR_PROCESSING_FAILED
+        };
+        let mut diagnostic = distil.diagnostic();
+        if diagnostic.path.is_none() {
+            diagnostic.path = Some(path.to_path_buf());
+        }
+        Self {
+            code,
+            message: distil.to_string(),
+            data: serde_json::to_value(diagnostic).ok(),
+        }
+    }
 }
 
 /// Parameters for `distil_directory` operation
@@ -123,6 +168,30 @@ struct DistilFileParams {
     options: DistilOptions,
 }
 
+/// Parameters for `distil_source` operation
+#[derive(Debug, Clone, Deserialize)]
+struct DistilSourceParams {
+    /// Source code to distil
+    content: String,
+    /// Language name (`typescript`) or extension (`ts`)
+    #[serde(default)]
+    language: Option<String>,
+    /// Path shown in the output; picks the language if `language` is unset
+    #[serde(default)]
+    filename: Option<PathBuf>,
+    #[serde(default)]
+    options: DistilOptions,
+}
+
+impl DistilSourceParams {
+    /// Path the source is shown under
+    fn display_path(&self) -> PathBuf {
+        self.filename
+            .clone()
+            .unwrap_or_else(|| PathBuf::from("<source>"))
+    }
+}
+
 /// Parameters for `list_dir` operation
 #[derive(Debug, Clone, Deserialize)]
 struct ListDirParams {
@@ -132,67 +201,139 @@ struct ListDirParams {
 }
 
 /// Distillation options (simplified from CLI)
+///
+/// Options left out of a request come from the project config
+/// (`aid.toml` / `.aidrc` at the project root of the requested path, with
+/// `profile` selected) and then from the defaults.
 #[derive(Debug, Clone, Deserialize, Default)]
 struct DistilOptions {
-    #[serde(default = "default_true")]
-    include_public: bool,
     #[serde(default)]
-    include_protected: bool,
+    profile: Option<String>,
+
+    #[serde(default)]
+    include_public: Option<bool>,
     #[serde(default)]
-    include_internal: bool,
+    include_protected: Option<bool>,
     #[serde(default)]
-    include_private: bool,
+    include_internal: Option<bool>,
+    #[serde(default)]
+    include_private: Option<bool>,
 
     #[serde(default)]
-    include_comments: bool,
-    #[serde(default = "default_true")]
-    include_docstrings: bool,
+    include_comments: Option<bool>,
+    #[serde(default)]
+    include_docstrings: Option<bool>,
+    #[serde(default)]
+    include_implementation: Option<bool>,
+    #[serde(default)]
+    include_imports: Option<bool>,
     #[serde(default)]
-    include_implementation: bool,
-    #[serde(default = "default_true")]
-    include_imports: bool,
-    #[serde(default = "default_true")]
-    include_annotations: bool,
-    #[serde(default = "default_true")]
-    include_fields: bool,
-    #[serde(default = "default_true")]
-    include_methods: bool,
+    include_annotations: Option<bool>,
+    #[serde(default)]
+    include_fields: Option<bool>,
+    #[serde(default)]
+    include_methods: Option<bool>,
+    #[serde(default)]
+    include_deprecated: Option<bool>,
+    #[serde(default)]
+    collapse_overloads: Option<bool>,
 
     #[serde(default)]
     format: String, // "text", "md", "json", "jsonl", "xml"
-}
 
-fn default_true() -> bool {
-    true
+    #[serde(default)]
+    tokenizer: Option<String>, // "cl100k" (default), "o200k", "estimate"
+
+    #[serde(default)]
+    max_tokens: Option<usize>,
+
+    /// Return `{output, tokens, budget}` instead of the output alone
+    #[serde(default)]
+    token_report: bool,
 }
 
-impl From<DistilOptions> for ProcessOptions {
-    fn from(opts: DistilOptions) -> Self {
-        ProcessOptions {
-            include_public: opts.include_public,
-            include_protected: opts.include_protected,
-            include_internal: opts.include_internal,
-            include_private: opts.include_private,
-            include_comments: opts.include_comments,
-            include_docstrings: opts.include_docstrings,
-            include_implementation: opts.include_implementation,
-            include_imports: opts.include_imports,
-            include_annotations: opts.include_annotations,
-            include_fields: opts.include_fields,
-            include_methods: opts.include_methods,
-            raw_mode: false,
-            workers: 0, // Auto
-            recursive: true,
-            file_path_type: distiller_core::options::PathType::Relative,
-            relative_path_prefix: None,
-            base_path: None,
-            include_patterns: Vec::new(),
-            exclude_patterns: Vec::new(),
-            continue_on_error: false,
+impl DistilOptions {
+    /// Process options and output format for a request on `path`
+    fn resolve(&self, path: &Path) -> Result<(ProcessOptions, String)> {
+        let start = if path.is_dir() {
+            path
+        } else {
+            path.parent()
+                .filter(|p| !p.as_os_str().is_empty())
+                .unwrap_or(Path::new("."))
+        };
+        let root = ProjectRoot::detect(start);
+
+        let mut options = ProcessOptions::default();
+        let settings =
+            config::apply_config(&root.path, self.profile.as_deref(), None, &mut options)?
+                .map(|(_, settings)| settings);
+
+        macro_rules! set {
+            ($($field:ident),*) => {
+                $(if let Some(value) = self.$field {
+                    options.$field = value;
+                })*
+            };
+        }
+        set!(
+            include_public,
+            include_protected,
+            include_internal,
+            include_private,
+            include_comments,
+            include_docstrings,
+            include_implementation,
+            include_imports,
+            include_annotations,
+            include_fields,
+            include_methods,
+            include_deprecated,
+            collapse_overloads
+        );
+
+        let format = if self.format.is_empty() {
+            settings
+                .and_then(|s| s.format)
+                .unwrap_or_else(|| "text".to_string())
+        } else {
+            self.format.clone()
+        };
+        Ok((options, format))
+    }
+
+    /// Tokenizer for the token report
+    fn tokenizer(&self) -> Result<Tokenizer> {
+        match &self.tokenizer {
+            Some(name) => Ok(name.parse().map_err(DistilError::invalid_config)?),
+            None => Ok(Tokenizer::default()),
         }
     }
 }
 
+/// Result of `distil_directory`, `distil_file` and `distil_source`
+///
+/// The formatted output, or the output with its token report when the
+/// request sets `token_report`.
+#[derive(Debug, Serialize)]
+#[serde(untagged)]
+enum DistilResponse {
+    Output(String),
+    Report(Box<DistilResult>),
+}
+
+/// Formatted output with its token report
+#[derive(Debug, Serialize)]
+struct DistilResult {
+    /// Formatted output
+    output: String,
+    /// Token counts per file, per language and in total
+    tokens: TokenReport,
+    /// What was omitted to fit `max_tokens`, if given
+    #[serde(skip_serializing_if = "Option::is_none")]
+    budget: Option<Fitted>,
+}
+
 /// MCP Server state
 #[allow(dead_code)]
 struct McpServer {
@@ -209,64 +350,58 @@ impl McpServer {
     }
 
     /// Handle `distil_directory` operation
-    async fn handle_distil_directory(&self, params: DistilDirectoryParams) -> Result<String> {
+    async fn handle_distil_directory(
+        &self,
+        params: DistilDirectoryParams,
+    ) -> Result<DistilResponse> {
         let path = &params.path;
         if !path.exists() {
-            anyhow::bail!("Path does not exist: {}", path.display());
+            return Err(DistilError::FileNotFound { path: path.clone() }.into());
         }
         if !path.is_dir() {
-            anyhow::bail!("Path is not a directory: {}", path.display());
+            return Err(DistilError::invalid_config_at(path, "Path is not a directory").into());
         }
 
-        // Update processor options
-        let proc_opts: ProcessOptions = params.options.clone().into();
+        // Resolve options against the project config
+        let (proc_opts, format) = params.options.resolve(path)?;
         let processor = Processor::new(proc_opts);
         let mut processor = processor;
         register_all_languages(&mut processor);
 
-        // Process directory
-        let node = processor
-            .process_path(path)
-            .context("Failed to process directory")?;
+        // Process directory and apply visibility/content options
+        let mut node = processor.process_path(path)?;
+        Stripper::new(processor.options().clone()).visit_node(&mut node);
 
         // Extract files
         let files = extract_files(&node);
 
         if files.is_empty() {
-            anyhow::bail!("No files found in directory");
+            return Err(DistilError::invalid_config_at(path, "No files found in directory").into());
         }
 
-        // Format output
-        let format = if params.options.format.is_empty() {
-            "text"
-        } else {
-            &params.options.format
-        };
-
-        let output = self.format_files(&files, format)?;
-        Ok(output)
+        // Format output and count tokens
+        self.distil_result(&processor, &node, &files, &format, &params.options, None)
     }
 
     /// Handle `distil_file` operation
-    async fn handle_distil_file(&self, params: DistilFileParams) -> Result<String> {
+    async fn handle_distil_file(&self, params: DistilFileParams) -> Result<DistilResponse> {
         let path = &params.path;
         if !path.exists() {
-            anyhow::bail!("File does not exist: {}", path.display());
+            return Err(DistilError::FileNotFound { path: path.clone() }.into());
         }
         if !path.is_file() {
-            anyhow::bail!("Path is not a file: {}", path.display());
+            return Err(DistilError::invalid_config_at(path, "Path is not a file").into());
         }
 
-        // Update processor options
-        let proc_opts: ProcessOptions = params.options.clone().into();
+        // Resolve options against the project config
+        let (proc_opts, format) = params.options.resolve(path)?;
         let processor = Processor::new(proc_opts);
         let mut processor = processor;
         register_all_languages(&mut processor);
 
-        // Process file
-        let node = processor
-            .process_path(path)
-            .context("Failed to process file")?;
+        // Process file and apply visibility/content options
+        let mut node = processor.process_path(path)?;
+        Stripper::new(processor.options().clone()).visit_node(&mut node);
 
         // Extract files
         let files = extract_files(&node);
@@ -275,25 +410,55 @@ impl McpServer {
             anyhow::bail!("Failed to process file");
         }
 
-        // Format output
-        let format = if params.options.format.is_empty() {
-            "text"
-        } else {
-            &params.options.format
-        };
+        // Format output and count tokens
+        self.distil_result(&processor, &node, &files, &format, &params.options, None)
+    }
 
-        let output = self.format_files(&files, format)?;
-        Ok(output)
+    /// Handle `distil_source` operation
+    ///
+    /// The source is processed and stripped like a file's, without touching
+    /// the filesystem; `filename` need not exist.
+    async fn handle_distil_source(&self, params: DistilSourceParams) -> Result<DistilResponse> {
+        if params.language.is_none() && params.filename.is_none() {
+            return Err(
+                DistilError::invalid_config("distil_source needs language or filename").into(),
+            );
+        }
+        let path = params.display_path();
+
+        // Resolve options against the project config around the filename
+        let (proc_opts, format) = params.options.resolve(&path)?;
+        let processor = Processor::new(proc_opts);
+        let mut processor = processor;
+        register_all_languages(&mut processor);
+
+        // Process source and apply visibility/content options
+        let mut node =
+            processor.process_source(&params.content, &path, params.language.as_deref())?;
+        Stripper::new(processor.options().clone()).visit_node(&mut node);
+
+        // Extract files
+        let files = extract_files(&node);
+
+        // Format output and count tokens against the source given
+        self.distil_result(
+            &processor,
+            &node,
+            &files,
+            &format,
+            &params.options,
+            Some(&params.content),
+        )
     }
 
     /// Handle `list_dir` operation
     async fn handle_list_dir(&self, params: ListDirParams) -> Result<Vec<FileInfo>> {
         let path = &params.path;
         if !path.exists() {
-            anyhow::bail!("Path does not exist: {}", path.display());
+            return Err(DistilError::FileNotFound { path: path.clone() }.into());
         }
         if !path.is_dir() {
-            anyhow::bail!("Path is not a directory: {}", path.display());
+            return Err(DistilError::invalid_config_at(path, "Path is not a directory").into());
         }
 
         let mut entries = Vec::new();
@@ -333,6 +498,7 @@ impl McpServer {
             operations: vec![
                 "distil_directory".to_string(),
                 "distil_file".to_string(),
+                "distil_source".to_string(),
                 "list_dir".to_string(),
                 "get_capa".to_string(),
             ],
@@ -361,6 +527,97 @@ impl McpServer {
         })
     }
 
+    /// Format `files`, counting tokens before and after distillation if
+    /// the request asks for a token report
+    ///
+    /// `node` is already stripped with the processor's options, so with
+    /// `max_tokens` set the budget only degrades what the caller asked for
+    /// until its output fits.
+    /// Originals are read from disk, except `source`, the original of a
+    /// single file given in the request.
+    fn distil_result(
+        &self,
+        processor: &Processor,
+        node: &Node,
+        files: &[File],
+        format: &str,
+        options: &DistilOptions,
+        source: Option<&str>,
+    ) -> Result<DistilResponse> {
+        let tokenizer = options.tokenizer()?;
+        let budget = options
+            .max_tokens
+            .map(|max_tokens| {
+                budget::fit(
+                    node,
+                    processor.options(),
+                    max_tokens,
+                    tokenizer,
+                    |node, omitted| {
+                        let record = budget::Record {
+                            max_tokens,
+                            omitted,
+                        };
+                        let rendered =
+                            self.format_budgeted(&extract_files(node), format, record)?;
+                        Ok::<_, anyhow::Error>(match budget::note(max_tokens, omitted) {
+                            Some(text) => annotate(format, &rendered, &text),
+                            None => rendered,
+                        })
+                    },
+                )
+            })
+            .transpose()?;
+
+        let fitted_files = budget.as_ref().map(|fitted| extract_files(&fitted.node));
+        let files = fitted_files.as_deref().unwrap_or(files);
+        let output = match &budget {
+            Some(fitted) => fitted.output.clone(),
+            None => self.format_files(files, format)?,
+        };
+        if !options.token_report {
+            return Ok(DistilResponse::Output(output));
+        }
+        let tokens = TokenReport::build(
+            tokenizer,
+            files,
+            &output,
+            &processor.options().fallback_encodings,
+            |_| source.map(str::to_owned),
+            |path| processor.language_for(path),
+            |file| self.format_files(std::slice::from_ref(file), format),
+        )?;
+        Ok(DistilResponse::Report(Box::new(DistilResult {
+            output,
+            tokens,
+            budget,
+        })))
+    }
+
+    /// Format budgeted files, recording the budget in JSON and JSONL output
+    fn format_budgeted(
+        &self,
+        files: &[File],
+        format: &str,
+        budget: budget::Record,
+    ) -> Result<String> {
+        match format {
+            "json" => {
+                let metadata = Metadata {
+                    options: None,
+                    budget: Some(budget),
+                };
+                JsonFormatter::new()
+                    .format_files_with_metadata(files, &metadata)
+                    .context("Failed to format as JSON")
+            }
+            "jsonl" => JsonlFormatter::new()
+                .format_files_with_budget(files, budget)
+                .context("Failed to format as JSONL"),
+            _ => self.format_files(files, format),
+        }
+    }
+
     /// Format files using specified formatter
     fn format_files(&self, files: &[File], format: &str) -> Result<String> {
         match format {
@@ -394,7 +651,7 @@ impl McpServer {
                     .format_files(files)
                     .context("Failed to format as XML")
             }
-            _ => anyhow::bail!("Unsupported format: {format}"),
+            _ => Err(DistilError::invalid_config(format!("Unsupported format: {format}")).into()),
         }
     }
 }
@@ -417,6 +674,21 @@ struct ServerCapabilities {
     supported_formats: Vec<String>,
 }
 
+/// Put `note` at the top of `output` as a comment, where the format has one
+///
+/// JSON and JSONL output record the omissions in their metadata instead.
+fn annotate(format: &str, output: &str, note: &str) -> String {
+    match format {
+        "text" => format!("<omitted>{note}</omitted>\n{output}"),
+        "md" | "markdown" => format!("> {note}\n\n{output}"),
+        "xml" => match output.split_once('\n') {
+            Some((declaration, rest)) => format!("{declaration}\n<!-- {note} -->\n{rest}"),
+            None => output.to_string(),
+        },
+        _ => output.to_string(),
+    }
+}
+
 /// Extract File nodes from an IR Node (recursive for Directory)
 fn extract_files(node: &Node) -> Vec<File> {
     let mut files = Vec::new();
@@ -621,24 +893,9 @@ async fn main() -> Result<()> {
 
                         // Handle operation
                         match server.handle_distil_directory(params.clone()).await {
-                            Ok(result) => JsonRpcResponse {
-                                jsonrpc: "2.0".to_string(),
-                                id: request.id,
-                                result: Some(serde_json::Value::String(result)),
-                                error: None,
-                            },
+                            Ok(result) => JsonRpcResponse::success(request.id, &result),
                             Err(e) => {
-                                let error_msg = e.to_string();
-                                let error = if error_msg.contains("does not exist")
-                                    || error_msg.contains("not a directory")
-                                {
-                                    JsonRpcError::file_not_found(params.path.display().to_string())
-                                } else {
-                                    JsonRpcError::processing_failed(
-                                        error_msg,
-                                        Some(params.path.display().to_string()),
-                                    )
-                                };
+                                let error = JsonRpcError::from_error(&e, &params.path);
                                 JsonRpcResponse {
                                     jsonrpc: "2.0".to_string(),
                                     id: request.id,
@@ -674,24 +931,47 @@ async fn main() -> Result<()> {
 
                         // Handle operation
                         match server.handle_distil_file(params.clone()).await {
-                            Ok(result) => JsonRpcResponse {
-                                jsonrpc: "2.0".to_string(),
-                                id: request.id,
-                                result: Some(serde_json::Value::String(result)),
-                                error: None,
-                            },
+                            Ok(result) => JsonRpcResponse::success(request.id, &result),
                             Err(e) => {
-                                let error_msg = e.to_string();
-                                let error = if error_msg.contains("does not exist")
-                                    || error_msg.contains("not a file")
-                                {
-                                    JsonRpcError::file_not_found(params.path.display().to_string())
-                                } else {
-                                    JsonRpcError::processing_failed(
-                                        error_msg,
-                                        Some(params.path.display().to_string()),
-                                    )
+                                let error = JsonRpcError::from_error(&e, &params.path);
+                                JsonRpcResponse {
+                                    jsonrpc: "2.0".to_string(),
+                                    id: request.id,
+                                    result: None,
+                                    error: Some(error),
+                                }
+                            }
+                        }
+                    }
+                    "distil_source" => {
+                        // Parse params
+                        let params: DistilSourceParams = match serde_json::from_value(
+                            request.params.unwrap_or(serde_json::Value::Null),
+                        ) {
+                            Ok(p) => p,
+                            Err(e) => {
+                                // Send error response and continue to next request
+                                let error_response = JsonRpcResponse {
+                                    jsonrpc: "2.0".to_string(),
+                                    id: request.id.clone(),
+                                    result: None,
+                                    error: Some(JsonRpcError {
+                                        code: -32602,
+                                        message: format!("Invalid params: {e}"),
+                                        data: None,
+                                    }),
                                 };
+                                send_response(&mut stdout, &error_response).await?;
+                                log::info!("📤 Sent error response for id={:?}", error_response.id);
+                                continue;
+                            }
+                        };
+
+                        // Handle operation
+                        match server.handle_distil_source(params.clone()).await {
+                            Ok(result) => JsonRpcResponse::success(request.id, &result),
+                            Err(e) => {
+                                let error = JsonRpcError::from_error(&e, &params.display_path());
                                 JsonRpcResponse {
                                     jsonrpc: "2.0".to_string(),
                                     id: request.id,
@@ -727,22 +1007,9 @@ async fn main() -> Result<()> {
 
                         // Handle operation
                         match server.handle_list_dir(params.clone()).await {
-                            Ok(result) => JsonRpcResponse {
-                                jsonrpc: "2.0".to_string(),
-                                id: request.id,
-                                result: Some(serde_json::to_value(result).unwrap()),
-                                error: None,
-                            },
+                            Ok(result) => JsonRpcResponse::success(request.id, &result),
                             Err(e) => {
-                                let error_msg = e.to_string();
-                                let error = if error_msg.contains("does not exist") {
-                                    JsonRpcError::file_not_found(params.path.display().to_string())
-                                } else {
-                                    JsonRpcError::processing_failed(
-                                        error_msg,
-                                        Some(params.path.display().to_string()),
-                                    )
-                                };
+                                let error = JsonRpcError::from_error(&e, &params.path);
                                 JsonRpcResponse {
                                     jsonrpc: "2.0".to_string(),
                                     id: request.id,
@@ -755,12 +1022,7 @@ async fn main() -> Result<()> {
                     "get_capa" => {
                         // No params needed for get_capa
                         match server.handle_get_capa().await {
-                            Ok(result) => JsonRpcResponse {
-                                jsonrpc: "2.0".to_string(),
-                                id: request.id,
-                                result: Some(serde_json::to_value(result).unwrap()),
-                                error: None,
-                            },
+                            Ok(result) => JsonRpcResponse::success(request.id, &result),
                             Err(e) => JsonRpcResponse {
                                 jsonrpc: "2.0".to_string(),
                                 id: request.id,